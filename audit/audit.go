@@ -0,0 +1,345 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// DirName is the name of the dir in the Forta dir which the audit logs are written to.
+const DirName = "audit"
+
+// Event types
+const (
+	EventProcessStarted = "process.started"
+	EventConfigChanged  = "config.changed"
+	EventKeyUsed        = "key.used"
+	EventAdminAPICall   = "admin.api.call"
+	EventAgentStarted   = "agent.started"
+	EventAgentStopped   = "agent.stopped"
+	EventImageVerified  = "image.verified"
+)
+
+// Entry is an audit log entry. The hash covers the entry and the hash of the previous entry so that the
+// entries cannot be changed or removed without breaking the chain. The chain cannot tell if the latest
+// entries are removed, so the heads of the logs should be recorded outside of the node to detect that.
+type Entry struct {
+	Seq      uint64            `json:"seq"`
+	Time     string            `json:"time"`
+	Process  string            `json:"process"`
+	Event    string            `json:"event"`
+	Details  map[string]string `json:"details,omitempty"`
+	PrevHash string            `json:"prevHash"`
+	Hash     string            `json:"hash"`
+}
+
+func (entry *Entry) computeHash() string {
+	hashed := *entry
+	hashed.Hash = ""
+	b, _ := json.Marshal(&hashed)
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:])
+}
+
+// Log is the append-only audit log of a process.
+type Log struct {
+	process  string
+	file     *os.File
+	seq      uint64
+	lastHash string
+	mu       sync.Mutex
+}
+
+// Dir returns the audit logs dir.
+func Dir(fortaDir string) string {
+	return path.Join(fortaDir, DirName)
+}
+
+// Open opens the audit log of the process in the dir and continues the chain of the existing entries.
+// The last line is truncated if it was not written completely. The dir and the file are owned by the
+// owner of the Forta dir so that the CLI and the node containers can write the logs next to each other.
+func Open(dir, process string) (*Log, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := chownToParent(dir, dir); err != nil {
+		return nil, err
+	}
+	filePath := path.Join(dir, fmt.Sprintf("%s.log", process))
+	entries, size, err := readEntries(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := truncateTorn(file, size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := chownToParent(filePath, dir); err != nil {
+		file.Close()
+		return nil, err
+	}
+	auditLog := &Log{process: process, file: file}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		auditLog.seq = last.Seq
+		auditLog.lastHash = last.Hash
+	}
+	return auditLog, nil
+}
+
+// Record appends an entry to the log.
+func (auditLog *Log) Record(event string, details map[string]string) error {
+	auditLog.mu.Lock()
+	defer auditLog.mu.Unlock()
+
+	entry := &Entry{
+		Seq:      auditLog.seq + 1,
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		Process:  auditLog.process,
+		Event:    event,
+		Details:  details,
+		PrevHash: auditLog.lastHash,
+	}
+	entry.Hash = entry.computeHash()
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := auditLog.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if err := auditLog.file.Sync(); err != nil {
+		return err
+	}
+	auditLog.seq = entry.Seq
+	auditLog.lastHash = entry.Hash
+	return nil
+}
+
+// Close closes the log file.
+func (auditLog *Log) Close() error {
+	auditLog.mu.Lock()
+	defer auditLog.mu.Unlock()
+	return auditLog.file.Close()
+}
+
+var (
+	current   *Log
+	currentMu sync.RWMutex
+)
+
+// Init opens the audit log of the process and records the start of the process.
+func Init(cfg config.Config, process string) (io.Closer, error) {
+	auditLog, err := Open(Dir(cfg.FortaDir), process)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log: %v", err)
+	}
+	currentMu.Lock()
+	current = auditLog
+	currentMu.Unlock()
+
+	Record(EventProcessStarted, map[string]string{"version": config.Version})
+	return auditLog, nil
+}
+
+// Record appends an entry to the audit log of the process. It only logs the failures so that the
+// auditing does not stop the node.
+func Record(event string, details map[string]string) {
+	currentMu.RLock()
+	auditLog := current
+	currentMu.RUnlock()
+	if auditLog == nil {
+		return
+	}
+	if err := auditLog.Record(event, details); err != nil {
+		log.WithError(err).WithField("event", event).Error("failed to write the audit log")
+	}
+}
+
+// Append records a single entry to the audit log of the process. It is useful for the short-lived CLI
+// commands.
+func Append(cfg config.Config, process, event string, details map[string]string) error {
+	auditLog, err := Open(Dir(cfg.FortaDir), process)
+	if err != nil {
+		return err
+	}
+	defer auditLog.Close()
+	return auditLog.Record(event, details)
+}
+
+// truncateTorn drops the last line which was torn by a crash or a full disk and terminates the last
+// entry if only its newline is missing.
+func truncateTorn(file *os.File, size int64) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > size {
+		log.WithFields(log.Fields{
+			"file":  file.Name(),
+			"bytes": info.Size() - size,
+		}).Warn("truncating the torn last line of the audit log")
+		if err := file.Truncate(size); err != nil {
+			return err
+		}
+	}
+	if size == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
+
+// chownToParent gives the file to the owner of the parent of the dir if the process runs as root.
+func chownToParent(filePath, dir string) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	info, err := os.Stat(path.Dir(dir))
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Uid == 0 {
+		return nil
+	}
+	return os.Chown(filePath, int(stat.Uid), int(stat.Gid))
+}
+
+// ReadEntries reads the entries of an audit log file. A torn last line is skipped.
+func ReadEntries(filePath string) ([]*Entry, error) {
+	entries, _, err := readEntries(filePath)
+	return entries, err
+}
+
+// readEntries reads the entries and returns the size of the valid lines.
+func readEntries(filePath string) ([]*Entry, int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var (
+		entries []*Entry
+		size    int64
+	)
+	reader := bufio.NewReader(file)
+	for {
+		b, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		complete := err == nil
+		line := strings.TrimSpace(string(b))
+		if len(line) > 0 {
+			var entry Entry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				// only the last line can be torn
+				if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+					return entries, size, nil
+				}
+				return nil, 0, fmt.Errorf("invalid entry after seq %d: %v", len(entries), err)
+			}
+			entries = append(entries, &entry)
+		}
+		size += int64(len(b))
+		if !complete {
+			return entries, size, nil
+		}
+	}
+}
+
+// Heads returns the latest entry of each process. They should be kept outside of the node so that
+// removing the latest entries can be detected.
+func Heads(entries []*Entry) map[string]*Entry {
+	heads := make(map[string]*Entry)
+	for _, entry := range entries {
+		if head, ok := heads[entry.Process]; !ok || entry.Seq > head.Seq {
+			heads[entry.Process] = entry
+		}
+	}
+	return heads
+}
+
+// Verify checks the sequence and the hash chain of the entries of a log.
+func Verify(entries []*Entry) error {
+	var prev *Entry
+	for _, entry := range entries {
+		expectedSeq, expectedPrevHash := uint64(1), ""
+		if prev != nil {
+			expectedSeq, expectedPrevHash = prev.Seq+1, prev.Hash
+		}
+		if entry.Seq != expectedSeq {
+			return fmt.Errorf("expected seq %d but found %d", expectedSeq, entry.Seq)
+		}
+		if entry.PrevHash != expectedPrevHash {
+			return fmt.Errorf("entry %d does not link to the previous entry", entry.Seq)
+		}
+		if entry.Hash != entry.computeHash() {
+			return fmt.Errorf("entry %d has an invalid hash", entry.Seq)
+		}
+		prev = entry
+	}
+	return nil
+}
+
+// ReadAll reads the logs of all processes in the dir and returns the entries in time order. The chain of
+// each log is verified if verify is true.
+func ReadAll(dir string, verify bool) ([]*Entry, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var all []*Entry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".log") {
+			continue
+		}
+		entries, err := ReadEntries(path.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", file.Name(), err)
+		}
+		if verify {
+			if err := Verify(entries); err != nil {
+				return nil, fmt.Errorf("audit log %s is broken: %v", file.Name(), err)
+			}
+		}
+		all = append(all, entries...)
+	}
+	times := make(map[*Entry]time.Time, len(all))
+	for _, entry := range all {
+		times[entry], _ = time.Parse(time.RFC3339Nano, entry.Time)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return times[all[i]].Before(times[all[j]])
+	})
+	return all, nil
+}
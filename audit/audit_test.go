@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	r := require.New(t)
+
+	fortaDir := t.TempDir()
+	dir := Dir(fortaDir)
+	auditLog, err := Open(dir, "supervisor")
+	r.NoError(err)
+	r.NoError(auditLog.Record(EventAgentStarted, map[string]string{"agent": "0x1"}))
+	r.NoError(auditLog.Record(EventAgentStopped, map[string]string{"agent": "0x1"}))
+	r.NoError(auditLog.Close())
+
+	// continues the chain after reopening
+	auditLog, err = Open(dir, "supervisor")
+	r.NoError(err)
+	r.NoError(auditLog.Record(EventAgentStarted, map[string]string{"agent": "0x2"}))
+	r.NoError(auditLog.Close())
+
+	r.NoError(Append(config.Config{FortaDir: fortaDir}, "cli", EventKeyUsed, map[string]string{"purpose": "export"}))
+
+	entries, err := ReadEntries(path.Join(dir, "supervisor.log"))
+	r.NoError(err)
+	r.Len(entries, 3)
+	r.NoError(Verify(entries))
+	r.Equal(uint64(3), entries[2].Seq)
+	r.Equal(entries[1].Hash, entries[2].PrevHash)
+
+	all, err := ReadAll(dir, true)
+	r.NoError(err)
+	r.Len(all, 4)
+	r.Equal("cli", all[3].Process)
+	r.Equal(EventKeyUsed, all[3].Event)
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	auditLog, err := Open(dir, "runner")
+	r.NoError(err)
+	for _, agentID := range []string{"0x1", "0x2", "0x3"} {
+		r.NoError(auditLog.Record(EventAgentStarted, map[string]string{"agent": agentID}))
+	}
+	r.NoError(auditLog.Close())
+
+	filePath := path.Join(dir, "runner.log")
+	b, err := ioutil.ReadFile(filePath)
+	r.NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+
+	// changed entry
+	var entry Entry
+	r.NoError(json.Unmarshal([]byte(lines[1]), &entry))
+	entry.Details["agent"] = "0x4"
+	changed, err := json.Marshal(&entry)
+	r.NoError(err)
+	r.NoError(ioutil.WriteFile(filePath, []byte(strings.Join([]string{lines[0], string(changed), lines[2]}, "\n")), 0600))
+	_, err = ReadAll(dir, true)
+	r.Error(err)
+
+	// removed entry
+	r.NoError(ioutil.WriteFile(filePath, []byte(strings.Join([]string{lines[0], lines[2]}, "\n")), 0600))
+	entries, err := ReadEntries(filePath)
+	r.NoError(err)
+	r.Error(Verify(entries))
+
+	// can still be read without verifying
+	all, err := ReadAll(dir, false)
+	r.NoError(err)
+	r.Len(all, 2)
+
+	r.NoError(os.Remove(filePath))
+	all, err = ReadAll(dir, true)
+	r.NoError(err)
+	r.Empty(all)
+}
+
+func TestOpen_TornLastLine(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	auditLog, err := Open(dir, "scanner")
+	r.NoError(err)
+	r.NoError(auditLog.Record(EventAgentStarted, map[string]string{"agent": "0x1"}))
+	r.NoError(auditLog.Close())
+
+	filePath := path.Join(dir, "scanner.log")
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0600)
+	r.NoError(err)
+	_, err = f.WriteString(`{"seq":2,"time":"20`)
+	r.NoError(err)
+	r.NoError(f.Close())
+
+	entries, err := ReadEntries(filePath)
+	r.NoError(err)
+	r.Len(entries, 1)
+
+	// the torn line is dropped and the chain continues
+	auditLog, err = Open(dir, "scanner")
+	r.NoError(err)
+	r.NoError(auditLog.Record(EventAgentStopped, map[string]string{"agent": "0x1"}))
+	r.NoError(auditLog.Close())
+	entries, err = ReadEntries(filePath)
+	r.NoError(err)
+	r.Len(entries, 2)
+	r.NoError(Verify(entries))
+
+	heads := Heads(entries)
+	r.Equal(entries[1], heads["scanner"])
+}
@@ -2,6 +2,7 @@ package agentgrpc
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 const defaultAgentResponseMaxByteCount = 1000000 // 1M
@@ -26,7 +28,8 @@ const (
 
 // Client allows us to communicate with an agent.
 type Client struct {
-	conn *grpc.ClientConn
+	conn      *grpc.ClientConn
+	tlsConfig *tls.Config
 	protocol.AgentClient
 }
 
@@ -35,16 +38,31 @@ func NewClient() *Client {
 	return &Client{}
 }
 
+// WithTLS makes the client dial the agents with the TLS config.
+func (client *Client) WithTLS(tlsConfig *tls.Config) *Client {
+	client.tlsConfig = tlsConfig
+	return client
+}
+
 // Dial dials an agent using the config.
 func (client *Client) Dial(cfg config.AgentConfig) error {
+	return client.DialHost(cfg, cfg.GrpcHost())
+}
+
+// DialHost dials an agent at the given host.
+func (client *Client) DialHost(cfg config.AgentConfig, host string) error {
 	var (
 		conn *grpc.ClientConn
 		err  error
 	)
+	transportOpt := grpc.WithInsecure()
+	if client.tlsConfig != nil {
+		transportOpt = grpc.WithTransportCredentials(credentials.NewTLS(client.tlsConfig))
+	}
 	for i := 0; i < 10; i++ {
 		conn, err = grpc.Dial(
-			fmt.Sprintf("%s:%s", cfg.ContainerName(), cfg.GrpcPort()),
-			grpc.WithInsecure(),
+			fmt.Sprintf("%s:%s", host, cfg.GrpcPort()),
+			transportOpt,
 			grpc.WithBlock(),
 			grpc.WithTimeout(10*time.Second),
 			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(defaultAgentResponseMaxByteCount)),
@@ -0,0 +1,250 @@
+package agentgrpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"time"
+)
+
+// TLS file names
+const (
+	CACertFileName = "ca.pem"
+	CAKeyFileName  = "ca-key.pem"
+	CertFileName   = "cert.pem"
+	KeyFileName    = "key.pem"
+
+	ClientCertFileName   = "agent-client-cert.pem"
+	ClientKeyFileName    = "agent-client-key.pem"
+	ClientCACertFileName = "agent-ca.pem"
+)
+
+const (
+	caValidity   = time.Hour * 24 * 365 * 10
+	certValidity = time.Hour * 24 * 365
+)
+
+// CA is the node-local certificate authority which issues the agent server certificates and the scanner
+// client certificates for the mutual TLS on the agent gRPC channel.
+type CA struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     *ecdsa.PrivateKey
+}
+
+// LoadCA loads the CA from the dir.
+func LoadCA(dir string) (*CA, error) {
+	certPEM, err := ioutil.ReadFile(path.Join(dir, CACertFileName))
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(path.Join(dir, CAKeyFileName))
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ca key pair: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("ca key is not an ecdsa key")
+	}
+	return &CA{cert: cert, certPEM: certPEM, key: key}, nil
+}
+
+// LoadOrCreateCA loads the CA from the dir or creates a new CA in the dir if it does not exist.
+func LoadOrCreateCA(dir string) (*CA, error) {
+	ca, err := LoadCA(dir)
+	if err == nil {
+		return ca, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template, err := certTemplate("forta-node-agent-ca", caValidity)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, keyPEM, err := encodePEM(der, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeKeyPair(dir, CACertFileName, CAKeyFileName, certPEM, keyPEM); err != nil {
+		return nil, err
+	}
+	return LoadCA(dir)
+}
+
+func certTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+func encodePEM(der []byte, key *ecdsa.PrivateKey) (certPEM, keyPEM []byte, err error) {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return
+}
+
+// writeKeyPair writes the files atomically so that the readers never see a partial key pair.
+func writeKeyPair(dir, certFileName, keyFileName string, certPEM, keyPEM []byte) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for fileName, b := range map[string][]byte{certFileName: certPEM, keyFileName: keyPEM} {
+		tmpPath := path.Join(dir, fmt.Sprintf(".%s.tmp", fileName))
+		if err := ioutil.WriteFile(tmpPath, b, 0600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpPath, path.Join(dir, fileName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CertPEM returns the CA certificate.
+func (ca *CA) CertPEM() []byte {
+	return ca.certPEM
+}
+
+func (ca *CA) issue(name string, extKeyUsage x509.ExtKeyUsage) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template, err := certTemplate(name, certValidity)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.DNSNames = []string{name}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{extKeyUsage}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodePEM(der, key)
+}
+
+// WriteAgentCerts issues the server certificate of the agent container and writes it to the dir with
+// the CA certificate which the agent verifies the scanner with. The files are readable only by the
+// user which the agent container runs as.
+func (ca *CA) WriteAgentCerts(dir, containerName string, uid int) error {
+	certPEM, keyPEM, err := ca.issue(containerName, x509.ExtKeyUsageServerAuth)
+	if err != nil {
+		return fmt.Errorf("failed to issue the agent certificate: %v", err)
+	}
+	if err := writeKeyPair(dir, CertFileName, KeyFileName, certPEM, keyPEM); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path.Join(dir, CACertFileName), ca.certPEM, 0600); err != nil {
+		return err
+	}
+	for _, fileName := range []string{CertFileName, KeyFileName, CACertFileName} {
+		if err := os.Chown(path.Join(dir, fileName), uid, -1); err != nil {
+			return err
+		}
+	}
+	return os.Chown(dir, uid, -1)
+}
+
+// ClientFiles issues the client certificate of the scanner and returns the files which the scanner
+// loads with LoadClientTLSConfig. The CA key is not one of them.
+func (ca *CA) ClientFiles(clientName string) (map[string][]byte, error) {
+	certPEM, keyPEM, err := ca.issue(clientName, x509.ExtKeyUsageClientAuth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue the client certificate: %v", err)
+	}
+	return map[string][]byte{
+		ClientCertFileName:   certPEM,
+		ClientKeyFileName:    keyPEM,
+		ClientCACertFileName: ca.certPEM,
+	}, nil
+}
+
+// LoadClientTLSConfig loads the client files from the dir and returns the config which requires the
+// agent server certificate for the server name.
+func LoadClientTLSConfig(dir, serverName string) (*tls.Config, error) {
+	pair, err := tls.LoadX509KeyPair(path.Join(dir, ClientCertFileName), path.Join(dir, ClientKeyFileName))
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := ioutil.ReadFile(path.Join(dir, ClientCACertFileName))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("invalid ca certificate")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		RootCAs:      pool,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ServerTLSConfig returns the config which requires the client certificates issued by the CA. It is the
+// config which the agents should use with the files written by WriteAgentCerts.
+func ServerTLSConfig(dir string) (*tls.Config, error) {
+	pair, err := tls.LoadX509KeyPair(path.Join(dir, CertFileName), path.Join(dir, KeyFileName))
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := ioutil.ReadFile(path.Join(dir, CACertFileName))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("invalid ca certificate")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
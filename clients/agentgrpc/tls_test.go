@@ -0,0 +1,100 @@
+package agentgrpc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/clients/agentgrpc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const testAgentContainerName = "forta-agent-0x1234"
+
+func startTLSAgentServer(r *require.Assertions, certDir string) (string, func()) {
+	serverTLS, err := agentgrpc.ServerTLSConfig(certDir)
+	r.NoError(err)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverTLS)))
+	protocol.RegisterAgentServer(server, &agentServer{r: r, disableAssertion: true})
+	go server.Serve(lis)
+	return lis.Addr().String(), server.Stop
+}
+
+func initializeAgent(address string, opt grpc.DialOption) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, err := grpc.Dial(address, opt)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	agentClient := agentgrpc.NewClient()
+	agentClient.WithConn(conn)
+	return agentClient.Invoke(ctx, agentgrpc.MethodInitialize, &protocol.InitializeRequest{}, &protocol.InitializeResponse{})
+}
+
+func TestAgentMTLS(t *testing.T) {
+	r := require.New(t)
+
+	caDir := t.TempDir()
+	ca, err := agentgrpc.LoadOrCreateCA(caDir)
+	r.NoError(err)
+	sameCA, err := agentgrpc.LoadOrCreateCA(caDir)
+	r.NoError(err)
+	r.Equal(ca.CertPEM(), sameCA.CertPEM())
+
+	certDir := path.Join(caDir, testAgentContainerName)
+	r.NoError(ca.WriteAgentCerts(certDir, testAgentContainerName, os.Getuid()))
+	address, stop := startTLSAgentServer(r, certDir)
+	defer stop()
+
+	// only the agent user can read the agent files
+	info, err := os.Stat(certDir)
+	r.NoError(err)
+	r.Equal(os.FileMode(0700), info.Mode().Perm())
+	for _, fileName := range []string{agentgrpc.CertFileName, agentgrpc.KeyFileName, agentgrpc.CACertFileName} {
+		info, err := os.Stat(path.Join(certDir, fileName))
+		r.NoError(err)
+		r.Equal(os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// the scanner with a certificate from the node ca can call the agent
+	clientDir := writeClientFiles(t, r, ca)
+	_, err = os.Stat(path.Join(clientDir, agentgrpc.CAKeyFileName))
+	r.True(os.IsNotExist(err))
+	clientTLS, err := agentgrpc.LoadClientTLSConfig(clientDir, testAgentContainerName)
+	r.NoError(err)
+	r.NoError(initializeAgent(address, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS))))
+
+	// the clients without a certificate from the node ca cannot call the agent
+	r.Error(initializeAgent(address, grpc.WithInsecure()))
+	otherCA, err := agentgrpc.LoadOrCreateCA(t.TempDir())
+	r.NoError(err)
+	otherTLS, err := agentgrpc.LoadClientTLSConfig(writeClientFiles(t, r, otherCA), testAgentContainerName)
+	r.NoError(err)
+	r.Error(initializeAgent(address, grpc.WithTransportCredentials(credentials.NewTLS(otherTLS))))
+
+	// the scanner does not accept a certificate issued for another agent
+	wrongNameTLS, err := agentgrpc.LoadClientTLSConfig(clientDir, "forta-agent-0x5678")
+	r.NoError(err)
+	r.Error(initializeAgent(address, grpc.WithTransportCredentials(credentials.NewTLS(wrongNameTLS))))
+}
+
+func writeClientFiles(t *testing.T, r *require.Assertions, ca *agentgrpc.CA) string {
+	files, err := ca.ClientFiles("forta-scanner")
+	r.NoError(err)
+	dir := t.TempDir()
+	for fileName, b := range files {
+		r.NoError(ioutil.WriteFile(path.Join(dir, fileName), b, 0400))
+	}
+	return dir
+}
@@ -0,0 +1,886 @@
+package clients
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-units"
+	"github.com/forta-network/forta-core-go/utils/workers"
+	"github.com/forta-network/forta-node/config"
+	"github.com/goccy/go-json"
+	log "github.com/sirupsen/logrus"
+)
+
+// DockerLabelFortaNetworks keeps the networks which a container joined when it was created, since
+// containerd cannot attach running containers to networks.
+const DockerLabelFortaNetworks = "network.forta.networks"
+
+const nerdctlBinary = "nerdctl"
+
+var errNetworkAttachNotSupported = errors.New("containerd cannot attach running containers to networks")
+
+// nerdctlRunner runs nerdctl with the arguments.
+type nerdctlRunner func(ctx context.Context, args ...string) (stdout, stderr []byte, err error)
+
+func runNerdctl(ctx context.Context, args ...string) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, nerdctlBinary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), stderr.Bytes(), nil
+}
+
+// nerdctlContainer is a line of the container list output.
+type nerdctlContainer struct {
+	ID     string `json:"ID"`
+	Names  string `json:"Names"`
+	Image  string `json:"Image"`
+	Status string `json:"Status"`
+	Labels string `json:"Labels"`
+	Size   string `json:"Size"`
+}
+
+// nerdctlStats is a line of the container stats output.
+type nerdctlStats struct {
+	ID       string `json:"ID"`
+	CPUPerc  string `json:"CPUPerc"`
+	MemUsage string `json:"MemUsage"`
+	NetIO    string `json:"NetIO"`
+}
+
+// nerdctlImage is a line of the image list output.
+type nerdctlImage struct {
+	ID         string `json:"ID"`
+	Repository string `json:"Repository"`
+	Tag        string `json:"Tag"`
+	Digest     string `json:"Digest"`
+}
+
+// nerdctlNetwork is a line of the network list output.
+type nerdctlNetwork struct {
+	ID     string `json:"ID"`
+	Name   string `json:"Name"`
+	Labels string `json:"Labels"`
+}
+
+// containerdClient manages the containers on containerd by using nerdctl, which mirrors the
+// Docker CLI. The network IDs it returns are the network names.
+type containerdClient struct {
+	address   string
+	namespace string
+	run       nerdctlRunner
+	workers   *workers.Group
+	labels    []dockerLabel
+}
+
+// NewContainerdClient creates a new client which manages the containers in the configured
+// containerd namespace.
+func NewContainerdClient(name string, runtime config.ContainerRuntimeConfig, socket string) *containerdClient {
+	return &containerdClient{
+		address:   socket,
+		namespace: runtime.Namespace,
+		run:       runNerdctl,
+		workers:   workers.New(10),
+		labels:    initLabels(name),
+	}
+}
+
+func (d *containerdClient) nerdctl(ctx context.Context, args ...string) ([]byte, error) {
+	stdout, _, err := d.run(ctx, append([]string{"--address", d.address, "--namespace", d.namespace}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("nerdctl %s failed: %w", args[0], err)
+	}
+	return stdout, nil
+}
+
+// parseLabels parses the comma separated labels of the list outputs.
+func parseLabels(labelsStr string) map[string]string {
+	labels := make(map[string]string)
+	for _, label := range strings.Split(labelsStr, ",") {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv[0]) == 0 {
+			continue
+		}
+		if len(kv) == 2 {
+			labels[kv[0]] = kv[1]
+		} else {
+			labels[kv[0]] = ""
+		}
+	}
+	return labels
+}
+
+func (d *containerdClient) hasLabels(labels map[string]string) bool {
+	for _, label := range d.labels {
+		if labels[label.Name] != label.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// containerState converts the human readable status to the Docker container state.
+func containerState(status string) string {
+	status = strings.ToLower(status)
+	for prefix, state := range map[string]string{
+		"up":         "running",
+		"exited":     "exited",
+		"created":    "created",
+		"paused":     "paused",
+		"restarting": "restarting",
+		"removing":   "removing",
+		"dead":       "dead",
+	} {
+		if strings.HasPrefix(status, prefix) {
+			return state
+		}
+	}
+	return status
+}
+
+// decodeLines decodes the JSON lines of the list outputs.
+func decodeLines(b []byte, newValue func() interface{}) error {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, newValue()); err != nil {
+			return fmt.Errorf("failed to decode nerdctl output: %v", err)
+		}
+	}
+	return scanner.Err()
+}
+
+// PullImage pulls an image using the given ref.
+func (d *containerdClient) PullImage(ctx context.Context, refStr string) error {
+	return d.workers.Execute(func() ([]interface{}, error) {
+		_, err := d.nerdctl(ctx, "pull", "--quiet", refStr)
+		return nil, err
+	}).Error
+}
+
+func (d *containerdClient) listNetworks(ctx context.Context) ([]*nerdctlNetwork, error) {
+	b, err := d.nerdctl(ctx, "network", "ls", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+	var networks []*nerdctlNetwork
+	err = decodeLines(b, func() interface{} {
+		networks = append(networks, &nerdctlNetwork{})
+		return networks[len(networks)-1]
+	})
+	return networks, err
+}
+
+func (d *containerdClient) CreatePublicNetwork(ctx context.Context, name string) (string, error) {
+	return d.createNetwork(ctx, name, false)
+}
+
+// CreateInternalNetwork creates a network without external access. nerdctl must support
+// the internal networks.
+func (d *containerdClient) CreateInternalNetwork(ctx context.Context, name string) (string, error) {
+	return d.createNetwork(ctx, name, true)
+}
+
+func (d *containerdClient) createNetwork(ctx context.Context, name string, internal bool) (string, error) {
+	// Reuse if network exists.
+	networks, err := d.listNetworks(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, network := range networks {
+		if network.Name == name {
+			return network.Name, nil
+		}
+	}
+
+	args := []string{"network", "create"}
+	if internal {
+		args = append(args, "--internal")
+	}
+	for _, label := range d.labels {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", label.Name, label.Value))
+	}
+	if _, err := d.nerdctl(ctx, append(args, name)...); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (d *containerdClient) RemoveNetworkByName(ctx context.Context, networkName string) error {
+	networks, err := d.listNetworks(ctx)
+	if err != nil {
+		return err
+	}
+	for _, network := range networks {
+		if network.Name == networkName {
+			_, err := d.nerdctl(ctx, "network", "rm", networkName)
+			return err
+		}
+	}
+	return nil
+}
+
+// AttachNetwork succeeds only if the container has joined the network when it was created.
+func (d *containerdClient) AttachNetwork(ctx context.Context, containerID string, networkID string) error {
+	container, err := d.GetContainerByID(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	for _, network := range strings.Split(container.Labels[DockerLabelFortaNetworks], ";") {
+		if network == networkID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", errNetworkAttachNotSupported, networkID)
+}
+
+// DetachNetwork does nothing since the containers leave their networks only when they are removed.
+func (d *containerdClient) DetachNetwork(ctx context.Context, containerID string, networkID string) error {
+	return nil
+}
+
+// GetContainers returns all of the containers.
+func (d *containerdClient) GetContainers(ctx context.Context) (DockerContainerList, error) {
+	b, err := d.nerdctl(ctx, "ps", "--all", "--no-trunc", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+	var list []*nerdctlContainer
+	if err := decodeLines(b, func() interface{} {
+		list = append(list, &nerdctlContainer{})
+		return list[len(list)-1]
+	}); err != nil {
+		return nil, err
+	}
+	var containers DockerContainerList
+	for _, c := range list {
+		labels := parseLabels(c.Labels)
+		if !d.hasLabels(labels) {
+			continue
+		}
+		containers = append(containers, types.Container{
+			ID:     c.ID,
+			Names:  []string{"/" + c.Names},
+			Image:  c.Image,
+			Labels: labels,
+			State:  containerState(c.Status),
+			Status: c.Status,
+		})
+	}
+	return containers, nil
+}
+
+// GetContainerDiskUsage returns the size of the writable layer of the container in bytes.
+func (d *containerdClient) GetContainerDiskUsage(ctx context.Context, id string) (int64, error) {
+	b, err := d.nerdctl(ctx, "ps", "--all", "--no-trunc", "--size", "--format", "{{json .}}")
+	if err != nil {
+		return 0, err
+	}
+	var list []*nerdctlContainer
+	if err := decodeLines(b, func() interface{} {
+		list = append(list, &nerdctlContainer{})
+		return list[len(list)-1]
+	}); err != nil {
+		return 0, err
+	}
+	for _, c := range list {
+		if c.ID != id {
+			continue
+		}
+		// like "1.2 MB (virtual 100 MB)"
+		size := strings.TrimSpace(strings.SplitN(c.Size, "(", 2)[0])
+		if len(size) == 0 {
+			return 0, nil
+		}
+		return units.FromHumanSize(size)
+	}
+	return 0, ErrContainerNotFound
+}
+
+// GetContainerStats returns the current resource usage of the container.
+func (d *containerdClient) GetContainerStats(ctx context.Context, id string) (*ContainerStats, error) {
+	b, err := d.nerdctl(ctx, "stats", "--no-stream", "--no-trunc", "--format", "{{json .}}", id)
+	if err != nil {
+		return nil, err
+	}
+	var list []*nerdctlStats
+	if err := decodeLines(b, func() interface{} {
+		list = append(list, &nerdctlStats{})
+		return list[len(list)-1]
+	}); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrContainerNotFound
+	}
+	return parseNerdctlStats(list[0])
+}
+
+// parseNerdctlStats parses the human readable stats, like "1.5%", "10MiB / 1GiB" and "1kB / 2kB".
+func parseNerdctlStats(stats *nerdctlStats) (*ContainerStats, error) {
+	var (
+		result ContainerStats
+		err    error
+	)
+	if cpu := strings.TrimSuffix(strings.TrimSpace(stats.CPUPerc), "%"); len(cpu) > 0 {
+		if result.CPUPercent, err = strconv.ParseFloat(cpu, 64); err != nil {
+			return nil, fmt.Errorf("invalid cpu usage: %v", err)
+		}
+	}
+	if mem := strings.TrimSpace(strings.SplitN(stats.MemUsage, "/", 2)[0]); len(mem) > 0 {
+		if result.MemoryUsage, err = units.RAMInBytes(mem); err != nil {
+			return nil, fmt.Errorf("invalid memory usage: %v", err)
+		}
+	}
+	if netIO := strings.SplitN(stats.NetIO, "/", 2); len(netIO) == 2 {
+		if result.NetworkRx, err = units.FromHumanSize(strings.TrimSpace(netIO[0])); err != nil {
+			return nil, fmt.Errorf("invalid network usage: %v", err)
+		}
+		if result.NetworkTx, err = units.FromHumanSize(strings.TrimSpace(netIO[1])); err != nil {
+			return nil, fmt.Errorf("invalid network usage: %v", err)
+		}
+	}
+	return &result, nil
+}
+
+// GetFortaServiceContainers returns all of the non-agent forta containers.
+func (d *containerdClient) GetFortaServiceContainers(ctx context.Context) (fortaContainers DockerContainerList, err error) {
+	return getFortaServiceContainers(ctx, d)
+}
+
+// GetContainerByName gets a container by using a name lookup over all containers.
+func (d *containerdClient) GetContainerByName(ctx context.Context, name string) (*types.Container, error) {
+	return getContainerByName(ctx, d, name)
+}
+
+// GetContainerByID gets a container by using an ID lookup over all containers.
+func (d *containerdClient) GetContainerByID(ctx context.Context, id string) (*types.Container, error) {
+	return getContainerByID(ctx, d, id)
+}
+
+// InspectContainer returns the full state of the container.
+func (d *containerdClient) InspectContainer(ctx context.Context, id string) (*types.ContainerJSON, error) {
+	b, err := d.nerdctl(ctx, "container", "inspect", "--mode", "dockercompat", id)
+	if err != nil {
+		return nil, err
+	}
+	var inspections []*types.ContainerJSON
+	if err := json.Unmarshal(b, &inspections); err != nil {
+		return nil, fmt.Errorf("failed to decode container inspection: %v", err)
+	}
+	if len(inspections) == 0 {
+		return nil, fmt.Errorf("%w with id '%s'", ErrContainerNotFound, id)
+	}
+	return inspections[0], nil
+}
+
+// StartContainer kicks off a container as a daemon and returns a summary of the container
+func (d *containerdClient) StartContainer(ctx context.Context, config DockerContainerConfig) (*DockerContainer, error) {
+	log.WithFields(log.Fields{
+		"image": config.Image,
+		"name":  config.Name,
+	}).Info("StartContainer()")
+
+	if config.DiskSize > 0 {
+		log.WithField("name", config.Name).Warn("containerd cannot limit the writable layer size - ignoring")
+	}
+
+	// If we already have the container but it is not running, then just start it.
+	containerID, err := d.findOrCreateContainer(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.nerdctl(ctx, "start", containerID); err != nil {
+		return nil, err
+	}
+	inspection, err := d.InspectContainer(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"id":   containerID,
+		"name": config.Name,
+	}).Info("container is starting")
+	return &DockerContainer{Name: config.Name, ID: containerID, Config: config, ImageHash: inspection.Image}, nil
+}
+
+func (d *containerdClient) findOrCreateContainer(ctx context.Context, config DockerContainerConfig) (string, error) {
+	foundContainer, err := d.GetContainerByName(ctx, config.Name)
+	if err == nil {
+		return foundContainer.ID, nil
+	}
+	if !errors.Is(err, ErrContainerNotFound) {
+		return "", err
+	}
+
+	// nerdctl reads the seccomp profile from a file only when creating the container
+	securityOpts, cleanup, err := securityOptFiles(config.SecurityOpts)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+	config.SecurityOpts = securityOpts
+
+	b, err := d.nerdctl(ctx, createArgs(config, d.labels)...)
+	if err != nil {
+		return "", err
+	}
+	containerID := strings.TrimSpace(string(b))
+
+	for fn, b := range config.Files {
+		if err := d.copyFile(ctx, fn, b, containerID); err != nil {
+			return "", err
+		}
+	}
+	return containerID, nil
+}
+
+// createArgs converts the container config to the create command arguments. The networks are
+// joined at creation and are labeled so that the later attach requests can be checked.
+func createArgs(config DockerContainerConfig, clientLabels []dockerLabel) []string {
+	args := []string{"create", "--name", config.Name}
+
+	labels := labelsToMap(clientLabels)
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+	var networks []string
+	if len(config.NetworkID) > 0 {
+		networks = append(networks, config.NetworkID)
+	}
+	networks = append(networks, config.LinkNetworkIDs...)
+	if len(networks) > 0 {
+		labels[DockerLabelFortaNetworks] = strings.Join(networks, ";")
+	}
+	for _, network := range networks {
+		args = append(args, "--network", network)
+	}
+	for _, kv := range sortedKeyValues(labels) {
+		args = append(args, "--label", kv)
+	}
+	for _, kv := range sortedKeyValues(config.Env) {
+		args = append(args, "--env", kv)
+	}
+
+	for _, hp := range sortedKeys(config.Ports) {
+		hostIP := "0.0.0.0"
+		hostPort := hp
+		parts := strings.Split(hp, ":")
+		if len(parts) == 2 {
+			hostIP = parts[0]
+			hostPort = parts[1]
+		}
+		args = append(args, "--publish", fmt.Sprintf("%s:%s:%s", hostIP, hostPort, withTcp(config.Ports[hp])))
+	}
+	if config.PublishAllPorts {
+		args = append(args, "--publish-all")
+	}
+	for _, hostVol := range sortedKeys(config.Volumes) {
+		args = append(args, "--volume", fmt.Sprintf("%s:%s", hostVol, config.Volumes[hostVol]))
+	}
+
+	maxLogSize := config.MaxLogSize
+	if maxLogSize == "" {
+		maxLogSize = "10m"
+	}
+	maxLogFiles := config.MaxLogFiles
+	if maxLogFiles == 0 {
+		maxLogFiles = 10
+	}
+	args = append(args,
+		"--log-driver", "json-file",
+		"--log-opt", fmt.Sprintf("max-size=%s", maxLogSize),
+		"--log-opt", fmt.Sprintf("max-file=%d", maxLogFiles),
+	)
+
+	if config.CPUQuota > 0 {
+		args = append(args, "--cpu-quota", fmt.Sprintf("%d", config.CPUQuota))
+	}
+	if config.CPUShares > 0 {
+		args = append(args, "--cpu-shares", fmt.Sprintf("%d", config.CPUShares))
+	}
+	if config.Memory > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%d", config.Memory))
+	}
+	if config.DialHost {
+		args = append(args, "--add-host", "host.docker.internal:host-gateway")
+	}
+	if config.GPUs > 0 {
+		args = append(args, "--gpus", fmt.Sprintf("%d", config.GPUs))
+	}
+	if config.ReadOnlyRootfs {
+		args = append(args, "--read-only")
+	}
+	for _, path := range sortedKeys(config.Tmpfs) {
+		tmpfs := path
+		if opts := config.Tmpfs[path]; len(opts) > 0 {
+			tmpfs = fmt.Sprintf("%s:%s", path, opts)
+		}
+		args = append(args, "--tmpfs", tmpfs)
+	}
+	for _, capability := range config.CapDrop {
+		args = append(args, "--cap-drop", capability)
+	}
+	for _, capability := range config.CapAdd {
+		args = append(args, "--cap-add", capability)
+	}
+	for _, opt := range config.SecurityOpts {
+		args = append(args, "--security-opt", opt)
+	}
+
+	args = append(args, config.Image)
+	return append(args, config.Cmd...)
+}
+
+// securityOptFiles writes the inline seccomp profiles to temporary files and replaces them
+// with the file paths. The cleanup func removes the files.
+func securityOptFiles(securityOpts []string) ([]string, func(), error) {
+	var (
+		opts  []string
+		files []string
+	)
+	cleanup := func() {
+		for _, file := range files {
+			_ = os.Remove(file)
+		}
+	}
+	for _, opt := range securityOpts {
+		profile := strings.TrimPrefix(opt, "seccomp=")
+		if profile == opt || !strings.HasPrefix(strings.TrimSpace(profile), "{") {
+			opts = append(opts, opt)
+			continue
+		}
+		f, err := ioutil.TempFile("", "seccomp-*.json")
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		files = append(files, f.Name())
+		_, err = f.WriteString(profile)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		opts = append(opts, fmt.Sprintf("seccomp=%s", f.Name()))
+	}
+	return opts, cleanup, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeyValues(m map[string]string) []string {
+	var kvs []string
+	for _, k := range sortedKeys(m) {
+		kvs = append(kvs, fmt.Sprintf("%s=%s", k, m[k]))
+	}
+	return kvs
+}
+
+// copyFile copies content bytes into container at /filename
+func (d *containerdClient) copyFile(ctx context.Context, filename string, content []byte, containerID string) error {
+	f, err := ioutil.TempFile("", "forta-file-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0400); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	_, err = d.nerdctl(ctx, "cp", f.Name(), fmt.Sprintf("%s:/%s", containerID, filename))
+	return err
+}
+
+// StopContainer kills a container by ID
+func (d *containerdClient) StopContainer(ctx context.Context, id string) error {
+	return d.stopContainer(ctx, id, "SIGKILL")
+}
+
+// InterruptContainer stops a container by sending an interrupt signal.
+func (d *containerdClient) InterruptContainer(ctx context.Context, id string) error {
+	return d.stopContainer(ctx, id, "SIGINT")
+}
+
+// TerminateContainer stops a container by sending an termination signal.
+func (d *containerdClient) TerminateContainer(ctx context.Context, id string) error {
+	return d.stopContainer(ctx, id, "SIGTERM")
+}
+
+func (d *containerdClient) stopContainer(ctx context.Context, containerID, signal string) error {
+	log.WithFields(log.Fields{
+		"id":     containerID,
+		"signal": signal,
+	}).Infof("stopping container")
+	_, err := d.nerdctl(ctx, "kill", "--signal", signal, containerID)
+	if err == nil {
+		return nil
+	}
+	if isNoSuchContainerErr(err) || isNotRunningErr(err) {
+		return nil
+	}
+	return err
+}
+
+// RemoveContainer kills and a container by ID.
+func (d *containerdClient) RemoveContainer(ctx context.Context, containerID string) error {
+	_, err := d.nerdctl(ctx, "rm", "--force", "--volumes", containerID)
+	return err
+}
+
+// WaitContainerExit waits for container exit by checking periodically.
+func (d *containerdClient) WaitContainerExit(ctx context.Context, id string) error {
+	return waitContainerExit(ctx, d, id)
+}
+
+// WaitContainerStart waits for container start by checking periodically.
+func (d *containerdClient) WaitContainerStart(ctx context.Context, id string) error {
+	return waitContainerStart(ctx, d, id)
+}
+
+// Prune removes the stopped containers and the unused networks of this client.
+func (d *containerdClient) Prune(ctx context.Context) error {
+	containers, err := d.GetContainers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, container := range containers {
+		if container.State == "running" || container.State == "restarting" {
+			continue
+		}
+		if _, err := d.nerdctl(ctx, "rm", container.ID); err != nil {
+			return err
+		}
+		log.Infof("pruned container %s", container.ID)
+	}
+
+	networks, err := d.listNetworks(ctx)
+	if err != nil {
+		return err
+	}
+	for _, network := range networks {
+		if !d.hasLabels(parseLabels(network.Labels)) {
+			continue
+		}
+		// the networks in use cannot be removed
+		if _, err := d.nerdctl(ctx, "network", "rm", network.Name); err != nil {
+			log.WithError(err).WithField("network", network.Name).Debug("skipped pruning network")
+			continue
+		}
+		log.Infof("pruned network %s", network.Name)
+	}
+	return nil
+}
+
+// WaitContainerPrune waits for container prune by checking periodically.
+func (d *containerdClient) WaitContainerPrune(ctx context.Context, id string) error {
+	return waitContainerPrune(ctx, d, id)
+}
+
+// Nuke makes sure that all running Forta containers are stopped and pruned, quickly enough.
+func (d *containerdClient) Nuke(ctx context.Context) error {
+	return nuke(ctx, d)
+}
+
+// HasLocalImage checks if we have an image locally.
+func (d *containerdClient) HasLocalImage(ctx context.Context, ref string) bool {
+	_, err := d.InspectImage(ctx, ref)
+	return err == nil
+}
+
+// InspectImage returns the details of the local image.
+func (d *containerdClient) InspectImage(ctx context.Context, ref string) (*types.ImageInspect, error) {
+	b, err := d.nerdctl(ctx, "image", "inspect", "--mode", "dockercompat", ref)
+	if err != nil {
+		return nil, err
+	}
+	var inspections []*types.ImageInspect
+	if err := json.Unmarshal(b, &inspections); err != nil {
+		return nil, fmt.Errorf("failed to decode image inspection: %v", err)
+	}
+	if len(inspections) == 0 {
+		return nil, fmt.Errorf("image not found: %s", ref)
+	}
+	return inspections[0], nil
+}
+
+// ReadImageFile reads a file from the image. The file is copied from a container which is created but
+// never started.
+func (d *containerdClient) ReadImageFile(ctx context.Context, ref, filePath string) ([]byte, error) {
+	b, err := d.nerdctl(ctx, "create", "--network", "none", "--entrypoint", "true", ref)
+	if err != nil {
+		return nil, err
+	}
+	containerID := strings.TrimSpace(string(b))
+	defer func() {
+		_ = d.RemoveContainer(context.Background(), containerID)
+	}()
+	dir, err := ioutil.TempDir("", "forta-image-file-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	localPath := path.Join(dir, path.Base(filePath))
+	if _, err := d.nerdctl(ctx, "cp", fmt.Sprintf("%s:%s", containerID, filePath), localPath); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(localPath)
+}
+
+// GetImages returns the local images. The image references are merged by the image IDs.
+func (d *containerdClient) GetImages(ctx context.Context) ([]types.ImageSummary, error) {
+	b, err := d.nerdctl(ctx, "images", "--no-trunc", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+	var list []*nerdctlImage
+	if err := decodeLines(b, func() interface{} {
+		list = append(list, &nerdctlImage{})
+		return list[len(list)-1]
+	}); err != nil {
+		return nil, err
+	}
+	var images []types.ImageSummary
+	indexes := make(map[string]int)
+	for _, img := range list {
+		i, ok := indexes[img.ID]
+		if !ok {
+			i = len(images)
+			indexes[img.ID] = i
+			images = append(images, types.ImageSummary{ID: img.ID})
+		}
+		if len(img.Repository) == 0 || img.Repository == "<none>" {
+			continue
+		}
+		if len(img.Tag) > 0 && img.Tag != "<none>" {
+			images[i].RepoTags = append(images[i].RepoTags, fmt.Sprintf("%s:%s", img.Repository, img.Tag))
+		}
+		if len(img.Digest) > 0 {
+			images[i].RepoDigests = append(images[i].RepoDigests, fmt.Sprintf("%s@%s", img.Repository, img.Digest))
+		}
+	}
+	return images, nil
+}
+
+// RemoveImage removes the local image if no containers use it.
+func (d *containerdClient) RemoveImage(ctx context.Context, ref string) error {
+	_, err := d.nerdctl(ctx, "image", "rm", ref)
+	return err
+}
+
+// EnsureLocalImage ensures that we have the image locally.
+func (d *containerdClient) EnsureLocalImage(ctx context.Context, name, ref string) error {
+	return ensureLocalImage(ctx, d, name, ref)
+}
+
+// FollowContainerLogs streams the timestamped stdout and stderr lines of the container which were
+// written after the given time, until the container stops or the context is done.
+func (d *containerdClient) FollowContainerLogs(ctx context.Context, containerID string, since time.Time) (io.ReadCloser, error) {
+	args := []string{"--address", d.address, "--namespace", d.namespace, "logs", "--follow", "--timestamps"}
+	if !since.IsZero() {
+		args = append(args, "--since", since.Format(time.RFC3339Nano))
+	}
+	cmd := exec.CommandContext(ctx, nerdctlBinary, append(args, containerID)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("nerdctl logs failed: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	// copy line by line so that the stdout and the stderr lines are not mixed
+	for _, r := range []io.Reader{stdout, stderr} {
+		wg.Add(1)
+		go func(r io.Reader) {
+			defer wg.Done()
+			scanner := bufio.NewScanner(r)
+			for scanner.Scan() {
+				mu.Lock()
+				_, _ = fmt.Fprintln(pw, scanner.Text())
+				mu.Unlock()
+			}
+		}(r)
+	}
+	go func() {
+		wg.Wait()
+		pw.CloseWithError(cmd.Wait())
+	}()
+	return pr, nil
+}
+
+// GetContainerLogs gets the container logs. The stdout and stderr lines are merged in the
+// order of their timestamps.
+func (d *containerdClient) GetContainerLogs(ctx context.Context, containerID, tail string, truncate int) (string, error) {
+	args := []string{"--address", d.address, "--namespace", d.namespace, "logs", "--timestamps"}
+	if len(tail) > 0 {
+		args = append(args, "--tail", tail)
+	}
+	stdout, stderr, err := d.run(ctx, append(args, containerID)...)
+	if err != nil {
+		return "", fmt.Errorf("nerdctl logs failed: %w", err)
+	}
+	var lines []string
+	for _, b := range [][]byte{stdout, stderr} {
+		for _, line := range strings.Split(string(b), "\n") {
+			if len(line) > 0 {
+				lines = append(lines, line)
+			}
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return strings.SplitN(lines[i], " ", 2)[0] < strings.SplitN(lines[j], " ", 2)[0]
+	})
+	logs := strings.Join(lines, "\n")
+	if truncate >= 0 && len(logs) > truncate {
+		logs = logs[:truncate]
+	}
+	return logs, nil
+}
@@ -0,0 +1,196 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testNerdctl struct {
+	stdout map[string]string
+	stderr map[string]string
+	calls  [][]string
+}
+
+func (n *testNerdctl) run(ctx context.Context, args ...string) ([]byte, []byte, error) {
+	args = args[4:] // skip the address and the namespace
+	n.calls = append(n.calls, args)
+	cmd := args[0]
+	switch cmd {
+	case "network", "container", "image":
+		cmd = strings.Join(args[:2], " ")
+	}
+	return []byte(n.stdout[cmd]), []byte(n.stderr[cmd]), nil
+}
+
+func (n *testNerdctl) call(cmd string) []string {
+	for _, call := range n.calls {
+		if call[0] == cmd {
+			return call
+		}
+	}
+	return nil
+}
+
+func testContainerdClient(n *testNerdctl) *containerdClient {
+	cli := NewContainerdClient("supervisor", config.ContainerRuntimeConfig{
+		Runtime:   config.ContainerRuntimeContainerd,
+		Namespace: "forta",
+	}, config.DefaultContainerdSocket)
+	cli.run = n.run
+	return cli
+}
+
+const testContainerList = `{"ID":"id1","Names":"forta-scanner","Image":"forta-node","Status":"Up 2 minutes","Labels":"network.forta=true,network.forta.supervisor=supervisor,network.forta.networks=forta-scanner;forta-nats"}
+{"ID":"id2","Names":"forta-agent-1","Image":"agent","Status":"Exited (137) 1 minute ago","Labels":"network.forta=true,network.forta.supervisor=supervisor"}
+{"ID":"id3","Names":"other","Image":"other","Status":"Up 1 hour","Labels":"network.forta=true"}
+`
+
+func TestContainerdGetContainers(t *testing.T) {
+	r := require.New(t)
+
+	cli := testContainerdClient(&testNerdctl{stdout: map[string]string{"ps": testContainerList}})
+	containers, err := cli.GetContainers(context.Background())
+	r.NoError(err)
+	r.Len(containers, 2)
+
+	r.Equal("/forta-scanner", containers[0].Names[0])
+	r.Equal("running", containers[0].State)
+	r.Equal("exited", containers[1].State)
+
+	serviceContainers, err := cli.GetFortaServiceContainers(context.Background())
+	r.NoError(err)
+	r.Len(serviceContainers, 1)
+
+	_, err = cli.GetContainerByName(context.Background(), "other")
+	r.True(errors.Is(err, ErrContainerNotFound))
+}
+
+func TestContainerdAttachNetwork(t *testing.T) {
+	r := require.New(t)
+
+	cli := testContainerdClient(&testNerdctl{stdout: map[string]string{"ps": testContainerList}})
+	r.NoError(cli.AttachNetwork(context.Background(), "id1", "forta-nats"))
+	r.True(errors.Is(cli.AttachNetwork(context.Background(), "id1", "forta-agent-1"), errNetworkAttachNotSupported))
+}
+
+func TestContainerdStartContainer(t *testing.T) {
+	r := require.New(t)
+
+	n := &testNerdctl{stdout: map[string]string{
+		"create":            "id4\n",
+		"container inspect": `[{"Id":"id4","Image":"sha256:abc"}]`,
+	}}
+	cli := testContainerdClient(n)
+	container, err := cli.StartContainer(context.Background(), DockerContainerConfig{
+		Name:           "forta-json-rpc",
+		Image:          "forta-node",
+		Cmd:            []string{"forta", "json-rpc"},
+		Env:            map[string]string{"B": "2", "A": "1"},
+		NetworkID:      "forta-scanner",
+		LinkNetworkIDs: []string{"forta-nats"},
+		Ports:          map[string]string{"127.0.0.1:8545": "8545"},
+		Volumes:        map[string]string{"/host": "/container"},
+		Files:          map[string][]byte{"passphrase": []byte("123")},
+		Memory:         1024,
+		DialHost:       true,
+		ReadOnlyRootfs: true,
+		Tmpfs:          map[string]string{"/tmp": "size=64m"},
+		CapDrop:        []string{"ALL"},
+		SecurityOpts:   []string{"no-new-privileges", `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}`},
+	})
+	r.NoError(err)
+	r.Equal("id4", container.ID)
+	r.Equal("sha256:abc", container.ImageHash)
+
+	r.Equal([]string{
+		"create", "--name", "forta-json-rpc",
+		"--network", "forta-scanner", "--network", "forta-nats",
+		"--label", "network.forta=true",
+		"--label", "network.forta.networks=forta-scanner;forta-nats",
+		"--label", "network.forta.supervisor=supervisor",
+		"--env", "A=1", "--env", "B=2",
+		"--publish", "127.0.0.1:8545:8545/tcp",
+		"--volume", "/host:/container",
+		"--log-driver", "json-file", "--log-opt", "max-size=10m", "--log-opt", "max-file=10",
+		"--memory", "1024",
+		"--add-host", "host.docker.internal:host-gateway",
+		"--read-only", "--tmpfs", "/tmp:size=64m", "--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+	}, n.call("create")[:38])
+	// the inline seccomp profile is passed as a file
+	seccompOpt := n.call("create")[39]
+	r.True(strings.HasPrefix(seccompOpt, "seccomp="))
+	r.NoFileExists(strings.TrimPrefix(seccompOpt, "seccomp="))
+	r.Equal([]string{"forta-node", "forta", "json-rpc"}, n.call("create")[40:])
+	r.Equal("id4:/passphrase", n.call("cp")[2])
+	r.Equal([]string{"start", "id4"}, n.call("start"))
+}
+
+func TestContainerdGetContainerLogs(t *testing.T) {
+	r := require.New(t)
+
+	cli := testContainerdClient(&testNerdctl{
+		stdout: map[string]string{"logs": "2022-05-01T00:00:00Z out1\n2022-05-01T00:00:02Z out2\n"},
+		stderr: map[string]string{"logs": "2022-05-01T00:00:01Z err1\n"},
+	})
+	logs, err := cli.GetContainerLogs(context.Background(), "id1", "10", 1000)
+	r.NoError(err)
+	r.Equal("2022-05-01T00:00:00Z out1\n2022-05-01T00:00:01Z err1\n2022-05-01T00:00:02Z out2", logs)
+
+	logs, err = cli.GetContainerLogs(context.Background(), "id1", "10", 10)
+	r.NoError(err)
+	r.Len(logs, 10)
+}
+
+func TestContainerdGetContainerDiskUsage(t *testing.T) {
+	r := require.New(t)
+
+	cli := testContainerdClient(&testNerdctl{stdout: map[string]string{
+		"ps": `{"ID":"id1","Names":"forta-agent-1","Size":"1.5MB (virtual 100MB)"}` + "\n",
+	}})
+	usage, err := cli.GetContainerDiskUsage(context.Background(), "id1")
+	r.NoError(err)
+	r.Equal(int64(1500000), usage)
+
+	_, err = cli.GetContainerDiskUsage(context.Background(), "id2")
+	r.True(errors.Is(err, ErrContainerNotFound))
+}
+
+func TestContainerdGetImages(t *testing.T) {
+	r := require.New(t)
+
+	cli := testContainerdClient(&testNerdctl{stdout: map[string]string{
+		"images": `{"ID":"sha256:1","Repository":"disco.forta.network/bafy","Tag":"<none>","Digest":"sha256:a"}` + "\n" +
+			`{"ID":"sha256:1","Repository":"foo","Tag":"latest","Digest":"sha256:a"}` + "\n" +
+			`{"ID":"sha256:2","Repository":"<none>","Tag":"<none>","Digest":""}` + "\n",
+	}})
+	images, err := cli.GetImages(context.Background())
+	r.NoError(err)
+	r.Len(images, 2)
+	r.Equal("sha256:1", images[0].ID)
+	r.Equal([]string{"foo:latest"}, images[0].RepoTags)
+	r.Equal([]string{"disco.forta.network/bafy@sha256:a", "foo@sha256:a"}, images[0].RepoDigests)
+	r.Equal("sha256:2", images[1].ID)
+	r.Empty(images[1].RepoDigests)
+}
+
+func TestContainerdGetContainerStats(t *testing.T) {
+	r := require.New(t)
+
+	cli := testContainerdClient(&testNerdctl{stdout: map[string]string{
+		"stats": `{"ID":"id1","CPUPerc":"12.50%","MemUsage":"10MiB / 1GiB","NetIO":"1.5kB / 2MB"}` + "\n",
+	}})
+	stats, err := cli.GetContainerStats(context.Background(), "id1")
+	r.NoError(err)
+	r.Equal(&ContainerStats{
+		CPUPercent:  12.5,
+		MemoryUsage: 10 * 1024 * 1024,
+		NetworkRx:   1500,
+		NetworkTx:   2000000,
+	}, stats)
+}
@@ -0,0 +1,220 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// The helpers below only use the DockerClient interface so that every container runtime
+// backend shares the same lookup, wait and cleanup behavior.
+
+func getFortaServiceContainers(ctx context.Context, d DockerClient) (fortaContainers DockerContainerList, err error) {
+	containers, err := d.GetContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, container := range containers {
+		if !strings.Contains(container.Names[0][1:], "forta-agent") {
+			fortaContainers = append(fortaContainers, container)
+		}
+	}
+	return
+}
+
+func getContainerByName(ctx context.Context, d DockerClient, name string) (*types.Container, error) {
+	containers, err := d.GetContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		if c.Names[0][1:] == name {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w with name '%s'", ErrContainerNotFound, name)
+}
+
+func getContainerByID(ctx context.Context, d DockerClient, id string) (*types.Container, error) {
+	containers, err := d.GetContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w with id '%s'", ErrContainerNotFound, id)
+}
+
+func nuke(ctx context.Context, d DockerClient) error {
+	var err error
+	for i := 0; i < 4; i++ {
+		err = nukeOnce(ctx, d)
+		if err == nil {
+			return nil
+		}
+		log.WithError(err).Error("failed to nuke - retrying")
+	}
+	return fmt.Errorf("all nuke retries failed: %v", err)
+}
+
+func nukeOnce(ctx context.Context, d DockerClient) error {
+	// step 1: put the supervisor to the top of the list so it doesn't do funny restarts
+	containers, err := d.GetContainers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get forta containers list: %v", err)
+	}
+	supervisorContainer, err := d.GetContainerByName(ctx, config.DockerSupervisorContainerName)
+	if err == nil {
+		containers = append([]types.Container{*supervisorContainer}, containers...)
+	}
+	if err != nil && !errors.Is(err, ErrContainerNotFound) {
+		return fmt.Errorf("unexpected error while getting supervisor container: %v", err)
+	}
+
+	// step 2: stop all and wait until each exit
+	for _, container := range containers {
+		if err := d.StopContainer(ctx, container.ID); err != nil {
+			return fmt.Errorf("failed to stop: %v", err)
+		}
+		if err := d.WaitContainerExit(ctx, container.ID); err != nil {
+			return err
+		}
+	}
+
+	// step 3: prune everything
+	if err := d.Prune(ctx); err != nil {
+		return fmt.Errorf("failed to prune: %v", err)
+	}
+
+	// step 4: ensure that the containers are really pruned
+	for _, container := range containers {
+		if err := d.WaitContainerPrune(ctx, container.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func waitContainerExit(ctx context.Context, d DockerClient, id string) error {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	logger := log.WithFields(log.Fields{
+		"id": id,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	for {
+		logger.Info("waiting for container exit")
+		c, err := d.GetContainerByID(ctx, id)
+		if err != nil && errors.Is(err, ErrContainerNotFound) {
+			logger.Info("no need to wait for container exit - not found")
+			return nil
+		}
+		if err != nil {
+			logger.WithError(err).Error("failed while waiting for container exit")
+			return err
+		}
+		if c.State == "exited" || c.State == "created" {
+			return nil
+		}
+		logger.WithField("containerState", c.State).Info("still waiting for exit")
+		<-ticker.C
+	}
+}
+
+func waitContainerStart(ctx context.Context, d DockerClient, id string) error {
+	ticker := time.NewTicker(time.Second)
+	start := time.Now()
+	logger := log.WithFields(log.Fields{
+		"id": id,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	for t := range ticker.C {
+		logger.Info("waiting for container start")
+		c, err := d.GetContainerByID(ctx, id)
+		if err == nil && c != nil && c.State == "running" {
+			logger.Info("container started")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// if the conditions are not met within 30 seconds, it's a failure
+		if t.After(start.Add(time.Second * 30)) {
+			return errors.New("container did not start")
+		}
+	}
+	return nil
+}
+
+func waitContainerPrune(ctx context.Context, d DockerClient, id string) error {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	logger := log.WithFields(log.Fields{
+		"id": id,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	for {
+		logger.Infof("waiting for container prune")
+		c, err := d.GetContainerByID(ctx, id)
+		if err != nil && errors.Is(err, ErrContainerNotFound) {
+			return nil
+		}
+		if err != nil {
+			logger.WithError(err).Error("error while waiting for prune")
+			return err
+		}
+		logger.WithField("containerState", c.State).Info("container state while waiting for prune")
+		if !(c.State == "exited" || c.State == "dead") {
+			err = fmt.Errorf("cannot prune container with status '%s' - container needs to stop first", c.State)
+			logger.WithError(err).Error("error while waiting for prune")
+			return err
+		}
+		<-ticker.C
+	}
+}
+
+func ensureLocalImage(ctx context.Context, d DockerClient, name, ref string) error {
+	log.WithFields(log.Fields{
+		"image": ref,
+		"name":  name,
+	}).Info("ensuring local image")
+	if d.HasLocalImage(ctx, ref) {
+		log.Infof("found local image for '%s': %s", name, ref)
+		return nil
+	}
+
+	ticker := time.NewTicker(time.Minute)
+
+	for {
+		err := d.PullImage(ctx, ref)
+		if err == nil {
+			break
+		}
+		log.WithFields(log.Fields{
+			"name":  name,
+			"ref":   ref,
+			"error": err,
+		}).Error("failed to pull image - retrying")
+		<-ticker.C
+	}
+
+	log.Infof("pulled image for '%s': %s", name, ref)
+	return nil
+}
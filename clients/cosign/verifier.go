@@ -0,0 +1,256 @@
+package cosign
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+// Cosign signature artifact constants
+const (
+	SimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	SignatureAnnotation    = "dev.cosignproject.cosign/signature"
+
+	maxBlobSize = 1 << 20
+)
+
+var errNotFound = errors.New("not found")
+
+// Verification errors
+var (
+	ErrNoSignature      = errors.New("no cosign signature found")
+	ErrInvalidSignature = errors.New("invalid cosign signature")
+)
+
+// Verifier verifies the cosign signatures of the images.
+type Verifier interface {
+	Verify(ctx context.Context, imageRef, publicKeyPEM string) error
+}
+
+// Manifest is the OCI manifest of the signature artifact.
+type Manifest struct {
+	Layers []Descriptor `json:"layers"`
+}
+
+// Descriptor is an OCI content descriptor.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// SimpleSigning is the signed payload.
+type SimpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+type verifier struct {
+	client *http.Client
+}
+
+// NewVerifier creates a new verifier which reads the signatures from the image registries.
+func NewVerifier() *verifier {
+	return &verifier{client: &http.Client{Timeout: time.Minute}}
+}
+
+func parsePublicKey(publicKeyPEM string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the public key: %v", err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an ECDSA key")
+	}
+	return ecdsaKey, nil
+}
+
+// Verify checks if any of the cosign signatures of the image is signed by the key and is
+// for the digest of the image. The image reference must have a digest.
+func (v *verifier) Verify(ctx context.Context, imageRef, publicKeyPEM string) error {
+	key, err := parsePublicKey(publicKeyPEM)
+	if err != nil {
+		return err
+	}
+	repoRef, digest := utils.SplitImageRef(imageRef)
+	if len(digest) == 0 {
+		return fmt.Errorf("image reference has no digest: %s", imageRef)
+	}
+	parts := strings.SplitN(repoRef, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("image reference has no registry: %s", imageRef)
+	}
+	repo := &repository{client: v.client, host: parts[0], name: parts[1]}
+
+	var manifest Manifest
+	b, err := repo.get(ctx, fmt.Sprintf("manifests/sha256-%s.sig", digest), "application/vnd.oci.image.manifest.v1+json")
+	if errors.Is(err, errNotFound) {
+		return fmt.Errorf("%w: %s", ErrNoSignature, imageRef)
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return fmt.Errorf("failed to decode the signature manifest: %v", err)
+	}
+
+	for _, layer := range manifest.Layers {
+		signature, ok := layer.Annotations[SignatureAnnotation]
+		if layer.MediaType != SimpleSigningMediaType || !ok {
+			continue
+		}
+		payload, err := repo.get(ctx, fmt.Sprintf("blobs/%s", layer.Digest), "")
+		if err != nil {
+			return err
+		}
+		if err := verifyPayload(key, payload, layer.Digest, signature, digest); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidSignature, imageRef)
+}
+
+func verifyPayload(key *ecdsa.PublicKey, payload []byte, payloadDigest, signature, imageDigest string) error {
+	hash := sha256.Sum256(payload)
+	if payloadDigest != "sha256:"+hex.EncodeToString(hash[:]) {
+		return errors.New("payload digest mismatch")
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	if !ecdsa.VerifyASN1(key, hash[:], sig) {
+		return errors.New("bad signature")
+	}
+	var simpleSigning SimpleSigning
+	if err := json.Unmarshal(payload, &simpleSigning); err != nil {
+		return err
+	}
+	if simpleSigning.Critical.Image.DockerManifestDigest != "sha256:"+imageDigest {
+		return errors.New("signature is for another image")
+	}
+	return nil
+}
+
+// repository reads from an image repository with the registry API and gets an anonymous
+// token if the registry asks for it.
+type repository struct {
+	client *http.Client
+	host   string
+	name   string
+	token  string
+}
+
+func (repo *repository) get(ctx context.Context, path, accept string) ([]byte, error) {
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s/v2/%s/%s", repo.host, repo.name, path), nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", accept)
+		}
+		if len(repo.token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+repo.token)
+		}
+		resp, err := repo.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxBlobSize))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && len(repo.token) == 0:
+			if err := repo.authenticate(ctx, resp.Header.Get("WWW-Authenticate")); err != nil {
+				return nil, err
+			}
+			continue
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", errNotFound, path)
+		case resp.StatusCode != http.StatusOK:
+			return nil, fmt.Errorf("registry responded with status %d", resp.StatusCode)
+		}
+		return b, nil
+	}
+	return nil, errors.New("registry authentication failed")
+}
+
+// authenticate gets an anonymous token from the realm in the bearer challenge.
+func (repo *repository) authenticate(ctx context.Context, challenge string) error {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return fmt.Errorf("unsupported registry auth challenge: %s", challenge)
+	}
+	params := make(map[string]string)
+	for _, param := range strings.Split(strings.TrimPrefix(challenge, "Bearer "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) == 2 {
+			params[kv[0]] = strings.Trim(kv[1], `"`)
+		}
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || len(realm.Host) == 0 {
+		return fmt.Errorf("invalid registry auth realm: %s", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if len(params[key]) > 0 {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := repo.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry token request failed with status %d", resp.StatusCode)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return err
+	}
+	repo.token = token.Token
+	if len(repo.token) == 0 {
+		repo.token = token.AccessToken
+	}
+	if len(repo.token) == 0 {
+		return errors.New("registry returned no token")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cosign
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDigest = "cdd4ddccf5e9c740eb4144bcc68e3ea3a056789ec7453e94a6416dcfc80937a4"
+
+func testKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	b, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}))
+}
+
+// testRegistry serves a signature for the test digest and asks for a token first.
+func testRegistry(t *testing.T, key *ecdsa.PrivateKey, signedDigest string) *httptest.Server {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"repo"},"image":{"docker-manifest-digest":"sha256:%s"},"type":"cosign container image signature"},"optional":null}`, signedDigest))
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+	payloadDigest := "sha256:" + hex.EncodeToString(hash[:])
+
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "test-token"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:repo:pull"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case fmt.Sprintf("/v2/repo/manifests/sha256-%s.sig", testDigest):
+			_ = json.NewEncoder(w).Encode(&Manifest{Layers: []Descriptor{{
+				MediaType:   SimpleSigningMediaType,
+				Digest:      payloadDigest,
+				Size:        int64(len(payload)),
+				Annotations: map[string]string{SignatureAnnotation: base64.StdEncoding.EncodeToString(sig)},
+			}}})
+		case "/v2/repo/blobs/" + payloadDigest:
+			_, _ = w.Write(payload)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testImageRef(srv *httptest.Server, digest string) string {
+	return fmt.Sprintf("%s/repo@sha256:%s", strings.TrimPrefix(srv.URL, "https://"), digest)
+}
+
+func TestVerify(t *testing.T) {
+	r := require.New(t)
+
+	key, publicKey := testKey(t)
+	srv := testRegistry(t, key, testDigest)
+	v := &verifier{client: srv.Client()}
+
+	r.NoError(v.Verify(context.Background(), testImageRef(srv, testDigest), publicKey))
+
+	// another key
+	_, otherPublicKey := testKey(t)
+	r.ErrorIs(v.Verify(context.Background(), testImageRef(srv, testDigest), otherPublicKey), ErrInvalidSignature)
+
+	// no signature
+	otherDigest := strings.Repeat("1", 64)
+	r.ErrorIs(v.Verify(context.Background(), testImageRef(srv, otherDigest), publicKey), ErrNoSignature)
+}
+
+func TestVerify_OtherImage(t *testing.T) {
+	r := require.New(t)
+
+	// the signature is for another image
+	key, publicKey := testKey(t)
+	srv := testRegistry(t, key, strings.Repeat("2", 64))
+	v := &verifier{client: srv.Client()}
+
+	r.ErrorIs(v.Verify(context.Background(), testImageRef(srv, testDigest), publicKey), ErrInvalidSignature)
+}
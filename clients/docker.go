@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"github.com/forta-network/forta-core-go/utils/workers"
 	"github.com/forta-network/forta-node/config"
@@ -27,6 +29,7 @@ const (
 	DockerLabelForta                          = "network.forta"
 	DockerLabelFortaSupervisor                = "network.forta.supervisor"
 	DockerLabelFortaSupervisorStrategyVersion = "network.forta.supervisor.strategy-version"
+	DockerLabelFortaAgentID                   = "network.forta.agent-id"
 
 	DockerLabelFortaSettingsAgentLogsEnable = "network.forta.settings.agent-logs.enable"
 )
@@ -67,10 +70,39 @@ type DockerContainerConfig struct {
 	MaxLogSize      string
 	MaxLogFiles     int
 	CPUQuota        int64
+	CPUShares       int64
 	Memory          int64
+	DiskSize        int64 // writable layer limit in bytes, needs storage driver support
+	GPUs            int
+	GPURuntime      string // the runtime which exposes the GPUs to the container with docker
 	Cmd             []string
 	DialHost        bool
 	Labels          map[string]string
+	ReadOnlyRootfs  bool
+	Tmpfs           map[string]string // mount path -> options
+	CapDrop         []string
+	CapAdd          []string
+	SecurityOpts    []string // like "no-new-privileges" and "seccomp=<profile JSON>"
+}
+
+// gpuEnvVars selects the first GPUs for the NVIDIA container runtime.
+func gpuEnvVars(gpus int) []string {
+	var devices []string
+	for i := 0; i < gpus; i++ {
+		devices = append(devices, strconv.Itoa(i))
+	}
+	return []string{
+		fmt.Sprintf("NVIDIA_VISIBLE_DEVICES=%s", strings.Join(devices, ",")),
+		"NVIDIA_DRIVER_CAPABILITIES=compute,utility",
+	}
+}
+
+// ContainerStats contains the resource usage of a container.
+type ContainerStats struct {
+	CPUPercent  float64 // 100 is one CPU
+	MemoryUsage int64   // bytes, without the page cache
+	NetworkRx   int64   // bytes received since the container started
+	NetworkTx   int64   // bytes sent since the container started
 }
 
 // DockerContainerList contains the full container data.
@@ -236,6 +268,17 @@ func (d *dockerClient) AttachNetwork(ctx context.Context, containerID string, ne
 	return err
 }
 
+func (d *dockerClient) DetachNetwork(ctx context.Context, containerID string, networkID string) error {
+	err := d.cli.NetworkDisconnect(ctx, networkID, containerID, true)
+	if err == nil {
+		return nil
+	}
+	if strings.Contains(err.Error(), "is not connected") || isNoSuchContainerErr(err) {
+		return nil
+	}
+	return err
+}
+
 func withTcp(port string) string {
 	return fmt.Sprintf("%s/tcp", port)
 }
@@ -273,96 +316,84 @@ func (d *dockerClient) GetContainers(ctx context.Context) (DockerContainerList,
 
 // GetFortaServiceContainers returns all of the non-agent forta containers.
 func (d *dockerClient) GetFortaServiceContainers(ctx context.Context) (fortaContainers DockerContainerList, err error) {
-	containers, err := d.cli.ContainerList(ctx, types.ContainerListOptions{
-		All:     true,
-		Filters: d.labelFilter(),
-	})
-	for _, container := range containers {
-		if !strings.Contains(container.Names[0][1:], "forta-agent") {
-			fortaContainers = append(fortaContainers, container)
-		}
-	}
-	return
+	return getFortaServiceContainers(ctx, d)
 }
 
 // GetContainerByName gets a container by using a name lookup over all containers.
 func (d *dockerClient) GetContainerByName(ctx context.Context, name string) (*types.Container, error) {
-	containers, err := d.GetContainers(ctx)
-	if err != nil {
-		return nil, err
-	}
-	for _, c := range containers {
-		if c.Names[0][1:] == name {
-			return &c, nil
-		}
-	}
-	return nil, fmt.Errorf("%w with name '%s'", ErrContainerNotFound, name)
+	return getContainerByName(ctx, d, name)
 }
 
 // GetContainerByName gets a container by using an ID lookup over all containers.
 func (d *dockerClient) GetContainerByID(ctx context.Context, id string) (*types.Container, error) {
-	containers, err := d.GetContainers(ctx)
+	return getContainerByID(ctx, d, id)
+}
+
+// InspectContainer returns the full state of the container.
+func (d *dockerClient) InspectContainer(ctx context.Context, id string) (*types.ContainerJSON, error) {
+	inspection, err := d.cli.ContainerInspect(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	for _, c := range containers {
-		if c.ID == id {
-			return &c, nil
-		}
-	}
-	return nil, fmt.Errorf("%w with id '%s'", ErrContainerNotFound, id)
+	return &inspection, nil
 }
 
-// Nuke makes sure that all running Forta containers are stopped and pruned, quickly enough.
-func (d *dockerClient) Nuke(ctx context.Context) error {
-	var err error
-	for i := 0; i < 4; i++ {
-		err = d.nuke(ctx)
-		if err == nil {
-			return nil
-		}
-		log.WithError(err).Error("failed to nuke - retrying")
+// GetContainerStats returns the current resource usage of the container.
+func (d *dockerClient) GetContainerStats(ctx context.Context, id string) (*ContainerStats, error) {
+	resp, err := d.cli.ContainerStats(ctx, id, false)
+	if err != nil {
+		return nil, err
 	}
-	return fmt.Errorf("all nuke retries failed: %v", err)
+	defer resp.Body.Close()
+	var stats types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to decode container stats: %v", err)
+	}
+	return dockerContainerStats(&stats), nil
 }
 
-func (d *dockerClient) nuke(ctx context.Context) error {
-	// step 1: put the supervisor to the top of the list so it doesn't do funny restarts
-	containers, err := d.GetContainers(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get forta containers list: %v", err)
-	}
-	supervisorContainer, err := d.GetContainerByName(ctx, config.DockerSupervisorContainerName)
-	if err == nil {
-		containers = append([]types.Container{*supervisorContainer}, containers...)
+// dockerContainerStats calculates the usage like the Docker CLI does.
+func dockerContainerStats(stats *types.StatsJSON) *ContainerStats {
+	var result ContainerStats
+
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
 	}
-	if err != nil && !errors.Is(err, ErrContainerNotFound) {
-		return fmt.Errorf("unexpected error while getting supervisor container: %v", err)
+	if cpuDelta > 0 && systemDelta > 0 {
+		result.CPUPercent = cpuDelta / systemDelta * onlineCPUs * 100
 	}
 
-	// step 2: stop all and wait until each exit
-	for _, container := range containers {
-		if err := d.StopContainer(ctx, container.ID); err != nil {
-			return fmt.Errorf("failed to stop: %v", err)
-		}
-		if err := d.WaitContainerExit(ctx, container.ID); err != nil {
-			return err
-		}
+	memUsage := stats.MemoryStats.Usage
+	if cache := stats.MemoryStats.Stats["cache"]; cache < memUsage {
+		memUsage -= cache
 	}
+	result.MemoryUsage = int64(memUsage)
 
-	// step 3: prune everything
-	if err := d.Prune(ctx); err != nil {
-		return fmt.Errorf("failed to prune: %v", err)
+	for _, network := range stats.Networks {
+		result.NetworkRx += int64(network.RxBytes)
+		result.NetworkTx += int64(network.TxBytes)
 	}
+	return &result
+}
 
-	// step 4: ensure that the containers are really pruned
-	for _, container := range containers {
-		if err := d.WaitContainerPrune(ctx, container.ID); err != nil {
-			return err
-		}
+// GetContainerDiskUsage returns the size of the writable layer of the container in bytes.
+func (d *dockerClient) GetContainerDiskUsage(ctx context.Context, id string) (int64, error) {
+	inspection, _, err := d.cli.ContainerInspectWithRaw(ctx, id, true)
+	if err != nil {
+		return 0, err
+	}
+	if inspection.SizeRw == nil {
+		return 0, nil
 	}
+	return *inspection.SizeRw, nil
+}
 
-	return nil
+// Nuke makes sure that all running Forta containers are stopped and pruned, quickly enough.
+func (d *dockerClient) Nuke(ctx context.Context) error {
+	return nuke(ctx, d)
 }
 
 // StartContainer kicks off a container as a daemon and returns a summary of the container
@@ -461,14 +492,27 @@ func (d *dockerClient) StartContainer(ctx context.Context, config DockerContaine
 			Type: "json-file",
 		},
 		Resources: container.Resources{
-			CPUQuota: config.CPUQuota,
-			Memory:   config.Memory,
+			CPUQuota:  config.CPUQuota,
+			CPUShares: config.CPUShares,
+			Memory:    config.Memory,
 		},
+		ReadonlyRootfs: config.ReadOnlyRootfs,
+		Tmpfs:          config.Tmpfs,
+		CapDrop:        config.CapDrop,
+		CapAdd:         config.CapAdd,
+		SecurityOpt:    config.SecurityOpts,
 	}
 
 	if config.DialHost {
 		hostCfg.ExtraHosts = append(hostCfg.ExtraHosts, "host.docker.internal:host-gateway")
 	}
+	if config.DiskSize > 0 {
+		hostCfg.StorageOpt = map[string]string{"size": fmt.Sprintf("%d", config.DiskSize)}
+	}
+	if config.GPUs > 0 {
+		hostCfg.Runtime = config.GPURuntime
+		cntCfg.Env = append(cntCfg.Env, gpuEnvVars(config.GPUs)...)
+	}
 
 	cont, err := d.cli.ContainerCreate(
 		ctx,
@@ -539,10 +583,11 @@ func (d *dockerClient) stopContainer(ctx context.Context, containerID, signal st
 	return err
 }
 
-// RemoveContainer kills and a container by ID.
+// RemoveContainer kills and removes a container by ID, together with its anonymous volumes.
 func (d *dockerClient) RemoveContainer(ctx context.Context, containerID string) error {
 	return d.cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{
-		Force: true,
+		Force:         true,
+		RemoveVolumes: true,
 	})
 }
 
@@ -556,90 +601,70 @@ func isNotRunningErr(err error) bool {
 
 // WaitContainerExit waits for container exit by checking periodically.
 func (d *dockerClient) WaitContainerExit(ctx context.Context, id string) error {
-	ticker := time.NewTicker(500 * time.Millisecond)
-	logger := log.WithFields(log.Fields{
-		"id": id,
-	})
-
-	ctx, cancel := context.WithTimeout(ctx, time.Minute)
-	defer cancel()
-
-	for {
-		logger.Info("waiting for container exit")
-		c, err := d.GetContainerByID(ctx, id)
-		if err != nil && errors.Is(err, ErrContainerNotFound) {
-			logger.Info("no need to wait for container exit - not found")
-			return nil
-		}
-		if err != nil {
-			logger.WithError(err).Error("failed while waiting for container exit")
-			return err
-		}
-		if c.State == "exited" || c.State == "created" {
-			return nil
-		}
-		logger.WithField("containerState", c.State).Info("still waiting for exit")
-		<-ticker.C
-	}
+	return waitContainerExit(ctx, d, id)
 }
 
 // WaitContainerStart waits for container start by checking periodically.
 func (d *dockerClient) WaitContainerStart(ctx context.Context, id string) error {
-	ticker := time.NewTicker(time.Second)
-	start := time.Now()
-	logger := log.WithFields(log.Fields{
-		"id": id,
-	})
+	return waitContainerStart(ctx, d, id)
+}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Minute)
-	defer cancel()
+// WaitContainerPrune waits for container prune by checking periodically.
+func (d *dockerClient) WaitContainerPrune(ctx context.Context, id string) error {
+	return waitContainerPrune(ctx, d, id)
+}
 
-	for t := range ticker.C {
-		logger.Info("waiting for container start")
-		c, err := d.GetContainerByID(ctx, id)
-		if err == nil && c != nil && c.State == "running" {
-			logger.Info("container started")
-			return nil
-		}
-		if err != nil {
-			return err
-		}
-		// if the conditions are not met within 30 seconds, it's a failure
-		if t.After(start.Add(time.Second * 30)) {
-			return errors.New("container did not start")
-		}
+// InspectImage returns the details of the local image.
+func (d *dockerClient) InspectImage(ctx context.Context, ref string) (*types.ImageInspect, error) {
+	inspection, _, err := d.cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return &inspection, nil
 }
 
-// WaitContainerPrune waits for container prune by checking periodically.
-func (d *dockerClient) WaitContainerPrune(ctx context.Context, id string) error {
-	ticker := time.NewTicker(500 * time.Millisecond)
-	logger := log.WithFields(log.Fields{
-		"id": id,
-	})
+// ReadImageFile reads a file from the image. The file is copied from a container which is created but
+// never started.
+func (d *dockerClient) ReadImageFile(ctx context.Context, ref, filePath string) ([]byte, error) {
+	cont, err := d.cli.ContainerCreate(ctx, &container.Config{
+		Image:      ref,
+		Entrypoint: []string{"true"},
+	}, &container.HostConfig{NetworkMode: "none"}, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = d.cli.ContainerRemove(context.Background(), cont.ID, types.ContainerRemoveOptions{Force: true})
+	}()
+	rc, _, err := d.cli.CopyFromContainer(ctx, cont.ID, filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	if _, err := tr.Next(); err != nil {
+		return nil, fmt.Errorf("failed to read %s from the image: %v", filePath, err)
+	}
+	return io.ReadAll(tr)
+}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Minute)
-	defer cancel()
+// GetImages returns the local images.
+func (d *dockerClient) GetImages(ctx context.Context) ([]types.ImageSummary, error) {
+	return d.cli.ImageList(ctx, types.ImageListOptions{})
+}
 
-	for {
-		logger.Infof("waiting for container prune")
-		c, err := d.GetContainerByID(ctx, id)
-		if err != nil && errors.Is(err, ErrContainerNotFound) {
-			return nil
-		}
-		if err != nil {
-			logger.WithError(err).Error("error while waiting for prune")
-			return err
-		}
-		logger.WithField("containerState", c.State).Info("container state while waiting for prune")
-		if !(c.State == "exited" || c.State == "dead") {
-			err = fmt.Errorf("cannot prune container with status '%s' - container needs to stop first", c.State)
-			logger.WithError(err).Error("error while waiting for prune")
-			return err
+// RemoveImage removes the local image if no containers use it.
+func (d *dockerClient) RemoveImage(ctx context.Context, ref string) error {
+	res, err := d.cli.ImageRemove(ctx, ref, types.ImageRemoveOptions{PruneChildren: true})
+	if err != nil {
+		return err
+	}
+	for _, item := range res {
+		if len(item.Deleted) > 0 {
+			log.Infof("deleted image layer %s", item.Deleted)
 		}
-		<-ticker.C
 	}
+	return nil
 }
 
 // HasLocalImage checks if we have an image locally.
@@ -650,32 +675,7 @@ func (d *dockerClient) HasLocalImage(ctx context.Context, ref string) bool {
 
 // EnsureLocalImage ensures that we have the image locally.
 func (d *dockerClient) EnsureLocalImage(ctx context.Context, name, ref string) error {
-	log.WithFields(log.Fields{
-		"image": ref,
-		"name":  name,
-	}).Info("ensuring local image")
-	if d.HasLocalImage(ctx, ref) {
-		log.Infof("found local image for '%s': %s", name, ref)
-		return nil
-	}
-
-	ticker := time.NewTicker(time.Minute)
-
-	for {
-		err := d.PullImage(ctx, ref)
-		if err == nil {
-			break
-		}
-		log.WithFields(log.Fields{
-			"name":  name,
-			"ref":   ref,
-			"error": err,
-		}).Error("failed to pull image - retrying")
-		<-ticker.C
-	}
-
-	log.Infof("pulled image for '%s': %s", name, ref)
-	return nil
+	return ensureLocalImage(ctx, d, name, ref)
 }
 
 // GetContainerLogs gets the container logs.
@@ -708,6 +708,32 @@ func (d *dockerClient) GetContainerLogs(ctx context.Context, containerID, tail s
 	return strings.Join(lines, "\n"), nil
 }
 
+// FollowContainerLogs streams the timestamped stdout and stderr lines of the container which were
+// written after the given time, until the container stops or the context is done.
+func (d *dockerClient) FollowContainerLogs(ctx context.Context, containerID string, since time.Time) (io.ReadCloser, error) {
+	opts := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+		Follow:     true,
+	}
+	if !since.IsZero() {
+		opts.Since = since.Format(time.RFC3339Nano)
+	}
+	r, err := d.cli.ContainerLogs(ctx, containerID, opts)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		// the writes are sequential so the lines are not mixed
+		_, err := stdcopy.StdCopy(pw, pw, r)
+		r.Close()
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
 func (d *dockerClient) labelFilter() filters.Args {
 	filter := filters.NewArgs()
 	for _, label := range d.labels {
@@ -748,6 +774,28 @@ func NewDockerClient(name string) (*dockerClient, error) {
 	}, nil
 }
 
+// NewRuntimeClient creates a new client which talks to the configured container runtime through
+// the socket. Podman serves a Docker-compatible API so the docker client drives both.
+func NewRuntimeClient(name string, runtime config.ContainerRuntimeConfig, socket string) (DockerClient, error) {
+	if runtime.Runtime == config.ContainerRuntimeContainerd {
+		return NewContainerdClient(name, runtime, socket), nil
+	}
+	cli, err := client.NewClientWithOpts(client.WithHost("unix://" + socket))
+	if err != nil {
+		return nil, err
+	}
+	if runtime.Runtime == config.ContainerRuntimePodman {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		cli.NegotiateAPIVersion(ctx)
+	}
+	return &dockerClient{
+		cli:     cli,
+		workers: workers.New(10),
+		labels:  initLabels(name),
+	}, nil
+}
+
 // NewAuthDockerClient creates a new docker client with credentials
 func NewAuthDockerClient(name string, username, password string) (*dockerClient, error) {
 	if len(username) == 0 && len(password) == 0 {
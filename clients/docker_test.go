@@ -0,0 +1,32 @@
+package clients
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerContainerStats(t *testing.T) {
+	r := require.New(t)
+
+	var stats types.StatsJSON
+	stats.PreCPUStats.CPUUsage.TotalUsage = 100
+	stats.PreCPUStats.SystemUsage = 1000
+	stats.CPUStats.CPUUsage.TotalUsage = 200
+	stats.CPUStats.SystemUsage = 2000
+	stats.CPUStats.OnlineCPUs = 4
+	stats.MemoryStats.Usage = 3000
+	stats.MemoryStats.Stats = map[string]uint64{"cache": 1000}
+	stats.Networks = map[string]types.NetworkStats{
+		"eth0": {RxBytes: 10, TxBytes: 20},
+		"eth1": {RxBytes: 1, TxBytes: 2},
+	}
+
+	r.Equal(&ContainerStats{
+		CPUPercent:  40,
+		MemoryUsage: 2000,
+		NetworkRx:   11,
+		NetworkTx:   22,
+	}, dockerContainerStats(&stats))
+}
@@ -3,6 +3,7 @@ package clients
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/forta-network/forta-core-go/domain"
 	"google.golang.org/grpc"
@@ -21,11 +22,15 @@ type DockerClient interface {
 	CreatePublicNetwork(ctx context.Context, name string) (string, error)
 	CreateInternalNetwork(ctx context.Context, name string) (string, error)
 	AttachNetwork(ctx context.Context, containerID string, networkID string) error
+	DetachNetwork(ctx context.Context, containerID string, networkID string) error
 	RemoveNetworkByName(ctx context.Context, networkName string) error
 	GetContainers(ctx context.Context) (DockerContainerList, error)
 	GetFortaServiceContainers(ctx context.Context) (fortaContainers DockerContainerList, err error)
 	GetContainerByName(ctx context.Context, name string) (*types.Container, error)
 	GetContainerByID(ctx context.Context, id string) (*types.Container, error)
+	InspectContainer(ctx context.Context, id string) (*types.ContainerJSON, error)
+	GetContainerDiskUsage(ctx context.Context, id string) (int64, error)
+	GetContainerStats(ctx context.Context, id string) (*ContainerStats, error)
 	StartContainer(ctx context.Context, config DockerContainerConfig) (*DockerContainer, error)
 	StopContainer(ctx context.Context, id string) error
 	InterruptContainer(ctx context.Context, id string) error
@@ -37,8 +42,13 @@ type DockerClient interface {
 	WaitContainerPrune(ctx context.Context, id string) error
 	Nuke(ctx context.Context) error
 	HasLocalImage(ctx context.Context, ref string) bool
+	InspectImage(ctx context.Context, ref string) (*types.ImageInspect, error)
+	ReadImageFile(ctx context.Context, ref, filePath string) ([]byte, error)
+	GetImages(ctx context.Context) ([]types.ImageSummary, error)
+	RemoveImage(ctx context.Context, ref string) error
 	EnsureLocalImage(ctx context.Context, name, ref string) error
 	GetContainerLogs(ctx context.Context, containerID, tail string, truncate int) (string, error)
+	FollowContainerLogs(ctx context.Context, containerID string, since time.Time) (io.ReadCloser, error)
 }
 
 // MessageClient receives and publishes messages.
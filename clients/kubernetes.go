@@ -0,0 +1,613 @@
+package clients
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+	"github.com/goccy/go-json"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	kubectlBinary = "kubectl"
+
+	kubernetesAppLabel = "app.kubernetes.io/name"
+	kubernetesGPU      = "nvidia.com/gpu"
+)
+
+// kubectlRunner runs kubectl with the arguments and writes the input to its stdin.
+type kubectlRunner func(ctx context.Context, stdin []byte, args ...string) (stdout []byte, err error)
+
+func runKubectl(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, kubectlBinary, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// kubernetesDeploymentList is the deployment list output.
+type kubernetesDeploymentList struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Labels      map[string]string `json:"labels"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas *int `json:"replicas"`
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						Image string `json:"image"`
+					} `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+		Status struct {
+			ReadyReplicas int `json:"readyReplicas"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// kubernetesClient runs the agent containers as deployments on a Kubernetes cluster by using
+// kubectl. Each agent deployment has a service with the same name which the scanner connects to
+// and a network policy which lets only the scanner in. The cluster pulls the images and connects
+// the pods, so the image and the network operations do nothing. The container IDs and the network
+// IDs are the names.
+type kubernetesClient struct {
+	cfg        config.KubernetesConfig
+	namespace  string
+	kubeconfig string
+	run        kubectlRunner
+	labels     []dockerLabel
+}
+
+// NewKubernetesClient creates a new client which manages the agents in the configured namespace.
+func NewKubernetesClient(name string, cfg config.KubernetesConfig) *kubernetesClient {
+	return &kubernetesClient{
+		cfg:        cfg,
+		namespace:  cfg.Namespace,
+		kubeconfig: cfg.KubeconfigPath(),
+		run:        runKubectl,
+		labels:     initLabels(name),
+	}
+}
+
+func (d *kubernetesClient) args(args ...string) []string {
+	base := []string{"--namespace", d.namespace}
+	if len(d.kubeconfig) > 0 {
+		base = append(base, "--kubeconfig", d.kubeconfig)
+	}
+	return append(base, args...)
+}
+
+func (d *kubernetesClient) kubectl(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	stdout, err := d.run(ctx, stdin, d.args(args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("kubectl %s failed: %w", args[0], err)
+	}
+	return stdout, nil
+}
+
+func isKubernetesNotFoundErr(err error) bool {
+	return strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "not found")
+}
+
+func (d *kubernetesClient) labelSelector() string {
+	var selectors []string
+	for _, label := range d.labels {
+		selectors = append(selectors, fmt.Sprintf("%s=%s", label.Name, label.Value))
+	}
+	return strings.Join(selectors, ",")
+}
+
+// PullImage does nothing since the cluster pulls the images.
+func (d *kubernetesClient) PullImage(ctx context.Context, refStr string) error {
+	return nil
+}
+
+// CreatePublicNetwork returns the name since the pods share the cluster network.
+func (d *kubernetesClient) CreatePublicNetwork(ctx context.Context, name string) (string, error) {
+	return name, nil
+}
+
+// CreateInternalNetwork returns the name since the pods share the cluster network.
+func (d *kubernetesClient) CreateInternalNetwork(ctx context.Context, name string) (string, error) {
+	return name, nil
+}
+
+// AttachNetwork does nothing since the pods share the cluster network.
+func (d *kubernetesClient) AttachNetwork(ctx context.Context, containerID string, networkID string) error {
+	return nil
+}
+
+// DetachNetwork does nothing since the pods share the cluster network.
+func (d *kubernetesClient) DetachNetwork(ctx context.Context, containerID string, networkID string) error {
+	return nil
+}
+
+// RemoveNetworkByName does nothing since the pods share the cluster network.
+func (d *kubernetesClient) RemoveNetworkByName(ctx context.Context, networkName string) error {
+	return nil
+}
+
+// GetContainers returns the agent deployments of this client. A deployment which is scaled
+// to zero is exited and a deployment without ready pods is created.
+func (d *kubernetesClient) GetContainers(ctx context.Context) (DockerContainerList, error) {
+	b, err := d.kubectl(ctx, nil, "get", "deployments", "--selector", d.labelSelector(), "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	var list kubernetesDeploymentList
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode kubectl output: %v", err)
+	}
+	var containers DockerContainerList
+	for _, item := range list.Items {
+		labels := make(map[string]string)
+		for k, v := range item.Metadata.Annotations {
+			labels[k] = v
+		}
+		for k, v := range item.Metadata.Labels {
+			labels[k] = v
+		}
+		var image string
+		if len(item.Spec.Template.Spec.Containers) > 0 {
+			image = item.Spec.Template.Spec.Containers[0].Image
+		}
+		state, status := "created", "Starting"
+		switch {
+		case item.Spec.Replicas != nil && *item.Spec.Replicas == 0:
+			state, status = "exited", "Scaled to 0"
+		case item.Status.ReadyReplicas > 0:
+			state, status = "running", "Ready"
+		}
+		containers = append(containers, types.Container{
+			ID:     item.Metadata.Name,
+			Names:  []string{"/" + item.Metadata.Name},
+			Image:  image,
+			Labels: labels,
+			State:  state,
+			Status: status,
+		})
+	}
+	return containers, nil
+}
+
+// GetFortaServiceContainers returns all of the non-agent forta containers.
+func (d *kubernetesClient) GetFortaServiceContainers(ctx context.Context) (fortaContainers DockerContainerList, err error) {
+	return getFortaServiceContainers(ctx, d)
+}
+
+// GetContainerByName gets a container by using a name lookup over all containers.
+func (d *kubernetesClient) GetContainerByName(ctx context.Context, name string) (*types.Container, error) {
+	return getContainerByName(ctx, d, name)
+}
+
+// GetContainerByID gets a container by using an ID lookup over all containers.
+func (d *kubernetesClient) GetContainerByID(ctx context.Context, id string) (*types.Container, error) {
+	return getContainerByID(ctx, d, id)
+}
+
+// InspectContainer returns the state of the deployment in the container inspection format.
+func (d *kubernetesClient) InspectContainer(ctx context.Context, id string) (*types.ContainerJSON, error) {
+	c, err := d.GetContainerByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    c.ID,
+			Name:  c.Names[0],
+			Image: c.Image,
+			State: &types.ContainerState{
+				Status:  c.State,
+				Running: c.State == "running",
+			},
+		},
+		Config: &container.Config{
+			Image:  c.Image,
+			Labels: c.Labels,
+		},
+	}, nil
+}
+
+// GetContainerDiskUsage returns zero since the kubelet limits the ephemeral storage of the pods.
+func (d *kubernetesClient) GetContainerDiskUsage(ctx context.Context, id string) (int64, error) {
+	return 0, nil
+}
+
+// GetContainerStats returns the CPU and the memory usage of the agent pod from the metrics API.
+// The network usage is not available.
+func (d *kubernetesClient) GetContainerStats(ctx context.Context, id string) (*ContainerStats, error) {
+	b, err := d.kubectl(ctx, nil, "top", "pod", "--selector", fmt.Sprintf("%s=%s", kubernetesAppLabel, id), "--no-headers")
+	if err != nil {
+		return nil, err
+	}
+	// like "forta-agent-0x04ec6a-cdd4-7d9c5b6f4-x2x9q   12m   10Mi"
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 {
+		return nil, ErrContainerNotFound
+	}
+	return parseKubernetesStats(fields[1], fields[2])
+}
+
+func parseKubernetesStats(cpu, memory string) (*ContainerStats, error) {
+	var (
+		result ContainerStats
+		err    error
+	)
+	if strings.HasSuffix(cpu, "m") {
+		millicores, err := strconv.ParseFloat(strings.TrimSuffix(cpu, "m"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu usage: %v", err)
+		}
+		result.CPUPercent = millicores / 10
+	} else {
+		cores, err := strconv.ParseFloat(cpu, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu usage: %v", err)
+		}
+		result.CPUPercent = cores * 100
+	}
+	if result.MemoryUsage, err = units.RAMInBytes(memory); err != nil {
+		return nil, fmt.Errorf("invalid memory usage: %v", err)
+	}
+	return &result, nil
+}
+
+// StartContainer creates or updates the agent deployment and the service and scales the
+// deployment up if it was stopped.
+func (d *kubernetesClient) StartContainer(ctx context.Context, config DockerContainerConfig) (*DockerContainer, error) {
+	log.WithFields(log.Fields{
+		"image": config.Image,
+		"name":  config.Name,
+	}).Info("StartContainer()")
+
+	manifest, err := d.agentManifest(config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.kubectl(ctx, manifest, "apply", "--filename", "-"); err != nil {
+		return nil, err
+	}
+	return &DockerContainer{Name: config.Name, ID: config.Name, Config: config}, nil
+}
+
+// agentManifest returns the deployment, the service and the network policy of the agent. The
+// hardening options and the resource limits are translated to their pod equivalents. The seccomp
+// profiles cannot be passed inline, so the default profile of the runtime is used instead. The
+// agent gRPC channel is not encrypted, so the pod accepts the connections only from the scanner.
+func (d *kubernetesClient) agentManifest(cfg DockerContainerConfig) ([]byte, error) {
+	scannerIPBlock, err := d.cfg.ScannerIPBlock()
+	if err != nil {
+		return nil, err
+	}
+	port := config.AgentGrpcPort
+	if envPort, ok := cfg.Env[config.EnvAgentGrpcPort]; ok {
+		port = envPort
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid agent port: %v", err)
+	}
+
+	labels := labelsToMap(d.labels)
+	labels[kubernetesAppLabel] = cfg.Name
+	selector := map[string]string{kubernetesAppLabel: cfg.Name}
+
+	var envNames []string
+	for name := range cfg.Env {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+	env := []map[string]interface{}{}
+	for _, name := range envNames {
+		env = append(env, map[string]interface{}{"name": name, "value": cfg.Env[name]})
+	}
+
+	limits := make(map[string]string)
+	requests := make(map[string]string)
+	if cfg.CPUQuota > 0 {
+		limits["cpu"] = fmt.Sprintf("%dm", cfg.CPUQuota/100) // 100000 is one CPU
+	}
+	if cfg.CPUShares > 0 {
+		requests["cpu"] = fmt.Sprintf("%dm", cfg.CPUShares*1000/1024)
+	}
+	if cfg.Memory > 0 {
+		limits["memory"] = strconv.FormatInt(cfg.Memory, 10)
+	}
+	if cfg.DiskSize > 0 {
+		limits["ephemeral-storage"] = strconv.FormatInt(cfg.DiskSize, 10)
+	}
+	if cfg.GPUs > 0 {
+		limits[kubernetesGPU] = strconv.Itoa(cfg.GPUs)
+	}
+
+	securityContext := map[string]interface{}{
+		"readOnlyRootFilesystem": cfg.ReadOnlyRootfs,
+		"capabilities": map[string]interface{}{
+			"drop": cfg.CapDrop,
+			"add":  cfg.CapAdd,
+		},
+	}
+	for _, opt := range cfg.SecurityOpts {
+		switch {
+		case opt == "no-new-privileges":
+			securityContext["allowPrivilegeEscalation"] = false
+		case strings.HasPrefix(opt, "seccomp=") && opt != "seccomp=unconfined":
+			securityContext["seccompProfile"] = map[string]string{"type": "RuntimeDefault"}
+		}
+	}
+
+	var tmpfsPaths []string
+	for path := range cfg.Tmpfs {
+		tmpfsPaths = append(tmpfsPaths, path)
+	}
+	sort.Strings(tmpfsPaths)
+	volumes := []map[string]interface{}{}
+	volumeMounts := []map[string]interface{}{}
+	for i, path := range tmpfsPaths {
+		name := fmt.Sprintf("tmpfs-%d", i)
+		emptyDir := map[string]interface{}{"medium": "Memory"}
+		for _, opt := range strings.Split(cfg.Tmpfs[path], ",") {
+			if size := strings.TrimPrefix(opt, "size="); size != opt {
+				sizeBytes, err := units.RAMInBytes(size)
+				if err != nil {
+					return nil, fmt.Errorf("invalid tmpfs size: %v", err)
+				}
+				emptyDir["sizeLimit"] = strconv.FormatInt(sizeBytes, 10)
+			}
+		}
+		volumes = append(volumes, map[string]interface{}{"name": name, "emptyDir": emptyDir})
+		volumeMounts = append(volumeMounts, map[string]interface{}{"name": name, "mountPath": path})
+	}
+
+	agentContainer := map[string]interface{}{
+		"name":  "agent",
+		"image": cfg.Image,
+		"env":   env,
+		"ports": []map[string]interface{}{
+			{"name": "grpc", "containerPort": portNum},
+		},
+		"resources": map[string]interface{}{
+			"limits":   limits,
+			"requests": requests,
+		},
+		"securityContext": securityContext,
+		"volumeMounts":    volumeMounts,
+	}
+	if len(cfg.Cmd) > 0 {
+		agentContainer["args"] = cfg.Cmd
+	}
+
+	deployment := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":        cfg.Name,
+			"labels":      labels,
+			"annotations": cfg.Labels,
+		},
+		"spec": map[string]interface{}{
+			"replicas": 1,
+			"strategy": map[string]string{"type": "Recreate"},
+			"selector": map[string]interface{}{"matchLabels": selector},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels":      labels,
+					"annotations": cfg.Labels,
+				},
+				"spec": map[string]interface{}{
+					"automountServiceAccountToken": false,
+					"enableServiceLinks":           false,
+					"containers":                   []interface{}{agentContainer},
+					"volumes":                      volumes,
+				},
+			},
+		},
+	}
+	service := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":   cfg.Name,
+			"labels": labels,
+		},
+		"spec": map[string]interface{}{
+			"selector": selector,
+			"ports": []map[string]interface{}{
+				{"name": "grpc", "port": portNum, "targetPort": portNum},
+			},
+		},
+	}
+	networkPolicy := map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "NetworkPolicy",
+		"metadata": map[string]interface{}{
+			"name":   cfg.Name,
+			"labels": labels,
+		},
+		"spec": map[string]interface{}{
+			"podSelector": map[string]interface{}{"matchLabels": selector},
+			"policyTypes": []string{"Ingress"},
+			"ingress": []map[string]interface{}{
+				{
+					"from": []map[string]interface{}{
+						{"ipBlock": map[string]string{"cidr": scannerIPBlock}},
+					},
+					"ports": []map[string]interface{}{
+						{"protocol": "TCP", "port": portNum},
+					},
+				},
+			},
+		},
+	}
+	return json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      []interface{}{deployment, service, networkPolicy},
+	})
+}
+
+// StopContainer scales the deployment to zero.
+func (d *kubernetesClient) StopContainer(ctx context.Context, id string) error {
+	_, err := d.kubectl(ctx, nil, "scale", fmt.Sprintf("deployment/%s", id), "--replicas", "0")
+	if err != nil && isKubernetesNotFoundErr(err) {
+		return nil
+	}
+	return err
+}
+
+// InterruptContainer scales the deployment to zero, which interrupts the pod first.
+func (d *kubernetesClient) InterruptContainer(ctx context.Context, id string) error {
+	return d.StopContainer(ctx, id)
+}
+
+// TerminateContainer scales the deployment to zero, which terminates the pod.
+func (d *kubernetesClient) TerminateContainer(ctx context.Context, id string) error {
+	return d.StopContainer(ctx, id)
+}
+
+// RemoveContainer deletes the deployment, the service and the network policy.
+func (d *kubernetesClient) RemoveContainer(ctx context.Context, containerID string) error {
+	_, err := d.kubectl(ctx, nil, "delete", fmt.Sprintf("deployment/%s", containerID), fmt.Sprintf("service/%s", containerID),
+		fmt.Sprintf("networkpolicy/%s", containerID), "--ignore-not-found", "--wait=false")
+	return err
+}
+
+// WaitContainerExit waits for container exit by checking periodically.
+func (d *kubernetesClient) WaitContainerExit(ctx context.Context, id string) error {
+	return waitContainerExit(ctx, d, id)
+}
+
+// WaitContainerStart waits for container start by checking periodically.
+func (d *kubernetesClient) WaitContainerStart(ctx context.Context, id string) error {
+	return waitContainerStart(ctx, d, id)
+}
+
+// Prune removes the stopped deployments of this client.
+func (d *kubernetesClient) Prune(ctx context.Context) error {
+	containers, err := d.GetContainers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, container := range containers {
+		if container.State != "exited" {
+			continue
+		}
+		if err := d.RemoveContainer(ctx, container.ID); err != nil {
+			return err
+		}
+		log.Infof("pruned deployment %s", container.ID)
+	}
+	return nil
+}
+
+// WaitContainerPrune waits for container prune by checking periodically.
+func (d *kubernetesClient) WaitContainerPrune(ctx context.Context, id string) error {
+	return waitContainerPrune(ctx, d, id)
+}
+
+// Nuke makes sure that all running Forta containers are stopped and pruned, quickly enough.
+func (d *kubernetesClient) Nuke(ctx context.Context) error {
+	return nuke(ctx, d)
+}
+
+// HasLocalImage returns true since the cluster pulls the images.
+func (d *kubernetesClient) HasLocalImage(ctx context.Context, ref string) bool {
+	return true
+}
+
+// InspectImage returns the digest of the reference. The images can be used only by their digests,
+// which the cluster pulls exactly.
+func (d *kubernetesClient) InspectImage(ctx context.Context, ref string) (*types.ImageInspect, error) {
+	if _, digest := utils.SplitImageRef(ref); len(digest) == 0 {
+		return nil, fmt.Errorf("image must be referenced by digest on kubernetes: %s", ref)
+	}
+	return &types.ImageInspect{ID: ref, RepoDigests: []string{ref}}, nil
+}
+
+// ReadImageFile is not supported since the cluster pulls the images.
+func (d *kubernetesClient) ReadImageFile(ctx context.Context, ref, filePath string) ([]byte, error) {
+	return nil, fmt.Errorf("cannot read the image files on kubernetes: %s", ref)
+}
+
+// EnsureLocalImage does nothing since the cluster pulls the images.
+func (d *kubernetesClient) EnsureLocalImage(ctx context.Context, name, ref string) error {
+	return nil
+}
+
+// GetImages returns no images since the cluster manages them.
+func (d *kubernetesClient) GetImages(ctx context.Context) ([]types.ImageSummary, error) {
+	return nil, nil
+}
+
+// RemoveImage does nothing since the cluster manages the images.
+func (d *kubernetesClient) RemoveImage(ctx context.Context, ref string) error {
+	return nil
+}
+
+// GetContainerLogs gets the logs of the agent pod.
+func (d *kubernetesClient) GetContainerLogs(ctx context.Context, containerID, tail string, truncate int) (string, error) {
+	args := []string{"logs", fmt.Sprintf("deployment/%s", containerID), "--timestamps"}
+	if len(tail) > 0 {
+		args = append(args, "--tail", tail)
+	}
+	b, err := d.kubectl(ctx, nil, args...)
+	if err != nil {
+		return "", err
+	}
+	logs := strings.TrimSpace(string(b))
+	if truncate >= 0 && len(logs) > truncate {
+		logs = logs[:truncate]
+	}
+	return logs, nil
+}
+
+// FollowContainerLogs streams the timestamped log lines of the agent pod which were written after
+// the given time, until the pod stops or the context is done.
+func (d *kubernetesClient) FollowContainerLogs(ctx context.Context, containerID string, since time.Time) (io.ReadCloser, error) {
+	args := []string{"logs", fmt.Sprintf("deployment/%s", containerID), "--follow", "--timestamps"}
+	if !since.IsZero() {
+		args = append(args, "--since-time", since.Format(time.RFC3339Nano))
+	}
+	cmd := exec.CommandContext(ctx, kubectlBinary, d.args(args...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("kubectl logs failed: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			_, _ = fmt.Fprintln(pw, scanner.Text())
+		}
+		pw.CloseWithError(cmd.Wait())
+	}()
+	return pr, nil
+}
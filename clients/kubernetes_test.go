@@ -0,0 +1,138 @@
+package clients
+
+import (
+	"context"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+)
+
+type testKubectl struct {
+	stdout map[string]string
+	calls  [][]string
+	stdin  []byte
+}
+
+func (k *testKubectl) run(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	args = args[2:] // skip the namespace
+	k.calls = append(k.calls, args)
+	if stdin != nil {
+		k.stdin = stdin
+	}
+	return []byte(k.stdout[args[0]]), nil
+}
+
+func testKubernetesClient(k *testKubectl) *kubernetesClient {
+	cli := NewKubernetesClient("supervisor", config.KubernetesConfig{Namespace: "forta-agents", NodeHost: "10.0.0.5"})
+	cli.run = k.run
+	return cli
+}
+
+const testDeploymentList = `{"items":[
+{"metadata":{"name":"forta-agent-1","labels":{"network.forta":"true","network.forta.supervisor":"supervisor"},"annotations":{"network.forta.agent-id":"0x1"}},"spec":{"replicas":1,"template":{"spec":{"containers":[{"image":"agent1"}]}}},"status":{"readyReplicas":1}},
+{"metadata":{"name":"forta-agent-2","labels":{"network.forta":"true","network.forta.supervisor":"supervisor"}},"spec":{"replicas":0,"template":{"spec":{"containers":[{"image":"agent2"}]}}},"status":{}}
+]}`
+
+func TestKubernetesGetContainers(t *testing.T) {
+	r := require.New(t)
+
+	k := &testKubectl{stdout: map[string]string{"get": testDeploymentList}}
+	cli := testKubernetesClient(k)
+	containers, err := cli.GetContainers(context.Background())
+	r.NoError(err)
+	r.Len(containers, 2)
+
+	r.Equal("/forta-agent-1", containers[0].Names[0])
+	r.Equal("running", containers[0].State)
+	r.Equal("0x1", containers[0].Labels["network.forta.agent-id"])
+	r.Equal("exited", containers[1].State)
+	r.Contains(k.calls[0], "network.forta=true,network.forta.supervisor=supervisor")
+}
+
+func TestKubernetesStartContainer(t *testing.T) {
+	r := require.New(t)
+
+	k := &testKubectl{}
+	cli := testKubernetesClient(k)
+	container, err := cli.StartContainer(context.Background(), DockerContainerConfig{
+		Name:           "forta-agent-1",
+		Image:          "disco.forta.network/bafybeibvkqkf7i2ylbjvfg7h2aexhvbtvqzdvdzaokkv4nhwvskylrqgqu@sha256:7e5b3f05b8ef4cd2e5d45d9e5fa1aab7e74e1f8e8d3b5cf3bc20e7c3a5d80c1d",
+		Env:            map[string]string{config.EnvAgentGrpcPort: "50052"},
+		CPUQuota:       50000,
+		Memory:         1024,
+		GPUs:           1,
+		ReadOnlyRootfs: true,
+		SecurityOpts:   []string{"no-new-privileges"},
+		Tmpfs:          map[string]string{"/tmp": "rw,size=64m"},
+	})
+	r.NoError(err)
+	r.Equal("forta-agent-1", container.ID)
+	r.Equal([]string{"apply", "--filename", "-"}, k.calls[0])
+
+	var manifest struct {
+		Items []struct {
+			Kind string `json:"kind"`
+			Spec struct {
+				Ports   []struct{ Port int } `json:"ports"`
+				Ingress []struct {
+					From []struct {
+						IPBlock struct {
+							CIDR string `json:"cidr"`
+						} `json:"ipBlock"`
+					} `json:"from"`
+				} `json:"ingress"`
+				Template struct {
+					Spec struct {
+						Containers []struct {
+							Resources struct {
+								Limits map[string]string `json:"limits"`
+							} `json:"resources"`
+							SecurityContext struct {
+								AllowPrivilegeEscalation *bool `json:"allowPrivilegeEscalation"`
+							} `json:"securityContext"`
+						} `json:"containers"`
+						Volumes []struct {
+							EmptyDir struct {
+								SizeLimit string `json:"sizeLimit"`
+							} `json:"emptyDir"`
+						} `json:"volumes"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	r.NoError(json.Unmarshal(k.stdin, &manifest))
+	r.Len(manifest.Items, 3)
+
+	deployment := manifest.Items[0]
+	r.Equal("Deployment", deployment.Kind)
+	limits := deployment.Spec.Template.Spec.Containers[0].Resources.Limits
+	r.Equal("500m", limits["cpu"])
+	r.Equal("1024", limits["memory"])
+	r.Equal("1", limits[kubernetesGPU])
+	r.False(*deployment.Spec.Template.Spec.Containers[0].SecurityContext.AllowPrivilegeEscalation)
+	r.Equal("67108864", deployment.Spec.Template.Spec.Volumes[0].EmptyDir.SizeLimit)
+
+	service := manifest.Items[1]
+	r.Equal("Service", service.Kind)
+	r.Equal(50052, service.Spec.Ports[0].Port)
+
+	// only the scanner on the node host can reach the agent
+	networkPolicy := manifest.Items[2]
+	r.Equal("NetworkPolicy", networkPolicy.Kind)
+	r.Equal("10.0.0.5/32", networkPolicy.Spec.Ingress[0].From[0].IPBlock.CIDR)
+}
+
+func TestKubernetesGetContainerStats(t *testing.T) {
+	r := require.New(t)
+
+	cli := testKubernetesClient(&testKubectl{stdout: map[string]string{
+		"top": "forta-agent-1-7d9c5b6f4-x2x9q   125m   10Mi\n",
+	}})
+	stats, err := cli.GetContainerStats(context.Background(), "forta-agent-1")
+	r.NoError(err)
+	r.Equal(12.5, stats.CPUPercent)
+	r.Equal(int64(10*1024*1024), stats.MemoryUsage)
+}
@@ -0,0 +1,217 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+	"github.com/goccy/go-json"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultAckedSubjects are the critical subjects which are published with an ack if no subjects are
+// configured.
+var DefaultAckedSubjects = []string{SubjectAgentsActionRun, SubjectAgentsActionStop, SubjectAgentsVersionsLatest}
+
+// AckInboxHeader is the header of an acked message which has the inbox that the consumer acks to.
+const AckInboxHeader = "Forta-Ack-Inbox"
+
+// ackInboxPrefix is the prefix of the ack inboxes. The inboxes are not under the subjects which the
+// streams keep, so the stream publish acks are never taken as the acks of the consumers.
+const ackInboxPrefix = "_FORTA_ACK"
+
+// ackPublishAttempts is how many times an acked message is published before the publisher gives up.
+// The timeout of an acked publish is shared by the attempts.
+var ackPublishAttempts = 3
+
+// Acked publish errors
+var (
+	ErrNoSubscribers = errors.New("no subscribers")
+	ErrAckTimeout    = errors.New("timed out waiting for the ack")
+)
+
+// AckedPublisher publishes the messages and waits until a subscriber handles them.
+type AckedPublisher interface {
+	PublishAcked(subject string, payload interface{}, timeout time.Duration) error
+}
+
+// ackedHandler is the handler of the consumer which acks the messages.
+type ackedHandler struct {
+	handler interface{}
+}
+
+// WithAck marks the handler as the consumer which acks the acked messages of the subject. The other
+// subscribers of the subject receive the same messages but never ack them.
+func WithAck(handler interface{}) interface{} {
+	return &ackedHandler{handler: handler}
+}
+
+// ackInbox returns a new inbox for the ack of a message of the subject.
+func ackInbox(subject string) string {
+	return fmt.Sprintf("%s.%s.%s", ackInboxPrefix, subject, strings.TrimPrefix(nats.NewInbox(), nats.InboxPrefix))
+}
+
+// ackPayload is the reply of a subscriber to an acked message.
+type ackPayload struct {
+	Error string `json:"error,omitempty"`
+}
+
+func newAck(err error) []byte {
+	var ack ackPayload
+	if err != nil {
+		ack.Error = err.Error()
+	}
+	b, _ := json.Marshal(&ack)
+	return b
+}
+
+func decodeAck(data []byte) error {
+	var ack ackPayload
+	if err := json.Unmarshal(data, &ack); err != nil {
+		return fmt.Errorf("invalid ack: %v", err)
+	}
+	if len(ack.Error) > 0 {
+		return fmt.Errorf("subscriber failed to handle the msg: %s", ack.Error)
+	}
+	return nil
+}
+
+// IsAckedSubject tells if the subject should be published with an ack.
+func IsAckedSubject(cfg config.AckedPublishConfig, subject string) bool {
+	subjects := cfg.Subjects
+	if len(subjects) == 0 {
+		subjects = DefaultAckedSubjects
+	}
+	for _, ackedSubject := range subjects {
+		if ackedSubject == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishCritical publishes the message with an ack if the acks are enabled for the subject, so that the
+// publisher waits while the subscriber is busy. It is a plain publish otherwise or if the client cannot
+// wait for the acks.
+func PublishCritical(client clients.MessageClient, cfg config.AckedPublishConfig, subject string, payload interface{}) error {
+	publisher, ok := client.(AckedPublisher)
+	if !cfg.Enable || !ok || !IsAckedSubject(cfg, subject) {
+		client.Publish(subject, payload)
+		return nil
+	}
+	return publisher.PublishAcked(subject, payload, time.Duration(cfg.TimeoutSeconds)*time.Second)
+}
+
+// PublishAcked publishes the message with an ack inbox and waits for the ack of the consumer which was
+// subscribed WithAck. The other subscribers and the streams which keep the subject do not ack. The
+// message is published again if the ack times out, so the acked consumers must handle the duplicates.
+func (client *Client) PublishAcked(subject string, payload interface{}, timeout time.Duration) error {
+	logger := client.logger.WithField("subject", subject)
+	data, _ := json.Marshal(payload)
+	logger.Debugf("publishing with ack: %s", string(data))
+	attemptTimeout := timeout / time.Duration(ackPublishAttempts)
+	var err error
+	for attempt := 1; attempt <= ackPublishAttempts; attempt++ {
+		err = client.publishAckedOnce(subject, data, attemptTimeout)
+		if err != ErrAckTimeout {
+			return err
+		}
+		logger.WithField("attempt", attempt).Warn("timed out waiting for the ack")
+	}
+	return err
+}
+
+func (client *Client) publishAckedOnce(subject string, data []byte, timeout time.Duration) error {
+	inbox := ackInbox(subject)
+	sub, err := client.nc.SubscribeSync(inbox)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to the ack inbox: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	header := versionHeader(subject)
+	header.Set(AckInboxHeader, inbox)
+	if err := client.nc.PublishMsg(&nats.Msg{
+		Subject: subject,
+		Header:  header,
+		Data:    data,
+	}); err != nil {
+		return fmt.Errorf("failed to publish msg: %v", err)
+	}
+	msg, err := sub.NextMsg(timeout)
+	switch {
+	case err == nats.ErrTimeout:
+		return ErrAckTimeout
+	case err != nil:
+		return fmt.Errorf("failed to receive the ack: %v", err)
+	}
+	return decodeAck(msg.Data)
+}
+
+// ack sends the result of handling the message to the ack inbox of the message if it has one.
+func (client *Client) ack(logger *log.Entry, m *nats.Msg, err error) {
+	inbox := m.Header.Get(AckInboxHeader)
+	if len(inbox) == 0 {
+		return
+	}
+	if err := client.nc.Publish(inbox, newAck(err)); err != nil {
+		logger.Errorf("failed to send ack: %v", err)
+	}
+}
+
+// PublishAcked delivers the message to the subscribers and waits for the ack of the consumer which was
+// subscribed WithAck. Unlike the plain publish, it waits while the acked consumer has too many pending
+// messages.
+func (client *LocalClient) PublishAcked(subject string, payload interface{}, timeout time.Duration) error {
+	logger := client.logger.WithField("subject", subject)
+	data, _ := json.Marshal(payload)
+	logger.Debugf("publishing with ack: %s", string(data))
+	return client.bus.publishAcked(subject, data, timeout)
+}
+
+func (bus *LocalBus) publishAcked(subject string, data []byte, timeout time.Duration) error {
+	bus.mu.RLock()
+	subs := append([]*localSubscription{}, bus.subs[subject]...)
+	bus.mu.RUnlock()
+	var ackedSubs int
+	for _, sub := range subs {
+		if sub.acks {
+			ackedSubs++
+		}
+	}
+	if ackedSubs == 0 {
+		return ErrNoSubscribers
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	acks := make(chan error, ackedSubs)
+	for _, sub := range subs {
+		if !sub.acks {
+			select {
+			case sub.msgs <- &localMessage{version: SchemaVersion(subject), data: data}:
+			default:
+				sub.logger.Error("failed to publish msg: slow subscriber")
+			}
+			continue
+		}
+		select {
+		case sub.msgs <- &localMessage{version: SchemaVersion(subject), data: data, acks: acks}:
+		case <-timer.C:
+			return ErrAckTimeout
+		}
+	}
+	select {
+	case err := <-acks:
+		if err != nil {
+			return fmt.Errorf("subscriber failed to handle the msg: %v", err)
+		}
+		return nil
+	case <-timer.C:
+		return ErrAckTimeout
+	}
+}
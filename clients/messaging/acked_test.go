@@ -0,0 +1,122 @@
+package messaging
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/require"
+)
+
+func testAckedPublish(t *testing.T, publisher AckedPublisher, subscriber interface {
+	Subscribe(subject string, handler interface{})
+}) {
+	r := require.New(t)
+
+	// the other subscribers of the subject do not ack
+	subscriber.Subscribe(SubjectAgentsActionRun, AgentsHandler(func(payload AgentPayload) error {
+		return nil
+	}))
+	release := make(chan struct{})
+	subscriber.Subscribe(SubjectAgentsActionRun, WithAck(AgentsHandler(func(payload AgentPayload) error {
+		<-release
+		if payload[0].ID == "0x2" {
+			return errors.New("failed")
+		}
+		return nil
+	})))
+
+	// the publisher waits until the subscriber handles the message
+	done := make(chan error, 1)
+	go func() {
+		done <- publisher.PublishAcked(SubjectAgentsActionRun, AgentPayload{{ID: "0x1"}}, time.Second*5)
+	}()
+	select {
+	case <-done:
+		r.FailNow("publish did not wait for the ack")
+	case <-time.After(time.Millisecond * 100):
+	}
+	close(release)
+	r.NoError(<-done)
+
+	r.Error(publisher.PublishAcked(SubjectAgentsActionRun, AgentPayload{{ID: "0x2"}}, time.Second*5))
+}
+
+func noRetryDelay(t *testing.T) {
+	retryDelay := handleRetryDelay
+	handleRetryDelay = 0
+	t.Cleanup(func() {
+		handleRetryDelay = retryDelay
+	})
+}
+
+func TestPublishAcked_NATS(t *testing.T) {
+	noRetryDelay(t)
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	require.NoError(t, err)
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+	defer srv.Shutdown()
+
+	publisher := NewClient("publisher", srv.ClientURL())
+	subscriber := NewClient("subscriber", srv.ClientURL())
+	testAckedPublish(t, publisher, subscriber)
+
+	// a subscriber which does not ack and the stream which keeps the subject are not taken as the acks
+	r := require.New(t)
+	r.NoError(subscriber.EnsureLifecycleStream(time.Hour))
+	subscriber.Subscribe(SubjectAgentsActionStop, AgentsHandler(func(payload AgentPayload) error {
+		return nil
+	}))
+	r.Equal(ErrAckTimeout, publisher.PublishAcked(SubjectAgentsActionStop, AgentPayload{{ID: "0x1"}}, time.Millisecond*300))
+
+	// the message is published again after an ack timeout
+	var attempts int32
+	subscriber.Subscribe(SubjectAgentsVersionsLatest, WithAck(AgentsHandler(func(payload AgentPayload) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			time.Sleep(time.Millisecond * 500)
+		}
+		return nil
+	})))
+	r.NoError(publisher.PublishAcked(SubjectAgentsVersionsLatest, AgentPayload{{ID: "0x1"}}, time.Millisecond*900))
+	r.Equal(int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestPublishAcked_Local(t *testing.T) {
+	noRetryDelay(t)
+	bus := NewLocalBus()
+	testAckedPublish(t, bus.NewClient("publisher"), bus.NewClient("subscriber"))
+
+	r := require.New(t)
+	r.Equal(ErrNoSubscribers, bus.NewClient("publisher").PublishAcked(SubjectAgentsActionStop, AgentPayload{{ID: "0x1"}}, time.Second))
+
+	// the acked publish times out instead of dropping the message when the subscriber is busy
+	block := make(chan struct{})
+	defer close(block)
+	publisher := bus.NewClient("publisher")
+	publisher.Subscribe(SubjectScannerBlock, WithAck(ScannerHandler(func(payload ScannerPayload) error {
+		<-block
+		return nil
+	})))
+	r.Equal(ErrAckTimeout, publisher.PublishAcked(SubjectScannerBlock, &ScannerPayload{}, time.Millisecond*100))
+}
+
+func TestPublishCritical(t *testing.T) {
+	r := require.New(t)
+
+	bus := NewLocalBus()
+	publisher := bus.NewClient("publisher")
+	cfg := config.AckedPublishConfig{Enable: true, TimeoutSeconds: 1}
+
+	// the critical subjects need a subscriber and the others do not
+	r.Equal(ErrNoSubscribers, PublishCritical(publisher, cfg, SubjectAgentsActionRun, AgentPayload{}))
+	r.NoError(PublishCritical(publisher, cfg, SubjectScannerBlock, &ScannerPayload{}))
+	cfg.Subjects = []string{SubjectScannerBlock}
+	r.NoError(PublishCritical(publisher, cfg, SubjectAgentsActionRun, AgentPayload{}))
+	r.Equal(ErrNoSubscribers, PublishCritical(publisher, cfg, SubjectScannerBlock, &ScannerPayload{}))
+	cfg.Enable = false
+	r.NoError(PublishCritical(publisher, cfg, SubjectScannerBlock, &ScannerPayload{}))
+}
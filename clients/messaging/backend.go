@@ -0,0 +1,21 @@
+package messaging
+
+import (
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+)
+
+// Message bus backends
+const (
+	BackendNATS  = "nats"
+	BackendLocal = "local"
+)
+
+// NewBackendClient creates a client of the configured backend. The local backend connects only the clients
+// in the same process and the NATS URL is not used with it.
+func NewBackendClient(name string, cfg config.MessagingConfig, natsURL string) clients.MessageClient {
+	if cfg.Backend == BackendLocal {
+		return NewLocalClient(name)
+	}
+	return NewClient(name, natsURL)
+}
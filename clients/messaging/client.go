@@ -1,6 +1,7 @@
 package messaging
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -16,8 +17,10 @@ var (
 	BufferSize = 1000
 )
 
-// Client wraps the NATS client to publish and receive our messages.
+// Client wraps the NATS client to publish and receive our messages. It is the default message bus
+// backend.
 type Client struct {
+	name   string
 	logger *log.Entry
 	nc     *nats.Conn
 }
@@ -43,6 +46,7 @@ func NewClient(name, natsURL string) *Client {
 	}
 	logger.Info("successfully connected")
 	client := &Client{
+		name:   name,
 		logger: logger,
 		nc:     nc,
 	}
@@ -51,64 +55,127 @@ func NewClient(name, natsURL string) *Client {
 
 // AgentsHandler handles agents.* subjects.
 type AgentsHandler func(AgentPayload) error
+type AgentFailedHandler func(AgentFailedPayload) error
+type AgentRejectedHandler func(AgentRejectedPayload) error
 type AgentMetricHandler func(*protocol.AgentMetricList) error
 type ScannerHandler func(ScannerPayload) error
+type DeadLetterHandler func(DeadLetterPayload) error
 
-// Subscribe subscribes the consumer to this client.
-func (client *Client) Subscribe(subject string, handler interface{}) {
-	// TODO: Configure redelivery options somehow.
-	logger := client.logger.WithField("subject", subject)
-	_, err := client.nc.Subscribe(subject, func(m *nats.Msg) {
-		logger.Debugf("received: %s", string(m.Data))
-
-		var err error
-		switch h := handler.(type) {
-		case AgentsHandler:
-			var payload AgentPayload
-			err = json.Unmarshal(m.Data, &payload)
-			if err != nil {
-				break
-			}
-			err = h(payload)
+var errNoHandler = errors.New("no handler found")
 
-		case AgentMetricHandler:
-			var payload protocol.AgentMetricList
-			err = proto.Unmarshal(m.Data, &payload)
-			if err != nil {
-				break
-			}
-			err = h(&payload)
+// handleMessage decodes the message for the handler type and calls the handler.
+func handleMessage(handler interface{}, data []byte) error {
+	switch h := handler.(type) {
+	case AgentsHandler:
+		var payload AgentPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return &decodeError{err}
+		}
+		return h(payload)
 
-		case ScannerHandler:
-			var payload ScannerPayload
-			err = json.Unmarshal(m.Data, &payload)
-			if err != nil {
-				break
-			}
-			err = h(payload)
+	case AgentFailedHandler:
+		var payload AgentFailedPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return &decodeError{err}
+		}
+		return h(payload)
 
-		default:
-			logger.Panicf("no handler found")
+	case AgentRejectedHandler:
+		var payload AgentRejectedPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return &decodeError{err}
 		}
+		return h(payload)
 
-		if err != nil {
-			if err := m.Nak(); err != nil {
-				logger.Errorf("failed to send nak: %v", err)
+	case AgentMetricHandler:
+		var payload protocol.AgentMetricList
+		if err := proto.Unmarshal(data, &payload); err != nil {
+			return &decodeError{err}
+		}
+		return h(&payload)
+
+	case ScannerHandler:
+		var payload ScannerPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return &decodeError{err}
+		}
+		return h(payload)
+
+	case DeadLetterHandler:
+		var payload DeadLetterPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return &decodeError{err}
+		}
+		return h(payload)
+
+	default:
+		return errNoHandler
+	}
+}
+
+// Subscribe subscribes the consumer to this client. The messages which the consumer fails to handle
+// are moved to the dead-letter subject, after a few attempts if the consumer is Idempotent. The consumers
+// which are subscribed WithAck ack the acked messages.
+// The messages are handled in order by a worker of the subscription so that the retries never block the
+// NATS callback. Like a NATS slow consumer, a subscriber which has too many pending messages misses
+// the message.
+func (client *Client) Subscribe(subject string, handler interface{}) {
+	logger := client.logger.WithField("subject", subject)
+	handler, acks, retries := unwrapHandler(handler)
+	switch handler.(type) {
+	case AgentsHandler, AgentFailedHandler, AgentRejectedHandler, AgentMetricHandler, ScannerHandler, DeadLetterHandler:
+	default:
+		logger.Panicf("no handler found")
+	}
+	msgs := make(chan *nats.Msg, BufferSize)
+	_, err := client.nc.Subscribe(subject, func(m *nats.Msg) {
+		select {
+		case msgs <- m:
+		default:
+			logger.Error("failed to handle msg: slow subscriber")
+			if acks {
+				client.ack(logger, m, errors.New("slow subscriber"))
 			}
-			logger.Errorf("failed to handle msg: %v", err)
 		}
 	})
 	if err != nil {
 		logger.Panicf("failed to subscribe: %v", err)
 	}
+	go func() {
+		for m := range msgs {
+			client.handleMsg(logger, subject, handler, acks, retries, m)
+		}
+	}()
 	logger.Info("subscribed")
 }
 
+func (client *Client) handleMsg(logger *log.Entry, subject string, handler interface{}, acks, retries bool, m *nats.Msg) {
+	logger.Debugf("received: %s", string(m.Data))
+
+	version, err := msgSchemaVersion(m)
+	attempts := 1
+	if err == nil {
+		attempts, err = handleVersioned(logger, handler, subject, version, m.Data, handleAttempts(retries))
+	}
+	if acks {
+		client.ack(logger, m, err)
+	}
+	if err != nil {
+		client.deadLetter(logger, subject, version, m.Data, attempts, err)
+	}
+}
+
+func (client *Client) deadLetter(logger *log.Entry, subject string, version int, data []byte, attempts int, err error) {
+	if deadLetter, ok := newDeadLetter(logger, client.name, subject, version, data, attempts, err); ok {
+		client.Publish(SubjectMessagingDeadLetter, deadLetter)
+	}
+}
+
 // Publish publishes new messages.
 func (client *Client) Publish(subject string, payload interface{}) {
 	logger := client.logger.WithField("subject", subject)
 	data, _ := json.Marshal(payload)
-	if err := client.nc.Publish(subject, data); err != nil {
+	if err := client.publish(subject, data); err != nil {
 		logger.Errorf("failed to publish msg: %v", err)
 	}
 	logger.Debugf("published: %s", string(data))
@@ -118,8 +185,17 @@ func (client *Client) Publish(subject string, payload interface{}) {
 func (client *Client) PublishProto(subject string, payload proto.Message) {
 	logger := client.logger.WithField("subject", subject)
 	data, _ := proto.Marshal(payload)
-	if err := client.nc.Publish(subject, data); err != nil {
+	if err := client.publish(subject, data); err != nil {
 		logger.Errorf("failed to publish msg: %v", err)
 	}
 	logger.Debugf("published: %s", string(data))
 }
+
+// publish publishes the data with the schema version of the subject.
+func (client *Client) publish(subject string, data []byte) error {
+	return client.nc.PublishMsg(&nats.Msg{
+		Subject: subject,
+		Header:  versionHeader(subject),
+		Data:    data,
+	})
+}
@@ -0,0 +1,75 @@
+package messaging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSubscribe(t *testing.T) {
+	r := require.New(t)
+
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1})
+	r.NoError(err)
+	go srv.Start()
+	r.True(srv.ReadyForConnections(5 * time.Second))
+	defer srv.Shutdown()
+
+	client := NewClient("test", srv.ClientURL())
+	deadLetters := make(chan DeadLetterPayload, 10)
+	client.Subscribe(SubjectMessagingDeadLetter, DeadLetterHandler(func(payload DeadLetterPayload) error {
+		deadLetters <- payload
+		return nil
+	}))
+
+	// the handlers which are not idempotent are not retried
+	blocks := make(chan ScannerPayload, 10)
+	client.Subscribe(SubjectScannerBlock, ScannerHandler(func(payload ScannerPayload) error {
+		blocks <- payload
+		return errors.New("failed")
+	}))
+	client.Publish(SubjectScannerBlock, &ScannerPayload{LatestBlockInput: 1})
+	select {
+	case deadLetter := <-deadLetters:
+		r.Equal(SubjectScannerBlock, deadLetter.Subject)
+		r.Equal(1, deadLetter.Attempts)
+	case <-time.After(time.Second):
+		r.FailNow("dead letter not received")
+	}
+	r.Len(blocks, 1)
+
+	// the idempotent handlers are retried by the worker while the next messages are queued
+	release := make(chan struct{})
+	metrics := make(chan string, 10)
+	client.Subscribe(SubjectMetricAgent, Idempotent(AgentMetricHandler(func(payload *protocol.AgentMetricList) error {
+		<-release
+		metrics <- payload.Metrics[0].AgentId
+		if payload.Metrics[0].AgentId == "0x1" {
+			return errors.New("failed")
+		}
+		return nil
+	})))
+	client.PublishProto(SubjectMetricAgent, &protocol.AgentMetricList{Metrics: []*protocol.AgentMetric{{AgentId: "0x1"}}})
+	client.PublishProto(SubjectMetricAgent, &protocol.AgentMetricList{Metrics: []*protocol.AgentMetric{{AgentId: "0x2"}}})
+	close(release)
+	select {
+	case deadLetter := <-deadLetters:
+		r.Equal(SubjectMetricAgent, deadLetter.Subject)
+		r.Equal(MaxHandleAttempts, deadLetter.Attempts)
+	case <-time.After(time.Second * 2):
+		r.FailNow("dead letter not received")
+	}
+	for i := 0; i < MaxHandleAttempts; i++ {
+		r.Equal("0x1", <-metrics)
+	}
+	select {
+	case agentID := <-metrics:
+		r.Equal("0x2", agentID)
+	case <-time.After(time.Second):
+		r.FailNow("next msg not handled")
+	}
+}
@@ -0,0 +1,86 @@
+package messaging
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxHandleAttempts is how many times a subscriber tries to handle a message before the message is moved
+// to the dead-letter subject. Only the idempotent handlers are retried.
+var MaxHandleAttempts = 3
+
+var handleRetryDelay = time.Millisecond * 200
+
+// idempotentHandler is the handler which can handle the same message more than once.
+type idempotentHandler struct {
+	handler interface{}
+}
+
+// Idempotent marks the handler as safe to retry. The messages which the other handlers fail to handle are
+// moved to the dead-letter subject after the first attempt.
+func Idempotent(handler interface{}) interface{} {
+	return &idempotentHandler{handler: handler}
+}
+
+// unwrapHandler returns the handler and tells if it acks the messages and if it can be retried.
+func unwrapHandler(handler interface{}) (h interface{}, acks bool, retries bool) {
+	for {
+		switch wrapped := handler.(type) {
+		case *ackedHandler:
+			handler, acks = wrapped.handler, true
+		case *idempotentHandler:
+			handler, retries = wrapped.handler, true
+		case *latestOnlyHandler:
+			handler = wrapped.handler
+		default:
+			return handler, acks, retries
+		}
+	}
+}
+
+// handleAttempts is how many times a handler tries to handle a message.
+func handleAttempts(retries bool) int {
+	if retries {
+		return MaxHandleAttempts
+	}
+	return 1
+}
+
+// handleWithRetry handles the message until it succeeds or the attempts run out. It returns the number of
+// attempts and the last error. The payloads which cannot be decoded are not retried.
+func handleWithRetry(logger *log.Entry, handler interface{}, data []byte, maxAttempts int) (int, error) {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = handleMessage(handler, data)
+		if _, ok := err.(*decodeError); ok || err == nil || err == errNoHandler {
+			return attempt, err
+		}
+		logger.WithField("attempt", attempt).Errorf("failed to handle msg: %v", err)
+		if attempt < maxAttempts {
+			time.Sleep(handleRetryDelay)
+		}
+	}
+	return maxAttempts, err
+}
+
+// newDeadLetter creates the dead letter of a message which the subscriber failed to handle. The dead
+// letters which cannot be handled are only logged so that they never loop.
+func newDeadLetter(
+	logger *log.Entry, clientName, subject string, version int, data []byte, attempts int, err error,
+) (*DeadLetterPayload, bool) {
+	if subject == SubjectMessagingDeadLetter {
+		logger.Errorf("dropped the dead letter after %d attempts: %v", attempts, err)
+		return nil, false
+	}
+	logger.WithField("attempts", attempts).Warnf("moving the msg to the dead letters: %v", err)
+	return &DeadLetterPayload{
+		Subject:       subject,
+		SchemaVersion: version,
+		Client:        clientName,
+		Error:         err.Error(),
+		Attempts:      attempts,
+		Data:          data,
+		Timestamp:     time.Now().UTC(),
+	}, true
+}
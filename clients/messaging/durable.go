@@ -0,0 +1,172 @@
+package messaging
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// LifecycleStream is the JetStream stream which keeps the agent lifecycle messages so that the
+// subscribers can replay the messages which they missed while they were down.
+const LifecycleStream = "AGENT_LIFECYCLE"
+
+// lifecycleMaxDeliver limits the redeliveries of a message which cannot be handled.
+const lifecycleMaxDeliver = 10
+
+// lifecycleAckWait is how long a delivered message waits for the ack before it is redelivered. The
+// messages which were delivered to a subscriber which went down are replayed after this.
+var lifecycleAckWait = time.Second * 30
+
+// LifecycleSubjects are the subjects which the lifecycle stream keeps.
+var LifecycleSubjects = []string{SubjectAgentsActionRun, SubjectAgentsActionStop, SubjectAgentsVersionsLatest}
+
+// latestOnlyHandler is the handler which needs only the latest message of the subject.
+type latestOnlyHandler struct {
+	handler interface{}
+}
+
+// LatestOnly marks the handler of a durable subscription as the handler of the latest state, like the
+// latest agent list. The messages are versioned by their stream sequence and a message which is older
+// than the last handled message, like a redelivered message, is dropped.
+func LatestOnly(handler interface{}) interface{} {
+	return &latestOnlyHandler{handler: handler}
+}
+
+func isLatestOnly(handler interface{}) bool {
+	for {
+		switch wrapped := handler.(type) {
+		case *latestOnlyHandler:
+			return true
+		case *ackedHandler:
+			handler = wrapped.handler
+		case *idempotentHandler:
+			handler = wrapped.handler
+		default:
+			return false
+		}
+	}
+}
+
+// EnsureLifecycleStream creates the lifecycle stream if it does not exist and adds the missing lifecycle
+// subjects to an existing stream. The messages which are published to the lifecycle subjects are kept on
+// the stream until they are older than the max age.
+func (client *Client) EnsureLifecycleStream(maxAge time.Duration) error {
+	js, err := client.nc.JetStream()
+	if err != nil {
+		return err
+	}
+	streamCfg := &nats.StreamConfig{
+		Name:     LifecycleStream,
+		Subjects: LifecycleSubjects,
+		Storage:  nats.FileStorage,
+		MaxAge:   maxAge,
+	}
+	if info, err := js.StreamInfo(LifecycleStream); err == nil {
+		if hasSubjects(info.Config.Subjects, LifecycleSubjects) {
+			return nil
+		}
+		if _, err := js.UpdateStream(streamCfg); err != nil {
+			return fmt.Errorf("failed to update the lifecycle stream: %v", err)
+		}
+		client.logger.WithField("stream", LifecycleStream).Info("updated the lifecycle stream subjects")
+		return nil
+	}
+	if _, err = js.AddStream(streamCfg); err != nil {
+		return fmt.Errorf("failed to create the lifecycle stream: %v", err)
+	}
+	client.logger.WithField("stream", LifecycleStream).Info("created the lifecycle stream")
+	return nil
+}
+
+func hasSubjects(subjects, required []string) bool {
+	found := make(map[string]bool)
+	for _, subject := range subjects {
+		found[subject] = true
+	}
+	for _, subject := range required {
+		if !found[subject] {
+			return false
+		}
+	}
+	return true
+}
+
+// SubscribeDurable subscribes the consumer to the subject on the stream with a durable consumer. The
+// consumer continues from the last acknowledged message after a restart, so the messages which were
+// published in the meantime are replayed. A new consumer receives only the new messages.
+// The messages are acknowledged after they are handled and the failed messages are redelivered a few
+// times before they are moved to the dead-letter subject. The consumers which are subscribed WithAck ack
+// the acked messages, also when the messages are redelivered. The LatestOnly consumers skip the messages
+// which are older than the last handled message.
+func (client *Client) SubscribeDurable(subject, durable string, handler interface{}) {
+	logger := client.logger.WithField("subject", subject).WithField("durable", durable)
+	latestOnly := isLatestOnly(handler)
+	handler, acks, _ := unwrapHandler(handler)
+	js, err := client.nc.JetStream()
+	if err != nil {
+		logger.Panicf("failed to get jetstream context: %v", err)
+	}
+	var lastHandledSeq uint64
+	_, err = js.Subscribe(subject, func(m *nats.Msg) {
+		logger.Debugf("received: %s", string(m.Data))
+
+		var seq uint64
+		if md, err := m.Metadata(); err == nil {
+			seq = md.Sequence.Stream
+		}
+		if latestOnly && seq > 0 && seq <= lastHandledSeq {
+			logger.WithField("sequence", seq).Info("dropping the msg which is older than the last handled msg")
+			if acks {
+				client.ack(logger, m, nil)
+			}
+			if err := m.Ack(); err != nil {
+				logger.Errorf("failed to send ack: %v", err)
+			}
+			return
+		}
+
+		version, err := msgSchemaVersion(m)
+		if err == nil {
+			err = handleVersionedOnce(logger, handler, subject, version, m.Data)
+		}
+		if err == errNoHandler {
+			logger.Panicf("no handler found")
+		}
+		if acks {
+			client.ack(logger, m, err)
+		}
+		if _, ok := err.(*SchemaError); ok {
+			client.deadLetter(logger, subject, version, m.Data, 1, err)
+			if err := m.Term(); err != nil {
+				logger.Errorf("failed to terminate the msg: %v", err)
+			}
+			return
+		}
+		if err != nil {
+			logger.Errorf("failed to handle msg: %v", err)
+			if md, mdErr := m.Metadata(); mdErr == nil && md.NumDelivered >= lifecycleMaxDeliver {
+				client.deadLetter(logger, subject, version, m.Data, int(md.NumDelivered), err)
+				if err := m.Term(); err != nil {
+					logger.Errorf("failed to terminate the msg: %v", err)
+				}
+				return
+			}
+			if err := m.Nak(); err != nil {
+				logger.Errorf("failed to send nak: %v", err)
+			}
+			return
+		}
+		if seq > lastHandledSeq {
+			lastHandledSeq = seq
+		}
+		if err := m.Ack(); err != nil {
+			logger.Errorf("failed to send ack: %v", err)
+		}
+	}, nats.Durable(durable), nats.ManualAck(), nats.AckExplicit(), nats.DeliverNew(),
+		nats.AckWait(lifecycleAckWait), nats.MaxDeliver(lifecycleMaxDeliver))
+	if err != nil {
+		logger.Panicf("failed to subscribe: %v", err)
+	}
+	logger.Info("subscribed")
+}
@@ -0,0 +1,141 @@
+package messaging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeDurable(t *testing.T) {
+	r := require.New(t)
+
+	ackWait := lifecycleAckWait
+	lifecycleAckWait = time.Millisecond * 200
+	defer func() {
+		lifecycleAckWait = ackWait
+	}()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	r.NoError(err)
+	go srv.Start()
+	r.True(srv.ReadyForConnections(5 * time.Second))
+	defer srv.Shutdown()
+
+	publisher := NewClient("publisher", srv.ClientURL())
+	r.NoError(publisher.EnsureLifecycleStream(time.Hour))
+	r.NoError(publisher.EnsureLifecycleStream(time.Hour))
+
+	subscriber := NewClient("subscriber", srv.ClientURL())
+	stopCh := make(chan AgentPayload, 10)
+	failed := false
+	subscriber.SubscribeDurable(SubjectAgentsActionStop, "test-stop", AgentsHandler(func(payload AgentPayload) error {
+		// fails once to get the message redelivered
+		if payload[0].ID == "0x2" && !failed {
+			failed = true
+			return errors.New("failed")
+		}
+		stopCh <- payload
+		return nil
+	}))
+
+	publisher.Publish(SubjectAgentsActionStop, AgentPayload{{ID: "0x1"}})
+	r.Equal("0x1", receive(t, stopCh)[0].ID)
+	publisher.Publish(SubjectAgentsActionStop, AgentPayload{{ID: "0x2"}})
+	r.Equal("0x2", receive(t, stopCh)[0].ID)
+	r.True(failed)
+
+	// the messages which were published while the subscriber was down are replayed
+	subscriber.nc.Close()
+	publisher.Publish(SubjectAgentsActionStop, AgentPayload{{ID: "0x3"}})
+	publisher.Publish(SubjectAgentsActionStop, AgentPayload{{ID: "0x4"}})
+
+	restarted := NewClient("subscriber", srv.ClientURL())
+	restarted.SubscribeDurable(SubjectAgentsActionStop, "test-stop", AgentsHandler(func(payload AgentPayload) error {
+		stopCh <- payload
+		return nil
+	}))
+	replayed := map[string]bool{
+		receive(t, stopCh)[0].ID: true,
+		receive(t, stopCh)[0].ID: true,
+	}
+	r.Equal(map[string]bool{"0x3": true, "0x4": true}, replayed)
+}
+
+func TestSubscribeDurable_LatestOnly(t *testing.T) {
+	r := require.New(t)
+
+	ackWait := lifecycleAckWait
+	lifecycleAckWait = time.Millisecond * 200
+	defer func() {
+		lifecycleAckWait = ackWait
+	}()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	r.NoError(err)
+	go srv.Start()
+	r.True(srv.ReadyForConnections(5 * time.Second))
+	defer srv.Shutdown()
+
+	publisher := NewClient("publisher", srv.ClientURL())
+	r.NoError(publisher.EnsureLifecycleStream(time.Hour))
+
+	subscriber := NewClient("subscriber", srv.ClientURL())
+	versionsCh := make(chan AgentPayload, 10)
+	failed := false
+	subscriber.SubscribeDurable(SubjectAgentsVersionsLatest, "test-versions", LatestOnly(AgentsHandler(func(payload AgentPayload) error {
+		// the older list fails once and it is redelivered after the newer list
+		if len(payload) == 1 && !failed {
+			failed = true
+			return errors.New("failed")
+		}
+		versionsCh <- payload
+		return nil
+	})))
+
+	publisher.Publish(SubjectAgentsVersionsLatest, AgentPayload{{ID: "0x1"}})
+	publisher.Publish(SubjectAgentsVersionsLatest, AgentPayload{{ID: "0x1"}, {ID: "0x2"}})
+	r.Len(receive(t, versionsCh), 2)
+	time.Sleep(lifecycleAckWait * 3)
+	r.True(failed)
+	r.Len(versionsCh, 0)
+}
+
+func TestEnsureLifecycleStream_AddSubjects(t *testing.T) {
+	r := require.New(t)
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	r.NoError(err)
+	go srv.Start()
+	r.True(srv.ReadyForConnections(5 * time.Second))
+	defer srv.Shutdown()
+
+	client := NewClient("test", srv.ClientURL())
+	js, err := client.nc.JetStream()
+	r.NoError(err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: LifecycleStream, Subjects: []string{SubjectAgentsActionStop}})
+	r.NoError(err)
+
+	r.NoError(client.EnsureLifecycleStream(time.Hour))
+	info, err := js.StreamInfo(LifecycleStream)
+	r.NoError(err)
+	r.ElementsMatch(LifecycleSubjects, info.Config.Subjects)
+}
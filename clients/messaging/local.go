@@ -0,0 +1,142 @@
+package messaging
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// LocalBus is the in-process message bus. The messages are encoded like they are on NATS so that the
+// subscribers never share the payloads with the publishers.
+type LocalBus struct {
+	subs map[string][]*localSubscription
+	mu   sync.RWMutex
+}
+
+type localSubscription struct {
+	client  *LocalClient
+	subject string
+	logger  *log.Entry
+	handler interface{}
+	acks    bool
+	retries bool
+	msgs    chan *localMessage
+}
+
+// localMessage is a published message. The acked messages have the channel which the subscribers send
+// the result to.
+type localMessage struct {
+	version int
+	data    []byte
+	acks    chan error
+}
+
+// processBus is the bus which connects the local clients in this process.
+var processBus = NewLocalBus()
+
+// NewLocalBus creates a new in-process message bus.
+func NewLocalBus() *LocalBus {
+	return &LocalBus{
+		subs: make(map[string][]*localSubscription),
+	}
+}
+
+func (bus *LocalBus) subscribe(sub *localSubscription, subject string) {
+	bus.mu.Lock()
+	bus.subs[subject] = append(bus.subs[subject], sub)
+	bus.mu.Unlock()
+
+	go func() {
+		for msg := range sub.msgs {
+			sub.logger.Debugf("received: %s", string(msg.data))
+			attempts, err := handleVersioned(sub.logger, sub.handler, sub.subject, msg.version, msg.data, handleAttempts(sub.retries))
+			if msg.acks != nil {
+				msg.acks <- err
+			}
+			if err == nil {
+				continue
+			}
+			deadLetter, ok := newDeadLetter(sub.logger, sub.client.name, sub.subject, msg.version, msg.data, attempts, err)
+			if ok {
+				sub.client.Publish(SubjectMessagingDeadLetter, deadLetter)
+			}
+		}
+	}()
+}
+
+// publish delivers the message to the subscribers. Like a NATS slow consumer, a subscriber which has
+// too many pending messages misses the message.
+func (bus *LocalBus) publish(logger *log.Entry, subject string, data []byte) {
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	for _, sub := range bus.subs[subject] {
+		select {
+		case sub.msgs <- &localMessage{version: SchemaVersion(subject), data: data}:
+		default:
+			logger.Error("failed to publish msg: slow subscriber")
+		}
+	}
+}
+
+// NewClient creates a new client of the bus.
+func (bus *LocalBus) NewClient(name string) *LocalClient {
+	return &LocalClient{
+		name:   name,
+		logger: log.WithField("name", fmt.Sprintf("%s/messaging", name)).WithField("bus", "local"),
+		bus:    bus,
+	}
+}
+
+// LocalClient publishes and receives the messages on an in-process bus.
+type LocalClient struct {
+	name   string
+	logger *log.Entry
+	bus    *LocalBus
+}
+
+// NewLocalClient creates a new client which talks to the other local clients in this process.
+func NewLocalClient(name string) *LocalClient {
+	return processBus.NewClient(name)
+}
+
+// Subscribe subscribes the consumer to this client. The messages which the consumer fails to handle
+// are moved to the dead-letter subject, after a few attempts if the consumer is Idempotent. The consumers
+// which are subscribed WithAck ack the acked messages.
+func (client *LocalClient) Subscribe(subject string, handler interface{}) {
+	logger := client.logger.WithField("subject", subject)
+	handler, acks, retries := unwrapHandler(handler)
+	switch handler.(type) {
+	case AgentsHandler, AgentFailedHandler, AgentRejectedHandler, AgentMetricHandler, ScannerHandler, DeadLetterHandler:
+	default:
+		logger.Panicf("no handler found")
+	}
+	client.bus.subscribe(&localSubscription{
+		client:  client,
+		subject: subject,
+		logger:  logger,
+		handler: handler,
+		acks:    acks,
+		retries: retries,
+		msgs:    make(chan *localMessage, BufferSize),
+	}, subject)
+	logger.Info("subscribed")
+}
+
+// Publish publishes new messages.
+func (client *LocalClient) Publish(subject string, payload interface{}) {
+	logger := client.logger.WithField("subject", subject)
+	data, _ := json.Marshal(payload)
+	client.bus.publish(logger, subject, data)
+	logger.Debugf("published: %s", string(data))
+}
+
+// PublishProto publishes new messages.
+func (client *LocalClient) PublishProto(subject string, payload proto.Message) {
+	logger := client.logger.WithField("subject", subject)
+	data, _ := proto.Marshal(payload)
+	client.bus.publish(logger, subject, data)
+	logger.Debugf("published: %s", string(data))
+}
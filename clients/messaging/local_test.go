@@ -0,0 +1,98 @@
+package messaging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBus(t *testing.T) {
+	r := require.New(t)
+
+	bus := NewLocalBus()
+	publisher := bus.NewClient("publisher")
+	subscriber := bus.NewClient("subscriber")
+
+	agentsCh := make(chan AgentPayload, 10)
+	subscriber.Subscribe(SubjectAgentsActionRun, AgentsHandler(func(payload AgentPayload) error {
+		agentsCh <- payload
+		return nil
+	}))
+	metricsCh := make(chan *protocol.AgentMetricList, 10)
+	subscriber.Subscribe(SubjectMetricAgent, Idempotent(AgentMetricHandler(func(payload *protocol.AgentMetricList) error {
+		metricsCh <- payload
+		return errors.New("failed")
+	})))
+	deadLettersCh := make(chan DeadLetterPayload, 10)
+	publisher.Subscribe(SubjectMessagingDeadLetter, DeadLetterHandler(func(payload DeadLetterPayload) error {
+		deadLettersCh <- payload
+		return nil
+	}))
+
+	payload := AgentPayload{{ID: "0x1"}}
+	publisher.Publish(SubjectAgentsActionRun, payload)
+	publisher.Publish(SubjectAgentsActionRun, AgentPayload{{ID: "0x2"}})
+	publisher.Publish(SubjectAgentsActionStop, AgentPayload{{ID: "0x3"}})
+	publisher.PublishProto(SubjectMetricAgent, &protocol.AgentMetricList{
+		Metrics: []*protocol.AgentMetric{{AgentId: "0x1", Name: "tx.request", Value: 1}},
+	})
+
+	// the subscribers receive copies in order
+	payload[0].ID = "0x4"
+	r.Equal("0x1", receive(t, agentsCh)[0].ID)
+	r.Equal("0x2", receive(t, agentsCh)[0].ID)
+	select {
+	case <-metricsCh:
+	case <-time.After(time.Second):
+		r.FailNow("metrics not received")
+	}
+
+	// the metric goes to the dead letters after the failed attempts
+	select {
+	case deadLetter := <-deadLettersCh:
+		r.Equal(SubjectMetricAgent, deadLetter.Subject)
+		r.Equal("subscriber", deadLetter.Client)
+		r.Equal("failed", deadLetter.Error)
+		r.Equal(MaxHandleAttempts, deadLetter.Attempts)
+		r.NotEmpty(deadLetter.Data)
+	case <-time.After(time.Second * 2):
+		r.FailNow("dead letter not received")
+	}
+	r.Len(metricsCh, MaxHandleAttempts-1)
+	r.Len(agentsCh, 0)
+
+	// the clients of the other buses do not receive the messages
+	otherCh := make(chan AgentPayload, 1)
+	NewLocalBus().NewClient("other").Subscribe(SubjectAgentsActionRun, AgentsHandler(func(payload AgentPayload) error {
+		otherCh <- payload
+		return nil
+	}))
+	publisher.Publish(SubjectAgentsActionRun, AgentPayload{{ID: "0x5"}})
+	r.Equal("0x5", receive(t, agentsCh)[0].ID)
+	r.Len(otherCh, 0)
+
+	r.Panics(func() {
+		subscriber.Subscribe(SubjectScannerBlock, func(ScannerPayload) error { return nil })
+	})
+}
+
+func TestNewBackendClient(t *testing.T) {
+	r := require.New(t)
+
+	client := NewBackendClient("test", config.MessagingConfig{Backend: BackendLocal}, "")
+	r.IsType(&LocalClient{}, client)
+}
+
+func receive(t *testing.T, ch chan AgentPayload) AgentPayload {
+	select {
+	case payload := <-ch:
+		return payload
+	case <-time.After(time.Second):
+		require.FailNow(t, "message not received")
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+package messaging
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+)
+
+// SchemaVersionHeader is the message header which has the schema version of the payload.
+const SchemaVersionHeader = "Forta-Schema-Version"
+
+// LegacySchemaVersion is the version of the payloads which do not have a schema version. The services
+// which were built before the versioning publish them.
+const LegacySchemaVersion = 1
+
+// schemaVersions are the current schema versions of the payloads. The version of a subject is increased
+// when its payload changes in a way which the older services cannot decode, and an upgrade from the
+// previous version is added to the schema upgrades. The new optional fields do not need a new version.
+var schemaVersions = map[string]int{
+	SubjectAgentsVersionsLatest: 1,
+	SubjectAgentsActionRun:      1,
+	SubjectAgentsActionStop:     1,
+	SubjectAgentsStatusRunning:  1,
+	SubjectAgentsStatusAttached: 1,
+	SubjectAgentsStatusStopped:  1,
+	SubjectAgentsStatusFailed:   1,
+	SubjectAgentsRejected:       1,
+	SubjectMetricAgent:          1,
+	SubjectScannerBlock:         1,
+	SubjectScannerProvider:      1,
+	SubjectMessagingDeadLetter:  1,
+}
+
+// schemaUpgrade converts a payload to the next schema version.
+type schemaUpgrade func(data []byte) ([]byte, error)
+
+// schemaUpgrades are the upgrades of the payloads by the subject and the version which they upgrade from.
+var schemaUpgrades = map[string]map[int]schemaUpgrade{}
+
+// SchemaVersion returns the current schema version of the subject payload.
+func SchemaVersion(subject string) int {
+	if version, ok := schemaVersions[subject]; ok {
+		return version
+	}
+	return LegacySchemaVersion
+}
+
+// decodeError is the error of decoding a payload. Retrying does not help with it.
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return e.err.Error()
+}
+
+// SchemaError is returned when a payload of a schema version cannot be decoded.
+type SchemaError struct {
+	Subject        string
+	Version        int
+	CurrentVersion int
+	Err            error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf(
+		"failed to decode the %s payload of schema version %d (current version %d): %v",
+		e.Subject, e.Version, e.CurrentVersion, e.Err,
+	)
+}
+
+// versionHeader creates the header which has the current schema version of the subject.
+func versionHeader(subject string) nats.Header {
+	return nats.Header{SchemaVersionHeader: []string{strconv.Itoa(SchemaVersion(subject))}}
+}
+
+// msgSchemaVersion returns the schema version of a received message.
+func msgSchemaVersion(m *nats.Msg) (int, error) {
+	value := m.Header.Get(SchemaVersionHeader)
+	if len(value) == 0 {
+		return LegacySchemaVersion, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, &SchemaError{
+			Subject:        m.Subject,
+			CurrentVersion: SchemaVersion(m.Subject),
+			Err:            fmt.Errorf("invalid schema version %q", value),
+		}
+	}
+	return version, nil
+}
+
+// upgradePayload converts the payload of an older schema version to the current version. The payloads of
+// the newer versions are decoded as they are, which ignores the new fields.
+func upgradePayload(subject string, version int, data []byte) ([]byte, error) {
+	current := SchemaVersion(subject)
+	for ; version < current; version++ {
+		schemaErr := &SchemaError{Subject: subject, Version: version, CurrentVersion: current}
+		upgrade, ok := schemaUpgrades[subject][version]
+		if !ok {
+			schemaErr.Err = fmt.Errorf("no upgrade from version %d", version)
+			return nil, schemaErr
+		}
+		var err error
+		if data, err = upgrade(data); err != nil {
+			schemaErr.Err = err
+			return nil, schemaErr
+		}
+	}
+	return data, nil
+}
+
+var newerSchemaWarnings sync.Map
+
+// handleVersioned upgrades the payload to the current schema version and handles it with retries. It
+// returns the number of attempts and the last error.
+func handleVersioned(
+	logger *log.Entry, handler interface{}, subject string, version int, data []byte, maxAttempts int,
+) (int, error) {
+	return handleUpgraded(logger, subject, version, data, func(upgraded []byte) (int, error) {
+		return handleWithRetry(logger, handler, upgraded, maxAttempts)
+	})
+}
+
+// handleVersionedOnce upgrades the payload to the current schema version and handles it once.
+func handleVersionedOnce(logger *log.Entry, handler interface{}, subject string, version int, data []byte) error {
+	_, err := handleUpgraded(logger, subject, version, data, func(upgraded []byte) (int, error) {
+		return 1, handleMessage(handler, upgraded)
+	})
+	return err
+}
+
+// handleUpgraded upgrades the payload and handles it. The decode errors are returned as schema errors.
+func handleUpgraded(
+	logger *log.Entry, subject string, version int, data []byte, handle func([]byte) (int, error),
+) (int, error) {
+	current := SchemaVersion(subject)
+	if version > current {
+		// warn once per subject and version during the rolling updates
+		if _, warned := newerSchemaWarnings.LoadOrStore(fmt.Sprintf("%s/%d", subject, version), true); !warned {
+			logger.WithFields(log.Fields{
+				"schemaVersion":  version,
+				"currentVersion": current,
+			}).Warn("received a payload of a newer schema version - decoding it with the current version")
+		}
+	}
+	upgraded, err := upgradePayload(subject, version, data)
+	if err != nil {
+		return 1, err
+	}
+	attempts, err := handle(upgraded)
+	if decodeErr, ok := err.(*decodeError); ok {
+		return attempts, &SchemaError{Subject: subject, Version: version, CurrentVersion: current, Err: decodeErr.err}
+	}
+	return attempts, err
+}
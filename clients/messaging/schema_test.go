@@ -0,0 +1,120 @@
+package messaging
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchemaSubject = "test.schema"
+
+func TestHandleVersioned(t *testing.T) {
+	r := require.New(t)
+
+	schemaVersions[testSchemaSubject] = 2
+	schemaUpgrades[testSchemaSubject] = map[int]schemaUpgrade{
+		// version 1 had a single agent ID
+		1: func(data []byte) ([]byte, error) {
+			return append(append([]byte(`[{"id":`), data...), []byte(`}]`)...), nil
+		},
+	}
+	defer func() {
+		delete(schemaVersions, testSchemaSubject)
+		delete(schemaUpgrades, testSchemaSubject)
+	}()
+
+	var received AgentPayload
+	handler := AgentsHandler(func(payload AgentPayload) error {
+		received = payload
+		return nil
+	})
+	logger := log.WithField("test", t.Name())
+
+	// the older payloads are upgraded
+	_, err := handleVersioned(logger, handler, testSchemaSubject, 1, []byte(`"0x1"`), MaxHandleAttempts)
+	r.NoError(err)
+	r.Equal("0x1", received[0].ID)
+
+	// the current and the newer payloads are decoded as they are
+	_, err = handleVersioned(logger, handler, testSchemaSubject, 2, []byte(`[{"id":"0x2"}]`), MaxHandleAttempts)
+	r.NoError(err)
+	r.Equal("0x2", received[0].ID)
+	_, err = handleVersioned(logger, handler, testSchemaSubject, 3, []byte(`[{"id":"0x3","newField":true}]`), MaxHandleAttempts)
+	r.NoError(err)
+	r.Equal("0x3", received[0].ID)
+
+	// the payloads which cannot be decoded are not retried
+	attempts, err := handleVersioned(logger, handler, testSchemaSubject, 3, []byte(`{"agents":[]}`), MaxHandleAttempts)
+	r.Equal(1, attempts)
+	schemaErr, ok := err.(*SchemaError)
+	r.True(ok)
+	r.Equal(3, schemaErr.Version)
+	r.Equal(2, schemaErr.CurrentVersion)
+	r.Contains(err.Error(), "schema version 3")
+
+	// the upgrade is missing
+	delete(schemaUpgrades, testSchemaSubject)
+	_, err = handleVersioned(logger, handler, testSchemaSubject, 1, []byte(`"0x1"`), MaxHandleAttempts)
+	r.IsType(&SchemaError{}, err)
+}
+
+func TestSchemaVersionHeader(t *testing.T) {
+	r := require.New(t)
+
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1})
+	r.NoError(err)
+	go srv.Start()
+	r.True(srv.ReadyForConnections(5 * time.Second))
+	defer srv.Shutdown()
+
+	nc, err := nats.Connect(srv.ClientURL())
+	r.NoError(err)
+	defer nc.Close()
+	sub, err := nc.SubscribeSync(SubjectScannerBlock)
+	r.NoError(err)
+
+	client := NewClient("test", srv.ClientURL())
+	client.Publish(SubjectScannerBlock, &ScannerPayload{LatestBlockInput: 1})
+	msg, err := sub.NextMsg(time.Second)
+	r.NoError(err)
+	r.Equal("1", msg.Header.Get(SchemaVersionHeader))
+
+	// the legacy payloads without the header and the invalid versions
+	blocks := make(chan ScannerPayload, 1)
+	deadLetters := make(chan DeadLetterPayload, 1)
+	client.Subscribe(SubjectScannerBlock, ScannerHandler(func(payload ScannerPayload) error {
+		blocks <- payload
+		return nil
+	}))
+	client.Subscribe(SubjectMessagingDeadLetter, DeadLetterHandler(func(payload DeadLetterPayload) error {
+		deadLetters <- payload
+		return nil
+	}))
+	r.NoError(nc.Publish(SubjectScannerBlock, []byte(`{"latestBlockInput":2}`)))
+	select {
+	case payload := <-blocks:
+		r.Equal(uint64(2), payload.LatestBlockInput)
+	case <-time.After(time.Second):
+		r.FailNow("legacy payload not received")
+	}
+
+	r.NoError(nc.PublishMsg(&nats.Msg{
+		Subject: SubjectScannerBlock,
+		Header:  nats.Header{SchemaVersionHeader: []string{"invalid"}},
+		Data:    []byte(`{"latestBlockInput":3}`),
+	}))
+	select {
+	case deadLetter := <-deadLetters:
+		r.Equal(SubjectScannerBlock, deadLetter.Subject)
+		r.Contains(deadLetter.Error, "invalid schema version")
+		r.True(bytes.Contains(deadLetter.Data, []byte(`"latestBlockInput":3`)))
+	case <-time.After(time.Second):
+		r.FailNow("dead letter not received")
+	}
+	r.Len(blocks, 0)
+}
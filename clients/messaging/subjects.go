@@ -1,6 +1,8 @@
 package messaging
 
 import (
+	"time"
+
 	"github.com/forta-network/forta-core-go/protocol"
 	"github.com/forta-network/forta-node/config"
 )
@@ -13,17 +15,58 @@ const (
 	SubjectAgentsStatusRunning  = "agents.status.running"
 	SubjectAgentsStatusAttached = "agents.status.attached"
 	SubjectAgentsStatusStopped  = "agents.status.stopped"
+	SubjectAgentsStatusFailed   = "agents.status.failed"
+	SubjectAgentsRejected       = "agents.rejected"
 	SubjectMetricAgent          = "metric.agent"
 	SubjectScannerBlock         = "scanner.block"
+	SubjectScannerProvider      = "scanner.provider"
+	SubjectMessagingDeadLetter  = "messaging.deadletter"
 )
 
 // AgentPayload is the message payload.
 type AgentPayload []config.AgentConfig
 
+// AgentRejectedPayload is the message payload for the agents which were not run because
+// their manifests are not valid.
+type AgentRejectedPayload struct {
+	AgentID       string `json:"agentId"`
+	Manifest      string `json:"manifest"`
+	SchemaVersion int    `json:"schemaVersion"`
+	Field         string `json:"field"`
+	Reason        string `json:"reason"`
+}
+
+// AgentFailedPayload is the message payload for the agents which the supervisor stopped restarting
+// because they kept exiting.
+type AgentFailedPayload struct {
+	Agent    config.AgentConfig `json:"agent"`
+	Restarts int                `json:"restarts"`
+	Reason   string             `json:"reason"`
+}
+
 // AgentMetricPayload is the message payload for metrics.
 type AgentMetricPayload *protocol.AgentMetricList
 
 // ScannerPayload is the message payload for general scanner info.
 type ScannerPayload struct {
+	ChainID          uint64 `json:"chainId,omitempty"`
 	LatestBlockInput uint64 `json:"latestBlockInput"`
 }
+
+// ProviderPayload is the message payload for the JSON-RPC provider changes.
+type ProviderPayload struct {
+	ChainID  uint64 `json:"chainId,omitempty"`
+	Previous string `json:"previous"`
+	Active   string `json:"active"`
+}
+
+// DeadLetterPayload is the message payload for the messages which a subscriber failed to handle.
+type DeadLetterPayload struct {
+	Subject       string    `json:"subject"`
+	SchemaVersion int       `json:"schemaVersion,omitempty"`
+	Client        string    `json:"client"`
+	Error         string    `json:"error"`
+	Attempts      int       `json:"attempts"`
+	Data          []byte    `json:"data"`
+	Timestamp     time.Time `json:"timestamp"`
+}
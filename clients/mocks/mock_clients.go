@@ -6,7 +6,9 @@ package mock_clients
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	types "github.com/docker/docker/api/types"
 	domain "github.com/forta-network/forta-core-go/domain"
@@ -86,6 +88,20 @@ func (mr *MockDockerClientMockRecorder) CreatePublicNetwork(ctx, name interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePublicNetwork", reflect.TypeOf((*MockDockerClient)(nil).CreatePublicNetwork), ctx, name)
 }
 
+// DetachNetwork mocks base method.
+func (m *MockDockerClient) DetachNetwork(ctx context.Context, containerID, networkID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DetachNetwork", ctx, containerID, networkID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DetachNetwork indicates an expected call of DetachNetwork.
+func (mr *MockDockerClientMockRecorder) DetachNetwork(ctx, containerID, networkID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachNetwork", reflect.TypeOf((*MockDockerClient)(nil).DetachNetwork), ctx, containerID, networkID)
+}
+
 // EnsureLocalImage mocks base method.
 func (m *MockDockerClient) EnsureLocalImage(ctx context.Context, name, ref string) error {
 	m.ctrl.T.Helper()
@@ -100,6 +116,21 @@ func (mr *MockDockerClientMockRecorder) EnsureLocalImage(ctx, name, ref interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureLocalImage", reflect.TypeOf((*MockDockerClient)(nil).EnsureLocalImage), ctx, name, ref)
 }
 
+// FollowContainerLogs mocks base method.
+func (m *MockDockerClient) FollowContainerLogs(ctx context.Context, containerID string, since time.Time) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FollowContainerLogs", ctx, containerID, since)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FollowContainerLogs indicates an expected call of FollowContainerLogs.
+func (mr *MockDockerClientMockRecorder) FollowContainerLogs(ctx, containerID, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FollowContainerLogs", reflect.TypeOf((*MockDockerClient)(nil).FollowContainerLogs), ctx, containerID, since)
+}
+
 // GetContainerByID mocks base method.
 func (m *MockDockerClient) GetContainerByID(ctx context.Context, id string) (*types.Container, error) {
 	m.ctrl.T.Helper()
@@ -130,6 +161,21 @@ func (mr *MockDockerClientMockRecorder) GetContainerByName(ctx, name interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContainerByName", reflect.TypeOf((*MockDockerClient)(nil).GetContainerByName), ctx, name)
 }
 
+// GetContainerDiskUsage mocks base method.
+func (m *MockDockerClient) GetContainerDiskUsage(ctx context.Context, id string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContainerDiskUsage", ctx, id)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContainerDiskUsage indicates an expected call of GetContainerDiskUsage.
+func (mr *MockDockerClientMockRecorder) GetContainerDiskUsage(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContainerDiskUsage", reflect.TypeOf((*MockDockerClient)(nil).GetContainerDiskUsage), ctx, id)
+}
+
 // GetContainerLogs mocks base method.
 func (m *MockDockerClient) GetContainerLogs(ctx context.Context, containerID, tail string, truncate int) (string, error) {
 	m.ctrl.T.Helper()
@@ -145,6 +191,21 @@ func (mr *MockDockerClientMockRecorder) GetContainerLogs(ctx, containerID, tail,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContainerLogs", reflect.TypeOf((*MockDockerClient)(nil).GetContainerLogs), ctx, containerID, tail, truncate)
 }
 
+// GetContainerStats mocks base method.
+func (m *MockDockerClient) GetContainerStats(ctx context.Context, id string) (*clients.ContainerStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContainerStats", ctx, id)
+	ret0, _ := ret[0].(*clients.ContainerStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContainerStats indicates an expected call of GetContainerStats.
+func (mr *MockDockerClientMockRecorder) GetContainerStats(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContainerStats", reflect.TypeOf((*MockDockerClient)(nil).GetContainerStats), ctx, id)
+}
+
 // GetContainers mocks base method.
 func (m *MockDockerClient) GetContainers(ctx context.Context) (clients.DockerContainerList, error) {
 	m.ctrl.T.Helper()
@@ -175,6 +236,21 @@ func (mr *MockDockerClientMockRecorder) GetFortaServiceContainers(ctx interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFortaServiceContainers", reflect.TypeOf((*MockDockerClient)(nil).GetFortaServiceContainers), ctx)
 }
 
+// GetImages mocks base method.
+func (m *MockDockerClient) GetImages(ctx context.Context) ([]types.ImageSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImages", ctx)
+	ret0, _ := ret[0].([]types.ImageSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImages indicates an expected call of GetImages.
+func (mr *MockDockerClientMockRecorder) GetImages(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImages", reflect.TypeOf((*MockDockerClient)(nil).GetImages), ctx)
+}
+
 // HasLocalImage mocks base method.
 func (m *MockDockerClient) HasLocalImage(ctx context.Context, ref string) bool {
 	m.ctrl.T.Helper()
@@ -189,6 +265,36 @@ func (mr *MockDockerClientMockRecorder) HasLocalImage(ctx, ref interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasLocalImage", reflect.TypeOf((*MockDockerClient)(nil).HasLocalImage), ctx, ref)
 }
 
+// InspectContainer mocks base method.
+func (m *MockDockerClient) InspectContainer(ctx context.Context, id string) (*types.ContainerJSON, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InspectContainer", ctx, id)
+	ret0, _ := ret[0].(*types.ContainerJSON)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InspectContainer indicates an expected call of InspectContainer.
+func (mr *MockDockerClientMockRecorder) InspectContainer(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectContainer", reflect.TypeOf((*MockDockerClient)(nil).InspectContainer), ctx, id)
+}
+
+// InspectImage mocks base method.
+func (m *MockDockerClient) InspectImage(ctx context.Context, ref string) (*types.ImageInspect, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InspectImage", ctx, ref)
+	ret0, _ := ret[0].(*types.ImageInspect)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InspectImage indicates an expected call of InspectImage.
+func (mr *MockDockerClientMockRecorder) InspectImage(ctx, ref interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InspectImage", reflect.TypeOf((*MockDockerClient)(nil).InspectImage), ctx, ref)
+}
+
 // InterruptContainer mocks base method.
 func (m *MockDockerClient) InterruptContainer(ctx context.Context, id string) error {
 	m.ctrl.T.Helper()
@@ -245,6 +351,21 @@ func (mr *MockDockerClientMockRecorder) PullImage(ctx, refStr interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PullImage", reflect.TypeOf((*MockDockerClient)(nil).PullImage), ctx, refStr)
 }
 
+// ReadImageFile mocks base method.
+func (m *MockDockerClient) ReadImageFile(ctx context.Context, ref, filePath string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadImageFile", ctx, ref, filePath)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadImageFile indicates an expected call of ReadImageFile.
+func (mr *MockDockerClientMockRecorder) ReadImageFile(ctx, ref, filePath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadImageFile", reflect.TypeOf((*MockDockerClient)(nil).ReadImageFile), ctx, ref, filePath)
+}
+
 // RemoveContainer mocks base method.
 func (m *MockDockerClient) RemoveContainer(ctx context.Context, containerID string) error {
 	m.ctrl.T.Helper()
@@ -259,6 +380,20 @@ func (mr *MockDockerClientMockRecorder) RemoveContainer(ctx, containerID interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveContainer", reflect.TypeOf((*MockDockerClient)(nil).RemoveContainer), ctx, containerID)
 }
 
+// RemoveImage mocks base method.
+func (m *MockDockerClient) RemoveImage(ctx context.Context, ref string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveImage", ctx, ref)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveImage indicates an expected call of RemoveImage.
+func (mr *MockDockerClientMockRecorder) RemoveImage(ctx, ref interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveImage", reflect.TypeOf((*MockDockerClient)(nil).RemoveImage), ctx, ref)
+}
+
 // RemoveNetworkByName mocks base method.
 func (m *MockDockerClient) RemoveNetworkByName(ctx context.Context, networkName string) error {
 	m.ctrl.T.Helper()
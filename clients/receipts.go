@@ -0,0 +1,40 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// ReceiptBackend gets the transaction receipts.
+type ReceiptBackend interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*gethtypes.Receipt, error)
+}
+
+// WaitForReceipt polls the receipt of the transaction until the transaction is mined or the context is
+// done. It fails if the transaction was reverted.
+func WaitForReceipt(ctx context.Context, backend ReceiptBackend, txHash string, interval time.Duration) (*gethtypes.Receipt, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		receipt, err := backend.TransactionReceipt(ctx, common.HexToHash(txHash))
+		switch {
+		case err == nil && receipt.Status == gethtypes.ReceiptStatusSuccessful:
+			return receipt, nil
+		case err == nil:
+			return receipt, fmt.Errorf("transaction %s was reverted", txHash)
+		case !errors.Is(err, ethereum.NotFound):
+			return nil, fmt.Errorf("failed to get the receipt of %s: %v", txHash, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("transaction %s is not mined yet: %v", txHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package clients
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+type testReceiptBackend struct {
+	pending int
+	status  uint64
+}
+
+func (b *testReceiptBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*gethtypes.Receipt, error) {
+	if b.pending > 0 {
+		b.pending--
+		return nil, ethereum.NotFound
+	}
+	return &gethtypes.Receipt{TxHash: txHash, Status: b.status}, nil
+}
+
+func TestWaitForReceipt(t *testing.T) {
+	r := require.New(t)
+
+	ctx := context.Background()
+	receipt, err := WaitForReceipt(ctx, &testReceiptBackend{pending: 2, status: gethtypes.ReceiptStatusSuccessful}, "0x1", time.Millisecond)
+	r.NoError(err)
+	r.Equal(common.HexToHash("0x1"), receipt.TxHash)
+
+	_, err = WaitForReceipt(ctx, &testReceiptBackend{status: gethtypes.ReceiptStatusFailed}, "0x1", time.Millisecond)
+	r.Error(err)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+	_, err = WaitForReceipt(ctx, &testReceiptBackend{pending: 1000}, "0x1", time.Millisecond)
+	r.Error(err)
+}
@@ -0,0 +1,85 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxSecretSize = 1 << 20
+
+// ErrSecretNotFound is returned when there is no secret at the path.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Client reads the secrets from the KV version 2 secrets engine of HashiCorp Vault.
+type Client interface {
+	ReadSecret(ctx context.Context, secretPath string) (map[string]string, error)
+}
+
+type client struct {
+	address    string
+	mount      string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new client which reads the secrets from the mount.
+func NewClient(address, mount, token string) Client {
+	return &client{
+		address:    strings.TrimSuffix(address, "/"),
+		mount:      strings.Trim(mount, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: time.Second * 30},
+	}
+}
+
+type secretResponse struct {
+	Data struct {
+		Data map[string]json.RawMessage `json:"data"`
+	} `json:"data"`
+}
+
+// ReadSecret reads the latest version of the secret. The values which are not strings are returned as JSON.
+func (c *client) ReadSecret(ctx context.Context, secretPath string) (map[string]string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", c.address, c.mount, strings.Trim(secretPath, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", c.token)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrSecretNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to read the secret: %s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSecretSize))
+	if err != nil {
+		return nil, err
+	}
+	var secretResp secretResponse
+	if err := json.Unmarshal(b, &secretResp); err != nil {
+		return nil, fmt.Errorf("invalid secret response: %v", err)
+	}
+	secret := make(map[string]string)
+	for key, value := range secretResp.Data.Data {
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			s = string(value)
+		}
+		secret[key] = s
+	}
+	return secret, nil
+}
@@ -0,0 +1,38 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSecret(t *testing.T) {
+	r := require.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if req.URL.Path != "/v1/kv/data/agents/agent-1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"API_KEY":"abc","LIMIT":10},"metadata":{"version":2}}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "/kv/", "test-token")
+	secret, err := client.ReadSecret(context.Background(), "/agents/agent-1")
+	r.NoError(err)
+	r.Equal(map[string]string{"API_KEY": "abc", "LIMIT": "10"}, secret)
+
+	_, err = client.ReadSecret(context.Background(), "agents/agent-2")
+	r.Equal(ErrSecretNotFound, err)
+
+	_, err = NewClient(server.URL, "kv", "bad-token").ReadSecret(context.Background(), "agents/agent-1")
+	r.Error(err)
+}
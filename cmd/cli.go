@@ -10,8 +10,10 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/creasty/defaults"
+	"github.com/ethereum/go-ethereum/console/prompt"
 
 	"github.com/forta-network/forta-node/config"
 	"gopkg.in/yaml.v3"
@@ -22,10 +24,11 @@ import (
 )
 
 const (
-	keyFortaDir         = "forta_dir"
-	keyFortaPassphrase  = "forta_passphrase"
-	keyFortaDevelopment = "forta_development"
-	keyFortaExposeNats  = "forta_expose_nats"
+	keyFortaDir            = "forta_dir"
+	keyFortaPassphrase     = "forta_passphrase"
+	keyFortaPassphraseFile = "forta_passphrase_file"
+	keyFortaDevelopment    = "forta_development"
+	keyFortaExposeNats     = "forta_expose_nats"
 )
 
 var (
@@ -49,14 +52,26 @@ publishes alerts about them`,
 
 	cmdFortaInit = &cobra.Command{
 		Use:   "init",
-		Short: "initialize a config file and a private key (doesn't overwrite)",
+		Short: "initialize a config file and a private key (doesn't overwrite) - use --interactive for a guided setup",
 		RunE:  handleFortaInit,
 	}
 
 	cmdFortaRun = &cobra.Command{
 		Use:   "run",
 		Short: "launch the node",
-		RunE:  withContractAddresses(withInitialized(withValidConfig(handleFortaRun))),
+		RunE:  withContractAddresses(withInitialized(withValidConfig(withPassphrase(handleFortaRun)))),
+	}
+
+	cmdFortaBench = &cobra.Command{
+		Use:   "bench",
+		Short: "replay block and tx fixtures through the local agents and report their throughput, latency and drops",
+		RunE:  withInitialized(withValidConfig(handleFortaBench)),
+	}
+
+	cmdFortaReplay = &cobra.Command{
+		Use:   "replay",
+		Short: "scan a historical block range and write the alerts to the replay dir",
+		RunE:  withContractAddresses(withInitialized(withValidConfig(withPassphrase(handleFortaReplay)))),
 	}
 
 	cmdFortaAccount = &cobra.Command{
@@ -65,19 +80,67 @@ publishes alerts about them`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
 		},
+		Deprecated: "please use 'forta keys' instead",
 	}
 
 	cmdFortaAccountAddress = &cobra.Command{
-		Use:   "address",
-		Short: "show the scanner address",
-		RunE:  withInitialized(handleFortaAccountAddress),
+		Use:        "address",
+		Short:      "show the scanner address",
+		RunE:       withInitialized(handleFortaAccountAddress),
+		Deprecated: "please use 'forta keys' instead",
 	}
 
 	cmdFortaAccountImport = &cobra.Command{
-		Use:    "import",
-		Short:  "import new scanner account (removes the old one)",
-		RunE:   withInitialized(handleFortaAccountImport),
-		Hidden: true,
+		Use:        "import",
+		Short:      "import new scanner account (removes the old one)",
+		RunE:       withInitialized(withPassphrase(handleFortaAccountImport)),
+		Hidden:     true,
+		Deprecated: "please use 'forta keys' instead",
+	}
+
+	cmdFortaAccountRotate = &cobra.Command{
+		Use:        "rotate",
+		Short:      "create a new scanner key and then complete the rotation with --complete after funding it",
+		RunE:       withContractAddresses(withInitialized(withValidConfig(withPassphrase(handleFortaAccountRotate)))),
+		Deprecated: "please use 'forta keys' instead",
+	}
+
+	cmdFortaKeys = &cobra.Command{
+		Use:   "keys",
+		Short: "scanner key management",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmdFortaKeysCreate = &cobra.Command{
+		Use:   "create",
+		Short: "create the scanner key if there is none",
+		RunE:  withPassphrase(handleFortaKeysCreate),
+	}
+
+	cmdFortaKeysImport = &cobra.Command{
+		Use:   "import",
+		Short: "import a private key hex or a keystore file as the scanner key (archives the current key)",
+		RunE:  withPassphrase(handleFortaKeysImport),
+	}
+
+	cmdFortaKeysExport = &cobra.Command{
+		Use:   "export",
+		Short: "export the scanner key as an encrypted keystore file",
+		RunE:  withInitialized(withPassphrase(handleFortaKeysExport)),
+	}
+
+	cmdFortaKeysList = &cobra.Command{
+		Use:   "list",
+		Short: "list the active, the pending and the archived scanner keys",
+		RunE:  handleFortaKeysList,
+	}
+
+	cmdFortaKeysRotate = &cobra.Command{
+		Use:   "rotate",
+		Short: "create a new scanner key and then complete the rotation with --complete after funding it",
+		RunE:  withContractAddresses(withInitialized(withValidConfig(withPassphrase(handleFortaAccountRotate)))),
 	}
 
 	cmdFortaAgent = &cobra.Command{
@@ -95,6 +158,72 @@ publishes alerts about them`,
 		RunE:  withAgentRegContractAddress(withDevOnly(withInitialized(withValidConfig(handleFortaAgentAdd)))),
 	}
 
+	cmdFortaAgentLogs = &cobra.Command{
+		Use:   "logs <agent-id>",
+		Short: "show the logs of an agent which the node runs",
+		Args:  cobra.ExactArgs(1),
+		RunE:  handleFortaAgentLogs,
+	}
+
+	cmdFortaAgentTest = &cobra.Command{
+		Use:   "test <image>",
+		Short: "run the protocol conformance checks of an agent image",
+		Args:  cobra.ExactArgs(1),
+		RunE:  handleFortaAgentTest,
+	}
+
+	cmdFortaRegistry = &cobra.Command{
+		Use:   "registry",
+		Short: "agent registry utils",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmdFortaRegistryResync = &cobra.Command{
+		Use:   "resync",
+		Short: "reload all agents from the current registry state and update the running node",
+		RunE:  withContractAddresses(withInitialized(withValidConfig(handleFortaRegistryResync))),
+	}
+
+	cmdFortaRegistryDryRun = &cobra.Command{
+		Use:   "dry-run",
+		Short: "show how the agents would change with the given pools without updating the running node",
+		RunE:  withContractAddresses(withInitialized(withValidConfig(handleFortaRegistryDryRun))),
+	}
+
+	cmdFortaLogs = &cobra.Command{
+		Use:   "logs",
+		Short: "show the merged logs of the node services",
+		RunE:  handleFortaLogs,
+	}
+
+	cmdFortaExportAlerts = &cobra.Command{
+		Use:   "export-alerts",
+		Short: "export the alerts of a time window from the local alert store as NDJSON or CSV",
+		RunE:  handleFortaExportAlerts,
+	}
+
+	cmdFortaAudit = &cobra.Command{
+		Use:   "audit",
+		Short: "audit log of the node",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmdFortaAuditExport = &cobra.Command{
+		Use:   "export",
+		Short: "verify the hash chains of the audit logs, export the entries as NDJSON and print the heads to keep",
+		RunE:  handleFortaAuditExport,
+	}
+
+	cmdFortaInstallService = &cobra.Command{
+		Use:   "install-service",
+		Short: "install and enable a systemd service which runs the node",
+		RunE:  handleFortaInstallService,
+	}
+
 	cmdFortaImages = &cobra.Command{
 		Use:   "images",
 		Short: "list the Forta node container images",
@@ -127,22 +256,48 @@ publishes alerts about them`,
 		RunE:  handleFortaStatus,
 	}
 
+	cmdFortaStatusOverview = &cobra.Command{
+		Use:   "overview",
+		Short: "display an overview of the node services: chain lag, agents, store size and publisher backlog",
+		RunE:  handleFortaStatusOverview,
+	}
+
+	cmdFortaConfig = &cobra.Command{
+		Use:   "config",
+		Short: "config file utils",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmdFortaConfigValidate = &cobra.Command{
+		Use:   "validate",
+		Short: "validate the config file, the JSON-RPC APIs and the scanner key before running the node",
+		RunE:  withInitialized(withPassphrase(handleFortaConfigValidate)),
+	}
+
+	cmdFortaConfigSchema = &cobra.Command{
+		Use:   "schema",
+		Short: "print the JSON schema of the config file",
+		RunE:  handleFortaConfigSchema,
+	}
+
 	cmdFortaRegister = &cobra.Command{
 		Use:   "register",
 		Short: "register your scan node to enable it for scanning (requires MATIC in your scan node address)",
-		RunE:  withContractAddresses(withInitialized(withValidConfig(handleFortaRegister))),
+		RunE:  withContractAddresses(withInitialized(withValidConfig(withPassphrase(handleFortaRegister)))),
 	}
 
 	cmdFortaEnable = &cobra.Command{
 		Use:   "enable",
 		Short: "enable your scan node (requires MATIC in your scan node address)",
-		RunE:  withContractAddresses(withInitialized(withValidConfig(handleFortaEnable))),
+		RunE:  withContractAddresses(withInitialized(withValidConfig(withPassphrase(handleFortaEnable)))),
 	}
 
 	cmdFortaDisable = &cobra.Command{
 		Use:   "disable",
 		Short: "disable your scan node (requires MATIC in your scan node address)",
-		RunE:  withContractAddresses(withInitialized(withValidConfig(handleFortaDisable))),
+		RunE:  withContractAddresses(withInitialized(withValidConfig(withPassphrase(handleFortaDisable)))),
 	}
 )
 
@@ -156,13 +311,38 @@ func init() {
 
 	cmdForta.AddCommand(cmdFortaInit)
 	cmdForta.AddCommand(cmdFortaRun)
+	cmdForta.AddCommand(cmdFortaReplay)
+	cmdForta.AddCommand(cmdFortaBench)
 
 	cmdForta.AddCommand(cmdFortaAccount)
 	cmdFortaAccount.AddCommand(cmdFortaAccountAddress)
 	cmdFortaAccount.AddCommand(cmdFortaAccountImport)
+	cmdFortaAccount.AddCommand(cmdFortaAccountRotate)
+
+	cmdForta.AddCommand(cmdFortaKeys)
+	cmdFortaKeys.AddCommand(cmdFortaKeysCreate)
+	cmdFortaKeys.AddCommand(cmdFortaKeysImport)
+	cmdFortaKeys.AddCommand(cmdFortaKeysExport)
+	cmdFortaKeys.AddCommand(cmdFortaKeysList)
+	cmdFortaKeys.AddCommand(cmdFortaKeysRotate)
 
 	cmdForta.AddCommand(cmdFortaAgent)
 	cmdFortaAgent.AddCommand(cmdFortaAgentAdd)
+	cmdFortaAgent.AddCommand(cmdFortaAgentLogs)
+	cmdFortaAgent.AddCommand(cmdFortaAgentTest)
+
+	cmdForta.AddCommand(cmdFortaRegistry)
+	cmdFortaRegistry.AddCommand(cmdFortaRegistryResync)
+	cmdFortaRegistry.AddCommand(cmdFortaRegistryDryRun)
+
+	cmdForta.AddCommand(cmdFortaLogs)
+
+	cmdForta.AddCommand(cmdFortaExportAlerts)
+
+	cmdForta.AddCommand(cmdFortaAudit)
+	cmdFortaAudit.AddCommand(cmdFortaAuditExport)
+
+	cmdForta.AddCommand(cmdFortaInstallService)
 
 	cmdForta.AddCommand(cmdFortaImages)
 
@@ -172,6 +352,11 @@ func init() {
 	cmdFortaBatch.AddCommand(cmdFortaBatchDecode)
 
 	cmdForta.AddCommand(cmdFortaStatus)
+	cmdFortaStatus.AddCommand(cmdFortaStatusOverview)
+
+	cmdForta.AddCommand(cmdFortaConfig)
+	cmdFortaConfig.AddCommand(cmdFortaConfigValidate)
+	cmdFortaConfig.AddCommand(cmdFortaConfigSchema)
 
 	cmdForta.AddCommand(cmdFortaRegister)
 	cmdForta.AddCommand(cmdFortaEnable)
@@ -188,18 +373,97 @@ func init() {
 	cmdForta.PersistentFlags().String("passphrase", "", "passphrase to decrypt the private key (overrides $FORTA_PASSPHRASE)")
 	viper.BindPFlag(keyFortaPassphrase, cmdForta.PersistentFlags().Lookup("passphrase"))
 
+	cmdForta.PersistentFlags().String("passphrase-file", "", "file that contains the passphrase (overrides $FORTA_PASSPHRASE_FILE)")
+	viper.BindPFlag(keyFortaPassphraseFile, cmdForta.PersistentFlags().Lookup("passphrase-file"))
+
 	cmdForta.PersistentFlags().Bool("expose-nats", false, "expose nats via public docker network")
 	viper.BindPFlag(keyFortaExposeNats, cmdForta.PersistentFlags().Lookup("expose-nats"))
 
+	// forta init
+	cmdFortaInit.Flags().BoolP("interactive", "i", false, "ask for the chain, the JSON-RPC APIs, the passphrase and the data dir")
+
 	// forta account import
 	cmdFortaAccountImport.Flags().String("file", "", "path to a file that contains a private key hex")
 	cmdFortaAccountImport.MarkFlagRequired("file")
 
+	// forta account rotate
+	cmdFortaAccountRotate.Flags().Bool("complete", false, "register the new key, disable the old scanner and start using the new key")
+	cmdFortaAccountRotate.Flags().String("owner-address", "", "Ethereum wallet address of the scanner owner (default: the owner of the current scanner)")
+
+	// forta keys import
+	cmdFortaKeysImport.Flags().String("file", "", "path to a file that contains a private key hex or a keystore file")
+	cmdFortaKeysImport.MarkFlagRequired("file")
+	cmdFortaKeysImport.Flags().Bool("force", false, "replace the current key and archive it")
+
+	// forta keys export
+	cmdFortaKeysExport.Flags().String("out", "", "path of the exported keystore file")
+	cmdFortaKeysExport.MarkFlagRequired("out")
+
+	// forta keys rotate
+	cmdFortaKeysRotate.Flags().Bool("complete", false, "register the new key, disable the old scanner and start using the new key")
+	cmdFortaKeysRotate.Flags().String("owner-address", "", "Ethereum wallet address of the scanner owner (default: the owner of the current scanner)")
+
 	// forta agent add
 	cmdFortaAgentAdd.Flags().Uint64Var(&parsedArgs.Version, "version", 0, "agent version")
 
+	// forta agent logs
+	cmdFortaAgentLogs.Flags().BoolP("follow", "f", false, "keep streaming the new logs")
+	cmdFortaAgentLogs.Flags().Int("tail", -1, "number of lines to show from the end of the logs (default: all)")
+
+	// forta agent test
+	cmdFortaAgentTest.Flags().Int("requests", 20, "number of back to back requests to send for the timeout check")
+	cmdFortaAgentTest.Flags().Duration("startup-timeout", time.Minute, "how long to wait for the agent to serve the gRPC API")
+	cmdFortaAgentTest.Flags().StringToString("env", nil, "environment variables of the agent container")
+	cmdFortaAgentTest.Flags().Bool("json", false, "print the report as JSON")
+	cmdFortaAgentTest.Flags().String("json-rpc-url", "", "JSON-RPC API which the agent uses during the test (default: scan.jsonRpc.url from the config)")
+
+	// forta logs
+	cmdFortaLogs.Flags().StringSlice("service", nil, fmt.Sprintf("services to show the logs of: %s (default: all)", strings.Join(logServices, ", ")))
+	cmdFortaLogs.Flags().BoolP("follow", "f", false, "keep streaming the new logs")
+	cmdFortaLogs.Flags().Int("tail", 100, "number of lines to show from the end of the logs of each service (-1: all)")
+
+	// forta export-alerts
+	cmdFortaExportAlerts.Flags().String("from", "24h", "start of the window: a timestamp, a date or a duration before now")
+	cmdFortaExportAlerts.Flags().String("to", "0s", "end of the window (exclusive): a timestamp, a date or a duration before now")
+	cmdFortaExportAlerts.Flags().String("format", ExportFormatNDJSON, "output format: ndjson, csv")
+	cmdFortaExportAlerts.Flags().String("out", "", "output file (default: stdout)")
+	cmdFortaExportAlerts.Flags().UintSlice("chain-id", nil, "chains to export the alerts of (default: all)")
+	cmdFortaExportAlerts.Flags().StringSlice("agent-id", nil, "agents to export the alerts of (default: all)")
+
+	// forta audit export
+	cmdFortaAuditExport.Flags().String("from", "", "start of the window: a timestamp, a date or a duration before now (default: all)")
+	cmdFortaAuditExport.Flags().String("to", "0s", "end of the window (exclusive): a timestamp, a date or a duration before now")
+	cmdFortaAuditExport.Flags().String("out", "", "output file (default: stdout)")
+	cmdFortaAuditExport.Flags().Bool("skip-verify", false, "export the entries even if the hash chains are broken")
+
+	// forta install-service
+	cmdFortaInstallService.Flags().String("name", defaultServiceName, "name of the systemd service")
+	cmdFortaInstallService.Flags().String("user", "", "user which runs the service (default: the user which ran sudo)")
+	cmdFortaInstallService.Flags().Bool("dry-run", false, "print the service unit instead of installing it")
+	cmdFortaInstallService.Flags().Bool("no-enable", false, "install the service without enabling it")
+
+	// forta registry dry-run
+	cmdFortaRegistryDryRun.Flags().StringSlice("pools", nil, "the pools to compare with the configured pools (scanner addresses or 'all')")
+	cmdFortaRegistryDryRun.MarkFlagRequired("pools")
+
 	// forta run
 	cmdFortaRun.Flags().BoolVar(&parsedArgs.NoCheck, "no-check", false, "disable scanner registry check and just run")
+	cmdFortaRun.Flags().Bool("dev", false, "run the scanner and the process agents in a single process without Docker and keep the alerts in memory")
+
+	// forta replay
+	cmdFortaReplay.Flags().Uint64("from", 0, "first block of the range")
+	cmdFortaReplay.MarkFlagRequired("from")
+	cmdFortaReplay.Flags().Uint64("to", 0, "last block of the range")
+	cmdFortaReplay.MarkFlagRequired("to")
+
+	// forta bench
+	cmdFortaBench.Flags().String("fixtures", "", "block and tx fixtures file in the protobuf JSON format (default: built-in synthetic fixtures)")
+	cmdFortaBench.Flags().Float64("tx-rate", 10, "tx requests per second")
+	cmdFortaBench.Flags().Float64("block-rate", 0.1, "block requests per second")
+	cmdFortaBench.Flags().Duration("duration", time.Minute, "how long to replay the fixtures")
+	cmdFortaBench.Flags().StringSlice("agent-id", nil, "agents from the dev agents file to benchmark (default: all with a command)")
+	cmdFortaBench.Flags().Bool("no-start", false, "do not start the agent processes and use the already running ones")
+	cmdFortaBench.Flags().Bool("json", false, "print the report as JSON")
 
 	// forta batch decode
 	cmdFortaBatchDecode.Flags().String("cid", "", "batch IPFS CID (content ID)")
@@ -212,16 +476,15 @@ func init() {
 	cmdFortaStatus.Flags().Bool("no-color", false, "disable colors")
 	cmdFortaStatus.Flags().String("show", StatusShowSummary, "filter statuses to show: summary (default), important, all")
 
+	// forta status overview
+	cmdFortaStatusOverview.Flags().Bool("no-color", false, "disable colors")
+
+	// forta config validate
+	cmdFortaConfigValidate.Flags().Bool("skip-connectivity", false, "skip checking the JSON-RPC APIs")
+
 	// forta register
 	cmdFortaRegister.Flags().String("owner-address", "", "Ethereum wallet address of the scanner owner")
 	cmdFortaRegister.MarkFlagRequired("owner-address")
-	cmdFortaRegister.MarkFlagRequired("passphrase")
-
-	// forta enable
-	cmdFortaEnable.MarkFlagRequired("passphrase")
-
-	// forta disable
-	cmdFortaDisable.MarkFlagRequired("passphrase")
 }
 
 func initConfig() {
@@ -229,6 +492,7 @@ func initConfig() {
 
 	viper.BindEnv(keyFortaDir)
 	viper.BindEnv(keyFortaPassphrase)
+	viper.BindEnv(keyFortaPassphraseFile)
 	viper.BindEnv(keyFortaDevelopment)
 	viper.BindEnv(keyFortaExposeNats)
 	viper.AutomaticEnv()
@@ -247,11 +511,17 @@ func initConfig() {
 	if err := defaults.Set(&cfg); err != nil {
 		panic(err)
 	}
+	cobra.CheckErr(config.ApplyEnvOverrides(&cfg, os.Environ()))
 
 	cfg.FortaDir = fortaDir
 	cfg.KeyDirPath = path.Join(cfg.FortaDir, config.DefaultKeysDirName)
 	cfg.Development = viper.GetBool(keyFortaDevelopment)
 	cfg.Passphrase = viper.GetString(keyFortaPassphrase)
+	if passphraseFile := viper.GetString(keyFortaPassphraseFile); len(cfg.Passphrase) == 0 && len(passphraseFile) > 0 {
+		b, err := ioutil.ReadFile(passphraseFile)
+		cobra.CheckErr(err)
+		cfg.Passphrase = strings.TrimRight(string(b), "\r\n")
+	}
 	cfg.ExposeNats = viper.GetBool(keyFortaExposeNats)
 
 	cfg.LocalAgentsPath = path.Join(cfg.FortaDir, config.DefaultLocalAgentsFileName)
@@ -318,6 +588,25 @@ func withInitialized(handler func(*cobra.Command, []string) error) func(*cobra.C
 	}
 }
 
+// withPassphrase asks for the passphrase if it was not provided with the flags, the env vars or the file.
+func withPassphrase(handler func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(cfg.Passphrase) == 0 && isTerminal() {
+			passphrase, err := prompt.Stdin.PromptPassword("Passphrase: ")
+			if err != nil {
+				return fmt.Errorf("failed to read the passphrase: %v", err)
+			}
+			cfg.Passphrase = passphrase
+		}
+		return handler(cmd, args)
+	}
+}
+
+func isTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 func withDevOnly(handler func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		if !cfg.Development {
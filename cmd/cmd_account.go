@@ -1,22 +1,37 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/forta-network/forta-core-go/registry"
+	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
 	"github.com/spf13/cobra"
 )
 
+const (
+	txMineTimeout      = time.Minute * 10
+	txMinePollInterval = time.Second * 5
+)
+
 func handleFortaAccountAddress(cmd *cobra.Command, args []string) error {
 	ks := keystore.NewKeyStore(cfg.KeyDirPath, keystore.StandardScryptN, keystore.StandardScryptP)
 	accounts := ks.Accounts()
 	if len(accounts) > 1 {
-		redBold("You have multiple accounts. Please import your scanner account again with 'forta account import'.")
+		redBold("You have multiple accounts. Please import your scanner account again with 'forta keys import --force'.")
 		cmd.Println("Your current account addresses:")
 		for _, account := range accounts {
 			cmd.Println(account.Address.Hex())
@@ -25,7 +40,7 @@ func handleFortaAccountAddress(cmd *cobra.Command, args []string) error {
 	}
 
 	if len(accounts) == 0 {
-		redBold("You have no accounts. Please import your scanner account with 'forta account import'.")
+		redBold("You have no accounts. Please import your scanner account with 'forta keys import --force'.")
 		return errors.New("no accounts")
 	}
 
@@ -44,9 +59,8 @@ func handleFortaAccountImport(cmd *cobra.Command, args []string) error {
 	}
 	hexKey := strings.TrimSpace(string(b))
 
-	if len(cfg.Passphrase) == 0 {
-		redBold("Your passphrase is not set. Please set it with FORTA_PASSPHRASE environment variable or provide it with the --passphrase flag.\n")
-		return errors.New("empty passhphrase")
+	if err := requirePassphrase(); err != nil {
+		return err
 	}
 
 	os.RemoveAll(cfg.KeyDirPath)
@@ -62,3 +76,191 @@ func handleFortaAccountImport(cmd *cobra.Command, args []string) error {
 	cmd.Println(account.Address.Hex())
 	return nil
 }
+
+func pendingKeyDirPath() string {
+	return path.Join(cfg.FortaDir, config.DefaultPendingKeysDirName)
+}
+
+func handleFortaAccountRotate(cmd *cobra.Command, args []string) error {
+	if err := requirePassphrase(); err != nil {
+		return err
+	}
+	complete, err := cmd.Flags().GetBool("complete")
+	if err != nil {
+		return err
+	}
+	if complete {
+		return completeKeyRotation(cmd)
+	}
+	return createPendingKey(cmd)
+}
+
+// createPendingKey creates the new key next to the current key so it can be funded before the rotation.
+func createPendingKey(cmd *cobra.Command) error {
+	currentKey, err := security.LoadKeyWithPassphrase(cfg.KeyDirPath, cfg.Passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to load scanner key: %v", err)
+	}
+
+	ks := keystore.NewKeyStore(pendingKeyDirPath(), keystore.StandardScryptN, keystore.StandardScryptP)
+	accounts := ks.Accounts()
+	if len(accounts) > 0 {
+		yellowBold("There is already a pending key: %s\n", accounts[0].Address.Hex())
+		whiteBold("Please fund it and do 'forta keys rotate --complete'.\n")
+		return nil
+	}
+	account, err := ks.NewAccount(cfg.Passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to create the new key: %v", err)
+	}
+
+	cmd.Printf("Current scanner address: %s\n", currentKey.Address.Hex())
+	printScannerAddress(account.Address.Hex())
+	whiteBold("\n%s\n", strings.Join([]string{
+		"- Please fund the new scanner address with some MATIC.",
+		"- Please do 'forta keys rotate --complete' to register the new scanner and to disable the current one.",
+	}, "\n"))
+	return nil
+}
+
+// completeKeyRotation registers the new key as a scanner of the same owner, disables the old scanner and
+// replaces the old key with the new key. The old key is kept in the archive dir.
+func completeKeyRotation(cmd *cobra.Command) error {
+	if recovered, err := recoverKeyRotation(); err != nil || recovered {
+		return err
+	}
+	currentKey, err := security.LoadKeyWithPassphrase(cfg.KeyDirPath, cfg.Passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to load scanner key: %v", err)
+	}
+	newKey, err := security.LoadKeyWithPassphrase(pendingKeyDirPath(), cfg.Passphrase)
+	if err != nil {
+		yellowBold("Please do 'forta keys rotate' first to create the new key.\n")
+		return fmt.Errorf("failed to load the pending key: %v", err)
+	}
+	currentAddressStr := currentKey.Address.Hex()
+	newAddressStr := newKey.Address.Hex()
+
+	ctx := context.Background()
+	currentReg, err := store.GetRegistryClient(ctx, cfg, registry.ClientConfig{
+		JsonRpcUrl: cfg.Registry.JsonRpc.Url,
+		ENSAddress: cfg.ENSConfig.ContractAddress,
+		Name:       "registry-client",
+		PrivateKey: currentKey.PrivateKey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create registry client: %v", err)
+	}
+	newReg, err := store.GetRegistryClient(ctx, cfg, registry.ClientConfig{
+		JsonRpcUrl: cfg.Registry.JsonRpc.Url,
+		ENSAddress: cfg.ENSConfig.ContractAddress,
+		Name:       "registry-client",
+		PrivateKey: newKey.PrivateKey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create registry client: %v", err)
+	}
+
+	currentScanner, err := currentReg.GetScanner(currentAddressStr)
+	if err != nil {
+		return fmt.Errorf("failed to get the current scanner: %v", err)
+	}
+	ownerAddressStr, err := cmd.Flags().GetString("owner-address")
+	if err != nil {
+		return err
+	}
+	if len(ownerAddressStr) == 0 && currentScanner != nil {
+		ownerAddressStr = currentScanner.Owner
+	}
+	if !common.IsHexAddress(ownerAddressStr) {
+		return errors.New("the current scanner is not registered - please provide a valid owner address with --owner-address")
+	}
+
+	// the registration is not sent again if the rotation is retried after it
+	newScanner, err := newReg.GetScanner(newAddressStr)
+	if err != nil {
+		return fmt.Errorf("failed to get the new scanner: %v", err)
+	}
+	if newScanner != nil {
+		whiteBold("The new scanner %s is already registered.\n", newAddressStr)
+	} else {
+		yellowBold("Sending a transaction to register the new scanner %s to chain %d...\n", newAddressStr, cfg.ChainID)
+		txHash, err := newReg.RegisterScanner(ownerAddressStr, int64(cfg.ChainID), "")
+		if err != nil && strings.Contains(err.Error(), "insufficient funds") {
+			yellowBold("This action requires Polygon (Mainnet) MATIC. Have you funded your new address %s yet?\n", newAddressStr)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to send the transaction: %v", err)
+		}
+		whiteBold("https://polygonscan.com/tx/%s\n", txHash)
+		yellowBold("Waiting for the transaction to be mined...\n")
+		if err := waitForTx(ctx, txHash); err != nil {
+			redBold("The current key is still active. Please check the transaction and run this command again.\n")
+			return err
+		}
+	}
+
+	if err := activatePendingKey(currentAddressStr); err != nil {
+		return fmt.Errorf("failed to activate the new key: %v", err)
+	}
+	greenBold("The new key is now active. The old key is kept in %s\n", archivedKeysDirPath())
+
+	if currentScanner != nil && currentScanner.Enabled {
+		yellowBold("Sending a transaction to disable the old scanner %s...\n", currentAddressStr)
+		txHash, err := currentReg.DisableScanner(registry.ScannerPermissionSelf, currentAddressStr)
+		if err != nil {
+			redBold("Failed to disable the old scanner: %v\n", err)
+		} else {
+			whiteBold("https://polygonscan.com/tx/%s\n", txHash)
+		}
+	}
+
+	whiteBold("\nPlease restart your node so that the alert batches are signed with the new key.\n")
+	return nil
+}
+
+// waitForTx waits until the registry transaction is mined successfully.
+func waitForTx(ctx context.Context, txHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, txMineTimeout)
+	defer cancel()
+	ethClient, err := ethclient.DialContext(ctx, cfg.Registry.JsonRpc.Url)
+	if err != nil {
+		return fmt.Errorf("failed to dial the registry api: %v", err)
+	}
+	defer ethClient.Close()
+	_, err = clients.WaitForReceipt(ctx, ethClient, txHash, txMinePollInterval)
+	return err
+}
+
+// activatePendingKey moves the current key to the archive dir and the pending key to the keys dir. The
+// current key is moved back if the pending key cannot be moved.
+func activatePendingKey(currentAddressStr string) error {
+	if err := archiveKeyDir(cfg.KeyDirPath, currentAddressStr); err != nil {
+		return err
+	}
+	if err := os.Rename(pendingKeyDirPath(), cfg.KeyDirPath); err != nil {
+		archiveDir := path.Join(archivedKeysDirPath(), strings.ToLower(currentAddressStr))
+		if restoreErr := os.Rename(archiveDir, cfg.KeyDirPath); restoreErr != nil {
+			return fmt.Errorf("%v (failed to restore the current key from %s: %v)", err, archiveDir, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// recoverKeyRotation activates the pending key if the rotation was interrupted after the current key
+// was archived.
+func recoverKeyRotation() (bool, error) {
+	if _, err := os.Stat(cfg.KeyDirPath); !os.IsNotExist(err) {
+		return false, nil
+	}
+	if _, err := os.Stat(pendingKeyDirPath()); err != nil {
+		return false, nil
+	}
+	if err := os.Rename(pendingKeyDirPath(), cfg.KeyDirPath); err != nil {
+		return false, fmt.Errorf("failed to activate the new key: %v", err)
+	}
+	greenBold("Completed the interrupted rotation. The new key is now active and the old key is kept in %s\n", archivedKeysDirPath())
+	yellowBold("Please make sure that the old scanner is disabled.\n")
+	return true, nil
+}
@@ -3,7 +3,10 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 
 	"github.com/forta-network/forta-core-go/ethereum"
 	"github.com/forta-network/forta-node/store"
@@ -59,6 +62,40 @@ func handleFortaAgentAdd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func handleFortaAgentLogs(cmd *cobra.Command, args []string) error {
+	follow, err := cmd.Flags().GetBool("follow")
+	if err != nil {
+		return err
+	}
+	tail, err := cmd.Flags().GetInt("tail")
+	if err != nil {
+		return err
+	}
+
+	// call the runner agent logs API on localhost
+	query := url.Values{}
+	query.Set("follow", fmt.Sprintf("%t", follow))
+	if tail >= 0 {
+		query.Set("tail", fmt.Sprintf("%d", tail))
+	}
+	logsURL := fmt.Sprintf(
+		"http://localhost:%s/agents/%s/logs?%s", config.DefaultAgentLogsPort, url.PathEscape(args[0]), query.Encode(),
+	)
+	resp, err := http.Get(logsURL)
+	if err != nil {
+		return fmt.Errorf("failed to get the agent logs - is the node running? (%v)", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("no logs found for agent %s", args[0])
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to get the agent logs failed with status %d", resp.StatusCode)
+	}
+	_, err = io.Copy(cmd.OutOrStdout(), resp.Body)
+	return err
+}
+
 // readLocalAgents tries to read the local agents and silently returns an
 // empty array if the file is not readable or not found.
 func readLocalAgents() ([]*config.AgentConfig, error) {
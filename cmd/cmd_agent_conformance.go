@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/services/conformance"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+const agentTestLogsTail = "50"
+
+// dockerLabelFortaAgentTest marks the agent test containers, so that the leftovers of the interrupted
+// tests are cleaned up with the other Forta containers.
+const dockerLabelFortaAgentTest = "network.forta.agent-test"
+
+func handleFortaAgentTest(cmd *cobra.Command, args []string) error {
+	image := args[0]
+	requests, err := cmd.Flags().GetInt("requests")
+	if err != nil {
+		return err
+	}
+	startupTimeout, err := cmd.Flags().GetDuration("startup-timeout")
+	if err != nil {
+		return err
+	}
+	env, err := cmd.Flags().GetStringToString("env")
+	if err != nil {
+		return err
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return err
+	}
+	jsonRpcURL, err := cmd.Flags().GetString("json-rpc-url")
+	if err != nil {
+		return err
+	}
+	if len(jsonRpcURL) == 0 {
+		jsonRpcURL = cfg.Scan.JsonRpc.Url
+	}
+	jsonRpcHandler, err := agentTestJsonRpcHandler(jsonRpcURL)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	dockerClient, err := clients.NewDockerClient("agent-test")
+	if err != nil {
+		return fmt.Errorf("failed to create the docker client: %v", err)
+	}
+	// remove the exited containers of the earlier tests
+	if err := dockerClient.Prune(ctx); err != nil {
+		return fmt.Errorf("failed to prune the old agent test containers: %v", err)
+	}
+	if err := dockerClient.EnsureLocalImage(ctx, "agent-test", image); err != nil {
+		return fmt.Errorf("failed to get the agent image: %v", err)
+	}
+	hostPort, err := freeLocalPort()
+	if err != nil {
+		return err
+	}
+
+	// the agent reaches the JSON-RPC API through a proxy at the host gateway, since the SDKs need a host and a port
+	jsonRpcLis, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return fmt.Errorf("failed to listen for the agent json-rpc requests: %v", err)
+	}
+	_, jsonRpcPort, _ := net.SplitHostPort(jsonRpcLis.Addr().String())
+	jsonRpcServer := &http.Server{Handler: jsonRpcHandler}
+	go jsonRpcServer.Serve(jsonRpcLis)
+	defer jsonRpcServer.Close()
+
+	containerEnv := map[string]string{
+		config.EnvAgentGrpcPort: config.AgentGrpcPort,
+		config.EnvJsonRpcHost:   "host.docker.internal",
+		config.EnvJsonRpcPort:   jsonRpcPort,
+	}
+	for k, v := range env {
+		containerEnv[k] = v
+	}
+	container, err := dockerClient.StartContainer(ctx, clients.DockerContainerConfig{
+		Name:  fmt.Sprintf("forta-agent-test-%d", time.Now().Unix()),
+		Image: image,
+		Env:   containerEnv,
+		Ports: map[string]string{fmt.Sprintf("127.0.0.1:%s", hostPort): config.AgentGrpcPort},
+		Labels: map[string]string{
+			dockerLabelFortaAgentTest: "true",
+		},
+		DialHost: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start the agent container: %v", err)
+	}
+	defer func() {
+		// the context can be cancelled already
+		cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cleanupCancel()
+		_ = dockerClient.StopContainer(cleanupCtx, container.ID)
+		_ = dockerClient.RemoveContainer(cleanupCtx, container.ID)
+	}()
+
+	if !asJSON {
+		greenBold("Testing agent image %s\n", image)
+	}
+	dialCtx, dialCancel := context.WithTimeout(ctx, startupTimeout)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, fmt.Sprintf("127.0.0.1:%s", hostPort), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		printAgentTestLogs(dockerClient, container.ID)
+		return fmt.Errorf("agent did not serve the gRPC API in %s: %v", startupTimeout, err)
+	}
+	defer conn.Close()
+
+	report, err := conformance.Run(ctx, image, conn, conformance.Options{
+		Requests: requests,
+		Alive: func() error {
+			inspection, err := dockerClient.InspectContainer(ctx, container.ID)
+			if err != nil {
+				return err
+			}
+			if !inspection.State.Running {
+				return fmt.Errorf("container exited with code %d", inspection.State.ExitCode)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		report.Print(os.Stdout)
+	}
+	if !report.Passed() {
+		printAgentTestLogs(dockerClient, container.ID)
+		return fmt.Errorf("agent failed the conformance checks")
+	}
+	if !asJSON {
+		greenBold("Agent passed the conformance checks\n")
+	}
+	return nil
+}
+
+// agentTestJsonRpcHandler forwards the JSON-RPC requests of the agent to the URL. Without a URL, the
+// requests get an error which tells how to set one.
+func agentTestJsonRpcHandler(jsonRpcURL string) (http.Handler, error) {
+	if len(jsonRpcURL) == 0 {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      nil,
+				"error": map[string]interface{}{
+					"code":    -32000,
+					"message": "no json-rpc url for the agent test - please use --json-rpc-url",
+				},
+			})
+		}), nil
+	}
+	target, err := url.Parse(jsonRpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json-rpc url: %v", err)
+	}
+	return &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			u := *target
+			r.Host = u.Host
+			r.URL = &u
+		},
+	}, nil
+}
+
+// freeLocalPort finds a port which the agent container can be published at.
+func freeLocalPort() (string, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to find a free port: %v", err)
+	}
+	defer lis.Close()
+	_, port, err := net.SplitHostPort(lis.Addr().String())
+	return port, err
+}
+
+func printAgentTestLogs(dockerClient clients.DockerClient, containerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	logs, err := dockerClient.GetContainerLogs(ctx, containerID, agentTestLogsTail, -1)
+	if err != nil {
+		return
+	}
+	yellowBold("Last %s lines of the agent logs:\n", agentTestLogsTail)
+	fmt.Fprintln(os.Stderr, logs)
+}
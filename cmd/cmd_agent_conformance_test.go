@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentTestJsonRpcHandler(t *testing.T) {
+	r := require.New(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		r.Equal("/v3/key", req.URL.Path)
+		r.Contains(string(body), "eth_blockNumber")
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+	}))
+	defer upstream.Close()
+
+	handler, err := agentTestJsonRpcHandler(upstream.URL + "/v3/key")
+	r.NoError(err)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)))
+	r.Equal(http.StatusOK, recorder.Code)
+	r.Equal(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`, recorder.Body.String())
+
+	handler, err = agentTestJsonRpcHandler("")
+	r.NoError(err)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)))
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	r.NoError(json.NewDecoder(recorder.Body).Decode(&resp))
+	r.Contains(resp.Error.Message, "--json-rpc-url")
+}
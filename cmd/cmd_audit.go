@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/forta-network/forta-node/audit"
+	"github.com/spf13/cobra"
+)
+
+func handleFortaAuditExport(cmd *cobra.Command, args []string) error {
+	outPath, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return err
+	}
+	fromStr, err := cmd.Flags().GetString("from")
+	if err != nil {
+		return err
+	}
+	toStr, err := cmd.Flags().GetString("to")
+	if err != nil {
+		return err
+	}
+	skipVerify, err := cmd.Flags().GetBool("skip-verify")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var from, to time.Time
+	if len(fromStr) > 0 {
+		if from, err = parseExportTime(fromStr, now); err != nil {
+			return fmt.Errorf("invalid --from: %v", err)
+		}
+	}
+	if to, err = parseExportTime(toStr, now); err != nil {
+		return fmt.Errorf("invalid --to: %v", err)
+	}
+
+	auditDir := audit.Dir(cfg.FortaDir)
+	entries, err := audit.ReadAll(auditDir, !skipVerify)
+	if err != nil {
+		redBold("The audit log could not be verified. Please use --skip-verify to export it anyway.\n")
+		return err
+	}
+
+	var out io.Writer = cmd.OutOrStdout()
+	if len(outPath) > 0 {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create the output file: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	encoder := json.NewEncoder(out)
+	var count int
+	for _, entry := range entries {
+		t, err := time.Parse(time.RFC3339Nano, entry.Time)
+		if err != nil || t.Before(from) || !t.Before(to) {
+			continue
+		}
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+		count++
+	}
+	toStderr(fmt.Sprintf("Exported %d audit log entries from %s\n", count, auditDir))
+
+	// the removed latest entries can be detected only by comparing with the heads which were kept
+	heads := audit.Heads(entries)
+	processes := make([]string, 0, len(heads))
+	for process := range heads {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
+	for _, process := range processes {
+		toStderr(fmt.Sprintf("Head of %s: seq %d hash %s\n", process, heads[process].Seq, heads[process].Hash))
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/forta-network/forta-core-go/encoding"
 	"github.com/forta-network/forta-core-go/protocol"
 	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-node/store"
 	"github.com/ipfs/go-cid"
 	"github.com/spf13/cobra"
 )
@@ -64,6 +65,10 @@ func handleFortaBatchDecode(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to decode: %v", err)
 	}
 
+	if err := store.MigrateAlertBatch(&alertBatch); err != nil {
+		yellowBold("Failed to migrate the alerts to the latest schema: %v\n", err)
+	}
+
 	// indent by two spaces
 	b, _ := json.MarshalIndent(&alertBatch, "", "  ")
 
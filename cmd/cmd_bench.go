@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/services/bench"
+	"github.com/forta-network/forta-node/services/runner"
+	"github.com/forta-network/forta-node/store"
+	"github.com/spf13/cobra"
+)
+
+func handleFortaBench(cmd *cobra.Command, args []string) error {
+	fixturesPath, err := cmd.Flags().GetString("fixtures")
+	if err != nil {
+		return err
+	}
+	txRate, err := cmd.Flags().GetFloat64("tx-rate")
+	if err != nil {
+		return err
+	}
+	blockRate, err := cmd.Flags().GetFloat64("block-rate")
+	if err != nil {
+		return err
+	}
+	duration, err := cmd.Flags().GetDuration("duration")
+	if err != nil {
+		return err
+	}
+	agentIDs, err := cmd.Flags().GetStringSlice("agent-id")
+	if err != nil {
+		return err
+	}
+	noStart, err := cmd.Flags().GetBool("no-start")
+	if err != nil {
+		return err
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return err
+	}
+	if txRate < 0 || blockRate < 0 || txRate+blockRate == 0 {
+		return fmt.Errorf("--tx-rate and --block-rate must not be negative and one of them must be greater than zero")
+	}
+	if duration <= 0 {
+		return fmt.Errorf("--duration must be greater than zero")
+	}
+
+	fixtures, err := bench.SyntheticFixtures()
+	if len(fixturesPath) > 0 {
+		fixtures, err = bench.LoadFixtures(fixturesPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load the fixtures: %v", err)
+	}
+	agents, err := benchAgents(agentIDs)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if !noStart {
+		benchCfg := cfg
+		benchCfg.DevProcess = true
+		processAgents := runner.NewProcessAgents(ctx, benchCfg)
+		if err := processAgents.Start(); err != nil {
+			return err
+		}
+		defer processAgents.Stop()
+	}
+
+	if !asJSON {
+		greenBold("Benchmarking %d agent(s) for %s with %.1f tx/s and %.1f block/s\n", len(agents), duration, txRate, blockRate)
+	}
+	report, err := bench.Run(ctx, cfg, agents, fixtures, bench.Options{
+		TxRate:    txRate,
+		BlockRate: blockRate,
+		Duration:  duration,
+	})
+	if err != nil {
+		return fmt.Errorf("benchmark failed: %v", err)
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	report.Print(os.Stdout)
+	return nil
+}
+
+// benchAgents returns the process agents from the dev agents file.
+func benchAgents(agentIDs []string) ([]config.AgentConfig, error) {
+	filePath := path.Join(cfg.FortaDir, config.DefaultDevAgentsFileName)
+	devAgents, err := store.ReadDevAgents(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the dev agents: %v", err)
+	}
+	include := make(map[string]bool)
+	for _, agentID := range agentIDs {
+		include[agentID] = true
+	}
+	var agents []config.AgentConfig
+	for _, agent := range devAgents {
+		if len(include) > 0 && !include[agent.ID] {
+			continue
+		}
+		if !agent.IsProcess() {
+			yellowBold("Skipping agent %s: only the agents with a command can be benchmarked\n", agent.ID)
+			continue
+		}
+		agents = append(agents, *agent)
+	}
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agents with a command in %s", filePath)
+	}
+	return agents, nil
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-node/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const configCheckTimeout = time.Second * 10
+
+// configRpcCheck is a JSON-RPC endpoint from the config which the node connects to.
+type configRpcCheck struct {
+	Name    string
+	JsonRpc config.JsonRpcConfig
+	ChainID int // not compared if zero
+}
+
+func handleFortaConfigSchema(cmd *cobra.Command, args []string) error {
+	b, err := json.MarshalIndent(config.GenerateSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func handleFortaConfigValidate(cmd *cobra.Command, args []string) error {
+	skipConnectivity, err := cmd.Flags().GetBool("skip-connectivity")
+	if err != nil {
+		return err
+	}
+
+	configPath := cfg.ConfigFilePath()
+	whiteBold("Validating %s\n", configPath)
+
+	var failed bool
+	if !checkConfigSchema(configPath) {
+		failed = true
+	}
+	if !checkContractAddresses(skipConnectivity) {
+		failed = true
+	}
+	if err := validateConfig(); err != nil {
+		redBold("✘ The config has invalid values - please fix the fields above.\n")
+		failed = true
+	} else {
+		greenBold("✔ The config values are valid.\n")
+	}
+	if !skipConnectivity {
+		for _, check := range configRpcChecks() {
+			if !checkConfigRpc(check) {
+				failed = true
+			}
+		}
+	}
+	if !checkScannerKey() {
+		failed = true
+	}
+
+	if failed {
+		return errors.New("invalid config")
+	}
+	greenBold("The config is ready - you can start the node with 'forta run'.\n")
+	return nil
+}
+
+// checkConfigSchema checks the config file against the schema and prints the mismatching fields.
+func checkConfigSchema(configPath string) bool {
+	b, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		redBold("✘ Failed to read the config file: %v\n", err)
+		return false
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		redBold("✘ The config file is not valid YAML: %v\n", err)
+		toStderr("  Please check the indentation and make sure that the tabs are not used.\n")
+		return false
+	}
+	if doc == nil {
+		doc = map[string]interface{}{} // empty config file
+	}
+	schemaErrs := config.GenerateSchema().Validate(doc)
+	if len(schemaErrs) == 0 {
+		greenBold("✔ The config file matches the schema.\n")
+		return true
+	}
+	redBold("✘ The config file does not match the schema:\n")
+	for _, schemaErr := range schemaErrs {
+		fmt.Fprintf(os.Stderr, "  - %s\n", schemaErr.Error())
+	}
+	toStderr("  See 'forta config schema' for all fields.\n")
+	return false
+}
+
+// checkContractAddresses resolves the contract addresses which are not in the config, so that they
+// are validated with the rest of the config.
+func checkContractAddresses(skipConnectivity bool) bool {
+	if len(cfg.Registry.ContractAddress) > 0 {
+		return true
+	}
+	if cache, ok := getContractAddressCache(); ok {
+		setContractAddressesFromCache(cache)
+		return true
+	}
+	if skipConnectivity {
+		yellowBold("The contract addresses are not resolved yet - please validate without --skip-connectivity.\n")
+		return true
+	}
+	if err := ensureLatestContractAddresses(); err != nil {
+		redBold("✘ Failed to resolve the contract addresses: %v\n", err)
+		toStderr("  Please check ens.jsonRpc or set registry.contractAddress.\n")
+		return false
+	}
+	return true
+}
+
+// configRpcChecks returns the JSON-RPC endpoints which the node needs.
+func configRpcChecks() []configRpcCheck {
+	checks := chainRpcChecks("", cfg.ChainID, cfg.Scan, cfg.Trace)
+	for i, chain := range cfg.Chains {
+		checks = append(checks, chainRpcChecks(fmt.Sprintf("chains[%d].", i), chain.ChainID, chain.Scan, chain.Trace)...)
+	}
+	if len(cfg.JsonRpcProxy.JsonRpc.Url) > 0 {
+		checks = append(checks, configRpcCheck{Name: "jsonRpcProxy.jsonRpc", JsonRpc: cfg.JsonRpcProxy.JsonRpc, ChainID: cfg.ChainID})
+	}
+	if cfg.Mempool.Enabled && len(cfg.Mempool.JsonRpc.Url) > 0 {
+		checks = append(checks, configRpcCheck{Name: "mempool.jsonRpc", JsonRpc: cfg.Mempool.JsonRpc, ChainID: cfg.ChainID})
+	}
+	checks = append(checks, configRpcCheck{Name: "registry.jsonRpc", JsonRpc: cfg.Registry.JsonRpc})
+	if cfg.ENSConfig.Override {
+		checks = append(checks, configRpcCheck{Name: "ens.jsonRpc", JsonRpc: cfg.ENSConfig.JsonRpc})
+	}
+	if cfg.Publish.Anchor.Enable {
+		checks = append(checks, configRpcCheck{Name: "publish.anchor.jsonRpc", JsonRpc: cfg.Publish.Anchor.JsonRpc})
+	}
+	return checks
+}
+
+func chainRpcChecks(prefix string, chainID int, scanCfg config.ScannerConfig, traceCfg config.TraceConfig) []configRpcCheck {
+	if scanCfg.DataSource.Type == "file" {
+		return nil
+	}
+	checks := []configRpcCheck{{Name: prefix + "scan.jsonRpc", JsonRpc: scanCfg.JsonRpc, ChainID: chainID}}
+	for i, fallback := range scanCfg.FallbackJsonRpc {
+		checks = append(checks, configRpcCheck{Name: fmt.Sprintf("%sscan.fallbackJsonRpc[%d]", prefix, i), JsonRpc: fallback, ChainID: chainID})
+	}
+	if traceCfg.Enabled {
+		checks = append(checks, configRpcCheck{Name: prefix + "trace.jsonRpc", JsonRpc: traceCfg.JsonRpc, ChainID: chainID})
+	}
+	return checks
+}
+
+// checkConfigRpc connects to the JSON-RPC endpoint and checks the chain ID.
+func checkConfigRpc(check configRpcCheck) bool {
+	if len(check.JsonRpc.Url) == 0 {
+		redBold("✘ %s: the url is missing.\n", check.Name)
+		toStderr("  Please set the url of a JSON-RPC API which the node can use.\n")
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), configCheckTimeout)
+	defer cancel()
+	rpcClient, err := rpc.DialContext(ctx, check.JsonRpc.Url)
+	if err != nil {
+		redBold("✘ %s: failed to connect to %s: %v\n", check.Name, check.JsonRpc.Url, err)
+		return false
+	}
+	defer rpcClient.Close()
+	for k, v := range check.JsonRpc.Headers {
+		rpcClient.SetHeader(k, v)
+	}
+	chainID, err := ethclient.NewClient(rpcClient).ChainID(ctx)
+	if err != nil {
+		redBold("✘ %s: failed to get the chain ID from %s: %v\n", check.Name, check.JsonRpc.Url, err)
+		toStderr("  Please make sure that the API is reachable and the API key in the url or the headers is correct.\n")
+		return false
+	}
+	if check.ChainID != 0 && chainID.Int64() != int64(check.ChainID) {
+		redBold("✘ %s: %s is on chain %s but chain %d is expected.\n", check.Name, check.JsonRpc.Url, chainID, check.ChainID)
+		toStderr("  Please use a JSON-RPC API of the chain or change the chainId.\n")
+		return false
+	}
+	greenBold("✔ %s: connected to chain %s.\n", check.Name, chainID)
+	return true
+}
+
+// checkScannerKey checks if the scanner key can be decrypted with the passphrase.
+func checkScannerKey() bool {
+	if len(cfg.Passphrase) == 0 {
+		redBold("✘ The passphrase is missing.\n")
+		toStderr("  Please set FORTA_PASSPHRASE or FORTA_PASSPHRASE_FILE, or run this command in a terminal.\n")
+		return false
+	}
+	key, err := security.LoadKeyWithPassphrase(cfg.KeyDirPath, cfg.Passphrase)
+	if err != nil {
+		redBold("✘ Failed to load the scanner key: %v\n", err)
+		toStderr("  Please make sure that the passphrase is the one used with 'forta init'.\n")
+		return false
+	}
+	greenBold("✔ The scanner key %s is unlocked with the passphrase.\n", key.Address.Hex())
+	return true
+}
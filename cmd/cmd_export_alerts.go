@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
+	"github.com/spf13/cobra"
+)
+
+// Export formats
+const (
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatCSV    = "csv"
+)
+
+var exportAlertsCSVHeader = []string{
+	"timestamp", "chainId", "blockNumber", "alertHash", "agentId", "alertId", "name", "severity", "type",
+	"protocol", "description", "addresses",
+}
+
+func handleFortaExportAlerts(cmd *cobra.Command, args []string) error {
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return err
+	}
+	outPath, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return err
+	}
+	chainIDs, err := cmd.Flags().GetUintSlice("chain-id")
+	if err != nil {
+		return err
+	}
+	agentIDs, err := cmd.Flags().GetStringSlice("agent-id")
+	if err != nil {
+		return err
+	}
+	fromStr, err := cmd.Flags().GetString("from")
+	if err != nil {
+		return err
+	}
+	toStr, err := cmd.Flags().GetString("to")
+	if err != nil {
+		return err
+	}
+	if format != ExportFormatNDJSON && format != ExportFormatCSV {
+		return fmt.Errorf("unknown format %q - please use %s or %s", format, ExportFormatNDJSON, ExportFormatCSV)
+	}
+
+	now := time.Now()
+	from, err := parseExportTime(fromStr, now)
+	if err != nil {
+		return fmt.Errorf("invalid --from: %v", err)
+	}
+	to, err := parseExportTime(toStr, now)
+	if err != nil {
+		return fmt.Errorf("invalid --to: %v", err)
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("--from must be before --to")
+	}
+
+	var out io.Writer = cmd.OutOrStdout()
+	if len(outPath) > 0 {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create the output file: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var chains []uint64
+	for _, chainID := range chainIDs {
+		chains = append(chains, uint64(chainID))
+	}
+	agents := make(map[string]bool)
+	for _, agentID := range agentIDs {
+		agents[strings.ToLower(agentID)] = true
+	}
+
+	var (
+		count     int
+		csvWriter *csv.Writer
+		encoder   = json.NewEncoder(out)
+	)
+	if format == ExportFormatCSV {
+		csvWriter = csv.NewWriter(out)
+		if err := csvWriter.Write(exportAlertsCSVHeader); err != nil {
+			return err
+		}
+	}
+	alertsDir := path.Join(cfg.FortaDir, config.DefaultAlertsDirName)
+	err = store.ReadStoredAlerts(alertsDir, chains, from, to, func(alert *protocol.SignedAlert) error {
+		if len(agents) > 0 && !agents[strings.ToLower(alert.GetAlert().GetAgent().GetId())] {
+			return nil
+		}
+		count++
+		if csvWriter != nil {
+			return csvWriter.Write(alertCSVRecord(alert))
+		}
+		return encoder.Encode(alert)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read the alerts: %v", err)
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+	}
+	toStderr(fmt.Sprintf("Exported %d alerts from %s\n", count, alertsDir))
+	return nil
+}
+
+// parseExportTime parses an RFC3339 timestamp, a date or a duration before now like 24h.
+func parseExportTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a timestamp like 2022-05-01T00:00:00Z, a date like 2022-05-01 or a duration like 24h")
+	}
+	return now.Add(-d), nil
+}
+
+func alertCSVRecord(alert *protocol.SignedAlert) []string {
+	finding := alert.GetAlert().GetFinding()
+	return []string{
+		alert.GetAlert().GetTimestamp(),
+		alert.GetChainId(),
+		alert.GetBlockNumber(),
+		alert.GetAlert().GetId(),
+		alert.GetAlert().GetAgent().GetId(),
+		finding.GetAlertId(),
+		finding.GetName(),
+		finding.GetSeverity().String(),
+		finding.GetType().String(),
+		finding.GetProtocol(),
+		finding.GetDescription(),
+		strings.Join(finding.GetAddresses(), ";"),
+	}
+}
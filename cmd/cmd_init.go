@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -13,14 +14,44 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// initConfigValues are the values of the generated config file.
+type initConfigValues struct {
+	config.EnvDefaults
+	ChainID  int
+	ScanURL  string
+	TraceURL string // no trace section if empty
+
+	checkConnectivity bool
+}
+
 func handleFortaInit(cmd *cobra.Command, args []string) error {
 	if isInitialized() {
 		greenBold("Already initialized - please ensure that your configuration at %s is correct!\n", cfg.ConfigFilePath())
 		return nil
 	}
 
+	values := initConfigValues{
+		EnvDefaults: config.GetEnvDefaults(cfg.Development),
+		ChainID:     1,
+		ScanURL:     "<required>",
+		TraceURL:    "<required>",
+	}
+	interactive, err := cmd.Flags().GetBool("interactive")
+	if err != nil {
+		return err
+	}
+	initialDir := cfg.FortaDir
+	if interactive {
+		if !isTerminal() {
+			return errors.New("the interactive init needs a terminal")
+		}
+		if err := runInitWizard(&values); err != nil {
+			return err
+		}
+	}
+
 	if !isDirInitialized() {
-		if err := os.Mkdir(cfg.FortaDir, 0755); err != nil {
+		if err := os.MkdirAll(cfg.FortaDir, 0755); err != nil {
 			return err
 		}
 	}
@@ -31,7 +62,7 @@ func handleFortaInit(cmd *cobra.Command, args []string) error {
 			return err
 		}
 		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, config.GetEnvDefaults(cfg.Development)); err != nil {
+		if err := tmpl.Execute(&buf, values); err != nil {
 			return err
 		}
 		if err := os.WriteFile(cfg.ConfigFilePath(), buf.Bytes(), 0644); err != nil {
@@ -60,6 +91,12 @@ func handleFortaInit(cmd *cobra.Command, args []string) error {
 	}
 
 	color.Green("\nSuccessfully initialized at %s\n", cfg.FortaDir)
+	if values.checkConnectivity {
+		checkInitConnectivity(values)
+	}
+	if cfg.FortaDir != initialDir {
+		yellowBold("\nPlease set FORTA_DIR=%s before running the other commands.\n", cfg.FortaDir)
+	}
 	whiteBold("\n%s\n", strings.Join([]string{
 		"- Please make sure that all of the values in config.yml are set correctly.",
 		"- Please fund your scanner address with some MATIC.",
@@ -76,17 +113,19 @@ func printScannerAddress(address string) {
 
 const defaultConfig = `# Auto generated by 'forta init' - safe to modify
 # The chainId is the chainId of the network that is analyzed (1=mainnet)
-chainId: 1
+chainId: {{.ChainID}}
 
 # The scan settings are used to retrieve the transactions that are analyzed
 scan:
   jsonRpc:
-    url: <required>
+    url: {{.ScanURL}}
+{{- if .TraceURL}}
 
 # The trace endpoint must support trace_block (such as alchemy)
 trace:
   jsonRpc:
-    url: <required>
+    url: {{.TraceURL}}
+{{- end}}
 
 # The registry settings are used to discover and load agents
 # registry:
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/forta-network/forta-node/config"
+)
+
+// traceChainID is the chain which has tracing enabled by default.
+const traceChainID = 1
+
+// runInitWizard asks for the data dir, the chain, the JSON-RPC APIs and the passphrase.
+func runInitWizard(values *initConfigValues) error {
+	whiteBold("This will set up a Forta node. Press enter to accept the defaults in brackets.\n\n")
+
+	fortaDir, err := promptInput("Data directory", cfg.FortaDir, func(s string) error {
+		if !filepath.IsAbs(s) {
+			return fmt.Errorf("please enter an absolute path")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	setFortaDir(filepath.Clean(fortaDir))
+
+	if !isConfigFileInitialized() {
+		if err := promptConfigValues(values); err != nil {
+			return err
+		}
+	} else {
+		yellowBold("Keeping the existing config at %s\n", cfg.ConfigFilePath())
+	}
+
+	if !isKeyInitialized() && len(cfg.Passphrase) == 0 {
+		passphrase, err := promptNewPassphrase()
+		if err != nil {
+			return err
+		}
+		cfg.Passphrase = passphrase
+	}
+	return nil
+}
+
+func promptConfigValues(values *initConfigValues) error {
+	fmt.Println("\nKnown chains:")
+	for _, chain := range config.GetAllChainSettings() {
+		fmt.Printf("  %d: %s\n", chain.ChainID, chain.Name)
+	}
+	chainIDStr, err := promptInput("Chain ID", strconv.Itoa(values.ChainID), func(s string) error {
+		if n, err := strconv.Atoi(s); err != nil || n < 1 {
+			return fmt.Errorf("please enter a positive number")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	values.ChainID, _ = strconv.Atoi(chainIDStr)
+
+	chainName := config.GetChainSettings(values.ChainID).Name
+	values.ScanURL, err = promptInput(fmt.Sprintf("JSON-RPC API URL of %s", chainName), "", validateInitURL)
+	if err != nil {
+		return err
+	}
+
+	values.TraceURL = ""
+	if values.ChainID == traceChainID {
+		values.TraceURL, err = promptInput("Trace API URL (must support trace_block)", values.ScanURL, validateInitURL)
+		if err != nil {
+			return err
+		}
+	}
+	values.checkConnectivity = true
+	return nil
+}
+
+func promptNewPassphrase() (string, error) {
+	yellowBold("\nThe passphrase encrypts the scanner key. Please do not lose it.\n")
+	for {
+		passphrase, err := prompt.Stdin.PromptPassword("Passphrase: ")
+		if err != nil {
+			return "", fmt.Errorf("failed to read the passphrase: %v", err)
+		}
+		if len(passphrase) == 0 {
+			redBold("The passphrase can't be empty.\n")
+			continue
+		}
+		confirmation, err := prompt.Stdin.PromptPassword("Repeat passphrase: ")
+		if err != nil {
+			return "", fmt.Errorf("failed to read the passphrase: %v", err)
+		}
+		if passphrase != confirmation {
+			redBold("The passphrases do not match.\n")
+			continue
+		}
+		return passphrase, nil
+	}
+}
+
+// promptInput asks until the input is valid. The default value is used if the input is empty.
+func promptInput(question, defaultValue string, validate func(string) error) (string, error) {
+	if len(defaultValue) > 0 {
+		question = fmt.Sprintf("%s [%s]", question, defaultValue)
+	}
+	for {
+		input, err := prompt.Stdin.PromptInput(question + ": ")
+		if err != nil {
+			return "", fmt.Errorf("failed to read the input: %v", err)
+		}
+		input = strings.TrimSpace(input)
+		if len(input) == 0 {
+			input = defaultValue
+		}
+		if len(input) == 0 {
+			redBold("Please enter a value.\n")
+			continue
+		}
+		if err := validate(input); err != nil {
+			redBold("%v\n", err)
+			continue
+		}
+		return input, nil
+	}
+}
+
+func validateInitURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || len(u.Host) == 0 || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ws" && u.Scheme != "wss") {
+		return fmt.Errorf("please enter a URL like https://example.com")
+	}
+	return nil
+}
+
+// setFortaDir changes the data dir and the paths in it.
+func setFortaDir(fortaDir string) {
+	cfg.FortaDir = fortaDir
+	cfg.KeyDirPath = path.Join(cfg.FortaDir, config.DefaultKeysDirName)
+	cfg.LocalAgentsPath = path.Join(cfg.FortaDir, config.DefaultLocalAgentsFileName)
+}
+
+// checkInitConnectivity checks the JSON-RPC APIs which were entered.
+func checkInitConnectivity(values initConfigValues) {
+	fmt.Println()
+	ok := checkConfigRpc(configRpcCheck{Name: "scan.jsonRpc", JsonRpc: config.JsonRpcConfig{Url: values.ScanURL}, ChainID: values.ChainID})
+	if len(values.TraceURL) > 0 {
+		ok = checkConfigRpc(configRpcCheck{Name: "trace.jsonRpc", JsonRpc: config.JsonRpcConfig{Url: values.TraceURL}, ChainID: values.ChainID}) && ok
+	}
+	if !ok {
+		yellowBold("Please fix the URLs in %s and check them with 'forta config validate'.\n", cfg.ConfigFilePath())
+	}
+}
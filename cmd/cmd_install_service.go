@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"path/filepath"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultServiceName    = "forta"
+	defaultServiceDirPath = "/etc/systemd/system"
+	defaultServiceEnvDir  = "/etc/forta"
+)
+
+// serviceUnitValues are the values of the systemd unit.
+type serviceUnitValues struct {
+	ExecPath    string
+	User        string
+	FortaDir    string
+	EnvFilePath string
+}
+
+// serviceUnit runs the node after Docker and restarts it on failure. The node only needs to write
+// to the data dir and to talk to Docker, so the rest of the system is read-only for the service.
+const serviceUnit = `[Unit]
+Description=Forta node
+Documentation=https://docs.forta.network
+Wants=network-online.target
+After=network-online.target docker.service
+Requires=docker.service
+StartLimitIntervalSec=600
+StartLimitBurst=10
+
+[Service]
+Type=simple
+User={{.User}}
+SupplementaryGroups=docker
+EnvironmentFile={{.EnvFilePath}}
+ExecStart={{.ExecPath}} run
+Restart=on-failure
+RestartSec=15
+TimeoutStopSec=120
+KillSignal=SIGTERM
+
+NoNewPrivileges=true
+ProtectSystem=strict
+ProtectHome=read-only
+ReadWritePaths={{.FortaDir}}
+PrivateTmp=true
+PrivateDevices=true
+ProtectKernelTunables=true
+ProtectKernelModules=true
+ProtectKernelLogs=true
+ProtectControlGroups=true
+ProtectClock=true
+ProtectHostname=true
+RestrictSUIDSGID=true
+RestrictRealtime=true
+LockPersonality=true
+RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6
+CapabilityBoundingSet=
+
+[Install]
+WantedBy=multi-user.target
+`
+
+func handleFortaInstallService(cmd *cobra.Command, args []string) error {
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return err
+	}
+	serviceUser, err := cmd.Flags().GetString("user")
+	if err != nil {
+		return err
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+	noEnable, err := cmd.Flags().GetBool("no-enable")
+	if err != nil {
+		return err
+	}
+
+	if len(serviceUser) == 0 {
+		if serviceUser, err = defaultServiceUser(); err != nil {
+			return err
+		}
+	}
+	// under sudo, the default forta dir is in the home dir of root instead of the service user
+	fortaDir, err := serviceFortaDir(viper.GetString(keyFortaDir), serviceUser)
+	if err != nil {
+		return err
+	}
+	if fortaDir != cfg.FortaDir {
+		viper.Set(keyFortaDir, fortaDir)
+		initConfig()
+	}
+	if !isInitialized() {
+		yellowBold("Please make sure you do 'forta init' as %s first and check your configuration at %s/config.yml\n", serviceUser, cfg.FortaDir)
+		return errors.New("not initialized")
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the forta binary: %v", err)
+	}
+	if execPath, err = filepath.EvalSymlinks(execPath); err != nil {
+		return fmt.Errorf("failed to find the forta binary: %v", err)
+	}
+	values := serviceUnitValues{
+		ExecPath:    execPath,
+		User:        serviceUser,
+		FortaDir:    cfg.FortaDir,
+		EnvFilePath: path.Join(defaultServiceEnvDir, fmt.Sprintf("%s.env", name)),
+	}
+	unit, err := renderServiceUnit(values)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		cmd.Print(string(unit))
+		return nil
+	}
+
+	if os.Geteuid() != 0 {
+		redBold("Please run this command as root (e.g. with sudo) to install the service.\n")
+		return errors.New("not root")
+	}
+	if err := writeServiceEnvFile(values.EnvFilePath, cfg.FortaDir, cfg.Passphrase, viper.GetString(keyFortaPassphraseFile)); err != nil {
+		return err
+	}
+	unitPath := path.Join(defaultServiceDirPath, fmt.Sprintf("%s.service", name))
+	if err := os.WriteFile(unitPath, unit, 0644); err != nil {
+		return fmt.Errorf("failed to write the service unit: %v", err)
+	}
+	greenBold("Wrote the service unit to %s\n", unitPath)
+
+	if err := runSystemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if noEnable {
+		whiteBold("Please enable and start the service with 'systemctl enable --now %s'.\n", name)
+		return nil
+	}
+	if err := runSystemctl("enable", name); err != nil {
+		return err
+	}
+	greenBold("Enabled the %s service.\n", name)
+	whiteBold("Please start it with 'systemctl start %s' and see the logs with 'journalctl -fu %s'.\n", name, name)
+	return nil
+}
+
+// defaultServiceUser is the user which ran sudo or the current user.
+func defaultServiceUser() (string, error) {
+	if sudoUser := os.Getenv("SUDO_USER"); len(sudoUser) > 0 {
+		return sudoUser, nil
+	}
+	currentUser, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the current user: %v", err)
+	}
+	return currentUser.Username, nil
+}
+
+// serviceFortaDir is the configured forta dir or the default forta dir in the home dir of the service user.
+func serviceFortaDir(configuredDir, serviceUser string) (string, error) {
+	if len(configuredDir) > 0 {
+		return configuredDir, nil
+	}
+	u, err := user.Lookup(serviceUser)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the service user %s: %v", serviceUser, err)
+	}
+	return path.Join(u.HomeDir, ".forta"), nil
+}
+
+func renderServiceUnit(values serviceUnitValues) ([]byte, error) {
+	tmpl, err := template.New("service-unit").Parse(serviceUnit)
+	if err != nil {
+		return nil, err
+	}
+	var unit bytes.Buffer
+	if err := tmpl.Execute(&unit, values); err != nil {
+		return nil, err
+	}
+	return unit.Bytes(), nil
+}
+
+// writeServiceEnvFile writes the data dir and the passphrase to the env file. The existing env file
+// is kept since it can contain other settings. Only root can read the env file since it can contain
+// the passphrase.
+func writeServiceEnvFile(envFilePath, fortaDir, passphrase, passphraseFile string) error {
+	envDir := path.Dir(envFilePath)
+	if err := os.MkdirAll(envDir, 0700); err != nil {
+		return fmt.Errorf("failed to create the env file dir: %v", err)
+	}
+	if err := os.Chmod(envDir, 0700); err != nil {
+		return fmt.Errorf("failed to set the env file dir permissions: %v", err)
+	}
+	if _, err := os.Stat(envFilePath); err == nil {
+		yellowBold("Keeping the existing env file at %s\n", envFilePath)
+		return os.Chmod(envFilePath, 0600)
+	}
+
+	var env bytes.Buffer
+	fmt.Fprintf(&env, "FORTA_DIR=%s\n", fortaDir)
+	switch {
+	case len(passphraseFile) > 0:
+		fmt.Fprintf(&env, "FORTA_PASSPHRASE_FILE=%s\n", passphraseFile)
+	case len(passphrase) > 0:
+		fmt.Fprintf(&env, "FORTA_PASSPHRASE=%s\n", passphrase)
+	default:
+		yellowBold("The passphrase is not set - please add FORTA_PASSPHRASE to %s before starting the service.\n", envFilePath)
+	}
+	if err := os.WriteFile(envFilePath, env.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write the env file: %v", err)
+	}
+	greenBold("Wrote the env file to %s\n", envFilePath)
+	return nil
+}
+
+func runSystemctl(args ...string) error {
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %v failed: %v: %s", args, err, string(out))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"os/user"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceFortaDir(t *testing.T) {
+	r := require.New(t)
+
+	fortaDir, err := serviceFortaDir("/data/forta", "nobody")
+	r.NoError(err)
+	r.Equal("/data/forta", fortaDir)
+
+	currentUser, err := user.Current()
+	r.NoError(err)
+	fortaDir, err = serviceFortaDir("", currentUser.Username)
+	r.NoError(err)
+	r.Equal(path.Join(currentUser.HomeDir, ".forta"), fortaDir)
+
+	_, err = serviceFortaDir("", "forta-test-unknown-user")
+	r.Error(err)
+}
+
+func TestRenderServiceUnit(t *testing.T) {
+	r := require.New(t)
+
+	unit, err := renderServiceUnit(serviceUnitValues{
+		ExecPath:    "/usr/bin/forta",
+		User:        "alice",
+		FortaDir:    "/home/alice/.forta",
+		EnvFilePath: "/etc/forta/forta.env",
+	})
+	r.NoError(err)
+	r.Contains(string(unit), "User=alice\n")
+	r.Contains(string(unit), "ExecStart=/usr/bin/forta run\n")
+	r.Contains(string(unit), "ReadWritePaths=/home/alice/.forta\n")
+	r.Contains(string(unit), "EnvironmentFile=/etc/forta/forta.env\n")
+}
+
+func TestWriteServiceEnvFile(t *testing.T) {
+	r := require.New(t)
+
+	envDir := path.Join(t.TempDir(), "forta")
+	envFilePath := path.Join(envDir, "forta.env")
+	r.NoError(writeServiceEnvFile(envFilePath, "/home/alice/.forta", "secret", ""))
+
+	dirInfo, err := os.Stat(envDir)
+	r.NoError(err)
+	r.Equal(os.FileMode(0700), dirInfo.Mode().Perm())
+	fileInfo, err := os.Stat(envFilePath)
+	r.NoError(err)
+	r.Equal(os.FileMode(0600), fileInfo.Mode().Perm())
+	b, err := os.ReadFile(envFilePath)
+	r.NoError(err)
+	r.Equal("FORTA_DIR=/home/alice/.forta\nFORTA_PASSPHRASE=secret\n", string(b))
+
+	// the existing env file is kept with the restricted permissions
+	r.NoError(os.Chmod(envDir, 0755))
+	r.NoError(os.Chmod(envFilePath, 0644))
+	r.NoError(writeServiceEnvFile(envFilePath, "/other", "", "/run/passphrase"))
+	dirInfo, err = os.Stat(envDir)
+	r.NoError(err)
+	r.Equal(os.FileMode(0700), dirInfo.Mode().Perm())
+	fileInfo, err = os.Stat(envFilePath)
+	r.NoError(err)
+	r.Equal(os.FileMode(0600), fileInfo.Mode().Perm())
+	b, err = os.ReadFile(envFilePath)
+	r.NoError(err)
+	r.True(strings.HasPrefix(string(b), "FORTA_DIR=/home/alice/.forta\n"))
+}
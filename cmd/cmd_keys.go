@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-node/audit"
+	"github.com/forta-network/forta-node/config"
+	"github.com/spf13/cobra"
+)
+
+func archivedKeysDirPath() string {
+	return path.Join(cfg.FortaDir, config.DefaultArchivedKeysDirName)
+}
+
+func requirePassphrase() error {
+	if len(cfg.Passphrase) == 0 {
+		redBold("Your passphrase is not set. Please set it with FORTA_PASSPHRASE environment variable or provide it with the --passphrase flag.\n")
+		return errors.New("empty passhphrase")
+	}
+	return nil
+}
+
+func handleFortaKeysCreate(cmd *cobra.Command, args []string) error {
+	if err := requirePassphrase(); err != nil {
+		return err
+	}
+	if isKeyInitialized() {
+		yellowBold("There is already a scanner key. Please use 'forta keys rotate' to replace it.\n")
+		return errors.New("key exists")
+	}
+	ks := keystore.NewKeyStore(cfg.KeyDirPath, keystore.StandardScryptN, keystore.StandardScryptP)
+	account, err := ks.NewAccount(cfg.Passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to create the key: %v", err)
+	}
+	printScannerAddress(account.Address.Hex())
+	return nil
+}
+
+// handleFortaKeysImport imports a private key hex or a keystore file as the scanner key. The current key
+// is archived instead of being removed.
+func handleFortaKeysImport(cmd *cobra.Command, args []string) error {
+	if err := requirePassphrase(); err != nil {
+		return err
+	}
+	filePath, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return err
+	}
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read the key file: %v", err)
+	}
+	key, err := parseImportedKey(b)
+	if err != nil {
+		return err
+	}
+
+	if isKeyInitialized() {
+		currentKey, err := security.LoadKeyWithPassphrase(cfg.KeyDirPath, cfg.Passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to load scanner key: %v", err)
+		}
+		if currentKey.Address == key.Address {
+			yellowBold("The key %s is already the scanner key.\n", key.Address.Hex())
+			return nil
+		}
+		if !force {
+			yellowBold("The current scanner key %s will be archived. Please use --force to continue.\n", currentKey.Address.Hex())
+			return errors.New("key exists")
+		}
+		if err := archiveKeyDir(cfg.KeyDirPath, currentKey.Address.Hex()); err != nil {
+			return fmt.Errorf("failed to archive the current key: %v", err)
+		}
+		whiteBold("Archived the current key %s\n", currentKey.Address.Hex())
+	}
+
+	ks := keystore.NewKeyStore(cfg.KeyDirPath, keystore.StandardScryptN, keystore.StandardScryptP)
+	account, err := ks.ImportECDSA(key.PrivateKey, cfg.Passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to import: %v", err)
+	}
+	if err := audit.Append(cfg, "cli", audit.EventKeyUsed, map[string]string{
+		"address": account.Address.Hex(),
+		"purpose": "import",
+	}); err != nil {
+		return fmt.Errorf("failed to write the audit log: %v", err)
+	}
+	printScannerAddress(account.Address.Hex())
+	whiteBold("\nPlease restart your node if it is running.\n")
+	return nil
+}
+
+// parseImportedKey parses a private key hex or decrypts a keystore file.
+func parseImportedKey(b []byte) (*keystore.Key, error) {
+	content := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(content, "{") {
+		privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(content, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the private key hex: %v", err)
+		}
+		return &keystore.Key{Address: crypto.PubkeyToAddress(privateKey.PublicKey), PrivateKey: privateKey}, nil
+	}
+
+	// try the node passphrase first and then ask for the passphrase of the file
+	if key, err := keystore.DecryptKey(b, cfg.Passphrase); err == nil {
+		return key, nil
+	}
+	if !isTerminal() {
+		return nil, errors.New("failed to decrypt the keystore file with the passphrase")
+	}
+	passphrase, err := prompt.Stdin.PromptPassword("Keystore file passphrase: ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the passphrase: %v", err)
+	}
+	key, err := keystore.DecryptKey(b, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the keystore file: %v", err)
+	}
+	return key, nil
+}
+
+// handleFortaKeysExport writes the scanner key as a keystore file which is encrypted with the export
+// passphrase. The node passphrase is used if the export passphrase can't be asked.
+func handleFortaKeysExport(cmd *cobra.Command, args []string) error {
+	if err := requirePassphrase(); err != nil {
+		return err
+	}
+	outPath, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(outPath); err == nil {
+		return fmt.Errorf("%s already exists", outPath)
+	}
+	key, err := security.LoadKeyWithPassphrase(cfg.KeyDirPath, cfg.Passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to load scanner key: %v", err)
+	}
+
+	exportPassphrase := cfg.Passphrase
+	if isTerminal() {
+		whiteBold("Please enter the passphrase which encrypts the exported key.\n")
+		if exportPassphrase, err = promptNewPassphrase(); err != nil {
+			return err
+		}
+	} else {
+		yellowBold("The exported key is encrypted with the node passphrase.\n")
+	}
+	if err := audit.Append(cfg, "cli", audit.EventKeyUsed, map[string]string{
+		"address": key.Address.Hex(),
+		"purpose": "export",
+	}); err != nil {
+		return fmt.Errorf("failed to write the audit log: %v", err)
+	}
+	b, err := keystore.EncryptKey(key, exportPassphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt the key: %v", err)
+	}
+	if err := ioutil.WriteFile(outPath, b, 0600); err != nil {
+		return fmt.Errorf("failed to write the key: %v", err)
+	}
+	greenBold("Exported the scanner key %s to %s\n", key.Address.Hex(), outPath)
+	return nil
+}
+
+func handleFortaKeysList(cmd *cobra.Command, args []string) error {
+	var found bool
+	printKeys := func(dir, status string) {
+		ks := keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP)
+		for _, account := range ks.Accounts() {
+			cmd.Printf("%s  %s\n", account.Address.Hex(), status)
+			found = true
+		}
+	}
+	printKeys(cfg.KeyDirPath, "active")
+	printKeys(pendingKeyDirPath(), "pending (complete with 'forta keys rotate --complete')")
+	entries, _ := os.ReadDir(archivedKeysDirPath())
+	for _, entry := range entries {
+		if entry.IsDir() {
+			printKeys(path.Join(archivedKeysDirPath(), entry.Name()), "archived")
+		}
+	}
+	if !found {
+		yellowBold("No keys found - please create one with 'forta keys create' or 'forta init'.\n")
+	}
+	return nil
+}
+
+// archiveKeyDir moves the key dir to the archive dir.
+func archiveKeyDir(keyDir, addressStr string) error {
+	archiveDir := path.Join(archivedKeysDirPath(), strings.ToLower(addressStr))
+	if err := os.MkdirAll(path.Dir(archiveDir), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(archiveDir); err == nil {
+		return fmt.Errorf("%s already exists", archiveDir)
+	}
+	return os.Rename(keyDir, archiveDir)
+}
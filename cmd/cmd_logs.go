@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+	"github.com/spf13/cobra"
+)
+
+const logTimestampFormat = "2006-01-02T15:04:05.000Z"
+
+// logServices are the node services which run in containers, in the order of the labels.
+var logServices = []string{"supervisor", "updater", "scanner", "json-rpc", "nats", "ipfs"}
+
+// logLine is a container log line with the service label.
+type logLine struct {
+	Time    time.Time
+	Service string
+	Text    string
+}
+
+func handleFortaLogs(cmd *cobra.Command, args []string) error {
+	services, err := cmd.Flags().GetStringSlice("service")
+	if err != nil {
+		return err
+	}
+	follow, err := cmd.Flags().GetBool("follow")
+	if err != nil {
+		return err
+	}
+	tail, err := cmd.Flags().GetInt("tail")
+	if err != nil {
+		return err
+	}
+	if len(services) == 0 {
+		services = logServices
+	}
+	for _, service := range services {
+		if !isLogService(service) {
+			return fmt.Errorf("unknown service %q - please use one of: %s", service, strings.Join(logServices, ", "))
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	dockerClient, err := clients.NewRuntimeClient("", cfg.ContainerRuntime, cfg.ContainerRuntime.HostSocketPath())
+	if err != nil {
+		return fmt.Errorf("failed to create the container runtime client: %v", err)
+	}
+	containerIDs, err := findServiceContainers(ctx, dockerClient, services)
+	if err != nil {
+		return err
+	}
+	width := 0
+	for service := range containerIDs {
+		if len(service) > width {
+			width = len(service)
+		}
+	}
+
+	tailStr := "all"
+	if tail >= 0 {
+		tailStr = strconv.Itoa(tail)
+	}
+	var history []logLine
+	lastTimestamps := make(map[string]time.Time)
+	for service, containerID := range containerIDs {
+		logs, err := dockerClient.GetContainerLogs(ctx, containerID, tailStr, -1)
+		if err != nil {
+			return fmt.Errorf("failed to get the %s logs: %v", service, err)
+		}
+		lines := parseLogLines(service, logs)
+		if len(lines) > 0 {
+			lastTimestamps[service] = lines[len(lines)-1].Time
+		}
+		history = append(history, lines...)
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Time.Before(history[j].Time)
+	})
+	out := cmd.OutOrStdout()
+	for _, line := range history {
+		fmt.Fprintln(out, formatLogLine(line, width))
+	}
+	if !follow {
+		return nil
+	}
+
+	linesCh := make(chan logLine)
+	var wg sync.WaitGroup
+	now := time.Now()
+	for service, containerID := range containerIDs {
+		since, ok := lastTimestamps[service]
+		if !ok {
+			since = now
+		}
+		wg.Add(1)
+		go func(service, containerID string, since time.Time) {
+			defer wg.Done()
+			followServiceLogs(ctx, dockerClient, service, containerID, since, linesCh)
+		}(service, containerID, since)
+	}
+	go func() {
+		wg.Wait()
+		close(linesCh)
+	}()
+	for line := range linesCh {
+		fmt.Fprintln(out, formatLogLine(line, width))
+	}
+	return nil
+}
+
+func isLogService(service string) bool {
+	for _, logService := range logServices {
+		if service == logService {
+			return true
+		}
+	}
+	return false
+}
+
+// findServiceContainers returns the container IDs of the services by the service names.
+func findServiceContainers(ctx context.Context, dockerClient clients.DockerClient, services []string) (map[string]string, error) {
+	containers, err := dockerClient.GetContainers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the containers: %v", err)
+	}
+	containerIDs := make(map[string]string)
+	for _, service := range services {
+		name := fmt.Sprintf("%s-%s", config.ContainerNamePrefix, service)
+		for _, container := range containers {
+			if len(container.Names) > 0 && container.Names[0][1:] == name { // remove / in the beginning
+				containerIDs[service] = container.ID
+			}
+		}
+	}
+	if len(containerIDs) == 0 {
+		return nil, errors.New("no node containers found - is the node running?")
+	}
+	return containerIDs, nil
+}
+
+// followServiceLogs sends the log lines which were written after the given time.
+func followServiceLogs(ctx context.Context, dockerClient clients.DockerClient, service, containerID string, since time.Time, linesCh chan<- logLine) {
+	logs, err := dockerClient.FollowContainerLogs(ctx, containerID, since)
+	if err != nil {
+		redBold("failed to follow the %s logs: %v\n", service, err)
+		return
+	}
+	defer logs.Close()
+
+	previous := since
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		line := parseLogLine(service, scanner.Text(), previous)
+		// the lines at the same time as the last history line are written again
+		if !line.Time.After(since) {
+			continue
+		}
+		previous = line.Time
+		select {
+		case linesCh <- line:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func parseLogLines(service, logs string) []logLine {
+	var (
+		lines []logLine
+		last  time.Time
+	)
+	for _, text := range strings.Split(logs, "\n") {
+		if len(strings.TrimSpace(text)) == 0 {
+			continue
+		}
+		line := parseLogLine(service, text, last)
+		last = line.Time
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseLogLine splits the timestamp which the container runtime adds to the line. The lines without
+// a timestamp get the time of the previous line.
+func parseLogLine(service, text string, previous time.Time) logLine {
+	parts := strings.SplitN(text, " ", 2)
+	ts, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil || len(parts) < 2 {
+		return logLine{Time: previous, Service: service, Text: text}
+	}
+	return logLine{Time: ts, Service: service, Text: parts[1]}
+}
+
+func formatLogLine(line logLine, width int) string {
+	return fmt.Sprintf("%s %-*s | %s", line.Time.UTC().Format(logTimestampFormat), width, line.Service, line.Text)
+}
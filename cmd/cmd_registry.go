@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/fatih/color"
+	"github.com/forta-network/forta-core-go/ethereum"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/services/registry"
+	"github.com/forta-network/forta-node/store"
+	"github.com/spf13/cobra"
+)
+
+func getScannerAddress() (string, error) {
+	ks := keystore.NewKeyStore(cfg.KeyDirPath, keystore.StandardScryptN, keystore.StandardScryptP)
+	accounts := ks.Accounts()
+	if len(accounts) != 1 {
+		redBold("Please make sure that you have a single scanner account. See 'forta account address'.\n")
+		return "", errors.New("no single scanner account")
+	}
+	return accounts[0].Address.Hex(), nil
+}
+
+func handleFortaRegistryResync(cmd *cobra.Command, args []string) error {
+	scannerAddress, err := getScannerAddress()
+	if err != nil {
+		return err
+	}
+
+	ethClient, err := ethereum.NewStreamEthClient(context.Background(), "registry", cfg.Registry.JsonRpc.Url)
+	if err != nil {
+		return err
+	}
+	reg, err := store.NewRegistryStore(context.Background(), cfg, ethClient)
+	if err != nil {
+		return fmt.Errorf("failed to initialize registry")
+	}
+
+	agentConfigs, err := reg.GetAgents(scannerAddress)
+	if err != nil {
+		return fmt.Errorf("failed to load the agents: %v", err)
+	}
+	cmd.Printf("Found %d agents in the registry:\n", len(agentConfigs))
+	for _, agentCfg := range agentConfigs {
+		cmd.Printf("%s %s\n", agentCfg.ID, color.New(color.FgYellow).Sprintf(agentCfg.Image))
+	}
+
+	// the running node reloads the agents when it sees the request
+	requestPath := path.Join(cfg.FortaDir, registry.ResyncRequestFileName)
+	if err := ioutil.WriteFile(requestPath, []byte{}, 0644); err != nil {
+		return fmt.Errorf("failed to write the resync request: %v", err)
+	}
+	greenBold("Requested the node to resync the agents! The running node will update its agents on the next registry check.\n")
+	return nil
+}
+
+func handleFortaRegistryDryRun(cmd *cobra.Command, args []string) error {
+	pools, err := cmd.Flags().GetStringSlice("pools")
+	if err != nil {
+		return err
+	}
+	scannerAddress, err := getScannerAddress()
+	if err != nil {
+		return err
+	}
+
+	ethClient, err := ethereum.NewStreamEthClient(context.Background(), "registry", cfg.Registry.JsonRpc.Url)
+	if err != nil {
+		return err
+	}
+	current, err := store.NewRegistryStore(context.Background(), cfg, ethClient)
+	if err != nil {
+		return fmt.Errorf("failed to initialize registry")
+	}
+	currentAgents, err := current.GetAgents(scannerAddress)
+	if err != nil {
+		return fmt.Errorf("failed to load the current agents: %v", err)
+	}
+
+	dryRunCfg := cfg
+	dryRunCfg.Registry.PoolIDs = pools
+	dryRun, err := store.NewRegistryStore(context.Background(), dryRunCfg, ethClient)
+	if err != nil {
+		return fmt.Errorf("failed to initialize registry")
+	}
+	dryRunAgents, err := dryRun.GetAgents(scannerAddress)
+	if err != nil {
+		return fmt.Errorf("failed to load the agents of the pools: %v", err)
+	}
+
+	mc, err := store.NewManifestClient(cfg.Registry.IPFS, nil)
+	if err != nil {
+		return fmt.Errorf("failed to initialize the manifest client: %v", err)
+	}
+
+	changes := registry.CompareAgents(currentAgents, dryRunAgents)
+	cmd.Printf("Current agents: %d, agents with pools %v: %d\n", len(currentAgents), pools, len(dryRunAgents))
+	printAgentChanges(cmd, mc, "Added", color.FgGreen, changes.Added)
+	printAgentChanges(cmd, mc, "Updated", color.FgYellow, changes.Updated)
+	printAgentChanges(cmd, mc, "Removed", color.FgRed, changes.Removed)
+	if len(changes.Added)+len(changes.Updated)+len(changes.Removed) == 0 {
+		greenBold("No changes.\n")
+	}
+	return nil
+}
+
+func printAgentChanges(cmd *cobra.Command, mc store.ManifestClient, title string, titleColor color.Attribute, agents []*config.AgentConfig) {
+	if len(agents) == 0 {
+		return
+	}
+	cmd.Printf("%s (%d):\n", color.New(titleColor, color.Bold).Sprint(title), len(agents))
+	for _, agentCfg := range agents {
+		cmd.Printf("  %s\n", agentCfg.ID)
+		cmd.Printf("    image: %s\n", color.New(color.FgYellow).Sprint(agentCfg.Image))
+		cmd.Printf("    manifest: %s\n", agentCfg.Manifest)
+		cmd.Printf("    pools: %v\n", agentCfg.Pools)
+		if len(agentCfg.ChainIDs) > 0 {
+			cmd.Printf("    chain IDs: %v\n", agentCfg.ChainIDs)
+		}
+		if len(agentCfg.Manifest) == 0 {
+			continue
+		}
+		m, err := mc.GetAgentManifest(context.Background(), agentCfg.Manifest)
+		if err != nil {
+			cmd.Printf("    (failed to get the manifest: %v)\n", err)
+			continue
+		}
+		if m.Manifest.Name != nil {
+			cmd.Printf("    name: %s\n", *m.Manifest.Name)
+		}
+		if m.Manifest.Version != nil {
+			cmd.Printf("    version: %s\n", *m.Manifest.Version)
+		}
+		if m.Manifest.Repository != nil {
+			cmd.Printf("    repository: %s\n", *m.Manifest.Repository)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/forta-network/forta-node/cmd/runner"
+	"github.com/spf13/cobra"
+)
+
+func handleFortaReplay(cmd *cobra.Command, args []string) error {
+	from, err := cmd.Flags().GetUint64("from")
+	if err != nil {
+		return err
+	}
+	to, err := cmd.Flags().GetUint64("to")
+	if err != nil {
+		return err
+	}
+	if to < from {
+		return fmt.Errorf("--to (%d) must not be less than --from (%d)", to, from)
+	}
+	if to == 0 {
+		return fmt.Errorf("--to must be greater than zero")
+	}
+
+	cfg.Scan.StartBlock = int(from)
+	cfg.Scan.EndBlock = int(to)
+	greenBold("Replaying blocks %d-%d. The alerts are written to %s\n", from, to, cfg.ReplayDir())
+	runner.Run(cfg)
+	return nil
+}
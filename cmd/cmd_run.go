@@ -7,6 +7,8 @@ import (
 
 	"github.com/forta-network/forta-core-go/registry"
 	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/cmd/dev"
 	"github.com/forta-network/forta-node/cmd/runner"
 	"github.com/forta-network/forta-node/store"
 	"github.com/spf13/cobra"
@@ -15,9 +17,22 @@ import (
 // errors
 var (
 	ErrCannotRunScanner = errors.New("cannot run scanner")
+	ErrLocalMessaging   = errors.New("the local messaging backend is only available with 'forta run --dev'")
 )
 
 func handleFortaRun(cmd *cobra.Command, args []string) error {
+	devMode, err := cmd.Flags().GetBool("dev")
+	if err != nil {
+		return err
+	}
+	if err := checkMessagingBackend(devMode); err != nil {
+		return err
+	}
+	// the dev process doesn't need a registered scanner
+	if devMode {
+		dev.Run(cfg)
+		return nil
+	}
 	if err := checkScannerState(); err != nil {
 		return err
 	}
@@ -25,6 +40,15 @@ func handleFortaRun(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// checkMessagingBackend rejects the local backend outside the dev process, since the node containers
+// cannot reach an in-process bus and would silently use NATS instead.
+func checkMessagingBackend(devMode bool) error {
+	if !devMode && cfg.Messaging.Backend == messaging.BackendLocal {
+		return ErrLocalMessaging
+	}
+	return nil
+}
+
 func checkScannerState() error {
 	if parsedArgs.NoCheck {
 		return nil
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMessagingBackend(t *testing.T) {
+	r := require.New(t)
+
+	backend := cfg.Messaging.Backend
+	defer func() { cfg.Messaging.Backend = backend }()
+
+	cfg.Messaging.Backend = messaging.BackendNATS
+	r.NoError(checkMessagingBackend(false))
+	r.NoError(checkMessagingBackend(true))
+
+	cfg.Messaging.Backend = messaging.BackendLocal
+	r.Equal(ErrLocalMessaging, checkMessagingBackend(false))
+	r.NoError(checkMessagingBackend(true))
+}
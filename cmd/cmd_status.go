@@ -8,8 +8,10 @@ import (
 	"io"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/docker/go-units"
 	"github.com/fatih/color"
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-node/config"
@@ -96,6 +98,81 @@ func handleFortaStatus(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// overviewServices are the node containers which report the statuses of the node services. The scanner
+// container runs the scanner, the registry and the publisher services.
+var overviewServices = []string{
+	config.DockerSupervisorContainerName,
+	config.DockerScannerContainerName,
+	config.DockerJSONRPCProxyContainerName,
+}
+
+func handleFortaStatusOverview(cmd *cobra.Command, args []string) error {
+	noColor, err := cmd.Flags().GetBool("no-color")
+	if err != nil {
+		return err
+	}
+	if noColor {
+		color.NoColor = true
+		ballPrefix = ""
+	}
+
+	// call the runner health server on localhost
+	reports := health.NewClient().CheckHealth("forta", config.DefaultHealthPort)
+	fmt.Fprint(os.Stdout, formatOverview(reports))
+	return nil
+}
+
+// formatOverview summarizes the service statuses and the important numbers which are found in the reports.
+func formatOverview(reports health.Reports) string {
+	w := new(bytes.Buffer)
+	for _, service := range overviewServices {
+		containerName := fmt.Sprintf("forta.container.%s", service)
+		status, details := health.StatusUnknown, "not found"
+		if report, ok := reports.GetByName(containerName); ok {
+			status, details = report.Status, report.Details
+		}
+		// the summary of the service tells more if the container is running
+		if summary, ok := reports.GetByName(fmt.Sprintf("%s.summary", containerName)); ok && status == health.StatusOK {
+			status = summary.Status
+			if len(summary.Details) > 0 {
+				details = summary.Details
+			}
+		}
+		writeStatusBall(w, status)
+		writeName(w, service)
+		fmt.Fprint(w, ": ")
+		writeDetails(w, status, details)
+		fmt.Fprint(w, "\n")
+	}
+	fmt.Fprint(w, "\n")
+
+	scannerReport := func(name string) string {
+		report, ok := reports.GetByName(fmt.Sprintf("forta.container.%s.service.%s", config.DockerScannerContainerName, name))
+		if !ok || len(report.Details) == 0 {
+			return "?"
+		}
+		return report.Details
+	}
+	writeOverviewLine(w, "chain lag", fmt.Sprintf("%s blocks", scannerReport("tx-stream.chain.lag")))
+	writeOverviewLine(w, "agents", fmt.Sprintf(
+		"%s running, %s failed", scannerReport("agent-pool.agents.total"), scannerReport("agent-pool.agents.failed"),
+	))
+	storeSize := scannerReport("registry.manifest-cache.size")
+	if size, err := strconv.ParseInt(storeSize, 10, 64); err == nil {
+		storeSize = units.HumanSize(float64(size))
+	}
+	writeOverviewLine(w, "store size", storeSize)
+	writeOverviewLine(w, "publisher backlog", fmt.Sprintf(
+		"%s alerts, %s batches", scannerReport("publisher.backlog.alerts"), scannerReport("publisher.backlog.batches"),
+	))
+	return w.String()
+}
+
+func writeOverviewLine(w io.Writer, name, value string) {
+	writeName(w, fmt.Sprintf("%-18s", name+":"))
+	fmt.Fprintln(w, value)
+}
+
 func formatReportsPretty(reports health.Reports) {
 	w := new(bytes.Buffer)
 	for _, report := range reports {
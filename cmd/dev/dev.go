@@ -0,0 +1,102 @@
+package dev
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/forta-network/forta-node/audit"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/cmd/scanner"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/crash"
+	"github.com/forta-network/forta-node/logutils"
+	"github.com/forta-network/forta-node/services"
+	"github.com/forta-network/forta-node/services/runner"
+	"github.com/nats-io/nats-server/v2/server"
+	log "github.com/sirupsen/logrus"
+)
+
+const natsReadyTimeout = time.Second * 10
+
+// startNatsServer starts the NATS server which the dev process services talk through.
+func startNatsServer() (*server.Server, error) {
+	port, err := strconv.Atoi(config.DefaultNatsPort)
+	if err != nil {
+		return nil, err
+	}
+	natsServer, err := server.NewServer(&server.Options{
+		Host: "127.0.0.1",
+		Port: port,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the nats server: %v", err)
+	}
+	go natsServer.Start()
+	if !natsServer.ReadyForConnections(natsReadyTimeout) {
+		natsServer.Shutdown()
+		return nil, fmt.Errorf("nats server is not ready at port %d", port)
+	}
+	return natsServer, nil
+}
+
+// Run runs the scanner, the registry and the process agents in a single process without Docker.
+// The alerts are kept in memory and served at the alerts API.
+func Run(cfg config.Config) {
+	cfg.DevProcess = true
+	ctx, cancel := services.InitMainContext()
+	defer cancel()
+
+	logger := log.WithField("process", "dev")
+	lvl, err := log.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		logger.WithError(err).Error("could not initialize log level")
+		return
+	}
+	log.SetLevel(lvl)
+	crash.Init(cfg, "dev")
+	defer crash.Capture("dev")
+	logFile, err := logutils.InitFileOutput(cfg, "dev")
+	if err != nil {
+		logger.WithError(err).Error("could not initialize the log file")
+		return
+	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
+	auditLog, err := audit.Init(cfg, "dev")
+	if err != nil {
+		logger.WithError(err).Error("could not initialize the audit log")
+		return
+	}
+	defer auditLog.Close()
+	logger.Info("starting")
+	defer logger.Info("exiting")
+
+	// the services talk through the in-process bus without a broker if the local backend is selected
+	if cfg.Messaging.Backend != messaging.BackendLocal {
+		natsServer, err := startNatsServer()
+		if err != nil {
+			logger.WithError(err).Error("could not start the nats server")
+			return
+		}
+		defer natsServer.Shutdown()
+	}
+
+	scannerServices, err := scanner.InitDevServices(ctx, cfg)
+	if err != nil {
+		logger.WithError(err).Error("could not initialize services")
+		return
+	}
+	msgClient := messaging.NewBackendClient("dev-agents", cfg.Messaging, fmt.Sprintf("localhost:%s", config.DefaultNatsPort))
+	serviceList := append([]services.Service{
+		runner.NewDevAgents(ctx, msgClient),
+		runner.NewProcessAgents(ctx, cfg),
+	}, scannerServices...)
+
+	services.StartPprof(ctx, cfg)
+	logger.WithField("alerts", fmt.Sprintf("http://localhost:%s/alerts", config.DefaultAlertsPort)).Info("serving the alerts")
+	if err := services.StartServices(ctx, cancel, logger, serviceList); err != nil {
+		logger.WithError(err).Error("error running services")
+	}
+}
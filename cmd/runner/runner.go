@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/forta-network/forta-node/audit"
 	"github.com/forta-network/forta-node/clients"
 	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/crash"
+	"github.com/forta-network/forta-node/logutils"
 	"github.com/forta-network/forta-node/services"
 	"github.com/forta-network/forta-node/services/runner"
 	"github.com/forta-network/forta-node/store"
@@ -18,21 +21,36 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the image store: %v", err)
 	}
-	dockerClient, err := clients.NewDockerClient("runner")
+	dockerClient, err := clients.NewRuntimeClient("runner", cfg.ContainerRuntime, cfg.ContainerRuntime.HostSocketPath())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the docker client: %v", err)
 	}
-	globalDockerClient, err := clients.NewDockerClient("")
+	globalDockerClient, err := clients.NewRuntimeClient("", cfg.ContainerRuntime, cfg.ContainerRuntime.HostSocketPath())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the docker client: %v", err)
 	}
 
+	if cfg.ContainerRuntime.Runtime != config.ContainerRuntimeDocker {
+		log.WithFields(log.Fields{
+			"runtime": cfg.ContainerRuntime.Runtime,
+			"socket":  cfg.ContainerRuntime.HostSocketPath(),
+		}).Info("using alternative container runtime")
+	}
+
 	if cfg.Development {
 		log.Warn("running in development mode")
 	}
 
+	nodeRunner := runner.NewRunner(ctx, cfg, imgStore, dockerClient, globalDockerClient)
 	return []services.Service{
-		runner.NewRunner(ctx, cfg, imgStore, dockerClient, globalDockerClient),
+		nodeRunner,
+		runner.NewProcessAgents(ctx, cfg),
+		runner.NewAgentLogs(ctx, globalDockerClient),
+		runner.NewAdminAPI(ctx, cfg, nodeRunner),
+		runner.NewMetricsAPI(ctx, cfg, nodeRunner),
+		runner.NewNodeHealthAPI(ctx, cfg, nodeRunner),
+		runner.NewTelemetryStatsReporter(ctx, cfg, nodeRunner),
+		runner.NewDashboard(ctx, cfg, nodeRunner),
 	}, nil
 }
 
@@ -42,6 +60,22 @@ func Run(cfg config.Config) {
 	defer cancel()
 
 	logger := log.WithField("process", "runner")
+	crash.Init(cfg, "runner")
+	defer crash.Capture("runner")
+	logFile, err := logutils.InitFileOutput(cfg, "runner")
+	if err != nil {
+		logger.WithError(err).Error("could not initialize the log file")
+		return
+	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
+	auditLog, err := audit.Init(cfg, "runner")
+	if err != nil {
+		logger.WithError(err).Error("could not initialize the audit log")
+		return
+	}
+	defer auditLog.Close()
 	logger.Info("starting")
 	defer logger.Info("exiting")
 
@@ -50,6 +84,7 @@ func Run(cfg config.Config) {
 		logger.WithError(err).Error("could not initialize services")
 		return
 	}
+	services.StartPprof(ctx, cfg)
 
 	if err := services.StartServices(ctx, cancel, log.NewEntry(log.StandardLogger()), serviceList); err != nil {
 		logger.WithError(err).Error("error running services")
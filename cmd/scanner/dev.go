@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-node/audit"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/healthutils"
+	"github.com/forta-network/forta-node/services"
+	"github.com/forta-network/forta-node/services/publisher"
+	"github.com/forta-network/forta-node/store"
+)
+
+// InitDevServices creates the scanner services of the dev process. The services use the host paths and
+// the local message bus, and the alerts are kept in memory and served by the alerts API instead of
+// being published.
+func InitDevServices(ctx context.Context, cfg config.Config) ([]services.Service, error) {
+	cfg.DevProcess = true
+
+	msgClient := messaging.NewBackendClient("scanner", cfg.Messaging, fmt.Sprintf("localhost:%s", config.DefaultNatsPort))
+
+	key, err := security.LoadKeyWithPassphrase(cfg.KeyDirPath, cfg.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scanner key: %v", err)
+	}
+	audit.Record(audit.EventKeyUsed, map[string]string{"address": key.Address.Hex(), "purpose": "scanner"})
+
+	alertStore := store.NewMemoryAlertStore(store.DefaultMemoryAlertsSize)
+	as, err := initAlertSender(ctx, key, alertStore)
+	if err != nil {
+		return nil, err
+	}
+
+	scannerSvcs, healthReporters, err := initScannerServices(ctx, cfg, key, as, msgClient)
+	if err != nil {
+		return nil, err
+	}
+	svcs := []services.Service{
+		health.NewService(ctx, "", healthutils.DefaultHealthServerErrHandler, health.CheckerFrom(
+			summarizeReports, healthReporters...,
+		)),
+		publisher.NewAlertsAPI(ctx, alertStore),
+	}
+	return append(svcs, scannerSvcs...), nil
+}
@@ -3,6 +3,10 @@ package scanner
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -11,32 +15,109 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/clients/webhook"
 	"github.com/forta-network/forta-core-go/ethereum"
 	"github.com/forta-network/forta-core-go/feeds"
 	"github.com/forta-network/forta-core-go/security"
 	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/audit"
 	"github.com/forta-network/forta-node/clients"
 	"github.com/forta-network/forta-node/clients/messaging"
 	"github.com/forta-network/forta-node/config"
 	"github.com/forta-network/forta-node/healthutils"
+	"github.com/forta-network/forta-node/rpccache"
 	"github.com/forta-network/forta-node/services"
 	"github.com/forta-network/forta-node/services/registry"
 	"github.com/forta-network/forta-node/services/scanner"
 	"github.com/forta-network/forta-node/services/scanner/agentpool"
+	"github.com/forta-network/forta-node/store"
+	log "github.com/sirupsen/logrus"
 )
 
-func initTxStream(ctx context.Context, ethClient, traceClient ethereum.Client, cfg config.Config) (*scanner.TxStreamService, feeds.BlockFeed, error) {
-	cfg.Scan.JsonRpc.Url = utils.ConvertToDockerHostURL(cfg.Scan.JsonRpc.Url)
-	cfg.Registry.JsonRpc.Url = utils.ConvertToDockerHostURL(cfg.Registry.JsonRpc.Url)
-	cfg.Registry.IPFS.APIURL = utils.ConvertToDockerHostURL(cfg.Registry.IPFS.APIURL)
-	cfg.Registry.IPFS.GatewayURL = utils.ConvertToDockerHostURL(cfg.Registry.IPFS.GatewayURL)
+// dockerHostURL converts the localhost URLs so that the scanner container can reach the host. The URLs
+// are used as they are in the dev process.
+func dockerHostURL(cfg config.Config, rawurl string) string {
+	if cfg.DevProcess {
+		return rawurl
+	}
+	return utils.ConvertToDockerHostURL(rawurl)
+}
+
+// initScanRPCClient creates the client for the chain data requests which the core client doesn't support.
+func initScanRPCClient(cfg config.Config, failover *scanner.ProviderFailover) (*rpc.Client, error) {
+	url := cfg.Scan.JsonRpc.Url
+	if failover != nil {
+		url = failover.URL()
+	}
+	rpcClient, err := ethereum.NewRpcClient(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the scan rpc client: %v", err)
+	}
+	rpcClient.SetHeader("Content-Type", "application/json")
+	for h, v := range cfg.Scan.JsonRpc.Headers {
+		rpcClient.SetHeader(h, v)
+	}
+	return rpcClient, nil
+}
+
+// blockOffset returns the number of confirmations which the blocks need before they are processed.
+// The replayed blocks are already confirmed.
+func blockOffset(cfg config.Config) int {
+	if cfg.IsReplay() {
+		return 0
+	}
+	if cfg.Scan.Confirmations != nil {
+		return *cfg.Scan.Confirmations
+	}
+	return config.GetBlockOffset(cfg.ChainID)
+}
+
+// initCheckpoint loads the block checkpoint of the chain and returns the block which the block feed should
+// start from. The block feed starts from the latest block if there is no checkpoint.
+func initCheckpoint(
+	ctx context.Context, cfg config.Config, blockClient ethereum.Client,
+) (*scanner.BlockCheckpoint, *big.Int, error) {
+	checkpoint := scanner.NewBlockCheckpoint(store.NewFileStringStore(
+		path.Join(cfg.FortaDir, fmt.Sprintf(".last-block-%d", cfg.ChainID)),
+	))
+	last, ok := checkpoint.Load()
+	if !ok {
+		return checkpoint, nil, nil
+	}
+	head, err := blockClient.BlockNumber(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the latest block for the checkpoint: %v", err)
+	}
+	start := scanner.StartBlock(last, head.Uint64(), blockOffset(cfg), uint64(cfg.Scan.Checkpoint.MaxBackfill))
+	log.WithFields(log.Fields{
+		"chainId":    cfg.ChainID,
+		"checkpoint": last,
+		"start":      start,
+	}).Info("resuming from the block checkpoint")
+	return checkpoint, new(big.Int).SetUint64(start), nil
+}
+
+// isFileDataSource tells if the blocks are read from a file instead of the JSON-RPC providers.
+func isFileDataSource(cfg config.Config) bool {
+	return cfg.Scan.DataSource.Type == scanner.DataSourceFile
+}
+
+func initTxStream(
+	ctx context.Context, ethClient, traceClient ethereum.Client, rpcClient *rpc.Client, cfg config.Config,
+) (*scanner.TxStreamService, scanner.DataSource, error) {
+	cfg.Scan.JsonRpc.Url = dockerHostURL(cfg, cfg.Scan.JsonRpc.Url)
+	cfg.Registry.JsonRpc.Url = dockerHostURL(cfg, cfg.Registry.JsonRpc.Url)
+	cfg.Registry.IPFS.APIURL = dockerHostURL(cfg, cfg.Registry.IPFS.APIURL)
+	cfg.Registry.IPFS.GatewayURL = dockerHostURL(cfg, cfg.Registry.IPFS.GatewayURL)
 
 	url := cfg.Scan.JsonRpc.Url
 	chainID := config.ParseBigInt(cfg.ChainID)
 
-	if url == "" {
+	fileSource := isFileDataSource(cfg)
+	if url == "" && !fileSource {
 		return nil, nil, fmt.Errorf("scan.jsonRpc.url is required")
 	}
 	if cfg.Trace.Enabled && cfg.Trace.JsonRpc.Url == "" {
@@ -52,21 +133,87 @@ func initTxStream(ctx context.Context, ethClient, traceClient ethereum.Client, c
 	if cfg.Scan.BlockMaxAgeSeconds > 0 {
 		maxAge = time.Duration(cfg.Scan.BlockMaxAgeSeconds) * time.Second
 	}
-	blockFeed, err := feeds.NewBlockFeed(ctx, ethClient, traceClient, feeds.BlockFeedConfig{
-		ChainID:             chainID,
-		Tracing:             cfg.Trace.Enabled,
-		RateLimit:           rateLimit,
-		SkipBlocksOlderThan: &maxAge,
-		Offset:              config.GetBlockOffset(cfg.ChainID),
-	})
-	if err != nil {
-		return nil, nil, err
+	skipBlocksOlderThan := &maxAge
+	if fileSource {
+		// the recorded blocks are older than the max age
+		skipBlocksOlderThan = nil
+	}
+	blockClient := ethClient
+	if cfg.Scan.Finalized && !cfg.IsReplay() && !fileSource {
+		blockClient = scanner.NewFinalizedClient(ethClient, rpcClient, time.Duration(cfg.Scan.FinalizedPollSeconds)*time.Second)
+		// the finalized blocks can be older than the max age
+		skipBlocksOlderThan = nil
+	}
+
+	var (
+		checkpoint *scanner.BlockCheckpoint
+		startBlock *big.Int
+		err        error
+	)
+	if cfg.Scan.Checkpoint.Enabled && !cfg.IsReplay() && !fileSource {
+		checkpoint, startBlock, err = initCheckpoint(ctx, cfg, blockClient)
+		if err != nil {
+			return nil, nil, err
+		}
+		// the blocks after the checkpoint can be older than the max age
+		skipBlocksOlderThan = nil
+	}
+
+	// the lag is not tracked for the replayed and the recorded blocks
+	var lagTracker *scanner.ChainLagTracker
+	if !cfg.IsReplay() && !fileSource {
+		lagTracker = scanner.NewChainLagTracker(ctx, blockClient, blockOffset(cfg), cfg.Scan.CatchUp)
+		if cfg.Scan.CatchUp.SkipTraces {
+			traceClient = scanner.NewCatchUpTraceClient(traceClient, lagTracker)
+		}
+	}
+
+	var blockFeed scanner.DataSource
+	if fileSource {
+		blockFeed = scanner.NewFileDataSource(ctx, cfg.Scan.DataSource.Path, chainID, rateLimit)
+	} else {
+		blockFeed, err = scanner.NewRPCDataSource(ctx, blockClient, traceClient, feeds.BlockFeedConfig{
+			Start:               startBlock,
+			ChainID:             chainID,
+			Tracing:             cfg.Trace.Enabled,
+			RateLimit:           rateLimit,
+			SkipBlocksOlderThan: skipBlocksOlderThan,
+			Offset:              blockOffset(cfg),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var receiptFetcher *scanner.ReceiptFetcher
+	// the l2 fields are in the receipts
+	l2Type := config.GetL2Type(cfg.ChainID, cfg.Scan)
+	if (cfg.Scan.Receipts.Enabled || len(l2Type) > 0) && !fileSource {
+		receiptFetcher = scanner.NewReceiptFetcher(rpcClient, cfg.Scan.Receipts.BatchSize, cfg.Scan.Receipts.Workers)
+	}
+
+	if lagTracker != nil {
+		lagTracker.OnCatchUp(func(catchingUp bool) {
+			blockRateLimit, receiptBatchSize := cfg.Scan.BlockRateLimit, cfg.Scan.Receipts.BatchSize
+			if catchingUp {
+				blockRateLimit, receiptBatchSize = cfg.Scan.CatchUp.BlockRateLimit, cfg.Scan.CatchUp.ReceiptBatchSize
+			}
+			if rateLimit != nil {
+				rateLimit.Reset(time.Duration(blockRateLimit) * time.Millisecond)
+			}
+			if receiptFetcher != nil {
+				receiptFetcher.SetBatchSize(receiptBatchSize)
+			}
+		})
 	}
 
 	txStream, err := scanner.NewTxStreamService(ctx, ethClient, blockFeed, scanner.TxStreamServiceConfig{
 		JsonRpcConfig:       cfg.Scan.JsonRpc,
 		TraceJsonRpcConfig:  cfg.Trace.JsonRpc,
-		SkipBlocksOlderThan: &maxAge,
+		SkipBlocksOlderThan: skipBlocksOlderThan,
+		ReceiptFetcher:      receiptFetcher,
+		LagTracker:          lagTracker,
+		Checkpoint:          checkpoint,
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create the tx stream service: %v", err)
@@ -75,12 +222,53 @@ func initTxStream(ctx context.Context, ethClient, traceClient ethereum.Client, c
 	return txStream, blockFeed, nil
 }
 
-func initTxAnalyzer(ctx context.Context, cfg config.Config, as clients.AlertSender, stream *scanner.TxStreamService, ap *agentpool.AgentPool, msgClient clients.MessageClient) (*scanner.TxAnalyzerService, error) {
+func initMempoolStream(ctx context.Context, cfg config.Config) (*scanner.MempoolStreamService, error) {
+	if cfg.Mempool.JsonRpc.Url == "" {
+		return nil, fmt.Errorf("mempool requires a jsonRpc URL if enabled")
+	}
+	return scanner.NewMempoolStreamService(ctx, scanner.MempoolStreamServiceConfig{
+		ChainID:          config.ParseBigInt(cfg.ChainID),
+		JsonRpc:          cfg.Mempool.JsonRpc,
+		Source:           cfg.Mempool.Source,
+		PollInterval:     time.Duration(cfg.Mempool.PollIntervalSeconds) * time.Second,
+		RetryInterval:    time.Duration(cfg.Mempool.RetryIntervalSeconds) * time.Second,
+		MaxRetryInterval: time.Duration(cfg.Mempool.MaxRetryIntervalSeconds) * time.Second,
+	})
+}
+
+func initPendingTxAnalyzer(ctx context.Context, cfg config.Config, stream *scanner.MempoolStreamService, ap *agentpool.AgentPool, msgClient clients.MessageClient) (*scanner.PendingTxAnalyzerService, error) {
+	var webhookClient webhook.AlertWebhookClient
+	if cfg.Mempool.WebhookURL != "" {
+		var err error
+		webhookClient, err = webhook.NewAlertWebhookClient(cfg.Mempool.WebhookURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pending tx alert webhook url: %s", cfg.Mempool.WebhookURL)
+		}
+	}
+	return scanner.NewPendingTxAnalyzerService(ctx, scanner.PendingTxAnalyzerServiceConfig{
+		TxChannel:     stream.ReadOnlyTxStream(),
+		AgentPool:     ap,
+		MsgClient:     msgClient,
+		WebhookClient: webhookClient,
+	})
+}
+
+func initTxAnalyzer(
+	ctx context.Context, cfg config.Config, as clients.AlertSender, stream *scanner.TxStreamService, ap *agentpool.AgentPool,
+	msgClient clients.MessageClient, canaries *scanner.CanaryService,
+) (*scanner.TxAnalyzerService, error) {
+	var l2Enricher *scanner.L2Enricher
+	if l2Type := config.GetL2Type(cfg.ChainID, cfg.Scan); len(l2Type) > 0 {
+		l2Enricher = scanner.NewL2Enricher(l2Type, stream.ReceiptFetcher())
+	}
 	return scanner.NewTxAnalyzerService(ctx, scanner.TxAnalyzerServiceConfig{
 		TxChannel:   stream.ReadOnlyTxStream(),
 		AlertSender: as,
 		AgentPool:   ap,
 		MsgClient:   msgClient,
+		L2Enricher:  l2Enricher,
+		Canaries:    canaries,
+		Checkpoint:  stream.Checkpoint(),
 	})
 }
 
@@ -93,6 +281,193 @@ func initBlockAnalyzer(ctx context.Context, cfg config.Config, as clients.AlertS
 	})
 }
 
+// initScanClient creates the client of the chain data. If fallback providers, upstream limits or the cache are
+// configured, the client connects to a local endpoint which limits and forwards the requests to the first
+// healthy provider.
+func initScanClient(
+	ctx context.Context, cfg config.Config, apiName string, chainID int, scanCfg config.ScannerConfig, cache *rpccache.Cache,
+	msgClient clients.MessageClient,
+) (ethereum.Client, *scanner.ProviderFailover, error) {
+	if len(scanCfg.FallbackJsonRpc) == 0 && !scanCfg.Upstream.Enabled() && cache == nil {
+		ethClient, err := ethereum.NewStreamEthClient(ctx, apiName, scanCfg.JsonRpc.Url)
+		return ethClient, nil, err
+	}
+	providers := scanProviders(cfg, scanCfg)
+	var limiter *scanner.UpstreamLimiter
+	if scanCfg.Upstream.Enabled() {
+		limiter = scanner.NewUpstreamLimiter(scanCfg.Upstream)
+	}
+	var cacheAddr string
+	if cache != nil {
+		// the json-rpc proxy of the agents connects to the cache on the node network
+		nodeIP, err := nodeNetworkIP()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find the node network address: %v", err)
+		}
+		cacheAddr = net.JoinHostPort(nodeIP, config.DefaultScannerCachePort)
+	}
+	failover, err := scanner.NewProviderFailover(ctx, scanner.ProviderFailoverConfig{
+		Name:      fmt.Sprintf("%s-provider-failover", apiName),
+		ChainID:   uint64(chainID),
+		Providers: providers,
+		Failover:  scanCfg.Failover,
+		Limiter:   limiter,
+		Cache:     cache,
+		CacheAddr: cacheAddr,
+		MsgClient: msgClient,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	ethClient, err := ethereum.NewStreamEthClient(ctx, apiName, failover.URL())
+	if err != nil {
+		return nil, nil, err
+	}
+	return ethClient, failover, nil
+}
+
+// nodeNetworkIP returns the address of the scanner on the node network. The hostname points to the
+// network which the container was created with and the agent networks are attached later.
+func nodeNetworkIP() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	addrs, err := net.LookupHost(hostname)
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && !ip.IsLoopback() {
+			return addr, nil
+		}
+	}
+	return "", fmt.Errorf("no addresses for %s", hostname)
+}
+
+// scanProviders returns the primary and the fallback providers of the chain data.
+func scanProviders(cfg config.Config, scanCfg config.ScannerConfig) []config.JsonRpcConfig {
+	providers := []config.JsonRpcConfig{scanCfg.JsonRpc}
+	for _, fallback := range scanCfg.FallbackJsonRpc {
+		fallback.Url = dockerHostURL(cfg, fallback.Url)
+		providers = append(providers, fallback)
+	}
+	return providers
+}
+
+// reloadScanClient applies the provider and the upstream limit changes of the chain to the local endpoint.
+// The chain data is requested from the new providers without restarting the block feed.
+func reloadScanClient(failover *scanner.ProviderFailover, chainID int) services.Service {
+	return services.NewReloadFunc(fmt.Sprintf("%s-reload", failover.Name()), func(cfg config.Config) error {
+		scanCfg := cfg.Scan
+		if chainID != cfg.ChainID {
+			var found bool
+			for _, chain := range cfg.Chains {
+				if chain.ChainID == chainID {
+					scanCfg = cfg.ForChain(chain).Scan
+					found = true
+				}
+			}
+			if !found {
+				return nil // the chain is scanned until the restart
+			}
+		}
+		scanCfg.JsonRpc.Url = dockerHostURL(cfg, scanCfg.JsonRpc.Url)
+		failover.SetUpstream(scanCfg.Upstream)
+		return failover.SetProviders(scanProviders(cfg, scanCfg))
+	})
+}
+
+// initTraceClient creates the client of the traces. The traces are requested through the cache if it is enabled.
+func initTraceClient(
+	ctx context.Context, cfg config.Config, cache *rpccache.Cache,
+) (ethereum.Client, *scanner.ProviderFailover, error) {
+	if cache == nil || !cfg.Trace.Enabled {
+		traceClient, err := ethereum.NewStreamEthClient(ctx, "trace", cfg.Trace.JsonRpc.Url)
+		return traceClient, nil, err
+	}
+	traceEndpoint, err := scanner.NewProviderFailover(ctx, scanner.ProviderFailoverConfig{
+		Name:      "trace-cache",
+		ChainID:   uint64(cfg.ChainID),
+		Providers: []config.JsonRpcConfig{cfg.Trace.JsonRpc},
+		Cache:     cache,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	traceClient, err := ethereum.NewStreamEthClient(ctx, "trace", traceEndpoint.URL())
+	if err != nil {
+		return nil, nil, err
+	}
+	return traceClient, traceEndpoint, nil
+}
+
+// initChains creates the block feeds and the analyzers of the additional chains. The agent pool is shared
+// by all chains and sends the requests only to the agents which declare the chain.
+func initChains(
+	ctx context.Context, cfg config.Config, as clients.AlertSender, ap *agentpool.AgentPool, msgClient clients.MessageClient,
+	canaries *scanner.CanaryService,
+) (svcs []services.Service, reporters []health.Reporter, blockFeeds []feeds.BlockFeed, err error) {
+	for _, chain := range cfg.Chains {
+		chainCfg := cfg.ForChain(chain)
+		chainCfg.Scan.JsonRpc.Url = dockerHostURL(cfg, chainCfg.Scan.JsonRpc.Url)
+		chainCfg.Trace.JsonRpc.Url = dockerHostURL(cfg, chainCfg.Trace.JsonRpc.Url)
+
+		ethClient, failover, err := initScanClient(ctx, cfg, fmt.Sprintf("chain-%d", chain.ChainID), chain.ChainID, chainCfg.Scan, nil, msgClient)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if failover != nil {
+			svcs = append(svcs, failover, reloadScanClient(failover, chain.ChainID))
+			reporters = append(reporters, failover)
+		}
+		traceClient, err := ethereum.NewStreamEthClient(ctx, fmt.Sprintf("trace-%d", chain.ChainID), chainCfg.Trace.JsonRpc.Url)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rpcClient, err := initScanRPCClient(chainCfg, failover)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		txStream, blockFeed, err := initTxStream(ctx, ethClient, traceClient, rpcClient, chainCfg)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("chain %d: %v", chain.ChainID, err)
+		}
+		txAnalyzer, err := initTxAnalyzer(ctx, chainCfg, as, txStream, ap, msgClient, canaries)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		blockAnalyzer, err := initBlockAnalyzer(ctx, chainCfg, as, txStream, ap, msgClient)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		logStream, err := initLogStream(ctx, chainCfg, ethClient, blockFeed, ap)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		svcs = append(svcs, txStream, txAnalyzer, blockAnalyzer, logStream)
+		reporters = append(reporters, ethClient, traceClient, blockFeed, txStream, txAnalyzer, blockAnalyzer, logStream)
+		blockFeeds = append(blockFeeds, blockFeed)
+	}
+	return
+}
+
+func initLogStream(
+	ctx context.Context, cfg config.Config, ethClient ethereum.Client, blockFeed scanner.DataSource, ap *agentpool.AgentPool,
+) (*scanner.LogStreamService, error) {
+	// the logs of the recorded blocks are in the block events
+	var logClient ethereum.Client
+	if !isFileDataSource(cfg) {
+		logClient = ethClient
+	}
+	return scanner.NewLogStreamService(ctx, scanner.LogStreamServiceConfig{
+		ChainID:   int64(cfg.ChainID),
+		EthClient: logClient,
+		BlockFeed: blockFeed,
+		AgentPool: ap,
+	})
+}
+
 func initAlertSender(ctx context.Context, key *keystore.Key, pubClient clients.PublishClient) (clients.AlertSender, error) {
 	return clients.NewAlertSender(ctx, pubClient, clients.AlertSenderConfig{
 		Key: key,
@@ -105,13 +480,21 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 	// can't dial localhost - need to dial host gateway from container
 	cfg.Scan.JsonRpc.Url = utils.ConvertToDockerHostURL(cfg.Scan.JsonRpc.Url)
 	cfg.Trace.JsonRpc.Url = utils.ConvertToDockerHostURL(cfg.Trace.JsonRpc.Url)
+	cfg.Mempool.JsonRpc.Url = utils.ConvertToDockerHostURL(cfg.Mempool.JsonRpc.Url)
+	cfg.Mempool.WebhookURL = utils.ConvertToDockerHostURL(cfg.Mempool.WebhookURL)
 	cfg.Registry.JsonRpc.Url = utils.ConvertToDockerHostURL(cfg.Registry.JsonRpc.Url)
 	cfg.Registry.IPFS.APIURL = utils.ConvertToDockerHostURL(cfg.Registry.IPFS.APIURL)
 	cfg.Registry.IPFS.GatewayURL = utils.ConvertToDockerHostURL(cfg.Registry.IPFS.GatewayURL)
+	cfg.Registry.IPFS.LocalNodeURL = utils.ConvertToDockerHostURL(cfg.Registry.IPFS.LocalNodeURL)
+	cfg.Registry.IPFS.LocalNodeAPIURL = utils.ConvertToDockerHostURL(cfg.Registry.IPFS.LocalNodeAPIURL)
+	for i, gatewayURL := range cfg.Registry.IPFS.FallbackGatewayURLs {
+		cfg.Registry.IPFS.FallbackGatewayURLs[i] = utils.ConvertToDockerHostURL(gatewayURL)
+	}
 	cfg.Publish.APIURL = utils.ConvertToDockerHostURL(cfg.Publish.APIURL)
 	cfg.Publish.IPFS.APIURL = utils.ConvertToDockerHostURL(cfg.Publish.IPFS.APIURL)
 	cfg.Publish.IPFS.GatewayURL = utils.ConvertToDockerHostURL(cfg.Publish.IPFS.GatewayURL)
 	cfg.PrivateModeConfig.WebhookURL = utils.ConvertToDockerHostURL(cfg.PrivateModeConfig.WebhookURL)
+	cfg.Tracing.Endpoint = utils.ConvertToDockerHostURL(cfg.Tracing.Endpoint)
 
 	msgClient := messaging.NewClient("scanner", fmt.Sprintf("%s:%s", config.DockerNatsContainerName, config.DefaultNatsPort))
 
@@ -119,73 +502,221 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 	if err != nil {
 		return nil, err
 	}
+	audit.Record(audit.EventKeyUsed, map[string]string{"address": key.Address.Hex(), "purpose": "scanner"})
 
 	publisherSvc, err := publisher.NewPublisher(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	as, err := initAlertSender(ctx, key, publisherSvc)
+	// route the notifications of the additional chains to their own publishers
+	chainRouter := publisher.NewChainRouter(publisherSvc)
+	var chainPublishers []*publisher.Publisher
+	for _, chain := range cfg.Chains {
+		chainPublisher, err := publisher.NewChainPublisher(ctx, cfg, chain)
+		if err != nil {
+			return nil, err
+		}
+		chainRouter.AddChain(uint64(chain.ChainID), chainPublisher)
+		chainPublishers = append(chainPublishers, chainPublisher)
+	}
+
+	as, err := initAlertSender(ctx, key, chainRouter)
 	if err != nil {
 		return nil, err
 	}
 
-	ethClient, err := ethereum.NewStreamEthClient(ctx, "chain", cfg.Scan.JsonRpc.Url)
+	scannerSvcs, healthReporters, err := initScannerServices(ctx, cfg, key, as, msgClient)
 	if err != nil {
 		return nil, err
 	}
+	healthReporters = append(healthReporters, publisherSvc)
+	for _, chainPublisher := range chainPublishers {
+		healthReporters = append(healthReporters, chainPublisher)
+	}
+
+	// the publishers are ready before the alerts are sent
+	svcs := []services.Service{
+		health.NewService(ctx, "", healthutils.DefaultHealthServerErrHandler, health.CheckerFrom(
+			summarizeReports, healthReporters...,
+		)),
+		publisherSvc,
+	}
+	for _, chainPublisher := range chainPublishers {
+		svcs = append(svcs, chainPublisher)
+	}
+	if cfg.Publish.Incidents.Enable {
+		incidentSources := []publisher.IncidentSource{publisherSvc}
+		for _, chainPublisher := range chainPublishers {
+			incidentSources = append(incidentSources, chainPublisher)
+		}
+		svcs = append(svcs, publisher.NewIncidentsAPI(ctx, incidentSources...))
+	}
+	if cfg.Publish.AgentSLA.Enable {
+		svcs = append(svcs, publisher.NewAgentSLAAPI(ctx, cfg.Publish.AgentSLA, msgClient))
+	}
+	if cfg.Publish.Attestation.Enable {
+		svcs = append(svcs, publisher.NewAttestationAPI(ctx, cfg, key, msgClient))
+	}
+	return append(svcs, scannerSvcs...), nil
+}
 
-	traceClient, err := ethereum.NewStreamEthClient(ctx, "trace", cfg.Trace.JsonRpc.Url)
+// initScannerServices creates the block feeds, the analyzers and the registry service which send the alerts
+// with the alert sender.
+func initScannerServices(
+	ctx context.Context, cfg config.Config, key *keystore.Key, as clients.AlertSender, msgClient clients.MessageClient,
+) ([]services.Service, []health.Reporter, error) {
+	// the cache is shared by the block feed, the tx feed and the json-rpc proxy of the agents
+	var cache *rpccache.Cache
+	if cfg.Scan.Cache.Enabled {
+		cache = rpccache.NewCache(cfg.Scan.Cache.Size, time.Duration(cfg.Scan.Cache.TTLSeconds)*time.Second)
+	}
+
+	ethClient, failover, err := initScanClient(ctx, cfg, "chain", cfg.ChainID, cfg.Scan, cache, msgClient)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	txStream, blockFeed, err := initTxStream(ctx, ethClient, traceClient, cfg)
+	traceClient, traceEndpoint, err := initTraceClient(ctx, cfg, cache)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	rpcClient, err := initScanRPCClient(cfg, failover)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txStream, blockFeed, err := initTxStream(ctx, ethClient, traceClient, rpcClient, cfg)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	registryClient, err := ethereum.NewStreamEthClient(ctx, "registry", cfg.Registry.JsonRpc.Url)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	registryService := registry.New(cfg, key.Address, msgClient, registryClient)
-	agentPool := agentpool.NewAgentPool(ctx, cfg.Scan, msgClient)
-	txAnalyzer, err := initTxAnalyzer(ctx, cfg, as, txStream, agentPool, msgClient)
+	agentPool := agentpool.NewAgentPool(ctx, cfg, msgClient)
+	// the canary results of all chains are received by the shared agent pool
+	var canaries *scanner.CanaryService
+	if cfg.AgentCanaries.Enable && !cfg.IsReplay() {
+		canaries, err = scanner.NewCanaryService(ctx, cfg, agentPool, msgClient)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create the canary service: %v", err)
+		}
+	}
+	txAnalyzer, err := initTxAnalyzer(ctx, cfg, as, txStream, agentPool, msgClient, canaries)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	blockAnalyzer, err := initBlockAnalyzer(ctx, cfg, as, txStream, agentPool, msgClient)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	chainSvcs, chainReporters, chainBlockFeeds, err := initChains(ctx, cfg, as, agentPool, msgClient, canaries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logStream, err := initLogStream(ctx, cfg, ethClient, blockFeed, agentPool)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userOpStream, err := scanner.NewUserOpStreamService(ctx, scanner.UserOpStreamServiceConfig{
+		BlockFeed:   blockFeed,
+		AgentPool:   agentPool,
+		EntryPoints: cfg.Scan.UserOps.GetEntryPoints(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		mempoolStream     *scanner.MempoolStreamService
+		pendingTxAnalyzer *scanner.PendingTxAnalyzerService
+	)
+	if cfg.Mempool.Enabled && !cfg.IsReplay() {
+		mempoolStream, err = initMempoolStream(ctx, cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create the mempool stream service: %v", err)
+		}
+		pendingTxAnalyzer, err = initPendingTxAnalyzer(ctx, cfg, mempoolStream, agentPool, msgClient)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Start the main block feed so all transaction feeds can start consuming.
-	if !cfg.Scan.DisableAutostart {
+	switch {
+	case cfg.IsReplay():
+		// only the main chain is replayed
+		log.WithFields(log.Fields{
+			"from": cfg.Scan.StartBlock,
+			"to":   cfg.Scan.EndBlock,
+		}).Info("replaying block range")
+		blockFeed.StartRange(int64(cfg.Scan.StartBlock), int64(cfg.Scan.EndBlock), int64(cfg.Scan.BlockRateLimit))
+	case !cfg.Scan.DisableAutostart:
 		blockFeed.Start()
 	}
+	for i, chainBlockFeed := range chainBlockFeeds {
+		if !cfg.IsReplay() && !cfg.Chains[i].Scan.DisableAutostart {
+			chainBlockFeed.Start()
+		}
+	}
 
-	svcs := []services.Service{
-		health.NewService(ctx, "", healthutils.DefaultHealthServerErrHandler, health.CheckerFrom(
-			summarizeReports,
-			ethClient, traceClient, blockFeed, txStream, txAnalyzer, blockAnalyzer, agentPool, registryService,
-			publisherSvc,
-		)),
+	healthReporters := []health.Reporter{
+		ethClient, traceClient, blockFeed, txStream, txAnalyzer, blockAnalyzer, logStream, userOpStream, agentPool,
+		registryService,
+	}
+	if mempoolStream != nil {
+		healthReporters = append(healthReporters, mempoolStream, pendingTxAnalyzer)
+	}
+	if failover != nil {
+		healthReporters = append(healthReporters, failover)
+	}
+	if cache != nil {
+		healthReporters = append(healthReporters, cache)
+	}
+	if canaries != nil {
+		healthReporters = append(healthReporters, canaries)
+	}
+	healthReporters = append(healthReporters, chainReporters...)
+
+	var svcs []services.Service
+	// the failover endpoint must be ready before the other services make requests
+	if failover != nil {
+		svcs = append(svcs, failover, reloadScanClient(failover, cfg.ChainID))
+	}
+	if traceEndpoint != nil {
+		svcs = append(svcs, traceEndpoint)
+	}
+	svcs = append(svcs,
 		txStream,
 		txAnalyzer,
 		blockAnalyzer,
+		logStream,
+		userOpStream,
 		scanner.NewScannerAPI(ctx, blockFeed),
 		scanner.NewTxLogger(ctx),
-		publisherSvc,
+	)
+
+	if mempoolStream != nil {
+		svcs = append(svcs, mempoolStream, pendingTxAnalyzer)
 	}
+	if canaries != nil {
+		svcs = append(svcs, canaries)
+	}
+	svcs = append(svcs, chainSvcs...)
 
 	// for performance tests, this flag avoids using registry service
 	if !cfg.Registry.Disable {
 		svcs = append(svcs, registryService)
 	}
 
-	return svcs, nil
+	return svcs, healthReporters, nil
 }
 
 func summarizeReports(reports health.Reports) *health.Report {
@@ -234,6 +765,13 @@ func summarizeReports(reports health.Reports) *health.Report {
 	if ok && len(lastBlock.Details) > 0 {
 		summary.Addf("at block %s.", lastBlock.Details)
 	}
+	catchUp, ok := reports.NameContains("tx-stream.chain.catch-up")
+	if ok && catchUp.Details == "true" {
+		chainLag, _ := reports.NameContains("tx-stream.chain.lag")
+		if chainLag != nil {
+			summary.Addf("catching up with the chain head, %s blocks behind.", chainLag.Details)
+		}
+	}
 
 	// report block request failures but ignore "not found"s because we hit them when we are
 	// asking for the latest block that is not just yet available
@@ -7,6 +7,7 @@ import (
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/security"
 	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/audit"
 	"github.com/forta-network/forta-node/config"
 	"github.com/forta-network/forta-node/healthutils"
 	"github.com/forta-network/forta-node/services"
@@ -26,6 +27,7 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 	if err != nil {
 		return nil, err
 	}
+	audit.Record(audit.EventKeyUsed, map[string]string{"address": key.Address.Hex(), "purpose": "supervisor"})
 	svc, err := supervisor.NewSupervisorService(ctx, supervisor.SupervisorServiceConfig{
 		Config:     cfg,
 		Passphrase: passphrase,
@@ -81,6 +81,7 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 
 	log.WithFields(log.Fields{
 		"developmentMode": developmentMode,
+		"channel":         cfg.AutoUpdate.Channel,
 	}).Info("updater modes")
 
 	address, err := loadAddressFromKeyFile()
@@ -94,8 +95,13 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 		updateDelay = *cfg.AutoUpdate.UpdateDelay
 	}
 
+	source, err := updater.NewReleaseSource(cfg.AutoUpdate, rg)
+	if err != nil {
+		return nil, err
+	}
+
 	updaterService := updater.NewUpdaterService(
-		ctx, rg, rc, config.DefaultContainerPort,
+		ctx, source, rc, config.DefaultContainerPort,
 		developmentMode, updateDelay,
 	)
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/forta-network/forta-core-go/protocol"
 	"github.com/forta-network/forta-core-go/utils"
@@ -12,12 +13,103 @@ const (
 )
 
 type AgentConfig struct {
-	ID         string  `yaml:"id" json:"id"`
-	Image      string  `yaml:"image" json:"image"`
-	Manifest   string  `yaml:"manifest" json:"manifest"`
-	IsLocal    bool    `yaml:"isLocal" json:"isLocal"`
-	StartBlock *uint64 `yaml:"startBlock" json:"startBlock,omitempty"`
-	StopBlock  *uint64 `yaml:"stopBlock" json:"stopBlock,omitempty"`
+	ID                  string          `yaml:"id" json:"id"`
+	Image               string          `yaml:"image" json:"image"`
+	Manifest            string          `yaml:"manifest" json:"manifest"`
+	IsLocal             bool            `yaml:"isLocal" json:"isLocal"`
+	StartBlock          *uint64         `yaml:"startBlock" json:"startBlock,omitempty"`
+	StopBlock           *uint64         `yaml:"stopBlock" json:"stopBlock,omitempty"`
+	PendingTransactions bool            `yaml:"pendingTransactions" json:"pendingTransactions,omitempty"`
+	LogFilters          []LogFilter     `yaml:"logFilters" json:"logFilters,omitempty"`
+	ChainIDs            []int64         `yaml:"chainIds" json:"chainIds,omitempty"`
+	UserOperations      bool            `yaml:"userOperations" json:"userOperations,omitempty"`
+	Pools               []string        `yaml:"pools" json:"pools,omitempty"`
+	Resources           *AgentResources `yaml:"resources" json:"resources,omitempty"`
+	EgressHosts         []string        `yaml:"egressHosts" json:"egressHosts,omitempty"`
+	CosignPublicKey     string          `yaml:"cosignPublicKey" json:"cosignPublicKey,omitempty"` // PEM encoded image signing key
+	Command             []string        `yaml:"command" json:"command,omitempty"`                 // local process agents only
+	Port                string          `yaml:"port" json:"port,omitempty"`                       // local process agents only
+}
+
+// AgentResources are the resource limits which an agent requests. Zero values fall back to
+// the node limits.
+type AgentResources struct {
+	CPUShares    int64   `yaml:"cpuShares" json:"cpuShares,omitempty"`
+	MaxCPUs      float64 `yaml:"maxCpus" json:"maxCpus,omitempty"`
+	MaxMemoryMiB int     `yaml:"maxMemoryMib" json:"maxMemoryMib,omitempty"`
+	GPUs         int     `yaml:"gpus" json:"gpus,omitempty"` // run only if the node enables the GPUs
+}
+
+// LogFilter selects the logs which an agent subscribes to. An empty address list matches
+// all addresses. Each topic position is matched against any of the listed topics and an
+// empty position matches any topic, like in eth_getLogs.
+type LogFilter struct {
+	Addresses []string   `yaml:"addresses" json:"addresses,omitempty"`
+	Topics    [][]string `yaml:"topics" json:"topics,omitempty"`
+}
+
+// Matches tells if the log matches the filter.
+func (lf LogFilter) Matches(address string, topics []string) bool {
+	if len(lf.Addresses) > 0 && !containsFold(lf.Addresses, address) {
+		return false
+	}
+	for i, position := range lf.Topics {
+		if len(position) == 0 {
+			continue
+		}
+		if i >= len(topics) || !containsFold(position, topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SupportsChain tells if the agent should run on the chain. The agents which don't declare
+// any chains run only on the main chain of the node.
+func (ac AgentConfig) SupportsChain(chainID, mainChainID int64) bool {
+	if len(ac.ChainIDs) == 0 {
+		return chainID == mainChainID
+	}
+	for _, supported := range ac.ChainIDs {
+		if supported == chainID {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsAnyChain tells if the agent should run on any of the chains.
+func (ac AgentConfig) SupportsAnyChain(chainIDs []int64, mainChainID int64) bool {
+	for _, chainID := range chainIDs {
+		if ac.SupportsChain(chainID, mainChainID) {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscribesToLogs tells if the agent receives the matching logs instead of all transactions.
+func (ac AgentConfig) SubscribesToLogs() bool {
+	return len(ac.LogFilters) > 0
+}
+
+// MatchesLog tells if any of the log filters of the agent matches the log.
+func (ac AgentConfig) MatchesLog(address string, topics []string) bool {
+	for _, lf := range ac.LogFilters {
+		if lf.Matches(address, topics) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
 }
 
 // ToAgentInfo transforms the agent config to the agent info.
@@ -43,6 +135,28 @@ func (ac AgentConfig) ContainerName() string {
 	return fmt.Sprintf("%s-agent-%s-%s", ContainerNamePrefix, utils.ShortenString(ac.ID, 8), utils.ShortenString(digest, 4))
 }
 
+// UsesGPU tells if the agent requests GPUs.
+func (ac AgentConfig) UsesGPU() bool {
+	return ac.Resources != nil && ac.Resources.GPUs > 0
+}
+
+// IsProcess tells if the agent runs as a local process on the host instead of a container.
+func (ac AgentConfig) IsProcess() bool {
+	return len(ac.Command) > 0
+}
+
 func (ac AgentConfig) GrpcPort() string {
+	if ac.IsProcess() && len(ac.Port) > 0 {
+		return ac.Port
+	}
 	return AgentGrpcPort
 }
+
+// GrpcHost returns the host which the agent is dialed at. The process agents are reached
+// through the host of the scanner container.
+func (ac AgentConfig) GrpcHost() string {
+	if ac.IsProcess() {
+		return "host.docker.internal"
+	}
+	return ac.ContainerName()
+}
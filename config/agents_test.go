@@ -12,3 +12,28 @@ func TestAgentConfig_ContainerName(t *testing.T) {
 	}
 	assert.Equal(t, "forta-agent-0x04f65c-de86", cfg.ContainerName())
 }
+
+func TestLogFilter_Matches(t *testing.T) {
+	lf := LogFilter{
+		Addresses: []string{"0xAbC0000000000000000000000000000000000001"},
+		Topics:    [][]string{{"0x01", "0x02"}, {}, {"0x03"}},
+	}
+	assert.True(t, lf.Matches("0xabc0000000000000000000000000000000000001", []string{"0x02", "0xff", "0x03"}))
+	assert.False(t, lf.Matches("0xabc0000000000000000000000000000000000002", []string{"0x02", "0xff", "0x03"}))
+	assert.False(t, lf.Matches("0xabc0000000000000000000000000000000000001", []string{"0x04", "0xff", "0x03"}))
+	assert.False(t, lf.Matches("0xabc0000000000000000000000000000000000001", []string{"0x01", "0xff"}))
+	assert.True(t, LogFilter{}.Matches("0xabc0000000000000000000000000000000000002", nil))
+}
+
+func TestAgentConfig_SupportsAnyChain(t *testing.T) {
+	assert.True(t, AgentConfig{}.SupportsAnyChain([]int64{1, 137}, 1))
+	assert.True(t, AgentConfig{ChainIDs: []int64{137}}.SupportsAnyChain([]int64{1, 137}, 1))
+	assert.False(t, AgentConfig{ChainIDs: []int64{56}}.SupportsAnyChain([]int64{1, 137}, 1))
+}
+
+func TestAgentConfig_SupportsChain(t *testing.T) {
+	assert.True(t, AgentConfig{}.SupportsChain(1, 1))
+	assert.False(t, AgentConfig{}.SupportsChain(137, 1))
+	assert.True(t, AgentConfig{ChainIDs: []int64{1, 137}}.SupportsChain(137, 1))
+	assert.False(t, AgentConfig{ChainIDs: []int64{137}}.SupportsChain(1, 1))
+}
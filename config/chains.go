@@ -7,12 +7,19 @@ var defaultRateLimiting = &RateLimitConfig{
 	Burst: 50, // 100,
 }
 
+// L2 types
+const (
+	L2Optimism = "optimism"
+	L2Arbitrum = "arbitrum"
+)
+
 // ChainSettings contains chain-specific settings.
 type ChainSettings struct {
 	Name                string
 	ChainID             int
 	Offset              int
 	JsonRpcRateLimiting *RateLimitConfig
+	L2                  string
 }
 
 var allChainSettings = []ChainSettings{
@@ -45,12 +52,14 @@ var allChainSettings = []ChainSettings{
 		ChainID:             42161,
 		Offset:              defaultBlockOffset,
 		JsonRpcRateLimiting: defaultRateLimiting,
+		L2:                  L2Arbitrum,
 	},
 	{
 		Name:                "Optimism",
 		ChainID:             10,
 		Offset:              defaultBlockOffset,
 		JsonRpcRateLimiting: defaultRateLimiting,
+		L2:                  L2Optimism,
 	},
 }
 
@@ -69,7 +78,23 @@ func GetChainSettings(chainID int) *ChainSettings {
 	}
 }
 
+// GetAllChainSettings returns the settings of the known chains.
+func GetAllChainSettings() []ChainSettings {
+	return append([]ChainSettings{}, allChainSettings...)
+}
+
 // GetBlockOffset returns the block offset for a chain.
 func GetBlockOffset(chainID int) int {
 	return GetChainSettings(chainID).Offset
 }
+
+// GetL2Type returns the L2 type of the chain. The type in the scanner config overrides the chain settings.
+func GetL2Type(chainID int, scanCfg ScannerConfig) string {
+	if scanCfg.L2.Disable {
+		return ""
+	}
+	if len(scanCfg.L2.Type) > 0 {
+		return scanCfg.L2.Type
+	}
+	return GetChainSettings(chainID).L2
+}
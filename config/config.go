@@ -13,13 +13,125 @@ type JsonRpcConfig struct {
 	Headers map[string]string `yaml:"headers" json:"headers"`
 }
 
+// FailoverConfig configures how the scanner switches between the JSON-RPC providers. Every provider
+// is checked at each interval and is considered unhealthy if it is behind the highest known block by more
+// than MaxHeadLag blocks, if its recent error rate exceeds MaxErrorRate or if it responds slower than
+// MaxLatencyMs. The first healthy provider in the configured order is used.
+type FailoverConfig struct {
+	CheckIntervalSeconds int     `yaml:"checkIntervalSeconds" json:"checkIntervalSeconds" default:"10" validate:"min=1"`
+	MaxHeadLag           int64   `yaml:"maxHeadLag" json:"maxHeadLag" default:"5" validate:"min=0"`
+	MaxErrorRate         float64 `yaml:"maxErrorRate" json:"maxErrorRate" default:"0.5" validate:"gt=0,lte=1"`
+	MaxLatencyMs         int64   `yaml:"maxLatencyMs" json:"maxLatencyMs" default:"5000" validate:"min=1"`
+}
+
+// UpstreamConfig limits the requests to the JSON-RPC providers of a chain. The requests are delayed to stay
+// under Rate requests per second. If DailyBudget is set, the requests are paced so that the remaining
+// budget lasts until the end of the day (UTC) and they wait for the next day after the budget is used.
+type UpstreamConfig struct {
+	Rate        float64 `yaml:"rate" json:"rate" validate:"min=0"`
+	Burst       int     `yaml:"burst" json:"burst" default:"10" validate:"min=1"`
+	DailyBudget int64   `yaml:"dailyBudget" json:"dailyBudget" validate:"min=0"`
+}
+
+// Enabled tells if the requests should be limited.
+func (cfg UpstreamConfig) Enabled() bool {
+	return cfg.Rate > 0 || cfg.DailyBudget > 0
+}
+
+// ReceiptsConfig enables fetching the transaction receipts, so that the agents receive the actual
+// status and gas usage of the transactions. The receipts of a block are requested in JSON-RPC batches
+// of BatchSize requests and up to Workers batches are requested at once.
+type ReceiptsConfig struct {
+	Enabled   bool `yaml:"enabled" json:"enabled"`
+	BatchSize int  `yaml:"batchSize" json:"batchSize" default:"100" validate:"min=1"`
+	Workers   int  `yaml:"workers" json:"workers" default:"4" validate:"min=1"`
+}
+
+// CatchUpConfig enables the catch-up mode which the scanner enters when the last processed block is more than
+// MaxLag blocks behind the chain head. While catching up, the blocks are requested every BlockRateLimit
+// milliseconds, the receipts are requested in batches of ReceiptBatchSize and the traces are skipped if
+// SkipTraces is enabled. The chain head is checked every CheckIntervalSeconds.
+type CatchUpConfig struct {
+	Enabled              bool  `yaml:"enabled" json:"enabled"`
+	MaxLag               int64 `yaml:"maxLag" json:"maxLag" default:"50" validate:"min=2"`
+	BlockRateLimit       int   `yaml:"blockRateLimit" json:"blockRateLimit" default:"20" validate:"min=1"`
+	ReceiptBatchSize     int   `yaml:"receiptBatchSize" json:"receiptBatchSize" default:"500" validate:"min=1"`
+	SkipTraces           bool  `yaml:"skipTraces" json:"skipTraces"`
+	CheckIntervalSeconds int   `yaml:"checkIntervalSeconds" json:"checkIntervalSeconds" default:"15" validate:"min=1"`
+}
+
+// CheckpointConfig enables resuming the scanning from the last processed block after a restart. If the last
+// processed block is more than MaxBackfill blocks behind the chain head, the scanning resumes MaxBackfill blocks
+// behind the chain head. The old blocks are not skipped while the checkpoint is enabled.
+type CheckpointConfig struct {
+	Enabled     bool  `yaml:"enabled" json:"enabled"`
+	MaxBackfill int64 `yaml:"maxBackfill" json:"maxBackfill" default:"1000" validate:"min=0"`
+}
+
+// DataSourceConfig selects the source of the chain data. The "rpc" source reads the blocks from the JSON-RPC
+// providers and the "file" source reads them from the file at Path which has a JSON block on every line.
+type DataSourceConfig struct {
+	Type string `yaml:"type" json:"type" default:"rpc" validate:"oneof=rpc file"`
+	Path string `yaml:"path" json:"path" validate:"required_if=Type file"`
+}
+
+// L2Config configures adding the L2 specific info of the transactions to the transaction events. The type
+// of the known L2 chains is set by default and Type sets it for the other chains. The receipts are fetched
+// on the L2 chains, since the L1 fee fields are in the receipts.
+type L2Config struct {
+	Disable bool   `yaml:"disable" json:"disable"`
+	Type    string `yaml:"type" json:"type" validate:"omitempty,oneof=optimism arbitrum"`
+}
+
+// UserOpsConfig configures the ERC-4337 user operation feed. The handleOps and handleAggregatedOps calls to the
+// EntryPoints are decoded into user operations which are sent to the agents that declare userOperations in their manifests. The
+// default EntryPoint is used if no EntryPoints are set.
+type UserOpsConfig struct {
+	EntryPoints []string `yaml:"entryPoints" json:"entryPoints"`
+}
+
+// DefaultEntryPointAddress is the address of the ERC-4337 EntryPoint v0.6.
+const DefaultEntryPointAddress = "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"
+
+// GetEntryPoints returns the EntryPoint addresses.
+func (uc UserOpsConfig) GetEntryPoints() []string {
+	if len(uc.EntryPoints) == 0 {
+		return []string{DefaultEntryPointAddress}
+	}
+	return uc.EntryPoints
+}
+
+// CacheConfig enables the cache of the blocks, receipts, logs and traces which is shared by the scanner
+// and the JSON-RPC proxy of the agents. Up to Size responses are kept for TTLSeconds.
+type CacheConfig struct {
+	Enabled    bool `yaml:"enabled" json:"enabled"`
+	Size       int  `yaml:"size" json:"size" default:"10000" validate:"min=1"`
+	TTLSeconds int  `yaml:"ttlSeconds" json:"ttlSeconds" default:"300" validate:"min=1"`
+}
+
+// ScannerConfig configures the scanning of a chain. Confirmations is the number of blocks which are waited
+// for before a block is processed and the default of the chain is used if it is not set. If Finalized is
+// enabled, only the blocks which the chain tags as finalized are processed.
 type ScannerConfig struct {
-	StartBlock         int           `yaml:"-" json:"_startBlock"`
-	EndBlock           int           `yaml:"-" json:"_endBlock"`
-	JsonRpc            JsonRpcConfig `yaml:"jsonRpc" json:"jsonRpc"`
-	DisableAutostart   bool          `yaml:"disableAutostart" json:"disableAutostart"`
-	BlockRateLimit     int           `yaml:"blockRateLimit" json:"blockRateLimit" default:"200"`
-	BlockMaxAgeSeconds int64         `json:"blockMaxAgeSeconds" json:"blockMaxAgeSeconds" default:"600"`
+	StartBlock           int              `yaml:"-" json:"_startBlock"`
+	EndBlock             int              `yaml:"-" json:"_endBlock"`
+	JsonRpc              JsonRpcConfig    `yaml:"jsonRpc" json:"jsonRpc"`
+	FallbackJsonRpc      []JsonRpcConfig  `yaml:"fallbackJsonRpc" json:"fallbackJsonRpc" validate:"dive"`
+	Failover             FailoverConfig   `yaml:"failover" json:"failover"`
+	Receipts             ReceiptsConfig   `yaml:"receipts" json:"receipts"`
+	Upstream             UpstreamConfig   `yaml:"upstream" json:"upstream"`
+	Cache                CacheConfig      `yaml:"cache" json:"cache"`
+	CatchUp              CatchUpConfig    `yaml:"catchUp" json:"catchUp"`
+	Checkpoint           CheckpointConfig `yaml:"checkpoint" json:"checkpoint"`
+	DataSource           DataSourceConfig `yaml:"dataSource" json:"dataSource"`
+	L2                   L2Config         `yaml:"l2" json:"l2"`
+	UserOps              UserOpsConfig    `yaml:"userOps" json:"userOps"`
+	Confirmations        *int             `yaml:"confirmations" json:"confirmations" validate:"omitempty,min=0"`
+	Finalized            bool             `yaml:"finalized" json:"finalized"`
+	FinalizedPollSeconds int              `yaml:"finalizedPollSeconds" json:"finalizedPollSeconds" default:"5" validate:"min=1"`
+	DisableAutostart     bool             `yaml:"disableAutostart" json:"disableAutostart"`
+	BlockRateLimit       int              `yaml:"blockRateLimit" json:"blockRateLimit" default:"200"`
+	BlockMaxAgeSeconds   int64            `json:"blockMaxAgeSeconds" json:"blockMaxAgeSeconds" default:"600"`
 }
 
 type TraceConfig struct {
@@ -27,38 +139,118 @@ type TraceConfig struct {
 	Enabled bool          `yaml:"enabled" json:"enabled"`
 }
 
+// MempoolConfig configures the pending transaction feed. Only the agents which declare
+// pendingTransactions in their manifests receive the pending transactions.
+//
+// Source selects how the pending transactions are read: "subscription" uses eth_subscribe,
+// "filter" polls eth_newPendingTransactionFilter, "txpool" polls txpool_content and "auto"
+// tries them in this order. PollIntervalSeconds is the polling interval of the filter and txpool
+// sources and the chain head refresh interval. A failing source is retried with an exponential
+// backoff which starts from RetryIntervalSeconds and is capped at MaxRetryIntervalSeconds.
+type MempoolConfig struct {
+	JsonRpc                 JsonRpcConfig `yaml:"jsonRpc" json:"jsonRpc"`
+	Enabled                 bool          `yaml:"enabled" json:"enabled"`
+	Source                  string        `yaml:"source" json:"source" default:"auto" validate:"oneof=auto subscription filter txpool"`
+	PollIntervalSeconds     int           `yaml:"pollIntervalSeconds" json:"pollIntervalSeconds" default:"2" validate:"min=1"`
+	RetryIntervalSeconds    int           `yaml:"retryIntervalSeconds" json:"retryIntervalSeconds" default:"5" validate:"min=1"`
+	MaxRetryIntervalSeconds int           `yaml:"maxRetryIntervalSeconds" json:"maxRetryIntervalSeconds" default:"300" validate:"gtefield=RetryIntervalSeconds"`
+	WebhookURL              string        `yaml:"webhookUrl" json:"webhookUrl" validate:"omitempty,url"`
+}
+
+// ChainConfig configures an additional chain which the node scans along with the main chain.
+// Only the agents which declare the chain ID in their manifests receive the data from this chain.
+type ChainConfig struct {
+	ChainID int           `yaml:"chainId" json:"chainId" validate:"required,min=1"`
+	Scan    ScannerConfig `yaml:"scan" json:"scan"`
+	Trace   TraceConfig   `yaml:"trace" json:"trace"`
+}
+
 type RateLimitConfig struct {
 	Rate  float64 `yaml:"rate" json:"rate"`
 	Burst int     `yaml:"burst" json:"burst" validate:"min=1"`
 }
 
+// JsonRpcProxyConfig configures the JSON-RPC proxy of the agents. The responses of the requests for
+// specific blocks are cached if the cache is enabled. Each agent can send up to DailyQuota requests per
+// UTC day and the limits can be overridden per agent.
 type JsonRpcProxyConfig struct {
-	JsonRpc         JsonRpcConfig    `yaml:"jsonRpc" json:"jsonRpc"`
+	JsonRpc         JsonRpcConfig             `yaml:"jsonRpc" json:"jsonRpc"`
+	RateLimitConfig *RateLimitConfig          `yaml:"rateLimit" json:"rateLimit"`
+	Cache           CacheConfig               `yaml:"cache" json:"cache"`
+	DailyQuota      int                       `yaml:"dailyQuota" json:"dailyQuota" validate:"min=0"`
+	Agents          []AgentJsonRpcProxyConfig `yaml:"agents" json:"agents" validate:"dive"`
+	Restrictions    JsonRpcRestrictionsConfig `yaml:"restrictions" json:"restrictions"`
+}
+
+// JsonRpcRestrictionsConfig limits the requests of each agent to the JSON-RPC proxy. Only the allowed
+// methods are forwarded and the read-only methods are allowed if the list is empty.
+type JsonRpcRestrictionsConfig struct {
+	Disable               bool     `yaml:"disable" json:"disable"`
+	AllowedMethods        []string `yaml:"allowedMethods" json:"allowedMethods"`
+	MaxRequestSizeKB      int      `yaml:"maxRequestSizeKb" json:"maxRequestSizeKb" default:"128" validate:"min=1"`
+	MaxResponseSizeKB     int      `yaml:"maxResponseSizeKb" json:"maxResponseSizeKb" default:"10240" validate:"min=1"`
+	MaxConcurrentRequests int      `yaml:"maxConcurrentRequests" json:"maxConcurrentRequests" default:"20" validate:"min=1"`
+}
+
+// AgentJsonRpcProxyConfig overrides the JSON-RPC proxy limits of an agent.
+type AgentJsonRpcProxyConfig struct {
+	AgentID         string           `yaml:"agentId" json:"agentId" validate:"required"`
 	RateLimitConfig *RateLimitConfig `yaml:"rateLimit" json:"rateLimit"`
+	DailyQuota      int              `yaml:"dailyQuota" json:"dailyQuota" validate:"min=0"`
 }
 
 type LogConfig struct {
-	Level       string `yaml:"level" json:"level" default:"info" `
-	MaxLogSize  string `yaml:"maxLogSize" json:"maxLogSize" default:"50m" `
-	MaxLogFiles int    `yaml:"maxLogFiles" json:"maxLogFiles" default:"10" `
+	Level       string        `yaml:"level" json:"level" default:"info" `
+	MaxLogSize  string        `yaml:"maxLogSize" json:"maxLogSize" default:"50m" `
+	MaxLogFiles int           `yaml:"maxLogFiles" json:"maxLogFiles" default:"10" `
+	File        LogFileConfig `yaml:"file" json:"file"`
 }
 
+// LogFileConfig configures writing the logs of the node processes to the rotating files in the logs dir
+// in addition to the standard output. The rotated files which are older than MaxAgeDays or exceed
+// MaxBackups are removed.
+type LogFileConfig struct {
+	Enable             bool `yaml:"enable" json:"enable"`
+	MaxSizeMB          int  `yaml:"maxSizeMb" json:"maxSizeMb" default:"100" validate:"min=1"`
+	MaxAgeDays         int  `yaml:"maxAgeDays" json:"maxAgeDays" default:"7" validate:"min=0"`
+	MaxBackups         int  `yaml:"maxBackups" json:"maxBackups" default:"10" validate:"min=0"`
+	DisableCompression bool `yaml:"disableCompression" json:"disableCompression"`
+}
+
+// RegistryConfig configures the agent registry. In addition to the agents assigned to its own scanner address,
+// the node runs the agents of the PoolIDs which are the addresses of other scanners. The "all" pool selects
+// all agents of the chain. The node reloads all agents every ReconcileIntervalSeconds to recover from
+// the missed changes, and zero disables reloading. The ImageSignaturePolicy decides what happens
+// if the cosign signature of an agent image can't be verified with the key from the agent manifest.
 type RegistryConfig struct {
-	JsonRpc              JsonRpcConfig `yaml:"jsonRpc" json:"jsonRpc" default:"{\"url\": \"https://polygon-rpc.com\"}"`
-	IPFS                 IPFSConfig    `yaml:"ipfs" json:"ipfs"`
-	ContractAddress      string        `yaml:"contractAddress" json:"contractAddress" validate:"eth_addr"`
-	ContainerRegistry    string        `yaml:"containerRegistry" json:"containerRegistry" validate:"hostname|hostname_port" default:"disco.forta.network" `
-	Username             string        `yaml:"username" json:"username"`
-	Password             string        `yaml:"password" json:"password"`
-	Disable              bool          `yaml:"disable" json:"disable"` // for testing situations
-	CheckIntervalSeconds int           `yaml:"checkIntervalSeconds" json:"checkIntervalSeconds" default:"15"`
+	JsonRpc                  JsonRpcConfig `yaml:"jsonRpc" json:"jsonRpc" default:"{\"url\": \"https://polygon-rpc.com\"}"`
+	IPFS                     IPFSConfig    `yaml:"ipfs" json:"ipfs"`
+	ContractAddress          string        `yaml:"contractAddress" json:"contractAddress" validate:"eth_addr"`
+	ContainerRegistry        string        `yaml:"containerRegistry" json:"containerRegistry" validate:"hostname|hostname_port" default:"disco.forta.network" `
+	Username                 string        `yaml:"username" json:"username"`
+	Password                 string        `yaml:"password" json:"password"`
+	Disable                  bool          `yaml:"disable" json:"disable"` // for testing situations
+	CheckIntervalSeconds     int           `yaml:"checkIntervalSeconds" json:"checkIntervalSeconds" default:"15"`
+	PoolIDs                  []string      `yaml:"poolIds" json:"poolIds"`
+	ReconcileIntervalSeconds int           `yaml:"reconcileIntervalSeconds" json:"reconcileIntervalSeconds" default:"3600" validate:"min=0"`
+	MinAgentStakeWei         string        `yaml:"minAgentStakeWei" json:"minAgentStakeWei" validate:"omitempty,numeric"` // the agents with less active stake are not run
+	ImageSignaturePolicy     string        `yaml:"imageSignaturePolicy" json:"imageSignaturePolicy" default:"off" validate:"oneof=enforce warn off"`
 }
 
+// IPFSConfig configures the IPFS access. The agent manifests are read from the gateway of the local IPFS
+// node at LocalNodeURL first, then from GatewayURL and then from the FallbackGatewayURLs in order. A failing
+// gateway is not used again until its backoff expires. If PinManifests is enabled, the agent manifests are
+// pinned via the API of the local node at LocalNodeAPIURL.
 type IPFSConfig struct {
-	GatewayURL string `yaml:"gatewayUrl" json:"gatewayUrl" validate:"url" default:"https://ipfs.forta.network" `
-	APIURL     string `yaml:"apiUrl" json:"apiUrl" validate:"url" default:"https://ipfs.forta.network" `
-	Username   string `yaml:"username" json:"username"`
-	Password   string `yaml:"password" json:"password"`
+	GatewayURL            string   `yaml:"gatewayUrl" json:"gatewayUrl" validate:"url" default:"https://ipfs.forta.network" `
+	APIURL                string   `yaml:"apiUrl" json:"apiUrl" validate:"url" default:"https://ipfs.forta.network" `
+	Username              string   `yaml:"username" json:"username"`
+	Password              string   `yaml:"password" json:"password"`
+	FallbackGatewayURLs   []string `yaml:"fallbackGatewayUrls" json:"fallbackGatewayUrls" validate:"dive,url"`
+	LocalNodeURL          string   `yaml:"localNodeUrl" json:"localNodeUrl" validate:"omitempty,url"`
+	GatewayTimeoutSeconds int      `yaml:"gatewayTimeoutSeconds" json:"gatewayTimeoutSeconds" default:"10" validate:"min=1"`
+	LocalNodeAPIURL       string   `yaml:"localNodeApiUrl" json:"localNodeApiUrl" validate:"omitempty,url"`
+	PinManifests          bool     `yaml:"pinManifests" json:"pinManifests"` // pins the agent manifests on the local node
 }
 
 type BatchConfig struct {
@@ -72,18 +264,279 @@ type TestAlertsConfig struct {
 	WebhookURL string `yaml:"webhookUrl" json:"webhookUrl" validate:"omitempty,url"`
 }
 
+type KafkaConfig struct {
+	Enable      bool            `yaml:"enable" json:"enable"`
+	Brokers     []string        `yaml:"brokers" json:"brokers" validate:"required_if=Enable true"`
+	Topic       string          `yaml:"topic" json:"topic" validate:"required_if=Enable true"`
+	Encoding    string          `yaml:"encoding" json:"encoding" default:"json" validate:"oneof=json protobuf"`
+	PartitionBy string          `yaml:"partitionBy" json:"partitionBy" default:"agent" validate:"oneof=agent chain"` // batches are partitioned by chain
+	Batch       SinkBatchConfig `yaml:"batch" json:"batch"`
+}
+
+// JetStreamConfig enables a persistent stream of alerts on the NATS server of the node. The consumers
+// can replay the stream from any sequence until the alerts expire.
+type JetStreamConfig struct {
+	Enable      bool            `yaml:"enable" json:"enable"`
+	Stream      string          `yaml:"stream" json:"stream" default:"forta-alerts" validate:"excludesall=.*> "`
+	Subject     string          `yaml:"subject" json:"subject" default:"forta.alerts" validate:"excludesall=*> "`
+	Encoding    string          `yaml:"encoding" json:"encoding" default:"json" validate:"oneof=json protobuf"`
+	MaxAgeHours int             `yaml:"maxAgeHours" json:"maxAgeHours" default:"168" validate:"min=1"`
+	Batch       SinkBatchConfig `yaml:"batch" json:"batch"`
+}
+
+// AnchorConfig submits the keccak256 hash of every published batch to a registry contract with
+// the scanner key. The contract needs the function anchor(bytes32 batchHash, uint256 chainId, uint256 blockEnd, string ref).
+// The scanner address pays for the anchor transactions, so it needs funds on the anchor chain.
+type AnchorConfig struct {
+	Enable          bool          `yaml:"enable" json:"enable"`
+	JsonRpc         JsonRpcConfig `yaml:"jsonRpc" json:"jsonRpc" default:"{\"url\": \"https://polygon-rpc.com\"}"`
+	ContractAddress string        `yaml:"contractAddress" json:"contractAddress" validate:"omitempty,eth_addr"`
+	GasLimit        uint64        `yaml:"gasLimit" json:"gasLimit"` // estimated if zero
+}
+
+type AlertFilterConfig struct {
+	MinSeverity string            `yaml:"minSeverity" json:"minSeverity" validate:"omitempty,oneof=INFO LOW MEDIUM HIGH CRITICAL"`
+	AgentIDs    []string          `yaml:"agentIds" json:"agentIds"`
+	Metadata    map[string]string `yaml:"metadata" json:"metadata"`
+}
+
+type AlertRouteConfig struct {
+	Filter   AlertFilterConfig `yaml:"filter" json:"filter"`
+	ChainIDs []int             `yaml:"chainIds" json:"chainIds"`
+	Sinks    []string          `yaml:"sinks" json:"sinks" validate:"min=1"`
+}
+
+// SinkBatchConfig sends the alerts of a sink together in batches. The webhook batches are JSON objects with
+// the list of alerts and the Kafka and JetStream batches are single messages with the list of alerts
+// in the sink encoding.
+type SinkBatchConfig struct {
+	MaxAlerts       int  `yaml:"maxAlerts" json:"maxAlerts" validate:"min=0"` // disables batching if zero
+	IntervalSeconds int  `yaml:"intervalSeconds" json:"intervalSeconds" default:"10" validate:"min=1"`
+	Compress        bool `yaml:"compress" json:"compress"` // compresses the batches with zstd
+}
+
+type WebhookSinkConfig struct {
+	URL        string            `yaml:"url" json:"url" validate:"url"`
+	Headers    map[string]string `yaml:"headers" json:"headers"`
+	Filter     AlertFilterConfig `yaml:"filter" json:"filter"`
+	MaxRetries int               `yaml:"maxRetries" json:"maxRetries" default:"5" validate:"min=0"`
+	Batch      SinkBatchConfig   `yaml:"batch" json:"batch"`
+}
+
+type SlackConfig struct {
+	WebhookURL string            `yaml:"webhookUrl" json:"webhookUrl" validate:"url"`
+	Filter     AlertFilterConfig `yaml:"filter" json:"filter"`
+}
+
+type TelegramConfig struct {
+	BotToken string            `yaml:"botToken" json:"botToken" validate:"required"`
+	ChatID   string            `yaml:"chatId" json:"chatId" validate:"required"`
+	Filter   AlertFilterConfig `yaml:"filter" json:"filter"`
+}
+
+type PagerDutyConfig struct {
+	RoutingKey string            `yaml:"routingKey" json:"routingKey" validate:"required"`
+	Filter     AlertFilterConfig `yaml:"filter" json:"filter"`
+}
+
+type SyslogSinkConfig struct {
+	Network            string            `yaml:"network" json:"network" default:"udp" validate:"oneof=udp tcp tls"`
+	Address            string            `yaml:"address" json:"address" validate:"hostname_port"`
+	Format             string            `yaml:"format" json:"format" default:"cef" validate:"oneof=cef leef"`
+	InsecureSkipVerify bool              `yaml:"insecureSkipVerify" json:"insecureSkipVerify"`
+	Filter             AlertFilterConfig `yaml:"filter" json:"filter"`
+}
+
+type AlertSamplingConfig struct {
+	AgentIDs           []string `yaml:"agentIds" json:"agentIds"` // applies to all agents if empty
+	MaxSeverity        string   `yaml:"maxSeverity" json:"maxSeverity" default:"INFO" validate:"oneof=UNKNOWN INFO LOW MEDIUM"`
+	MaxAlertsPerMinute int      `yaml:"maxAlertsPerMinute" json:"maxAlertsPerMinute" validate:"min=1"`
+}
+
+type IncidentsConfig struct {
+	Enable        bool   `yaml:"enable" json:"enable"`
+	WindowSeconds int    `yaml:"windowSeconds" json:"windowSeconds" default:"600" validate:"min=1"`
+	MaxIncidents  int    `yaml:"maxIncidents" json:"maxIncidents" default:"1000" validate:"min=1"`
+	HostPort      string `yaml:"hostPort" json:"hostPort" default:"8092" validate:"numeric"`
+}
+
+// AgentSLAConfig serves the uptime, the latency, the timeout ratio and the error ratio of each agent
+// over the last WindowMinutes, computed from the agent metrics.
+type AgentSLAConfig struct {
+	Enable        bool   `yaml:"enable" json:"enable"`
+	WindowMinutes int    `yaml:"windowMinutes" json:"windowMinutes" default:"60" validate:"min=1"`
+	HostPort      string `yaml:"hostPort" json:"hostPort" default:"8099" validate:"numeric"`
+}
+
+// AttestationConfig serves the attestation which the scanner signs with its key, so that the pool
+// owners can check that the scanner is scanning. The requests need the token as the bearer token.
+type AttestationConfig struct {
+	Enable   bool   `yaml:"enable" json:"enable"`
+	HostPort string `yaml:"hostPort" json:"hostPort" default:"8100" validate:"numeric"`
+	Token    string `yaml:"token" json:"token" validate:"required_if=Enable true"`
+}
+
+// AlertStoreConfig keeps the alerts in daily files in the Forta dir, so that they can be exported
+// with 'forta export-alerts' even if the node APIs are down.
+type AlertStoreConfig struct {
+	Disable       bool `yaml:"disable" json:"disable"`
+	RetentionDays int  `yaml:"retentionDays" json:"retentionDays" default:"7" validate:"min=1"`
+}
+
+type NotificationsConfig struct {
+	Slack     []SlackConfig     `yaml:"slack" json:"slack" validate:"dive"`
+	Telegram  []TelegramConfig  `yaml:"telegram" json:"telegram" validate:"dive"`
+	PagerDuty []PagerDutyConfig `yaml:"pagerDuty" json:"pagerDuty" validate:"dive"`
+}
+
 type PublisherConfig struct {
-	SkipPublish bool             `yaml:"skipPublish" json:"skipPublish" default:"false"`
-	APIURL      string           `yaml:"apiUrl" json:"apiUrl" default:"https://alerts.forta.network" validate:"url"`
-	IPFS        IPFSConfig       `yaml:"ipfs" json:"ipfs" validate:"required_unless=SkipPublish true"`
-	Batch       BatchConfig      `yaml:"batch" json:"batch"`
-	TestAlerts  TestAlertsConfig `yaml:"testAlerts" json:"testAlerts"`
+	SkipPublish   bool                  `yaml:"skipPublish" json:"skipPublish" default:"false"`
+	APIURL        string                `yaml:"apiUrl" json:"apiUrl" default:"https://alerts.forta.network" validate:"url"`
+	IPFS          IPFSConfig            `yaml:"ipfs" json:"ipfs" validate:"required_unless=SkipPublish true"`
+	Batch         BatchConfig           `yaml:"batch" json:"batch"`
+	TestAlerts    TestAlertsConfig      `yaml:"testAlerts" json:"testAlerts"`
+	UploadBatches bool                  `yaml:"uploadBatches" json:"uploadBatches"` // uploads the signed batches to IPFS
+	Anchor        AnchorConfig          `yaml:"anchor" json:"anchor"`
+	Kafka         KafkaConfig           `yaml:"kafka" json:"kafka"`
+	JetStream     JetStreamConfig       `yaml:"jetStream" json:"jetStream"`
+	Webhooks      []WebhookSinkConfig   `yaml:"webhooks" json:"webhooks" validate:"dive"`
+	Notifications NotificationsConfig   `yaml:"notifications" json:"notifications"`
+	Syslog        []SyslogSinkConfig    `yaml:"syslog" json:"syslog" validate:"dive"`
+	Sampling      []AlertSamplingConfig `yaml:"sampling" json:"sampling" validate:"dive"`
+	Routes        []AlertRouteConfig    `yaml:"routes" json:"routes" validate:"dive"` // sends to all sinks if empty
+	Incidents     IncidentsConfig       `yaml:"incidents" json:"incidents"`
+	Store         AlertStoreConfig      `yaml:"store" json:"store"`
+	AgentSLA      AgentSLAConfig        `yaml:"agentSla" json:"agentSla"`
+	Attestation   AttestationConfig     `yaml:"attestation" json:"attestation"`
 }
 
+// ResourcesConfig limits the resources of the agent containers. The agents can declare lower limits
+// in their manifests and the node operator can override the limits of an agent in the Agents list.
+//
+// The disk usage of the agent containers is checked periodically and the agents which exceed the
+// limit are stopped. The storage driver can enforce the limit as well if it supports quotas
+// (e.g. overlay2 on xfs with pquota).
+//
+// The agents which request GPUs get them through the GPU runtime only if the node enables them.
 type ResourcesConfig struct {
-	DisableAgentLimits bool    `yaml:"disableAgentLimits" json:"disableAgentLimits" default:"false" `
-	AgentMaxMemoryMiB  int     `yaml:"agentMaxMemoryMib" json:"agentMaxMemoryMib" validate:"omitempty,min=100"`
-	AgentMaxCPUs       float64 `yaml:"agentMaxCpus" json:"agentMaxCpus" validate:"omitempty,gt=0"`
+	DisableAgentLimits    bool                   `yaml:"disableAgentLimits" json:"disableAgentLimits" default:"false" `
+	AgentMaxMemoryMiB     int                    `yaml:"agentMaxMemoryMib" json:"agentMaxMemoryMib" validate:"omitempty,min=100"`
+	AgentMaxCPUs          float64                `yaml:"agentMaxCpus" json:"agentMaxCpus" validate:"omitempty,gt=0"`
+	AgentCPUShares        int64                  `yaml:"agentCpuShares" json:"agentCpuShares" validate:"omitempty,min=2"`
+	AgentMaxDiskMiB       int                    `yaml:"agentMaxDiskMib" json:"agentMaxDiskMib" validate:"omitempty,min=100"`
+	AgentDiskStorageQuota bool                   `yaml:"agentDiskStorageQuota" json:"agentDiskStorageQuota"`
+	EnableAgentGPUs       bool                   `yaml:"enableAgentGpus" json:"enableAgentGpus"`
+	AgentGPURuntime       string                 `yaml:"agentGpuRuntime" json:"agentGpuRuntime" default:"nvidia"`
+	Agents                []AgentResourcesConfig `yaml:"agents" json:"agents" validate:"dive"`
+}
+
+// AgentResourcesConfig overrides the resource limits of an agent.
+type AgentResourcesConfig struct {
+	AgentID      string  `yaml:"agentId" json:"agentId" validate:"required"`
+	MaxMemoryMiB int     `yaml:"maxMemoryMib" json:"maxMemoryMib" validate:"omitempty,min=100"`
+	MaxCPUs      float64 `yaml:"maxCpus" json:"maxCpus" validate:"omitempty,gt=0"`
+	CPUShares    int64   `yaml:"cpuShares" json:"cpuShares" validate:"omitempty,min=2"`
+	MaxDiskMiB   int     `yaml:"maxDiskMib" json:"maxDiskMib" validate:"omitempty,min=100"`
+}
+
+// AgentRestartsConfig limits restarting the agent containers which exit. The restarts back off
+// exponentially and an agent which exits more than the max attempts within the period is marked
+// as failed and is not restarted until its config changes.
+type AgentRestartsConfig struct {
+	MaxAttempts   int `yaml:"maxAttempts" json:"maxAttempts" validate:"omitempty,min=1"`
+	PeriodMinutes int `yaml:"periodMinutes" json:"periodMinutes" validate:"omitempty,min=1"`
+}
+
+// AgentCleanupConfig configures removing the containers, the networks and the images which
+// are left by the agents that do not run anymore.
+type AgentCleanupConfig struct {
+	Disable                 bool `yaml:"disable" json:"disable"`
+	ImageGracePeriodMinutes int  `yaml:"imageGracePeriodMinutes" json:"imageGracePeriodMinutes" validate:"omitempty,min=1"`
+}
+
+// EgressConfig restricts the outbound connections of the agents to the hosts which they declare in
+// the manifests. The node operator can replace the hosts of an agent. The agents are isolated in
+// internal networks and reach the allowed hosts through the egress proxy.
+type EgressConfig struct {
+	Enable bool                `yaml:"enable" json:"enable"`
+	Agents []AgentEgressConfig `yaml:"agents" json:"agents" validate:"dive"`
+}
+
+// AgentEgressConfig overrides the allowed outbound hosts of an agent.
+type AgentEgressConfig struct {
+	AgentID      string   `yaml:"agentId" json:"agentId" validate:"required"`
+	AllowedHosts []string `yaml:"allowedHosts" json:"allowedHosts"`
+}
+
+// SecurityConfig hardens the agent containers. The agents run with a restrictive seccomp profile,
+// without capabilities and with a read-only root filesystem which has a tmpfs scratch dir at /tmp.
+// The node operator can relax the hardening for the agents which need more.
+//
+// If AgentMTLS is enabled, the agent gRPC channel requires mutual TLS with the certificates issued by
+// a node-local CA. The agent containers get their certificates at start and they need an SDK which serves
+// gRPC with the certificate files in the TLS env vars.
+type SecurityConfig struct {
+	DisableAgentHardening bool                  `yaml:"disableAgentHardening" json:"disableAgentHardening"`
+	AgentTmpfsSizeMiB     int                   `yaml:"agentTmpfsSizeMib" json:"agentTmpfsSizeMib" validate:"omitempty,min=1"`
+	Agents                []AgentSecurityConfig `yaml:"agents" json:"agents" validate:"dive"`
+	AgentMTLS             bool                  `yaml:"agentMtls" json:"agentMtls"`
+}
+
+// AgentSecurityConfig relaxes the hardening of an agent.
+type AgentSecurityConfig struct {
+	AgentID        string   `yaml:"agentId" json:"agentId" validate:"required"`
+	DisableSeccomp bool     `yaml:"disableSeccomp" json:"disableSeccomp"`
+	WritableRootfs bool     `yaml:"writableRootfs" json:"writableRootfs"`
+	Capabilities   []string `yaml:"capabilities" json:"capabilities"`
+}
+
+// AgentSecretsConfig maps the secrets to the agents. The secrets are injected to the agent containers as
+// env vars when they start.
+type AgentSecretsConfig struct {
+	Vault  VaultConfig         `yaml:"vault" json:"vault"`
+	Agents []AgentSecretConfig `yaml:"agents" json:"agents" validate:"dive"`
+}
+
+// VaultConfig configures the HashiCorp Vault KV version 2 secrets engine which the agent secrets are read
+// from. The token is read from the token file in the Forta dir.
+type VaultConfig struct {
+	Address   string `yaml:"address" json:"address" validate:"omitempty,url"`
+	Mount     string `yaml:"mount" json:"mount" default:"secret"`
+	TokenFile string `yaml:"tokenFile" json:"tokenFile" default:".vault-token"`
+}
+
+// AgentSecretConfig has the secrets of an agent. The values from the Vault path override the env values
+// in the config.
+type AgentSecretConfig struct {
+	AgentID   string            `yaml:"agentId" json:"agentId" validate:"required"`
+	Env       map[string]string `yaml:"env" json:"env"`
+	VaultPath string            `yaml:"vaultPath" json:"vaultPath"`
+}
+
+// MessagingConfig selects the internal message bus backend. The local backend is an in-process bus which
+// only the single process deployments (forta run --dev) can use, so 'forta run' rejects it without --dev.
+type MessagingConfig struct {
+	Backend   string                `yaml:"backend" json:"backend" default:"nats" validate:"oneof=nats local"`
+	Lifecycle LifecycleStreamConfig `yaml:"lifecycle" json:"lifecycle"`
+	Ack       AckedPublishConfig    `yaml:"ack" json:"ack"`
+}
+
+// AckedPublishConfig makes the publishers of the critical subjects wait until a subscriber has handled the
+// message, so that the publishers slow down with a busy supervisor instead of piling up the messages. The
+// agent actions and the latest agent versions are acked if no subjects are set. Only the consumer of a
+// subject acks, and the timeout is shared by the few attempts to publish a message.
+type AckedPublishConfig struct {
+	Enable         bool     `yaml:"enable" json:"enable"`
+	Subjects       []string `yaml:"subjects" json:"subjects"`
+	TimeoutSeconds int      `yaml:"timeoutSeconds" json:"timeoutSeconds" default:"120" validate:"min=1"`
+}
+
+// LifecycleStreamConfig keeps the agent lifecycle messages on a JetStream stream of the node NATS server,
+// so that the supervisor replays the run, the stop and the latest versions messages which it missed while it
+// was down.
+type LifecycleStreamConfig struct {
+	Durable     bool `yaml:"durable" json:"durable"`
+	MaxAgeHours int  `yaml:"maxAgeHours" json:"maxAgeHours" default:"24" validate:"min=1"`
 }
 
 type ENSConfig struct {
@@ -94,13 +547,31 @@ type ENSConfig struct {
 }
 
 type TelemetryConfig struct {
-	URL     string `yaml:"url" json:"url" default:"https://alerts.forta.network/telemetry" validate:"url"`
-	Disable bool   `yaml:"disable" json:"disable"`
+	URL     string               `yaml:"url" json:"url" default:"https://alerts.forta.network/telemetry" validate:"url"`
+	Disable bool                 `yaml:"disable" json:"disable"`
+	Stats   TelemetryStatsConfig `yaml:"stats" json:"stats"`
+}
+
+// TelemetryStatsConfig enables reporting the anonymized operational stats of the node to the URL every
+// IntervalMinutes. The stats are identified by a random installation ID instead of the scanner address
+// and they do not include the report details.
+type TelemetryStatsConfig struct {
+	Enable          bool   `yaml:"enable" json:"enable"`
+	URL             string `yaml:"url" json:"url" validate:"required_if=Enable true"`
+	IntervalMinutes int    `yaml:"intervalMinutes" json:"intervalMinutes" default:"60" validate:"min=1"`
 }
 
+// AutoUpdateConfig configures updating the node to the new releases. The stable releases are read from
+// the scanner version contract by default. If ReleaseURL is set, the release of the Channel is read from
+// the release index at the URL and it is used only if it is signed by one of the ReleaseSigners. The
+// update is rolled back if the node is not healthy after HealthCheckMinutes.
 type AutoUpdateConfig struct {
-	Disable     bool `yaml:"disable" json:"disable"`
-	UpdateDelay *int `yaml:"updateDelay" json:"updateDelay"`
+	Disable            bool     `yaml:"disable" json:"disable"`
+	UpdateDelay        *int     `yaml:"updateDelay" json:"updateDelay"`
+	Channel            string   `yaml:"channel" json:"channel" default:"stable" validate:"oneof=stable testing"`
+	ReleaseURL         string   `yaml:"releaseUrl" json:"releaseUrl" validate:"omitempty,url"`
+	ReleaseSigners     []string `yaml:"releaseSigners" json:"releaseSigners" validate:"dive,eth_addr"`
+	HealthCheckMinutes int      `yaml:"healthCheckMinutes" json:"healthCheckMinutes" default:"5" validate:"min=1"`
 }
 
 type AgentLogsConfig struct {
@@ -120,6 +591,117 @@ type PrivateModeConfig struct {
 	ContainerRegistry *ContainerRegistryConfig `yaml:"containerRegistry" json:"containerRegistry"`
 }
 
+// ContainerRuntimeConfig selects the container runtime which runs the node containers. Podman
+// is supported through its Docker-compatible API service and can run rootless. containerd is
+// driven with nerdctl and the namespace isolates the node containers and images. The socket is
+// the path of the runtime API socket on the host and is detected if not specified.
+// The running containers cannot join new networks with containerd, so the agents cannot get their
+// own networks. The agents run on containerd only if SharedAgentNetwork allows them to share the node
+// network with the node containers and the other agents.
+type ContainerRuntimeConfig struct {
+	Runtime            string `yaml:"runtime" json:"runtime" default:"docker" validate:"oneof=docker podman containerd"`
+	Socket             string `yaml:"socket" json:"socket"`
+	Namespace          string `yaml:"namespace" json:"namespace" default:"forta"`
+	SharedAgentNetwork bool   `yaml:"sharedAgentNetwork" json:"sharedAgentNetwork"`
+}
+
+// KubernetesConfig runs the agents on a Kubernetes cluster instead of the node host. The agents
+// run as deployments in the namespace and the scanner connects to them through their services,
+// so the node should run in the same cluster. The kubeconfig is a file in the Forta dir and the
+// in-cluster config is used if it is not specified. The agents reach the JSON-RPC proxy of the
+// node at the proxy host port of the node host and identify themselves with their tokens. Only the
+// scanner CIDR can reach the agent pods and it is the node host if it is not specified.
+type KubernetesConfig struct {
+	Enable        bool   `yaml:"enable" json:"enable"`
+	Namespace     string `yaml:"namespace" json:"namespace" default:"forta-agents"`
+	Kubeconfig    string `yaml:"kubeconfig" json:"kubeconfig"`
+	NodeHost      string `yaml:"nodeHost" json:"nodeHost"`
+	ProxyHostPort string `yaml:"proxyHostPort" json:"proxyHostPort" default:"8545" validate:"numeric"`
+	ScannerCIDR   string `yaml:"scannerCidr" json:"scannerCidr" validate:"omitempty,cidr"`
+}
+
+// AdminAPIConfig serves the management API of the node on the host, so that the nodes can be managed
+// centrally. The API is served only over TLS and the requests need the token as the bearer token. The
+// relative certificate and key paths are in the Forta dir.
+type AdminAPIConfig struct {
+	Enable      bool   `yaml:"enable" json:"enable"`
+	Address     string `yaml:"address" json:"address" default:":8096" validate:"hostname_port"`
+	Token       string `yaml:"token" json:"token" validate:"required_if=Enable true"`
+	TLSCertFile string `yaml:"tlsCertFile" json:"tlsCertFile" validate:"required_if=Enable true"`
+	TLSKeyFile  string `yaml:"tlsKeyFile" json:"tlsKeyFile" validate:"required_if=Enable true"`
+}
+
+// MetricsConfig serves the health reports of all node containers as Prometheus metrics on the host, so
+// that a single scrape covers the whole node.
+type MetricsConfig struct {
+	Enable  bool   `yaml:"enable" json:"enable"`
+	Address string `yaml:"address" json:"address" default:"127.0.0.1:8097" validate:"hostname_port"`
+}
+
+// NodeHealthConfig serves the composed health of the node on the host for the orchestration probes. The
+// node is ready when the containers are running, the chain lag and the publisher backlog are below the
+// limits, enough agents are running and the Forta dir is writable.
+type NodeHealthConfig struct {
+	Enable              bool   `yaml:"enable" json:"enable"`
+	Address             string `yaml:"address" json:"address" default:"127.0.0.1:8098" validate:"hostname_port"`
+	MaxChainLag         int64  `yaml:"maxChainLag" json:"maxChainLag" default:"50" validate:"min=0"`
+	MinRunningAgents    int    `yaml:"minRunningAgents" json:"minRunningAgents" default:"1" validate:"min=0"`
+	MaxPublisherBacklog int    `yaml:"maxPublisherBacklog" json:"maxPublisherBacklog" default:"500" validate:"min=0"`
+}
+
+// DashboardConfig serves a web UI on the host which shows the recent alerts, the health of the agents, the
+// chain lag and the resource usage of the containers, for the operators who don't run Grafana. The summary
+// API requires the token as the bearer token, which is passed to the page as the token query parameter.
+// The token is required if the address is not a loopback address.
+type DashboardConfig struct {
+	Enable      bool   `yaml:"enable" json:"enable"`
+	Address     string `yaml:"address" json:"address" default:"127.0.0.1:8101" validate:"hostname_port"`
+	Token       string `yaml:"token" json:"token"`
+	AlertsLimit int    `yaml:"alertsLimit" json:"alertsLimit" default:"50" validate:"min=1"`
+}
+
+// TracingConfig exports the spans of the block lifecycle, from the block ingestion to the alert publishing,
+// to an OpenTelemetry collector with OTLP over HTTP. The spans of a block are sampled together.
+type TracingConfig struct {
+	Enable      bool    `yaml:"enable" json:"enable"`
+	Endpoint    string  `yaml:"endpoint" json:"endpoint" default:"http://localhost:4318" validate:"url"`
+	ServiceName string  `yaml:"serviceName" json:"serviceName" default:"forta-node"`
+	SampleRatio float64 `yaml:"sampleRatio" json:"sampleRatio" default:"1" validate:"min=0,max=1"`
+}
+
+// DebugConfig serves the runtime profiles of the node processes with pprof on localhost. The containers
+// serve them on their own localhost, so they can be read with 'docker exec'.
+type DebugConfig struct {
+	Pprof     bool   `yaml:"pprof" json:"pprof"`
+	PprofPort string `yaml:"pprofPort" json:"pprofPort" default:"6060" validate:"numeric"`
+}
+
+// AgentCanary is a synthetic transaction which is sent to an agent periodically with the alert IDs of the
+// findings which the agent should return for it. The agent should return at least one finding if no alert
+// IDs are expected. The fixture is a tx request in the protobuf JSON format and its relative path is in
+// the Forta dir.
+type AgentCanary struct {
+	AgentID          string   `yaml:"agentId" json:"agentId" validate:"required"`
+	Fixture          string   `yaml:"fixture" json:"fixture" validate:"required"`
+	ExpectedAlertIDs []string `yaml:"expectedAlertIds" json:"expectedAlertIds"`
+}
+
+// AgentCanariesConfig injects the canary transactions into the agents periodically and reports the agents
+// which stop returning the expected findings after the max misses. The canary findings are not published.
+type AgentCanariesConfig struct {
+	Enable          bool          `yaml:"enable" json:"enable"`
+	IntervalMinutes int           `yaml:"intervalMinutes" json:"intervalMinutes" default:"15" validate:"min=1"`
+	TimeoutSeconds  int           `yaml:"timeoutSeconds" json:"timeoutSeconds" default:"60" validate:"min=1"`
+	MaxMisses       int           `yaml:"maxMisses" json:"maxMisses" default:"2" validate:"min=1"`
+	Canaries        []AgentCanary `yaml:"canaries" json:"canaries" validate:"dive"`
+}
+
+// DevAgentsEnabled tells if the agents of the dev agents file can run. The dev agents skip the image
+// verification and the pinning, so only the development nodes and the dev process run them.
+func DevAgentsEnabled(cfg Config) bool {
+	return cfg.Development || cfg.DevProcess
+}
+
 type Config struct {
 	// runtime values
 
@@ -128,6 +710,7 @@ type Config struct {
 	KeyDirPath                     string         `yaml:"-" json:"_keyDirPath"`
 	Passphrase                     string         `yaml:"-" json:"_passphrase"`
 	ExposeNats                     bool           `yaml:"-" json:"_exposeNats"`
+	DevProcess                     bool           `yaml:"-" json:"_devProcess"`
 	LocalAgentsPath                string         `yaml:"-" json:"_localAgentsPath"`
 	LocalAgents                    []*AgentConfig `yaml:"-" json:"_localAgents"`
 	AgentRegistryContractAddress   string         `yaml:"-" json:"_agentRegistryContractAddress"`
@@ -138,19 +721,55 @@ type Config struct {
 
 	ChainID int `yaml:"chainId" json:"chainId" default:"1" `
 
-	Scan  ScannerConfig `yaml:"scan" json:"scan"`
-	Trace TraceConfig   `yaml:"trace" json:"trace"`
+	Scan    ScannerConfig `yaml:"scan" json:"scan"`
+	Trace   TraceConfig   `yaml:"trace" json:"trace"`
+	Mempool MempoolConfig `yaml:"mempool" json:"mempool"`
+	Chains  []ChainConfig `yaml:"chains" json:"chains" validate:"dive"`
+
+	Registry          RegistryConfig         `yaml:"registry" json:"registry"`
+	Publish           PublisherConfig        `yaml:"publish" json:"publish"`
+	JsonRpcProxy      JsonRpcProxyConfig     `yaml:"jsonRpcProxy" json:"jsonRpcProxy"`
+	Log               LogConfig              `yaml:"log" json:"log"`
+	ResourcesConfig   ResourcesConfig        `yaml:"resources" json:"resources"`
+	AgentRestarts     AgentRestartsConfig    `yaml:"agentRestarts" json:"agentRestarts"`
+	AgentCleanup      AgentCleanupConfig     `yaml:"agentCleanup" json:"agentCleanup"`
+	Egress            EgressConfig           `yaml:"egress" json:"egress"`
+	Security          SecurityConfig         `yaml:"security" json:"security"`
+	AgentSecrets      AgentSecretsConfig     `yaml:"agentSecrets" json:"agentSecrets"`
+	ENSConfig         ENSConfig              `yaml:"ens" json:"ens"`
+	TelemetryConfig   TelemetryConfig        `yaml:"telemetry" json:"telemetry"`
+	AutoUpdate        AutoUpdateConfig       `yaml:"autoUpdate" json:"autoUpdate"`
+	AgentLogsConfig   AgentLogsConfig        `yaml:"agentLogs" json:"agentLogs"`
+	PrivateModeConfig PrivateModeConfig      `yaml:"privateMode" json:"privateMode"`
+	ContainerRuntime  ContainerRuntimeConfig `yaml:"containerRuntime" json:"containerRuntime"`
+	Kubernetes        KubernetesConfig       `yaml:"kubernetes" json:"kubernetes"`
+	AdminAPI          AdminAPIConfig         `yaml:"adminApi" json:"adminApi"`
+	Metrics           MetricsConfig          `yaml:"metrics" json:"metrics"`
+	Tracing           TracingConfig          `yaml:"tracing" json:"tracing"`
+	Debug             DebugConfig            `yaml:"debug" json:"debug"`
+	NodeHealth        NodeHealthConfig       `yaml:"nodeHealth" json:"nodeHealth"`
+	Messaging         MessagingConfig        `yaml:"messaging" json:"messaging"`
+	AgentCanaries     AgentCanariesConfig    `yaml:"agentCanaries" json:"agentCanaries"`
+	Dashboard         DashboardConfig        `yaml:"dashboard" json:"dashboard"`
+}
+
+// ForChain returns a copy of the config which has the scan settings of the additional chain
+// as the main chain settings.
+func (cfg Config) ForChain(chain ChainConfig) Config {
+	cfg.ChainID = chain.ChainID
+	cfg.Scan = chain.Scan
+	cfg.Trace = chain.Trace
+	cfg.Chains = nil
+	return cfg
+}
 
-	Registry          RegistryConfig     `yaml:"registry" json:"registry"`
-	Publish           PublisherConfig    `yaml:"publish" json:"publish"`
-	JsonRpcProxy      JsonRpcProxyConfig `yaml:"jsonRpcProxy" json:"jsonRpcProxy"`
-	Log               LogConfig          `yaml:"log" json:"log"`
-	ResourcesConfig   ResourcesConfig    `yaml:"resources" json:"resources"`
-	ENSConfig         ENSConfig          `yaml:"ens" json:"ens"`
-	TelemetryConfig   TelemetryConfig    `yaml:"telemetry" json:"telemetry"`
-	AutoUpdate        AutoUpdateConfig   `yaml:"autoUpdate" json:"autoUpdate"`
-	AgentLogsConfig   AgentLogsConfig    `yaml:"agentLogs" json:"agentLogs"`
-	PrivateModeConfig PrivateModeConfig  `yaml:"privateMode" json:"privateMode"`
+// ScannedChainIDs returns the main chain ID and the IDs of the additional chains.
+func (cfg Config) ScannedChainIDs() []int64 {
+	chainIDs := []int64{int64(cfg.ChainID)}
+	for _, chain := range cfg.Chains {
+		chainIDs = append(chainIDs, int64(chain.ChainID))
+	}
+	return chainIDs
 }
 
 func (cfg *Config) ConfigFilePath() string {
@@ -168,6 +787,9 @@ func GetConfigForContainer() (Config, error) {
 		return Config{}, err
 	}
 	applyContextDefaults(&cfg)
+	if err := applyReplayEnv(&cfg); err != nil {
+		return Config{}, err
+	}
 	return cfg, nil
 }
 
@@ -191,5 +813,8 @@ func getConfigFromFile(filename string) (Config, error) {
 	if err := defaults.Set(&cfg); err != nil {
 		return Config{}, err
 	}
+	if err := ApplyEnvOverrides(&cfg, os.Environ()); err != nil {
+		return Config{}, err
+	}
 	return cfg, nil
 }
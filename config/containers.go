@@ -23,6 +23,10 @@ var (
 
 	DockerNetworkName = DockerScannerContainerName
 
+	// the agent CA is kept in a volume which only the supervisor mounts
+	DockerAgentCAVolumeName     = fmt.Sprintf("%s-agent-ca", ContainerNamePrefix)
+	DefaultContainerAgentCAPath = "/etc/forta-agent-ca"
+
 	DefaultContainerFortaDirPath        = "/.forta"
 	DefaultContainerConfigPath          = path.Join(DefaultContainerFortaDirPath, DefaultConfigFileName)
 	DefaultContainerKeyDirPath          = path.Join(DefaultContainerFortaDirPath, DefaultKeysDirName)
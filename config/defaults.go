@@ -2,10 +2,34 @@ package config
 
 const (
 	DefaultLocalAgentsFileName = "local-agents.json"
+	DefaultDevAgentsFileName   = "local-agents.yml"
 	DefaultKeysDirName         = ".keys"
+	DefaultPendingKeysDirName  = ".keys-pending"
+	DefaultArchivedKeysDirName = ".keys-archive"
 	DefaultConfigFileName      = "config.yml"
+	DefaultReplayDirName       = "replay"
+	DefaultJetStreamDirName    = "jetstream"
+	DefaultAlertsDirName       = "alerts"
+	DefaultRejectedReleaseFile = "rejected-release"
+	DefaultPausedAgentsFile    = "paused-agents.json"
+	DefaultMsgDeadLettersFile  = "message-dead-letters.log"
+	DefaultLogLevelFile        = ".log-level"
+	DefaultDebugDumpFile       = ".debug-dump"
+	DefaultDebugDumpsDirName   = "debug"
+	DefaultLogsDirName         = "logs"
+	DefaultTelemetryIDFile     = ".telemetry-id"
+	DefaultAgentTLSDirName     = ".agent-tls"
+	DefaultAgentTLSMountPath   = "/etc/forta-tls"
 	DefaultNatsPort            = "4222"
 	DefaultContainerPort       = "8089"
 	DefaultHealthPort          = "8090"
+	DefaultJSONRPCProxyPort    = "8545"
+	DefaultScannerCachePort    = "8091"
+	DefaultIncidentsPort       = "8092"
+	DefaultEgressProxyPort     = "8093"
+	DefaultAgentLogsPort       = "8094"
+	DefaultAlertsPort          = "8095"
+	DefaultAgentSLAPort        = "8099"
+	DefaultAttestationPort     = "8100"
 	DefaultFortaNodeBinaryPath = "/forta-node" // the path for the common binary in the container image
 )
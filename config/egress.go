@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// GetAgentEgressHosts returns the outbound hosts which the agent is allowed to connect to. The
+// node operator decides in the end.
+func GetAgentEgressHosts(egressCfg EgressConfig, agent AgentConfig) []string {
+	for _, override := range egressCfg.Agents {
+		if strings.EqualFold(override.AgentID, agent.ID) {
+			return override.AllowedHosts
+		}
+	}
+	return agent.EgressHosts
+}
+
+// EgressHostAllowed tells if any of the allowed hosts matches the host and port. An allowed
+// host can start with "*." to match the subdomains and can end with a port to match only
+// that port.
+func EgressHostAllowed(allowedHosts []string, host, port string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if allowedHost, allowedPort, err := net.SplitHostPort(allowed); err == nil {
+			if allowedPort != port {
+				continue
+			}
+			allowed = allowedHost
+		}
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+			continue
+		}
+		if allowed == host {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateEgressHost checks if the allowed host is a host name pattern with an optional port.
+func ValidateEgressHost(allowed string) bool {
+	if host, port, err := net.SplitHostPort(allowed); err == nil {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return false
+		}
+		allowed = host
+	}
+	allowed = strings.TrimPrefix(allowed, "*.")
+	if len(allowed) == 0 || strings.ContainsAny(allowed, "/:@*? ") {
+		return false
+	}
+	return true
+}
+
+// EgressProxyEnv returns the env vars which direct the agents to the egress proxy.
+func EgressProxyEnv() map[string]string {
+	proxyURL := fmt.Sprintf("http://%s:%s", DockerJSONRPCProxyContainerName, DefaultEgressProxyPort)
+	noProxy := fmt.Sprintf("%s,%s", DockerJSONRPCProxyContainerName, DockerScannerContainerName)
+	return map[string]string{
+		"HTTP_PROXY":  proxyURL,
+		"HTTPS_PROXY": proxyURL,
+		"NO_PROXY":    noProxy,
+		"http_proxy":  proxyURL,
+		"https_proxy": proxyURL,
+		"no_proxy":    noProxy,
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEgressHostAllowed(t *testing.T) {
+	r := require.New(t)
+
+	allowed := []string{"api.example.com", "*.example.org", "rpc.example.net:8545"}
+	r.True(EgressHostAllowed(allowed, "API.example.com", "443"))
+	r.True(EgressHostAllowed(allowed, "a.b.example.org", "80"))
+	r.False(EgressHostAllowed(allowed, "example.org", "80"))
+	r.True(EgressHostAllowed(allowed, "rpc.example.net", "8545"))
+	r.False(EgressHostAllowed(allowed, "rpc.example.net", "443"))
+	r.False(EgressHostAllowed(allowed, "evil.com", "443"))
+}
+
+func TestGetAgentEgressHosts(t *testing.T) {
+	r := require.New(t)
+
+	agent := AgentConfig{ID: "0xAgent", EgressHosts: []string{"api.example.com"}}
+	r.Equal([]string{"api.example.com"}, GetAgentEgressHosts(EgressConfig{}, agent))
+	r.Empty(GetAgentEgressHosts(EgressConfig{Agents: []AgentEgressConfig{{AgentID: "0xagent"}}}, agent))
+}
+
+func TestValidateEgressHost(t *testing.T) {
+	r := require.New(t)
+
+	r.True(ValidateEgressHost("api.example.com"))
+	r.True(ValidateEgressHost("*.example.com:443"))
+	r.False(ValidateEgressHost("https://example.com"))
+	r.False(ValidateEgressHost("example.com/path"))
+	r.False(ValidateEgressHost("*."))
+}
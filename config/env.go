@@ -1,14 +1,26 @@
 package config
 
 const (
-	EnvHostFortaDir = "HOST_FORTA_DIR" // for retrieving forta dir path on the host os
-	EnvDevelopment  = "FORTA_DEVELOPMENT"
-	EnvReleaseInfo  = "FORTA_RELEASE_INFO"
+	EnvHostFortaDir      = "HOST_FORTA_DIR"      // for retrieving forta dir path on the host os
+	EnvHostRuntimeSocket = "HOST_RUNTIME_SOCKET" // for retrieving container runtime socket path on the host os
+	EnvDevelopment       = "FORTA_DEVELOPMENT"
+	EnvReleaseInfo       = "FORTA_RELEASE_INFO"
+	EnvReplayFrom        = "FORTA_REPLAY_FROM"
+	EnvReplayTo          = "FORTA_REPLAY_TO"
 
 	// Agent env vars
 	EnvJsonRpcHost   = "JSON_RPC_HOST"
 	EnvJsonRpcPort   = "JSON_RPC_PORT"
+	EnvJsonRpcToken  = "JSON_RPC_TOKEN" // for identifying the agent pods to the proxy
 	EnvAgentGrpcPort = "AGENT_GRPC_PORT"
+
+	// JSON-RPC proxy env vars
+	EnvAgentTokenSecret = "AGENT_TOKEN_SECRET"
+
+	// Agent TLS env vars which point to the files in the agent container
+	EnvAgentGrpcTLSCert     = "AGENT_GRPC_TLS_CERT"
+	EnvAgentGrpcTLSKey      = "AGENT_GRPC_TLS_KEY"
+	EnvAgentGrpcTLSClientCA = "AGENT_GRPC_TLS_CLIENT_CA"
 )
 
 // EnvDefaults contain default values for one env.
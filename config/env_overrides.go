@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"gopkg.in/yaml.v3"
+)
+
+// Env override format
+const (
+	EnvOverridePrefix    = "FORTA_"
+	EnvOverrideSeparator = "__"
+)
+
+// ApplyEnvOverrides sets the config fields from the FORTA_ prefixed env vars, so that the node can be
+// configured without editing the config file. The nested fields are separated with double underscores
+// and the names are matched without the case and the underscores, e.g. FORTA_SCAN__JSON_RPC__URL sets
+// scan.jsonRpc.url and FORTA_CHAINS__0__CHAIN_ID sets the chainId of the first additional chain.
+// The lists can be comma separated or in the YAML flow style and the objects are in the YAML flow style.
+// The map keys are used as they are, e.g. FORTA_SCAN__JSON_RPC__HEADERS__Authorization.
+//
+// The env vars which do not start with a config field name are ignored, since the node uses
+// the same prefix for other purposes.
+func ApplyEnvOverrides(cfg *Config, environ []string) error {
+	overrides := GetEnvOverrides(environ)
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := applyEnvOverride(reflect.ValueOf(cfg).Elem(), envOverridePath(name), overrides[name]); err != nil {
+			return fmt.Errorf("invalid $%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// GetEnvOverrides returns the env vars which override the config fields.
+func GetEnvOverrides(environ []string) map[string]string {
+	overrides := make(map[string]string)
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], EnvOverridePrefix) {
+			continue
+		}
+		path := envOverridePath(parts[0])
+		if _, ok := findYamlField(reflect.TypeOf(Config{}), path[0]); !ok {
+			continue
+		}
+		overrides[parts[0]] = parts[1]
+	}
+	return overrides
+}
+
+func envOverridePath(name string) []string {
+	return strings.Split(strings.TrimPrefix(name, EnvOverridePrefix), EnvOverrideSeparator)
+}
+
+func applyEnvOverride(v reflect.Value, path []string, value string) error {
+	if len(path) == 0 {
+		return setEnvOverrideValue(v, value)
+	}
+	segment := path[0]
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+			setEnvOverrideDefaults(v.Elem())
+		}
+		return applyEnvOverride(v.Elem(), path, value)
+
+	case reflect.Struct:
+		i, ok := findYamlField(v.Type(), segment)
+		if !ok {
+			return fmt.Errorf("unknown field %q", segment)
+		}
+		return applyEnvOverride(v.Field(i), path[1:], value)
+
+	case reflect.Slice:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 {
+			return fmt.Errorf("expected a list index but found %q", segment)
+		}
+		if index >= v.Len() {
+			grown := reflect.MakeSlice(v.Type(), index+1, index+1)
+			reflect.Copy(grown, v)
+			for i := v.Len(); i < grown.Len(); i++ {
+				setEnvOverrideDefaults(grown.Index(i))
+			}
+			v.Set(grown)
+		}
+		return applyEnvOverride(v.Index(index), path[1:], value)
+
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		if len(path) > 1 || v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key %q", segment)
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := setEnvOverrideValue(elem, value); err != nil {
+			return err
+		}
+		v.SetMapIndex(reflect.ValueOf(segment), elem)
+		return nil
+	}
+	return fmt.Errorf("unexpected field %q in a %s value", segment, v.Kind())
+}
+
+func setEnvOverrideValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+		return nil
+
+	case reflect.Slice:
+		if !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var items []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); len(item) > 0 {
+					items = append(items, item)
+				}
+			}
+			value = fmt.Sprintf("[%s]", strings.Join(items, ", "))
+		}
+	}
+	// decode into a new value so that the existing list items and the object fields are replaced
+	decoded := reflect.New(v.Type())
+	setEnvOverrideDefaults(decoded.Elem())
+	if err := yaml.Unmarshal([]byte(value), decoded.Interface()); err != nil {
+		return err
+	}
+	v.Set(decoded.Elem())
+	return nil
+}
+
+// setEnvOverrideDefaults sets the defaults of the new values like the new list items.
+func setEnvOverrideDefaults(v reflect.Value) {
+	if v.Kind() == reflect.Struct && v.CanAddr() {
+		defaults.Set(v.Addr().Interface())
+	}
+}
+
+// findYamlField finds the struct field by its YAML name without the case and the underscores.
+func findYamlField(typ reflect.Type, segment string) (int, bool) {
+	if typ.Kind() != reflect.Struct {
+		return 0, false
+	}
+	segment = normalizeEnvOverrideName(segment)
+	for i := 0; i < typ.NumField(); i++ {
+		name := yamlFieldName(typ.Field(i))
+		if len(name) > 0 && normalizeEnvOverrideName(name) == segment {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func normalizeEnvOverrideName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/creasty/defaults"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEnvOverrides(t *testing.T) {
+	r := require.New(t)
+
+	var cfg Config
+	r.NoError(defaults.Set(&cfg))
+	cfg.Registry.PoolIDs = []string{"0xold"}
+
+	r.NoError(ApplyEnvOverrides(&cfg, []string{
+		"FORTA_DIR=/root/.forta",
+		"FORTA_PASSPHRASE=secret",
+		"FORTA_CHAIN_ID=137",
+		"FORTA_SCAN__JSON_RPC__URL=https://polygon.example.com",
+		"FORTA_SCAN__JSON_RPC__HEADERS__Authorization=Bearer token",
+		"FORTA_SCAN__FAILOVER__MAX_HEAD_LAG=0",
+		"FORTA_TRACE__ENABLED=true",
+		"FORTA_REGISTRY__POOL_IDS=0x1, 0x2",
+		"FORTA_CHAINS__1__CHAIN_ID=56",
+		"FORTA_JSON_RPC_PROXY__RATE_LIMIT__BURST=5",
+		"FORTA_PUBLISH__KAFKA__BROKERS=[a:9092, b:9092]",
+	}))
+
+	r.Equal("", cfg.FortaDir)
+	r.Equal("", cfg.Passphrase)
+	r.Equal(137, cfg.ChainID)
+	r.Equal("https://polygon.example.com", cfg.Scan.JsonRpc.Url)
+	r.Equal(map[string]string{"Authorization": "Bearer token"}, cfg.Scan.JsonRpc.Headers)
+	r.Equal(int64(0), cfg.Scan.Failover.MaxHeadLag)
+	r.True(cfg.Trace.Enabled)
+	r.Equal([]string{"0x1", "0x2"}, cfg.Registry.PoolIDs)
+	r.Len(cfg.Chains, 2)
+	r.Equal(56, cfg.Chains[1].ChainID)
+	r.Equal(10, cfg.Chains[1].Scan.Failover.CheckIntervalSeconds) // default
+	r.Equal(5, cfg.JsonRpcProxy.RateLimitConfig.Burst)
+	r.Equal([]string{"a:9092", "b:9092"}, cfg.Publish.Kafka.Brokers)
+
+	err := ApplyEnvOverrides(&cfg, []string{"FORTA_SCAN__JSON_RPC__URLS=x"})
+	r.EqualError(err, `invalid $FORTA_SCAN__JSON_RPC__URLS: unknown field "URLS"`)
+
+	err = ApplyEnvOverrides(&cfg, []string{"FORTA_CHAIN_ID=mainnet"})
+	r.Error(err)
+}
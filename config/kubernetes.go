@@ -0,0 +1,55 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path"
+)
+
+// KubectlHostPath is where the kubectl binary is found on the host and inside the supervisor container.
+const KubectlHostPath = "/usr/local/bin/kubectl"
+
+// KubeconfigPath returns the path of the kubeconfig inside the node containers. Empty means the
+// in-cluster config.
+func (cfg KubernetesConfig) KubeconfigPath() string {
+	if len(cfg.Kubeconfig) == 0 {
+		return ""
+	}
+	return path.Join(DefaultContainerFortaDirPath, cfg.Kubeconfig)
+}
+
+// AgentHost returns the host which the agent is dialed at. The container agents are reached through
+// their services in the cluster.
+func (cfg KubernetesConfig) AgentHost(agent AgentConfig) string {
+	if !cfg.Enable || agent.IsProcess() {
+		return agent.GrpcHost()
+	}
+	return fmt.Sprintf("%s.%s.svc", agent.ContainerName(), cfg.Namespace)
+}
+
+// ScannerIPBlock returns the CIDR which the scanner dials the agent pods from.
+func (cfg KubernetesConfig) ScannerIPBlock() (string, error) {
+	if len(cfg.ScannerCIDR) > 0 {
+		return cfg.ScannerCIDR, nil
+	}
+	ip := net.ParseIP(cfg.NodeHost)
+	switch {
+	case ip == nil:
+		return "", fmt.Errorf("scanner cidr must be set if the node host is not an ip address: %s", cfg.NodeHost)
+	case ip.To4() != nil:
+		return ip.String() + "/32", nil
+	default:
+		return ip.String() + "/128", nil
+	}
+}
+
+// AgentJsonRpcToken returns the token which identifies the agent pod to the JSON-RPC proxy. The pods
+// reach the proxy through the node host, so the proxy cannot find them from their addresses.
+func AgentJsonRpcToken(secret string, agent AgentConfig) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(agent.ID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
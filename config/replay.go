@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+)
+
+// IsReplay tells if the node replays a historical block range instead of scanning the latest blocks.
+func (cfg *Config) IsReplay() bool {
+	return cfg.Scan.EndBlock > 0
+}
+
+// ReplayDir returns the directory which keeps the alerts of the replayed block range, apart from
+// the published alerts.
+func (cfg *Config) ReplayDir() string {
+	return path.Join(cfg.FortaDir, DefaultReplayDirName, fmt.Sprintf("%d-%d", cfg.Scan.StartBlock, cfg.Scan.EndBlock))
+}
+
+// ReplayEnv returns the env vars which pass the replayed block range to the containers.
+func (cfg *Config) ReplayEnv() map[string]string {
+	if !cfg.IsReplay() {
+		return nil
+	}
+	return map[string]string{
+		EnvReplayFrom: strconv.Itoa(cfg.Scan.StartBlock),
+		EnvReplayTo:   strconv.Itoa(cfg.Scan.EndBlock),
+	}
+}
+
+// applyReplayEnv sets the replayed block range from the env vars.
+func applyReplayEnv(cfg *Config) error {
+	from, to := os.Getenv(EnvReplayFrom), os.Getenv(EnvReplayTo)
+	if len(from) == 0 || len(to) == 0 {
+		return nil
+	}
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return fmt.Errorf("invalid $%s: %v", EnvReplayFrom, err)
+	}
+	end, err := strconv.Atoi(to)
+	if err != nil {
+		return fmt.Errorf("invalid $%s: %v", EnvReplayTo, err)
+	}
+	if start < 0 || end < start {
+		return fmt.Errorf("invalid replay range: %d-%d", start, end)
+	}
+	cfg.Scan.StartBlock = start
+	cfg.Scan.EndBlock = end
+	return nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayEnv(t *testing.T) {
+	r := require.New(t)
+
+	var cfg Config
+	r.False(cfg.IsReplay())
+	r.Nil(cfg.ReplayEnv())
+
+	cfg.Scan.StartBlock = 100
+	cfg.Scan.EndBlock = 200
+	r.True(cfg.IsReplay())
+	for k, v := range cfg.ReplayEnv() {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	var containerCfg Config
+	r.NoError(applyReplayEnv(&containerCfg))
+	r.Equal(100, containerCfg.Scan.StartBlock)
+	r.Equal(200, containerCfg.Scan.EndBlock)
+
+	os.Setenv(EnvReplayTo, "50")
+	r.Error(applyReplayEnv(&containerCfg))
+}
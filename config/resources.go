@@ -1,33 +1,99 @@
 package config
 
+import "strings"
+
+// defaultCPUShares is the CPU shares of the containers if not specified.
+const defaultCPUShares = 1024
+
+// defaultAgentDiskMiB is the writable layer limit of the agent containers if not specified.
+const defaultAgentDiskMiB = 1024
+
 // AgentResourceLimits contain the agent resource limits data.
 type AgentResourceLimits struct {
-	CPUQuota int64 // in microseconds
-	Memory   int64 // in bytes
+	CPUQuota  int64 // in microseconds
+	CPUShares int64
+	Memory    int64 // in bytes
+	Disk      int64 // in bytes
+	GPUs      int
 }
 
-// GetAgentResourceLimits calculates and returns the resource limits by
+// GetAgentResourceLimits calculates and returns the resource limits of the agent by
 // taking the configuration into account. Zero values mean no limits.
-func GetAgentResourceLimits(resourcesCfg ResourcesConfig) *AgentResourceLimits {
+func GetAgentResourceLimits(resourcesCfg ResourcesConfig, agent AgentConfig) *AgentResourceLimits {
 	var limits AgentResourceLimits
 
+	if resourcesCfg.EnableAgentGPUs && agent.UsesGPU() {
+		limits.GPUs = agent.Resources.GPUs
+	}
+
 	if resourcesCfg.DisableAgentLimits {
 		return &limits
 	}
 
 	limits.CPUQuota = getDefaultCPUQuotaPerAgent()
 	if resourcesCfg.AgentMaxCPUs > 0 {
-		limits.CPUQuota = int64(resourcesCfg.AgentMaxCPUs * float64(100000))
+		limits.CPUQuota = cpusToQuota(resourcesCfg.AgentMaxCPUs)
 	}
 
 	limits.Memory = getDefaultMemoryPerAgent()
 	if resourcesCfg.AgentMaxMemoryMiB > 0 {
-		limits.Memory = int64(resourcesCfg.AgentMaxMemoryMiB * 104858)
+		limits.Memory = mibToBytes(resourcesCfg.AgentMaxMemoryMiB)
+	}
+
+	limits.CPUShares = resourcesCfg.AgentCPUShares
+
+	limits.Disk = mibToBytes(defaultAgentDiskMiB)
+	if resourcesCfg.AgentMaxDiskMiB > 0 {
+		limits.Disk = mibToBytes(resourcesCfg.AgentMaxDiskMiB)
+	}
+
+	// the agents can ask for less but not more than the node limits
+	if res := agent.Resources; res != nil {
+		if res.MaxCPUs > 0 && cpusToQuota(res.MaxCPUs) < limits.CPUQuota {
+			limits.CPUQuota = cpusToQuota(res.MaxCPUs)
+		}
+		if res.MaxMemoryMiB > 0 && mibToBytes(res.MaxMemoryMiB) < limits.Memory {
+			limits.Memory = mibToBytes(res.MaxMemoryMiB)
+		}
+		maxShares := limits.CPUShares
+		if maxShares == 0 {
+			maxShares = defaultCPUShares
+		}
+		if res.CPUShares > 0 && res.CPUShares < maxShares {
+			limits.CPUShares = res.CPUShares
+		}
+	}
+
+	// the node operator decides in the end
+	for _, override := range resourcesCfg.Agents {
+		if !strings.EqualFold(override.AgentID, agent.ID) {
+			continue
+		}
+		if override.MaxCPUs > 0 {
+			limits.CPUQuota = cpusToQuota(override.MaxCPUs)
+		}
+		if override.MaxMemoryMiB > 0 {
+			limits.Memory = mibToBytes(override.MaxMemoryMiB)
+		}
+		if override.CPUShares > 0 {
+			limits.CPUShares = override.CPUShares
+		}
+		if override.MaxDiskMiB > 0 {
+			limits.Disk = mibToBytes(override.MaxDiskMiB)
+		}
 	}
 
 	return &limits
 }
 
+func cpusToQuota(cpus float64) int64 {
+	return int64(cpus * float64(100000))
+}
+
+func mibToBytes(mib int) int64 {
+	return int64(mib) * 1048576
+}
+
 // getDefaultCPUQuotaPerAgent returns the default CFS microseconds value allowed per agent
 func getDefaultCPUQuotaPerAgent() int64 {
 	return 20000 // just 20%
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAgentResourceLimits(t *testing.T) {
+	r := require.New(t)
+
+	resourcesCfg := ResourcesConfig{
+		AgentMaxCPUs:      0.5,
+		AgentMaxMemoryMiB: 500,
+		Agents: []AgentResourcesConfig{
+			{AgentID: "0xAGENT2", MaxMemoryMiB: 2000, CPUShares: 2048, MaxDiskMiB: 200},
+		},
+	}
+
+	limits := GetAgentResourceLimits(resourcesCfg, AgentConfig{ID: "0xagent1"})
+	r.Equal(&AgentResourceLimits{CPUQuota: 50000, Memory: 500 * 1048576, Disk: 1024 * 1048576}, limits)
+
+	// the agents can ask for less but not more
+	limits = GetAgentResourceLimits(resourcesCfg, AgentConfig{
+		ID:        "0xagent1",
+		Resources: &AgentResources{MaxCPUs: 2, MaxMemoryMiB: 200, CPUShares: 4096},
+	})
+	r.Equal(&AgentResourceLimits{CPUQuota: 50000, Memory: 200 * 1048576, Disk: 1024 * 1048576}, limits)
+
+	// the node operator can override
+	limits = GetAgentResourceLimits(resourcesCfg, AgentConfig{
+		ID:        "0xagent2",
+		Resources: &AgentResources{CPUShares: 512},
+	})
+	r.Equal(&AgentResourceLimits{CPUQuota: 50000, CPUShares: 2048, Memory: 2000 * 1048576, Disk: 200 * 1048576}, limits)
+
+	limits = GetAgentResourceLimits(ResourcesConfig{DisableAgentLimits: true}, AgentConfig{ID: "0xagent2"})
+	r.Equal(&AgentResourceLimits{}, limits)
+
+	// the GPUs are given only if the node enables them
+	gpuAgent := AgentConfig{ID: "0xagent3", Resources: &AgentResources{GPUs: 2}}
+	r.Equal(0, GetAgentResourceLimits(resourcesCfg, gpuAgent).GPUs)
+	limits = GetAgentResourceLimits(ResourcesConfig{DisableAgentLimits: true, EnableAgentGPUs: true}, gpuAgent)
+	r.Equal(&AgentResourceLimits{GPUs: 2}, limits)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// Container runtimes
+const (
+	ContainerRuntimeDocker     = "docker"
+	ContainerRuntimePodman     = "podman"
+	ContainerRuntimeContainerd = "containerd"
+)
+
+// Container runtime sockets
+const (
+	// DefaultContainerRuntimeSocket is where the runtime socket is found on the host with Docker
+	// and where the Docker-compatible sockets are mounted inside the node containers.
+	DefaultContainerRuntimeSocket = "/var/run/docker.sock"
+	// DefaultPodmanSocket is the socket of the rootful Podman API service.
+	DefaultPodmanSocket = "/run/podman/podman.sock"
+	// DefaultContainerdSocket is the socket of containerd on the host and inside the node containers.
+	DefaultContainerdSocket = "/run/containerd/containerd.sock"
+)
+
+// nerdctlHostPaths are shared with the supervisor which runs nerdctl because the containers created
+// from inside call back the host nerdctl binary as an OCI hook and share its state. The binaries are
+// mounted read-only.
+var nerdctlHostPaths = map[string]string{
+	"/usr/local/bin/nerdctl": "/usr/local/bin/nerdctl:ro",
+	"/var/lib/nerdctl":       "/var/lib/nerdctl",
+	"/var/lib/cni":           "/var/lib/cni",
+	"/etc/cni/net.d":         "/etc/cni/net.d",
+	"/opt/cni/bin":           "/opt/cni/bin:ro",
+}
+
+// nerdctlInspectHostPaths are enough for inspecting the containers with nerdctl.
+var nerdctlInspectHostPaths = map[string]string{
+	"/usr/local/bin/nerdctl": "/usr/local/bin/nerdctl:ro",
+	"/var/lib/nerdctl":       "/var/lib/nerdctl:ro",
+}
+
+// HostSocketPath returns the path of the runtime API socket on the host. Podman is driven through
+// its Docker-compatible API service so the rootless socket under $XDG_RUNTIME_DIR is preferred
+// when the node runs as a regular user.
+func (cfg ContainerRuntimeConfig) HostSocketPath() string {
+	switch {
+	case len(cfg.Socket) > 0:
+		return cfg.Socket
+	case cfg.Runtime == ContainerRuntimeContainerd:
+		return DefaultContainerdSocket
+	case cfg.Runtime != ContainerRuntimePodman:
+		return DefaultContainerRuntimeSocket
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); len(runtimeDir) > 0 && os.Getuid() != 0 {
+		return path.Join(runtimeDir, "podman", "podman.sock")
+	}
+	if os.Getuid() != 0 {
+		return fmt.Sprintf("/run/user/%d/podman/podman.sock", os.Getuid())
+	}
+	return DefaultPodmanSocket
+}
+
+// ContainerSocketPath returns the path of the runtime API socket inside the node containers.
+func (cfg ContainerRuntimeConfig) ContainerSocketPath() string {
+	if cfg.Runtime == ContainerRuntimeContainerd {
+		return DefaultContainerdSocket
+	}
+	return DefaultContainerRuntimeSocket
+}
+
+// Volumes returns the mounts which give a node container access to the container runtime.
+func (cfg ContainerRuntimeConfig) Volumes(hostSocket string) map[string]string {
+	return cfg.volumes(hostSocket, nerdctlHostPaths)
+}
+
+// InspectVolumes returns the mounts which give a node container access to inspect the containers. The
+// node containers which do not create the containers cannot change the networks of the host with these.
+func (cfg ContainerRuntimeConfig) InspectVolumes(hostSocket string) map[string]string {
+	return cfg.volumes(hostSocket, nerdctlInspectHostPaths)
+}
+
+func (cfg ContainerRuntimeConfig) volumes(hostSocket string, hostPaths map[string]string) map[string]string {
+	volumes := map[string]string{
+		hostSocket: cfg.ContainerSocketPath(),
+	}
+	if cfg.Runtime == ContainerRuntimeContainerd {
+		for hostPath, containerPath := range hostPaths {
+			volumes[hostPath] = containerPath
+		}
+	}
+	return volumes
+}
+
+// CanIsolateAgents tells if the agents can run in their own networks.
+func (cfg ContainerRuntimeConfig) CanIsolateAgents() bool {
+	return cfg.Runtime != ContainerRuntimeContainerd
+}
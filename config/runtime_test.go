@@ -0,0 +1,44 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerRuntimeHostSocketPath(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(DefaultContainerRuntimeSocket, ContainerRuntimeConfig{Runtime: ContainerRuntimeDocker}.HostSocketPath())
+	r.Equal("/tmp/podman.sock", ContainerRuntimeConfig{Runtime: ContainerRuntimePodman, Socket: "/tmp/podman.sock"}.HostSocketPath())
+
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	podmanSocket := ContainerRuntimeConfig{Runtime: ContainerRuntimePodman}.HostSocketPath()
+	if os.Getuid() == 0 {
+		r.Equal(DefaultPodmanSocket, podmanSocket)
+	} else {
+		r.Equal("/run/user/1000/podman/podman.sock", podmanSocket)
+	}
+}
+
+func TestContainerRuntimeVolumes(t *testing.T) {
+	r := require.New(t)
+
+	podmanRuntime := ContainerRuntimeConfig{Runtime: ContainerRuntimePodman}
+	r.Equal(map[string]string{"/run/user/1000/podman/podman.sock": DefaultContainerRuntimeSocket}, podmanRuntime.Volumes("/run/user/1000/podman/podman.sock"))
+
+	containerdRuntime := ContainerRuntimeConfig{Runtime: ContainerRuntimeContainerd}
+	r.Equal(DefaultContainerdSocket, containerdRuntime.HostSocketPath())
+	volumes := containerdRuntime.Volumes(DefaultContainerdSocket)
+	r.Equal(DefaultContainerdSocket, volumes[DefaultContainerdSocket])
+	r.Equal("/usr/local/bin/nerdctl:ro", volumes["/usr/local/bin/nerdctl"])
+	r.Equal("/etc/cni/net.d", volumes["/etc/cni/net.d"])
+
+	// the containers which only inspect do not get the network config
+	volumes = containerdRuntime.InspectVolumes(DefaultContainerdSocket)
+	r.Equal(DefaultContainerdSocket, volumes[DefaultContainerdSocket])
+	r.Equal("/var/lib/nerdctl:ro", volumes["/var/lib/nerdctl"])
+	r.NotContains(volumes, "/etc/cni/net.d")
+	r.NotContains(volumes, "/var/lib/cni")
+}
@@ -0,0 +1,442 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the JSON schema draft which the config schema follows.
+const SchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a JSON schema which describes the config file. Only the keywords which can be derived
+// from the config struct tags are supported.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"` // a type name or a list of type names
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // false or a schema
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+}
+
+// SchemaError is a config file value which does not match the schema.
+type SchemaError struct {
+	Path   string
+	Reason string
+}
+
+func (err SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Path, err.Reason)
+}
+
+// GenerateSchema generates the schema of the config file from the YAML names, the defaults and
+// the validation tags of the config fields.
+func GenerateSchema() *Schema {
+	schema := schemaForType(reflect.TypeOf(Config{}))
+	schema.Schema = SchemaVersion
+	return schema
+}
+
+func schemaForType(typ reflect.Type) *Schema {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		schema := schemaForType(typ.Elem())
+		schema.Type = []interface{}{schema.Type, "null"}
+		return schema
+
+	case reflect.Struct:
+		schema := &Schema{
+			Type:                 "object",
+			Properties:           make(map[string]*Schema),
+			AdditionalProperties: false,
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := yamlFieldName(field)
+			if len(name) == 0 {
+				continue
+			}
+			fieldSchema := schemaForType(field.Type)
+			applyFieldTags(fieldSchema, field)
+			if isRequiredField(field) {
+				schema.Required = append(schema.Required, name)
+			}
+			schema.Properties[name] = fieldSchema
+		}
+		sort.Strings(schema.Required)
+		return schema
+
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaForType(typ.Elem())}
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaForType(typ.Elem())}
+
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+
+	default:
+		return &Schema{}
+	}
+}
+
+// yamlFieldName returns the name of the field in the config file. Empty means that the field
+// is not read from the config file.
+func yamlFieldName(field reflect.StructField) string {
+	if len(field.PkgPath) > 0 {
+		return "" // unexported
+	}
+	tag, ok := field.Tag.Lookup("yaml")
+	if !ok {
+		return strings.ToLower(field.Name)
+	}
+	name := strings.SplitN(tag, ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if len(name) == 0 {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// isRequiredField tells if the field should be in the config file. The fields which have
+// defaults are never required.
+func isRequiredField(field reflect.StructField) bool {
+	if _, ok := field.Tag.Lookup("default"); ok {
+		return false
+	}
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+func applyFieldTags(schema *Schema, field reflect.StructField) {
+	if defaultValue, ok := field.Tag.Lookup("default"); ok {
+		schema.Default = parseSchemaValue(schema, defaultValue)
+	}
+
+	var omitEmpty bool
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "dive" {
+			break // the rest applies to the items
+		}
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		switch name {
+		case "omitempty":
+			omitEmpty = true
+
+		case "url":
+			schema.Format = "uri"
+
+		case "oneof":
+			if omitEmpty {
+				schema.Enum = append(schema.Enum, parseSchemaValue(schema, ""))
+			}
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, parseSchemaValue(schema, value))
+			}
+
+		case "min", "gte", "gt", "max", "lte":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			applyLimit(schema, name, n, omitEmpty)
+		}
+	}
+
+	// the rules after dive apply to the items
+	if i := strings.Index(field.Tag.Get("validate"), "dive,"); i >= 0 && schema.Items != nil {
+		applyFieldTags(schema.Items, reflect.StructField{Tag: reflect.StructTag(fmt.Sprintf(`validate:"%s"`, field.Tag.Get("validate")[i+5:]))})
+	}
+}
+
+func applyLimit(schema *Schema, rule string, n float64, omitEmpty bool) {
+	switch schema.Type {
+	case "integer", "number":
+		// the zero value is allowed with omitempty, so the minimum can't be expressed
+		if omitEmpty && (rule == "min" || rule == "gte" || rule == "gt") && n >= 0 {
+			schema.Minimum = floatPtr(0)
+			return
+		}
+		switch rule {
+		case "min", "gte":
+			schema.Minimum = floatPtr(n)
+		case "gt":
+			schema.ExclusiveMinimum = floatPtr(n)
+		case "max", "lte":
+			schema.Maximum = floatPtr(n)
+		}
+
+	case "string":
+		if (rule == "min" || rule == "gte") && !omitEmpty {
+			schema.MinLength = intPtr(int(n))
+		}
+
+	case "array":
+		if (rule == "min" || rule == "gte") && !omitEmpty {
+			schema.MinItems = intPtr(int(n))
+		}
+	}
+}
+
+// parseSchemaValue converts the tag value to the type of the schema.
+func parseSchemaValue(schema *Schema, value string) interface{} {
+	typ := schema.Type
+	if types, ok := typ.([]interface{}); ok && len(types) > 0 {
+		typ = types[0]
+	}
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "object", "array":
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v
+		}
+		return nil
+	}
+	return value
+}
+
+func floatPtr(n float64) *float64 {
+	return &n
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// Validate checks the value which is decoded from the config file and returns the mismatches.
+func (schema *Schema) Validate(value interface{}) []SchemaError {
+	var errs []SchemaError
+	schema.validate("", value, &errs)
+	return errs
+}
+
+func (schema *Schema) validate(path string, value interface{}, errs *[]SchemaError) {
+	fail := func(reason string, args ...interface{}) {
+		p := path
+		if len(p) == 0 {
+			p = "(root)"
+		}
+		*errs = append(*errs, SchemaError{Path: p, Reason: fmt.Sprintf(reason, args...)})
+	}
+
+	if !schema.matchesType(value) {
+		fail("expected %s but found %s", schema.typeNames(), schemaTypeOf(value))
+		return
+	}
+	if value == nil {
+		return
+	}
+
+	if len(schema.Enum) > 0 {
+		var found bool
+		for _, allowed := range schema.Enum {
+			if schemaValuesEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %v but found %v", schema.enumNames(), value)
+		}
+	}
+
+	if n, ok := toFloat(value); ok {
+		if schema.Minimum != nil && n < *schema.Minimum {
+			fail("must be at least %v but found %v", *schema.Minimum, value)
+		}
+		if schema.ExclusiveMinimum != nil && n <= *schema.ExclusiveMinimum {
+			fail("must be greater than %v but found %v", *schema.ExclusiveMinimum, value)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			fail("must be at most %v but found %v", *schema.Maximum, value)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if schema.MinLength != nil && len(v) < *schema.MinLength {
+			fail("must have at least %d characters", *schema.MinLength)
+		}
+		if schema.Format == "uri" && len(v) > 0 {
+			if u, err := url.Parse(v); err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+				fail("must be a URL with a scheme (e.g. https://) but found %q", v)
+			}
+		}
+
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			fail("must have at least %d items", *schema.MinItems)
+		}
+		if schema.Items != nil {
+			for i, item := range v {
+				schema.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required field %q", name)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldPath := key
+			if len(path) > 0 {
+				fieldPath = path + "." + key
+			}
+			if fieldSchema, ok := schema.Properties[key]; ok {
+				fieldSchema.validate(fieldPath, v[key], errs)
+				continue
+			}
+			switch additional := schema.AdditionalProperties.(type) {
+			case *Schema:
+				additional.validate(fieldPath, v[key], errs)
+			case bool:
+				if !additional {
+					*errs = append(*errs, SchemaError{Path: fieldPath, Reason: schema.unknownFieldReason(key)})
+				}
+			}
+		}
+	}
+}
+
+func (schema *Schema) unknownFieldReason(key string) string {
+	for name := range schema.Properties {
+		if strings.EqualFold(name, key) {
+			return fmt.Sprintf("unknown field - did you mean %q?", name)
+		}
+	}
+	return "unknown field"
+}
+
+func (schema *Schema) typeNames() []string {
+	switch typ := schema.Type.(type) {
+	case string:
+		return []string{typ}
+	case []interface{}:
+		var names []string
+		for _, name := range typ {
+			names = append(names, fmt.Sprint(name))
+		}
+		return names
+	}
+	return nil
+}
+
+func (schema *Schema) enumNames() []string {
+	var names []string
+	for _, value := range schema.Enum {
+		names = append(names, fmt.Sprintf("%q", fmt.Sprint(value)))
+	}
+	return names
+}
+
+func (schema *Schema) matchesType(value interface{}) bool {
+	names := schema.typeNames()
+	if len(names) == 0 {
+		return true
+	}
+	actual := schemaTypeOf(value)
+	for _, name := range names {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaTypeOf returns the schema type name of a value which is decoded from YAML.
+func schemaTypeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64:
+		if n, _ := toFloat(v); n == math.Trunc(n) && !math.IsInf(n, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return reflect.TypeOf(value).Kind().String()
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func schemaValuesEqual(a, b interface{}) bool {
+	na, okA := toFloat(a)
+	nb, okB := toFloat(b)
+	if okA && okB {
+		return na == nb
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateSchema(t *testing.T) {
+	r := require.New(t)
+
+	schema := GenerateSchema()
+	r.Equal(SchemaVersion, schema.Schema)
+	r.NotContains(schema.Properties, "_fortaDir")
+
+	failover := schema.Properties["scan"].Properties["failover"]
+	r.Equal("integer", failover.Properties["checkIntervalSeconds"].Type)
+	r.Equal(int64(10), failover.Properties["checkIntervalSeconds"].Default)
+	r.Equal(1.0, *failover.Properties["checkIntervalSeconds"].Minimum)
+	r.Equal(0.0, *failover.Properties["maxErrorRate"].ExclusiveMinimum)
+	r.Equal(1.0, *failover.Properties["maxErrorRate"].Maximum)
+
+	r.Equal([]interface{}{"rpc", "file"}, schema.Properties["scan"].Properties["dataSource"].Properties["type"].Enum)
+	r.Equal([]string{"chainId"}, schema.Properties["chains"].Items.Required)
+	r.Equal("uri", schema.Properties["registry"].Properties["ipfs"].Properties["fallbackGatewayUrls"].Items.Format)
+}
+
+func TestSchemaValidate(t *testing.T) {
+	r := require.New(t)
+
+	var doc interface{}
+	r.NoError(yaml.Unmarshal([]byte(`
+chainId: "1"
+scan:
+  jsonRpc:
+    url: localhost:8545
+  failover:
+    maxErrorRate: 2
+  dataSource:
+    type: files
+chains:
+  - scan: {}
+registry:
+  jsonrpc: {}
+log:
+  level: debug
+`), &doc))
+
+	var paths []string
+	for _, err := range GenerateSchema().Validate(doc) {
+		paths = append(paths, err.Path)
+	}
+	r.Equal([]string{
+		"chainId",
+		"chains[0]",
+		"registry.jsonrpc",
+		"scan.dataSource.type",
+		"scan.failover.maxErrorRate",
+		"scan.jsonRpc.url",
+	}, paths)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	_ "embed" // for the seccomp profile
+	"strings"
+)
+
+// defaultAgentTmpfsSizeMiB is the size of the agent scratch dir if not specified.
+const defaultAgentTmpfsSizeMiB = 64
+
+// AgentScratchDir is the writable dir of the agents which have a read-only root filesystem.
+const AgentScratchDir = "/tmp"
+
+// agentSeccompProfile allows the syscalls which the agent runtimes need and denies the ones
+// which are useful for escaping the container, like ptrace, mount, bpf and creating namespaces.
+//
+//go:embed seccomp.json
+var agentSeccompProfile string
+
+// AgentSecurityOptions contain the hardening options of an agent container.
+type AgentSecurityOptions struct {
+	SeccompProfile   string // empty means the default profile of the runtime
+	NoNewPrivileges  bool
+	DropCapabilities bool
+	Capabilities     []string // kept after dropping the rest
+	ReadOnlyRootfs   bool
+	TmpfsSizeMiB     int // zero means no scratch dir
+}
+
+// GetAgentSecurityOptions returns the hardening options of the agent by taking the configuration
+// into account. Zero values mean no hardening.
+func GetAgentSecurityOptions(securityCfg SecurityConfig, agent AgentConfig) *AgentSecurityOptions {
+	var opts AgentSecurityOptions
+
+	if securityCfg.DisableAgentHardening {
+		return &opts
+	}
+
+	opts.SeccompProfile = agentSeccompProfile
+	opts.NoNewPrivileges = true
+	opts.DropCapabilities = true
+	opts.ReadOnlyRootfs = true
+
+	for _, override := range securityCfg.Agents {
+		if !strings.EqualFold(override.AgentID, agent.ID) {
+			continue
+		}
+		if override.DisableSeccomp {
+			opts.SeccompProfile = ""
+		}
+		if override.WritableRootfs {
+			opts.ReadOnlyRootfs = false
+		}
+		for _, capability := range override.Capabilities {
+			capability = strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+			opts.Capabilities = append(opts.Capabilities, capability)
+		}
+	}
+
+	if opts.ReadOnlyRootfs {
+		opts.TmpfsSizeMiB = defaultAgentTmpfsSizeMiB
+		if securityCfg.AgentTmpfsSizeMiB > 0 {
+			opts.TmpfsSizeMiB = securityCfg.AgentTmpfsSizeMiB
+		}
+	}
+
+	return &opts
+}
+
+// UseAgentMTLS tells if the agent gRPC channel requires mutual TLS. The process agents on the host and
+// the agent pods do not get the certificates. The network policies of the pods let only the scanner in.
+func UseAgentMTLS(cfg Config, agent AgentConfig) bool {
+	return cfg.Security.AgentMTLS && !agent.IsProcess() && !cfg.Kubernetes.Enable
+}
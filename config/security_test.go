@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAgentSecurityOptions(t *testing.T) {
+	r := require.New(t)
+
+	securityCfg := SecurityConfig{
+		Agents: []AgentSecurityConfig{
+			{AgentID: "0xAGENT2", DisableSeccomp: true, WritableRootfs: true, Capabilities: []string{"cap_net_raw"}},
+		},
+	}
+
+	opts := GetAgentSecurityOptions(securityCfg, AgentConfig{ID: "0xagent1"})
+	r.Equal(&AgentSecurityOptions{
+		SeccompProfile:   agentSeccompProfile,
+		NoNewPrivileges:  true,
+		DropCapabilities: true,
+		ReadOnlyRootfs:   true,
+		TmpfsSizeMiB:     defaultAgentTmpfsSizeMiB,
+	}, opts)
+
+	// the node operator can relax the hardening of an agent
+	opts = GetAgentSecurityOptions(securityCfg, AgentConfig{ID: "0xagent2"})
+	r.Equal(&AgentSecurityOptions{
+		NoNewPrivileges:  true,
+		DropCapabilities: true,
+		Capabilities:     []string{"NET_RAW"},
+	}, opts)
+
+	opts = GetAgentSecurityOptions(SecurityConfig{AgentTmpfsSizeMiB: 128}, AgentConfig{ID: "0xagent1"})
+	r.Equal(128, opts.TmpfsSizeMiB)
+
+	opts = GetAgentSecurityOptions(SecurityConfig{DisableAgentHardening: true}, AgentConfig{ID: "0xagent1"})
+	r.Equal(&AgentSecurityOptions{}, opts)
+}
+
+func TestAgentSeccompProfile(t *testing.T) {
+	r := require.New(t)
+
+	var profile struct {
+		DefaultAction string `json:"defaultAction"`
+		Syscalls      []struct {
+			Names  []string `json:"names"`
+			Action string   `json:"action"`
+		} `json:"syscalls"`
+	}
+	r.NoError(json.Unmarshal([]byte(agentSeccompProfile), &profile))
+	r.Equal("SCMP_ACT_ERRNO", profile.DefaultAction)
+
+	allowed := make(map[string]bool)
+	for _, syscall := range profile.Syscalls {
+		if syscall.Action != "SCMP_ACT_ALLOW" {
+			continue
+		}
+		for _, name := range syscall.Names {
+			allowed[name] = true
+		}
+	}
+	for _, name := range []string{"ptrace", "mount", "bpf", "unshare", "setns", "keyctl", "io_uring_setup"} {
+		r.False(allowed[name], name)
+	}
+}
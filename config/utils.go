@@ -1,8 +1,11 @@
 package config
 
 import (
+	"io/ioutil"
 	"math/big"
 	"os"
+	"path"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -32,6 +35,27 @@ func InitLogLevel(cfg Config) error {
 	return nil
 }
 
+// LogLevelOverride returns the log level which was set with the admin API. It is empty if the log level
+// from the config should be used.
+func LogLevelOverride(fortaDir string) string {
+	if len(fortaDir) == 0 {
+		return ""
+	}
+	b, err := ioutil.ReadFile(path.Join(fortaDir, DefaultLogLevelFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// LogLevel returns the log level override or the log level from the config.
+func LogLevel(cfg Config) string {
+	if lvl := LogLevelOverride(cfg.FortaDir); len(lvl) > 0 {
+		return lvl
+	}
+	return cfg.Log.Level
+}
+
 func readFile(filename string, cfg *Config) error {
 	f, err := os.Open(filename)
 	if f != nil {
@@ -0,0 +1,221 @@
+package crash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DirName is the name of the dir in the Forta dir which the crash reports are written to.
+	DirName = "crashes"
+
+	logTailSize = 200
+)
+
+// Report is the crash report which is written when a goroutine panics.
+type Report struct {
+	Process           string   `json:"process"`
+	Goroutine         string   `json:"goroutine"`
+	Time              string   `json:"time"`
+	Version           string   `json:"version"`
+	Panic             string   `json:"panic"`
+	Stack             string   `json:"stack"`
+	ConfigFingerprint string   `json:"configFingerprint"`
+	LogTail           []string `json:"logTail"`
+}
+
+// reporter collects the crash report details of the process.
+type reporter struct {
+	dir         string
+	process     string
+	fingerprint string
+	tail        *logTail
+}
+
+var (
+	current    *reporter
+	currentMu  sync.RWMutex
+	crashCount uint64
+)
+
+// Init initializes the crash reports of the process. It keeps the recent log lines so they can be
+// included in the crash reports.
+func Init(cfg config.Config, process string) {
+	tail := &logTail{}
+	log.AddHook(tail)
+
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = &reporter{
+		dir:         Dir(cfg.FortaDir),
+		process:     process,
+		fingerprint: configFingerprint(cfg),
+		tail:        tail,
+	}
+}
+
+// Dir returns the crash reports dir.
+func Dir(fortaDir string) string {
+	return path.Join(fortaDir, DirName)
+}
+
+// Count returns the number of the panics which were captured in this process.
+func Count() uint64 {
+	return atomic.LoadUint64(&crashCount)
+}
+
+// configFingerprint helps telling if the crashes happened with the same config without including it.
+func configFingerprint(cfg config.Config) string {
+	b, _ := json.Marshal(cfg)
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:8])
+}
+
+// Recover recovers the panic of the goroutine, writes the crash report and calls the panic handlers.
+// It should be deferred directly.
+func Recover(goroutine string, onPanic ...func()) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	handle(goroutine, r)
+	for _, handler := range onPanic {
+		handler()
+	}
+}
+
+// Capture writes the crash report and panics again so that the process exits. It should be deferred
+// directly.
+func Capture(goroutine string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	handle(goroutine, r)
+	panic(r)
+}
+
+// Go runs the function in a new goroutine which recovers from the panics.
+func Go(goroutine string, fn func(), onPanic ...func()) {
+	go func() {
+		defer Recover(goroutine, onPanic...)
+		fn()
+	}()
+}
+
+func handle(goroutine string, r interface{}) {
+	atomic.AddUint64(&crashCount, 1)
+	stack := string(debug.Stack())
+	logger := log.WithFields(log.Fields{
+		"goroutine": goroutine,
+		"panic":     fmt.Sprint(r),
+	})
+	filePath, err := writeReport(goroutine, r, stack)
+	if err != nil {
+		logger.WithError(err).Error("recovered from panic - failed to write the crash report")
+		return
+	}
+	logger.WithField("report", filePath).Error("recovered from panic")
+}
+
+func writeReport(goroutine string, r interface{}, stack string) (string, error) {
+	currentMu.RLock()
+	rep := current
+	currentMu.RUnlock()
+	if rep == nil {
+		return "", fmt.Errorf("crash reports are not initialized")
+	}
+
+	now := time.Now().UTC()
+	report := &Report{
+		Process:           rep.process,
+		Goroutine:         goroutine,
+		Time:              now.Format(time.RFC3339Nano),
+		Version:           config.Version,
+		Panic:             fmt.Sprint(r),
+		Stack:             stack,
+		ConfigFingerprint: rep.fingerprint,
+		LogTail:           rep.tail.Lines(),
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(rep.dir, 0755); err != nil {
+		return "", err
+	}
+	fileName := fmt.Sprintf("%s-%s-%d.json", rep.process, now.Format("20060102T150405.000"), Count())
+	filePath := path.Join(rep.dir, fileName)
+	return filePath, ioutil.WriteFile(filePath, b, 0644)
+}
+
+// ListReports returns the names of the crash reports in the dir.
+func ListReports(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, file.Name())
+		}
+	}
+	return names, nil
+}
+
+// logTail keeps the last log lines.
+type logTail struct {
+	lines []string
+	next  int
+	mu    sync.Mutex
+}
+
+// Levels implements logrus.Hook.
+func (tail *logTail) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (tail *logTail) Fire(entry *log.Entry) error {
+	line, err := entry.String()
+	if err != nil {
+		return nil
+	}
+	line = strings.TrimSuffix(line, "\n")
+
+	tail.mu.Lock()
+	defer tail.mu.Unlock()
+	if len(tail.lines) < logTailSize {
+		tail.lines = append(tail.lines, line)
+		return nil
+	}
+	tail.lines[tail.next] = line
+	tail.next = (tail.next + 1) % logTailSize
+	return nil
+}
+
+// Lines returns the log lines from the oldest to the newest.
+func (tail *logTail) Lines() []string {
+	tail.mu.Lock()
+	defer tail.mu.Unlock()
+	lines := make([]string, 0, len(tail.lines))
+	lines = append(lines, tail.lines[tail.next:]...)
+	return append(lines, tail.lines[:tail.next]...)
+}
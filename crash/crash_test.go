@@ -0,0 +1,74 @@
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecover(t *testing.T) {
+	r := require.New(t)
+
+	cfg := config.Config{FortaDir: t.TempDir()}
+	Init(cfg, "scanner")
+	log.Info("before the panic")
+
+	countBefore := Count()
+	handled := make(chan struct{})
+	Go("test-goroutine", func() {
+		panic("something went wrong")
+	}, func() {
+		close(handled)
+	})
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		r.FailNow("panic was not handled")
+	}
+	r.Equal(countBefore+1, Count())
+
+	names, err := ListReports(Dir(cfg.FortaDir))
+	r.NoError(err)
+	r.Len(names, 1)
+	b, err := ioutil.ReadFile(path.Join(Dir(cfg.FortaDir), names[0]))
+	r.NoError(err)
+	var report Report
+	r.NoError(json.Unmarshal(b, &report))
+	r.Equal("scanner", report.Process)
+	r.Equal("test-goroutine", report.Goroutine)
+	r.Equal("something went wrong", report.Panic)
+	r.Contains(report.Stack, "TestRecover")
+	r.Equal(configFingerprint(cfg), report.ConfigFingerprint)
+	r.Len(report.ConfigFingerprint, 16)
+	r.Contains(fmt.Sprint(report.LogTail), "before the panic")
+}
+
+func TestCapture(t *testing.T) {
+	r := require.New(t)
+
+	Init(config.Config{FortaDir: t.TempDir()}, "runner")
+	r.PanicsWithValue("fatal", func() {
+		defer Capture("main")
+		panic("fatal")
+	})
+}
+
+func TestLogTail(t *testing.T) {
+	r := require.New(t)
+
+	tail := &logTail{}
+	for i := 0; i < logTailSize+5; i++ {
+		r.NoError(tail.Fire(&log.Entry{Logger: log.StandardLogger(), Message: fmt.Sprintf("line-%d", i)}))
+	}
+	lines := tail.Lines()
+	r.Len(lines, logTailSize)
+	r.Contains(lines[0], "line-5")
+	r.Contains(lines[logTailSize-1], fmt.Sprintf("line-%d", logTailSize+4))
+}
@@ -0,0 +1,111 @@
+package healthutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+)
+
+const containerReportPrefix = "forta.container."
+
+// metricFamily is a Prometheus metric family with the samples in the order they were added.
+type metricFamily struct {
+	name    string
+	help    string
+	samples []string
+}
+
+func (mf *metricFamily) add(labels string, value float64) {
+	mf.samples = append(mf.samples, fmt.Sprintf("%s{%s} %s", mf.name, labels, strconv.FormatFloat(value, 'f', -1, 64)))
+}
+
+func (mf *metricFamily) write(w io.Writer) {
+	if len(mf.samples) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", mf.name, mf.help, mf.name)
+	for _, sample := range mf.samples {
+		fmt.Fprintln(w, sample)
+	}
+}
+
+// reportLabels splits the report name into the container, the service and the report:
+// forta.container.<container>.service.<service>.<report> or forta.container.<container>.<report>
+func reportLabels(name string) (container, service, report string) {
+	if !strings.HasPrefix(name, containerReportPrefix) {
+		return "", "", name
+	}
+	parts := strings.SplitN(strings.TrimPrefix(name, containerReportPrefix), ".", 2)
+	container = parts[0]
+	if len(parts) == 1 {
+		return
+	}
+	report = parts[1]
+	if strings.HasPrefix(report, "service.") {
+		parts = strings.SplitN(strings.TrimPrefix(report, "service."), ".", 2)
+		service = parts[0]
+		report = ""
+		if len(parts) == 2 {
+			report = parts[1]
+		}
+	}
+	return
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func isHealthy(status health.Status) bool {
+	return status != health.StatusDown && status != health.StatusFailing && status != health.StatusLagging
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// WriteMetrics writes the health reports in the Prometheus text format. The containers and the reports
+// have the same metrics and the container, service and report labels tell them apart: the status of the
+// reports is a healthy gauge and the numeric and time details are value and timestamp gauges.
+func WriteMetrics(w io.Writer, reports health.Reports) error {
+	containerUp := &metricFamily{name: "forta_container_up", help: "Whether the node container is running."}
+	reportHealthy := &metricFamily{name: "forta_report_healthy", help: "Whether the health report is not down, failing or lagging."}
+	reportValue := &metricFamily{name: "forta_report_value", help: "The numeric value of the health report."}
+	reportTimestamp := &metricFamily{name: "forta_report_timestamp_seconds", help: "The time of the health report as a Unix timestamp."}
+
+	for _, report := range reports {
+		container, service, name := reportLabels(report.Name)
+		if len(name) == 0 && len(service) == 0 {
+			containerUp.add(fmt.Sprintf(`container="%s"`, escapeLabel(container)), boolValue(isHealthy(report.Status)))
+			continue
+		}
+		labels := fmt.Sprintf(
+			`container="%s",service="%s",report="%s"`,
+			escapeLabel(container), escapeLabel(service), escapeLabel(name),
+		)
+		if report.Status != health.StatusInfo {
+			reportHealthy.add(labels, boolValue(isHealthy(report.Status)))
+		}
+		if value, err := strconv.ParseFloat(report.Details, 64); err == nil {
+			reportValue.add(labels, value)
+			continue
+		}
+		if ts, err := time.Parse(time.RFC3339, report.Details); err == nil {
+			reportTimestamp.add(labels, float64(ts.Unix()))
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, family := range []*metricFamily{containerUp, reportHealthy, reportValue, reportTimestamp} {
+		family.write(bw)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,41 @@
+package healthutils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	r := require.New(t)
+
+	reports := health.Reports{
+		{Name: "forta.container.forta-scanner", Status: health.StatusOK, Details: "running"},
+		{Name: "forta.container.forta-supervisor", Status: health.StatusDown, Details: "exited"},
+		{Name: "forta.container.forta-scanner.service.registry.agents.added.count", Status: health.StatusInfo, Details: "3"},
+		{Name: "forta.container.forta-scanner.service.registry.event.checked.time", Status: health.StatusOK, Details: "2022-05-10T20:00:00Z"},
+		{Name: "forta.container.forta-scanner.service.publisher.event.batch-publish.error", Status: health.StatusFailing, Details: "some \"error\""},
+		{Name: "forta.container.forta-scanner.summary", Status: health.StatusOK, Details: "all services are healthy"},
+	}
+
+	var buf bytes.Buffer
+	r.NoError(WriteMetrics(&buf, reports))
+	r.Equal(`# HELP forta_container_up Whether the node container is running.
+# TYPE forta_container_up gauge
+forta_container_up{container="forta-scanner"} 1
+forta_container_up{container="forta-supervisor"} 0
+# HELP forta_report_healthy Whether the health report is not down, failing or lagging.
+# TYPE forta_report_healthy gauge
+forta_report_healthy{container="forta-scanner",service="registry",report="event.checked.time"} 1
+forta_report_healthy{container="forta-scanner",service="publisher",report="event.batch-publish.error"} 0
+forta_report_healthy{container="forta-scanner",service="",report="summary"} 1
+# HELP forta_report_value The numeric value of the health report.
+# TYPE forta_report_value gauge
+forta_report_value{container="forta-scanner",service="registry",report="agents.added.count"} 3
+# HELP forta_report_timestamp_seconds The time of the health report as a Unix timestamp.
+# TYPE forta_report_timestamp_seconds gauge
+forta_report_timestamp_seconds{container="forta-scanner",service="registry",report="event.checked.time"} 1652212800
+`, buf.String())
+}
@@ -0,0 +1,147 @@
+package healthutils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/config"
+)
+
+// Node check names
+const (
+	CheckContainers       = "containers"
+	CheckChainLag         = "chain.lag"
+	CheckRunningAgents    = "agents.running"
+	CheckStoreWritable    = "store.writable"
+	CheckPublisherBacklog = "publisher.backlog"
+)
+
+// NodeChecks composes the checks of the node from the reports of the node containers. The store error is
+// the result of the write check on the Forta dir.
+func NodeChecks(reports health.Reports, storeErr error, cfg config.NodeHealthConfig) health.Reports {
+	return health.Reports{
+		checkContainers(reports),
+		checkChainLag(reports, cfg.MaxChainLag),
+		checkRunningAgents(reports, cfg.MinRunningAgents),
+		checkStoreWritable(storeErr),
+		checkPublisherBacklog(reports, cfg.MaxPublisherBacklog),
+	}
+}
+
+// IsAlive tells if the node is running: the containers are up and the Forta dir is writable.
+func IsAlive(checks health.Reports) bool {
+	for _, check := range checks {
+		if (check.Name == CheckContainers || check.Name == CheckStoreWritable) && check.Status != health.StatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+// IsReady tells if all checks of the node are passing.
+func IsReady(checks health.Reports) bool {
+	for _, check := range checks {
+		if check.Status != health.StatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+func newCheck(name string, ok bool, details string) *health.Report {
+	status := health.StatusOK
+	if !ok {
+		status = health.StatusFailing
+	}
+	return &health.Report{Name: name, Status: status, Details: details}
+}
+
+func checkContainers(reports health.Reports) *health.Report {
+	var down []string
+	var count int
+	for _, report := range reports {
+		if report.Name == "docker" && report.Status == health.StatusDown {
+			return newCheck(CheckContainers, false, fmt.Sprintf("docker is down: %s", report.Details))
+		}
+		container, service, name := reportLabels(report.Name)
+		if len(container) == 0 || len(service) > 0 || len(name) > 0 || report.Status == health.StatusInfo {
+			continue
+		}
+		count++
+		if report.Status == health.StatusDown {
+			down = append(down, container)
+		}
+	}
+	if count == 0 {
+		return newCheck(CheckContainers, false, "no containers found")
+	}
+	if len(down) > 0 {
+		sort.Strings(down)
+		return newCheck(CheckContainers, false, fmt.Sprintf("not running: %s", strings.Join(down, ", ")))
+	}
+	return newCheck(CheckContainers, true, fmt.Sprintf("%d running", count))
+}
+
+// ReportValues finds the numeric values of the reports of the services which have the prefix.
+func ReportValues(reports health.Reports, servicePrefix, reportName string) (values []int64) {
+	for _, report := range reports {
+		_, service, name := reportLabels(report.Name)
+		if name != reportName || !strings.HasPrefix(service, servicePrefix) {
+			continue
+		}
+		value, err := strconv.ParseInt(report.Details, 10, 64)
+		if err != nil {
+			continue
+		}
+		values = append(values, value)
+	}
+	return
+}
+
+func checkChainLag(reports health.Reports, maxLag int64) *health.Report {
+	values := ReportValues(reports, "tx-stream", "chain.lag")
+	if len(values) == 0 {
+		return newCheck(CheckChainLag, false, "no chain lag reported")
+	}
+	var lag int64
+	for _, value := range values {
+		if value > lag {
+			lag = value
+		}
+	}
+	return newCheck(CheckChainLag, lag <= maxLag, fmt.Sprintf("%d blocks (max %d)", lag, maxLag))
+}
+
+func checkRunningAgents(reports health.Reports, minAgents int) *health.Report {
+	values := ReportValues(reports, "agent-pool", "agents.total")
+	if len(values) == 0 {
+		return newCheck(CheckRunningAgents, minAgents == 0, "no agents reported")
+	}
+	var agents int64
+	for _, value := range values {
+		agents += value
+	}
+	return newCheck(CheckRunningAgents, agents >= int64(minAgents), fmt.Sprintf("%d agents (min %d)", agents, minAgents))
+}
+
+func checkStoreWritable(storeErr error) *health.Report {
+	if storeErr != nil {
+		return newCheck(CheckStoreWritable, false, storeErr.Error())
+	}
+	return newCheck(CheckStoreWritable, true, "writable")
+}
+
+func checkPublisherBacklog(reports health.Reports, maxBacklog int) *health.Report {
+	values := ReportValues(reports, "publisher", "backlog.alerts")
+	if len(values) == 0 {
+		return newCheck(CheckPublisherBacklog, false, "no backlog reported")
+	}
+	var backlog int64
+	for _, value := range values {
+		backlog += value
+	}
+	return newCheck(CheckPublisherBacklog, backlog <= int64(maxBacklog), fmt.Sprintf("%d alerts (max %d)", backlog, maxBacklog))
+}
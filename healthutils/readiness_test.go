@@ -0,0 +1,81 @@
+package healthutils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+var testNodeHealthConfig = config.NodeHealthConfig{
+	MaxChainLag:         10,
+	MinRunningAgents:    2,
+	MaxPublisherBacklog: 100,
+}
+
+func testNodeReports(lag, agents, backlog string) health.Reports {
+	return health.Reports{
+		{Name: "forta.container.forta-scanner", Status: health.StatusOK, Details: "running"},
+		{Name: "forta.container.forta-nats", Status: health.StatusOK, Details: "running"},
+		{Name: "forta.container.forta-nats", Status: health.StatusInfo, Details: "no source found"},
+		{Name: "forta.container.forta-scanner.service.tx-stream.chain.lag", Status: health.StatusInfo, Details: lag},
+		{Name: "forta.container.forta-scanner.service.agent-pool.agents.total", Status: health.StatusOK, Details: agents},
+		{Name: "forta.container.forta-scanner.service.publisher.backlog.alerts", Status: health.StatusInfo, Details: backlog},
+		{Name: "forta.container.forta-scanner.service.publisher-137.backlog.alerts", Status: health.StatusInfo, Details: backlog},
+	}
+}
+
+func checkStatuses(checks health.Reports) map[string]health.Status {
+	statuses := make(map[string]health.Status)
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestNodeChecks_Ready(t *testing.T) {
+	r := require.New(t)
+
+	checks := NodeChecks(testNodeReports("10", "2", "50"), nil, testNodeHealthConfig)
+	r.Len(checks, 5)
+	r.True(IsAlive(checks))
+	r.True(IsReady(checks))
+	publisherBacklog, _ := checks.GetByName(CheckPublisherBacklog)
+	r.Equal("100 alerts (max 100)", publisherBacklog.Details)
+}
+
+func TestNodeChecks_NotReady(t *testing.T) {
+	r := require.New(t)
+
+	checks := NodeChecks(testNodeReports("11", "1", "51"), nil, testNodeHealthConfig)
+	r.True(IsAlive(checks))
+	r.False(IsReady(checks))
+	r.Equal(map[string]health.Status{
+		CheckContainers:       health.StatusOK,
+		CheckChainLag:         health.StatusFailing,
+		CheckRunningAgents:    health.StatusFailing,
+		CheckStoreWritable:    health.StatusOK,
+		CheckPublisherBacklog: health.StatusFailing,
+	}, checkStatuses(checks))
+}
+
+func TestNodeChecks_NotAlive(t *testing.T) {
+	r := require.New(t)
+
+	reports := append(testNodeReports("0", "2", "0"), &health.Report{
+		Name: "forta.container.forta-supervisor", Status: health.StatusDown, Details: "exited",
+	})
+	checks := NodeChecks(reports, nil, testNodeHealthConfig)
+	r.False(IsAlive(checks))
+	containers, _ := checks.GetByName(CheckContainers)
+	r.Equal("not running: forta-supervisor", containers.Details)
+
+	checks = NodeChecks(testNodeReports("0", "2", "0"), errors.New("read-only file system"), testNodeHealthConfig)
+	r.False(IsAlive(checks))
+
+	checks = NodeChecks(health.Reports{{Name: "docker", Status: health.StatusDown, Details: "no docker"}}, nil, testNodeHealthConfig)
+	r.False(IsAlive(checks))
+	r.False(IsReady(checks))
+}
@@ -0,0 +1,29 @@
+package logutils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// InitFileOutput writes the logs to the rotating file of the process in the logs dir in addition to the
+// standard error if it is enabled. The returned closer is nil if the file output is disabled.
+func InitFileOutput(cfg config.Config, process string) (io.Closer, error) {
+	fileCfg := cfg.Log.File
+	if !fileCfg.Enable {
+		return nil, nil
+	}
+	filePath := path.Join(cfg.FortaDir, config.DefaultLogsDirName, fmt.Sprintf("%s.log", process))
+	rf, err := NewRotatingFile(
+		filePath, fileCfg.MaxSizeMB, fileCfg.MaxAgeDays, fileCfg.MaxBackups, !fileCfg.DisableCompression,
+	)
+	if err != nil {
+		return nil, err
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, rf))
+	return rf, nil
+}
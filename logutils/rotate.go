@@ -0,0 +1,210 @@
+package logutils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	megabyte         = 1024 * 1024
+	backupTimeFormat = "20060102T150405.000"
+	compressedExt    = ".gz"
+)
+
+// RotatingFile is a log file which is rotated when it reaches the max size. The rotated files are
+// compressed and the oldest ones are removed when there are too many or they are too old.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+
+	file   *os.File
+	size   int64
+	mu     sync.Mutex
+	millMu sync.Mutex // serializes the compression and the cleanup
+}
+
+// NewRotatingFile opens the file to append the logs.
+func NewRotatingFile(filePath string, maxSizeMB, maxAgeDays, maxBackups int, compress bool) (*RotatingFile, error) {
+	return newRotatingFile(
+		filePath, int64(maxSizeMB)*megabyte, time.Duration(maxAgeDays)*24*time.Hour, maxBackups, compress,
+	)
+}
+
+func newRotatingFile(filePath string, maxSize int64, maxAge time.Duration, maxBackups int, compress bool) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		path:       filePath,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		compress:   compress,
+	}
+	if err := os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the log dir: %v", err)
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open the log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat the log file: %v", err)
+	}
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+// Write implements the io.Writer interface.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current file to a backup file and opens a new file.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return fmt.Errorf("failed to close the log file: %v", err)
+	}
+	backupPath := rf.backupPath(time.Now().UTC())
+	if err := os.Rename(rf.path, backupPath); err != nil {
+		return fmt.Errorf("failed to rotate the log file: %v", err)
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	go rf.mill(backupPath)
+	return nil
+}
+
+// backupPath returns the path like <dir>/<name>-<time>.log for <dir>/<name>.log so that the backups
+// sort by time.
+func (rf *RotatingFile) backupPath(t time.Time) string {
+	ext := path.Ext(rf.path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(rf.path, ext), t.Format(backupTimeFormat), ext)
+}
+
+// mill compresses the rotated file and removes the old backups.
+func (rf *RotatingFile) mill(backupPath string) {
+	rf.millMu.Lock()
+	defer rf.millMu.Unlock()
+
+	if rf.compress {
+		if err := compressFile(backupPath); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to compress the log file %s: %v\n", backupPath, err)
+		}
+	}
+	if err := rf.removeOldBackups(time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to remove the old log files: %v\n", err)
+	}
+}
+
+func compressFile(filePath string) error {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(filePath+compressedExt, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(filePath)
+}
+
+// backups returns the backup file names from the oldest to the newest.
+func (rf *RotatingFile) backups() ([]os.FileInfo, error) {
+	files, err := ioutil.ReadDir(path.Dir(rf.path))
+	if err != nil {
+		return nil, err
+	}
+	ext := path.Ext(rf.path)
+	prefix := strings.TrimSuffix(path.Base(rf.path), ext) + "-"
+	var backups []os.FileInfo
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.HasSuffix(name, ext) || strings.HasSuffix(name, ext+compressedExt) {
+			backups = append(backups, file)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Name() < backups[j].Name()
+	})
+	return backups, nil
+}
+
+func (rf *RotatingFile) removeOldBackups(now time.Time) error {
+	backups, err := rf.backups()
+	if err != nil {
+		return err
+	}
+	for i, backup := range backups {
+		tooMany := rf.maxBackups > 0 && len(backups)-i > rf.maxBackups
+		tooOld := rf.maxAge > 0 && now.Sub(backup.ModTime()) > rf.maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(path.Join(path.Dir(rf.path), backup.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
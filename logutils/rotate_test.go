@@ -0,0 +1,77 @@
+package logutils
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	filePath := path.Join(dir, "scanner.log")
+	rf, err := newRotatingFile(filePath, 10, 0, 2, true)
+	r.NoError(err)
+	defer rf.Close()
+
+	_, err = rf.Write([]byte("line-1\n"))
+	r.NoError(err)
+	// exceeds the max size and rotates the file
+	_, err = rf.Write([]byte("line-2\n"))
+	r.NoError(err)
+
+	b, err := ioutil.ReadFile(filePath)
+	r.NoError(err)
+	r.Equal("line-2\n", string(b))
+
+	var backups []os.FileInfo
+	r.Eventually(func() bool {
+		rf.millMu.Lock()
+		defer rf.millMu.Unlock()
+		backups, err = rf.backups()
+		return err == nil && len(backups) == 1 && strings.HasSuffix(backups[0].Name(), ".log.gz")
+	}, time.Second, time.Millisecond*10)
+
+	f, err := os.Open(path.Join(dir, backups[0].Name()))
+	r.NoError(err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	r.NoError(err)
+	b, err = ioutil.ReadAll(gz)
+	r.NoError(err)
+	r.Equal("line-1\n", string(b))
+}
+
+func TestRotatingFile_RemoveOldBackups(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	rf, err := newRotatingFile(path.Join(dir, "scanner.log"), 10, time.Hour, 2, false)
+	r.NoError(err)
+	defer rf.Close()
+
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		backupPath := rf.backupPath(now.Add(time.Duration(i) * time.Second))
+		r.NoError(ioutil.WriteFile(backupPath, []byte("logs"), 0644))
+	}
+	oldPath := rf.backupPath(now.Add(-time.Second))
+	r.NoError(ioutil.WriteFile(oldPath, []byte("logs"), 0644))
+	r.NoError(os.Chtimes(oldPath, now.Add(-time.Hour*2), now.Add(-time.Hour*2)))
+	r.NoError(ioutil.WriteFile(path.Join(dir, "runner-20220101T000000.000.log"), []byte("logs"), 0644))
+
+	r.NoError(rf.removeOldBackups(now))
+	backups, err := rf.backups()
+	r.NoError(err)
+	r.Len(backups, 2)
+	r.Equal(path.Base(rf.backupPath(now.Add(time.Second*2))), backups[0].Name())
+	r.Equal(path.Base(rf.backupPath(now.Add(time.Second*3))), backups[1].Name())
+	r.FileExists(path.Join(dir, "runner-20220101T000000.000.log"))
+}
@@ -17,6 +17,12 @@ const (
 	MetricTxError          = "tx.error"
 	MetricTxSuccess        = "tx.success"
 	MetricTxDrop           = "tx.drop"
+	MetricTxTimeout        = "tx.timeout"
+	MetricPendingTxRequest = "pending-tx.request"
+	MetricPendingTxLatency = "pending-tx.latency"
+	MetricPendingTxError   = "pending-tx.error"
+	MetricPendingTxSuccess = "pending-tx.success"
+	MetricPendingTxDrop    = "pending-tx.drop"
 	MetricTxBlockAge       = "tx.block.age"
 	MetricTxEventAge       = "tx.event.age"
 	MetricBlockBlockAge    = "block.block.age"
@@ -26,12 +32,23 @@ const (
 	MetricBlockError       = "block.error"
 	MetricBlockSuccess     = "block.success"
 	MetricBlockDrop        = "block.drop"
+	MetricBlockTimeout     = "block.timeout"
 	MetricStop             = "agent.stop"
 	MetricJSONRPCLatency   = "jsonrpc.latency"
 	MetricJSONRPCRequest   = "jsonrpc.request"
 	MetricJSONRPCSuccess   = "jsonrpc.success"
 	MetricJSONRPCThrottled = "jsonrpc.throttled"
+	MetricJSONRPCCacheHit  = "jsonrpc.cache.hit"
+	MetricJSONRPCQuota     = "jsonrpc.quota.exceeded"
+	MetricJSONRPCViolation = "jsonrpc.violation"
 	MetricFindingsDropped  = "findings.dropped"
+	MetricDiskUsage        = "agent.disk.usage"
+	MetricCPUUsage         = "agent.cpu.usage"
+	MetricMemoryUsage      = "agent.memory.usage"
+	MetricNetworkRx        = "agent.network.rx"
+	MetricNetworkTx        = "agent.network.tx"
+	MetricRestarts         = "agent.restarts"
+	MetricCanaryMiss       = "agent.canary.miss"
 )
 
 func SendAgentMetrics(client clients.MessageClient, ms []*protocol.AgentMetric) {
@@ -104,6 +121,22 @@ func GetTxMetrics(agt config.AgentConfig, resp *protocol.EvaluateTxResponse, tim
 	return createMetrics(agt.ID, resp.Timestamp, metrics)
 }
 
+func GetPendingTxMetrics(agt config.AgentConfig, resp *protocol.EvaluateTxResponse) []*protocol.AgentMetric {
+	metrics := make(map[string]float64)
+
+	metrics[MetricPendingTxRequest] = 1
+	metrics[MetricFinding] = float64(len(resp.Findings))
+	metrics[MetricPendingTxLatency] = float64(resp.LatencyMs)
+
+	if resp.Status == protocol.ResponseStatus_ERROR {
+		metrics[MetricPendingTxError] = 1
+	} else if resp.Status == protocol.ResponseStatus_SUCCESS {
+		metrics[MetricPendingTxSuccess] = 1
+	}
+
+	return createMetrics(agt.ID, resp.Timestamp, metrics)
+}
+
 func GetJSONRPCMetrics(agt config.AgentConfig, at time.Time, success, throttled int, latencyMs time.Duration) []*protocol.AgentMetric {
 	values := make(map[string]float64)
 	if latencyMs > 0 {
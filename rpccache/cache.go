@@ -0,0 +1,336 @@
+package rpccache
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+)
+
+// cacheableMethods are the methods which return the data of a specific block or transaction.
+var cacheableMethods = map[string]bool{
+	"eth_getBlockByHash":        true,
+	"eth_getBlockByNumber":      true,
+	"eth_getTransactionReceipt": true,
+	"eth_getBlockReceipts":      true,
+	"eth_getLogs":               true,
+	"trace_block":               true,
+}
+
+// CacheHitHeader is set on the responses which are served from the cache.
+const CacheHitHeader = "X-Forta-Cache"
+
+// blockTags are the block parameters which don't point to a specific block.
+var blockTags = map[string]bool{
+	"latest":    true,
+	"pending":   true,
+	"earliest":  true,
+	"safe":      true,
+	"finalized": true,
+}
+
+// Cache keeps the responses of the JSON-RPC requests for the blocks, receipts, logs and traces, so that
+// the same data is not fetched from the providers again by the scanner and the agents. The entries expire
+// after the TTL, so that a reorged block is not kept for long.
+type Cache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	entries map[string]*list.Element
+	order   *list.List
+	hits    int64
+	misses  int64
+	mu      sync.Mutex
+}
+
+type rpcCacheEntry struct {
+	key     string
+	result  json.RawMessage
+	expires time.Time
+}
+
+type rpcCacheRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcCacheResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+type rpcMethodPayload struct {
+	Method string `json:"method"`
+}
+
+// RequestMethods returns the methods of a single or a batch JSON-RPC request.
+func RequestMethods(body []byte) []string {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.HasPrefix(trimmed, "[") {
+		var batch []rpcMethodPayload
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return []string{"unknown"}
+		}
+		methods := make([]string, 0, len(batch))
+		for _, req := range batch {
+			methods = append(methods, req.Method)
+		}
+		return methods
+	}
+	var req rpcMethodPayload
+	if err := json.Unmarshal(body, &req); err != nil || len(req.Method) == 0 {
+		return []string{"unknown"}
+	}
+	return []string{req.Method}
+}
+
+// CacheableRequest tells if all methods of the request or the batch are the cacheable methods.
+func CacheableRequest(body []byte) bool {
+	methods := RequestMethods(body)
+	if len(methods) == 0 {
+		return false
+	}
+	for _, method := range methods {
+		if !cacheableMethods[method] {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheKey returns the key of a request if its response can be cached.
+func cacheKey(req *rpcCacheRequest) (string, bool) {
+	if !cacheableMethods[req.Method] {
+		return "", false
+	}
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return "", false
+	}
+	switch req.Method {
+	case "eth_getBlockByNumber", "eth_getBlockReceipts", "trace_block":
+		var blockNum string
+		if err := json.Unmarshal(params[0], &blockNum); err != nil || blockTags[blockNum] {
+			return "", false
+		}
+	case "eth_getLogs":
+		var filter struct {
+			FromBlock string `json:"fromBlock"`
+			ToBlock   string `json:"toBlock"`
+			BlockHash string `json:"blockHash"`
+		}
+		if err := json.Unmarshal(params[0], &filter); err != nil {
+			return "", false
+		}
+		if len(filter.BlockHash) == 0 && (len(filter.FromBlock) == 0 || len(filter.ToBlock) == 0 ||
+			blockTags[filter.FromBlock] || blockTags[filter.ToBlock]) {
+			return "", false
+		}
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, req.Params); err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s:%s", req.Method, strings.ToLower(compact.String())), true
+}
+
+// Get returns the cached result.
+func (c *Cache) Get(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if ok && c.now().After(elem.Value.(*rpcCacheEntry).expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*rpcCacheEntry).result, true
+}
+
+// Put adds the result to the cache and removes the least recently used entry if the cache is full.
+func (c *Cache) Put(key string, result json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &rpcCacheEntry{key: key, result: result, expires: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*rpcCacheEntry).key)
+	}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Handler serves the cached responses and caches the responses of the next handler. The batch requests
+// are always forwarded but their responses are cached, so that the agents can reuse the receipts which
+// the scanner has fetched in batches.
+func (c *Cache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		if strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+			c.serveBatch(w, req, body, next)
+			return
+		}
+
+		var rpcReq rpcCacheRequest
+		if err := json.Unmarshal(body, &rpcReq); err != nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+		key, ok := cacheKey(&rpcReq)
+		if !ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		if result, ok := c.Get(key); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(CacheHitHeader, "hit")
+			json.NewEncoder(w).Encode(&rpcCacheResponse{JSONRPC: "2.0", ID: rpcReq.ID, Result: result})
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, req)
+		if rec.status != http.StatusOK {
+			return
+		}
+		var rpcResp rpcCacheResponse
+		if err := json.Unmarshal(rec.body.Bytes(), &rpcResp); err != nil {
+			return
+		}
+		c.putResponse(key, &rpcResp)
+	})
+}
+
+// RestrictedHandler is the cache handler which serves only the cacheable methods. The other requests
+// are rejected since the agent requests do not pass through the proxy restrictions here.
+func (c *Cache) RestrictedHandler(next http.Handler) http.Handler {
+	handler := c.Handler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !CacheableRequest(body) {
+			http.Error(w, "method is not allowed", http.StatusForbidden)
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func (c *Cache) serveBatch(w http.ResponseWriter, req *http.Request, body []byte, next http.Handler) {
+	var batch []*rpcCacheRequest
+	if err := json.Unmarshal(body, &batch); err != nil {
+		next.ServeHTTP(w, req)
+		return
+	}
+	keys := make(map[string]string)
+	for _, rpcReq := range batch {
+		if key, ok := cacheKey(rpcReq); ok {
+			keys[string(rpcReq.ID)] = key
+		}
+	}
+	if len(keys) == 0 {
+		next.ServeHTTP(w, req)
+		return
+	}
+
+	rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, req)
+	if rec.status != http.StatusOK {
+		return
+	}
+	var responses []*rpcCacheResponse
+	if err := json.Unmarshal(rec.body.Bytes(), &responses); err != nil {
+		return
+	}
+	for _, rpcResp := range responses {
+		if key, ok := keys[string(rpcResp.ID)]; ok {
+			c.putResponse(key, rpcResp)
+		}
+	}
+}
+
+func (c *Cache) putResponse(key string, rpcResp *rpcCacheResponse) {
+	if len(rpcResp.Error) > 0 || len(rpcResp.Result) == 0 || string(rpcResp.Result) == "null" {
+		return
+	}
+	c.Put(key, rpcResp.Result)
+}
+
+// Name returns the name of the cache.
+func (c *Cache) Name() string {
+	return "rpc-cache"
+}
+
+// Health implements the health.Reporter interface.
+func (c *Cache) Health() health.Reports {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return health.Reports{
+		&health.Report{Name: "cache.entries", Status: health.StatusInfo, Details: fmt.Sprintf("%d", c.order.Len())},
+		&health.Report{Name: "cache.hits", Status: health.StatusInfo, Details: fmt.Sprintf("%d", c.hits)},
+		&health.Report{Name: "cache.misses", Status: health.StatusInfo, Details: fmt.Sprintf("%d", c.misses)},
+	}
+}
+
+// NewCache creates a new cache which keeps up to size responses for the TTL.
+func NewCache(size int, ttl time.Duration) *Cache {
+	if size < 1 {
+		size = 1
+	}
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
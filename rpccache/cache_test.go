@@ -0,0 +1,123 @@
+package rpccache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestMethods(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal([]string{"eth_blockNumber"}, RequestMethods([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)))
+	r.Equal(
+		[]string{"eth_getTransactionReceipt", "eth_getTransactionReceipt"},
+		RequestMethods([]byte(` [{"method":"eth_getTransactionReceipt"},{"method":"eth_getTransactionReceipt"}]`)),
+	)
+	r.Equal([]string{"unknown"}, RequestMethods([]byte(`not json`)))
+}
+
+func TestCache_Handler(t *testing.T) {
+	r := require.New(t)
+
+	var upstreamCalls int32
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&upstreamCalls, 1)
+		body, _ := ioutil.ReadAll(req.Body)
+		switch {
+		case strings.HasPrefix(string(body), "["):
+			fmt.Fprint(w, `[{"jsonrpc":"2.0","id":1,"result":{"status":"0x1"}},{"jsonrpc":"2.0","id":2,"result":null}]`)
+		case strings.Contains(string(body), "latest"):
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"number":"0x2"}}`)
+		default:
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"number":"0x1"}}`)
+		}
+	})
+	cache := NewCache(10, time.Minute)
+	server := httptest.NewServer(cache.Handler(upstream))
+	defer server.Close()
+
+	post := func(body string) string {
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+		r.NoError(err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		r.NoError(err)
+		return strings.TrimSpace(string(b))
+	}
+
+	req := `{"jsonrpc":"2.0","id":%d,"method":"eth_getBlockByNumber","params":["0x1",true]}`
+	post(fmt.Sprintf(req, 1))
+	r.Equal(`{"jsonrpc":"2.0","id":5,"result":{"number":"0x1"}}`, post(fmt.Sprintf(req, 5)))
+	r.Equal(int32(1), atomic.LoadInt32(&upstreamCalls))
+
+	// the latest block is not cached
+	latest := `{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["latest",true]}`
+	post(latest)
+	post(latest)
+	r.Equal(int32(3), atomic.LoadInt32(&upstreamCalls))
+
+	// the receipts from a batch are cached but the null results are not
+	post(`[{"jsonrpc":"2.0","id":1,"method":"eth_getTransactionReceipt","params":["0xAA"]},` +
+		`{"jsonrpc":"2.0","id":2,"method":"eth_getTransactionReceipt","params":["0xbb"]}]`)
+	r.Equal(int32(4), atomic.LoadInt32(&upstreamCalls))
+	_, ok := cache.Get(`eth_gettransactionreceipt:["0xaa"]`)
+	r.False(ok)
+	result, ok := cache.Get(`eth_getTransactionReceipt:["0xaa"]`)
+	r.True(ok)
+	r.Equal(`{"status":"0x1"}`, string(result))
+	_, ok = cache.Get(`eth_getTransactionReceipt:["0xbb"]`)
+	r.False(ok)
+}
+
+func TestCache_RestrictedHandler(t *testing.T) {
+	r := require.New(t)
+
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+	})
+	handler := NewCache(10, time.Minute).RestrictedHandler(upstream)
+
+	for body, status := range map[string]int{
+		`{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["0x1",true]}`:                             http.StatusOK,
+		`[{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[{"blockHash":"0x1"}]}]`:                           http.StatusOK,
+		`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x1"]}`:                                http.StatusForbidden,
+		`[{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[]},{"jsonrpc":"2.0","id":2,"method":"eth_call"}]`: http.StatusForbidden,
+		`not json`: http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		r.Equal(status, w.Code, body)
+	}
+}
+
+func TestCache_Eviction(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	cache := NewCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Put("a", []byte("1"))
+	cache.Put("b", []byte("2"))
+	_, ok := cache.Get("a")
+	r.True(ok)
+
+	// b is the least recently used
+	cache.Put("c", []byte("3"))
+	_, ok = cache.Get("b")
+	r.False(ok)
+	_, ok = cache.Get("a")
+	r.True(ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.Get("c")
+	r.False(ok)
+}
@@ -0,0 +1,242 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/services/scanner"
+	"github.com/forta-network/forta-node/services/scanner/agentpool"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultAttachTimeout is how long the benchmark waits for the agents to accept the connections.
+	DefaultAttachTimeout = time.Minute * 2
+
+	// the results of the last requests are waited until the agents are idle for a while
+	settleIdleDuration = time.Second * 2
+	settleMaxDuration  = time.Second * 30
+)
+
+// Options are the benchmark options.
+type Options struct {
+	// TxRate and BlockRate are the requests per second which are sent to every agent.
+	TxRate        float64
+	BlockRate     float64
+	Duration      time.Duration
+	AttachTimeout time.Duration
+}
+
+// Run attaches the agents to an agent pool like the scanner does, replays the fixtures through the pool
+// at the configured rates and reports how the agents kept up with them. The agents should already be
+// serving at their ports.
+func Run(ctx context.Context, cfg config.Config, agents []config.AgentConfig, fixtures *Fixtures, opts Options) (*Report, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if opts.AttachTimeout == 0 {
+		opts.AttachTimeout = DefaultAttachTimeout
+	}
+	// the agents are dialed on this host and the bench is the only subscriber of the actions
+	cfg.DevProcess = true
+	cfg.Messaging.Ack.Enable = false
+
+	bus := messaging.NewLocalBus()
+	msgClient := bus.NewClient("bench")
+	stats := newCollector()
+	msgClient.Subscribe(messaging.SubjectMetricAgent, messaging.AgentMetricHandler(stats.addMetrics))
+	runActions := make(chan struct{}, 1)
+	msgClient.Subscribe(messaging.SubjectAgentsActionRun, messaging.AgentsHandler(func(payload messaging.AgentPayload) error {
+		select {
+		case runActions <- struct{}{}:
+		default:
+		}
+		return nil
+	}))
+	attachedAgents := make(chan messaging.AgentPayload, len(agents))
+	msgClient.Subscribe(messaging.SubjectAgentsStatusAttached, messaging.AgentsHandler(func(payload messaging.AgentPayload) error {
+		attachedAgents <- payload
+		return nil
+	}))
+
+	pool := agentpool.NewAgentPool(ctx, cfg, bus.NewClient("agent-pool"))
+	if err := attach(ctx, msgClient, agents, runActions, attachedAgents, opts.AttachTimeout); err != nil {
+		return nil, err
+	}
+
+	results := newResultsDrainer(stats)
+	go results.drainTxs(ctx, pool.TxResults())
+	go results.drainBlocks(ctx, pool.BlockResults())
+
+	log.WithFields(log.Fields{
+		"txRate":    opts.TxRate,
+		"blockRate": opts.BlockRate,
+		"duration":  opts.Duration,
+	}).Info("replaying the fixtures")
+	startTime := time.Now()
+	txSent, blocksSent := replay(ctx, pool, fixtures, opts, hexutil.EncodeUint64(uint64(cfg.ChainID)))
+	results.settle(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	duration := opts.Duration
+	if lastResult := results.lastResult(); lastResult.Sub(startTime) > duration {
+		duration = lastResult.Sub(startTime)
+	}
+	return stats.report(duration, txSent, blocksSent), nil
+}
+
+// attach starts the agents in the pool by publishing the agent lifecycle messages which the supervisor
+// would publish and waits until the pool connects to all of them.
+func attach(
+	ctx context.Context, msgClient *messaging.LocalClient, agents []config.AgentConfig,
+	runActions <-chan struct{}, attachedAgents <-chan messaging.AgentPayload, timeout time.Duration,
+) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	msgClient.Publish(messaging.SubjectAgentsVersionsLatest, messaging.AgentPayload(agents))
+	select {
+	case <-runActions:
+	case <-timer.C:
+		return fmt.Errorf("timed out waiting for the agent pool to run the agents")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	msgClient.Publish(messaging.SubjectAgentsStatusRunning, messaging.AgentPayload(agents))
+
+	waiting := make(map[string]bool)
+	for _, agent := range agents {
+		waiting[agent.ID] = true
+	}
+	for len(waiting) > 0 {
+		select {
+		case attached := <-attachedAgents:
+			for _, agent := range attached {
+				delete(waiting, agent.ID)
+				log.WithField("agent", agent.ID).Info("attached the agent")
+			}
+		case <-timer.C:
+			var ids []string
+			for id := range waiting {
+				ids = append(ids, id)
+			}
+			return fmt.Errorf("timed out attaching the agents: %s", strings.Join(ids, ", "))
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// replay sends the fixtures to the pool at the rates until the duration ends. The fixtures are sent in
+// a loop with new request IDs and with the chain ID of the node.
+func replay(ctx context.Context, pool *agentpool.AgentPool, fixtures *Fixtures, opts Options, chainID string) (txSent, blocksSent int) {
+	txTicks, stopTx := rateTicker(opts.TxRate, len(fixtures.Txs))
+	defer stopTx()
+	blockTicks, stopBlocks := rateTicker(opts.BlockRate, len(fixtures.Blocks))
+	defer stopBlocks()
+	timer := time.NewTimer(opts.Duration)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case <-txTicks:
+			req := proto.Clone(fixtures.Txs[txSent%len(fixtures.Txs)]).(*protocol.EvaluateTxRequest)
+			req.RequestId = uuid.Must(uuid.NewUUID()).String()
+			req.Event.Network = &protocol.TransactionEvent_Network{ChainId: chainID}
+			pool.SendEvaluateTxRequest(req)
+			txSent++
+		case <-blockTicks:
+			req := proto.Clone(fixtures.Blocks[blocksSent%len(fixtures.Blocks)]).(*protocol.EvaluateBlockRequest)
+			req.RequestId = uuid.Must(uuid.NewUUID()).String()
+			req.Event.Network = &protocol.BlockEvent_Network{ChainId: chainID}
+			pool.SendEvaluateBlockRequest(req)
+			blocksSent++
+		}
+	}
+}
+
+// rateTicker ticks at the rate per second. It never ticks if the rate is zero or there are no fixtures.
+func rateTicker(rate float64, fixtureCount int) (<-chan time.Time, func()) {
+	if rate <= 0 || fixtureCount == 0 {
+		return nil, func() {}
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	return ticker.C, ticker.Stop
+}
+
+// resultsDrainer receives the results from the pool so that the agents are never blocked.
+type resultsDrainer struct {
+	stats *collector
+	last  time.Time
+	mu    sync.Mutex
+}
+
+func newResultsDrainer(stats *collector) *resultsDrainer {
+	return &resultsDrainer{stats: stats, last: time.Now()}
+}
+
+func (rd *resultsDrainer) received() {
+	rd.mu.Lock()
+	rd.last = time.Now()
+	rd.mu.Unlock()
+}
+
+func (rd *resultsDrainer) lastResult() time.Time {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	return rd.last
+}
+
+func (rd *resultsDrainer) drainTxs(ctx context.Context, txResults <-chan *scanner.TxResult) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case result := <-txResults:
+			rd.stats.addResponse(result.AgentConfig.ID, false, result.Response.Status, result.Response.LatencyMs)
+			rd.received()
+		}
+	}
+}
+
+func (rd *resultsDrainer) drainBlocks(ctx context.Context, blockResults <-chan *scanner.BlockResult) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case result := <-blockResults:
+			rd.stats.addResponse(result.AgentConfig.ID, true, result.Response.Status, result.Response.LatencyMs)
+			rd.received()
+		}
+	}
+}
+
+// settle waits until the agents respond to the last requests.
+func (rd *resultsDrainer) settle(ctx context.Context) {
+	deadline := time.Now().Add(settleMaxDuration)
+	ticker := time.NewTicker(settleIdleDuration / 4)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) && time.Since(rd.lastResult()) < settleIdleDuration {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/metrics"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type benchAgentServer struct {
+	protocol.UnimplementedAgentServer
+}
+
+func (s *benchAgentServer) Initialize(context.Context, *protocol.InitializeRequest) (*protocol.InitializeResponse, error) {
+	return &protocol.InitializeResponse{Status: protocol.ResponseStatus_SUCCESS}, nil
+}
+
+func (s *benchAgentServer) EvaluateTx(context.Context, *protocol.EvaluateTxRequest) (*protocol.EvaluateTxResponse, error) {
+	return &protocol.EvaluateTxResponse{Status: protocol.ResponseStatus_SUCCESS}, nil
+}
+
+func (s *benchAgentServer) EvaluateBlock(context.Context, *protocol.EvaluateBlockRequest) (*protocol.EvaluateBlockResponse, error) {
+	return &protocol.EvaluateBlockResponse{Status: protocol.ResponseStatus_ERROR}, nil
+}
+
+func TestSyntheticFixtures(t *testing.T) {
+	r := require.New(t)
+
+	fixtures, err := SyntheticFixtures()
+	r.NoError(err)
+	r.NotEmpty(fixtures.Blocks)
+	r.NotEmpty(fixtures.Txs)
+	for _, tx := range fixtures.Txs {
+		r.NotEmpty(tx.Event.Transaction.Hash)
+	}
+
+	_, err = parseFixtures([]byte(`{"blocks":[],"txs":[]}`))
+	r.Error(err)
+	_, err = parseFixtures([]byte(`{"txs":[{"event":{"network":{"chainId":"0x1"}}}]}`))
+	r.Error(err)
+}
+
+func TestReport(t *testing.T) {
+	r := require.New(t)
+
+	stats := newCollector()
+	for i := 100; i > 0; i-- {
+		stats.addResponse("0x1", false, protocol.ResponseStatus_SUCCESS, uint32(i))
+	}
+	stats.addResponse("0x1", true, protocol.ResponseStatus_ERROR, 1000)
+	r.NoError(stats.addMetrics(&protocol.AgentMetricList{Metrics: []*protocol.AgentMetric{
+		metrics.CreateAgentMetric("0x1", metrics.MetricTxDrop, 2),
+		metrics.CreateAgentMetric("0x1", metrics.MetricBlockTimeout, 1),
+		metrics.CreateAgentMetric("0x2", metrics.MetricTxDrop, 1),
+		metrics.CreateAgentMetric("0x2", metrics.MetricTxLatency, 10),
+	}}))
+
+	report := stats.report(time.Second*10, 102, 1)
+	r.Len(report.Agents, 2)
+	agent := report.Agents[0]
+	r.Equal("0x1", agent.AgentID)
+	r.Equal(100, agent.TxResponses)
+	r.Equal(1, agent.BlockResponses)
+	r.Equal(1, agent.Errors)
+	r.Equal(2, agent.Drops)
+	r.Equal(1, agent.Timeouts)
+	r.Equal(10.1, agent.Throughput)
+	r.Equal(uint32(51), agent.LatencyP50Ms)
+	r.Equal(uint32(91), agent.LatencyP90Ms)
+	r.Equal(uint32(100), agent.LatencyP99Ms)
+	r.Equal(uint32(1000), agent.LatencyMaxMs)
+	r.Equal(1, report.Agents[1].Drops)
+
+	var b bytes.Buffer
+	report.Print(&b)
+	r.Equal(5, strings.Count(b.String(), "\n"))
+	r.Contains(b.String(), "0x1")
+}
+
+func TestRun(t *testing.T) {
+	r := require.New(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	server := grpc.NewServer()
+	protocol.RegisterAgentServer(server, &benchAgentServer{})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	fixtures, err := SyntheticFixtures()
+	r.NoError(err)
+	_, port, err := net.SplitHostPort(lis.Addr().String())
+	r.NoError(err)
+	agents := []config.AgentConfig{{ID: "0xbench", Command: []string{"bench-agent"}, Port: port, IsLocal: true}}
+
+	report, err := Run(context.Background(), config.Config{ChainID: 137}, agents, fixtures, Options{
+		TxRate:    100,
+		BlockRate: 20,
+		Duration:  time.Millisecond * 500,
+	})
+	r.NoError(err)
+	r.Len(report.Agents, 1)
+	agent := report.Agents[0]
+	r.Equal("0xbench", agent.AgentID)
+	r.Greater(report.TxSent, 0)
+	r.Greater(report.BlocksSent, 0)
+	r.Equal(report.TxSent, agent.TxResponses+agent.Drops)
+	r.Equal(agent.BlockResponses, agent.Errors)
+}
@@ -0,0 +1,79 @@
+package bench
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/golang/protobuf/jsonpb"
+)
+
+//go:embed synthetic_fixtures.json
+var syntheticFixtures []byte
+
+// Fixtures are the block and the tx requests which are replayed through the agents. The fixtures file
+// has the requests in the protobuf JSON format:
+//
+//	{
+//	  "blocks": [{"event": {"blockNumber": "0xd59f80", ...}}],
+//	  "txs": [{"event": {"transaction": {"hash": "0x2f1c...", ...}, ...}}]
+//	}
+type Fixtures struct {
+	Blocks []*protocol.EvaluateBlockRequest
+	Txs    []*protocol.EvaluateTxRequest
+}
+
+type fixturesFile struct {
+	Blocks []json.RawMessage `json:"blocks"`
+	Txs    []json.RawMessage `json:"txs"`
+}
+
+// SyntheticFixtures returns the built-in fixtures. They are made up requests which look like the mainnet
+// blocks and txs, not the recorded chain data.
+func SyntheticFixtures() (*Fixtures, error) {
+	return parseFixtures(syntheticFixtures)
+}
+
+// LoadFixtures reads the fixtures from the file.
+func LoadFixtures(filePath string) (*Fixtures, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseFixtures(b)
+}
+
+func parseFixtures(b []byte) (*Fixtures, error) {
+	var file fixturesFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse the fixtures: %v", err)
+	}
+	var fixtures Fixtures
+	for i, raw := range file.Blocks {
+		var req protocol.EvaluateBlockRequest
+		if err := jsonpb.Unmarshal(bytes.NewReader(raw), &req); err != nil {
+			return nil, fmt.Errorf("blocks[%d]: %v", i, err)
+		}
+		if req.Event == nil {
+			return nil, fmt.Errorf("blocks[%d]: event is required", i)
+		}
+		fixtures.Blocks = append(fixtures.Blocks, &req)
+	}
+	for i, raw := range file.Txs {
+		var req protocol.EvaluateTxRequest
+		if err := jsonpb.Unmarshal(bytes.NewReader(raw), &req); err != nil {
+			return nil, fmt.Errorf("txs[%d]: %v", i, err)
+		}
+		if req.Event == nil || req.Event.Transaction == nil || req.Event.Block == nil {
+			return nil, fmt.Errorf("txs[%d]: event with the transaction and the block is required", i)
+		}
+		fixtures.Txs = append(fixtures.Txs, &req)
+	}
+	if len(fixtures.Blocks) == 0 && len(fixtures.Txs) == 0 {
+		return nil, fmt.Errorf("no block or tx fixtures")
+	}
+	return &fixtures, nil
+}
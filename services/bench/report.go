@@ -0,0 +1,158 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/metrics"
+)
+
+// AgentReport is the benchmark result of an agent.
+type AgentReport struct {
+	AgentID        string  `json:"agentId"`
+	TxResponses    int     `json:"txResponses"`
+	BlockResponses int     `json:"blockResponses"`
+	Errors         int     `json:"errors"`
+	Drops          int     `json:"drops"`
+	Timeouts       int     `json:"timeouts"`
+	Throughput     float64 `json:"throughput"`
+	LatencyP50Ms   uint32  `json:"latencyP50Ms"`
+	LatencyP90Ms   uint32  `json:"latencyP90Ms"`
+	LatencyP99Ms   uint32  `json:"latencyP99Ms"`
+	LatencyMaxMs   uint32  `json:"latencyMaxMs"`
+}
+
+// Report is the result of a benchmark.
+type Report struct {
+	Duration   time.Duration  `json:"duration"`
+	TxSent     int            `json:"txSent"`
+	BlocksSent int            `json:"blocksSent"`
+	Agents     []*AgentReport `json:"agents"`
+}
+
+// Print writes the report as a table.
+func (report *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "duration: %s, sent txs: %d, sent blocks: %d\n\n", report.Duration.Round(time.Millisecond), report.TxSent, report.BlocksSent)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tTX\tBLOCK\tREQ/S\tP50 MS\tP90 MS\tP99 MS\tMAX MS\tDROPS\tTIMEOUTS\tERRORS")
+	for _, agent := range report.Agents {
+		fmt.Fprintf(
+			tw, "%s\t%d\t%d\t%.1f\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			agent.AgentID, agent.TxResponses, agent.BlockResponses, agent.Throughput,
+			agent.LatencyP50Ms, agent.LatencyP90Ms, agent.LatencyP99Ms, agent.LatencyMaxMs,
+			agent.Drops, agent.Timeouts, agent.Errors,
+		)
+	}
+	tw.Flush()
+}
+
+type agentStats struct {
+	txResponses    int
+	blockResponses int
+	errors         int
+	drops          int
+	timeouts       int
+	latencies      []uint32
+}
+
+// collector aggregates the responses and the metrics of the agents.
+type collector struct {
+	agents map[string]*agentStats
+	mu     sync.Mutex
+}
+
+func newCollector() *collector {
+	return &collector{agents: make(map[string]*agentStats)}
+}
+
+func (c *collector) agentUnsafe(agentID string) *agentStats {
+	stats, ok := c.agents[agentID]
+	if !ok {
+		stats = &agentStats{}
+		c.agents[agentID] = stats
+	}
+	return stats
+}
+
+func (c *collector) addResponse(agentID string, isBlock bool, status protocol.ResponseStatus, latencyMs uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.agentUnsafe(agentID)
+	if isBlock {
+		stats.blockResponses++
+	} else {
+		stats.txResponses++
+	}
+	if status == protocol.ResponseStatus_ERROR {
+		stats.errors++
+	}
+	stats.latencies = append(stats.latencies, latencyMs)
+}
+
+// addMetrics counts the drops and the timeouts which the agent pool reports.
+func (c *collector) addMetrics(ms *protocol.AgentMetricList) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, m := range ms.Metrics {
+		switch m.Name {
+		case metrics.MetricTxDrop, metrics.MetricBlockDrop:
+			c.agentUnsafe(m.AgentId).drops += int(m.Value)
+		case metrics.MetricTxTimeout, metrics.MetricBlockTimeout:
+			c.agentUnsafe(m.AgentId).timeouts += int(m.Value)
+		}
+	}
+	return nil
+}
+
+func (c *collector) report(duration time.Duration, txSent, blocksSent int) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := &Report{Duration: duration, TxSent: txSent, BlocksSent: blocksSent}
+	for agentID, stats := range c.agents {
+		latencies := append([]uint32{}, stats.latencies...)
+		sort.Slice(latencies, func(i, j int) bool {
+			return latencies[i] < latencies[j]
+		})
+		agentReport := &AgentReport{
+			AgentID:        agentID,
+			TxResponses:    stats.txResponses,
+			BlockResponses: stats.blockResponses,
+			Errors:         stats.errors,
+			Drops:          stats.drops,
+			Timeouts:       stats.timeouts,
+			LatencyP50Ms:   percentile(latencies, 50),
+			LatencyP90Ms:   percentile(latencies, 90),
+			LatencyP99Ms:   percentile(latencies, 99),
+			LatencyMaxMs:   percentile(latencies, 100),
+		}
+		if duration > 0 {
+			agentReport.Throughput = float64(stats.txResponses+stats.blockResponses) / duration.Seconds()
+		}
+		report.Agents = append(report.Agents, agentReport)
+	}
+	sort.Slice(report.Agents, func(i, j int) bool {
+		return report.Agents[i].AgentID < report.Agents[j].AgentID
+	})
+	return report
+}
+
+// percentile returns the nearest-rank percentile of the sorted values.
+func percentile(sorted []uint32, p float64) uint32 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
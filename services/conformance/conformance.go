@@ -0,0 +1,300 @@
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/services/bench"
+	"github.com/forta-network/forta-node/services/scanner/agentpool/poolagent"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// Check names
+const (
+	CheckHandshake = "handshake"
+	CheckTx        = "evaluate-tx"
+	CheckBlock     = "evaluate-block"
+	CheckSchema    = "finding-schema"
+	CheckTimeouts  = "timeouts"
+	CheckHealth    = "health"
+)
+
+// Check statuses
+const (
+	StatusPass = "PASS"
+	StatusWarn = "WARN"
+	StatusFail = "FAIL"
+)
+
+// CheckResult is the result of a conformance check.
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Details string `json:"details"`
+}
+
+// Report is the result of the conformance checks of an agent.
+type Report struct {
+	Agent  string         `json:"agent"`
+	Checks []*CheckResult `json:"checks"`
+}
+
+// Passed tells if none of the checks failed.
+func (report *Report) Passed() bool {
+	for _, check := range report.Checks {
+		if check.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Print writes the report as a table.
+func (report *Report) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAILS")
+	for _, check := range report.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", check.Name, check.Status, check.Details)
+	}
+	tw.Flush()
+}
+
+func (report *Report) add(name, status, details string, args ...interface{}) {
+	report.Checks = append(report.Checks, &CheckResult{Name: name, Status: status, Details: fmt.Sprintf(details, args...)})
+}
+
+// Options are the conformance test options.
+type Options struct {
+	// Requests is how many tx requests are sent back to back for the timeout check.
+	Requests int
+	// Alive tells if the agent is still running after the requests, e.g. the container did not exit.
+	Alive func() error
+}
+
+// Run runs the conformance checks of the agent through the connection. The requests need to be answered
+// within the timeout which the scanner uses for the agents.
+func Run(ctx context.Context, agentID string, conn *grpc.ClientConn, opts Options) (*Report, error) {
+	fixtures, err := bench.SyntheticFixtures()
+	if err != nil {
+		return nil, err
+	}
+	client := protocol.NewAgentClient(conn)
+	report := &Report{Agent: agentID}
+
+	checkHandshake(ctx, report, client, agentID)
+
+	// the findings of each response
+	var responseFindings [][]*protocol.Finding
+	if txResp, ok := checkTx(ctx, report, client, fixtures.Txs[0]); ok {
+		responseFindings = append(responseFindings, txResp.Findings)
+	}
+	if blockResp, ok := checkBlock(ctx, report, client, fixtures.Blocks[0]); ok {
+		responseFindings = append(responseFindings, blockResp.Findings)
+	}
+	checkSchema(report, responseFindings)
+	checkTimeouts(ctx, report, client, fixtures.Txs, opts.Requests)
+	checkHealth(report, conn, opts.Alive)
+	return report, nil
+}
+
+func evaluateCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, poolagent.AgentTimeout)
+}
+
+func checkHandshake(ctx context.Context, report *Report, client protocol.AgentClient, agentID string) {
+	ctx, cancel := evaluateCtx(ctx)
+	defer cancel()
+	start := time.Now()
+	resp, err := client.Initialize(ctx, &protocol.InitializeRequest{AgentId: agentID})
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		report.add(CheckHandshake, StatusWarn, "initialize is not implemented")
+	case err != nil:
+		report.add(CheckHandshake, StatusFail, "initialize failed: %v", err)
+	case resp.Status != protocol.ResponseStatus_SUCCESS:
+		report.add(CheckHandshake, StatusFail, "initialize returned %s: %s", resp.Status, responseErrors(resp.Errors))
+	default:
+		report.add(CheckHandshake, StatusPass, "initialized in %s", time.Since(start).Round(time.Millisecond))
+	}
+}
+
+func checkTx(
+	ctx context.Context, report *Report, client protocol.AgentClient, req *protocol.EvaluateTxRequest,
+) (*protocol.EvaluateTxResponse, bool) {
+	ctx, cancel := evaluateCtx(ctx)
+	defer cancel()
+	start := time.Now()
+	resp, err := client.EvaluateTx(ctx, req)
+	switch {
+	case err != nil:
+		report.add(CheckTx, StatusFail, "request failed: %v", err)
+		return nil, false
+	case resp.Status != protocol.ResponseStatus_SUCCESS:
+		report.add(CheckTx, StatusFail, "returned %s: %s", resp.Status, responseErrors(resp.Errors))
+	default:
+		report.add(CheckTx, StatusPass, "%d finding(s) in %s", len(resp.Findings), time.Since(start).Round(time.Millisecond))
+	}
+	return resp, true
+}
+
+func checkBlock(
+	ctx context.Context, report *Report, client protocol.AgentClient, req *protocol.EvaluateBlockRequest,
+) (*protocol.EvaluateBlockResponse, bool) {
+	ctx, cancel := evaluateCtx(ctx)
+	defer cancel()
+	start := time.Now()
+	resp, err := client.EvaluateBlock(ctx, req)
+	switch {
+	case err != nil:
+		report.add(CheckBlock, StatusFail, "request failed: %v", err)
+		return nil, false
+	case resp.Status != protocol.ResponseStatus_SUCCESS:
+		report.add(CheckBlock, StatusFail, "returned %s: %s", resp.Status, responseErrors(resp.Errors))
+	default:
+		report.add(CheckBlock, StatusPass, "%d finding(s) in %s", len(resp.Findings), time.Since(start).Round(time.Millisecond))
+	}
+	return resp, true
+}
+
+// checkSchema validates the findings which the agent returned for the fixtures.
+func checkSchema(report *Report, responseFindings [][]*protocol.Finding) {
+	var (
+		findings []*protocol.Finding
+		errs     []string
+		warnings []string
+	)
+	for _, respFindings := range responseFindings {
+		if len(respFindings) > poolagent.MaxFindings {
+			warnings = append(warnings, fmt.Sprintf("the findings over %d in a response are dropped", poolagent.MaxFindings))
+		}
+		findings = append(findings, respFindings...)
+	}
+	if len(findings) == 0 {
+		report.add(CheckSchema, StatusPass, "no findings to validate")
+		return
+	}
+	for i, finding := range findings {
+		for _, err := range ValidateFinding(finding) {
+			errs = append(errs, fmt.Sprintf("findings[%d]: %s", i, err))
+		}
+		if finding.Severity == protocol.Finding_UNKNOWN {
+			warnings = append(warnings, fmt.Sprintf("findings[%d]: severity is unknown", i))
+		}
+		if finding.Type == protocol.Finding_UNKNOWN_TYPE {
+			warnings = append(warnings, fmt.Sprintf("findings[%d]: type is unknown", i))
+		}
+	}
+	switch {
+	case len(errs) > 0:
+		report.add(CheckSchema, StatusFail, "%s", strings.Join(errs, "; "))
+	case len(warnings) > 0:
+		report.add(CheckSchema, StatusWarn, "%s", strings.Join(warnings, "; "))
+	default:
+		report.add(CheckSchema, StatusPass, "%d valid finding(s)", len(findings))
+	}
+}
+
+// ValidateFinding returns the problems of a finding which make it an invalid alert.
+func ValidateFinding(finding *protocol.Finding) (errs []string) {
+	if len(finding.AlertId) == 0 {
+		errs = append(errs, "alertId is required")
+	}
+	if len(finding.Name) == 0 {
+		errs = append(errs, "name is required")
+	}
+	if len(finding.Description) == 0 {
+		errs = append(errs, "description is required")
+	}
+	if _, ok := protocol.Finding_Severity_name[int32(finding.Severity)]; !ok {
+		errs = append(errs, fmt.Sprintf("invalid severity %d", finding.Severity))
+	}
+	if _, ok := protocol.Finding_FindingType_name[int32(finding.Type)]; !ok {
+		errs = append(errs, fmt.Sprintf("invalid type %d", finding.Type))
+	}
+	for _, address := range finding.Addresses {
+		if !common.IsHexAddress(address) {
+			errs = append(errs, fmt.Sprintf("invalid address %q", address))
+		}
+	}
+	for key := range finding.Metadata {
+		if len(key) == 0 {
+			errs = append(errs, "metadata has an empty key")
+		}
+	}
+	return
+}
+
+// checkTimeouts sends the tx requests back to back and checks that the agent responds to all of them
+// within the timeout of the scanner.
+func checkTimeouts(ctx context.Context, report *Report, client protocol.AgentClient, txs []*protocol.EvaluateTxRequest, requests int) {
+	if requests <= 0 {
+		return
+	}
+	var (
+		slowest  time.Duration
+		timeouts int
+		failures int
+	)
+	for i := 0; i < requests; i++ {
+		reqCtx, cancel := evaluateCtx(ctx)
+		start := time.Now()
+		_, err := client.EvaluateTx(reqCtx, txs[i%len(txs)])
+		cancel()
+		if took := time.Since(start); took > slowest {
+			slowest = took
+		}
+		switch {
+		case status.Code(err) == codes.DeadlineExceeded:
+			timeouts++
+		case err != nil:
+			failures++
+		}
+	}
+	slowest = slowest.Round(time.Millisecond)
+	switch {
+	case timeouts > 0:
+		report.add(CheckTimeouts, StatusFail, "%d of %d requests timed out after %s", timeouts, requests, poolagent.AgentTimeout)
+	case failures > 0:
+		report.add(CheckTimeouts, StatusFail, "%d of %d requests failed", failures, requests)
+	case slowest > poolagent.AgentTimeout/2:
+		report.add(CheckTimeouts, StatusWarn, "slowest of %d requests took %s, close to the %s timeout", requests, slowest, poolagent.AgentTimeout)
+	default:
+		report.add(CheckTimeouts, StatusPass, "slowest of %d requests took %s", requests, slowest)
+	}
+}
+
+// checkHealth checks that the agent is still running and reachable after the requests.
+func checkHealth(report *Report, conn *grpc.ClientConn, alive func() error) {
+	if alive != nil {
+		if err := alive(); err != nil {
+			report.add(CheckHealth, StatusFail, "agent is not running: %v", err)
+			return
+		}
+	}
+	switch state := conn.GetState(); state {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		report.add(CheckHealth, StatusFail, "connection is %s", strings.ToLower(state.String()))
+	default:
+		report.add(CheckHealth, StatusPass, "agent is running")
+	}
+}
+
+func responseErrors(errs []*protocol.Error) string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	if len(messages) == 0 {
+		return "no errors"
+	}
+	return strings.Join(messages, ", ")
+}
@@ -0,0 +1,102 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type testAgentServer struct {
+	protocol.UnimplementedAgentServer
+	findings []*protocol.Finding
+}
+
+func (s *testAgentServer) EvaluateTx(context.Context, *protocol.EvaluateTxRequest) (*protocol.EvaluateTxResponse, error) {
+	return &protocol.EvaluateTxResponse{Status: protocol.ResponseStatus_SUCCESS, Findings: s.findings}, nil
+}
+
+func (s *testAgentServer) EvaluateBlock(context.Context, *protocol.EvaluateBlockRequest) (*protocol.EvaluateBlockResponse, error) {
+	return &protocol.EvaluateBlockResponse{Status: protocol.ResponseStatus_ERROR, Errors: []*protocol.Error{{Message: "no provider"}}}, nil
+}
+
+func runTestAgent(r *require.Assertions, server *testAgentServer, alive func() error) *Report {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	grpcServer := grpc.NewServer()
+	protocol.RegisterAgentServer(grpcServer, server)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	r.NoError(err)
+	defer conn.Close()
+	report, err := Run(context.Background(), "test-agent", conn, Options{Requests: 5, Alive: alive})
+	r.NoError(err)
+	return report
+}
+
+func checkStatuses(report *Report) map[string]string {
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestRun(t *testing.T) {
+	r := require.New(t)
+
+	report := runTestAgent(r, &testAgentServer{findings: []*protocol.Finding{{
+		AlertId:     "TEST-1",
+		Name:        "Test",
+		Description: "Test finding",
+		Severity:    protocol.Finding_HIGH,
+		Type:        protocol.Finding_EXPLOIT,
+		Addresses:   []string{"0x3f5ce5fbfe3e9af3971dd833d26ba9b5c936f0be"},
+	}}}, nil)
+	r.Equal(map[string]string{
+		CheckHandshake: StatusWarn,
+		CheckTx:        StatusPass,
+		CheckBlock:     StatusFail,
+		CheckSchema:    StatusPass,
+		CheckTimeouts:  StatusPass,
+		CheckHealth:    StatusPass,
+	}, checkStatuses(report))
+	r.False(report.Passed())
+	r.Contains(report.Checks[2].Details, "no provider")
+
+	report = runTestAgent(r, &testAgentServer{findings: []*protocol.Finding{{
+		AlertId:   "TEST-1",
+		Severity:  protocol.Finding_Severity(42),
+		Addresses: []string{"not-an-address"},
+	}}}, func() error {
+		return errors.New("exited")
+	})
+	statuses := checkStatuses(report)
+	r.Equal(StatusFail, statuses[CheckSchema])
+	r.Equal(StatusFail, statuses[CheckHealth])
+	r.Contains(report.Checks[3].Details, "name is required")
+	r.Contains(report.Checks[3].Details, "invalid severity 42")
+	r.Contains(report.Checks[3].Details, `invalid address "not-an-address"`)
+}
+
+func TestCheckSchema_Warnings(t *testing.T) {
+	r := require.New(t)
+
+	finding := &protocol.Finding{AlertId: "TEST-1", Name: "Test", Description: "Test finding"}
+	var findings []*protocol.Finding
+	for i := 0; i < 11; i++ {
+		findings = append(findings, finding)
+	}
+	report := &Report{}
+	checkSchema(report, [][]*protocol.Finding{findings})
+	r.Equal(StatusWarn, report.Checks[0].Status)
+	r.Contains(report.Checks[0].Details, "severity is unknown")
+	r.Contains(report.Checks[0].Details, "are dropped")
+	r.True(report.Passed())
+}
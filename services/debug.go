@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path"
+	runtimepprof "runtime/pprof"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// debugDumpLevels are the debug levels of the profiles which can be dumped. The goroutine dump has the
+// stack traces in the text format and the heap dump is in the pprof format.
+var debugDumpLevels = map[string]int{
+	"goroutine": 2,
+	"heap":      0,
+}
+
+// DebugDumpRequest requests the node processes to write their runtime profiles to the debug dumps dir.
+type DebugDumpRequest struct {
+	ID    string   `json:"id"`
+	Kinds []string `json:"kinds"`
+}
+
+// StartPprof serves the runtime profiles on localhost if it is enabled.
+func StartPprof(ctx context.Context, cfg config.Config) {
+	if !cfg.Debug.Pprof {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	server := &http.Server{
+		Addr:    fmt.Sprintf("127.0.0.1:%s", cfg.Debug.PprofPort),
+		Handler: mux,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("pprof server failed")
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	log.WithField("address", server.Addr).Warn("serving the runtime profiles")
+}
+
+// ValidateDebugDumpKinds checks if the profiles can be dumped.
+func ValidateDebugDumpKinds(kinds []string) error {
+	for _, kind := range kinds {
+		if _, ok := debugDumpLevels[kind]; !ok {
+			return fmt.Errorf("unknown dump kind '%s'", kind)
+		}
+	}
+	return nil
+}
+
+// DebugDumpsDir returns the dir which the processes write the dumps to.
+func DebugDumpsDir(fortaDir string) string {
+	return path.Join(fortaDir, config.DefaultDebugDumpsDirName)
+}
+
+// WriteDebugDumps writes the profiles of the current process to the dir and returns the file names.
+func WriteDebugDumps(dir, process string, kinds []string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the dumps dir: %v", err)
+	}
+	var fileNames []string
+	for _, kind := range kinds {
+		level, ok := debugDumpLevels[kind]
+		if !ok {
+			return fileNames, fmt.Errorf("unknown dump kind '%s'", kind)
+		}
+		ext := "pprof"
+		if level > 0 {
+			ext = "txt"
+		}
+		fileName := fmt.Sprintf("%s-%s-%s.%s", process, kind, time.Now().UTC().Format("20060102T150405"), ext)
+		f, err := os.Create(path.Join(dir, fileName))
+		if err != nil {
+			return fileNames, fmt.Errorf("failed to create the dump file: %v", err)
+		}
+		err = runtimepprof.Lookup(kind).WriteTo(f, level)
+		f.Close()
+		if err != nil {
+			return fileNames, fmt.Errorf("failed to write the %s dump: %v", kind, err)
+		}
+		fileNames = append(fileNames, fileName)
+	}
+	return fileNames, nil
+}
+
+// RequestDebugDumps requests the dumps from the node processes which watch the request.
+func RequestDebugDumps(fortaDir string, kinds []string) (*DebugDumpRequest, error) {
+	request := &DebugDumpRequest{
+		ID:    uuid.Must(uuid.NewUUID()).String(),
+		Kinds: kinds,
+	}
+	b, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path.Join(fortaDir, config.DefaultDebugDumpFile), b, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write the dump request: %v", err)
+	}
+	return request, nil
+}
+
+func readDebugDumpRequest(fortaDir string) *DebugDumpRequest {
+	b, err := ioutil.ReadFile(path.Join(fortaDir, config.DefaultDebugDumpFile))
+	if err != nil {
+		return nil
+	}
+	var request DebugDumpRequest
+	if err := json.Unmarshal(b, &request); err != nil {
+		return nil
+	}
+	return &request
+}
+
+// watchDebugDumpRequests writes the dumps of the process when a new dump is requested. The request
+// which exists at the start was for the previous run of the process.
+func watchDebugDumpRequests(ctx context.Context, logger *log.Entry, process string, cfg config.Config) {
+	var lastID string
+	if request := readDebugDumpRequest(cfg.FortaDir); request != nil {
+		lastID = request.ID
+	}
+	ticker := time.NewTicker(defaultConfigCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		request := readDebugDumpRequest(cfg.FortaDir)
+		if request == nil || request.ID == lastID {
+			continue
+		}
+		lastID = request.ID
+		fileNames, err := WriteDebugDumps(DebugDumpsDir(cfg.FortaDir), process, request.Kinds)
+		if err != nil {
+			logger.WithError(err).Error("failed to write the debug dumps")
+			continue
+		}
+		logger.WithField("files", fileNames).Info("wrote the debug dumps")
+	}
+}
@@ -0,0 +1,49 @@
+package json_rpc
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+const bearerPrefix = "Bearer "
+
+// requestToken returns the agent token from the basic auth password or the bearer token.
+func requestToken(req *http.Request) string {
+	if _, password, ok := req.BasicAuth(); ok {
+		return password
+	}
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, bearerPrefix) {
+		return strings.TrimPrefix(auth, bearerPrefix)
+	}
+	return ""
+}
+
+// isLoopbackAddr tells if the request is from the proxy container itself, like the API health checks.
+func isLoopbackAddr(hostPort string) bool {
+	host, _, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// findAgentFromToken finds the agent pod which the token belongs to.
+func (p *JsonRpcProxy) findAgentFromToken(token string) (*config.AgentConfig, bool) {
+	if len(token) == 0 {
+		return nil, false
+	}
+	p.agentConfigMu.RLock()
+	defer p.agentConfigMu.RUnlock()
+	for _, agentConfig := range p.agentConfigs {
+		agentToken := config.AgentJsonRpcToken(p.agentTokenSecret, agentConfig)
+		if subtle.ConstantTimeCompare([]byte(agentToken), []byte(token)) == 1 {
+			return &agentConfig, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,53 @@
+package json_rpc
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindAgentFromToken(t *testing.T) {
+	r := require.New(t)
+
+	agent := config.AgentConfig{ID: "0x1"}
+	p := &JsonRpcProxy{
+		agentTokenSecret: "secret",
+		agentConfigs:     []config.AgentConfig{{ID: "0x2"}, agent},
+	}
+	token := config.AgentJsonRpcToken("secret", agent)
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.RemoteAddr = "10.0.0.5:1234"
+	req.Header.Set("Authorization", "Bearer "+token)
+	agentConfig, foundAgent, ok := p.findAgent(req)
+	r.True(ok)
+	r.True(foundAgent)
+	r.Equal("0x1", agentConfig.ID)
+	r.Empty(req.Header.Get("Authorization"))
+
+	req = httptest.NewRequest("POST", "/", nil)
+	req.RemoteAddr = "10.0.0.5:1234"
+	req.SetBasicAuth("agent", token)
+	_, foundAgent, ok = p.findAgent(req)
+	r.True(ok)
+	r.True(foundAgent)
+
+	// the token of another secret is rejected
+	req = httptest.NewRequest("POST", "/", nil)
+	req.RemoteAddr = "10.0.0.5:1234"
+	req.Header.Set("Authorization", "Bearer "+config.AgentJsonRpcToken("other", agent))
+	_, _, ok = p.findAgent(req)
+	r.False(ok)
+
+	// the requests without the tokens are allowed only from the proxy container
+	req = httptest.NewRequest("POST", "/", nil)
+	req.RemoteAddr = "10.0.0.5:1234"
+	_, _, ok = p.findAgent(req)
+	r.False(ok)
+	req.RemoteAddr = "127.0.0.1:1234"
+	_, foundAgent, ok = p.findAgent(req)
+	r.True(ok)
+	r.False(foundAgent)
+}
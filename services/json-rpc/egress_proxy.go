@@ -0,0 +1,207 @@
+package json_rpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+var errEgressAddressNotAllowed = errors.New("egress to non-public addresses is not allowed")
+
+// nonPublicNetworks are the networks which the agents should never reach through the proxy,
+// including the node containers and the host.
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.168.0.0/16", "224.0.0.0/4", "240.0.0.0/4", "::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func isPublicIP(ip net.IP) bool {
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublicOnly refuses the connections to the non-public addresses after the host names are resolved.
+func dialPublicOnly(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", errEgressAddressNotAllowed, address)
+	}
+	return nil
+}
+
+// EgressProxy is an HTTP proxy which lets the agents connect only to their allowed hosts. The agent
+// networks have no outbound access when the egress policy is enabled, so this is the only way out.
+type EgressProxy struct {
+	cfg       config.EgressConfig
+	findAgent func(remoteAddr string) (*config.AgentConfig, bool)
+	dialer    *net.Dialer
+	forwarder *httputil.ReverseProxy
+	server    *http.Server
+
+	lastDenied    health.TimeTracker
+	lastDeniedMsg health.MessageTracker
+}
+
+// NewEgressProxy creates a new egress proxy which identifies the agents with the finder.
+func NewEgressProxy(cfg config.EgressConfig, findAgent func(remoteAddr string) (*config.AgentConfig, bool)) *EgressProxy {
+	dialer := &net.Dialer{Timeout: time.Second * 30, Control: dialPublicOnly}
+	return &EgressProxy{
+		cfg:       cfg,
+		findAgent: findAgent,
+		dialer:    dialer,
+		forwarder: &httputil.ReverseProxy{
+			Director: func(r *http.Request) {
+				r.Header.Del("Proxy-Authorization")
+			},
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     time.Minute,
+				TLSHandshakeTimeout: time.Second * 10,
+			},
+		},
+	}
+}
+
+// Start starts the proxy server.
+func (ep *EgressProxy) Start() {
+	ep.server = &http.Server{
+		Addr:    fmt.Sprintf(":%s", config.DefaultEgressProxyPort),
+		Handler: ep,
+	}
+	utils.GoListenAndServe(ep.server)
+}
+
+// Stop stops the proxy server.
+func (ep *EgressProxy) Stop() error {
+	if ep.server != nil {
+		return ep.server.Close()
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (ep *EgressProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	agent, ok := ep.findAgent(r.RemoteAddr)
+	if !ok {
+		http.Error(w, "unknown agent", http.StatusForbidden)
+		return
+	}
+
+	host, port, err := egressTarget(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !config.EgressHostAllowed(config.GetAgentEgressHosts(ep.cfg, *agent), host, port) {
+		log.WithFields(log.Fields{
+			"agent": agent.ID,
+			"host":  host,
+			"port":  port,
+		}).Warn("denied agent egress")
+		ep.lastDenied.Set()
+		ep.lastDeniedMsg.Set(fmt.Sprintf("%s: %s:%s", agent.ID, host, port))
+		http.Error(w, "host is not allowed", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		ep.tunnel(w, r)
+		return
+	}
+	ep.forwarder.ServeHTTP(w, r)
+}
+
+// egressTarget returns the host and the port which the agent wants to connect to.
+func egressTarget(r *http.Request) (string, string, error) {
+	if r.Method == http.MethodConnect {
+		return net.SplitHostPort(r.Host)
+	}
+	if !r.URL.IsAbs() {
+		return "", "", errors.New("not a proxy request")
+	}
+	port := r.URL.Port()
+	if len(port) == 0 {
+		port = "80"
+		if r.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return r.URL.Hostname(), port, nil
+}
+
+func (ep *EgressProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	targetConn, err := ep.dialer.DialContext(r.Context(), "tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		targetConn.Close()
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		targetConn.Close()
+		return
+	}
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		clientConn.Close()
+		targetConn.Close()
+		return
+	}
+
+	var once sync.Once
+	closeBoth := func() {
+		clientConn.Close()
+		targetConn.Close()
+	}
+	go func() {
+		_, _ = io.Copy(targetConn, clientBuf)
+		once.Do(closeBoth)
+	}()
+	go func() {
+		_, _ = io.Copy(clientConn, targetConn)
+		once.Do(closeBoth)
+	}()
+}
+
+// Health implements the health.Reporter interface.
+func (ep *EgressProxy) Health() health.Reports {
+	return health.Reports{
+		ep.lastDenied.GetReport("event.egress-denied.time"),
+		ep.lastDeniedMsg.GetReport("event.egress-denied.details"),
+	}
+}
@@ -0,0 +1,58 @@
+package json_rpc
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testEgressProxy(agent *config.AgentConfig) *EgressProxy {
+	return NewEgressProxy(config.EgressConfig{Enable: true}, func(remoteAddr string) (*config.AgentConfig, bool) {
+		return agent, agent != nil
+	})
+}
+
+func TestEgressProxy_Denied(t *testing.T) {
+	r := require.New(t)
+
+	proxy := testEgressProxy(&config.AgentConfig{ID: "agent", EgressHosts: []string{"api.example.com"}})
+
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://evil.example.com/secrets", nil))
+	r.Equal(http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodConnect, "http://evil.example.com:443", nil))
+	r.Equal(http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	testEgressProxy(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://api.example.com/", nil))
+	r.Equal(http.StatusForbidden, rec.Code)
+}
+
+func TestEgressProxy_NonPublicAddress(t *testing.T) {
+	r := require.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// even if the host is allowed, the node network and the host are not reachable
+	proxy := testEgressProxy(&config.AgentConfig{ID: "agent", EgressHosts: []string{"127.0.0.1"}})
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, server.URL, nil))
+	r.Equal(http.StatusBadGateway, rec.Code)
+}
+
+func TestIsPublicIP(t *testing.T) {
+	r := require.New(t)
+
+	r.True(isPublicIP(net.ParseIP("1.1.1.1")))
+	r.True(isPublicIP(net.ParseIP("2606:4700:4700::1111")))
+	r.False(isPublicIP(net.ParseIP("172.18.0.2")))
+	r.False(isPublicIP(net.ParseIP("169.254.169.254")))
+	r.False(isPublicIP(net.ParseIP("::1")))
+}
@@ -2,6 +2,7 @@ package json_rpc
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
@@ -23,7 +24,32 @@ type jsonRpcError struct {
 }
 
 func writeTooManyReqsErr(w http.ResponseWriter, req *http.Request) {
-	w.WriteHeader(http.StatusTooManyRequests)
+	writeJsonRpcErr(w, req, http.StatusTooManyRequests, "agent exceeds scan node request limit")
+}
+
+func writeQuotaExceededErr(w http.ResponseWriter, req *http.Request) {
+	writeJsonRpcErr(w, req, http.StatusTooManyRequests, "agent exceeds scan node daily request quota")
+}
+
+func writeUnauthorizedErr(w http.ResponseWriter, req *http.Request) {
+	writeJsonRpcErr(w, req, http.StatusUnauthorized, "agent token is missing or invalid")
+}
+
+func writeViolationErr(w http.ResponseWriter, req *http.Request, v *Violation) {
+	statusCode := http.StatusForbidden
+	switch v.Kind {
+	case ViolationRequestSize:
+		statusCode = http.StatusRequestEntityTooLarge
+	case ViolationResponseSize:
+		statusCode = http.StatusBadGateway
+	case ViolationConcurrency:
+		statusCode = http.StatusTooManyRequests
+	}
+	writeJsonRpcErr(w, req, statusCode, fmt.Sprintf("agent request violates scan node restrictions: %s", v.Details))
+}
+
+func writeJsonRpcErr(w http.ResponseWriter, req *http.Request, statusCode int, message string) {
+	w.WriteHeader(statusCode)
 
 	var reqPayload requestPayload
 	if err := json.NewDecoder(req.Body).Decode(&reqPayload); err != nil {
@@ -36,7 +62,7 @@ func writeTooManyReqsErr(w http.ResponseWriter, req *http.Request) {
 		ID:      reqPayload.ID,
 		Error: jsonRpcError{
 			Code:    -32000,
-			Message: "agent exceeds scan node request limit",
+			Message: message,
 		},
 	}); err != nil {
 		log.WithError(err).Error("failed to write jsonrpc error response body")
@@ -1,11 +1,16 @@
 package json_rpc
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,12 +27,16 @@ import (
 	"github.com/forta-network/forta-node/clients/messaging"
 	"github.com/forta-network/forta-node/config"
 	"github.com/forta-network/forta-node/metrics"
+	"github.com/forta-network/forta-node/rpccache"
 )
 
 // JsonRpcProxy proxies requests from agents to json-rpc endpoint
 type JsonRpcProxy struct {
 	ctx          context.Context
 	cfg          config.JsonRpcConfig
+	rpcUrl       *url.URL
+	scannerCache bool
+	cfgMu        sync.RWMutex
 	server       *http.Server
 	dockerClient clients.DockerClient
 	msgClient    clients.MessageClient
@@ -36,6 +45,16 @@ type JsonRpcProxy struct {
 	agentConfigMu sync.RWMutex
 
 	rateLimiter *RateLimiter
+	quotas      *QuotaTracker
+	guard       *RequestGuard
+	cache       *rpccache.Cache
+	egressProxy *EgressProxy
+
+	// the agent pods identify themselves with the tokens since they reach the proxy through the node host
+	agentTokenSecret string
+
+	scannerCacheHost   string
+	scannerCacheHostMu sync.Mutex
 
 	lastErr health.ErrorTracker
 }
@@ -45,39 +64,180 @@ func (p *JsonRpcProxy) Start() error {
 
 	p.registerMessageHandlers()
 
-	rpcUrl, err := url.Parse(p.cfg.Url)
-	if err != nil {
+	if err := p.setUpstream(p.cfg); err != nil {
 		return err
 	}
-	rp := httputil.NewSingleHostReverseProxy(rpcUrl)
+	rp := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			jCfg, rpcUrl := p.upstream()
+			u := *rpcUrl
+			if toScannerCache, _ := r.Context().Value(scannerCacheContextKey{}).(bool); toScannerCache {
+				u = url.URL{Scheme: "http", Host: p.getScannerCacheHost()}
+			}
+			r.Host = u.Host
+			r.URL = &u
+			if _, ok := r.Header["User-Agent"]; !ok {
+				// explicitly disable User-Agent so it's not set to default value
+				r.Header.Set("User-Agent", "")
+			}
+			for h, v := range jCfg.Headers {
+				r.Header.Set(h, v)
+			}
+		},
+		ModifyResponse: p.checkResponse,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			if v, ok := err.(*Violation); ok {
+				writeViolationErr(w, req, v)
+				return
+			}
+			if toScannerCache, _ := req.Context().Value(scannerCacheContextKey{}).(bool); toScannerCache {
+				p.resetScannerCacheHost()
+			}
+			log.WithError(err).Warn("failed to proxy the request")
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
 
-	d := rp.Director
-	rp.Director = func(r *http.Request) {
-		d(r)
-		r.Host = rpcUrl.Host
-		r.URL = rpcUrl
-		for h, v := range p.cfg.Headers {
-			r.Header.Set(h, v)
+	var handler http.Handler = rp
+	if p.cache != nil {
+		handler = p.cache.Handler(rp)
+	}
+	if p.scannerCache {
+		handler = p.scannerCacheHandler(handler)
+	}
+	p.server = &http.Server{
+		Addr:    ":8545",
+		Handler: p.corsHandler(p.metricHandler(handler)),
+	}
+	utils.GoListenAndServe(p.server)
+
+	if p.egressProxy != nil {
+		p.egressProxy.Start()
+	}
+	return nil
+}
+
+type scannerCacheContextKey struct{}
+
+// scannerCacheHandler routes the requests of the cacheable methods to the cache of the scanner.
+func (p *JsonRpcProxy) scannerCacheHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if rpccache.CacheableRequest(body) {
+			req = req.WithContext(context.WithValue(req.Context(), scannerCacheContextKey{}, true))
 		}
+		h.ServeHTTP(w, req)
+	})
+}
+
+// getScannerCacheHost returns the address of the scanner cache on the node network. The scanner is
+// attached to the agent networks too, so its name can point to an address which the cache is not on.
+func (p *JsonRpcProxy) getScannerCacheHost() string {
+	p.scannerCacheHostMu.Lock()
+	defer p.scannerCacheHostMu.Unlock()
+	if len(p.scannerCacheHost) > 0 {
+		return p.scannerCacheHost
+	}
+	host := net.JoinHostPort(config.DockerScannerContainerName, config.DefaultScannerCachePort)
+	container, err := p.dockerClient.GetContainerByName(p.ctx, config.DockerScannerContainerName)
+	if err != nil || container.NetworkSettings == nil {
+		return host
+	}
+	nodeNetwork, ok := container.NetworkSettings.Networks[config.DockerNetworkName]
+	if !ok || len(nodeNetwork.IPAddress) == 0 {
+		return host
+	}
+	p.scannerCacheHost = net.JoinHostPort(nodeNetwork.IPAddress, config.DefaultScannerCachePort)
+	return p.scannerCacheHost
+}
+
+func (p *JsonRpcProxy) resetScannerCacheHost() {
+	p.scannerCacheHostMu.Lock()
+	defer p.scannerCacheHostMu.Unlock()
+	p.scannerCacheHost = ""
+}
+
+// upstream returns the JSON-RPC API which the requests are forwarded to.
+func (p *JsonRpcProxy) upstream() (config.JsonRpcConfig, *url.URL) {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+	return p.cfg, p.rpcUrl
+}
+
+func (p *JsonRpcProxy) setUpstream(jCfg config.JsonRpcConfig) error {
+	rpcUrl, err := url.Parse(jCfg.Url)
+	if err != nil {
+		return err
+	}
+	p.cfgMu.Lock()
+	defer p.cfgMu.Unlock()
+	p.cfg = jCfg
+	p.rpcUrl = rpcUrl
+	return nil
+}
+
+// Reload applies the changes of the JSON-RPC API and the rate limits without dropping the agent requests.
+func (p *JsonRpcProxy) Reload(cfg config.Config) error {
+	if err := p.setUpstream(proxyJsonRpcConfig(cfg)); err != nil {
+		return fmt.Errorf("invalid json-rpc url: %v", err)
+	}
+	rateLimiting := proxyRateLimiting(cfg)
+	p.rateLimiter.SetLimit(rateLimiting.Rate, rateLimiting.Burst)
+	clientLimits, clientQuotas := proxyAgentLimits(cfg)
+	p.rateLimiter.SetClientLimits(clientLimits)
+	p.quotas.SetQuotas(cfg.JsonRpcProxy.DailyQuota, clientQuotas)
+	p.guard.SetConfig(cfg.JsonRpcProxy.Restrictions)
+	return nil
+}
+
+type agentContextKey struct{}
+
+// checkResponse checks the size of the response which is being forwarded to the agent.
+func (p *JsonRpcProxy) checkResponse(resp *http.Response) error {
+	agentConfig, _ := resp.Request.Context().Value(agentContextKey{}).(*config.AgentConfig)
+	return p.guard.CheckResponse(resp, func(v *Violation) {
+		p.reportViolation(agentConfig, v)
+	})
+}
+
+// reportViolation logs the violation and flags it in the agent metrics.
+func (p *JsonRpcProxy) reportViolation(agentConfig *config.AgentConfig, v *Violation) {
+	logger := log.WithFields(log.Fields{
+		"violation": v.Kind,
+		"details":   v.Details,
+	})
+	if agentConfig != nil {
+		logger = logger.WithField("agentId", agentConfig.ID)
+		p.msgClient.PublishProto(messaging.SubjectMetricAgent, &protocol.AgentMetricList{
+			Metrics: []*protocol.AgentMetric{metrics.CreateAgentMetric(agentConfig.ID, metrics.MetricJSONRPCViolation, 1)},
+		})
 	}
+	logger.Warn("json-rpc request violates the proxy restrictions")
+}
 
+// corsHandler answers the preflight requests before the agent and the request checks, since the
+// preflight requests don't have the tokens and the json-rpc bodies.
+func (p *JsonRpcProxy) corsHandler(h http.Handler) http.Handler {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowCredentials: true,
 	})
-
-	p.server = &http.Server{
-		Addr:    ":8545",
-		Handler: p.metricHandler(c.Handler(rp)),
-	}
-	utils.GoListenAndServe(p.server)
-	return nil
+	return c.Handler(h)
 }
 
 func (p *JsonRpcProxy) metricHandler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		t := time.Now()
-		agentConfig, foundAgent := p.findAgentFromRemoteAddr(req.RemoteAddr)
+		agentConfig, foundAgent, ok := p.findAgent(req)
+		if !ok {
+			writeUnauthorizedErr(w, req)
+			return
+		}
 		if foundAgent && p.rateLimiter.ExceedsLimit(agentConfig.ID) {
 			writeTooManyReqsErr(w, req)
 			p.msgClient.PublishProto(messaging.SubjectMetricAgent, &protocol.AgentMetricList{
@@ -86,17 +246,62 @@ func (p *JsonRpcProxy) metricHandler(h http.Handler) http.Handler {
 			return
 		}
 
+		clientID := strings.Split(req.RemoteAddr, ":")[0]
+		if foundAgent {
+			clientID = agentConfig.ID
+			req = req.WithContext(context.WithValue(req.Context(), agentContextKey{}, agentConfig))
+		}
+		if v := p.guard.CheckRequest(req); v != nil {
+			p.reportViolation(agentConfig, v)
+			writeViolationErr(w, req, v)
+			return
+		}
+		if v := p.guard.Acquire(clientID); v != nil {
+			p.reportViolation(agentConfig, v)
+			writeViolationErr(w, req, v)
+			return
+		}
+		defer p.guard.Release(clientID)
+
+		// the quota is charged only for the requests which pass the restrictions
+		if foundAgent && !p.quotas.Use(agentConfig.ID) {
+			writeQuotaExceededErr(w, req)
+			p.msgClient.PublishProto(messaging.SubjectMetricAgent, &protocol.AgentMetricList{
+				Metrics: []*protocol.AgentMetric{metrics.CreateAgentMetric(agentConfig.ID, metrics.MetricJSONRPCQuota, 1)},
+			})
+			return
+		}
+
 		h.ServeHTTP(w, req)
 
 		if foundAgent {
 			duration := time.Since(t)
+			agentMetrics := metrics.GetJSONRPCMetrics(*agentConfig, t, 1, 0, duration)
+			if len(w.Header().Get(rpccache.CacheHitHeader)) > 0 {
+				agentMetrics = append(agentMetrics, metrics.CreateAgentMetric(agentConfig.ID, metrics.MetricJSONRPCCacheHit, 1))
+			}
 			p.msgClient.PublishProto(messaging.SubjectMetricAgent, &protocol.AgentMetricList{
-				Metrics: metrics.GetJSONRPCMetrics(*agentConfig, t, 1, 0, duration),
+				Metrics: agentMetrics,
 			})
 		}
 	})
 }
 
+// findAgent finds the agent which sent the request. The agent pods must send their tokens and the
+// tokens are not forwarded.
+func (p *JsonRpcProxy) findAgent(req *http.Request) (agentConfig *config.AgentConfig, foundAgent bool, ok bool) {
+	if len(p.agentTokenSecret) == 0 {
+		agentConfig, foundAgent = p.findAgentFromRemoteAddr(req.RemoteAddr)
+		return agentConfig, foundAgent, true
+	}
+	if isLoopbackAddr(req.RemoteAddr) {
+		return nil, false, true
+	}
+	agentConfig, foundAgent = p.findAgentFromToken(requestToken(req))
+	req.Header.Del("Authorization")
+	return agentConfig, foundAgent, foundAgent
+}
+
 func (p *JsonRpcProxy) findAgentFromRemoteAddr(hostPort string) (*config.AgentConfig, bool) {
 	containers, err := p.dockerClient.GetContainers(p.ctx)
 	if err != nil {
@@ -148,6 +353,9 @@ func (p *JsonRpcProxy) handleAgentVersionsUpdate(payload messaging.AgentPayload)
 
 func (p *JsonRpcProxy) Stop() error {
 	log.Infof("Stopping %s", p.Name())
+	if p.egressProxy != nil {
+		p.egressProxy.Stop()
+	}
 	if p.server != nil {
 		return p.server.Close()
 	}
@@ -160,9 +368,26 @@ func (p *JsonRpcProxy) Name() string {
 
 // Health implements health.Reporter interface.
 func (p *JsonRpcProxy) Health() health.Reports {
-	return health.Reports{
+	reports := health.Reports{
 		p.lastErr.GetReport("api"),
+		&health.Report{
+			Name:    "quota.exceeded.count",
+			Status:  health.StatusInfo,
+			Details: strconv.Itoa(p.quotas.Exceeded()),
+		},
+		&health.Report{
+			Name:    "violations.count",
+			Status:  health.StatusInfo,
+			Details: strconv.Itoa(p.guard.Violations()),
+		},
 	}
+	if p.cache != nil {
+		reports = append(reports, p.cache.Health()...)
+	}
+	if p.egressProxy != nil {
+		reports = append(reports, p.egressProxy.Health()...)
+	}
+	return reports
 }
 
 func (p *JsonRpcProxy) apiHealthChecker() {
@@ -179,26 +404,67 @@ func (p *JsonRpcProxy) testAPI() {
 }
 
 func (p *JsonRpcProxy) registerMessageHandlers() {
-	p.msgClient.Subscribe(messaging.SubjectAgentsVersionsLatest, messaging.AgentsHandler(p.handleAgentVersionsUpdate))
+	p.msgClient.Subscribe(messaging.SubjectAgentsVersionsLatest, messaging.Idempotent(messaging.AgentsHandler(p.handleAgentVersionsUpdate)))
 }
 
-func NewJsonRpcProxy(ctx context.Context, cfg config.Config) (*JsonRpcProxy, error) {
+// proxyJsonRpcConfig returns the JSON-RPC API which the agent requests are forwarded to. The headers of the
+// proxy are added to the headers of the scan API if only the headers are configured.
+func proxyJsonRpcConfig(cfg config.Config) config.JsonRpcConfig {
 	jCfg := cfg.Scan.JsonRpc
 	if len(cfg.JsonRpcProxy.JsonRpc.Url) > 0 {
 		jCfg = cfg.JsonRpcProxy.JsonRpc
+	} else if len(cfg.JsonRpcProxy.JsonRpc.Headers) > 0 {
+		headers := make(map[string]string)
+		for h, v := range jCfg.Headers {
+			headers[h] = v
+		}
+		for h, v := range cfg.JsonRpcProxy.JsonRpc.Headers {
+			headers[h] = v
+		}
+		jCfg.Headers = headers
 	}
-	globalClient, err := clients.NewDockerClient("")
+	// can't dial localhost - need to dial host gateway from container
+	jCfg.Url = utils.ConvertToDockerHostURL(jCfg.Url)
+	return jCfg
+}
+
+// useScannerCache tells if the cacheable requests should be served by the scanner, which shares the
+// cached chain data with the agents.
+func useScannerCache(cfg config.Config) bool {
+	return cfg.Scan.Cache.Enabled && len(cfg.JsonRpcProxy.JsonRpc.Url) == 0
+}
+
+func proxyRateLimiting(cfg config.Config) *config.RateLimitConfig {
+	if cfg.JsonRpcProxy.RateLimitConfig != nil {
+		return cfg.JsonRpcProxy.RateLimitConfig
+	}
+	return config.GetChainSettings(cfg.ChainID).JsonRpcRateLimiting
+}
+
+// proxyAgentLimits returns the rate limits and the daily quotas of the agents which override the defaults.
+func proxyAgentLimits(cfg config.Config) (map[string]*config.RateLimitConfig, map[string]int) {
+	clientLimits := make(map[string]*config.RateLimitConfig)
+	clientQuotas := make(map[string]int)
+	for _, agentCfg := range cfg.JsonRpcProxy.Agents {
+		if agentCfg.RateLimitConfig != nil {
+			clientLimits[agentCfg.AgentID] = agentCfg.RateLimitConfig
+		}
+		clientQuotas[agentCfg.AgentID] = agentCfg.DailyQuota
+	}
+	return clientLimits, clientQuotas
+}
+
+func NewJsonRpcProxy(ctx context.Context, cfg config.Config) (*JsonRpcProxy, error) {
+	jCfg := proxyJsonRpcConfig(cfg)
+	globalClient, err := clients.NewRuntimeClient("", cfg.ContainerRuntime, cfg.ContainerRuntime.ContainerSocketPath())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the global docker client: %v", err)
 	}
 	msgClient := messaging.NewClient("json-rpc-proxy", fmt.Sprintf("%s:%s", config.DockerNatsContainerName, config.DefaultNatsPort))
 
-	rateLimiting := cfg.JsonRpcProxy.RateLimitConfig
-	if rateLimiting == nil {
-		rateLimiting = config.GetChainSettings(cfg.ChainID).JsonRpcRateLimiting
-	}
+	rateLimiting := proxyRateLimiting(cfg)
 
-	return &JsonRpcProxy{
+	proxy := &JsonRpcProxy{
 		ctx:          ctx,
 		cfg:          jCfg,
 		dockerClient: globalClient,
@@ -207,5 +473,21 @@ func NewJsonRpcProxy(ctx context.Context, cfg config.Config) (*JsonRpcProxy, err
 			rateLimiting.Rate,
 			rateLimiting.Burst,
 		),
-	}, nil
+		scannerCache:     useScannerCache(cfg),
+		quotas:           NewQuotaTracker(cfg.JsonRpcProxy.DailyQuota),
+		guard:            NewRequestGuard(cfg.JsonRpcProxy.Restrictions),
+		agentTokenSecret: os.Getenv(config.EnvAgentTokenSecret),
+	}
+	clientLimits, clientQuotas := proxyAgentLimits(cfg)
+	proxy.rateLimiter.SetClientLimits(clientLimits)
+	proxy.quotas.SetQuotas(cfg.JsonRpcProxy.DailyQuota, clientQuotas)
+	if cfg.JsonRpcProxy.Cache.Enabled {
+		proxy.cache = rpccache.NewCache(
+			cfg.JsonRpcProxy.Cache.Size, time.Duration(cfg.JsonRpcProxy.Cache.TTLSeconds)*time.Second,
+		)
+	}
+	if cfg.Egress.Enable {
+		proxy.egressProxy = NewEgressProxy(cfg.Egress, proxy.findAgentFromRemoteAddr)
+	}
+	return proxy, nil
 }
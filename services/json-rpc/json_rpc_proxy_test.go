@@ -0,0 +1,51 @@
+package json_rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyJsonRpcConfig(t *testing.T) {
+	r := require.New(t)
+
+	var cfg config.Config
+	cfg.Scan.JsonRpc = config.JsonRpcConfig{Url: "https://scan.example.com", Headers: map[string]string{"X-Key": "scan"}}
+	cfg.Scan.Cache.Enabled = true
+	cfg.JsonRpcProxy.JsonRpc.Headers = map[string]string{"X-Agent": "proxy"}
+
+	// the proxy headers apply without the proxy url
+	jCfg := proxyJsonRpcConfig(cfg)
+	r.Equal("https://scan.example.com", jCfg.Url)
+	r.Equal(map[string]string{"X-Key": "scan", "X-Agent": "proxy"}, jCfg.Headers)
+	r.Equal(map[string]string{"X-Key": "scan"}, cfg.Scan.JsonRpc.Headers)
+	r.True(useScannerCache(cfg))
+
+	cfg.JsonRpcProxy.JsonRpc.Url = "https://proxy.example.com"
+	jCfg = proxyJsonRpcConfig(cfg)
+	r.Equal("https://proxy.example.com", jCfg.Url)
+	r.Equal(map[string]string{"X-Agent": "proxy"}, jCfg.Headers)
+	r.False(useScannerCache(cfg))
+}
+
+func TestJsonRpcProxy_Preflight(t *testing.T) {
+	r := require.New(t)
+
+	p := &JsonRpcProxy{guard: NewRequestGuard(testRestrictions())}
+	handler := p.corsHandler(p.metricHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Fatal("preflight request should not be proxied")
+	})))
+
+	// the preflight requests are answered before the agent and the request checks
+	req := httptest.NewRequest(http.MethodOptions, "http://localhost:8545", nil)
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	r.Equal(http.StatusOK, w.Code)
+	r.Equal("*", w.Header().Get("Access-Control-Allow-Origin"))
+	r.Equal(0, p.guard.Violations())
+}
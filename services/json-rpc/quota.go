@@ -0,0 +1,79 @@
+package json_rpc
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuotaTracker counts the requests of the clients per UTC day and tells if a client used up its quota.
+type QuotaTracker struct {
+	quota        int
+	clientQuotas map[string]int
+	day          string
+	usage        map[string]int
+	exceeded     int
+	now          func() time.Time
+	mu           sync.Mutex
+}
+
+// NewQuotaTracker creates a new tracker with the default quota. The quota is unlimited if it is zero.
+func NewQuotaTracker(quota int) *QuotaTracker {
+	return &QuotaTracker{
+		quota:        quota,
+		clientQuotas: make(map[string]int),
+		usage:        make(map[string]int),
+		now:          time.Now,
+	}
+}
+
+// SetQuotas changes the default quota and the quotas of the clients without resetting the usage.
+func (qt *QuotaTracker) SetQuotas(quota int, clientQuotas map[string]int) {
+	lowerQuotas := make(map[string]int)
+	for clientID, clientQuota := range clientQuotas {
+		lowerQuotas[strings.ToLower(clientID)] = clientQuota
+	}
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	qt.quota = quota
+	qt.clientQuotas = lowerQuotas
+}
+
+// Use counts a request of the client and returns false if the client has used up its quota.
+func (qt *QuotaTracker) Use(clientID string) bool {
+	clientID = strings.ToLower(clientID)
+
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	if day := qt.now().UTC().Format("2006-01-02"); day != qt.day {
+		qt.day = day
+		qt.usage = make(map[string]int)
+	}
+	quota := qt.quota
+	if clientQuota, ok := qt.clientQuotas[clientID]; ok {
+		quota = clientQuota
+	}
+	if quota > 0 && qt.usage[clientID] >= quota {
+		qt.exceeded++
+		return false
+	}
+	qt.usage[clientID]++
+	return true
+}
+
+// Usage returns the number of the requests of the client today.
+func (qt *QuotaTracker) Usage(clientID string) int {
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	if qt.now().UTC().Format("2006-01-02") != qt.day {
+		return 0
+	}
+	return qt.usage[strings.ToLower(clientID)]
+}
+
+// Exceeded returns the number of the requests which were rejected because of the quotas.
+func (qt *QuotaTracker) Exceeded() int {
+	qt.mu.Lock()
+	defer qt.mu.Unlock()
+	return qt.exceeded
+}
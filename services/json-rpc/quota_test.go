@@ -0,0 +1,34 @@
+package json_rpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaTracker(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Date(2022, 5, 10, 23, 59, 0, 0, time.UTC)
+	qt := NewQuotaTracker(2)
+	qt.now = func() time.Time { return now }
+	qt.SetQuotas(2, map[string]int{"0xUnlimited": 0, "0xSmall": 1})
+
+	r.True(qt.Use("0xagent"))
+	r.True(qt.Use("0xAgent"))
+	r.False(qt.Use("0xagent"))
+	r.Equal(2, qt.Usage("0xagent"))
+
+	r.True(qt.Use("0xsmall"))
+	r.False(qt.Use("0xsmall"))
+	for i := 0; i < 5; i++ {
+		r.True(qt.Use("0xunlimited"))
+	}
+	r.Equal(2, qt.Exceeded())
+
+	// the usage starts over on the next day
+	now = now.Add(time.Minute)
+	r.Equal(0, qt.Usage("0xagent"))
+	r.True(qt.Use("0xagent"))
+}
@@ -1,9 +1,11 @@
 package json_rpc
 
 import (
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/forta-network/forta-node/config"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 )
@@ -12,6 +14,7 @@ import (
 type RateLimiter struct {
 	rate           float64
 	burst          int
+	clientLimits   map[string]*config.RateLimitConfig
 	clientLimiters map[string]*clientLimiter
 	mu             sync.Mutex
 }
@@ -42,13 +45,49 @@ func (rl *RateLimiter) ExceedsLimit(clientID string) bool {
 	defer rl.mu.Unlock()
 	limiter := rl.clientLimiters[clientID]
 	if limiter == nil {
-		limiter = &clientLimiter{Limiter: rate.NewLimiter(rate.Limit(rl.rate), rl.burst)}
+		rateN, burst := rl.rate, rl.burst
+		if limit, ok := rl.clientLimits[strings.ToLower(clientID)]; ok {
+			rateN, burst = limit.Rate, limit.Burst
+		}
+		limiter = &clientLimiter{Limiter: rate.NewLimiter(rate.Limit(rateN), burst)}
 		rl.clientLimiters[clientID] = limiter
 	}
 	limiter.lastReservation = time.Now()
 	return !limiter.Allow()
 }
 
+// SetLimit changes the rate and the burst. The limits of the clients start over.
+func (rl *RateLimiter) SetLimit(rateN float64, burst int) {
+	if rateN <= 0 {
+		log.Warn("ignoring non-positive rate limiter arg")
+		return
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.rate == rateN && rl.burst == burst {
+		return
+	}
+	rl.rate = rateN
+	rl.burst = burst
+	rl.clientLimiters = make(map[string]*clientLimiter)
+}
+
+// SetClientLimits overrides the rate and the burst of the clients. The limits of the clients start over.
+func (rl *RateLimiter) SetClientLimits(limits map[string]*config.RateLimitConfig) {
+	clientLimits := make(map[string]*config.RateLimitConfig)
+	for clientID, limit := range limits {
+		if limit.Rate <= 0 {
+			log.WithField("client", clientID).Warn("ignoring non-positive client rate limiter arg")
+			continue
+		}
+		clientLimits[strings.ToLower(clientID)] = limit
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.clientLimits = clientLimits
+	rl.clientLimiters = make(map[string]*clientLimiter)
+}
+
 // deallocate inactive limiters
 func (rl *RateLimiter) autoCleanup() {
 	ticker := time.NewTicker(time.Hour)
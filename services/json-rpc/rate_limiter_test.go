@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/forta-network/forta-node/config"
 	json_rpc "github.com/forta-network/forta-node/services/json-rpc"
 	"github.com/stretchr/testify/require"
 )
@@ -22,3 +23,29 @@ func TestRateLimiting(t *testing.T) {
 	reachedLimit = rateLimiter.ExceedsLimit(testClientID)
 	r.False(reachedLimit)
 }
+
+func TestRateLimiting_SetLimit(t *testing.T) {
+	r := require.New(t)
+	rateLimiter := json_rpc.NewRateLimiter(0.5, 1)
+	r.False(rateLimiter.ExceedsLimit(testClientID))
+	r.True(rateLimiter.ExceedsLimit(testClientID))
+
+	rateLimiter.SetLimit(0.5, 2)
+	r.False(rateLimiter.ExceedsLimit(testClientID))
+	r.False(rateLimiter.ExceedsLimit(testClientID))
+	r.True(rateLimiter.ExceedsLimit(testClientID))
+}
+
+func TestRateLimiting_SetClientLimits(t *testing.T) {
+	r := require.New(t)
+	rateLimiter := json_rpc.NewRateLimiter(0.5, 1)
+	rateLimiter.SetClientLimits(map[string]*config.RateLimitConfig{
+		"0xAgent": {Rate: 0.5, Burst: 2},
+	})
+	r.False(rateLimiter.ExceedsLimit("0xagent"))
+	r.False(rateLimiter.ExceedsLimit("0xagent"))
+	r.True(rateLimiter.ExceedsLimit("0xagent"))
+
+	r.False(rateLimiter.ExceedsLimit(testClientID))
+	r.True(rateLimiter.ExceedsLimit(testClientID))
+}
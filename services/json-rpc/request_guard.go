@@ -0,0 +1,263 @@
+package json_rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// DefaultAllowedMethods are the read-only methods which the agents can call when the allow-list is not
+// configured.
+var DefaultAllowedMethods = []string{
+	"eth_blockNumber",
+	"eth_call",
+	"eth_chainId",
+	"eth_estimateGas",
+	"eth_feeHistory",
+	"eth_gasPrice",
+	"eth_getBalance",
+	"eth_getBlockByHash",
+	"eth_getBlockByNumber",
+	"eth_getBlockReceipts",
+	"eth_getBlockTransactionCountByHash",
+	"eth_getBlockTransactionCountByNumber",
+	"eth_getCode",
+	"eth_getLogs",
+	"eth_getProof",
+	"eth_getStorageAt",
+	"eth_getTransactionByBlockHashAndIndex",
+	"eth_getTransactionByBlockNumberAndIndex",
+	"eth_getTransactionByHash",
+	"eth_getTransactionCount",
+	"eth_getTransactionReceipt",
+	"eth_getUncleByBlockHashAndIndex",
+	"eth_getUncleByBlockNumberAndIndex",
+	"eth_getUncleCountByBlockHash",
+	"eth_getUncleCountByBlockNumber",
+	"eth_maxPriorityFeePerGas",
+	"eth_protocolVersion",
+	"eth_syncing",
+	"net_listening",
+	"net_peerCount",
+	"net_version",
+	"web3_clientVersion",
+	"debug_traceBlockByHash",
+	"debug_traceBlockByNumber",
+	"debug_traceCall",
+	"debug_traceTransaction",
+	"trace_block",
+	"trace_call",
+	"trace_filter",
+	"trace_replayBlockTransactions",
+	"trace_replayTransaction",
+	"trace_transaction",
+}
+
+// Violation kinds
+const (
+	ViolationMethod       = "method"
+	ViolationRequestSize  = "request.size"
+	ViolationResponseSize = "response.size"
+	ViolationConcurrency  = "concurrency"
+)
+
+// Violation is a request which the proxy restrictions do not allow.
+type Violation struct {
+	Kind    string
+	Details string
+}
+
+func (v *Violation) Error() string {
+	return v.Details
+}
+
+// RequestGuard restricts the methods, the sizes and the concurrency of the requests of the clients.
+type RequestGuard struct {
+	cfg        config.JsonRpcRestrictionsConfig
+	allowed    map[string]bool
+	inFlight   map[string]int
+	violations int
+	mu         sync.Mutex
+}
+
+// NewRequestGuard creates a new request guard.
+func NewRequestGuard(cfg config.JsonRpcRestrictionsConfig) *RequestGuard {
+	guard := &RequestGuard{
+		inFlight: make(map[string]int),
+	}
+	guard.SetConfig(cfg)
+	return guard
+}
+
+// SetConfig changes the restrictions without affecting the requests in flight.
+func (guard *RequestGuard) SetConfig(cfg config.JsonRpcRestrictionsConfig) {
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultAllowedMethods
+	}
+	allowed := make(map[string]bool)
+	for _, method := range methods {
+		allowed[method] = true
+	}
+
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	guard.cfg = cfg
+	guard.allowed = allowed
+}
+
+func (guard *RequestGuard) violation(kind, format string, args ...interface{}) *Violation {
+	guard.mu.Lock()
+	guard.violations++
+	guard.mu.Unlock()
+	return &Violation{Kind: kind, Details: fmt.Sprintf(format, args...)}
+}
+
+// Violations returns the number of the violations so far.
+func (guard *RequestGuard) Violations() int {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	return guard.violations
+}
+
+// CheckRequest checks the size of the request body and the methods of the single or batch request in it.
+// The body is left readable for the next handlers. The OPTIONS requests don't have json-rpc bodies.
+func (guard *RequestGuard) CheckRequest(req *http.Request) *Violation {
+	guard.mu.Lock()
+	cfg, allowed := guard.cfg, guard.allowed
+	guard.mu.Unlock()
+	if cfg.Disable || req.Method == http.MethodOptions {
+		return nil
+	}
+
+	maxSize := int64(cfg.MaxRequestSizeKB) * 1024
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return guard.violation(ViolationRequestSize, "failed to read the request: %v", err)
+	}
+	if int64(len(body)) > maxSize {
+		return guard.violation(ViolationRequestSize, "request exceeds the size limit of %d KB", cfg.MaxRequestSizeKB)
+	}
+	methods, err := requestMethods(body)
+	if err != nil {
+		return guard.violation(ViolationMethod, "invalid json-rpc request: %v", err)
+	}
+	for _, method := range methods {
+		if !allowed[method] {
+			return guard.violation(ViolationMethod, "method not allowed: %s", method)
+		}
+	}
+	return nil
+}
+
+func requestMethods(body []byte) ([]string, error) {
+	type methodPayload struct {
+		Method string `json:"method"`
+	}
+	var payloads []*methodPayload
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &payloads); err != nil {
+			return nil, err
+		}
+	} else {
+		var payload methodPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, &payload)
+	}
+	methods := make([]string, 0, len(payloads))
+	for _, payload := range payloads {
+		methods = append(methods, payload.Method)
+	}
+	return methods, nil
+}
+
+// Acquire takes a concurrency slot of the client. The slot should be released after the request is done.
+func (guard *RequestGuard) Acquire(clientID string) *Violation {
+	clientID = strings.ToLower(clientID)
+
+	guard.mu.Lock()
+	if guard.cfg.Disable {
+		guard.mu.Unlock()
+		return nil
+	}
+	if guard.inFlight[clientID] >= guard.cfg.MaxConcurrentRequests {
+		maxConcurrent := guard.cfg.MaxConcurrentRequests
+		guard.mu.Unlock()
+		return guard.violation(ViolationConcurrency, "exceeds the limit of %d concurrent requests", maxConcurrent)
+	}
+	guard.inFlight[clientID]++
+	guard.mu.Unlock()
+	return nil
+}
+
+// Release releases a concurrency slot of the client.
+func (guard *RequestGuard) Release(clientID string) {
+	clientID = strings.ToLower(clientID)
+
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	if guard.inFlight[clientID] <= 1 {
+		delete(guard.inFlight, clientID)
+		return
+	}
+	guard.inFlight[clientID]--
+}
+
+// CheckResponse fails the responses which exceed the size limit. The responses with an unknown length
+// fail while they are read.
+func (guard *RequestGuard) CheckResponse(resp *http.Response, onViolation func(*Violation)) error {
+	guard.mu.Lock()
+	cfg := guard.cfg
+	guard.mu.Unlock()
+	if cfg.Disable {
+		return nil
+	}
+
+	maxSize := int64(cfg.MaxResponseSizeKB) * 1024
+	if resp.ContentLength > maxSize {
+		v := guard.violation(ViolationResponseSize, "response exceeds the size limit of %d KB", cfg.MaxResponseSizeKB)
+		onViolation(v)
+		return v
+	}
+	resp.Body = &limitedBody{
+		ReadCloser: resp.Body,
+		remaining:  maxSize,
+		onExceed: func() error {
+			v := guard.violation(ViolationResponseSize, "response exceeds the size limit of %d KB", cfg.MaxResponseSizeKB)
+			onViolation(v)
+			return v
+		},
+	}
+	return nil
+}
+
+// limitedBody fails the reads after the size limit.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	onExceed  func() error
+	err       error
+}
+
+func (body *limitedBody) Read(p []byte) (int, error) {
+	if body.err != nil {
+		return 0, body.err
+	}
+	n, err := body.ReadCloser.Read(p)
+	body.remaining -= int64(n)
+	if body.remaining < 0 {
+		body.err = body.onExceed()
+		return n, body.err
+	}
+	return n, err
+}
@@ -0,0 +1,115 @@
+package json_rpc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testRestrictions() config.JsonRpcRestrictionsConfig {
+	return config.JsonRpcRestrictionsConfig{
+		MaxRequestSizeKB:      1,
+		MaxResponseSizeKB:     1,
+		MaxConcurrentRequests: 2,
+	}
+}
+
+func testRequest(body string) *http.Request {
+	req, _ := http.NewRequest("POST", "http://asdf.asdf", bytes.NewBufferString(body))
+	return req
+}
+
+func TestRequestGuard_CheckRequest(t *testing.T) {
+	r := require.New(t)
+
+	guard := NewRequestGuard(testRestrictions())
+
+	req := testRequest(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+	r.Nil(guard.CheckRequest(req))
+	body, err := ioutil.ReadAll(req.Body)
+	r.NoError(err)
+	r.Contains(string(body), "eth_blockNumber")
+
+	v := guard.CheckRequest(testRequest(`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x"]}`))
+	r.NotNil(v)
+	r.Equal(ViolationMethod, v.Kind)
+
+	v = guard.CheckRequest(testRequest(`[{"id":1,"method":"eth_call"},{"id":2,"method":"personal_unlockAccount"}]`))
+	r.NotNil(v)
+	r.Equal(ViolationMethod, v.Kind)
+
+	v = guard.CheckRequest(testRequest("not json"))
+	r.NotNil(v)
+	r.Equal(ViolationMethod, v.Kind)
+
+	v = guard.CheckRequest(testRequest(`{"id":1,"method":"eth_call","params":["` + strings.Repeat("a", 1024) + `"]}`))
+	r.NotNil(v)
+	r.Equal(ViolationRequestSize, v.Kind)
+
+	options, _ := http.NewRequest(http.MethodOptions, "http://asdf.asdf", nil)
+	r.Nil(guard.CheckRequest(options))
+
+	r.Equal(4, guard.Violations())
+
+	guard.SetConfig(config.JsonRpcRestrictionsConfig{
+		AllowedMethods:        []string{"eth_sendRawTransaction"},
+		MaxRequestSizeKB:      1,
+		MaxResponseSizeKB:     1,
+		MaxConcurrentRequests: 1,
+	})
+	r.Nil(guard.CheckRequest(testRequest(`{"id":1,"method":"eth_sendRawTransaction"}`)))
+	r.NotNil(guard.CheckRequest(testRequest(`{"id":1,"method":"eth_blockNumber"}`)))
+
+	guard.SetConfig(config.JsonRpcRestrictionsConfig{Disable: true})
+	r.Nil(guard.CheckRequest(testRequest(`{"id":1,"method":"personal_unlockAccount"}`)))
+}
+
+func TestRequestGuard_Concurrency(t *testing.T) {
+	r := require.New(t)
+
+	guard := NewRequestGuard(testRestrictions())
+
+	r.Nil(guard.Acquire("0xAGENT"))
+	r.Nil(guard.Acquire("0xagent"))
+	v := guard.Acquire("0xagent")
+	r.NotNil(v)
+	r.Equal(ViolationConcurrency, v.Kind)
+	r.Nil(guard.Acquire("0xother"))
+
+	guard.Release("0xagent")
+	r.Nil(guard.Acquire("0xagent"))
+}
+
+func TestRequestGuard_CheckResponse(t *testing.T) {
+	r := require.New(t)
+
+	guard := NewRequestGuard(testRestrictions())
+	var violations []*Violation
+	onViolation := func(v *Violation) {
+		violations = append(violations, v)
+	}
+
+	small := []byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+	resp := &http.Response{ContentLength: int64(len(small)), Body: ioutil.NopCloser(bytes.NewReader(small))}
+	r.NoError(guard.CheckResponse(resp, onViolation))
+	body, err := ioutil.ReadAll(resp.Body)
+	r.NoError(err)
+	r.Equal(small, body)
+
+	large := bytes.Repeat([]byte("a"), 2048)
+	resp = &http.Response{ContentLength: int64(len(large)), Body: ioutil.NopCloser(bytes.NewReader(large))}
+	r.Error(guard.CheckResponse(resp, onViolation))
+	r.Len(violations, 1)
+
+	resp = &http.Response{ContentLength: -1, Body: ioutil.NopCloser(bytes.NewReader(large))}
+	r.NoError(guard.CheckResponse(resp, onViolation))
+	_, err = ioutil.ReadAll(resp.Body)
+	r.Error(err)
+	r.Len(violations, 2)
+	r.Equal(ViolationResponseSize, violations[1].Kind)
+}
@@ -0,0 +1,253 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/metrics"
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+	log "github.com/sirupsen/logrus"
+)
+
+// AgentSLA is the service level of an agent over the report window.
+type AgentSLA struct {
+	AgentID      string  `json:"agentId"`
+	Uptime       float64 `json:"uptime"`
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+	TimeoutRatio float64 `json:"timeoutRatio"`
+	ErrorRatio   float64 `json:"errorRatio"`
+	Requests     uint64  `json:"requests"`
+}
+
+// AgentSLAResponse is the response of the agent SLA report. The agents with the most timeouts and errors
+// come first.
+type AgentSLAResponse struct {
+	WindowMinutes int         `json:"windowMinutes"`
+	Agents        []*AgentSLA `json:"agents"`
+}
+
+// slaBucket has the counters of an agent for a minute.
+type slaBucket struct {
+	responses uint64
+	errors    uint64
+	timeouts  uint64
+	latencyMs float64
+}
+
+// AgentSLATracker keeps the per-minute counters of the agents from the agent metrics.
+type AgentSLATracker struct {
+	window  time.Duration
+	buckets map[string]map[int64]*slaBucket // agent ID -> bucket minute -> counters
+	mu      sync.Mutex
+}
+
+// NewAgentSLATracker creates a new tracker which keeps the counters for the window.
+func NewAgentSLATracker(window time.Duration) *AgentSLATracker {
+	return &AgentSLATracker{
+		window:  window,
+		buckets: make(map[string]map[int64]*slaBucket),
+	}
+}
+
+func bucketMinute(t time.Time) int64 {
+	return t.Unix() / 60
+}
+
+// AddAgentMetrics counts the responses, the errors and the timeouts of the agents.
+func (tracker *AgentSLATracker) AddAgentMetrics(ms *protocol.AgentMetricList) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	for _, m := range ms.Metrics {
+		t, err := time.Parse(time.RFC3339, m.Timestamp)
+		if err != nil {
+			continue
+		}
+		bucket := tracker.findBucket(m.AgentId, t)
+		switch m.Name {
+		case metrics.MetricTxRequest, metrics.MetricBlockRequest:
+			bucket.responses += uint64(m.Value)
+		case metrics.MetricTxError, metrics.MetricBlockError:
+			bucket.errors += uint64(m.Value)
+		case metrics.MetricTxTimeout, metrics.MetricBlockTimeout:
+			bucket.timeouts += uint64(m.Value)
+		case metrics.MetricTxLatency, metrics.MetricBlockLatency:
+			bucket.latencyMs += m.Value
+		}
+	}
+	tracker.prune(time.Now())
+	return nil
+}
+
+func (tracker *AgentSLATracker) findBucket(agentID string, t time.Time) *slaBucket {
+	agentBuckets, ok := tracker.buckets[agentID]
+	if !ok {
+		agentBuckets = make(map[int64]*slaBucket)
+		tracker.buckets[agentID] = agentBuckets
+	}
+	minute := bucketMinute(t)
+	bucket, ok := agentBuckets[minute]
+	if !ok {
+		bucket = &slaBucket{}
+		agentBuckets[minute] = bucket
+	}
+	return bucket
+}
+
+// prune removes the buckets which are out of the window.
+func (tracker *AgentSLATracker) prune(now time.Time) {
+	oldest := bucketMinute(now.Add(-tracker.window))
+	for agentID, agentBuckets := range tracker.buckets {
+		for minute := range agentBuckets {
+			if minute < oldest {
+				delete(agentBuckets, minute)
+			}
+		}
+		if len(agentBuckets) == 0 {
+			delete(tracker.buckets, agentID)
+		}
+	}
+}
+
+// Report computes the SLA of the agents over the window until now. The uptime is the ratio of the minutes
+// which the agent responded in to the minutes since the agent was first seen in the window.
+func (tracker *AgentSLATracker) Report(window time.Duration, now time.Time) []*AgentSLA {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if window > tracker.window {
+		window = tracker.window
+	}
+	oldest := bucketMinute(now.Add(-window))
+	current := bucketMinute(now)
+	agents := make([]*AgentSLA, 0, len(tracker.buckets))
+	for agentID, agentBuckets := range tracker.buckets {
+		var (
+			total     slaBucket
+			firstSeen = current + 1
+			upMinutes int64
+		)
+		for minute, bucket := range agentBuckets {
+			if minute < oldest || minute > current {
+				continue
+			}
+			if minute < firstSeen {
+				firstSeen = minute
+			}
+			if bucket.responses > 0 {
+				upMinutes++
+			}
+			total.responses += bucket.responses
+			total.errors += bucket.errors
+			total.timeouts += bucket.timeouts
+			total.latencyMs += bucket.latencyMs
+		}
+		if firstSeen > current {
+			continue
+		}
+		sla := &AgentSLA{
+			AgentID:  agentID,
+			Uptime:   float64(upMinutes) / float64(current-firstSeen+1),
+			Requests: total.responses + total.timeouts,
+		}
+		if total.responses > 0 {
+			sla.AvgLatencyMs = total.latencyMs / float64(total.responses)
+		}
+		if sla.Requests > 0 {
+			sla.TimeoutRatio = float64(total.timeouts) / float64(sla.Requests)
+			sla.ErrorRatio = float64(total.errors) / float64(sla.Requests)
+		}
+		agents = append(agents, sla)
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		failuresI := agents[i].TimeoutRatio + agents[i].ErrorRatio
+		failuresJ := agents[j].TimeoutRatio + agents[j].ErrorRatio
+		if failuresI != failuresJ {
+			return failuresI > failuresJ
+		}
+		return agents[i].AgentID < agents[j].AgentID
+	})
+	return agents
+}
+
+// AgentSLAAPI serves the agent SLA report.
+type AgentSLAAPI struct {
+	ctx       context.Context
+	cfg       config.AgentSLAConfig
+	msgClient clients.MessageClient
+	tracker   *AgentSLATracker
+	server    *http.Server
+}
+
+// NewAgentSLAAPI creates the API which tracks the agent metrics from the message client.
+func NewAgentSLAAPI(ctx context.Context, cfg config.AgentSLAConfig, msgClient clients.MessageClient) *AgentSLAAPI {
+	return &AgentSLAAPI{
+		ctx:       ctx,
+		cfg:       cfg,
+		msgClient: msgClient,
+		tracker:   NewAgentSLATracker(time.Duration(cfg.WindowMinutes) * time.Minute),
+	}
+}
+
+func (api *AgentSLAAPI) getAgentsSLA(w http.ResponseWriter, r *http.Request) {
+	windowMinutes := api.cfg.WindowMinutes
+	if s := r.URL.Query().Get("windowMinutes"); len(s) > 0 {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > api.cfg.WindowMinutes {
+			writeIncidentsError(w, 400, fmt.Sprintf("?windowMinutes must be an integer between 1 and %d", api.cfg.WindowMinutes))
+			return
+		}
+		windowMinutes = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&AgentSLAResponse{
+		WindowMinutes: windowMinutes,
+		Agents:        api.tracker.Report(time.Duration(windowMinutes)*time.Minute, time.Now()),
+	}); err != nil {
+		log.WithError(err).Error("error writing agent sla report")
+	}
+}
+
+func (api *AgentSLAAPI) Start() error {
+	api.msgClient.Subscribe(messaging.SubjectMetricAgent, messaging.AgentMetricHandler(api.tracker.AddAgentMetrics))
+
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/report/agents/sla", api.getAgentsSLA).Methods(http.MethodGet)
+
+	c := cors.New(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+	})
+
+	api.server = &http.Server{
+		Addr:    fmt.Sprintf(":%s", config.DefaultAgentSLAPort),
+		Handler: c.Handler(router),
+	}
+	utils.GoListenAndServe(api.server)
+	return nil
+}
+
+func (api *AgentSLAAPI) Stop() error {
+	log.Infof("Stopping %s", api.Name())
+	if api.server != nil {
+		return api.server.Close()
+	}
+	return nil
+}
+
+func (api *AgentSLAAPI) Name() string {
+	return "agent-sla-api"
+}
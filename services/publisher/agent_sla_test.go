@@ -0,0 +1,60 @@
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func testSLAMetric(agentID, name string, value float64, t time.Time) *protocol.AgentMetric {
+	return &protocol.AgentMetric{AgentId: agentID, Name: name, Value: value, Timestamp: t.Format(time.RFC3339)}
+}
+
+func TestAgentSLATracker(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now().UTC().Truncate(time.Minute).Add(time.Second * 30)
+	tracker := NewAgentSLATracker(time.Hour)
+	r.NoError(tracker.AddAgentMetrics(&protocol.AgentMetricList{Metrics: []*protocol.AgentMetric{
+		// agent-1 responded in both minutes and had one error
+		testSLAMetric("agent-1", metrics.MetricTxRequest, 1, now.Add(-time.Minute)),
+		testSLAMetric("agent-1", metrics.MetricTxLatency, 100, now.Add(-time.Minute)),
+		testSLAMetric("agent-1", metrics.MetricBlockRequest, 1, now),
+		testSLAMetric("agent-1", metrics.MetricBlockLatency, 300, now),
+		testSLAMetric("agent-1", metrics.MetricBlockError, 1, now),
+		// agent-2 timed out in the first minute
+		testSLAMetric("agent-2", metrics.MetricTxTimeout, 1, now.Add(-time.Minute)),
+		testSLAMetric("agent-2", metrics.MetricTxRequest, 1, now),
+		testSLAMetric("agent-2", metrics.MetricTxLatency, 50, now),
+		// out of the window
+		testSLAMetric("agent-3", metrics.MetricTxRequest, 1, now.Add(-time.Hour*2)),
+	}}))
+
+	agents := tracker.Report(time.Hour, now)
+	r.Len(agents, 2)
+
+	// the agents with the same failure ratios are sorted by ID
+	r.Equal("agent-1", agents[0].AgentID)
+	r.Equal(float64(1), agents[0].Uptime)
+	r.Equal(float64(200), agents[0].AvgLatencyMs)
+	r.Equal(float64(0), agents[0].TimeoutRatio)
+	r.Equal(0.5, agents[0].ErrorRatio)
+
+	r.Equal("agent-2", agents[1].AgentID)
+	r.Equal(0.5, agents[1].Uptime)
+	r.Equal(float64(50), agents[1].AvgLatencyMs)
+	r.Equal(0.5, agents[1].TimeoutRatio)
+	r.Equal(float64(0), agents[1].ErrorRatio)
+	r.Equal(uint64(2), agents[1].Requests)
+
+	// only the last minute
+	agents = tracker.Report(time.Second, now)
+	r.Len(agents, 2)
+	r.Equal("agent-1", agents[0].AgentID)
+	r.Equal(float64(1), agents[0].ErrorRatio)
+	r.Equal("agent-2", agents[1].AgentID)
+	r.Equal(float64(1), agents[1].Uptime)
+}
@@ -0,0 +1,86 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+	log "github.com/sirupsen/logrus"
+)
+
+// AlertSource provides the latest alerts.
+type AlertSource interface {
+	Alerts(agentID string, limit int) []*protocol.SignedAlert
+}
+
+// AlertsAPI serves the alerts which the dev process keeps instead of publishing.
+type AlertsAPI struct {
+	ctx    context.Context
+	source AlertSource
+	server *http.Server
+}
+
+// listAlerts returns the most recent alerts first. The alerts can be filtered by the agent ID.
+func (api *AlertsAPI) listAlerts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := -1
+	if s := query.Get("limit"); len(s) > 0 {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeIncidentsError(w, 400, "?limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	alerts := api.source.Alerts(query.Get("agentId"), limit)
+	if err := json.NewEncoder(w).Encode(&protocol.AlertResponse{Alerts: alerts}); err != nil {
+		log.WithError(err).Error("error writing alerts")
+	}
+}
+
+func (api *AlertsAPI) Start() error {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/alerts", api.listAlerts).Methods(http.MethodGet)
+
+	c := cors.New(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+	})
+
+	api.server = &http.Server{
+		Addr:    fmt.Sprintf(":%s", config.DefaultAlertsPort),
+		Handler: c.Handler(router),
+	}
+	utils.GoListenAndServe(api.server)
+	return nil
+}
+
+func (api *AlertsAPI) Stop() error {
+	log.Infof("Stopping %s", api.Name())
+	if api.server != nil {
+		return api.server.Close()
+	}
+	return nil
+}
+
+func (api *AlertsAPI) Name() string {
+	return "alerts-api"
+}
+
+// NewAlertsAPI creates the API which serves the alerts of the source.
+func NewAlertsAPI(ctx context.Context, source AlertSource) *AlertsAPI {
+	return &AlertsAPI{
+		ctx:    ctx,
+		source: source,
+	}
+}
@@ -0,0 +1,188 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	anchorBufferSize      = 100
+	anchorTimeout         = 5 * time.Minute
+	anchorReceiptInterval = 5 * time.Second
+)
+
+const batchAnchorABI = `[{"type":"function","name":"anchor","inputs":[{"name":"batchHash","type":"bytes32"},` +
+	`{"name":"chainId","type":"uint256"},{"name":"blockEnd","type":"uint256"},{"name":"ref","type":"string"}],"outputs":[]}]`
+
+// BatchAnchorContract stores the hashes of the batches on chain.
+type BatchAnchorContract interface {
+	Anchor(opts *bind.TransactOpts, batchHash common.Hash, chainID, blockEnd *big.Int, ref string) (*types.Transaction, error)
+}
+
+type boundAnchorContract struct {
+	bc *bind.BoundContract
+}
+
+func (c *boundAnchorContract) Anchor(opts *bind.TransactOpts, batchHash common.Hash, chainID, blockEnd *big.Int, ref string) (*types.Transaction, error) {
+	return c.bc.Transact(opts, "anchor", batchHash, chainID, blockEnd, ref)
+}
+
+// AnchorChain gets the chain ID and the transaction receipts of the anchor chain.
+type AnchorChain interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	clients.ReceiptBackend
+}
+
+// batchAnchor submits the hashes of the published batches to the anchor contract so the third parties
+// can verify when the node produced a batch. The transactions are signed with the scanner key, so the
+// scanner address needs funds on the anchor chain and the anchor transactions use the nonces of the
+// scanner address. The transactions are sent one at a time and a batch is recorded in the log together
+// with the transaction hash only after the transaction is mined.
+type batchAnchor struct {
+	ctx      context.Context
+	key      *keystore.Key
+	contract BatchAnchorContract
+	chain    AnchorChain
+	gasLimit uint64
+	recordCh chan *BatchRecord
+	log      *batchLog
+	dropped  int64
+
+	opts *bind.TransactOpts
+
+	lastAnchor health.TimeTracker
+	lastErr    health.ErrorTracker
+}
+
+func newBatchAnchor(ctx context.Context, cfg config.AnchorConfig, key *keystore.Key, anchorLog *batchLog) (*batchAnchor, error) {
+	if len(cfg.ContractAddress) == 0 {
+		return nil, errors.New("anchor contract address is required")
+	}
+	rpcClient, err := rpc.DialContext(ctx, cfg.JsonRpc.Url)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range cfg.JsonRpc.Headers {
+		rpcClient.SetHeader(k, v)
+	}
+	ec := ethclient.NewClient(rpcClient)
+	parsed, err := abi.JSON(strings.NewReader(batchAnchorABI))
+	if err != nil {
+		return nil, err
+	}
+	contract := &boundAnchorContract{
+		bc: bind.NewBoundContract(common.HexToAddress(cfg.ContractAddress), parsed, ec, ec, ec),
+	}
+	return &batchAnchor{
+		ctx:      ctx,
+		key:      key,
+		contract: contract,
+		chain:    ec,
+		gasLimit: cfg.GasLimit,
+		recordCh: make(chan *BatchRecord, anchorBufferSize),
+		log:      anchorLog,
+	}, nil
+}
+
+// Anchor queues the batch without blocking. The batch is not anchored if the queue is full.
+func (ba *batchAnchor) Anchor(record *BatchRecord) {
+	select {
+	case ba.recordCh <- record:
+	default:
+		atomic.AddInt64(&ba.dropped, 1)
+		log.WithField("ref", record.Ref).Warn("anchor queue is full - skipping batch")
+	}
+}
+
+func (ba *batchAnchor) transactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	if ba.opts != nil {
+		return ba.opts, nil
+	}
+	chainID, err := ba.chain.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(ba.key.PrivateKey, chainID)
+	if err != nil {
+		return nil, err
+	}
+	opts.GasLimit = ba.gasLimit
+	ba.opts = opts
+	return opts, nil
+}
+
+func (ba *batchAnchor) anchor(record *BatchRecord) error {
+	ctx, cancel := context.WithTimeout(ba.ctx, anchorTimeout)
+	defer cancel()
+
+	opts, err := ba.transactOpts(ctx)
+	if err != nil {
+		return err
+	}
+	txOpts := *opts
+	txOpts.Context = ctx
+	tx, err := ba.contract.Anchor(
+		&txOpts, common.HexToHash(record.Hash),
+		new(big.Int).SetUint64(record.ChainID), new(big.Int).SetUint64(record.BlockEnd), record.Ref,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := clients.WaitForReceipt(ctx, ba.chain, tx.Hash().Hex(), anchorReceiptInterval); err != nil {
+		return err
+	}
+	record.AnchorTx = tx.Hash().Hex()
+	return ba.log.Append(record)
+}
+
+func (ba *batchAnchor) run() {
+	for {
+		select {
+		case <-ba.ctx.Done():
+			return
+		case record := <-ba.recordCh:
+			err := ba.anchor(record)
+			ba.lastErr.Set(err)
+			if err != nil {
+				log.WithError(err).WithField("ref", record.Ref).Warn("failed to anchor batch")
+				continue
+			}
+			ba.lastAnchor.Set()
+			log.WithFields(log.Fields{
+				"ref":      record.Ref,
+				"hash":     record.Hash,
+				"anchorTx": record.AnchorTx,
+			}).Info("anchored batch")
+		}
+	}
+}
+
+// Health implements the health.Reporter interface.
+func (ba *batchAnchor) Health() health.Reports {
+	return health.Reports{
+		ba.lastAnchor.GetReport("event.batch-anchor.time"),
+		ba.lastErr.GetReport("event.batch-anchor.error"),
+		&health.Report{
+			Name:    "event.batch-anchor.dropped.count",
+			Status:  health.StatusInfo,
+			Details: strconv.FormatInt(atomic.LoadInt64(&ba.dropped), 10),
+		},
+	}
+}
@@ -0,0 +1,126 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+type testAnchorContract struct {
+	err       error
+	chainID   *big.Int
+	batchHash common.Hash
+	blockEnd  *big.Int
+	ref       string
+	from      common.Address
+}
+
+func (c *testAnchorContract) Anchor(opts *bind.TransactOpts, batchHash common.Hash, chainID, blockEnd *big.Int, ref string) (*types.Transaction, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.chainID, c.batchHash, c.blockEnd, c.ref, c.from = chainID, batchHash, blockEnd, ref, opts.From
+	return types.NewTx(&types.LegacyTx{Nonce: 1}), nil
+}
+
+type testAnchorChain struct {
+	calls    int
+	reverted bool
+}
+
+func (c *testAnchorChain) ChainID(ctx context.Context) (*big.Int, error) {
+	c.calls++
+	return big.NewInt(137), nil
+}
+
+func (c *testAnchorChain) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if c.reverted {
+		return &types.Receipt{TxHash: txHash, Status: types.ReceiptStatusFailed}, nil
+	}
+	return &types.Receipt{TxHash: txHash, Status: types.ReceiptStatusSuccessful}, nil
+}
+
+func testBatchAnchor(t *testing.T, contract BatchAnchorContract, chain AnchorChain) *batchAnchor {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return &batchAnchor{
+		ctx:      context.Background(),
+		key:      &keystore.Key{PrivateKey: privateKey, Address: crypto.PubkeyToAddress(privateKey.PublicKey)},
+		contract: contract,
+		chain:    chain,
+		recordCh: make(chan *BatchRecord, anchorBufferSize),
+		log:      &batchLog{path: path.Join(t.TempDir(), "anchored-batches.log")},
+	}
+}
+
+func TestBatchAnchor(t *testing.T) {
+	r := require.New(t)
+
+	contract := &testAnchorContract{}
+	chain := &testAnchorChain{}
+	anchor := testBatchAnchor(t, contract, chain)
+
+	hash := crypto.Keccak256Hash([]byte("batch"))
+	r.NoError(anchor.anchor(&BatchRecord{Ref: "ref1", Hash: hash.Hex(), ChainID: 1, BlockEnd: 100}))
+	r.NoError(anchor.anchor(&BatchRecord{Ref: "ref2", Hash: hash.Hex(), ChainID: 1, BlockEnd: 101}))
+	r.Equal(1, chain.calls)
+
+	r.Equal(hash, contract.batchHash)
+	r.Equal(int64(1), contract.chainID.Int64())
+	r.Equal(int64(101), contract.blockEnd.Int64())
+	r.Equal("ref2", contract.ref)
+	r.Equal(anchor.key.Address, contract.from)
+
+	b, err := os.ReadFile(anchor.log.path)
+	r.NoError(err)
+	var record BatchRecord
+	r.NoError(json.NewDecoder(bytes.NewReader(b)).Decode(&record))
+	r.Equal("ref1", record.Ref)
+	r.Equal(types.NewTx(&types.LegacyTx{Nonce: 1}).Hash().Hex(), record.AnchorTx)
+}
+
+func TestBatchAnchor_Error(t *testing.T) {
+	r := require.New(t)
+
+	anchor := testBatchAnchor(t, &testAnchorContract{err: errors.New("insufficient funds")}, &testAnchorChain{})
+	r.Error(anchor.anchor(&BatchRecord{Ref: "ref1"}))
+
+	_, err := os.Stat(anchor.log.path)
+	r.True(os.IsNotExist(err))
+}
+
+func TestBatchAnchor_Reverted(t *testing.T) {
+	r := require.New(t)
+
+	// the reverted transactions are not recorded
+	anchor := testBatchAnchor(t, &testAnchorContract{}, &testAnchorChain{reverted: true})
+	r.Error(anchor.anchor(&BatchRecord{Ref: "ref1"}))
+
+	_, err := os.Stat(anchor.log.path)
+	r.True(os.IsNotExist(err))
+}
+
+func TestBatchAnchor_QueueFull(t *testing.T) {
+	r := require.New(t)
+
+	anchor := testBatchAnchor(t, &testAnchorContract{}, &testAnchorChain{})
+	for i := 0; i < anchorBufferSize+2; i++ {
+		anchor.Anchor(&BatchRecord{Ref: "ref"})
+	}
+
+	report, ok := anchor.Health().GetByName("event.batch-anchor.dropped.count")
+	r.True(ok)
+	r.Equal("2", report.Details)
+}
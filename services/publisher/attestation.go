@@ -0,0 +1,261 @@
+package publisher
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/registry"
+	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+	log "github.com/sirupsen/logrus"
+)
+
+// attestationDomain is prefixed to the signed payload, so that the signature of an attestation cannot be
+// used as the signature of another message of the scanner.
+const attestationDomain = "forta-scanner-attestation:"
+
+// Attestation is the statement of the scanner about what it is scanning. It has only the data which the
+// node generates, since the scanner key should not sign the content from the requests.
+type Attestation struct {
+	Scanner   string           `json:"scanner"`
+	ChainID   int              `json:"chainId"`
+	Version   string           `json:"version"`
+	Agents    []string         `json:"agents"`
+	Chains    []*AttestedChain `json:"chains"`
+	Timestamp string           `json:"timestamp"`
+}
+
+// AttestedChain is the latest block which the scanner received from a chain.
+type AttestedChain struct {
+	ChainID     int64  `json:"chainId"`
+	LatestBlock uint64 `json:"latestBlock"`
+}
+
+// SignedAttestation has the attestation and the signature of the scanner key over the domain prefixed
+// payload. The payload is the JSON encoded attestation.
+type SignedAttestation struct {
+	Attestation *Attestation        `json:"attestation"`
+	Payload     string              `json:"payload"`
+	Signature   *protocol.Signature `json:"signature"`
+}
+
+// Attestation verification errors
+var (
+	ErrAttestationSigner   = errors.New("attestation is not signed by the scanner")
+	ErrAttestationMismatch = errors.New("attestation does not match the payload")
+	ErrAttestationScanner  = errors.New("attestation signer is not a registered scanner of the chain")
+)
+
+// ScannerRegistry looks up the scanners in the registry.
+type ScannerRegistry interface {
+	GetScanner(scannerID string) (*registry.Scanner, error)
+}
+
+func attestationMessage(payload []byte) []byte {
+	return append([]byte(attestationDomain), payload...)
+}
+
+// SignAttestation signs the attestation with the scanner key.
+func SignAttestation(key *keystore.Key, attestation *Attestation) (*SignedAttestation, error) {
+	payload, err := json.Marshal(attestation)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := security.SignBytes(key, attestationMessage(payload))
+	if err != nil {
+		return nil, err
+	}
+	return &SignedAttestation{
+		Attestation: attestation,
+		Payload:     string(payload),
+		Signature:   signature,
+	}, nil
+}
+
+// VerifyAttestation verifies that the payload is signed by the scanner which it attests for and that the
+// scanner is registered for the attested chain.
+func VerifyAttestation(signed *SignedAttestation, scannerRegistry ScannerRegistry) (*Attestation, error) {
+	if signed.Signature == nil {
+		return nil, security.ErrMissingSignature
+	}
+	var attestation Attestation
+	if err := json.Unmarshal([]byte(signed.Payload), &attestation); err != nil {
+		return nil, fmt.Errorf("invalid attestation payload: %v", err)
+	}
+	if attestation.Scanner != signed.Signature.Signer {
+		return nil, ErrAttestationSigner
+	}
+	if err := security.VerifySignature(attestationMessage([]byte(signed.Payload)), signed.Signature.Signer, signed.Signature.Signature); err != nil {
+		return nil, err
+	}
+	if signed.Attestation != nil {
+		b, _ := json.Marshal(signed.Attestation)
+		if string(b) != signed.Payload {
+			return nil, ErrAttestationMismatch
+		}
+	}
+	scanner, err := scannerRegistry.GetScanner(attestation.Scanner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the scanner from the registry: %v", err)
+	}
+	if scanner == nil || scanner.ChainID != int64(attestation.ChainID) {
+		return nil, ErrAttestationScanner
+	}
+	return &attestation, nil
+}
+
+// AttestationAPI serves the signed attestation of the scanner.
+type AttestationAPI struct {
+	ctx       context.Context
+	cfg       config.Config
+	key       *keystore.Key
+	msgClient clients.MessageClient
+	server    *http.Server
+
+	agents       map[string]bool
+	latestBlocks map[int64]uint64
+	mu           sync.RWMutex
+}
+
+// NewAttestationAPI creates the API which tracks the running agents and the latest blocks from the
+// message client.
+func NewAttestationAPI(ctx context.Context, cfg config.Config, key *keystore.Key, msgClient clients.MessageClient) *AttestationAPI {
+	return &AttestationAPI{
+		ctx:          ctx,
+		cfg:          cfg,
+		key:          key,
+		msgClient:    msgClient,
+		agents:       make(map[string]bool),
+		latestBlocks: make(map[int64]uint64),
+	}
+}
+
+func (api *AttestationAPI) handleAgentsRunning(payload messaging.AgentPayload) error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	for _, agent := range payload {
+		api.agents[agent.ID] = true
+	}
+	return nil
+}
+
+func (api *AttestationAPI) handleAgentsStopped(payload messaging.AgentPayload) error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	for _, agent := range payload {
+		delete(api.agents, agent.ID)
+	}
+	return nil
+}
+
+func (api *AttestationAPI) handleScannerBlock(payload messaging.ScannerPayload) error {
+	chainID := int64(payload.ChainID)
+	if chainID == 0 {
+		chainID = int64(api.cfg.ChainID)
+	}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if payload.LatestBlockInput > api.latestBlocks[chainID] {
+		api.latestBlocks[chainID] = payload.LatestBlockInput
+	}
+	return nil
+}
+
+// attestation creates the attestation of the current state.
+func (api *AttestationAPI) attestation(now time.Time) *Attestation {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+
+	attestation := &Attestation{
+		Scanner:   api.key.Address.Hex(),
+		ChainID:   api.cfg.ChainID,
+		Version:   config.Version,
+		Agents:    make([]string, 0, len(api.agents)),
+		Timestamp: now.UTC().Format(time.RFC3339),
+	}
+	for agentID := range api.agents {
+		attestation.Agents = append(attestation.Agents, agentID)
+	}
+	sort.Strings(attestation.Agents)
+	for _, chainID := range api.cfg.ScannedChainIDs() {
+		attestation.Chains = append(attestation.Chains, &AttestedChain{
+			ChainID:     chainID,
+			LatestBlock: api.latestBlocks[chainID],
+		})
+	}
+	return attestation
+}
+
+func (api *AttestationAPI) getAttestation(w http.ResponseWriter, r *http.Request) {
+	signed, err := SignAttestation(api.key, api.attestation(time.Now()))
+	if err != nil {
+		log.WithError(err).Error("failed to sign the attestation")
+		writeIncidentsError(w, 500, "failed to sign the attestation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(signed); err != nil {
+		log.WithError(err).Error("error writing attestation")
+	}
+}
+
+// withToken accepts only the requests which have the configured token as the bearer token.
+func (api *AttestationAPI) withToken(handler http.Handler) http.Handler {
+	token := api.cfg.Publish.Attestation.Token
+	expected := []byte(fmt.Sprintf("Bearer %s", token))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(token) == 0 || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeIncidentsError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (api *AttestationAPI) Start() error {
+	api.msgClient.Subscribe(messaging.SubjectAgentsStatusRunning, messaging.AgentsHandler(api.handleAgentsRunning))
+	api.msgClient.Subscribe(messaging.SubjectAgentsStatusStopped, messaging.AgentsHandler(api.handleAgentsStopped))
+	api.msgClient.Subscribe(messaging.SubjectScannerBlock, messaging.ScannerHandler(api.handleScannerBlock))
+
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/attestation", api.getAttestation).Methods(http.MethodGet)
+
+	c := cors.New(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+	})
+
+	api.server = &http.Server{
+		Addr:    fmt.Sprintf(":%s", config.DefaultAttestationPort),
+		Handler: c.Handler(api.withToken(router)),
+	}
+	utils.GoListenAndServe(api.server)
+	return nil
+}
+
+func (api *AttestationAPI) Stop() error {
+	log.Infof("Stopping %s", api.Name())
+	if api.server != nil {
+		return api.server.Close()
+	}
+	return nil
+}
+
+func (api *AttestationAPI) Name() string {
+	return "attestation-api"
+}
@@ -0,0 +1,127 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/forta-network/forta-core-go/registry"
+	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testAttestationKey(t *testing.T) *keystore.Key {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return &keystore.Key{PrivateKey: privateKey, Address: crypto.PubkeyToAddress(privateKey.PublicKey)}
+}
+
+type testScannerRegistry map[string]*registry.Scanner
+
+func (sr testScannerRegistry) GetScanner(scannerID string) (*registry.Scanner, error) {
+	return sr[scannerID], nil
+}
+
+func TestAttestationAPI(t *testing.T) {
+	r := require.New(t)
+
+	key := testAttestationKey(t)
+	cfg := config.Config{ChainID: 1, Chains: []config.ChainConfig{{ChainID: 137}}}
+	cfg.Publish.Attestation.Token = "secret"
+	api := NewAttestationAPI(context.Background(), cfg, key, nil)
+
+	r.NoError(api.handleAgentsRunning(messaging.AgentPayload{{ID: "0xb"}, {ID: "0xa"}, {ID: "0xc"}}))
+	r.NoError(api.handleAgentsStopped(messaging.AgentPayload{{ID: "0xc"}}))
+	r.NoError(api.handleScannerBlock(messaging.ScannerPayload{LatestBlockInput: 100}))
+	r.NoError(api.handleScannerBlock(messaging.ScannerPayload{LatestBlockInput: 99}))
+	r.NoError(api.handleScannerBlock(messaging.ScannerPayload{ChainID: 137, LatestBlockInput: 2000}))
+
+	handler := api.withToken(http.HandlerFunc(api.getAttestation))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/attestation", nil))
+	r.Equal(http.StatusUnauthorized, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/attestation", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	handler.ServeHTTP(recorder, req)
+	r.Equal(http.StatusUnauthorized, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/attestation", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(recorder, req)
+	r.Equal(http.StatusOK, recorder.Code)
+
+	var signed SignedAttestation
+	r.NoError(json.NewDecoder(recorder.Body).Decode(&signed))
+	attestation, err := VerifyAttestation(&signed, testScannerRegistry{
+		key.Address.Hex(): {ScannerID: key.Address.Hex(), ChainID: 1, Enabled: true},
+	})
+	r.NoError(err)
+	r.Equal(key.Address.Hex(), attestation.Scanner)
+	r.Equal(1, attestation.ChainID)
+	r.Equal([]string{"0xa", "0xb"}, attestation.Agents)
+	r.Equal([]*AttestedChain{{ChainID: 1, LatestBlock: 100}, {ChainID: 137, LatestBlock: 2000}}, attestation.Chains)
+}
+
+func TestVerifyAttestation(t *testing.T) {
+	r := require.New(t)
+
+	key := testAttestationKey(t)
+	attestation := &Attestation{
+		Scanner:   key.Address.Hex(),
+		ChainID:   1,
+		Agents:    []string{"0xa"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	scanners := testScannerRegistry{key.Address.Hex(): {ScannerID: key.Address.Hex(), ChainID: 1}}
+	signed, err := SignAttestation(key, attestation)
+	r.NoError(err)
+	_, err = VerifyAttestation(signed, scanners)
+	r.NoError(err)
+
+	// the signature is over the domain prefixed payload
+	plainSignature, err := security.SignBytes(key, []byte(signed.Payload))
+	r.NoError(err)
+	plain := *signed
+	plain.Signature = plainSignature
+	_, err = VerifyAttestation(&plain, scanners)
+	r.Error(err)
+
+	// not registered for the chain
+	_, err = VerifyAttestation(signed, testScannerRegistry{key.Address.Hex(): {ScannerID: key.Address.Hex(), ChainID: 137}})
+	r.Equal(ErrAttestationScanner, err)
+	_, err = VerifyAttestation(signed, testScannerRegistry{})
+	r.Equal(ErrAttestationScanner, err)
+
+	// changed attestation
+	changed := *signed
+	changedAttestation := *attestation
+	changedAttestation.Agents = []string{"0xa", "0xb"}
+	changed.Attestation = &changedAttestation
+	_, err = VerifyAttestation(&changed, scanners)
+	r.Equal(ErrAttestationMismatch, err)
+
+	// changed payload
+	changed = *signed
+	changed.Attestation = nil
+	changed.Payload = `{"scanner":"` + key.Address.Hex() + `","chainId":137}`
+	_, err = VerifyAttestation(&changed, scanners)
+	r.Error(err)
+
+	// signed by another key
+	otherKey := testAttestationKey(t)
+	other, err := SignAttestation(otherKey, attestation)
+	r.NoError(err)
+	_, err = VerifyAttestation(other, scanners)
+	r.Equal(ErrAttestationSigner, err)
+}
@@ -0,0 +1,38 @@
+package publisher
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// BatchRecord is an alert batch which was uploaded to IPFS or anchored on chain.
+type BatchRecord struct {
+	Ref        string    `json:"ref"`
+	Hash       string    `json:"hash,omitempty"`     // keccak256 of the batch data
+	AnchorTx   string    `json:"anchorTx,omitempty"` // the anchor transaction
+	ChainID    uint64    `json:"chainId"`
+	BlockStart uint64    `json:"blockStart"`
+	BlockEnd   uint64    `json:"blockEnd"`
+	AlertCount uint32    `json:"alertCount"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// batchLog appends the uploaded or anchored batches to a file as JSON lines.
+type batchLog struct {
+	path string
+}
+
+func (bl *batchLog) Append(record *BatchRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(bl.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
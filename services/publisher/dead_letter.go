@@ -0,0 +1,104 @@
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// deadLetterBufferSize is how many dead letters can wait to be written.
+	deadLetterBufferSize = 1000
+	// maxDeadLetterFileSize is the size after which the dead-letter file is rotated. Only the last
+	// rotated file is kept.
+	maxDeadLetterFileSize = 10 * 1024 * 1024
+)
+
+var errDeadLetterQueueFull = errors.New("dead-letter queue is full")
+
+// DeadLetter is an alert which could not be delivered to a sink.
+type DeadLetter struct {
+	Sink      string                `json:"sink"`
+	Error     string                `json:"error"`
+	Alert     *protocol.SignedAlert `json:"alert"`
+	Timestamp time.Time             `json:"timestamp"`
+}
+
+// deadLetterQueue appends the undeliverable alerts to a file as JSON lines. The file is written in
+// the background so that a slow disk never blocks the sinks.
+type deadLetterQueue struct {
+	path    string
+	letters chan []byte
+	dropped int64
+}
+
+func newDeadLetterQueue(path string) *deadLetterQueue {
+	q := &deadLetterQueue{
+		path:    path,
+		letters: make(chan []byte, deadLetterBufferSize),
+	}
+	go q.writeLoop()
+	return q
+}
+
+// Append queues the dead letter. The dead letter is dropped if the queue is full.
+func (q *deadLetterQueue) Append(sink string, alert *protocol.SignedAlert, deliveryErr error) error {
+	b, err := json.Marshal(&DeadLetter{
+		Sink:      sink,
+		Error:     deliveryErr.Error(),
+		Alert:     alert,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case q.letters <- append(b, '\n'):
+		return nil
+	default:
+		atomic.AddInt64(&q.dropped, 1)
+		return errDeadLetterQueueFull
+	}
+}
+
+func (q *deadLetterQueue) writeLoop() {
+	for b := range q.letters {
+		if err := q.write(b); err != nil {
+			log.WithError(err).WithField("path", q.path).Error("failed to write the dead letter")
+		}
+	}
+}
+
+func (q *deadLetterQueue) write(b []byte) error {
+	if info, err := os.Stat(q.path); err == nil && info.Size()+int64(len(b)) > maxDeadLetterFileSize {
+		if err := os.Rename(q.path, q.path+".1"); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(q.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(b)
+	return err
+}
+
+// Health implements the health.Reporter interface.
+func (q *deadLetterQueue) Health() health.Reports {
+	return health.Reports{
+		&health.Report{
+			Name:    "dead-letters.dropped.count",
+			Status:  health.StatusInfo,
+			Details: strconv.FormatInt(atomic.LoadInt64(&q.dropped), 10),
+		},
+	}
+}
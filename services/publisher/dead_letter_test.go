@@ -0,0 +1,40 @@
+package publisher
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterQueue_Rotate(t *testing.T) {
+	r := require.New(t)
+
+	q := &deadLetterQueue{path: path.Join(t.TempDir(), "dead-letters.log")}
+	r.NoError(os.WriteFile(q.path, make([]byte, maxDeadLetterFileSize), 0644))
+
+	r.NoError(q.write([]byte("letter\n")))
+	b, err := os.ReadFile(q.path)
+	r.NoError(err)
+	r.Equal("letter\n", string(b))
+	info, err := os.Stat(q.path + ".1")
+	r.NoError(err)
+	r.Equal(int64(maxDeadLetterFileSize), info.Size())
+}
+
+func TestDeadLetterQueue_Full(t *testing.T) {
+	r := require.New(t)
+
+	// no writer drains the queue
+	q := &deadLetterQueue{letters: make(chan []byte, 1)}
+	alert := &protocol.SignedAlert{Alert: &protocol.Alert{Id: "alert1"}}
+	r.NoError(q.Append("sink", alert, errors.New("failed")))
+	r.ErrorIs(q.Append("sink", alert, errors.New("failed")), errDeadLetterQueueFull)
+
+	report, ok := q.Health().GetByName("dead-letters.dropped.count")
+	r.True(ok)
+	r.Equal("1", report.Details)
+}
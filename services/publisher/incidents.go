@@ -0,0 +1,229 @@
+package publisher
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+)
+
+const maxIncidentAlertIDs = 100
+
+// Incident is a group of related alerts.
+type Incident struct {
+	ID          string    `json:"id"`
+	ChainID     uint64    `json:"chainId"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	MaxSeverity string    `json:"maxSeverity"`
+	AlertCount  int       `json:"alertCount"`
+	AlertIDs    []string  `json:"alertIds"` // only the first alerts
+	AgentIDs    []string  `json:"agentIds"`
+	Addresses   []string  `json:"addresses"`
+	TxHashes    []string  `json:"txHashes"`
+
+	severity protocol.Finding_Severity
+	keys     map[string]bool
+}
+
+func (incident *Incident) copy() *Incident {
+	c := *incident
+	c.AlertIDs = append([]string(nil), incident.AlertIDs...)
+	c.AgentIDs = append([]string(nil), incident.AgentIDs...)
+	c.Addresses = append([]string(nil), incident.Addresses...)
+	c.TxHashes = append([]string(nil), incident.TxHashes...)
+	c.keys = nil
+	return &c
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// incidentCorrelator groups the alerts which share an address or a transaction and which are seen
+// within the correlation window of each other. The alerts without any addresses and transactions
+// are grouped by the agent and the alert ID of the finding.
+type incidentCorrelator struct {
+	chainID      uint64
+	window       time.Duration
+	maxIncidents int
+
+	incidents []*Incident // in creation order
+	byKey     map[string]*Incident
+	mu        sync.RWMutex
+}
+
+func newIncidentCorrelator(cfg config.IncidentsConfig, chainID uint64) *incidentCorrelator {
+	return &incidentCorrelator{
+		chainID:      chainID,
+		window:       time.Duration(cfg.WindowSeconds) * time.Second,
+		maxIncidents: cfg.MaxIncidents,
+		byKey:        make(map[string]*Incident),
+	}
+}
+
+func alertAddresses(alert *protocol.Alert) (addresses []string) {
+	for _, address := range alert.Finding.Addresses {
+		if len(address) > 0 {
+			addresses = appendUnique(addresses, strings.ToLower(address))
+		}
+	}
+	return
+}
+
+func alertTxHash(alert *protocol.Alert) string {
+	return strings.ToLower(alert.Tags["txHash"])
+}
+
+func correlationKeys(alert *protocol.Alert, addresses []string, txHash string) (keys []string) {
+	for _, address := range addresses {
+		keys = append(keys, "address:"+address)
+	}
+	if len(txHash) > 0 {
+		keys = append(keys, "tx:"+txHash)
+	}
+	if len(keys) == 0 && alert.Agent != nil {
+		keys = append(keys, "finding:"+alert.Agent.Id+":"+alert.Finding.AlertId)
+	}
+	return
+}
+
+// Add correlates the alert with the recent incidents. The incidents which the alert links together
+// are merged into the oldest one.
+func (correlator *incidentCorrelator) Add(alert *protocol.SignedAlert, now time.Time) {
+	if alert.Alert == nil || alert.Alert.Finding == nil {
+		return
+	}
+	addresses := alertAddresses(alert.Alert)
+	txHash := alertTxHash(alert.Alert)
+	keys := correlationKeys(alert.Alert, addresses, txHash)
+
+	correlator.mu.Lock()
+	defer correlator.mu.Unlock()
+
+	var matched []*Incident
+	for _, key := range keys {
+		incident, ok := correlator.byKey[key]
+		if !ok || now.Sub(incident.LastSeen) > correlator.window {
+			continue
+		}
+		found := false
+		for _, m := range matched {
+			if m == incident {
+				found = true
+				break
+			}
+		}
+		if !found {
+			matched = append(matched, incident)
+		}
+	}
+
+	var incident *Incident
+	if len(matched) == 0 {
+		incident = &Incident{
+			ID:        alert.Alert.Id,
+			ChainID:   correlator.chainID,
+			FirstSeen: now,
+			keys:      make(map[string]bool),
+		}
+		correlator.incidents = append(correlator.incidents, incident)
+	} else {
+		sort.Slice(matched, func(i, j int) bool {
+			return matched[i].FirstSeen.Before(matched[j].FirstSeen)
+		})
+		incident = matched[0]
+		for _, other := range matched[1:] {
+			correlator.merge(incident, other)
+		}
+	}
+
+	incident.LastSeen = now
+	incident.AlertCount++
+	if len(incident.AlertIDs) < maxIncidentAlertIDs {
+		incident.AlertIDs = append(incident.AlertIDs, alert.Alert.Id)
+	}
+	if alert.Alert.Agent != nil {
+		incident.AgentIDs = appendUnique(incident.AgentIDs, alert.Alert.Agent.Id)
+	}
+	incident.Addresses = appendUnique(incident.Addresses, addresses...)
+	if len(txHash) > 0 {
+		incident.TxHashes = appendUnique(incident.TxHashes, txHash)
+	}
+	if alert.Alert.Finding.Severity > incident.severity {
+		incident.severity = alert.Alert.Finding.Severity
+	}
+	incident.MaxSeverity = incident.severity.String()
+	for _, key := range keys {
+		incident.keys[key] = true
+		correlator.byKey[key] = incident
+	}
+
+	for len(correlator.incidents) > correlator.maxIncidents {
+		correlator.remove(correlator.incidents[0])
+	}
+}
+
+// merge moves the alerts of the other incident into the incident.
+func (correlator *incidentCorrelator) merge(incident, other *Incident) {
+	incident.AlertCount += other.AlertCount
+	for _, alertID := range other.AlertIDs {
+		if len(incident.AlertIDs) >= maxIncidentAlertIDs {
+			break
+		}
+		incident.AlertIDs = append(incident.AlertIDs, alertID)
+	}
+	incident.AgentIDs = appendUnique(incident.AgentIDs, other.AgentIDs...)
+	incident.Addresses = appendUnique(incident.Addresses, other.Addresses...)
+	incident.TxHashes = appendUnique(incident.TxHashes, other.TxHashes...)
+	if other.severity > incident.severity {
+		incident.severity = other.severity
+	}
+	for key := range other.keys {
+		incident.keys[key] = true
+		correlator.byKey[key] = incident
+	}
+	other.keys = nil
+	correlator.remove(other)
+}
+
+func (correlator *incidentCorrelator) remove(incident *Incident) {
+	for key := range incident.keys {
+		if correlator.byKey[key] == incident {
+			delete(correlator.byKey, key)
+		}
+	}
+	for i, existing := range correlator.incidents {
+		if existing == incident {
+			correlator.incidents = append(correlator.incidents[:i], correlator.incidents[i+1:]...)
+			break
+		}
+	}
+}
+
+// Incidents returns copies of the incidents.
+func (correlator *incidentCorrelator) Incidents() []*Incident {
+	correlator.mu.RLock()
+	defer correlator.mu.RUnlock()
+
+	incidents := make([]*Incident, 0, len(correlator.incidents))
+	for _, incident := range correlator.incidents {
+		incidents = append(incidents, incident.copy())
+	}
+	return incidents
+}
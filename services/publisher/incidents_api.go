@@ -0,0 +1,152 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+	log "github.com/sirupsen/logrus"
+)
+
+// IncidentSource provides the correlated incidents.
+type IncidentSource interface {
+	Incidents() []*Incident
+}
+
+// IncidentsResponse is the response of the incidents API.
+type IncidentsResponse struct {
+	Incidents []*Incident `json:"incidents"`
+}
+
+// IncidentsAPI serves the incidents of the publishers.
+type IncidentsAPI struct {
+	ctx     context.Context
+	sources []IncidentSource
+	server  *http.Server
+}
+
+func writeIncidentsError(w http.ResponseWriter, code int, str string) {
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": str}); err != nil {
+		log.WithError(err).Errorf("error writing: %s", str)
+	}
+}
+
+// listIncidents returns the most recent incidents first. The incidents can be filtered by
+// the chain ID, the minimum severity and the last seen time.
+func (api *IncidentsAPI) listIncidents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var chainID uint64
+	if s := query.Get("chainId"); len(s) > 0 {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			writeIncidentsError(w, 400, "?chainId must be integer")
+			return
+		}
+		chainID = n
+	}
+
+	var minSeverity protocol.Finding_Severity
+	if s := query.Get("minSeverity"); len(s) > 0 {
+		severity, ok := protocol.Finding_Severity_value[s]
+		if !ok {
+			writeIncidentsError(w, 400, "?minSeverity must be a finding severity")
+			return
+		}
+		minSeverity = protocol.Finding_Severity(severity)
+	}
+
+	var since time.Time
+	if s := query.Get("since"); len(s) > 0 {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			writeIncidentsError(w, 400, "?since must be an RFC3339 timestamp")
+			return
+		}
+		since = t
+	}
+
+	limit := -1
+	if s := query.Get("limit"); len(s) > 0 {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeIncidentsError(w, 400, "?limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	incidents := make([]*Incident, 0)
+	for _, source := range api.sources {
+		for _, incident := range source.Incidents() {
+			if chainID != 0 && incident.ChainID != chainID {
+				continue
+			}
+			if incident.severity < minSeverity {
+				continue
+			}
+			if incident.LastSeen.Before(since) {
+				continue
+			}
+			incidents = append(incidents, incident)
+		}
+	}
+	sort.SliceStable(incidents, func(i, j int) bool {
+		return incidents[i].LastSeen.After(incidents[j].LastSeen)
+	})
+	if limit >= 0 && len(incidents) > limit {
+		incidents = incidents[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&IncidentsResponse{Incidents: incidents}); err != nil {
+		log.WithError(err).Error("error writing incidents")
+	}
+}
+
+func (api *IncidentsAPI) Start() error {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/incidents", api.listIncidents).Methods(http.MethodGet)
+
+	c := cors.New(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+	})
+
+	api.server = &http.Server{
+		Addr:    fmt.Sprintf(":%s", config.DefaultIncidentsPort),
+		Handler: c.Handler(router),
+	}
+	utils.GoListenAndServe(api.server)
+	return nil
+}
+
+func (api *IncidentsAPI) Stop() error {
+	log.Infof("Stopping %s", api.Name())
+	if api.server != nil {
+		return api.server.Close()
+	}
+	return nil
+}
+
+func (api *IncidentsAPI) Name() string {
+	return "incidents-api"
+}
+
+// NewIncidentsAPI creates the API which serves the incidents of the sources.
+func NewIncidentsAPI(ctx context.Context, sources ...IncidentSource) *IncidentsAPI {
+	return &IncidentsAPI{
+		ctx:     ctx,
+		sources: sources,
+	}
+}
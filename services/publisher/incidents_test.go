@@ -0,0 +1,140 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testIncidentAlert(id, agentID string, severity protocol.Finding_Severity, txHash string, addresses ...string) *protocol.SignedAlert {
+	alert := testWebhookAlert(id, agentID, severity)
+	alert.Alert.Finding.AlertId = "FINDING-1"
+	alert.Alert.Finding.Addresses = addresses
+	if len(txHash) > 0 {
+		alert.Alert.Tags = map[string]string{"txHash": txHash}
+	}
+	return alert
+}
+
+func TestIncidentCorrelator(t *testing.T) {
+	r := require.New(t)
+
+	correlator := newIncidentCorrelator(config.IncidentsConfig{WindowSeconds: 60, MaxIncidents: 10}, 1)
+	now := time.Now()
+
+	correlator.Add(testIncidentAlert("alert1", "agent1", protocol.Finding_LOW, "0xtx1", "0xA"), now)
+	// same address in a different case
+	correlator.Add(testIncidentAlert("alert2", "agent2", protocol.Finding_HIGH, "", "0xa"), now.Add(time.Second))
+	// unrelated
+	correlator.Add(testIncidentAlert("alert3", "agent1", protocol.Finding_INFO, "0xtx2", "0xb"), now.Add(2*time.Second))
+	// same address after the window
+	correlator.Add(testIncidentAlert("alert4", "agent1", protocol.Finding_INFO, "", "0xa"), now.Add(3*time.Minute))
+
+	incidents := correlator.Incidents()
+	r.Len(incidents, 3)
+
+	r.Equal("alert1", incidents[0].ID)
+	r.Equal(uint64(1), incidents[0].ChainID)
+	r.Equal(2, incidents[0].AlertCount)
+	r.Equal([]string{"alert1", "alert2"}, incidents[0].AlertIDs)
+	r.Equal([]string{"agent1", "agent2"}, incidents[0].AgentIDs)
+	r.Equal([]string{"0xa"}, incidents[0].Addresses)
+	r.Equal([]string{"0xtx1"}, incidents[0].TxHashes)
+	r.Equal("HIGH", incidents[0].MaxSeverity)
+
+	r.Equal("alert3", incidents[1].ID)
+	r.Equal("alert4", incidents[2].ID)
+}
+
+func TestIncidentCorrelator_Merge(t *testing.T) {
+	r := require.New(t)
+
+	correlator := newIncidentCorrelator(config.IncidentsConfig{WindowSeconds: 60, MaxIncidents: 10}, 1)
+	now := time.Now()
+
+	correlator.Add(testIncidentAlert("alert1", "agent1", protocol.Finding_LOW, "", "0xa"), now)
+	correlator.Add(testIncidentAlert("alert2", "agent2", protocol.Finding_MEDIUM, "0xtx1", "0xb"), now)
+	r.Len(correlator.Incidents(), 2)
+
+	// links the two incidents together
+	correlator.Add(testIncidentAlert("alert3", "agent3", protocol.Finding_INFO, "0xtx1", "0xa"), now)
+
+	incidents := correlator.Incidents()
+	r.Len(incidents, 1)
+	r.Equal("alert1", incidents[0].ID)
+	r.Equal(3, incidents[0].AlertCount)
+	r.ElementsMatch([]string{"0xa", "0xb"}, incidents[0].Addresses)
+	r.Equal("MEDIUM", incidents[0].MaxSeverity)
+
+	correlator.Add(testIncidentAlert("alert4", "agent1", protocol.Finding_INFO, "", "0xb"), now)
+	r.Len(correlator.Incidents(), 1)
+}
+
+func TestIncidentCorrelator_Evict(t *testing.T) {
+	r := require.New(t)
+
+	correlator := newIncidentCorrelator(config.IncidentsConfig{WindowSeconds: 60, MaxIncidents: 2}, 1)
+	now := time.Now()
+
+	correlator.Add(testIncidentAlert("alert1", "agent1", protocol.Finding_INFO, "", "0xa"), now)
+	correlator.Add(testIncidentAlert("alert2", "agent1", protocol.Finding_INFO, "", "0xb"), now)
+	correlator.Add(testIncidentAlert("alert3", "agent1", protocol.Finding_INFO, "", "0xc"), now)
+
+	incidents := correlator.Incidents()
+	r.Len(incidents, 2)
+	r.Equal("alert2", incidents[0].ID)
+	r.Equal("alert3", incidents[1].ID)
+
+	// the evicted incident is not found anymore
+	correlator.Add(testIncidentAlert("alert4", "agent1", protocol.Finding_INFO, "", "0xa"), now)
+	incidents = correlator.Incidents()
+	r.Len(incidents, 2)
+	r.Equal("alert4", incidents[1].ID)
+}
+
+type testIncidentSource []*Incident
+
+func (source testIncidentSource) Incidents() []*Incident {
+	return source
+}
+
+func TestIncidentsAPI(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now().UTC()
+	api := NewIncidentsAPI(context.Background(), testIncidentSource{
+		{ID: "incident1", ChainID: 1, LastSeen: now.Add(-time.Hour), severity: protocol.Finding_CRITICAL},
+		{ID: "incident2", ChainID: 1, LastSeen: now, severity: protocol.Finding_LOW},
+	}, testIncidentSource{
+		{ID: "incident3", ChainID: 137, LastSeen: now.Add(-time.Minute), severity: protocol.Finding_HIGH},
+	})
+
+	list := func(query string) []string {
+		w := httptest.NewRecorder()
+		api.listIncidents(w, httptest.NewRequest("GET", "/incidents"+query, nil))
+		r.Equal(200, w.Code)
+		var resp IncidentsResponse
+		r.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+		var ids []string
+		for _, incident := range resp.Incidents {
+			ids = append(ids, incident.ID)
+		}
+		return ids
+	}
+
+	r.Equal([]string{"incident2", "incident3", "incident1"}, list(""))
+	r.Equal([]string{"incident2", "incident1"}, list("?chainId=1"))
+	r.Equal([]string{"incident3", "incident1"}, list("?minSeverity=HIGH"))
+	r.Equal([]string{"incident2", "incident3"}, list("?since="+now.Add(-10*time.Minute).Format(time.RFC3339)))
+	r.Equal([]string{"incident2"}, list("?limit=1"))
+
+	w := httptest.NewRecorder()
+	api.listIncidents(w, httptest.NewRequest("GET", "/incidents?minSeverity=BAD", nil))
+	r.Equal(400, w.Code)
+}
@@ -0,0 +1,307 @@
+package publisher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	jetStreamBufferSize     = 1000
+	jetStreamMaxRetries     = 5
+	jetStreamInitialBackoff = time.Second
+)
+
+var (
+	errJetStreamQueueFull = errors.New("jetstream queue is full")
+	errJetStreamStopped   = errors.New("jetstream sink is stopped")
+)
+
+// JetStream manages the alert stream and publishes to it.
+type JetStream interface {
+	StreamInfo(stream string, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+	AddStream(cfg *nats.StreamConfig, opts ...nats.JSOpt) (*nats.StreamInfo, error)
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+}
+
+// JetStreamSink publishes the alerts to a persistent JetStream stream so the consumers can replay
+// the alerts from any stream sequence. The alerts which cannot be published are kept in the dead letters.
+type JetStreamSink struct {
+	ctx         context.Context
+	cfg         config.JetStreamConfig
+	chainID     int
+	connect     func() (JetStream, error)
+	js          JetStream
+	alertCh     chan *protocol.SignedAlert
+	batcher     *alertBatcher
+	deadLetters *deadLetterQueue
+
+	initialBackoff time.Duration
+
+	lastSend health.TimeTracker
+	lastErr  health.ErrorTracker
+	lastSeq  health.MessageTracker
+}
+
+// NewJetStreamSink creates a new sink which publishes to the stream on the NATS server of the node.
+func NewJetStreamSink(ctx context.Context, cfg config.JetStreamConfig, chainID int, deadLetters *deadLetterQueue) *JetStreamSink {
+	natsURL := fmt.Sprintf("%s:%s", config.DockerNatsContainerName, config.DefaultNatsPort)
+	return newJetStreamSink(ctx, cfg, chainID, deadLetters, func() (JetStream, error) {
+		nc, err := nats.Connect(natsURL)
+		if err != nil {
+			return nil, err
+		}
+		return nc.JetStream()
+	})
+}
+
+func newJetStreamSink(
+	ctx context.Context, cfg config.JetStreamConfig, chainID int, deadLetters *deadLetterQueue,
+	connect func() (JetStream, error),
+) *JetStreamSink {
+	jss := &JetStreamSink{
+		ctx:            ctx,
+		cfg:            cfg,
+		chainID:        chainID,
+		connect:        connect,
+		alertCh:        make(chan *protocol.SignedAlert, jetStreamBufferSize),
+		deadLetters:    deadLetters,
+		initialBackoff: jetStreamInitialBackoff,
+	}
+	if cfg.Batch.MaxAlerts > 0 {
+		jss.batcher = newAlertBatcher(cfg.Batch, jss.publishBatch)
+	}
+	return jss
+}
+
+// Send queues the alert without blocking. The alert goes to the dead letters if the queue is full.
+func (jss *JetStreamSink) Send(alert *protocol.SignedAlert) {
+	if jss.batcher != nil {
+		if !jss.batcher.Add(alert) {
+			jss.deadLetter(alert, errJetStreamQueueFull)
+		}
+		return
+	}
+	select {
+	case jss.alertCh <- alert:
+	default:
+		jss.deadLetter(alert, errJetStreamQueueFull)
+	}
+}
+
+func (jss *JetStreamSink) deadLetter(alert *protocol.SignedAlert, err error) {
+	log.WithError(err).WithField("alert", alert.Alert.Id).Warn("failed to publish alert to jetstream")
+	if err := jss.deadLetters.Append(jss.Name(), alert, err); err != nil {
+		log.WithError(err).Error("failed to write the dead letter")
+	}
+}
+
+// subject makes the alerts of every chain and agent filterable by the consumers.
+func (jss *JetStreamSink) subject(alert *protocol.SignedAlert) string {
+	agentID := "unknown"
+	if alert.Alert.Agent != nil && len(alert.Alert.Agent.Id) > 0 {
+		agentID = alert.Alert.Agent.Id
+	}
+	return fmt.Sprintf("%s.%d.%s", jss.cfg.Subject, jss.chainID, agentID)
+}
+
+func (jss *JetStreamSink) makeMessage(alert *protocol.SignedAlert) (*nats.Msg, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch jss.cfg.Encoding {
+	case "protobuf":
+		data, err = proto.Marshal(alert)
+	default:
+		var s string
+		s, err = (&jsonpb.Marshaler{}).MarshalToString(alert)
+		data = []byte(s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &nats.Msg{Subject: jss.subject(alert), Data: data}, nil
+}
+
+// ensureStream creates the stream if it does not exist.
+func (jss *JetStreamSink) ensureStream() error {
+	if _, err := jss.js.StreamInfo(jss.cfg.Stream); err == nil {
+		return nil
+	}
+	_, err := jss.js.AddStream(&nats.StreamConfig{
+		Name:     jss.cfg.Stream,
+		Subjects: []string{jss.cfg.Subject + ".>"},
+		Storage:  nats.FileStorage,
+		MaxAge:   time.Duration(jss.cfg.MaxAgeHours) * time.Hour,
+	})
+	return err
+}
+
+func (jss *JetStreamSink) init() bool {
+	backoff := jss.initialBackoff
+	for {
+		js, err := jss.connect()
+		if err == nil {
+			jss.js = js
+			err = jss.ensureStream()
+		}
+		jss.lastErr.Set(err)
+		if err == nil {
+			return true
+		}
+		log.WithError(err).Warn("failed to initialize the jetstream alert stream")
+		select {
+		case <-jss.ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// makeBatchMessage makes a single message with the alerts of the batch. The message ID is made from
+// the alert IDs so that the retries are not duplicated in the stream.
+func (jss *JetStreamSink) makeBatchMessage(batch []*protocol.SignedAlert) (*nats.Msg, string, error) {
+	data, contentEncoding, err := encodeAlertBatch(batch, jss.cfg.Encoding, jss.cfg.Batch.Compress)
+	if err != nil {
+		return nil, "", err
+	}
+	msg := &nats.Msg{Subject: fmt.Sprintf("%s.%d.batch", jss.cfg.Subject, jss.chainID), Data: data}
+	if len(contentEncoding) > 0 {
+		msg.Header = nats.Header{"Content-Encoding": []string{contentEncoding}}
+	}
+	hash := sha256.New()
+	for _, alert := range batch {
+		hash.Write([]byte(alert.Alert.Id))
+	}
+	return msg, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (jss *JetStreamSink) publishBatch(ctx context.Context, batch []*protocol.SignedAlert) {
+	// the stream is not ready if the sink is stopped before connecting
+	if jss.js == nil {
+		for _, alert := range batch {
+			jss.deadLetter(alert, errJetStreamStopped)
+		}
+		return
+	}
+	msg, msgID, err := jss.makeBatchMessage(batch)
+	if err == nil {
+		err = jss.publishMsg(msg, msgID)
+	}
+	jss.lastErr.Set(err)
+	if err != nil {
+		for _, alert := range batch {
+			jss.deadLetter(alert, err)
+		}
+		return
+	}
+	jss.lastSend.Set()
+}
+
+// publish publishes the alert with the alert ID as the message ID so that the retries are not
+// duplicated in the stream.
+func (jss *JetStreamSink) publish(alert *protocol.SignedAlert) error {
+	msg, err := jss.makeMessage(alert)
+	if err != nil {
+		return err
+	}
+	return jss.publishMsg(msg, alert.Alert.Id)
+}
+
+// publishMsg retries with exponential backoff.
+func (jss *JetStreamSink) publishMsg(msg *nats.Msg, msgID string) error {
+	backoff := jss.initialBackoff
+	for i := 0; ; i++ {
+		ack, err := jss.js.PublishMsg(msg, nats.MsgId(msgID))
+		if err == nil {
+			jss.lastSeq.Set(strconv.FormatUint(ack.Sequence, 10))
+			return nil
+		}
+		if i >= jetStreamMaxRetries {
+			return err
+		}
+		select {
+		case <-jss.ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (jss *JetStreamSink) run() {
+	if !jss.init() {
+		return
+	}
+	for {
+		select {
+		case <-jss.ctx.Done():
+			return
+		case alert := <-jss.alertCh:
+			err := jss.publish(alert)
+			jss.lastErr.Set(err)
+			if err != nil {
+				jss.deadLetter(alert, err)
+				continue
+			}
+			jss.lastSend.Set()
+		}
+	}
+}
+
+// Start connects to the stream and starts publishing the queued alerts.
+func (jss *JetStreamSink) Start() {
+	if jss.batcher != nil {
+		go func() {
+			jss.init()
+			jss.batcher.run(jss.ctx)
+		}()
+		return
+	}
+	go jss.run()
+}
+
+// Name returns the name of the sink.
+func (jss *JetStreamSink) Name() string {
+	return "jetstream"
+}
+
+// Stop keeps the queued alerts in the dead letters. The last batch is flushed if the alerts are batched.
+func (jss *JetStreamSink) Stop() error {
+	if jss.batcher != nil {
+		return jss.batcher.Stop()
+	}
+	for {
+		select {
+		case alert := <-jss.alertCh:
+			jss.deadLetter(alert, errJetStreamStopped)
+		default:
+			return nil
+		}
+	}
+}
+
+// Health implements the health.Reporter interface.
+func (jss *JetStreamSink) Health() health.Reports {
+	return health.Reports{
+		jss.lastSend.GetReport("event.jetstream-send.time"),
+		jss.lastErr.GetReport("event.jetstream-send.error"),
+		jss.lastSeq.GetReport("event.jetstream-send.sequence"),
+	}
+}
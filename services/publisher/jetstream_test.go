@@ -0,0 +1,159 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/protobuf/proto"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func testJetStreamConfig() config.JetStreamConfig {
+	return config.JetStreamConfig{
+		Enable:      true,
+		Stream:      "forta-alerts",
+		Subject:     "forta.alerts",
+		Encoding:    "protobuf",
+		MaxAgeHours: 1,
+	}
+}
+
+func runTestJetStreamServer(t *testing.T) *server.Server {
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+	go srv.Start()
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func TestJetStreamSink(t *testing.T) {
+	r := require.New(t)
+
+	srv := runTestJetStreamServer(t)
+	nc, err := nats.Connect(srv.ClientURL())
+	r.NoError(err)
+	defer nc.Close()
+	js, err := nc.JetStream()
+	r.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deadLetters := newDeadLetterQueue(path.Join(t.TempDir(), "dead-letters.log"))
+	sink := newJetStreamSink(ctx, testJetStreamConfig(), 137, deadLetters, func() (JetStream, error) {
+		return js, nil
+	})
+	sink.Start()
+
+	sink.Send(testWebhookAlert("alert1", "0xagent1", protocol.Finding_HIGH))
+	sink.Send(testWebhookAlert("alert2", "0xagent2", protocol.Finding_LOW))
+	// duplicate
+	sink.Send(testWebhookAlert("alert1", "0xagent1", protocol.Finding_HIGH))
+
+	r.Eventually(func() bool {
+		info, err := js.StreamInfo("forta-alerts")
+		return err == nil && info.State.Msgs == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// replay from the beginning of the stream
+	sub, err := js.SubscribeSync("forta.alerts.137.0xagent2", nats.DeliverAll())
+	r.NoError(err)
+	msg, err := sub.NextMsg(time.Second)
+	r.NoError(err)
+	var alert protocol.SignedAlert
+	r.NoError(proto.Unmarshal(msg.Data, &alert))
+	r.Equal("alert2", alert.Alert.Id)
+
+	_, err = os.Stat(deadLetters.path)
+	r.True(os.IsNotExist(err))
+}
+
+type testJetStream struct {
+	JetStream
+	publishErr error
+}
+
+func (js *testJetStream) StreamInfo(stream string, opts ...nats.JSOpt) (*nats.StreamInfo, error) {
+	return &nats.StreamInfo{}, nil
+}
+
+func (js *testJetStream) PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	return nil, js.publishErr
+}
+
+func TestJetStreamSink_DeadLetter(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deadLetters := newDeadLetterQueue(path.Join(t.TempDir(), "dead-letters.log"))
+	sink := newJetStreamSink(ctx, testJetStreamConfig(), 1, deadLetters, func() (JetStream, error) {
+		return &testJetStream{publishErr: errors.New("no responders")}, nil
+	})
+	sink.initialBackoff = time.Millisecond
+	sink.Start()
+
+	sink.Send(testWebhookAlert("alert1", "0xagent1", protocol.Finding_HIGH))
+
+	r.Eventually(func() bool {
+		b, err := os.ReadFile(deadLetters.path)
+		return err == nil && len(b) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	r.Equal("no responders", sink.Health()[1].Details)
+}
+
+func TestJetStreamSink_Batch(t *testing.T) {
+	r := require.New(t)
+
+	srv := runTestJetStreamServer(t)
+	nc, err := nats.Connect(srv.ClientURL())
+	r.NoError(err)
+	defer nc.Close()
+	js, err := nc.JetStream()
+	r.NoError(err)
+
+	cfg := testJetStreamConfig()
+	cfg.Batch = config.SinkBatchConfig{MaxAlerts: 2, IntervalSeconds: 60}
+	deadLetters := newDeadLetterQueue(path.Join(t.TempDir(), "dead-letters.log"))
+	sink := newJetStreamSink(context.Background(), cfg, 137, deadLetters, func() (JetStream, error) {
+		return js, nil
+	})
+	sink.Start()
+
+	sink.Send(testWebhookAlert("alert1", "0xagent1", protocol.Finding_HIGH))
+	sink.Send(testWebhookAlert("alert2", "0xagent2", protocol.Finding_LOW))
+	sink.Send(testWebhookAlert("alert3", "0xagent1", protocol.Finding_HIGH))
+
+	r.Eventually(func() bool {
+		info, err := js.StreamInfo("forta-alerts")
+		return err == nil && info.State.Msgs == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the last alert is flushed while stopping
+	r.NoError(sink.Stop())
+	info, err := js.StreamInfo("forta-alerts")
+	r.NoError(err)
+	r.Equal(uint64(2), info.State.Msgs)
+
+	sub, err := js.SubscribeSync("forta.alerts.137.batch", nats.DeliverAll())
+	r.NoError(err)
+	msg, err := sub.NextMsg(time.Second)
+	r.NoError(err)
+	var batch protocol.AlertResponse
+	r.NoError(proto.Unmarshal(msg.Data, &batch))
+	r.Len(batch.Alerts, 2)
+	r.Equal("alert1", batch.Alerts[0].Alert.Id)
+}
@@ -0,0 +1,189 @@
+package publisher
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/segmentio/kafka-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const kafkaBufferSize = 1000
+
+// KafkaWriter writes messages to a Kafka topic.
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaSink publishes the alerts to a Kafka topic.
+type KafkaSink struct {
+	ctx     context.Context
+	cfg     config.KafkaConfig
+	chainID int
+	writer  KafkaWriter
+	msgCh   chan kafka.Message
+	batcher *alertBatcher
+
+	lastSend health.TimeTracker
+	lastErr  health.ErrorTracker
+	lastDrop health.TimeTracker
+}
+
+// NewKafkaSink creates a new Kafka sink which writes to the configured topic.
+func NewKafkaSink(ctx context.Context, cfg config.KafkaConfig, chainID int) *KafkaSink {
+	writer := kafka.NewWriter(kafka.WriterConfig{
+		Brokers:  cfg.Brokers,
+		Topic:    cfg.Topic,
+		Balancer: &kafka.Hash{},
+	})
+	return newKafkaSink(ctx, cfg, chainID, writer)
+}
+
+func newKafkaSink(ctx context.Context, cfg config.KafkaConfig, chainID int, writer KafkaWriter) *KafkaSink {
+	ks := &KafkaSink{
+		ctx:     ctx,
+		cfg:     cfg,
+		chainID: chainID,
+		writer:  writer,
+		msgCh:   make(chan kafka.Message, kafkaBufferSize),
+	}
+	if cfg.Batch.MaxAlerts > 0 {
+		ks.batcher = newAlertBatcher(cfg.Batch, ks.writeBatch)
+	}
+	return ks
+}
+
+// Send queues the alert without blocking. The alert is dropped if the queue is full.
+func (ks *KafkaSink) Send(alert *protocol.SignedAlert) {
+	if ks.batcher != nil {
+		if !ks.batcher.Add(alert) {
+			ks.lastDrop.Set()
+			log.WithField("alert", alert.Alert.Id).Warn("kafka queue is full - dropping alert")
+		}
+		return
+	}
+	msg, err := ks.makeMessage(alert)
+	if err != nil {
+		log.WithError(err).Warn("failed to encode the alert for kafka")
+		return
+	}
+	select {
+	case ks.msgCh <- msg:
+	default:
+		ks.lastDrop.Set()
+		log.WithField("alert", alert.Alert.Id).Warn("kafka queue is full - dropping alert")
+	}
+}
+
+func (ks *KafkaSink) makeMessage(alert *protocol.SignedAlert) (kafka.Message, error) {
+	var (
+		value []byte
+		err   error
+	)
+	switch ks.cfg.Encoding {
+	case "protobuf":
+		value, err = proto.Marshal(alert)
+	default:
+		var s string
+		s, err = (&jsonpb.Marshaler{}).MarshalToString(alert)
+		value = []byte(s)
+	}
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	return kafka.Message{Key: []byte(ks.partitionKey(alert)), Value: value}, nil
+}
+
+// partitionKey makes the messages of the same agent or the same chain go to the same partition.
+func (ks *KafkaSink) partitionKey(alert *protocol.SignedAlert) string {
+	if ks.cfg.PartitionBy == "chain" || alert.Alert.Agent == nil {
+		return strconv.Itoa(ks.chainID)
+	}
+	return alert.Alert.Agent.Id
+}
+
+// makeBatchMessage makes a single message with the alerts of the batch. The batches are partitioned by chain.
+func (ks *KafkaSink) makeBatchMessage(batch []*protocol.SignedAlert) (kafka.Message, error) {
+	value, contentEncoding, err := encodeAlertBatch(batch, ks.cfg.Encoding, ks.cfg.Batch.Compress)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	msg := kafka.Message{Key: []byte(strconv.Itoa(ks.chainID)), Value: value}
+	if len(contentEncoding) > 0 {
+		msg.Headers = []kafka.Header{{Key: "Content-Encoding", Value: []byte(contentEncoding)}}
+	}
+	return msg, nil
+}
+
+func (ks *KafkaSink) writeBatch(ctx context.Context, batch []*protocol.SignedAlert) {
+	msg, err := ks.makeBatchMessage(batch)
+	if err == nil {
+		err = ks.writer.WriteMessages(ctx, msg)
+	}
+	ks.lastErr.Set(err)
+	if err != nil {
+		log.WithError(err).Warnf("failed to write a batch of %d alerts to kafka", len(batch))
+		return
+	}
+	ks.lastSend.Set()
+}
+
+func (ks *KafkaSink) run() {
+	for {
+		select {
+		case <-ks.ctx.Done():
+			return
+		case msg := <-ks.msgCh:
+			err := ks.writer.WriteMessages(ks.ctx, msg)
+			ks.lastErr.Set(err)
+			if err != nil {
+				log.WithError(err).Warn("failed to write alert to kafka")
+				continue
+			}
+			ks.lastSend.Set()
+		}
+	}
+}
+
+// Start starts writing the queued alerts.
+func (ks *KafkaSink) Start() {
+	if ks.batcher != nil {
+		go ks.batcher.run(ks.ctx)
+		return
+	}
+	go ks.run()
+}
+
+// Name returns the name of the sink.
+func (ks *KafkaSink) Name() string {
+	return "kafka"
+}
+
+// Stop flushes the last batch and closes the writer.
+func (ks *KafkaSink) Stop() error {
+	if ks.batcher != nil {
+		if err := ks.batcher.Stop(); err != nil {
+			log.WithError(err).Warn("failed to flush the kafka alerts")
+		}
+	}
+	return ks.writer.Close()
+}
+
+// Health implements the health.Reporter interface.
+func (ks *KafkaSink) Health() health.Reports {
+	return health.Reports{
+		ks.lastSend.GetReport("event.kafka-send.time"),
+		ks.lastErr.GetReport("event.kafka-send.error"),
+		&health.Report{
+			Name:    "event.kafka-drop.time",
+			Status:  health.StatusInfo,
+			Details: ks.lastDrop.String(),
+		},
+	}
+}
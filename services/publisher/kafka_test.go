@@ -0,0 +1,117 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/protobuf/proto"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+type testKafkaWriter struct {
+	mu   sync.Mutex
+	msgs []kafka.Message
+}
+
+func (w *testKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *testKafkaWriter) Close() error {
+	return nil
+}
+
+func (w *testKafkaWriter) messages() []kafka.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.msgs
+}
+
+func testKafkaAlert(id, agentID string) *protocol.SignedAlert {
+	return &protocol.SignedAlert{
+		Alert: &protocol.Alert{
+			Id:    id,
+			Agent: &protocol.AgentInfo{Id: agentID},
+		},
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	writer := &testKafkaWriter{}
+	sink := newKafkaSink(ctx, config.KafkaConfig{Encoding: "json", PartitionBy: "agent"}, 137, writer)
+	sink.Start()
+
+	sink.Send(testKafkaAlert("alert1", "agent1"))
+	sink.Send(testKafkaAlert("alert2", "agent2"))
+
+	r.Eventually(func() bool {
+		return len(writer.messages()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	msgs := writer.messages()
+	r.Equal("agent1", string(msgs[0].Key))
+	r.Contains(string(msgs[0].Value), `"id":"alert1"`)
+	r.Equal("agent2", string(msgs[1].Key))
+}
+
+func TestKafkaSink_ProtobufByChain(t *testing.T) {
+	r := require.New(t)
+
+	sink := newKafkaSink(context.Background(), config.KafkaConfig{Encoding: "protobuf", PartitionBy: "chain"}, 137, &testKafkaWriter{})
+
+	msg, err := sink.makeMessage(testKafkaAlert("alert1", "agent1"))
+	r.NoError(err)
+	r.Equal("137", string(msg.Key))
+
+	var alert protocol.SignedAlert
+	r.NoError(proto.Unmarshal(msg.Value, &alert))
+	r.Equal("alert1", alert.Alert.Id)
+}
+
+func TestKafkaSink_Drop(t *testing.T) {
+	r := require.New(t)
+
+	sink := newKafkaSink(context.Background(), config.KafkaConfig{Encoding: "json"}, 1, &testKafkaWriter{})
+	for i := 0; i < kafkaBufferSize+1; i++ {
+		sink.Send(testKafkaAlert("alert", "agent"))
+	}
+	r.Len(sink.msgCh, kafkaBufferSize)
+	r.NotEmpty(sink.lastDrop.String())
+}
+
+func TestKafkaSink_Batch(t *testing.T) {
+	r := require.New(t)
+
+	writer := &testKafkaWriter{}
+	sink := newKafkaSink(context.Background(), config.KafkaConfig{
+		Encoding:    "json",
+		PartitionBy: "agent",
+		Batch:       config.SinkBatchConfig{MaxAlerts: 2, IntervalSeconds: 60},
+	}, 137, writer)
+	sink.Start()
+
+	sink.Send(testKafkaAlert("alert1", "agent1"))
+	sink.Send(testKafkaAlert("alert2", "agent2"))
+	sink.Send(testKafkaAlert("alert3", "agent1"))
+
+	// the last alert is flushed while stopping
+	r.NoError(sink.Stop())
+	msgs := writer.messages()
+	r.Len(msgs, 2)
+	r.Equal("137", string(msgs[0].Key))
+	r.Contains(string(msgs[0].Value), `"id":"alert2"`)
+	r.Contains(string(msgs[1].Value), `"id":"alert3"`)
+}
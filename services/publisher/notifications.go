@@ -0,0 +1,129 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+)
+
+const (
+	telegramAPIURL           = "https://api.telegram.org"
+	pagerDutyEventsURL       = "https://events.pagerduty.com/v2/enqueue"
+	notificationMaxRetries   = 5
+	notificationMaxTextRunes = 3000
+)
+
+// NewNotificationSinks creates the sinks which post the alerts to Slack, Telegram and PagerDuty.
+func NewNotificationSinks(ctx context.Context, cfg config.NotificationsConfig, deadLetters *deadLetterQueue) []AlertSink {
+	var sinks []AlertSink
+	for i, slackCfg := range cfg.Slack {
+		sinks = append(sinks, newWebhookSink(ctx, fmt.Sprintf("slack-%d", i), config.WebhookSinkConfig{
+			URL:        slackCfg.WebhookURL,
+			Filter:     slackCfg.Filter,
+			MaxRetries: notificationMaxRetries,
+		}, formatSlack, deadLetters))
+	}
+	for i, telegramCfg := range cfg.Telegram {
+		sinks = append(sinks, newWebhookSink(ctx, fmt.Sprintf("telegram-%d", i), config.WebhookSinkConfig{
+			URL:        fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIURL, telegramCfg.BotToken),
+			Filter:     telegramCfg.Filter,
+			MaxRetries: notificationMaxRetries,
+		}, telegramFormatter(telegramCfg.ChatID), deadLetters))
+	}
+	for i, pagerDutyCfg := range cfg.PagerDuty {
+		sinks = append(sinks, newWebhookSink(ctx, fmt.Sprintf("pagerduty-%d", i), config.WebhookSinkConfig{
+			URL:        pagerDutyEventsURL,
+			Filter:     pagerDutyCfg.Filter,
+			MaxRetries: notificationMaxRetries,
+		}, pagerDutyFormatter(pagerDutyCfg.RoutingKey), deadLetters))
+	}
+	return sinks
+}
+
+// alertText makes a short human-readable text from the alert.
+func alertText(alert *protocol.SignedAlert) string {
+	finding := alert.Alert.Finding
+	var agentID string
+	if alert.Alert.Agent != nil {
+		agentID = alert.Alert.Agent.Id
+	}
+	text := fmt.Sprintf(
+		"[%s] %s\n%s\nAlert ID: %s\nAgent: %s\nHash: %s",
+		finding.Severity.String(), finding.Name, finding.Description, finding.AlertId, agentID, alert.Alert.Id,
+	)
+	if runes := []rune(text); len(runes) > notificationMaxTextRunes {
+		text = string(runes[:notificationMaxTextRunes]) + "..."
+	}
+	return text
+}
+
+func marshalString(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func formatSlack(alert *protocol.SignedAlert) (string, error) {
+	return marshalString(map[string]string{"text": alertText(alert)})
+}
+
+func telegramFormatter(chatID string) alertFormatter {
+	return func(alert *protocol.SignedAlert) (string, error) {
+		return marshalString(map[string]string{
+			"chat_id": chatID,
+			"text":    alertText(alert),
+		})
+	}
+}
+
+// pagerDutySeverity maps the finding severities to the PagerDuty event severities.
+func pagerDutySeverity(severity protocol.Finding_Severity) string {
+	switch severity {
+	case protocol.Finding_CRITICAL:
+		return "critical"
+	case protocol.Finding_HIGH:
+		return "error"
+	case protocol.Finding_MEDIUM:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+func pagerDutyFormatter(routingKey string) alertFormatter {
+	return func(alert *protocol.SignedAlert) (string, error) {
+		finding := alert.Alert.Finding
+		source := "forta"
+		if alert.Alert.Agent != nil {
+			source = alert.Alert.Agent.Id
+		}
+		return marshalString(&pagerDutyEvent{
+			RoutingKey:  routingKey,
+			EventAction: "trigger",
+			DedupKey:    alert.Alert.Id,
+			Payload: pagerDutyPayload{
+				Summary:       strings.TrimSpace(fmt.Sprintf("%s: %s", finding.Name, finding.Description)),
+				Source:        source,
+				Severity:      pagerDutySeverity(finding.Severity),
+				CustomDetails: finding.Metadata,
+			},
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testNotificationAlert() *protocol.SignedAlert {
+	return &protocol.SignedAlert{
+		Alert: &protocol.Alert{
+			Id:    "0xalert",
+			Agent: &protocol.AgentInfo{Id: "0xagent"},
+			Finding: &protocol.Finding{
+				AlertId:     "EXPLOIT-1",
+				Name:        "Exploit",
+				Description: "Funds drained",
+				Severity:    protocol.Finding_CRITICAL,
+				Metadata:    map[string]string{"amount": "100"},
+			},
+		},
+	}
+}
+
+func TestNewNotificationSinks(t *testing.T) {
+	r := require.New(t)
+
+	sinks := NewNotificationSinks(context.Background(), config.NotificationsConfig{
+		Slack:     []config.SlackConfig{{WebhookURL: "https://hooks.slack.com/services/x"}},
+		Telegram:  []config.TelegramConfig{{BotToken: "token", ChatID: "123"}},
+		PagerDuty: []config.PagerDutyConfig{{RoutingKey: "key"}},
+	}, newDeadLetterQueue(""))
+	r.Len(sinks, 3)
+	r.Equal("slack-0", sinks[0].Name())
+	r.Equal("https://api.telegram.org/bottoken/sendMessage", sinks[1].(*WebhookSink).cfg.URL)
+	r.Equal(pagerDutyEventsURL, sinks[2].(*WebhookSink).cfg.URL)
+}
+
+func TestFormatSlack(t *testing.T) {
+	r := require.New(t)
+
+	body, err := formatSlack(testNotificationAlert())
+	r.NoError(err)
+	var msg map[string]string
+	r.NoError(json.Unmarshal([]byte(body), &msg))
+	r.Equal("[CRITICAL] Exploit\nFunds drained\nAlert ID: EXPLOIT-1\nAgent: 0xagent\nHash: 0xalert", msg["text"])
+}
+
+func TestFormatTelegram(t *testing.T) {
+	r := require.New(t)
+
+	body, err := telegramFormatter("123")(testNotificationAlert())
+	r.NoError(err)
+	var msg map[string]string
+	r.NoError(json.Unmarshal([]byte(body), &msg))
+	r.Equal("123", msg["chat_id"])
+	r.Contains(msg["text"], "[CRITICAL] Exploit")
+}
+
+func TestFormatPagerDuty(t *testing.T) {
+	r := require.New(t)
+
+	body, err := pagerDutyFormatter("key")(testNotificationAlert())
+	r.NoError(err)
+	var event pagerDutyEvent
+	r.NoError(json.Unmarshal([]byte(body), &event))
+	r.Equal("key", event.RoutingKey)
+	r.Equal("trigger", event.EventAction)
+	r.Equal("0xalert", event.DedupKey)
+	r.Equal("Exploit: Funds drained", event.Payload.Summary)
+	r.Equal("0xagent", event.Payload.Source)
+	r.Equal("critical", event.Payload.Severity)
+	r.Equal("100", event.Payload.CustomDetails["amount"])
+}
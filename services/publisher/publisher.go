@@ -4,17 +4,22 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"path"
+	"reflect"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/clients/webhook"
 	"github.com/forta-network/forta-core-go/clients/webhook/client/operations"
@@ -24,12 +29,14 @@ import (
 	"github.com/forta-network/forta-core-go/protocol/transform"
 	"github.com/forta-network/forta-core-go/release"
 	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-node/audit"
 	"github.com/forta-network/forta-node/clients"
 	"github.com/forta-network/forta-node/clients/alertapi"
 	"github.com/forta-network/forta-node/clients/messaging"
 	"github.com/forta-network/forta-node/config"
 	"github.com/forta-network/forta-node/services/publisher/testalerts"
 	"github.com/forta-network/forta-node/store"
+	"github.com/forta-network/forta-node/tracing"
 	ipfsapi "github.com/ipfs/go-ipfs-api"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
@@ -50,12 +57,23 @@ type Publisher struct {
 	ipfs              ipfs.Client
 	testAlertLogger   TestAlertLogger
 	metricsAggregator *AgentMetricsAggregator
-	messageClient     *messaging.Client
+	messageClient     clients.MessageClient
 	alertClient       clients.AlertAPIClient
 	webhookClient     webhook.AlertWebhookClient
+	sinks             []AlertSink
+	routes            alertRoutes
+	sinksCfg          config.PublisherConfig // only the sink settings are used
+	sinksMu           sync.RWMutex
+	deadLetters       *deadLetterQueue
+	sampler           *alertSampler
+	incidents         *incidentCorrelator
+	anchor            *batchAnchor
+	alertStore        *store.AlertFileStore
+	tracer            *blockTracer
 
 	batchRefStore    store.StringStore
 	lastReceiptStore store.StringStore
+	batchLog         *batchLog
 
 	server *grpc.Server
 
@@ -72,6 +90,7 @@ type Publisher struct {
 	lastBatchSkip       health.TimeTracker
 	lastBatchSkipReason health.MessageTracker
 	lastBatchPublishErr health.ErrorTracker
+	lastBatchUpload     health.TimeTracker
 	lastMetricsFlush    health.TimeTracker
 
 	latestBlockInput   uint64
@@ -152,6 +171,10 @@ func (pub *Publisher) publishNextBatch(batch *protocol.AlertBatch) error {
 	}
 	log.Tracef("alert payload: %s", string(buf.Bytes()))
 
+	if pub.cfg.Config.IsReplay() {
+		return pub.storeReplayBatch(batch, buf.Bytes())
+	}
+
 	if pub.skipPublish {
 		const reason = "skipping batch, because skipPublish is enabled"
 		log.Infof("alert batch: blockStart=%d, blockEnd=%d, alertCount=%d, maxSeverity=%s", batch.BlockStart, batch.BlockEnd, batch.AlertCount, batch.MaxSeverity.String())
@@ -180,13 +203,38 @@ func (pub *Publisher) publishNextBatch(batch *protocol.AlertBatch) error {
 		return err
 	}
 
-	cid, err := pub.ipfs.CalculateFileHash(buf.Bytes())
+	cid, err := pub.batchRef(buf.Bytes())
 	if err != nil {
 		return fmt.Errorf("failed to store alert data to ipfs: %v", err)
 	}
 	if err := pub.batchRefStore.Put(cid); err != nil {
 		return fmt.Errorf("failed to write last batch ref: %v", err)
 	}
+	if pub.anchor != nil {
+		pub.anchor.Anchor(&BatchRecord{
+			Ref:        cid,
+			Hash:       crypto.Keccak256Hash(buf.Bytes()).Hex(),
+			ChainID:    batch.ChainId,
+			BlockStart: batch.BlockStart,
+			BlockEnd:   batch.BlockEnd,
+			AlertCount: batch.AlertCount,
+			Timestamp:  time.Now().UTC(),
+		})
+	}
+	if pub.cfg.PublisherConfig.UploadBatches {
+		pub.lastBatchUpload.Set()
+		err := pub.batchLog.Append(&BatchRecord{
+			Ref:        cid,
+			ChainID:    batch.ChainId,
+			BlockStart: batch.BlockStart,
+			BlockEnd:   batch.BlockEnd,
+			AlertCount: batch.AlertCount,
+			Timestamp:  time.Now().UTC(),
+		})
+		if err != nil {
+			log.WithError(err).Warn("failed to record the uploaded batch")
+		}
+	}
 
 	logger := log.WithFields(
 		log.Fields{
@@ -273,6 +321,92 @@ func (pub *Publisher) publishNextBatch(batch *protocol.AlertBatch) error {
 	return nil
 }
 
+// sendToSinks sends the alert to the sinks of the matching routes or to all sinks if there are no routes.
+func (pub *Publisher) sendToSinks(alert *protocol.SignedAlert) {
+	pub.sinksMu.RLock()
+	defer pub.sinksMu.RUnlock()
+	sinks := pub.sinks
+	if pub.routes != nil {
+		sinks = pub.routes.Route(pub.cfg.ChainID, alert)
+	}
+	for _, sink := range sinks {
+		sink.Send(alert)
+	}
+}
+
+func (pub *Publisher) getSinks() []AlertSink {
+	pub.sinksMu.RLock()
+	defer pub.sinksMu.RUnlock()
+	return pub.sinks
+}
+
+// Reload replaces the alert sinks and the routes if their settings have changed. The new sinks are
+// started before the old sinks are stopped, so that the alerts keep going out.
+func (pub *Publisher) Reload(cfg config.Config) error {
+	pub.sinksMu.RLock()
+	changed := !reflect.DeepEqual(alertSinkSettings(pub.sinksCfg), alertSinkSettings(cfg.Publish))
+	pub.sinksMu.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	sinks, routes, err := newAlertSinks(pub.ctx, pub.cfg, cfg.Publish, pub.deadLetters)
+	if err != nil {
+		return err
+	}
+	for _, sink := range sinks {
+		sink.Start()
+	}
+
+	pub.sinksMu.Lock()
+	oldSinks := pub.sinks
+	pub.sinks = sinks
+	pub.routes = routes
+	pub.sinksCfg = cfg.Publish
+	pub.sinksMu.Unlock()
+
+	for _, sink := range oldSinks {
+		if err := sink.Stop(); err != nil {
+			log.WithError(err).WithField("sink", sink.Name()).Warn("failed to stop alert sink")
+		}
+	}
+	log.WithField("sinks", len(sinks)).Info("reloaded alert sinks")
+	return nil
+}
+
+func alertSinkSettings(cfg config.PublisherConfig) []interface{} {
+	return []interface{}{cfg.Kafka, cfg.JetStream, cfg.Webhooks, cfg.Notifications, cfg.Syslog, cfg.Routes}
+}
+
+// batchRef returns the IPFS CID of the signed batch. The batch is uploaded to IPFS only if enabled.
+func (pub *Publisher) batchRef(signedBatch []byte) (string, error) {
+	if pub.cfg.PublisherConfig.UploadBatches {
+		return pub.ipfs.AddFile(signedBatch)
+	}
+	return pub.ipfs.CalculateFileHash(signedBatch)
+}
+
+// storeReplayBatch writes the batches of a replay to the replay directory instead of publishing them.
+func (pub *Publisher) storeReplayBatch(batch *protocol.AlertBatch, signedBatch []byte) error {
+	logger := log.WithFields(log.Fields{
+		"blockStart": batch.BlockStart,
+		"blockEnd":   batch.BlockEnd,
+		"alertCount": batch.AlertCount,
+	})
+	if batch.AlertCount == 0 {
+		logger.Info("skipping replay batch without alerts")
+		pub.lastBatchSkip.Set()
+		pub.lastBatchSkipReason.Set("because there are no alerts in the replay batch")
+		return nil
+	}
+	fileName := path.Join(pub.cfg.Config.ReplayDir(), fmt.Sprintf("batch-%d-%d.json", batch.BlockStart, batch.BlockEnd))
+	if err := ioutil.WriteFile(fileName, signedBatch, 0644); err != nil {
+		return fmt.Errorf("failed to write replay batch: %v", err)
+	}
+	logger.WithField("file", fileName).Info("replay batch")
+	return nil
+}
+
 func (pub *Publisher) shouldSkipPublishing(batch *protocol.AlertBatch) (string, bool) {
 	if batch.AlertCount > 0 {
 		return "", false
@@ -288,12 +422,24 @@ func (pub *Publisher) shouldSkipPublishing(batch *protocol.AlertBatch) (string,
 	return "", false
 }
 
+// isMainChain tells if the publisher publishes the alerts of the main chain of the node.
+func (pub *Publisher) isMainChain() bool {
+	return pub.cfg.ChainID == pub.cfg.Config.ChainID
+}
+
 func (pub *Publisher) registerMessageHandlers() {
-	pub.messageClient.Subscribe(messaging.SubjectMetricAgent, messaging.AgentMetricHandler(pub.metricsAggregator.AddAgentMetrics))
+	// the agent metrics are published only with the batches of the main chain
+	if pub.isMainChain() {
+		pub.messageClient.Subscribe(messaging.SubjectMetricAgent, messaging.AgentMetricHandler(pub.metricsAggregator.AddAgentMetrics))
+	}
 	pub.messageClient.Subscribe(messaging.SubjectScannerBlock, messaging.ScannerHandler(pub.handleScannerBlock))
 }
 
 func (pub *Publisher) handleScannerBlock(payload messaging.ScannerPayload) error {
+	if payload.ChainID != 0 && payload.ChainID != uint64(pub.cfg.ChainID) {
+		return nil
+	}
+
 	pub.latestBlockInputMu.Lock()
 	defer pub.latestBlockInputMu.Unlock()
 
@@ -508,6 +654,7 @@ func (pub *Publisher) prepareLatestBatch() {
 	for i < pub.batchLimit {
 		select {
 		case notif := <-pub.notifCh:
+			times := notificationTimes{Handled: time.Now()}
 			alert := notif.SignedAlert
 			hasAlert := alert != nil
 			if hasAlert {
@@ -524,6 +671,40 @@ func (pub *Publisher) prepareLatestBatch() {
 				continue
 			}
 
+			// keep the sampled out alerts as notifications without alerts so the batch still shows
+			// that the agent processed the block or the transaction
+			if hasAlert && !pub.sampler.Keep(alert, time.Now()) {
+				notif.SignedAlert = nil
+				alert = nil
+				hasAlert = false
+			}
+
+			// the alerts are not stored again after the restarts and the replays but they are still published,
+			// since the alert can be stored before a batch which failed to publish
+			if hasAlert && pub.alertStore != nil {
+				err := pub.alertStore.Put(alert)
+				switch {
+				case errors.Is(err, store.ErrDuplicateAlert):
+					log.WithField("alertHash", store.StoredAlertHash(alert)).Debug("skipped storing the duplicate alert")
+				case err != nil:
+					log.WithError(err).Warn("failed to store the alert")
+				default:
+					times.Stored = time.Now()
+				}
+			}
+
+			if hasAlert {
+				pub.sendToSinks(alert)
+				times.Published = time.Now()
+				if pub.incidents != nil {
+					pub.incidents.Add(alert, time.Now())
+				}
+			}
+
+			if pub.tracer != nil {
+				pub.tracer.Trace(notif, times)
+			}
+
 			// Notifications with empty alerts shouldn't be taken into account while limiting the batch.
 			// Otherwise, we create too many batches very quickly.
 			if hasAlert {
@@ -568,8 +749,14 @@ func (pub *Publisher) prepareLatestBatch() {
 }
 
 func (pub *Publisher) Start() error {
+	for _, sink := range pub.getSinks() {
+		sink.Start()
+	}
 	go pub.prepareBatches()
 	go pub.publishBatches()
+	if pub.anchor != nil {
+		go pub.anchor.run()
+	}
 	pub.registerMessageHandlers()
 	return nil
 }
@@ -579,16 +766,24 @@ func (pub *Publisher) Stop() error {
 	if pub.server != nil {
 		pub.server.Stop()
 	}
+	for _, sink := range pub.getSinks() {
+		if err := sink.Stop(); err != nil {
+			log.WithError(err).WithField("sink", sink.Name()).Warn("failed to stop alert sink")
+		}
+	}
 	return nil
 }
 
 func (pub *Publisher) Name() string {
+	if !pub.isMainChain() {
+		return fmt.Sprintf("publisher-%d", pub.cfg.ChainID)
+	}
 	return "publisher"
 }
 
 // Health implements the health.Reporter interface.
 func (pub *Publisher) Health() health.Reports {
-	return health.Reports{
+	reports := health.Reports{
 		pub.lastBatchPublish.GetReport("event.batch-publish.time"),
 		pub.lastBatchPublishErr.GetReport("event.batch-publish.error"),
 		&health.Report{
@@ -598,16 +793,59 @@ func (pub *Publisher) Health() health.Reports {
 		},
 		pub.lastBatchSkipReason.GetReport("event.batch-skip.reason"),
 		pub.lastMetricsFlush.GetReport("event.metrics-flush.time"),
+		pub.lastBatchUpload.GetReport("event.batch-upload.time"),
+		&health.Report{
+			Name:    "backlog.alerts",
+			Status:  health.StatusInfo,
+			Details: strconv.Itoa(len(pub.notifCh)),
+		},
+		&health.Report{
+			Name:    "backlog.batches",
+			Status:  health.StatusInfo,
+			Details: strconv.Itoa(len(pub.batchCh)),
+		},
 	}
+	reports = append(reports, pub.sampler.Health()...)
+	if pub.deadLetters != nil {
+		reports = append(reports, pub.deadLetters.Health()...)
+	}
+	if pub.anchor != nil {
+		reports = append(reports, pub.anchor.Health()...)
+	}
+	if pub.tracer != nil {
+		reports = append(reports, pub.tracer.exporter.Health()...)
+	}
+	for _, sink := range pub.getSinks() {
+		reports = append(reports, sink.Health()...)
+	}
+	return reports
+}
+
+// Incidents implements the IncidentSource interface.
+func (pub *Publisher) Incidents() []*Incident {
+	if pub.incidents == nil {
+		return nil
+	}
+	return pub.incidents.Incidents()
 }
 
 func NewPublisher(ctx context.Context, cfg config.Config) (*Publisher, error) {
+	return newPublisher(ctx, cfg, cfg.ChainID)
+}
+
+// NewChainPublisher creates a publisher for one of the additional chains which the node scans.
+func NewChainPublisher(ctx context.Context, cfg config.Config, chain config.ChainConfig) (*Publisher, error) {
+	return newPublisher(ctx, cfg, chain.ChainID)
+}
+
+func newPublisher(ctx context.Context, cfg config.Config, chainID int) (*Publisher, error) {
 	mc := messaging.NewClient("metrics", fmt.Sprintf("%s:%s", config.DockerNatsContainerName, config.DefaultNatsPort))
 
 	key, err := security.LoadKey(config.DefaultContainerKeyDirPath)
 	if err != nil {
 		return nil, err
 	}
+	audit.Record(audit.EventKeyUsed, map[string]string{"address": key.Address.Hex(), "purpose": "publisher"})
 
 	releaseInfoStr := os.Getenv(config.EnvReleaseInfo)
 	var releaseSummary *release.ReleaseSummary
@@ -619,7 +857,7 @@ func NewPublisher(ctx context.Context, cfg config.Config) (*Publisher, error) {
 	apiClient := alertapi.NewClient(cfg.Publish.APIURL)
 
 	return initPublisher(ctx, mc, apiClient, PublisherConfig{
-		ChainID:         cfg.ChainID,
+		ChainID:         chainID,
 		Key:             key,
 		PublisherConfig: cfg.Publish,
 		ReleaseSummary:  releaseSummary,
@@ -627,7 +865,16 @@ func NewPublisher(ctx context.Context, cfg config.Config) (*Publisher, error) {
 	})
 }
 
-func initPublisher(ctx context.Context, mc *messaging.Client, alertClient clients.AlertAPIClient, cfg PublisherConfig) (*Publisher, error) {
+// chainFileName keeps the file names of the main chain the same and adds the chain ID
+// to the file names of the additional chains.
+func chainFileName(cfg PublisherConfig, name string) string {
+	if cfg.ChainID == cfg.Config.ChainID {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", name, cfg.ChainID)
+}
+
+func initPublisher(ctx context.Context, mc clients.MessageClient, alertClient clients.AlertAPIClient, cfg PublisherConfig) (*Publisher, error) {
 	ipfsClient, err := ipfs.NewClient(fmt.Sprintf("http://%s:5001", config.DockerIpfsContainerName))
 	if err != nil {
 		return nil, err
@@ -648,6 +895,15 @@ func initPublisher(ctx context.Context, mc *messaging.Client, alertClient client
 		testAlertLogger = testalerts.NewLogger(cfg.PublisherConfig.TestAlerts.WebhookURL)
 	}
 
+	// keep the alerts of a replay apart from the published alerts
+	storeDir := cfg.Config.FortaDir
+	if cfg.Config.IsReplay() {
+		storeDir = cfg.Config.ReplayDir()
+		if err := os.MkdirAll(storeDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create the replay dir: %v", err)
+		}
+	}
+
 	var webhookClient webhook.AlertWebhookClient
 	if cfg.Config.PrivateModeConfig.Enable {
 		dest := cfg.Config.PrivateModeConfig.WebhookURL
@@ -657,6 +913,39 @@ func initPublisher(ctx context.Context, mc *messaging.Client, alertClient client
 		}
 	}
 
+	deadLetters := newDeadLetterQueue(path.Join(storeDir, chainFileName(cfg, "dead-letters.log")))
+	sinks, routes, err := newAlertSinks(ctx, cfg, cfg.PublisherConfig, deadLetters)
+	if err != nil {
+		return nil, err
+	}
+
+	var anchor *batchAnchor
+	if cfg.PublisherConfig.Anchor.Enable {
+		anchorLog := &batchLog{path: path.Join(storeDir, chainFileName(cfg, "anchored-batches.log"))}
+		anchor, err = newBatchAnchor(ctx, cfg.PublisherConfig.Anchor, cfg.Key, anchorLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the batch anchor: %v", err)
+		}
+	}
+
+	// the alerts of a replay are in the replay batches
+	var alertStore *store.AlertFileStore
+	if !cfg.PublisherConfig.Store.Disable && !cfg.Config.IsReplay() {
+		alertStore = store.NewAlertFileStore(
+			path.Join(cfg.Config.FortaDir, config.DefaultAlertsDirName), uint64(cfg.ChainID), cfg.PublisherConfig.Store.RetentionDays,
+		)
+	}
+
+	var tracer *blockTracer
+	if cfg.Config.Tracing.Enable && !cfg.Config.IsReplay() {
+		tracer = newBlockTracer(tracing.NewExporter(ctx, cfg.Config.Tracing))
+	}
+
+	var incidents *incidentCorrelator
+	if cfg.PublisherConfig.Incidents.Enable {
+		incidents = newIncidentCorrelator(cfg.PublisherConfig.Incidents, uint64(cfg.ChainID))
+	}
+
 	return &Publisher{
 		ctx:               ctx,
 		cfg:               cfg,
@@ -666,8 +955,18 @@ func initPublisher(ctx context.Context, mc *messaging.Client, alertClient client
 		messageClient:     mc,
 		alertClient:       alertClient,
 		webhookClient:     webhookClient,
-		batchRefStore:     store.NewFileStringStore(path.Join(cfg.Config.FortaDir, ".last-batch")),
-		lastReceiptStore:  store.NewFileStringStore(path.Join(cfg.Config.FortaDir, ".last-receipt")),
+		sinks:             sinks,
+		routes:            routes,
+		sinksCfg:          cfg.PublisherConfig,
+		deadLetters:       deadLetters,
+		sampler:           newAlertSampler(cfg.PublisherConfig.Sampling),
+		incidents:         incidents,
+		anchor:            anchor,
+		alertStore:        alertStore,
+		tracer:            tracer,
+		batchRefStore:     store.NewFileStringStore(path.Join(storeDir, chainFileName(cfg, ".last-batch"))),
+		lastReceiptStore:  store.NewFileStringStore(path.Join(storeDir, chainFileName(cfg, ".last-receipt"))),
+		batchLog:          &batchLog{path: path.Join(storeDir, chainFileName(cfg, "uploaded-batches.log"))},
 
 		skipEmpty:     cfg.PublisherConfig.Batch.SkipEmpty,
 		skipPublish:   cfg.PublisherConfig.SkipPublish,
@@ -677,3 +976,32 @@ func initPublisher(ctx context.Context, mc *messaging.Client, alertClient client
 		batchCh:       make(chan *protocol.AlertBatch, defaultBatchBufferSize),
 	}, nil
 }
+
+// newAlertSinks creates the sinks and the routes from the sink settings.
+func newAlertSinks(
+	ctx context.Context, cfg PublisherConfig, publishCfg config.PublisherConfig, deadLetters *deadLetterQueue,
+) ([]AlertSink, alertRoutes, error) {
+	var sinks []AlertSink
+	if publishCfg.Kafka.Enable {
+		sinks = append(sinks, NewKafkaSink(ctx, publishCfg.Kafka, cfg.ChainID))
+	}
+	if publishCfg.JetStream.Enable {
+		sinks = append(sinks, NewJetStreamSink(ctx, publishCfg.JetStream, cfg.ChainID, deadLetters))
+	}
+	for i, webhookCfg := range publishCfg.Webhooks {
+		sinks = append(sinks, NewWebhookSink(ctx, fmt.Sprintf("webhook-%d", i), webhookCfg, deadLetters))
+	}
+	sinks = append(sinks, NewNotificationSinks(ctx, publishCfg.Notifications, deadLetters)...)
+	nodeVersion := "unknown"
+	if cfg.ReleaseSummary != nil && len(cfg.ReleaseSummary.Version) > 0 {
+		nodeVersion = cfg.ReleaseSummary.Version
+	}
+	for i, syslogCfg := range publishCfg.Syslog {
+		sinks = append(sinks, NewSyslogSink(ctx, fmt.Sprintf("syslog-%d", i), syslogCfg, nodeVersion, deadLetters))
+	}
+	routes, err := newAlertRoutes(publishCfg.Routes, sinks)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid alert routes: %v", err)
+	}
+	return sinks, routes, nil
+}
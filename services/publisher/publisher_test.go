@@ -1,7 +1,16 @@
 package publisher
 
 import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
 	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -77,3 +86,98 @@ func TestBatchData_AppendPrivateAlert_Block(t *testing.T) {
 	assert.Len(t, bd.PrivateAlerts[0].Alerts, 1)
 	assert.EqualValues(t, alert, bd.PrivateAlerts[0].Alerts[0])
 }
+
+func TestChainFileName(t *testing.T) {
+	mainChainCfg := PublisherConfig{ChainID: 1, Config: config.Config{ChainID: 1}}
+	assert.Equal(t, ".last-batch", chainFileName(mainChainCfg, ".last-batch"))
+
+	otherChainCfg := PublisherConfig{ChainID: 137, Config: config.Config{ChainID: 1}}
+	assert.Equal(t, ".last-batch-137", chainFileName(otherChainCfg, ".last-batch"))
+}
+
+func TestHandleScannerBlock_OtherChain(t *testing.T) {
+	pub := &Publisher{cfg: PublisherConfig{ChainID: 137, Config: config.Config{ChainID: 1}}}
+
+	assert.NoError(t, pub.handleScannerBlock(messaging.ScannerPayload{ChainID: 1, LatestBlockInput: 100}))
+	assert.Equal(t, uint64(0), pub.latestBlockInput)
+
+	assert.NoError(t, pub.handleScannerBlock(messaging.ScannerPayload{ChainID: 137, LatestBlockInput: 200}))
+	assert.Equal(t, uint64(200), pub.latestBlockInput)
+}
+
+func TestStoreReplayBatch(t *testing.T) {
+	r := assert.New(t)
+
+	cfg := config.Config{FortaDir: t.TempDir()}
+	cfg.Scan.StartBlock = 10
+	cfg.Scan.EndBlock = 20
+	r.NoError(os.MkdirAll(cfg.ReplayDir(), 0755))
+	pub := &Publisher{cfg: PublisherConfig{Config: cfg}}
+
+	r.NoError(pub.storeReplayBatch(&protocol.AlertBatch{BlockStart: 10, BlockEnd: 12}, []byte("empty")))
+	r.NoError(pub.storeReplayBatch(&protocol.AlertBatch{BlockStart: 13, BlockEnd: 15, AlertCount: 1}, []byte("alerts")))
+
+	files, err := ioutil.ReadDir(cfg.ReplayDir())
+	r.NoError(err)
+	if r.Len(files, 1) {
+		r.Equal("batch-13-15.json", files[0].Name())
+	}
+}
+
+type testIPFSClient struct {
+	added []string
+}
+
+func (c *testIPFSClient) AddFile(payload []byte) (string, error) {
+	c.added = append(c.added, string(payload))
+	return "added-ref", nil
+}
+
+func (c *testIPFSClient) CalculateFileHash(payload []byte) (string, error) {
+	return "hash-ref", nil
+}
+
+func (c *testIPFSClient) GetBytes(ctx context.Context, reference string) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *testIPFSClient) UnmarshalJson(ctx context.Context, reference string, target interface{}) error {
+	return nil
+}
+
+func TestBatchRef(t *testing.T) {
+	r := assert.New(t)
+
+	ipfsClient := &testIPFSClient{}
+	pub := &Publisher{ipfs: ipfsClient}
+
+	ref, err := pub.batchRef([]byte("batch"))
+	r.NoError(err)
+	r.Equal("hash-ref", ref)
+	r.Empty(ipfsClient.added)
+
+	pub.cfg.PublisherConfig.UploadBatches = true
+	ref, err = pub.batchRef([]byte("batch"))
+	r.NoError(err)
+	r.Equal("added-ref", ref)
+	r.Equal([]string{"batch"}, ipfsClient.added)
+}
+
+func TestBatchLog(t *testing.T) {
+	r := assert.New(t)
+
+	bl := &batchLog{path: path.Join(t.TempDir(), "uploaded-batches.log")}
+	r.NoError(bl.Append(&BatchRecord{Ref: "ref1", ChainID: 1, BlockStart: 10, BlockEnd: 12}))
+	r.NoError(bl.Append(&BatchRecord{Ref: "ref2", ChainID: 1, BlockStart: 13, BlockEnd: 15, AlertCount: 2}))
+
+	b, err := ioutil.ReadFile(bl.path)
+	r.NoError(err)
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	var refs []string
+	for dec.More() {
+		var record BatchRecord
+		r.NoError(dec.Decode(&record))
+		refs = append(refs, record.Ref)
+	}
+	r.Equal([]string{"ref1", "ref2"}, refs)
+}
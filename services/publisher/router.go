@@ -0,0 +1,50 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/clients"
+)
+
+// ChainRouter sends the notifications to the publishers of the chains which they come from.
+// The notifications from unknown chains are sent to the main publisher.
+type ChainRouter struct {
+	main       clients.PublishClient
+	publishers map[uint64]clients.PublishClient
+}
+
+// NewChainRouter creates a new chain router.
+func NewChainRouter(main clients.PublishClient) *ChainRouter {
+	return &ChainRouter{
+		main:       main,
+		publishers: make(map[uint64]clients.PublishClient),
+	}
+}
+
+// AddChain adds the publisher of an additional chain.
+func (r *ChainRouter) AddChain(chainID uint64, publisher clients.PublishClient) {
+	r.publishers[chainID] = publisher
+}
+
+// Notify implements clients.PublishClient.
+func (r *ChainRouter) Notify(ctx context.Context, req *protocol.NotifyRequest) (*protocol.NotifyResponse, error) {
+	chainID, err := hexutil.DecodeUint64(notifChainID(req))
+	if err == nil {
+		if publisher, ok := r.publishers[chainID]; ok {
+			return publisher.Notify(ctx, req)
+		}
+	}
+	return r.main.Notify(ctx, req)
+}
+
+func notifChainID(req *protocol.NotifyRequest) string {
+	if req.EvalBlockRequest != nil {
+		return req.EvalBlockRequest.Event.GetNetwork().GetChainId()
+	}
+	if req.EvalTxRequest != nil {
+		return req.EvalTxRequest.Event.GetNetwork().GetChainId()
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+type testPublishClient struct {
+	notifs []*protocol.NotifyRequest
+}
+
+func (c *testPublishClient) Notify(ctx context.Context, req *protocol.NotifyRequest) (*protocol.NotifyResponse, error) {
+	c.notifs = append(c.notifs, req)
+	return &protocol.NotifyResponse{}, nil
+}
+
+func TestChainRouter(t *testing.T) {
+	r := require.New(t)
+
+	mainPub := &testPublishClient{}
+	otherPub := &testPublishClient{}
+	router := NewChainRouter(mainPub)
+	router.AddChain(137, otherPub)
+
+	txNotif := func(chainID string) *protocol.NotifyRequest {
+		return &protocol.NotifyRequest{EvalTxRequest: &protocol.EvaluateTxRequest{Event: &protocol.TransactionEvent{
+			Network: &protocol.TransactionEvent_Network{ChainId: chainID},
+		}}}
+	}
+	blockNotif := &protocol.NotifyRequest{EvalBlockRequest: &protocol.EvaluateBlockRequest{Event: &protocol.BlockEvent{
+		Network: &protocol.BlockEvent_Network{ChainId: "0x89"},
+	}}}
+
+	for _, notif := range []*protocol.NotifyRequest{txNotif("0x1"), txNotif("0x89"), txNotif(""), blockNotif} {
+		_, err := router.Notify(context.Background(), notif)
+		r.NoError(err)
+	}
+	r.Len(mainPub.notifs, 2)
+	r.Len(otherPub.notifs, 2)
+}
@@ -0,0 +1,70 @@
+package publisher
+
+import (
+	"fmt"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+)
+
+// alertRoute sends the matching alerts to a set of sinks.
+type alertRoute struct {
+	filter   *alertFilter
+	chainIDs map[int]bool
+	sinks    []AlertSink
+}
+
+func (route *alertRoute) matches(chainID int, alert *protocol.SignedAlert) bool {
+	if route.chainIDs != nil && !route.chainIDs[chainID] {
+		return false
+	}
+	return route.filter.Matches(alert)
+}
+
+// alertRoutes decide which sinks the alerts go to.
+type alertRoutes []*alertRoute
+
+func newAlertRoutes(cfgs []config.AlertRouteConfig, sinks []AlertSink) (alertRoutes, error) {
+	sinksByName := make(map[string]AlertSink)
+	for _, sink := range sinks {
+		sinksByName[sink.Name()] = sink
+	}
+
+	var routes alertRoutes
+	for i, cfg := range cfgs {
+		route := &alertRoute{filter: newAlertFilter(cfg.Filter)}
+		if len(cfg.ChainIDs) > 0 {
+			route.chainIDs = make(map[int]bool)
+			for _, chainID := range cfg.ChainIDs {
+				route.chainIDs[chainID] = true
+			}
+		}
+		for _, name := range cfg.Sinks {
+			sink, ok := sinksByName[name]
+			if !ok {
+				return nil, fmt.Errorf("route %d: unknown alert sink '%s'", i, name)
+			}
+			route.sinks = append(route.sinks, sink)
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// Route returns the sinks of all routes which match the alert. Every sink is returned once.
+func (routes alertRoutes) Route(chainID int, alert *protocol.SignedAlert) []AlertSink {
+	var result []AlertSink
+	added := make(map[AlertSink]bool)
+	for _, route := range routes {
+		if !route.matches(chainID, alert) {
+			continue
+		}
+		for _, sink := range route.sinks {
+			if !added[sink] {
+				added[sink] = true
+				result = append(result, sink)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,118 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testSink struct {
+	name   string
+	alerts []*protocol.SignedAlert
+}
+
+func (s *testSink) Send(alert *protocol.SignedAlert) {
+	s.alerts = append(s.alerts, alert)
+}
+
+func (s *testSink) Start() {}
+
+func (s *testSink) Stop() error {
+	return nil
+}
+
+func (s *testSink) Name() string {
+	return s.name
+}
+
+func (s *testSink) Health() health.Reports {
+	return nil
+}
+
+func TestAlertRoutes(t *testing.T) {
+	r := require.New(t)
+
+	kafka := &testSink{name: "kafka"}
+	pagerDuty := &testSink{name: "pagerduty-0"}
+	slack := &testSink{name: "slack-0"}
+
+	routes, err := newAlertRoutes([]config.AlertRouteConfig{
+		{Sinks: []string{"kafka"}},
+		{Filter: config.AlertFilterConfig{MinSeverity: "CRITICAL"}, Sinks: []string{"pagerduty-0", "kafka"}},
+		{
+			Filter:   config.AlertFilterConfig{Metadata: map[string]string{"type": "exploit"}},
+			ChainIDs: []int{137},
+			Sinks:    []string{"slack-0"},
+		},
+	}, []AlertSink{kafka, pagerDuty, slack})
+	r.NoError(err)
+
+	info := testWebhookAlert("info", "agent1", protocol.Finding_INFO)
+	r.Equal([]AlertSink{kafka}, routes.Route(1, info))
+
+	critical := testWebhookAlert("critical", "agent1", protocol.Finding_CRITICAL)
+	r.Equal([]AlertSink{kafka, pagerDuty}, routes.Route(1, critical))
+
+	exploit := testWebhookAlert("exploit", "agent1", protocol.Finding_HIGH)
+	exploit.Alert.Finding.Metadata = map[string]string{"type": "exploit"}
+	r.Equal([]AlertSink{kafka}, routes.Route(1, exploit))
+	r.Equal([]AlertSink{kafka, slack}, routes.Route(137, exploit))
+}
+
+func TestAlertRoutes_UnknownSink(t *testing.T) {
+	_, err := newAlertRoutes([]config.AlertRouteConfig{{Sinks: []string{"kafka"}}}, nil)
+	require.Error(t, err)
+}
+
+func TestPublisher_SendToSinks(t *testing.T) {
+	r := require.New(t)
+
+	kafka := &testSink{name: "kafka"}
+	pagerDuty := &testSink{name: "pagerduty-0"}
+	alert := testWebhookAlert("info", "agent1", protocol.Finding_INFO)
+
+	pub := &Publisher{sinks: []AlertSink{kafka, pagerDuty}}
+	pub.sendToSinks(alert)
+	r.Len(kafka.alerts, 1)
+	r.Len(pagerDuty.alerts, 1)
+
+	routes, err := newAlertRoutes([]config.AlertRouteConfig{{Sinks: []string{"kafka"}}}, pub.sinks)
+	r.NoError(err)
+	pub.routes = routes
+	pub.sendToSinks(alert)
+	r.Len(kafka.alerts, 2)
+	r.Len(pagerDuty.alerts, 1)
+}
+
+func TestPublisher_ReloadSinks(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kafka := &testSink{name: "kafka"}
+	pub := &Publisher{ctx: ctx, sinks: []AlertSink{kafka}}
+
+	// no changes in the sink settings
+	r.NoError(pub.Reload(config.Config{}))
+	r.Equal([]AlertSink{kafka}, pub.getSinks())
+
+	var cfg config.Config
+	cfg.Publish.Webhooks = []config.WebhookSinkConfig{{URL: "http://localhost:8080"}}
+	cfg.Publish.Routes = []config.AlertRouteConfig{{Sinks: []string{"webhook-0"}}}
+	r.NoError(pub.Reload(cfg))
+	sinks := pub.getSinks()
+	r.Len(sinks, 1)
+	r.Equal("webhook-0", sinks[0].Name())
+	r.NotNil(pub.routes)
+
+	// invalid routes keep the current sinks
+	cfg.Publish.Routes = []config.AlertRouteConfig{{Sinks: []string{"kafka"}}}
+	r.Error(pub.Reload(cfg))
+	r.Equal(sinks, pub.getSinks())
+	r.NoError(pub.Stop())
+}
@@ -0,0 +1,100 @@
+package publisher
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+)
+
+const samplingWindow = time.Minute
+
+// samplingRule limits the number of alerts per agent in every minute. The alerts above the max severity
+// are never dropped.
+type samplingRule struct {
+	agentIDs    map[string]bool
+	maxSeverity protocol.Finding_Severity
+	limit       int
+	windows     map[string]*samplingCount
+}
+
+type samplingCount struct {
+	start time.Time
+	count int
+}
+
+func (rule *samplingRule) matches(agentID string, severity protocol.Finding_Severity) bool {
+	if rule.agentIDs != nil && !rule.agentIDs[agentID] {
+		return false
+	}
+	return severity <= rule.maxSeverity
+}
+
+func (rule *samplingRule) keep(agentID string, now time.Time) bool {
+	window, ok := rule.windows[agentID]
+	if !ok || now.Sub(window.start) >= samplingWindow {
+		window = &samplingCount{start: now}
+		rule.windows[agentID] = window
+	}
+	window.count++
+	return window.count <= rule.limit
+}
+
+// alertSampler drops the alerts of the noisy agents before they are published.
+type alertSampler struct {
+	rules   []*samplingRule
+	dropped uint64
+}
+
+func newAlertSampler(cfgs []config.AlertSamplingConfig) *alertSampler {
+	sampler := &alertSampler{}
+	for _, cfg := range cfgs {
+		rule := &samplingRule{
+			maxSeverity: protocol.Finding_Severity(protocol.Finding_Severity_value[cfg.MaxSeverity]),
+			limit:       cfg.MaxAlertsPerMinute,
+			windows:     make(map[string]*samplingCount),
+		}
+		if len(cfg.AgentIDs) > 0 {
+			rule.agentIDs = make(map[string]bool)
+			for _, agentID := range cfg.AgentIDs {
+				rule.agentIDs[agentID] = true
+			}
+		}
+		sampler.rules = append(sampler.rules, rule)
+	}
+	return sampler
+}
+
+// Keep tells if the alert should be kept. The first rule which matches the agent and the severity
+// of the alert is applied.
+func (sampler *alertSampler) Keep(alert *protocol.SignedAlert, now time.Time) bool {
+	if alert.Alert.Agent == nil || alert.Alert.Finding == nil {
+		return true
+	}
+	agentID := alert.Alert.Agent.Id
+	for _, rule := range sampler.rules {
+		if !rule.matches(agentID, alert.Alert.Finding.Severity) {
+			continue
+		}
+		if rule.keep(agentID, now) {
+			return true
+		}
+		atomic.AddUint64(&sampler.dropped, 1)
+		return false
+	}
+	return true
+}
+
+// Health implements the health.Reporter interface.
+func (sampler *alertSampler) Health() health.Reports {
+	return health.Reports{
+		&health.Report{
+			Name:    "alerts.sampled-out.count",
+			Status:  health.StatusInfo,
+			Details: strconv.FormatUint(atomic.LoadUint64(&sampler.dropped), 10),
+		},
+	}
+}
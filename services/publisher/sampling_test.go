@@ -0,0 +1,35 @@
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertSampler(t *testing.T) {
+	r := require.New(t)
+
+	sampler := newAlertSampler([]config.AlertSamplingConfig{
+		{AgentIDs: []string{"noisy"}, MaxSeverity: "MEDIUM", MaxAlertsPerMinute: 2},
+		{MaxSeverity: "INFO", MaxAlertsPerMinute: 1},
+	})
+	now := time.Now()
+
+	noisyInfo := testWebhookAlert("alert", "noisy", protocol.Finding_INFO)
+	r.True(sampler.Keep(noisyInfo, now))
+	r.True(sampler.Keep(noisyInfo, now))
+	r.False(sampler.Keep(noisyInfo, now))
+	r.True(sampler.Keep(testWebhookAlert("alert", "noisy", protocol.Finding_HIGH), now), "high alerts are always kept")
+	r.True(sampler.Keep(noisyInfo, now.Add(time.Minute)), "the next window should allow more")
+
+	// the second rule applies to the other agents
+	otherInfo := testWebhookAlert("alert", "other", protocol.Finding_INFO)
+	r.True(sampler.Keep(otherInfo, now))
+	r.False(sampler.Keep(otherInfo, now))
+	r.True(sampler.Keep(testWebhookAlert("alert", "other", protocol.Finding_LOW), now))
+
+	r.Equal("2", sampler.Health()[0].Details)
+}
@@ -0,0 +1,54 @@
+package publisher
+
+import (
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+)
+
+// AlertSink delivers the alerts to an external destination.
+type AlertSink interface {
+	// Send queues the alert without blocking.
+	Send(alert *protocol.SignedAlert)
+	Start()
+	Stop() error
+	Name() string
+	Health() health.Reports
+}
+
+// alertFilter matches the alerts by severity, agent and metadata.
+type alertFilter struct {
+	minSeverity protocol.Finding_Severity
+	agentIDs    map[string]bool
+	metadata    map[string]string
+}
+
+func newAlertFilter(cfg config.AlertFilterConfig) *alertFilter {
+	filter := &alertFilter{
+		minSeverity: protocol.Finding_Severity(protocol.Finding_Severity_value[cfg.MinSeverity]),
+		metadata:    cfg.Metadata,
+	}
+	if len(cfg.AgentIDs) > 0 {
+		filter.agentIDs = make(map[string]bool)
+		for _, agentID := range cfg.AgentIDs {
+			filter.agentIDs[agentID] = true
+		}
+	}
+	return filter
+}
+
+// Matches tells if the alert should be sent.
+func (f *alertFilter) Matches(alert *protocol.SignedAlert) bool {
+	if alert.Alert.Finding == nil || alert.Alert.Finding.Severity < f.minSeverity {
+		return false
+	}
+	if f.agentIDs != nil && (alert.Alert.Agent == nil || !f.agentIDs[alert.Alert.Agent.Id]) {
+		return false
+	}
+	for k, v := range f.metadata {
+		if alert.Alert.Finding.Metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
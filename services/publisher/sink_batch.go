@@ -0,0 +1,143 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	batchBufferSize     = 1000
+	batchFlushTimeout   = time.Second * 15
+	batchRequestTimeout = time.Second * 10
+)
+
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+// alertBatcher collects the alerts of a sink and delivers them together when the batch is full or when
+// the interval passes. The last batch is flushed before stopping.
+type alertBatcher struct {
+	cfg      config.SinkBatchConfig
+	deliver  func(ctx context.Context, batch []*protocol.SignedAlert)
+	alertCh  chan *protocol.SignedAlert
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	doneCh   chan struct{}
+}
+
+func newAlertBatcher(cfg config.SinkBatchConfig, deliver func(ctx context.Context, batch []*protocol.SignedAlert)) *alertBatcher {
+	return &alertBatcher{
+		cfg:     cfg,
+		deliver: deliver,
+		alertCh: make(chan *protocol.SignedAlert, batchBufferSize),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+}
+
+// Add queues the alert without blocking. It returns false if the queue is full.
+func (b *alertBatcher) Add(alert *protocol.SignedAlert) bool {
+	select {
+	case b.alertCh <- alert:
+		return true
+	default:
+		return false
+	}
+}
+
+// run collects the alerts until the context is done or the batcher is stopped.
+func (b *alertBatcher) run(ctx context.Context) {
+	defer close(b.doneCh)
+
+	ticker := time.NewTicker(time.Duration(b.cfg.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	var batch []*protocol.SignedAlert
+	for {
+		select {
+		case <-ctx.Done():
+			b.flush(batch)
+			return
+		case <-b.stopCh:
+			b.flush(batch)
+			return
+		case alert := <-b.alertCh:
+			batch = append(batch, alert)
+			if len(batch) >= b.cfg.MaxAlerts {
+				b.deliver(ctx, batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				b.deliver(ctx, batch)
+				batch = nil
+			}
+		}
+	}
+}
+
+// flush delivers the batch and the queued alerts with a deadline since the node is stopping.
+func (b *alertBatcher) flush(batch []*protocol.SignedAlert) {
+	for len(b.alertCh) > 0 {
+		batch = append(batch, <-b.alertCh)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), batchRequestTimeout)
+	defer cancel()
+	for len(batch) > 0 {
+		size := b.cfg.MaxAlerts
+		if size > len(batch) {
+			size = len(batch)
+		}
+		b.deliver(ctx, batch[:size])
+		batch = batch[size:]
+	}
+}
+
+// Stop waits until the last batch is flushed. It can be called more than once.
+func (b *alertBatcher) Stop() error {
+	b.stopOnce.Do(func() {
+		close(b.stopCh)
+	})
+	select {
+	case <-b.doneCh:
+	case <-time.After(batchFlushTimeout):
+		return fmt.Errorf("timed out while flushing the alerts")
+	}
+	return nil
+}
+
+// encodeAlertBatch encodes the batch as a list of alerts in the sink encoding and compresses it if enabled.
+// It returns the content encoding of the compressed batches.
+func encodeAlertBatch(batch []*protocol.SignedAlert, encoding string, compress bool) ([]byte, string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	msg := &protocol.AlertResponse{Alerts: batch}
+	switch encoding {
+	case "protobuf":
+		data, err = proto.Marshal(msg)
+	default:
+		var s string
+		s, err = (&jsonpb.Marshaler{}).MarshalToString(msg)
+		data = []byte(s)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return compressBatch(data, compress)
+}
+
+func compressBatch(data []byte, compress bool) ([]byte, string, error) {
+	if !compress {
+		return data, "", nil
+	}
+	return zstdEncoder.EncodeAll(data, nil), "zstd", nil
+}
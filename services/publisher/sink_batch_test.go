@@ -0,0 +1,103 @@
+package publisher
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/protobuf/proto"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+type testBatchServer struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (s *testBatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b, _ := ioutil.ReadAll(req.Body)
+	if req.Header.Get("Content-Encoding") == "zstd" {
+		dec, _ := zstd.NewReader(nil)
+		b, _ = dec.DecodeAll(b, nil)
+	}
+	var body struct {
+		Alerts []struct {
+			Alert struct {
+				ID string `json:"id"`
+			} `json:"alert"`
+		} `json:"alerts"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var ids []string
+	for _, alert := range body.Alerts {
+		ids = append(ids, alert.Alert.ID)
+	}
+	s.mu.Lock()
+	s.batches = append(s.batches, ids)
+	s.mu.Unlock()
+}
+
+func (s *testBatchServer) getBatches() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches
+}
+
+func TestWebhookSink_Batch(t *testing.T) {
+	r := require.New(t)
+
+	batchServer := &testBatchServer{}
+	server := httptest.NewServer(batchServer)
+	defer server.Close()
+
+	sink := testWebhookSink(t, server.URL, config.WebhookSinkConfig{
+		Batch: config.SinkBatchConfig{MaxAlerts: 2, IntervalSeconds: 60, Compress: true},
+	})
+	sink.Start()
+
+	sink.Send(testWebhookAlert("alert1", "agent1", protocol.Finding_HIGH))
+	sink.Send(testWebhookAlert("alert2", "agent1", protocol.Finding_HIGH))
+	sink.Send(testWebhookAlert("alert3", "agent1", protocol.Finding_HIGH))
+
+	// the last alert is flushed while stopping
+	r.NoError(sink.Stop())
+	r.Equal([][]string{{"alert1", "alert2"}, {"alert3"}}, batchServer.getBatches())
+
+	// the sink can be stopped again after reloading the sinks
+	r.NoError(sink.Stop())
+}
+
+func TestEncodeAlertBatch(t *testing.T) {
+	r := require.New(t)
+
+	batch := []*protocol.SignedAlert{
+		testWebhookAlert("alert1", "agent1", protocol.Finding_HIGH),
+		testWebhookAlert("alert2", "agent2", protocol.Finding_LOW),
+	}
+	data, contentEncoding, err := encodeAlertBatch(batch, "protobuf", true)
+	r.NoError(err)
+	r.Equal("zstd", contentEncoding)
+
+	dec, err := zstd.NewReader(nil)
+	r.NoError(err)
+	data, err = dec.DecodeAll(data, nil)
+	r.NoError(err)
+	var decoded protocol.AlertResponse
+	r.NoError(proto.Unmarshal(data, &decoded))
+	r.Len(decoded.Alerts, 2)
+	r.Equal("alert2", decoded.Alerts[1].Alert.Id)
+
+	data, contentEncoding, err = encodeAlertBatch(batch, "json", false)
+	r.NoError(err)
+	r.Empty(contentEncoding)
+	r.Contains(string(data), `"alerts":[`)
+}
@@ -0,0 +1,280 @@
+package publisher
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	syslogBufferSize    = 1000
+	syslogDialTimeout   = time.Second * 10
+	syslogWriteTimeout  = time.Second * 10
+	syslogFacilityLocal = 16 // local0
+	syslogAppName       = "forta-node"
+)
+
+// SyslogSink writes the alerts to a syslog server in CEF or LEEF format so that they can be collected
+// by the SIEM tools.
+type SyslogSink struct {
+	ctx         context.Context
+	name        string
+	cfg         config.SyslogSinkConfig
+	version     string
+	hostname    string
+	filter      *alertFilter
+	deadLetters *deadLetterQueue
+	alertCh     chan *protocol.SignedAlert
+	conn        net.Conn
+
+	lastSend       health.TimeTracker
+	lastErr        health.ErrorTracker
+	lastDeadLetter health.TimeTracker
+}
+
+// NewSyslogSink creates a new syslog sink.
+func NewSyslogSink(ctx context.Context, name string, cfg config.SyslogSinkConfig, version string, deadLetters *deadLetterQueue) *SyslogSink {
+	hostname, _ := os.Hostname()
+	if len(hostname) == 0 {
+		hostname = "-"
+	}
+	return &SyslogSink{
+		ctx:         ctx,
+		name:        name,
+		cfg:         cfg,
+		version:     version,
+		hostname:    hostname,
+		filter:      newAlertFilter(cfg.Filter),
+		deadLetters: deadLetters,
+		alertCh:     make(chan *protocol.SignedAlert, syslogBufferSize),
+	}
+}
+
+// Send queues the alert if it matches the filter.
+func (ss *SyslogSink) Send(alert *protocol.SignedAlert) {
+	if !ss.filter.Matches(alert) {
+		return
+	}
+	select {
+	case ss.alertCh <- alert:
+	default:
+		ss.deadLetter(alert, fmt.Errorf("queue is full"))
+	}
+}
+
+func (ss *SyslogSink) run() {
+	for {
+		select {
+		case <-ss.ctx.Done():
+			return
+		case alert := <-ss.alertCh:
+			err := ss.write(ss.message(alert, time.Now().UTC()))
+			ss.lastErr.Set(err)
+			if err != nil {
+				ss.deadLetter(alert, err)
+				continue
+			}
+			ss.lastSend.Set()
+		}
+	}
+}
+
+// write writes the message and reconnects once if the connection was broken.
+func (ss *SyslogSink) write(msg string) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if ss.conn == nil {
+			if ss.conn, err = ss.dial(); err != nil {
+				continue
+			}
+		}
+		ss.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+		if _, err = ss.conn.Write([]byte(msg)); err == nil {
+			return nil
+		}
+		ss.conn.Close()
+		ss.conn = nil
+	}
+	return err
+}
+
+func (ss *SyslogSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+	if ss.cfg.Network == "tls" {
+		return tls.DialWithDialer(dialer, "tcp", ss.cfg.Address, &tls.Config{
+			InsecureSkipVerify: ss.cfg.InsecureSkipVerify,
+		})
+	}
+	return dialer.Dial(ss.cfg.Network, ss.cfg.Address)
+}
+
+// message makes an RFC 5424 syslog message which contains the alert as the message.
+func (ss *SyslogSink) message(alert *protocol.SignedAlert, ts time.Time) string {
+	var severity protocol.Finding_Severity
+	if alert.Alert.Finding != nil {
+		severity = alert.Alert.Finding.Severity
+	}
+	var body string
+	switch ss.cfg.Format {
+	case "leef":
+		body = formatLEEF(alert, ss.version)
+	default:
+		body = formatCEF(alert, ss.version)
+	}
+	return fmt.Sprintf(
+		"<%d>1 %s %s %s - - - %s\n",
+		syslogFacilityLocal*8+syslogSeverity(severity), ts.Format(time.RFC3339), ss.hostname, syslogAppName, body,
+	)
+}
+
+func syslogSeverity(severity protocol.Finding_Severity) int {
+	switch severity {
+	case protocol.Finding_CRITICAL:
+		return 2
+	case protocol.Finding_HIGH:
+		return 3
+	case protocol.Finding_MEDIUM:
+		return 4
+	case protocol.Finding_LOW:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// siemSeverity maps the finding severities to the 0-10 scale of CEF and LEEF.
+func siemSeverity(severity protocol.Finding_Severity) int {
+	switch severity {
+	case protocol.Finding_CRITICAL:
+		return 10
+	case protocol.Finding_HIGH:
+		return 8
+	case protocol.Finding_MEDIUM:
+		return 5
+	case protocol.Finding_LOW:
+		return 3
+	case protocol.Finding_INFO:
+		return 1
+	default:
+		return 0
+	}
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+	leefEscaper         = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ", "|", " ")
+)
+
+type siemField struct {
+	key   string
+	value string
+}
+
+func siemFields(alert *protocol.SignedAlert) (finding *protocol.Finding, fields []siemField) {
+	finding = alert.Alert.Finding
+	if finding == nil {
+		finding = &protocol.Finding{}
+	}
+	var agentID string
+	if alert.Alert.Agent != nil {
+		agentID = alert.Alert.Agent.Id
+	}
+	fields = []siemField{
+		{"agentId", agentID},
+		{"alertHash", alert.Alert.Id},
+		{"chainId", alert.Alert.Tags["chainId"]},
+		{"txHash", alert.Alert.Tags["txHash"]},
+		{"blockNumber", alert.Alert.Tags["blockNumber"]},
+	}
+	return
+}
+
+// formatCEF formats the alert as a Common Event Format message.
+func formatCEF(alert *protocol.SignedAlert, version string) string {
+	finding, fields := siemFields(alert)
+	extensions := []string{"msg=" + cefExtensionEscaper.Replace(finding.Description)}
+	for i, field := range fields {
+		if len(field.value) == 0 {
+			continue
+		}
+		extensions = append(extensions,
+			fmt.Sprintf("cs%dLabel=%s", i+1, field.key),
+			fmt.Sprintf("cs%d=%s", i+1, cefExtensionEscaper.Replace(field.value)),
+		)
+	}
+	return fmt.Sprintf(
+		"CEF:0|Forta|Forta Node|%s|%s|%s|%d|%s",
+		cefHeaderEscaper.Replace(version), cefHeaderEscaper.Replace(finding.AlertId), cefHeaderEscaper.Replace(finding.Name),
+		siemSeverity(finding.Severity), strings.Join(extensions, " "),
+	)
+}
+
+// formatLEEF formats the alert as a Log Event Extended Format message.
+func formatLEEF(alert *protocol.SignedAlert, version string) string {
+	finding, fields := siemFields(alert)
+	attributes := []string{
+		fmt.Sprintf("sev=%d", siemSeverity(finding.Severity)),
+		"name=" + leefEscaper.Replace(finding.Name),
+		"msg=" + leefEscaper.Replace(finding.Description),
+	}
+	for _, field := range fields {
+		if len(field.value) == 0 {
+			continue
+		}
+		attributes = append(attributes, fmt.Sprintf("%s=%s", field.key, leefEscaper.Replace(field.value)))
+	}
+	return fmt.Sprintf(
+		"LEEF:1.0|Forta|Forta Node|%s|%s|%s",
+		leefEscaper.Replace(version), leefEscaper.Replace(finding.AlertId), strings.Join(attributes, "\t"),
+	)
+}
+
+func (ss *SyslogSink) deadLetter(alert *protocol.SignedAlert, err error) {
+	ss.lastDeadLetter.Set()
+	logger := log.WithError(err).WithFields(log.Fields{
+		"sink":  ss.name,
+		"alert": alert.Alert.Id,
+	})
+	logger.Warn("failed to deliver alert - moving to the dead-letter queue")
+	if err := ss.deadLetters.Append(ss.name, alert, err); err != nil {
+		logger.WithError(err).Error("failed to write to the dead-letter queue")
+	}
+}
+
+// Start starts writing the queued alerts.
+func (ss *SyslogSink) Start() {
+	go ss.run()
+}
+
+// Stop implements the AlertSink interface.
+func (ss *SyslogSink) Stop() error {
+	return nil
+}
+
+// Name returns the name of the sink.
+func (ss *SyslogSink) Name() string {
+	return ss.name
+}
+
+// Health implements the health.Reporter interface.
+func (ss *SyslogSink) Health() health.Reports {
+	return health.Reports{
+		ss.lastSend.GetReport(fmt.Sprintf("event.%s-send.time", ss.name)),
+		ss.lastErr.GetReport(fmt.Sprintf("event.%s-send.error", ss.name)),
+		&health.Report{
+			Name:    fmt.Sprintf("event.%s-dead-letter.time", ss.name),
+			Status:  health.StatusInfo,
+			Details: ss.lastDeadLetter.String(),
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testSyslogAlert() *protocol.SignedAlert {
+	return &protocol.SignedAlert{
+		Alert: &protocol.Alert{
+			Id:    "0xalert",
+			Agent: &protocol.AgentInfo{Id: "0xagent"},
+			Tags:  map[string]string{"chainId": "1", "txHash": "0xtx"},
+			Finding: &protocol.Finding{
+				AlertId:     "EXPLOIT-1",
+				Name:        "Exploit | drain",
+				Description: "a=b\nc",
+				Severity:    protocol.Finding_HIGH,
+			},
+		},
+	}
+}
+
+func TestFormatCEF(t *testing.T) {
+	require.Equal(t,
+		`CEF:0|Forta|Forta Node|v0.1.0|EXPLOIT-1|Exploit \| drain|8|msg=a\=b\nc `+
+			`cs1Label=agentId cs1=0xagent cs2Label=alertHash cs2=0xalert cs3Label=chainId cs3=1 cs4Label=txHash cs4=0xtx`,
+		formatCEF(testSyslogAlert(), "v0.1.0"),
+	)
+}
+
+func TestFormatLEEF(t *testing.T) {
+	require.Equal(t,
+		"LEEF:1.0|Forta|Forta Node|v0.1.0|EXPLOIT-1|sev=8\tname=Exploit   drain\tmsg=a=b c\t"+
+			"agentId=0xagent\talertHash=0xalert\tchainId=1\ttxHash=0xtx",
+		formatLEEF(testSyslogAlert(), "v0.1.0"),
+	)
+}
+
+func TestSyslogSink_Message(t *testing.T) {
+	sink := NewSyslogSink(context.Background(), "syslog-0", config.SyslogSinkConfig{Format: "leef"}, "v0.1.0", newDeadLetterQueue(""))
+	sink.hostname = "node"
+	ts := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	msg := sink.message(testSyslogAlert(), ts)
+	require.Equal(t, "<131>1 2022-05-01T10:00:00Z node forta-node - - - "+formatLEEF(testSyslogAlert(), "v0.1.0")+"\n", msg)
+}
+
+func TestSyslogSink_TCP(t *testing.T) {
+	r := require.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	defer listener.Close()
+
+	lineCh := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lineCh <- line
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink := NewSyslogSink(ctx, "syslog-0", config.SyslogSinkConfig{
+		Network: "tcp",
+		Address: listener.Addr().String(),
+		Format:  "cef",
+	}, "v0.1.0", newDeadLetterQueue(path.Join(t.TempDir(), "dead-letters.log")))
+	sink.Start()
+	sink.Send(testSyslogAlert())
+
+	select {
+	case line := <-lineCh:
+		r.Contains(line, formatCEF(testSyslogAlert(), "v0.1.0"))
+	case <-time.After(time.Second * 5):
+		r.FailNow("timed out")
+	}
+}
@@ -0,0 +1,137 @@
+package publisher
+
+import (
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/tracing"
+)
+
+const maxTracedBlocks = 1000
+
+// notificationTimes are the times when the publisher handled, stored and published the alert
+// of a notification. The store and publish times are zero if there was no alert.
+type notificationTimes struct {
+	Handled   time.Time
+	Stored    time.Time
+	Published time.Time
+}
+
+// spanExporter exports the sampled spans.
+type spanExporter interface {
+	Sampled(traceID tracing.TraceID) bool
+	Export(spans ...*tracing.Span)
+	Health() health.Reports
+}
+
+// blockTracer exports the lifecycle of the notifications as the spans of the block traces: the
+// block ingestion, the agent evaluation, the result handling, the storage and the publishing.
+type blockTracer struct {
+	exporter     spanExporter
+	tracedBlocks map[tracing.SpanID]bool
+}
+
+func newBlockTracer(exporter spanExporter) *blockTracer {
+	return &blockTracer{
+		exporter:     exporter,
+		tracedBlocks: make(map[tracing.SpanID]bool),
+	}
+}
+
+func parseTrackingTime(ts string) time.Time {
+	t, _ := time.Parse(domain.TimeTrackingTimestampFormat, ts)
+	return t
+}
+
+// notificationBlock returns the chain ID, the block number and the block hash of the evaluated event.
+func notificationBlock(notif *protocol.NotifyRequest) (chainID, blockNumber, blockHash string) {
+	if notif.EvalBlockRequest != nil {
+		event := notif.EvalBlockRequest.GetEvent()
+		return event.GetNetwork().GetChainId(), event.GetBlockNumber(), event.GetBlockHash()
+	}
+	event := notif.EvalTxRequest.GetEvent()
+	return event.GetNetwork().GetChainId(), event.GetBlock().GetBlockNumber(), event.GetBlock().GetBlockHash()
+}
+
+// Trace exports the spans of the notification.
+func (bt *blockTracer) Trace(notif *protocol.NotifyRequest, times notificationTimes) {
+	chainID, blockNumber, blockHash := notificationBlock(notif)
+	if len(blockHash) == 0 || notif.Timestamps == nil {
+		return
+	}
+	traceID, blockSpanID := tracing.BlockIDs(chainID, blockHash)
+	if !bt.exporter.Sampled(traceID) {
+		return
+	}
+
+	var (
+		block       = parseTrackingTime(notif.Timestamps.Block)
+		feed        = parseTrackingTime(notif.Timestamps.Feed)
+		botRequest  = parseTrackingTime(notif.Timestamps.BotRequest)
+		botResponse = parseTrackingTime(notif.Timestamps.BotResponse)
+	)
+	if feed.IsZero() || botRequest.IsZero() || botResponse.IsZero() {
+		return
+	}
+	if block.IsZero() {
+		block = feed
+	}
+
+	// the block span is the same for all agents so it is exported only once
+	blockSpan := &tracing.Span{
+		TraceID: traceID,
+		SpanID:  blockSpanID,
+		Name:    "block.ingest",
+		Start:   block,
+		End:     feed,
+		Attributes: map[string]string{
+			"chain.id":     chainID,
+			"block.number": blockNumber,
+			"block.hash":   blockHash,
+		},
+	}
+	var spans []*tracing.Span
+	if !bt.tracedBlocks[blockSpanID] {
+		if len(bt.tracedBlocks) >= maxTracedBlocks {
+			bt.tracedBlocks = make(map[tracing.SpanID]bool)
+		}
+		bt.tracedBlocks[blockSpanID] = true
+		spans = append(spans, blockSpan)
+	}
+
+	end := times.Handled
+	if !times.Published.IsZero() {
+		end = times.Published
+	} else if !times.Stored.IsZero() {
+		end = times.Stored
+	}
+	agentSpan := blockSpan.Child("agent.result", feed, end)
+	agentSpan.Attributes["agent.id"] = notif.GetAgentInfo().GetId()
+	agentSpan.Attributes["chain.id"] = chainID
+	agentSpan.Attributes["block.number"] = blockNumber
+	if txHash := notif.EvalTxRequest.GetEvent().GetTransaction().GetHash(); len(txHash) > 0 {
+		agentSpan.Attributes["tx.hash"] = txHash
+	}
+	if alert := notif.GetSignedAlert().GetAlert(); alert != nil {
+		agentSpan.Attributes["alert.id"] = alert.Id
+	}
+	spans = append(
+		spans,
+		agentSpan,
+		agentSpan.Child("agent.queue", feed, botRequest),
+		agentSpan.Child("agent.evaluate", botRequest, botResponse),
+		agentSpan.Child("result.handle", botResponse, times.Handled),
+	)
+
+	lastStep := times.Handled
+	if !times.Stored.IsZero() {
+		spans = append(spans, agentSpan.Child("alert.store", lastStep, times.Stored))
+		lastStep = times.Stored
+	}
+	if !times.Published.IsZero() {
+		spans = append(spans, agentSpan.Child("alert.publish", lastStep, times.Published))
+	}
+	bt.exporter.Export(spans...)
+}
@@ -0,0 +1,94 @@
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/tracing"
+	"github.com/stretchr/testify/require"
+)
+
+type testSpanExporter struct {
+	spans []*tracing.Span
+}
+
+func (exporter *testSpanExporter) Sampled(traceID tracing.TraceID) bool {
+	return true
+}
+
+func (exporter *testSpanExporter) Export(spans ...*tracing.Span) {
+	exporter.spans = append(exporter.spans, spans...)
+}
+
+func (exporter *testSpanExporter) Health() health.Reports {
+	return nil
+}
+
+func testTracedNotification(agentID string, start time.Time, withAlert bool) *protocol.NotifyRequest {
+	ts := &domain.TrackingTimestamps{
+		Block:       start,
+		Feed:        start.Add(time.Second),
+		BotRequest:  start.Add(time.Second * 2),
+		BotResponse: start.Add(time.Second * 3),
+	}
+	notif := &protocol.NotifyRequest{
+		EvalBlockRequest: &protocol.EvaluateBlockRequest{
+			Event: &protocol.BlockEvent{
+				BlockHash:   "0xblockhash",
+				BlockNumber: "0x1",
+				Network:     &protocol.BlockEvent_Network{ChainId: "0x1"},
+			},
+		},
+		AgentInfo:  &protocol.AgentInfo{Id: agentID},
+		Timestamps: ts.ToMessage(),
+	}
+	if withAlert {
+		notif.SignedAlert = &protocol.SignedAlert{Alert: &protocol.Alert{Id: "0xalert"}}
+	}
+	return notif
+}
+
+func TestBlockTracer(t *testing.T) {
+	r := require.New(t)
+
+	exporter := &testSpanExporter{}
+	tracer := newBlockTracer(exporter)
+	start := time.Now().UTC()
+	handled := start.Add(time.Second * 4)
+
+	tracer.Trace(testTracedNotification("agent-1", start, true), notificationTimes{
+		Handled:   handled,
+		Stored:    handled.Add(time.Millisecond),
+		Published: handled.Add(time.Millisecond * 2),
+	})
+	names := make([]string, 0, len(exporter.spans))
+	for _, span := range exporter.spans {
+		names = append(names, span.Name)
+	}
+	r.Equal([]string{
+		"block.ingest", "agent.result", "agent.queue", "agent.evaluate", "result.handle", "alert.store", "alert.publish",
+	}, names)
+
+	blockSpan, agentSpan := exporter.spans[0], exporter.spans[1]
+	r.Equal(blockSpan.SpanID, agentSpan.ParentSpanID)
+	r.Equal("agent-1", agentSpan.Attributes["agent.id"])
+	r.Equal("0xalert", agentSpan.Attributes["alert.id"])
+	r.True(agentSpan.End.Equal(handled.Add(time.Millisecond * 2)))
+	for _, span := range exporter.spans[2:] {
+		r.Equal(blockSpan.TraceID, span.TraceID)
+		r.Equal(agentSpan.SpanID, span.ParentSpanID)
+	}
+	r.True(exporter.spans[3].Start.Equal(start.Add(time.Second * 2)))
+	r.True(exporter.spans[3].End.Equal(start.Add(time.Second * 3)))
+
+	// the block span is exported once and the notification without alert has no store and publish spans
+	exporter.spans = nil
+	tracer.Trace(testTracedNotification("agent-2", start, false), notificationTimes{Handled: handled})
+	r.Len(exporter.spans, 4)
+	r.Equal("agent.result", exporter.spans[0].Name)
+	r.Equal(blockSpan.TraceID, exporter.spans[0].TraceID)
+	r.Equal(blockSpan.SpanID, exporter.spans[0].ParentSpanID)
+}
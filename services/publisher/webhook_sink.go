@@ -0,0 +1,267 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/protobuf/jsonpb"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	webhookBufferSize     = 1000
+	webhookRequestTimeout = time.Second * 10
+	webhookInitialBackoff = time.Second
+	webhookMaxBackoff     = time.Minute
+)
+
+// WebhookSink posts the matching alerts to a webhook. The alerts which could not be delivered
+// after the retries are written to the dead-letter queue.
+type WebhookSink struct {
+	ctx         context.Context
+	name        string
+	cfg         config.WebhookSinkConfig
+	filter      *alertFilter
+	format      alertFormatter
+	client      *http.Client
+	deadLetters *deadLetterQueue
+	batcher     *alertBatcher
+	alertCh     chan *protocol.SignedAlert
+	stopCh      chan struct{}
+	stopOnce    sync.Once
+	doneCh      chan struct{}
+
+	initialBackoff time.Duration
+
+	lastSend       health.TimeTracker
+	lastErr        health.ErrorTracker
+	lastDeadLetter health.TimeTracker
+}
+
+// alertFormatter makes the request body for an alert.
+type alertFormatter func(alert *protocol.SignedAlert) (string, error)
+
+func formatJSON(alert *protocol.SignedAlert) (string, error) {
+	return (&jsonpb.Marshaler{}).MarshalToString(alert)
+}
+
+// NewWebhookSink creates a new webhook sink which posts the alerts as JSON.
+func NewWebhookSink(ctx context.Context, name string, cfg config.WebhookSinkConfig, deadLetters *deadLetterQueue) *WebhookSink {
+	return newWebhookSink(ctx, name, cfg, formatJSON, deadLetters)
+}
+
+func newWebhookSink(ctx context.Context, name string, cfg config.WebhookSinkConfig, format alertFormatter, deadLetters *deadLetterQueue) *WebhookSink {
+	ws := &WebhookSink{
+		ctx:            ctx,
+		name:           name,
+		cfg:            cfg,
+		filter:         newAlertFilter(cfg.Filter),
+		format:         format,
+		client:         &http.Client{Timeout: webhookRequestTimeout},
+		deadLetters:    deadLetters,
+		alertCh:        make(chan *protocol.SignedAlert, webhookBufferSize),
+		stopCh:         make(chan struct{}),
+		doneCh:         make(chan struct{}),
+		initialBackoff: webhookInitialBackoff,
+	}
+	if cfg.Batch.MaxAlerts > 0 {
+		ws.batcher = newAlertBatcher(cfg.Batch, ws.deliverBatch)
+	}
+	return ws
+}
+
+// Send queues the alert if it matches the filter.
+func (ws *WebhookSink) Send(alert *protocol.SignedAlert) {
+	if !ws.filter.Matches(alert) {
+		return
+	}
+	if ws.batcher != nil {
+		if !ws.batcher.Add(alert) {
+			ws.deadLetter(alert, fmt.Errorf("queue is full"))
+		}
+		return
+	}
+	select {
+	case ws.alertCh <- alert:
+	default:
+		ws.deadLetter(alert, fmt.Errorf("queue is full"))
+	}
+}
+
+func (ws *WebhookSink) run() {
+	defer close(ws.doneCh)
+	for {
+		select {
+		case <-ws.ctx.Done():
+			return
+		case <-ws.stopCh:
+			return
+		case alert := <-ws.alertCh:
+			if err := ws.deliverAlert(ws.ctx, alert); err != nil {
+				ws.deadLetter(alert, err)
+			}
+		}
+	}
+}
+
+func (ws *WebhookSink) deliverBatch(ctx context.Context, batch []*protocol.SignedAlert) {
+	body, contentEncoding, err := ws.encodeBatch(batch)
+	if err == nil {
+		err = ws.deliver(ctx, body, contentEncoding)
+	}
+	if err == nil {
+		return
+	}
+	log.WithError(err).WithField("sink", ws.name).Warnf("failed to deliver a batch of %d alerts", len(batch))
+	for _, alert := range batch {
+		ws.deadLetter(alert, err)
+	}
+}
+
+// encodeBatch makes a JSON object with the list of alerts in the webhook format and compresses it if enabled.
+func (ws *WebhookSink) encodeBatch(batch []*protocol.SignedAlert) ([]byte, string, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"alerts":[`)
+	for i, alert := range batch {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		s, err := ws.format(alert)
+		if err != nil {
+			return nil, "", err
+		}
+		buf.WriteString(s)
+	}
+	buf.WriteString(`]}`)
+	return compressBatch(buf.Bytes(), ws.cfg.Batch.Compress)
+}
+
+func (ws *WebhookSink) deliverAlert(ctx context.Context, alert *protocol.SignedAlert) error {
+	body, err := ws.format(alert)
+	if err != nil {
+		return err
+	}
+	return ws.deliver(ctx, []byte(body), "")
+}
+
+// deliver posts the body and retries with exponential backoff until the max retries are reached.
+func (ws *WebhookSink) deliver(ctx context.Context, body []byte, contentEncoding string) error {
+	backoff := ws.initialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := ws.post(ctx, body, contentEncoding)
+		ws.lastErr.Set(err)
+		if err == nil {
+			ws.lastSend.Set()
+			return nil
+		}
+		if !retry || attempt >= ws.cfg.MaxRetries {
+			return err
+		}
+		log.WithError(err).WithField("sink", ws.name).Warnf("failed to send alert - retrying in %s", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > webhookMaxBackoff {
+			backoff = webhookMaxBackoff
+		}
+	}
+}
+
+// post sends the body and tells if the failed request should be retried.
+func (ws *WebhookSink) post(ctx context.Context, body []byte, contentEncoding string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ws.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(contentEncoding) > 0 {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	for k, v := range ws.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		// drop the url from the error since it can contain a token
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+func (ws *WebhookSink) deadLetter(alert *protocol.SignedAlert, err error) {
+	ws.lastDeadLetter.Set()
+	logger := log.WithError(err).WithFields(log.Fields{
+		"sink":  ws.name,
+		"alert": alert.Alert.Id,
+	})
+	logger.Warn("failed to deliver alert - moving to the dead-letter queue")
+	if err := ws.deadLetters.Append(ws.name, alert, err); err != nil {
+		logger.WithError(err).Error("failed to write to the dead-letter queue")
+	}
+}
+
+// Start starts delivering the queued alerts.
+func (ws *WebhookSink) Start() {
+	if ws.batcher != nil {
+		go ws.batcher.run(ws.ctx)
+		return
+	}
+	go ws.run()
+}
+
+// Stop waits until the last batch is flushed. It can be called more than once.
+func (ws *WebhookSink) Stop() error {
+	if ws.batcher != nil {
+		return ws.batcher.Stop()
+	}
+	ws.stopOnce.Do(func() {
+		close(ws.stopCh)
+	})
+	select {
+	case <-ws.doneCh:
+	case <-time.After(batchFlushTimeout):
+		return fmt.Errorf("timed out while flushing the alerts")
+	}
+	return nil
+}
+
+// Name returns the name of the sink.
+func (ws *WebhookSink) Name() string {
+	return ws.name
+}
+
+// Health implements the health.Reporter interface.
+func (ws *WebhookSink) Health() health.Reports {
+	return health.Reports{
+		ws.lastSend.GetReport(fmt.Sprintf("event.%s-send.time", ws.name)),
+		ws.lastErr.GetReport(fmt.Sprintf("event.%s-send.error", ws.name)),
+		&health.Report{
+			Name:    fmt.Sprintf("event.%s-dead-letter.time", ws.name),
+			Status:  health.StatusInfo,
+			Details: ws.lastDeadLetter.String(),
+		},
+	}
+}
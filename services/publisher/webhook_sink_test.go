@@ -0,0 +1,113 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testWebhookAlert(id, agentID string, severity protocol.Finding_Severity) *protocol.SignedAlert {
+	return &protocol.SignedAlert{
+		Alert: &protocol.Alert{
+			Id:      id,
+			Agent:   &protocol.AgentInfo{Id: agentID},
+			Finding: &protocol.Finding{Severity: severity},
+		},
+	}
+}
+
+func testWebhookSink(t *testing.T, url string, cfg config.WebhookSinkConfig) *WebhookSink {
+	cfg.URL = url
+	deadLetters := newDeadLetterQueue(path.Join(t.TempDir(), "dead-letters.log"))
+	sink := NewWebhookSink(context.Background(), "webhook-0", cfg, deadLetters)
+	sink.initialBackoff = time.Millisecond
+	return sink
+}
+
+func TestAlertFilter(t *testing.T) {
+	r := require.New(t)
+
+	filter := newAlertFilter(config.AlertFilterConfig{MinSeverity: "HIGH", AgentIDs: []string{"agent1"}})
+	r.True(filter.Matches(testWebhookAlert("alert", "agent1", protocol.Finding_CRITICAL)))
+	r.False(filter.Matches(testWebhookAlert("alert", "agent1", protocol.Finding_MEDIUM)))
+	r.False(filter.Matches(testWebhookAlert("alert", "agent2", protocol.Finding_HIGH)))
+
+	r.True(newAlertFilter(config.AlertFilterConfig{}).Matches(testWebhookAlert("alert", "agent2", protocol.Finding_INFO)))
+}
+
+func TestWebhookSink_Retry(t *testing.T) {
+	r := require.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Equal("secret", req.Header.Get("X-Api-Key"))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := ioutil.ReadAll(req.Body)
+		r.Contains(string(b), `"id":"alert1"`)
+	}))
+	defer server.Close()
+
+	sink := testWebhookSink(t, server.URL, config.WebhookSinkConfig{
+		Headers:    map[string]string{"X-Api-Key": "secret"},
+		MaxRetries: 5,
+	})
+	r.NoError(sink.deliverAlert(context.Background(), testWebhookAlert("alert1", "agent1", protocol.Finding_HIGH)))
+	r.Equal(int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestWebhookSink_DeadLetter(t *testing.T) {
+	r := require.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := testWebhookSink(t, server.URL, config.WebhookSinkConfig{MaxRetries: 5})
+	alert := testWebhookAlert("alert1", "agent1", protocol.Finding_HIGH)
+	err := sink.deliverAlert(context.Background(), alert)
+	r.Error(err)
+	r.Equal(int32(1), atomic.LoadInt32(&calls), "client errors should not be retried")
+
+	sink.deadLetter(alert, err)
+	var b []byte
+	r.Eventually(func() bool {
+		b, err = ioutil.ReadFile(sink.deadLetters.path)
+		return err == nil && len(b) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	var deadLetter DeadLetter
+	r.NoError(json.Unmarshal([]byte(strings.TrimSpace(string(b))), &deadLetter))
+	r.Equal("webhook-0", deadLetter.Sink)
+	r.Equal("alert1", deadLetter.Alert.Alert.Id)
+}
+
+func TestWebhookSink_MaxRetries(t *testing.T) {
+	r := require.New(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sink := testWebhookSink(t, server.URL, config.WebhookSinkConfig{MaxRetries: 2})
+	r.Error(sink.deliverAlert(context.Background(), testWebhookAlert("alert1", "agent1", protocol.Finding_HIGH)))
+	r.Equal(int32(3), atomic.LoadInt32(&calls))
+}
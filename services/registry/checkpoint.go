@@ -0,0 +1,254 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	regmsg "github.com/forta-network/forta-core-go/domain/registry"
+	coreregistry "github.com/forta-network/forta-core-go/registry"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// CheckpointFileName is the file in the Forta dir which keeps the last published agents.
+const CheckpointFileName = "registry-checkpoint.json"
+
+// maxReplayBlocks is the longest block range of which the registry events are replayed. The agents
+// are resynced without the replay if the checkpoint is older.
+const maxReplayBlocks = 100000
+
+// checkpoint is the registry state which the node has last published. The block is the latest block
+// before the last registry check, so that the events from the next block are the ones which the node
+// has not seen.
+type checkpoint struct {
+	Agents    []*config.AgentConfig `json:"agents"`
+	Block     uint64                `json:"block,omitempty"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+// EventReplayer replays the registry events of a block range.
+type EventReplayer interface {
+	ProcessBlockRange(startBlock *big.Int, endBlock *big.Int) error
+}
+
+// newRegistryListener creates a registry listener which calls the handlers for the agent registry and
+// the dispatch events.
+func (rs *RegistryService) newRegistryListener(handlers coreregistry.Handlers) (EventReplayer, error) {
+	return coreregistry.NewListener(context.Background(), coreregistry.ListenerConfig{
+		Name:       "registry-replay",
+		JsonRpcURL: rs.cfg.Registry.JsonRpc.Url,
+		ENSAddress: rs.cfg.ENSConfig.ContractAddress,
+		Handlers:   handlers,
+		ContractFilter: &coreregistry.ContractFilter{
+			AgentRegistry:    true,
+			DispatchRegistry: true,
+		},
+	})
+}
+
+func (rs *RegistryService) checkpointPath() string {
+	return path.Join(rs.cfg.FortaDir, CheckpointFileName)
+}
+
+// publishAgents publishes the agents and saves them as the checkpoint.
+func (rs *RegistryService) publishAgents(agts []*config.AgentConfig) {
+	rs.published = true
+	atomic.StoreInt32(&rs.stale, 0)
+	rs.counters.countChanges(CompareAgents(rs.agentsConfigs, agts))
+	rs.agentsConfigs = agts
+	rs.publishLatestVersions(agts)
+	rs.saveCheckpoint(agts)
+}
+
+// publishLatestVersions publishes the agents. The publish waits until the agent pool has handled the
+// agents if the acks are enabled.
+func (rs *RegistryService) publishLatestVersions(agts []*config.AgentConfig) {
+	err := messaging.PublishCritical(rs.msgClient, rs.cfg.Messaging.Ack, messaging.SubjectAgentsVersionsLatest, agts)
+	rs.lastPublishAckErr.Set(err)
+	if err != nil {
+		log.WithError(err).Warn("latest agent versions were not acked")
+	}
+}
+
+// checkBlock keeps the latest block before the registry is checked.
+func (rs *RegistryService) checkBlock() {
+	if rs.ethClient == nil || rs.cfg.PrivateModeConfig.Enable {
+		return
+	}
+	blockNum, err := rs.ethClient.BlockNumber(context.Background())
+	if err != nil {
+		log.WithError(err).Warn("failed to get the latest registry block")
+		return
+	}
+	rs.checkedBlock = blockNum.Uint64()
+}
+
+func (rs *RegistryService) saveCheckpoint(agts []*config.AgentConfig) {
+	if len(rs.cfg.FortaDir) == 0 {
+		return
+	}
+	b, err := json.Marshal(&checkpoint{Agents: agts, Block: rs.checkedBlock, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		log.WithError(err).Warn("failed to encode the registry checkpoint")
+		return
+	}
+	tmpPath := rs.checkpointPath() + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, b, 0644); err != nil {
+		log.WithError(err).Warn("failed to write the registry checkpoint")
+		return
+	}
+	if err := os.Rename(tmpPath, rs.checkpointPath()); err != nil {
+		log.WithError(err).Warn("failed to write the registry checkpoint")
+	}
+}
+
+// publishCheckpoint publishes the agents from the checkpoint so that the agents which were running
+// before a restart keep scanning while the registry or IPFS is not reachable. The agents are stale
+// until the next successful registry check, which loads all agents from the latest registry state
+// and picks up the changes which were made while the node was down.
+func (rs *RegistryService) publishCheckpoint() {
+	cp := rs.loadCheckpoint()
+	if cp == nil || len(cp.Agents) == 0 {
+		return
+	}
+	log.WithFields(log.Fields{
+		"count":     len(cp.Agents),
+		"updatedAt": cp.UpdatedAt,
+	}).Warn("publishing the stale agents from the registry checkpoint")
+	rs.published = true
+	atomic.StoreInt32(&rs.stale, 1)
+	rs.agentsConfigs = cp.Agents
+	rs.publishLatestVersions(cp.Agents)
+}
+
+func (rs *RegistryService) staleReport() *health.Report {
+	if atomic.LoadInt32(&rs.stale) == 1 {
+		return &health.Report{
+			Name:    "agents.stale",
+			Status:  health.StatusLagging,
+			Details: "running the last known agents from the checkpoint",
+		}
+	}
+	return &health.Report{Name: "agents.stale", Status: health.StatusOK}
+}
+
+func (rs *RegistryService) loadCheckpoint() *checkpoint {
+	if len(rs.cfg.FortaDir) == 0 {
+		return nil
+	}
+	b, err := ioutil.ReadFile(rs.checkpointPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		log.WithError(err).Warn("failed to read the registry checkpoint")
+		return nil
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		log.WithError(err).Warn("failed to decode the registry checkpoint - ignoring")
+		return nil
+	}
+	return &cp
+}
+
+// replayCheckpoint replays the registry events from the block after the checkpoint and requests a
+// resync if any of them changes the agents of the scanner, so that the agents which were added,
+// updated or unassigned while the node was down are picked up on the first registry check.
+func (rs *RegistryService) replayCheckpoint() {
+	cp := rs.loadCheckpoint()
+	if cp == nil || cp.Block == 0 || rs.ethClient == nil || rs.cfg.PrivateModeConfig.Enable {
+		return
+	}
+	latest, err := rs.ethClient.BlockNumber(context.Background())
+	if err != nil {
+		log.WithError(err).Warn("failed to get the latest registry block - resyncing the agents")
+		rs.requestResync()
+		return
+	}
+	if latest.Uint64() <= cp.Block {
+		return
+	}
+	logger := log.WithFields(log.Fields{
+		"from": cp.Block + 1,
+		"to":   latest.Uint64(),
+	})
+	if latest.Uint64()-cp.Block > maxReplayBlocks {
+		logger.Warn("registry checkpoint is too old to replay - resyncing the agents")
+		rs.requestResync()
+		return
+	}
+
+	newReplayer := rs.newEventReplayer
+	if newReplayer == nil {
+		newReplayer = rs.newRegistryListener
+	}
+	var changed bool
+	replayer, err := newReplayer(rs.replayHandlers(cp.Agents, &changed))
+	if err == nil {
+		err = replayer.ProcessBlockRange(new(big.Int).SetUint64(cp.Block+1), latest)
+	}
+	if err != nil {
+		logger.WithError(err).Warn("failed to replay the registry events - resyncing the agents")
+		rs.requestResync()
+		return
+	}
+	if changed {
+		logger.Info("replayed registry events change the agents - resyncing the agents")
+		rs.requestResync()
+		return
+	}
+	logger.Info("replayed registry events, no agent changes")
+}
+
+// replayHandlers set changed if an event assigns or unassigns an agent of the scanner or the pools, or
+// if it updates or disables an agent of the checkpoint.
+func (rs *RegistryService) replayHandlers(agts []*config.AgentConfig, changed *bool) coreregistry.Handlers {
+	scanners := append([]string{rs.scannerAddress.Hex()}, rs.cfg.Registry.PoolIDs...)
+	isKnownAgent := func(agentID string) bool {
+		for _, agt := range agts {
+			if strings.EqualFold(agt.ID, agentID) {
+				return true
+			}
+		}
+		return false
+	}
+	return coreregistry.Handlers{
+		DispatchHandler: func(logger *log.Entry, msg *regmsg.DispatchMessage) error {
+			for _, scanner := range scanners {
+				if strings.EqualFold(msg.ScannerID, scanner) {
+					*changed = true
+				}
+			}
+			return nil
+		},
+		SaveAgentHandler: func(logger *log.Entry, msg *regmsg.AgentSaveMessage) error {
+			if isKnownAgent(msg.AgentID) {
+				*changed = true
+			}
+			return nil
+		},
+		AgentActionHandler: func(logger *log.Entry, msg *regmsg.AgentMessage) error {
+			if isKnownAgent(msg.AgentID) {
+				*changed = true
+			}
+			return nil
+		},
+	}
+}
+
+// requestResync makes the next registry check reload all agents.
+func (rs *RegistryService) requestResync() {
+	if err := ioutil.WriteFile(rs.resyncRequestPath(), []byte{}, 0644); err != nil {
+		log.WithError(err).Warn("failed to request the registry resync")
+	}
+}
@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+)
+
+// agentCounters count the agent lifecycle changes which the registry service has published.
+type agentCounters struct {
+	added    uint64
+	updated  uint64
+	removed  uint64
+	rejected uint64
+	failed   uint64
+}
+
+func (ac *agentCounters) countChanges(changes *AgentChanges) {
+	atomic.AddUint64(&ac.added, uint64(len(changes.Added)))
+	atomic.AddUint64(&ac.updated, uint64(len(changes.Updated)))
+	atomic.AddUint64(&ac.removed, uint64(len(changes.Removed)))
+}
+
+func (ac *agentCounters) countRejected() {
+	atomic.AddUint64(&ac.rejected, 1)
+}
+
+func (ac *agentCounters) countFailed() {
+	atomic.AddUint64(&ac.failed, 1)
+}
+
+func counterReport(name string, counter *uint64) *health.Report {
+	return &health.Report{
+		Name:    name,
+		Status:  health.StatusInfo,
+		Details: strconv.FormatUint(atomic.LoadUint64(counter), 10),
+	}
+}
+
+func (ac *agentCounters) reports() health.Reports {
+	return health.Reports{
+		counterReport("agents.added.count", &ac.added),
+		counterReport("agents.updated.count", &ac.updated),
+		counterReport("agents.removed.count", &ac.removed),
+		counterReport("agents.rejected.count", &ac.rejected),
+		counterReport("agents.failed.count", &ac.failed),
+	}
+}
@@ -3,6 +3,10 @@ package registry
 import (
 	"context"
 	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"sync/atomic"
 	"time"
 
 	"github.com/forta-network/forta-node/store"
@@ -11,6 +15,7 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/ethereum"
+	coreregistry "github.com/forta-network/forta-core-go/registry"
 	"github.com/forta-network/forta-node/clients"
 	"github.com/forta-network/forta-node/clients/messaging"
 	"github.com/forta-network/forta-node/config"
@@ -19,6 +24,10 @@ import (
 	"golang.org/x/sync/semaphore"
 )
 
+// ResyncRequestFileName is the file in the Forta dir which requests the registry service to reload all agents
+// from the current registry state.
+const ResyncRequestFileName = ".registry-resync"
+
 // RegistryService listens to the agent scanner list changes so the node can stay in sync.
 type RegistryService struct {
 	cfg            config.Config
@@ -36,7 +45,19 @@ type RegistryService struct {
 
 	lastChecked        health.TimeTracker
 	lastChangeDetected health.TimeTracker
+	lastResync         health.TimeTracker
+	lastReconcile      health.TimeTracker
+	lastReconciled     time.Time
 	lastErr            health.ErrorTracker
+	lastFailedAgent    health.MessageTracker
+	lastPublishAckErr  health.ErrorTracker
+
+	published    bool
+	stale        int32  // running the agents from the checkpoint
+	checkedBlock uint64 // the latest block before the last registry check
+	counters     agentCounters
+
+	newEventReplayer func(handlers coreregistry.Handlers) (EventReplayer, error)
 }
 
 // IPFSClient interacts with an IPFS Gateway.
@@ -69,15 +90,51 @@ func (rs *RegistryService) Init() error {
 	if rs.cfg.PrivateModeConfig.Enable {
 		regStr, err = store.NewPrivateRegistryStore(context.Background(), rs.cfg)
 	} else {
-		regStr, err = store.NewRegistryStore(context.Background(), rs.cfg, rs.ethClient)
+		publicStore, storeErr := store.NewRegistryStore(context.Background(), rs.cfg, rs.ethClient)
+		if storeErr == nil {
+			publicStore.OnAgentRejected(rs.publishRejection)
+			regStr = publicStore
+		}
+		err = storeErr
 	}
 	if err != nil {
 		return err
 	}
+	if len(rs.cfg.FortaDir) > 0 {
+		if config.DevAgentsEnabled(rs.cfg) {
+			if err := rs.checkDevAgents(regStr); err != nil {
+				return err
+			}
+			regStr = store.NewDevAgentsStore(regStr, path.Join(rs.cfg.FortaDir, config.DefaultDevAgentsFileName))
+		}
+		regStr = store.NewPausedAgentsStore(regStr, path.Join(rs.cfg.FortaDir, config.DefaultPausedAgentsFile))
+	}
 	rs.registryStore = regStr
 	return nil
 }
 
+// checkDevAgents refuses the dev agents on a registered scanner, since the alerts of the dev agents would
+// be signed with the scanner key. The dev process does not publish the alerts.
+func (rs *RegistryService) checkDevAgents(regStr store.RegistryStore) error {
+	if rs.cfg.DevProcess {
+		return nil
+	}
+	scannerRegistry, ok := regStr.(interface {
+		IsRegisteredScanner(scanner string) (bool, error)
+	})
+	if !ok {
+		return nil
+	}
+	registered, err := scannerRegistry.IsRegisteredScanner(rs.scannerAddress.Hex())
+	if err != nil {
+		return fmt.Errorf("failed to check the scanner registration for the dev agents: %v", err)
+	}
+	if registered {
+		return fmt.Errorf("dev agents cannot run on a registered scanner - please use another scanner key in development")
+	}
+	return nil
+}
+
 // Start initializes and starts the registry service.
 func (rs *RegistryService) Start() error {
 	log.Infof("Starting %s", rs.Name())
@@ -85,11 +142,14 @@ func (rs *RegistryService) Start() error {
 		return err
 	}
 	rs.sem = semaphore.NewWeighted(1)
+	rs.msgClient.Subscribe(messaging.SubjectAgentsStatusFailed, messaging.AgentFailedHandler(rs.handleAgentFailed))
 	return rs.start()
 }
 
 func (rs *RegistryService) start() error {
+	rs.lastReconciled = time.Now()
 	go func() {
+		rs.replayCheckpoint()
 		ticker := time.NewTicker(time.Duration(rs.cfg.Registry.CheckIntervalSeconds) * time.Second)
 		for {
 			err := rs.publishLatestAgents()
@@ -97,6 +157,10 @@ func (rs *RegistryService) start() error {
 			if err != nil {
 				log.WithError(err).Error("failed to publish the latest agents")
 			}
+			// keep scanning with the last known agents if the registry is not reachable on startup
+			if err != nil && !rs.published {
+				rs.publishCheckpoint()
+			}
 			<-ticker.C
 		}
 	}()
@@ -108,7 +172,14 @@ func (rs *RegistryService) publishLatestAgents() error {
 	// only allow one executor at a time, even if slow
 	if rs.sem.TryAcquire(1) {
 		defer rs.sem.Release(1)
+		if rs.resyncRequested() {
+			return rs.resync()
+		}
+		if rs.shouldReconcile() {
+			return rs.reconcile()
+		}
 		rs.lastChecked.Set()
+		rs.checkBlock()
 		agts, changed, err := rs.registryStore.GetAgentsIfChanged(rs.scannerAddress.Hex())
 		if err != nil {
 			return fmt.Errorf("failed to get the scanner list agents version: %v", err)
@@ -116,15 +187,165 @@ func (rs *RegistryService) publishLatestAgents() error {
 		if changed {
 			rs.lastChangeDetected.Set()
 			log.WithField("count", len(agts)).Infof("publishing list of agents")
-			rs.agentsConfigs = agts
-			rs.msgClient.Publish(messaging.SubjectAgentsVersionsLatest, agts)
+			rs.publishAgents(agts)
 		} else {
 			log.Info("registry: no agent changes detected")
+			// move the checkpoint forward so that a restart replays only the new events
+			if rs.published && atomic.LoadInt32(&rs.stale) == 0 {
+				rs.saveCheckpoint(rs.agentsConfigs)
+			}
 		}
 	}
 	return nil
 }
 
+// publishRejection tells the other services which agent was rejected and why.
+func (rs *RegistryService) publishRejection(agentID, ref string, err *store.ManifestValidationError) {
+	log.WithFields(log.Fields{
+		"agentId":  agentID,
+		"manifest": ref,
+		"field":    err.Field,
+	}).WithError(err).Warn("rejected agent")
+	rs.counters.countRejected()
+	rs.msgClient.Publish(messaging.SubjectAgentsRejected, &messaging.AgentRejectedPayload{
+		AgentID:       agentID,
+		Manifest:      ref,
+		SchemaVersion: err.SchemaVersion,
+		Field:         err.Field,
+		Reason:        err.Reason,
+	})
+}
+
+// handleAgentFailed keeps track of the agents which the supervisor stopped restarting.
+func (rs *RegistryService) handleAgentFailed(payload messaging.AgentFailedPayload) error {
+	rs.counters.countFailed()
+	rs.lastFailedAgent.Set(fmt.Sprintf("%s: %s", payload.Agent.ID, payload.Reason))
+	return nil
+}
+
+// resync reloads all agents from the current registry state and publishes them even if the assignments
+// have not changed, so that a node which missed some changes gets back in sync. The resync is requested
+// with the resync request file in the Forta dir, e.g. by the admin API.
+func (rs *RegistryService) resync() error {
+	rs.lastChecked.Set()
+	rs.checkBlock()
+	agts, err := rs.registryStore.GetAgents(rs.scannerAddress.Hex())
+	if err != nil {
+		return fmt.Errorf("failed to resync the agents: %v", err)
+	}
+	if err := os.Remove(rs.resyncRequestPath()); err != nil && !os.IsNotExist(err) {
+		log.WithError(err).Warn("failed to remove the resync request")
+	}
+	rs.lastResync.Set()
+	rs.lastReconciled = time.Now()
+	log.WithField("count", len(agts)).Info("publishing resynced list of agents")
+	rs.publishAgents(agts)
+	return nil
+}
+
+func (rs *RegistryService) shouldReconcile() bool {
+	interval := time.Duration(rs.cfg.Registry.ReconcileIntervalSeconds) * time.Second
+	return interval > 0 && time.Since(rs.lastReconciled) > interval
+}
+
+// reconcile reloads all agents and publishes them if they differ from the last published agents. This
+// recovers the agents which were added or removed while the changes were missed.
+func (rs *RegistryService) reconcile() error {
+	rs.lastChecked.Set()
+	rs.checkBlock()
+	agts, err := rs.registryStore.GetAgents(rs.scannerAddress.Hex())
+	if err != nil {
+		return fmt.Errorf("failed to reconcile the agents: %v", err)
+	}
+	rs.lastReconciled = time.Now()
+	rs.lastReconcile.Set()
+	added, updated, removed := diffAgents(rs.agentsConfigs, agts)
+	if len(added) == 0 && len(updated) == 0 && len(removed) == 0 {
+		log.Info("registry: reconciled agents, no differences")
+		return nil
+	}
+	rs.lastChangeDetected.Set()
+	log.WithFields(log.Fields{
+		"count":   len(agts),
+		"added":   added,
+		"updated": updated,
+		"removed": removed,
+	}).Warn("registry: reconciled agents, publishing the missed changes")
+	rs.publishAgents(agts)
+	return nil
+}
+
+// diffAgents returns the container names of the agents which were added, updated and removed. An agent
+// is updated if any of its settings has changed while its container stays the same.
+func diffAgents(prev, latest []*config.AgentConfig) (added, updated, removed []string) {
+	prevByName := make(map[string]*config.AgentConfig)
+	for _, agentCfg := range prev {
+		prevByName[agentCfg.ContainerName()] = agentCfg
+	}
+	latestNames := make(map[string]bool)
+	for _, agentCfg := range latest {
+		latestNames[agentCfg.ContainerName()] = true
+		prevCfg, ok := prevByName[agentCfg.ContainerName()]
+		switch {
+		case !ok:
+			added = append(added, agentCfg.ContainerName())
+		case !reflect.DeepEqual(prevCfg, agentCfg):
+			updated = append(updated, agentCfg.ContainerName())
+		}
+	}
+	for _, agentCfg := range prev {
+		if !latestNames[agentCfg.ContainerName()] {
+			removed = append(removed, agentCfg.ContainerName())
+		}
+	}
+	return
+}
+
+// AgentChanges are the changes between two agent sets.
+type AgentChanges struct {
+	Added   []*config.AgentConfig
+	Updated []*config.AgentConfig
+	Removed []*config.AgentConfig
+}
+
+// CompareAgents compares the agents by their IDs. An agent is updated if its image or manifest has changed.
+func CompareAgents(prev, latest []*config.AgentConfig) *AgentChanges {
+	changes := &AgentChanges{}
+	prevByID := make(map[string]*config.AgentConfig)
+	for _, agentCfg := range prev {
+		prevByID[agentCfg.ID] = agentCfg
+	}
+	latestIDs := make(map[string]bool)
+	for _, agentCfg := range latest {
+		latestIDs[agentCfg.ID] = true
+		prevCfg, ok := prevByID[agentCfg.ID]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, agentCfg)
+		case prevCfg.Image != agentCfg.Image || prevCfg.Manifest != agentCfg.Manifest:
+			changes.Updated = append(changes.Updated, agentCfg)
+		}
+	}
+	for _, agentCfg := range prev {
+		if !latestIDs[agentCfg.ID] {
+			changes.Removed = append(changes.Removed, agentCfg)
+		}
+	}
+	return changes
+}
+
+func (rs *RegistryService) resyncRequestPath() string {
+	return path.Join(rs.cfg.FortaDir, ResyncRequestFileName)
+}
+
+func (rs *RegistryService) resyncRequested() bool {
+	if len(rs.cfg.FortaDir) == 0 {
+		return false
+	}
+	_, err := os.Stat(rs.resyncRequestPath())
+	return err == nil
+}
+
 // Stop stops the registry service.
 func (rs *RegistryService) Stop() error {
 	return nil
@@ -137,7 +358,7 @@ func (rs *RegistryService) Name() string {
 
 // Health implements the health.Reporter interface.
 func (rs *RegistryService) Health() health.Reports {
-	return health.Reports{
+	reports := health.Reports{
 		rs.lastErr.GetReport("event.checked.error"),
 		&health.Report{
 			Name:    "event.checked.time",
@@ -149,5 +370,16 @@ func (rs *RegistryService) Health() health.Reports {
 			Status:  health.StatusInfo,
 			Details: rs.lastChangeDetected.String(),
 		},
+		rs.lastResync.GetReport("event.resync.time"),
+		rs.lastReconcile.GetReport("event.reconcile.time"),
+		rs.staleReport(),
+		rs.lastFailedAgent.GetReport("event.agent-failed.details"),
+		rs.lastPublishAckErr.GetReport("event.publish-ack.error"),
+	}
+	reports = append(reports, rs.counters.reports()...)
+	// the manifest cache reports
+	if reporter, ok := rs.registryStore.(interface{ Health() health.Reports }); ok {
+		reports = append(reports, reporter.Health()...)
 	}
+	return reports
 }
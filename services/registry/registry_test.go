@@ -2,13 +2,24 @@ package registry
 
 import (
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/sync/semaphore"
 
+	"github.com/forta-network/forta-core-go/clients/health"
+	regmsg "github.com/forta-network/forta-core-go/domain/registry"
+	mock_ethereum "github.com/forta-network/forta-core-go/ethereum/mocks"
+	coreregistry "github.com/forta-network/forta-core-go/registry"
 	"github.com/forta-network/forta-node/clients/messaging"
 	mock_clients "github.com/forta-network/forta-node/clients/mocks"
 	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
 	mock_store "github.com/forta-network/forta-node/store/mocks"
 
 	"github.com/forta-network/forta-node/services/registry/regtypes"
@@ -105,7 +116,231 @@ func (s *Suite) TestPublishChanges() {
 	s.NoError(s.service.publishLatestAgents())
 }
 
+func (s *Suite) TestResyncRequest() {
+	s.service.cfg.FortaDir = s.T().TempDir()
+	requestPath := path.Join(s.service.cfg.FortaDir, ResyncRequestFileName)
+	s.r.NoError(ioutil.WriteFile(requestPath, []byte{}, 0644))
+
+	configs := (agentConfigs)([]*config.AgentConfig{
+		{
+			ID:    testAgentIDStr,
+			Image: fmt.Sprintf("%s/%s", testContainerRegistry, testImageRef),
+		},
+	})
+
+	// the agents are published even if they have not changed
+	s.registryStore.EXPECT().GetAgents(s.service.scannerAddress.Hex()).Return(configs, nil)
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsVersionsLatest, configs)
+	s.NoError(s.service.publishLatestAgents())
+
+	// the request is done
+	_, err := os.Stat(requestPath)
+	s.r.True(os.IsNotExist(err))
+	s.registryStore.EXPECT().GetAgentsIfChanged(s.service.scannerAddress.Hex()).Return(nil, false, nil)
+	s.NoError(s.service.publishLatestAgents())
+}
+
+func (s *Suite) TestReconcile() {
+	s.service.cfg.Registry.ReconcileIntervalSeconds = 60
+	configs := (agentConfigs)([]*config.AgentConfig{
+		{
+			ID:    testAgentIDStr,
+			Image: fmt.Sprintf("%s/%s", testContainerRegistry, testImageRef),
+		},
+	})
+	s.service.agentsConfigs = configs
+
+	// the agents are not published if they are the same
+	s.service.lastReconciled = time.Now().Add(-time.Hour)
+	s.registryStore.EXPECT().GetAgents(s.service.scannerAddress.Hex()).Return(configs, nil)
+	s.NoError(s.service.publishLatestAgents())
+
+	// the agents are not reconciled again before the interval
+	s.registryStore.EXPECT().GetAgentsIfChanged(s.service.scannerAddress.Hex()).Return(nil, false, nil)
+	s.NoError(s.service.publishLatestAgents())
+
+	// the missed changes are published
+	s.service.lastReconciled = time.Now().Add(-time.Hour)
+	s.registryStore.EXPECT().GetAgents(s.service.scannerAddress.Hex()).Return([]*config.AgentConfig{}, nil)
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsVersionsLatest, []*config.AgentConfig{})
+	s.NoError(s.service.publishLatestAgents())
+}
+
+func TestDiffAgents(t *testing.T) {
+	r := require.New(t)
+
+	image := fmt.Sprintf("%s/%s", testContainerRegistry, testImageRef)
+	agent1 := &config.AgentConfig{ID: "0x01", Image: image}
+	agent2 := &config.AgentConfig{ID: "0x02", Image: image}
+	agent2Updated := &config.AgentConfig{ID: "0x02", Image: strings.Replace(image, "sha256:cdd4", "sha256:abcd", 1)}
+
+	added, updated, removed := diffAgents([]*config.AgentConfig{agent1, agent2}, []*config.AgentConfig{agent1, agent2Updated})
+	r.Equal([]string{agent2Updated.ContainerName()}, added)
+	r.Empty(updated)
+	r.Equal([]string{agent2.ContainerName()}, removed)
+
+	added, updated, removed = diffAgents([]*config.AgentConfig{agent1}, []*config.AgentConfig{{ID: "0x01", Image: image}})
+	r.Empty(added)
+	r.Empty(updated)
+	r.Empty(removed)
+
+	// the settings which don't change the container are compared too
+	agent1Updated := &config.AgentConfig{ID: "0x01", Image: image, ChainIDs: []int64{137}}
+	added, updated, removed = diffAgents([]*config.AgentConfig{agent1}, []*config.AgentConfig{agent1Updated})
+	r.Empty(added)
+	r.Equal([]string{agent1.ContainerName()}, updated)
+	r.Empty(removed)
+}
+
+func TestCompareAgents(t *testing.T) {
+	r := require.New(t)
+
+	agent1 := &config.AgentConfig{ID: "0x01", Image: "image-1"}
+	agent2 := &config.AgentConfig{ID: "0x02", Image: "image-2"}
+	agent2Updated := &config.AgentConfig{ID: "0x02", Image: "image-2-updated"}
+	agent3 := &config.AgentConfig{ID: "0x03", Image: "image-3"}
+
+	changes := CompareAgents([]*config.AgentConfig{agent1, agent2}, []*config.AgentConfig{agent2Updated, agent3})
+	r.Equal([]*config.AgentConfig{agent3}, changes.Added)
+	r.Equal([]*config.AgentConfig{agent2Updated}, changes.Updated)
+	r.Equal([]*config.AgentConfig{agent1}, changes.Removed)
+}
+
 func (s *Suite) TestDoNotPublishChanges() {
 	s.registryStore.EXPECT().GetAgentsIfChanged(s.service.scannerAddress.Hex()).Return(nil, false, nil)
 	s.NoError(s.service.publishLatestAgents())
 }
+
+func (s *Suite) TestCheckpoint() {
+	s.service.cfg.FortaDir = s.T().TempDir()
+	agts := []*config.AgentConfig{{ID: testAgentIDStr, Image: testImageRef, Manifest: testAgentRef}}
+
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsVersionsLatest, agts)
+	s.service.publishAgents(agts)
+
+	// a restarted service publishes the agents from the checkpoint
+	s.service.agentsConfigs = nil
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsVersionsLatest, agentConfigs(agts))
+	s.service.publishCheckpoint()
+	s.r.Equal(health.StatusLagging, s.service.staleReport().Status)
+	s.r.Len(s.service.agentsConfigs, 1)
+	s.r.Equal(testAgentIDStr, s.service.agentsConfigs[0].ID)
+
+	// the agents are not stale after the next publish
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsVersionsLatest, agts)
+	s.service.publishAgents(agts)
+	s.r.Equal(health.StatusOK, s.service.staleReport().Status)
+}
+
+type testEventReplayer struct {
+	handlers   coreregistry.Handlers
+	startBlock *big.Int
+	endBlock   *big.Int
+	events     func(handlers coreregistry.Handlers)
+}
+
+func (tr *testEventReplayer) ProcessBlockRange(startBlock *big.Int, endBlock *big.Int) error {
+	tr.startBlock, tr.endBlock = startBlock, endBlock
+	tr.events(tr.handlers)
+	return nil
+}
+
+func (s *Suite) TestReplayCheckpoint() {
+	s.service.cfg.FortaDir = s.T().TempDir()
+	ethClient := mock_ethereum.NewMockClient(gomock.NewController(s.T()))
+	s.service.ethClient = ethClient
+	agts := []*config.AgentConfig{{ID: testAgentIDStr, Image: testImageRef, Manifest: testAgentRef}}
+
+	// the checkpoint keeps the block before the registry check
+	ethClient.EXPECT().BlockNumber(gomock.Any()).Return(big.NewInt(100), nil)
+	s.registryStore.EXPECT().GetAgentsIfChanged(s.service.scannerAddress.Hex()).Return(agts, true, nil)
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsVersionsLatest, agts)
+	s.NoError(s.service.publishLatestAgents())
+	cp := s.service.loadCheckpoint()
+	s.r.NotNil(cp)
+	s.r.Equal(uint64(100), cp.Block)
+
+	replayer := &testEventReplayer{}
+	s.service.newEventReplayer = func(handlers coreregistry.Handlers) (EventReplayer, error) {
+		replayer.handlers = handlers
+		return replayer, nil
+	}
+
+	// the events of the other scanners and agents do not change the agents
+	replayer.events = func(handlers coreregistry.Handlers) {
+		s.r.NoError(handlers.DispatchHandler(nil, &regmsg.DispatchMessage{ScannerID: "0x01", AgentID: "0x02"}))
+		s.r.NoError(handlers.AgentActionHandler(nil, &regmsg.AgentMessage{AgentID: "0x02"}))
+	}
+	ethClient.EXPECT().BlockNumber(gomock.Any()).Return(big.NewInt(150), nil)
+	s.service.replayCheckpoint()
+	s.r.Equal(int64(101), replayer.startBlock.Int64())
+	s.r.Equal(int64(150), replayer.endBlock.Int64())
+	s.r.False(s.service.resyncRequested())
+
+	// the agent of the scanner is updated while the node is down
+	replayer.events = func(handlers coreregistry.Handlers) {
+		msg := &regmsg.AgentSaveMessage{}
+		msg.AgentID = testAgentIDStr
+		s.r.NoError(handlers.SaveAgentHandler(nil, msg))
+	}
+	ethClient.EXPECT().BlockNumber(gomock.Any()).Return(big.NewInt(150), nil)
+	s.service.replayCheckpoint()
+	s.r.True(s.service.resyncRequested())
+	s.r.NoError(os.Remove(s.service.resyncRequestPath()))
+
+	// an agent is assigned to the scanner while the node is down
+	replayer.events = func(handlers coreregistry.Handlers) {
+		s.r.NoError(handlers.DispatchHandler(nil, &regmsg.DispatchMessage{ScannerID: strings.ToLower(testScannerAddressStr), AgentID: "0x03"}))
+	}
+	ethClient.EXPECT().BlockNumber(gomock.Any()).Return(big.NewInt(150), nil)
+	s.service.replayCheckpoint()
+	s.r.True(s.service.resyncRequested())
+	s.r.NoError(os.Remove(s.service.resyncRequestPath()))
+
+	// a checkpoint which is too old is not replayed
+	ethClient.EXPECT().BlockNumber(gomock.Any()).Return(big.NewInt(100+maxReplayBlocks+1), nil)
+	replayer.startBlock = nil
+	s.service.replayCheckpoint()
+	s.r.Nil(replayer.startBlock)
+	s.r.True(s.service.resyncRequested())
+}
+
+func (s *Suite) TestAgentCounters() {
+	agent1 := &config.AgentConfig{ID: "0x01", Image: "image-1"}
+	agent2 := &config.AgentConfig{ID: "0x02", Image: "image-2"}
+	agent2Updated := &config.AgentConfig{ID: "0x02", Image: "image-2-updated"}
+
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsVersionsLatest, gomock.Any()).Times(2)
+	s.service.publishAgents([]*config.AgentConfig{agent1, agent2})
+	s.service.publishAgents([]*config.AgentConfig{agent2Updated})
+
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsRejected, gomock.Any())
+	s.service.publishRejection("0x03", testAgentRef, &store.ManifestValidationError{SchemaVersion: 1, Field: "manifest", Reason: "required"})
+
+	s.r.NoError(s.service.handleAgentFailed(messaging.AgentFailedPayload{Agent: *agent1, Reason: "crash loop: OOM-killed"}))
+	s.r.Equal("0x01: crash loop: OOM-killed", s.service.lastFailedAgent.GetReport("event.agent-failed.details").Details)
+
+	var details []string
+	for _, report := range s.service.counters.reports() {
+		details = append(details, report.Details)
+	}
+	s.r.Equal([]string{"2", "1", "1", "1", "1"}, details)
+}
+
+type testScannerRegistry struct {
+	store.RegistryStore
+	registered bool
+}
+
+func (sr *testScannerRegistry) IsRegisteredScanner(scanner string) (bool, error) {
+	return sr.registered, nil
+}
+
+func (s *Suite) TestCheckDevAgents() {
+	s.NoError(s.service.checkDevAgents(&testScannerRegistry{registered: false}))
+	s.Error(s.service.checkDevAgents(&testScannerRegistry{registered: true}))
+
+	// the dev process does not publish the alerts
+	s.service.cfg.DevProcess = true
+	s.NoError(s.service.checkDevAgents(&testScannerRegistry{registered: true}))
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/forta-network/forta-node/audit"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultConfigCheckInterval = time.Second * 10
+
+// ReloadSignal makes the containers reload the config instead of exiting.
+const ReloadSignal = syscall.SIGHUP
+
+// Reloadable is implemented by the services which can apply the config changes at runtime. The services
+// should apply only the changes which are safe to apply without a restart and ignore the rest.
+type Reloadable interface {
+	Reload(cfg config.Config) error
+}
+
+// watchConfig reloads the config when the config file changes or when the reload signal is received, and
+// applies the new config to the services.
+func watchConfig(ctx context.Context, logger *log.Entry, cfg config.Config, serviceList []Service) {
+	reloadc := make(chan os.Signal, 1)
+	signal.Notify(reloadc, ReloadSignal)
+	defer signal.Stop(reloadc)
+
+	lastModified := configModTime()
+	ticker := time.NewTicker(defaultConfigCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-reloadc:
+			logger.Info("received the reload signal")
+
+		case <-ticker.C:
+			applyLogLevel(logger, cfg)
+			modified := configModTime()
+			if !modified.After(lastModified) {
+				continue
+			}
+			lastModified = modified
+			logger.Info("config file has changed")
+		}
+		if newCfg, ok := reloadConfig(logger, cfg, serviceList); ok {
+			cfg = newCfg
+		}
+	}
+}
+
+func configModTime() time.Time {
+	info, err := os.Stat(config.DefaultContainerConfigPath)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// reloadConfig reads the config file again and applies it. The contract addresses are kept since they
+// were resolved at the start.
+func reloadConfig(logger *log.Entry, cfg config.Config, serviceList []Service) (config.Config, bool) {
+	newCfg, err := config.GetConfigForContainer()
+	if err != nil {
+		logger.WithError(err).Error("could not reload config - keeping the current config")
+		return cfg, false
+	}
+	if len(newCfg.Registry.ContractAddress) == 0 {
+		newCfg.Registry.ContractAddress = cfg.Registry.ContractAddress
+	}
+	newCfg.ScannerVersionContractAddress = cfg.ScannerVersionContractAddress
+	newCfg.AgentRegistryContractAddress = cfg.AgentRegistryContractAddress
+
+	applyLogLevel(logger, newCfg)
+
+	for _, service := range serviceList {
+		reloadable, ok := service.(Reloadable)
+		if !ok {
+			continue
+		}
+		if err := reloadable.Reload(newCfg); err != nil {
+			logger.WithError(err).WithField("service", service.Name()).Error("failed to reload config")
+		}
+	}
+	logger.Info("reloaded config")
+	if sections := changedConfigSections(cfg, newCfg); len(sections) > 0 {
+		audit.Record(audit.EventConfigChanged, map[string]string{"sections": strings.Join(sections, ",")})
+	}
+	return newCfg, true
+}
+
+// changedConfigSections returns the names of the top level config sections which are different. The
+// runtime values are not from the config file and they are skipped.
+func changedConfigSections(oldCfg, newCfg config.Config) (sections []string) {
+	oldVal, newVal := reflect.ValueOf(oldCfg), reflect.ValueOf(newCfg)
+	cfgType := oldVal.Type()
+	for i := 0; i < cfgType.NumField(); i++ {
+		name := strings.Split(cfgType.Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "-" || reflect.DeepEqual(oldVal.Field(i).Interface(), newVal.Field(i).Interface()) {
+			continue
+		}
+		sections = append(sections, name)
+	}
+	return
+}
+
+// applyLogLevel sets the log level from the config or from the admin API override if it has changed.
+func applyLogLevel(logger *log.Entry, cfg config.Config) {
+	lvl, err := log.ParseLevel(config.LogLevel(cfg))
+	if err != nil {
+		logger.WithError(err).Error("could not reload log level")
+		return
+	}
+	if lvl == log.GetLevel() {
+		return
+	}
+	log.SetLevel(lvl)
+	logger.WithField("level", lvl).Info("reloaded log level")
+}
+
+// ReloadFunc is a service which only applies the config changes with a function. It helps reloading
+// the components which do not know which part of the config they use.
+type ReloadFunc struct {
+	name   string
+	reload func(cfg config.Config) error
+}
+
+// NewReloadFunc creates a new reload service.
+func NewReloadFunc(name string, reload func(cfg config.Config) error) *ReloadFunc {
+	return &ReloadFunc{name: name, reload: reload}
+}
+
+// Start implements the Service interface.
+func (rf *ReloadFunc) Start() error {
+	return nil
+}
+
+// Stop implements the Service interface.
+func (rf *ReloadFunc) Stop() error {
+	return nil
+}
+
+// Name implements the Service interface.
+func (rf *ReloadFunc) Name() string {
+	return rf.name
+}
+
+// Reload implements the Reloadable interface.
+func (rf *ReloadFunc) Reload(cfg config.Config) error {
+	return rf.reload(cfg)
+}
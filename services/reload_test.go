@@ -0,0 +1,21 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedConfigSections(t *testing.T) {
+	r := require.New(t)
+
+	oldCfg := config.Config{ChainID: 1, Passphrase: "old"}
+	newCfg := oldCfg
+	r.Empty(changedConfigSections(oldCfg, newCfg))
+
+	newCfg.Passphrase = "new"
+	newCfg.Log.Level = "debug"
+	newCfg.Security.AgentMTLS = true
+	r.Equal([]string{"log", "security"}, changedConfigSections(oldCfg, newCfg))
+}
@@ -0,0 +1,381 @@
+package runner
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/audit"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/services"
+	"github.com/forta-network/forta-node/services/registry"
+	"github.com/forta-network/forta-node/store"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultDeadLettersLimit = 100
+
+// AdminAPI serves the management API of the node. The changes are written to the Forta dir as requests
+// which the node containers pick up: the registry service stops the paused agents and starts the resumed
+// agents on the next check and the containers check the log level regularly.
+type AdminAPI struct {
+	ctx    context.Context
+	cfg    config.Config
+	server *http.Server
+
+	checkHealth       func() health.Reports
+	supervisorImageFn func() string
+	mu                sync.Mutex // protects the files
+}
+
+// AdminStatus is the status of the node.
+type AdminStatus struct {
+	SupervisorImage string         `json:"supervisorImage"`
+	LogLevel        string         `json:"logLevel"`
+	PausedAgents    []string       `json:"pausedAgents"`
+	Health          health.Reports `json:"health"`
+}
+
+// AdminLogLevelRequest changes the log level of the node.
+type AdminLogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// AdminDumpRequest requests the goroutine and heap dumps of the node processes.
+type AdminDumpRequest struct {
+	Kinds []string `json:"kinds"`
+}
+
+// AdminDumpResponse has the dumps of the runner. The containers write their dumps on their next check.
+type AdminDumpResponse struct {
+	ID    string   `json:"id"`
+	Files []string `json:"files"`
+}
+
+// NewAdminAPI creates the admin API which reports the health of the runner containers.
+func NewAdminAPI(ctx context.Context, cfg config.Config, runner *Runner) *AdminAPI {
+	return &AdminAPI{
+		ctx:         ctx,
+		cfg:         cfg,
+		checkHealth: runner.checkHealth,
+		supervisorImageFn: func() string {
+			runner.containerMu.RLock()
+			defer runner.containerMu.RUnlock()
+			return runner.currentSupervisorImg
+		},
+	}
+}
+
+// Start starts the service.
+func (api *AdminAPI) Start() error {
+	if !api.cfg.AdminAPI.Enable {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(api.fortaDirPath(api.cfg.AdminAPI.TLSCertFile), api.fortaDirPath(api.cfg.AdminAPI.TLSKeyFile))
+	if err != nil {
+		return fmt.Errorf("failed to load the admin API certificate: %v", err)
+	}
+	// the runner process uses the log level override as well
+	if lvl, err := log.ParseLevel(config.LogLevelOverride(api.cfg.FortaDir)); err == nil {
+		log.SetLevel(lvl)
+	}
+
+	api.server = &http.Server{
+		Addr:    api.cfg.AdminAPI.Address,
+		Handler: withAudit(api.withToken(api.router())),
+		TLSConfig: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		},
+	}
+	go func() {
+		switch err := api.server.ListenAndServeTLS("", ""); err {
+		case nil, http.ErrServerClosed:
+			// do nothing
+		default:
+			log.WithError(err).Error("admin API server error")
+		}
+	}()
+	log.WithField("address", api.cfg.AdminAPI.Address).Info("serving the admin API")
+	return nil
+}
+
+func (api *AdminAPI) router() http.Handler {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/status", api.getStatus).Methods(http.MethodGet)
+	router.HandleFunc("/agents/{id}/pause", api.pauseAgent).Methods(http.MethodPost)
+	router.HandleFunc("/agents/{id}/resume", api.resumeAgent).Methods(http.MethodPost)
+	router.HandleFunc("/resync", api.resync).Methods(http.MethodPost)
+	router.HandleFunc("/log-level", api.setLogLevel).Methods(http.MethodPut)
+	router.HandleFunc("/log-level", api.resetLogLevel).Methods(http.MethodDelete)
+	router.HandleFunc("/debug/dumps", api.requestDumps).Methods(http.MethodPost)
+	router.HandleFunc("/debug/dumps", api.listDumps).Methods(http.MethodGet)
+	router.HandleFunc("/debug/dumps/{name}", api.getDump).Methods(http.MethodGet)
+	router.HandleFunc("/messaging/dead-letters", api.listDeadLetters).Methods(http.MethodGet)
+	router.HandleFunc("/messaging/dead-letters", api.clearDeadLetters).Methods(http.MethodDelete)
+	return router
+}
+
+// withToken accepts only the requests which have the token as the bearer token.
+func (api *AdminAPI) withToken(handler http.Handler) http.Handler {
+	expected := []byte(fmt.Sprintf("Bearer %s", api.cfg.AdminAPI.Token))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(api.cfg.AdminAPI.Token) == 0 ||
+			subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeAdminResponse(w, http.StatusUnauthorized, map[string]string{"message": "invalid token"})
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder keeps the status code of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// withAudit records the calls to the audit log, including the ones which are rejected.
+func withAudit(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(rec, r)
+		audit.Record(audit.EventAdminAPICall, map[string]string{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"remoteAddr": r.RemoteAddr,
+			"status":     strconv.Itoa(rec.status),
+		})
+	})
+}
+
+func (api *AdminAPI) fortaDirPath(filePath string) string {
+	if path.IsAbs(filePath) {
+		return filePath
+	}
+	return path.Join(api.cfg.FortaDir, filePath)
+}
+
+func writeAdminResponse(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithError(err).Error("error writing the admin API response")
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, code int, err error) {
+	writeAdminResponse(w, code, map[string]string{"message": err.Error()})
+}
+
+func (api *AdminAPI) getStatus(w http.ResponseWriter, r *http.Request) {
+	pausedAgents, err := store.ReadPausedAgents(api.fortaDirPath(config.DefaultPausedAgentsFile))
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeAdminResponse(w, http.StatusOK, &AdminStatus{
+		SupervisorImage: api.supervisorImageFn(),
+		LogLevel:        config.LogLevel(api.cfg),
+		PausedAgents:    pausedAgents,
+		Health:          api.checkHealth(),
+	})
+}
+
+func (api *AdminAPI) pauseAgent(w http.ResponseWriter, r *http.Request) {
+	api.setAgentPaused(w, mux.Vars(r)["id"], true)
+}
+
+func (api *AdminAPI) resumeAgent(w http.ResponseWriter, r *http.Request) {
+	api.setAgentPaused(w, mux.Vars(r)["id"], false)
+}
+
+func (api *AdminAPI) setAgentPaused(w http.ResponseWriter, agentID string, paused bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	pausedAgents, err := store.SetAgentPaused(api.fortaDirPath(config.DefaultPausedAgentsFile), agentID, paused)
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.WithFields(log.Fields{
+		"agentId": agentID,
+		"paused":  paused,
+	}).Info("admin API: changed the paused agents")
+	writeAdminResponse(w, http.StatusOK, map[string][]string{"pausedAgents": pausedAgents})
+}
+
+func (api *AdminAPI) resync(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if err := ioutil.WriteFile(api.fortaDirPath(registry.ResyncRequestFileName), []byte{}, 0644); err != nil {
+		writeAdminError(w, http.StatusInternalServerError, fmt.Errorf("failed to write the resync request: %v", err))
+		return
+	}
+	log.Info("admin API: requested the registry resync")
+	writeAdminResponse(w, http.StatusAccepted, map[string]string{"message": "requested the resync"})
+}
+
+func (api *AdminAPI) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req AdminLogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+	lvl, err := log.ParseLevel(strings.TrimSpace(req.Level))
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if err := ioutil.WriteFile(api.fortaDirPath(config.DefaultLogLevelFile), []byte(lvl.String()), 0644); err != nil {
+		writeAdminError(w, http.StatusInternalServerError, fmt.Errorf("failed to write the log level: %v", err))
+		return
+	}
+	log.SetLevel(lvl)
+	log.WithField("level", lvl).Info("admin API: changed the log level")
+	writeAdminResponse(w, http.StatusOK, map[string]string{"logLevel": lvl.String()})
+}
+
+// resetLogLevel goes back to the log level from the config.
+func (api *AdminAPI) resetLogLevel(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if err := os.Remove(api.fortaDirPath(config.DefaultLogLevelFile)); err != nil && !os.IsNotExist(err) {
+		writeAdminError(w, http.StatusInternalServerError, fmt.Errorf("failed to remove the log level: %v", err))
+		return
+	}
+	if lvl, err := log.ParseLevel(api.cfg.Log.Level); err == nil {
+		log.SetLevel(lvl)
+	}
+	log.WithField("level", api.cfg.Log.Level).Info("admin API: reset the log level")
+	writeAdminResponse(w, http.StatusOK, map[string]string{"logLevel": api.cfg.Log.Level})
+}
+
+func (api *AdminAPI) requestDumps(w http.ResponseWriter, r *http.Request) {
+	req := AdminDumpRequest{Kinds: []string{"goroutine", "heap"}}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+			return
+		}
+	}
+	if err := services.ValidateDebugDumpKinds(req.Kinds); err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	request, err := services.RequestDebugDumps(api.cfg.FortaDir, req.Kinds)
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err)
+		return
+	}
+	files, err := services.WriteDebugDumps(services.DebugDumpsDir(api.cfg.FortaDir), "runner", req.Kinds)
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.WithField("kinds", req.Kinds).Info("admin API: requested the debug dumps")
+	writeAdminResponse(w, http.StatusAccepted, &AdminDumpResponse{ID: request.ID, Files: files})
+}
+
+func (api *AdminAPI) listDumps(w http.ResponseWriter, r *http.Request) {
+	files, err := ioutil.ReadDir(services.DebugDumpsDir(api.cfg.FortaDir))
+	if err != nil && !os.IsNotExist(err) {
+		writeAdminError(w, http.StatusInternalServerError, err)
+		return
+	}
+	fileNames := make([]string, 0, len(files))
+	for _, file := range files {
+		fileNames = append(fileNames, file.Name())
+	}
+	writeAdminResponse(w, http.StatusOK, map[string][]string{"files": fileNames})
+}
+
+func (api *AdminAPI) getDump(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if path.Base(name) != name || strings.HasPrefix(name, ".") {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid dump name"))
+		return
+	}
+	filePath := path.Join(services.DebugDumpsDir(api.cfg.FortaDir), name)
+	if _, err := os.Stat(filePath); err != nil {
+		writeAdminError(w, http.StatusNotFound, fmt.Errorf("dump not found"))
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, filePath)
+}
+
+// listDeadLetters returns the last bus messages which the node containers failed to handle.
+func (api *AdminAPI) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit := defaultDeadLettersLimit
+	if limitStr := r.URL.Query().Get("limit"); len(limitStr) > 0 {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			writeAdminError(w, http.StatusBadRequest, fmt.Errorf("?limit must be a positive number"))
+			return
+		}
+	}
+	deadLetters, err := store.ReadMessageDeadLetters(api.fortaDirPath(config.DefaultMsgDeadLettersFile), limit)
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deadLetters == nil {
+		deadLetters = []*messaging.DeadLetterPayload{}
+	}
+	writeAdminResponse(w, http.StatusOK, map[string][]*messaging.DeadLetterPayload{"deadLetters": deadLetters})
+}
+
+func (api *AdminAPI) clearDeadLetters(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if err := store.ClearMessageDeadLetters(api.fortaDirPath(config.DefaultMsgDeadLettersFile)); err != nil {
+		writeAdminError(w, http.StatusInternalServerError, fmt.Errorf("failed to clear the dead letters: %v", err))
+		return
+	}
+	log.Info("admin API: cleared the dead letters")
+	writeAdminResponse(w, http.StatusOK, map[string]string{"message": "cleared the dead letters"})
+}
+
+// Stop stops the service.
+func (api *AdminAPI) Stop() error {
+	if api.server != nil {
+		return api.server.Close()
+	}
+	return nil
+}
+
+// Name returns the name of the service.
+func (api *AdminAPI) Name() string {
+	return "admin-api"
+}
@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/services/registry"
+	"github.com/forta-network/forta-node/store"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminToken = "test-token"
+
+func testAdminAPI(t *testing.T) (*AdminAPI, http.Handler) {
+	cfg := config.Config{FortaDir: t.TempDir()}
+	cfg.Log.Level = "info"
+	cfg.AdminAPI.Token = testAdminToken
+	api := &AdminAPI{
+		ctx: context.Background(),
+		cfg: cfg,
+		checkHealth: func() health.Reports {
+			return health.Reports{{Name: "forta.container.forta-scanner", Status: health.StatusOK}}
+		},
+		supervisorImageFn: func() string { return "supervisor-image" },
+	}
+	return api, api.withToken(api.router())
+}
+
+func doAdminRequest(handler http.Handler, method, target, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminAPI_Token(t *testing.T) {
+	r := require.New(t)
+
+	_, handler := testAdminAPI(t)
+	r.Equal(http.StatusUnauthorized, doAdminRequest(handler, http.MethodGet, "/status", "", "").Code)
+	r.Equal(http.StatusUnauthorized, doAdminRequest(handler, http.MethodGet, "/status", "wrong-token", "").Code)
+	r.Equal(http.StatusOK, doAdminRequest(handler, http.MethodGet, "/status", testAdminToken, "").Code)
+}
+
+func TestAdminAPI_PauseResume(t *testing.T) {
+	r := require.New(t)
+
+	_, handler := testAdminAPI(t)
+	r.Equal(http.StatusOK, doAdminRequest(handler, http.MethodPost, "/agents/0xAgent/pause", testAdminToken, "").Code)
+
+	w := doAdminRequest(handler, http.MethodGet, "/status", testAdminToken, "")
+	var status AdminStatus
+	r.NoError(json.NewDecoder(w.Body).Decode(&status))
+	r.Equal([]string{"0xagent"}, status.PausedAgents)
+	r.Equal("supervisor-image", status.SupervisorImage)
+	r.Len(status.Health, 1)
+
+	r.Equal(http.StatusOK, doAdminRequest(handler, http.MethodPost, "/agents/0xagent/resume", testAdminToken, "").Code)
+	w = doAdminRequest(handler, http.MethodGet, "/status", testAdminToken, "")
+	status = AdminStatus{}
+	r.NoError(json.NewDecoder(w.Body).Decode(&status))
+	r.Empty(status.PausedAgents)
+}
+
+func TestAdminAPI_Resync(t *testing.T) {
+	r := require.New(t)
+
+	api, handler := testAdminAPI(t)
+	r.Equal(http.StatusAccepted, doAdminRequest(handler, http.MethodPost, "/resync", testAdminToken, "").Code)
+	r.FileExists(path.Join(api.cfg.FortaDir, registry.ResyncRequestFileName))
+}
+
+func TestAdminAPI_LogLevel(t *testing.T) {
+	r := require.New(t)
+	defer log.SetLevel(log.GetLevel())
+
+	api, handler := testAdminAPI(t)
+	r.Equal(http.StatusBadRequest, doAdminRequest(handler, http.MethodPut, "/log-level", testAdminToken, `{"level":"loud"}`).Code)
+
+	r.Equal(http.StatusOK, doAdminRequest(handler, http.MethodPut, "/log-level", testAdminToken, `{"level":"debug"}`).Code)
+	b, err := ioutil.ReadFile(path.Join(api.cfg.FortaDir, config.DefaultLogLevelFile))
+	r.NoError(err)
+	r.Equal("debug", string(b))
+	r.Equal("debug", config.LogLevel(api.cfg))
+	r.Equal(log.DebugLevel, log.GetLevel())
+
+	r.Equal(http.StatusOK, doAdminRequest(handler, http.MethodDelete, "/log-level", testAdminToken, "").Code)
+	r.Equal("info", config.LogLevel(api.cfg))
+	r.Equal(log.InfoLevel, log.GetLevel())
+}
+
+func TestAdminAPI_Dumps(t *testing.T) {
+	r := require.New(t)
+
+	api, handler := testAdminAPI(t)
+	r.Equal(http.StatusBadRequest, doAdminRequest(handler, http.MethodPost, "/debug/dumps", testAdminToken, `{"kinds":["cpu"]}`).Code)
+
+	w := doAdminRequest(handler, http.MethodPost, "/debug/dumps", testAdminToken, "")
+	r.Equal(http.StatusAccepted, w.Code)
+	var resp AdminDumpResponse
+	r.NoError(json.NewDecoder(w.Body).Decode(&resp))
+	r.NotEmpty(resp.ID)
+	r.Len(resp.Files, 2)
+	r.FileExists(path.Join(api.cfg.FortaDir, config.DefaultDebugDumpFile))
+
+	w = doAdminRequest(handler, http.MethodGet, "/debug/dumps", testAdminToken, "")
+	var list map[string][]string
+	r.NoError(json.NewDecoder(w.Body).Decode(&list))
+	r.ElementsMatch(resp.Files, list["files"])
+
+	w = doAdminRequest(handler, http.MethodGet, "/debug/dumps/"+resp.Files[0], testAdminToken, "")
+	r.Equal(http.StatusOK, w.Code)
+	r.Contains(w.Body.String(), "goroutine")
+	r.Equal(http.StatusNotFound, doAdminRequest(handler, http.MethodGet, "/debug/dumps/unknown.txt", testAdminToken, "").Code)
+	r.Equal(http.StatusBadRequest, doAdminRequest(handler, http.MethodGet, "/debug/dumps/.debug-dump", testAdminToken, "").Code)
+}
+
+func TestAdminAPI_DeadLetters(t *testing.T) {
+	r := require.New(t)
+
+	api, handler := testAdminAPI(t)
+	w := doAdminRequest(handler, http.MethodGet, "/messaging/dead-letters", testAdminToken, "")
+	r.Equal(http.StatusOK, w.Code)
+	r.JSONEq(`{"deadLetters":[]}`, w.Body.String())
+
+	deadLetters := store.NewMessageDeadLetters(path.Join(api.cfg.FortaDir, config.DefaultMsgDeadLettersFile))
+	for _, subject := range []string{messaging.SubjectAgentsActionRun, messaging.SubjectAgentsActionStop} {
+		r.NoError(deadLetters.Append(messaging.DeadLetterPayload{
+			Subject:  subject,
+			Client:   "supervisor",
+			Error:    "failed",
+			Attempts: 3,
+			Data:     []byte(`[]`),
+		}))
+	}
+
+	w = doAdminRequest(handler, http.MethodGet, "/messaging/dead-letters?limit=1", testAdminToken, "")
+	r.Equal(http.StatusOK, w.Code)
+	var resp map[string][]*messaging.DeadLetterPayload
+	r.NoError(json.NewDecoder(w.Body).Decode(&resp))
+	r.Len(resp["deadLetters"], 1)
+	r.Equal(messaging.SubjectAgentsActionStop, resp["deadLetters"][0].Subject)
+	r.Equal(`[]`, string(resp["deadLetters"][0].Data))
+	r.Equal(http.StatusBadRequest, doAdminRequest(handler, http.MethodGet, "/messaging/dead-letters?limit=0", testAdminToken, "").Code)
+
+	r.Equal(http.StatusOK, doAdminRequest(handler, http.MethodDelete, "/messaging/dead-letters", testAdminToken, "").Code)
+	w = doAdminRequest(handler, http.MethodGet, "/messaging/dead-letters", testAdminToken, "")
+	r.JSONEq(`{"deadLetters":[]}`, w.Body.String())
+}
@@ -0,0 +1,282 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	agentLogsSyncInterval   = time.Second * 5
+	agentLogsHistoryLines   = 1000
+	agentLogsFollowerBuffer = 100
+)
+
+// AgentLogs follows the logs of the agent containers, keeps the latest lines of every agent and
+// serves them on localhost, so that the bot developers can debug their agents without docker
+// access on the host. The history survives the agent restarts.
+type AgentLogs struct {
+	ctx    context.Context
+	client clients.DockerClient
+	server *http.Server
+
+	buffers   map[string]*logBuffer // agent ID -> logs
+	following map[string]bool       // container ID -> following
+	mu        sync.Mutex
+}
+
+// NewAgentLogs creates a new agent logs service which finds the agent containers with the client.
+func NewAgentLogs(ctx context.Context, client clients.DockerClient) *AgentLogs {
+	return &AgentLogs{
+		ctx:       ctx,
+		client:    client,
+		buffers:   make(map[string]*logBuffer),
+		following: make(map[string]bool),
+	}
+}
+
+// Start starts the service.
+func (al *AgentLogs) Start() error {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/agents/{id}/logs", al.getAgentLogs).Methods(http.MethodGet)
+	al.server = &http.Server{
+		Addr:    fmt.Sprintf("127.0.0.1:%s", config.DefaultAgentLogsPort),
+		Handler: router,
+	}
+	utils.GoListenAndServe(al.server)
+
+	go func() {
+		ticker := time.NewTicker(agentLogsSyncInterval)
+		defer ticker.Stop()
+		for {
+			al.sync()
+			select {
+			case <-al.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// sync starts following the logs of the running agent containers.
+func (al *AgentLogs) sync() {
+	containers, err := al.client.GetContainers(al.ctx)
+	if err != nil {
+		log.WithError(err).Warn("failed to get the containers - skipping agent logs sync")
+		return
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	for _, container := range containers {
+		agentID, ok := container.Labels[clients.DockerLabelFortaAgentID]
+		if !ok || container.State != "running" || al.following[container.ID] {
+			continue
+		}
+		buffer := al.getBufferUnsafe(agentID)
+		al.following[container.ID] = true
+		go al.follow(container.ID, buffer)
+	}
+}
+
+func (al *AgentLogs) getBufferUnsafe(agentID string) *logBuffer {
+	agentID = strings.ToLower(agentID)
+	buffer, ok := al.buffers[agentID]
+	if !ok {
+		buffer = newLogBuffer(agentLogsHistoryLines)
+		al.buffers[agentID] = buffer
+	}
+	return buffer
+}
+
+// follow adds the log lines of the container to the buffer until the container stops. The lines
+// which are in the buffer already are skipped when the same container runs again.
+func (al *AgentLogs) follow(containerID string, buffer *logBuffer) {
+	defer func() {
+		al.mu.Lock()
+		delete(al.following, containerID)
+		al.mu.Unlock()
+	}()
+
+	logs, err := al.client.FollowContainerLogs(al.ctx, containerID, buffer.lastTimestamp(containerID))
+	if err != nil {
+		log.WithError(err).WithField("container", containerID).Warn("failed to follow the agent logs")
+		return
+	}
+	defer logs.Close()
+
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		buffer.add(containerID, scanner.Text())
+	}
+}
+
+func writeAgentLogsError(w http.ResponseWriter, code int, str string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": str}); err != nil {
+		log.WithError(err).Errorf("error writing: %s", str)
+	}
+}
+
+// getAgentLogs writes the latest log lines of the agent and keeps writing the new lines if
+// the logs are followed. The lines can be limited with the tail.
+func (al *AgentLogs) getAgentLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	follow := query.Get("follow") == "true"
+
+	tail := agentLogsHistoryLines
+	if s := query.Get("tail"); len(s) > 0 {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeAgentLogsError(w, 400, "?tail must be a positive integer")
+			return
+		}
+		tail = n
+	}
+
+	agentID := strings.ToLower(mux.Vars(r)["id"])
+	al.mu.Lock()
+	buffer, ok := al.buffers[agentID]
+	al.mu.Unlock()
+	if !ok {
+		writeAgentLogsError(w, 404, "no logs found for the agent")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !follow {
+		for _, line := range buffer.history(tail) {
+			fmt.Fprintln(w, line)
+		}
+		return
+	}
+
+	history, lines, unfollow := buffer.follow(tail)
+	defer unfollow()
+	for _, line := range history {
+		fmt.Fprintln(w, line)
+	}
+	flusher, _ := w.(http.Flusher)
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case line := <-lines:
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// Stop stops the service.
+func (al *AgentLogs) Stop() error {
+	if al.server != nil {
+		return al.server.Close()
+	}
+	return nil
+}
+
+// Name returns the name of the service.
+func (al *AgentLogs) Name() string {
+	return "agent-logs"
+}
+
+// logBuffer is a ring buffer which keeps the latest log lines of an agent and sends the new lines
+// to the followers.
+type logBuffer struct {
+	lines      []string
+	next       int
+	full       bool
+	timestamps map[string]time.Time // container ID -> last line timestamp
+	followers  map[chan string]struct{}
+	mu         sync.Mutex
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{
+		lines:      make([]string, size),
+		timestamps: make(map[string]time.Time),
+		followers:  make(map[chan string]struct{}),
+	}
+}
+
+func (lb *logBuffer) add(containerID, line string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	// the lines start with an RFC3339 timestamp
+	if ts, err := time.Parse(time.RFC3339Nano, strings.SplitN(line, " ", 2)[0]); err == nil {
+		if !ts.After(lb.timestamps[containerID]) {
+			return
+		}
+		lb.timestamps[containerID] = ts
+	}
+
+	lb.lines[lb.next] = line
+	lb.next = (lb.next + 1) % len(lb.lines)
+	lb.full = lb.full || lb.next == 0
+
+	for follower := range lb.followers {
+		select {
+		case follower <- line:
+		default: // drop if the follower is too slow
+		}
+	}
+}
+
+func (lb *logBuffer) lastTimestamp(containerID string) time.Time {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.timestamps[containerID]
+}
+
+func (lb *logBuffer) history(tail int) []string {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.historyUnsafe(tail)
+}
+
+func (lb *logBuffer) historyUnsafe(tail int) []string {
+	var lines []string
+	if lb.full {
+		lines = append(lines, lb.lines[lb.next:]...)
+	}
+	lines = append(lines, lb.lines[:lb.next]...)
+	if len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return lines
+}
+
+// follow returns the history and the channel which receives the new lines until unfollowed.
+func (lb *logBuffer) follow(tail int) ([]string, <-chan string, func()) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	lines := make(chan string, agentLogsFollowerBuffer)
+	lb.followers[lines] = struct{}{}
+	unfollow := func() {
+		lb.mu.Lock()
+		delete(lb.followers, lines)
+		lb.mu.Unlock()
+	}
+	return lb.historyUnsafe(tail), lines, unfollow
+}
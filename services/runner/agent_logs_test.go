@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-node/clients"
+	mock_clients "github.com/forta-network/forta-node/clients/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogBuffer(t *testing.T) {
+	r := require.New(t)
+
+	buffer := newLogBuffer(3)
+	buffer.add("id1", "2022-05-01T00:00:00Z line1")
+	buffer.add("id1", "2022-05-01T00:00:01Z line2")
+	r.Equal([]string{"2022-05-01T00:00:00Z line1", "2022-05-01T00:00:01Z line2"}, buffer.history(10))
+
+	// the lines which were seen are skipped
+	buffer.add("id1", "2022-05-01T00:00:01Z line2")
+	buffer.add("id1", "2022-05-01T00:00:02Z line3")
+	buffer.add("id1", "2022-05-01T00:00:03Z line4")
+	r.Equal([]string{"2022-05-01T00:00:01Z line2", "2022-05-01T00:00:02Z line3", "2022-05-01T00:00:03Z line4"}, buffer.history(10))
+	r.Equal([]string{"2022-05-01T00:00:03Z line4"}, buffer.history(1))
+
+	history, lines, unfollow := buffer.follow(1)
+	r.Equal([]string{"2022-05-01T00:00:03Z line4"}, history)
+	buffer.add("id2", "2022-05-01T00:00:00Z line5")
+	r.Equal("2022-05-01T00:00:00Z line5", <-lines)
+	unfollow()
+	r.Empty(buffer.followers)
+}
+
+func TestAgentLogs(t *testing.T) {
+	r := require.New(t)
+
+	client := mock_clients.NewMockDockerClient(gomock.NewController(t))
+	al := NewAgentLogs(context.Background(), client)
+
+	client.EXPECT().GetContainers(gomock.Any()).Return(clients.DockerContainerList{
+		{ID: "id1", State: "running", Labels: map[string]string{clients.DockerLabelFortaAgentID: "0xAGENT"}},
+		{ID: "id2", State: "running"},
+	}, nil)
+	client.EXPECT().FollowContainerLogs(gomock.Any(), "id1", time.Time{}).Return(
+		ioutil.NopCloser(strings.NewReader("2022-05-01T00:00:00Z line1\n2022-05-01T00:00:01Z line2\n")), nil,
+	)
+	al.sync()
+	r.Eventually(func() bool {
+		al.mu.Lock()
+		defer al.mu.Unlock()
+		return !al.following["id1"]
+	}, time.Second, time.Millisecond*10)
+
+	rec := httptest.NewRecorder()
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/agents/0xagent/logs?tail=1", nil), map[string]string{"id": "0xagent"})
+	al.getAgentLogs(rec, req)
+	r.Equal(http.StatusOK, rec.Code)
+	r.Equal("2022-05-01T00:00:01Z line2\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	req = mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/agents/0xother/logs", nil), map[string]string{"id": "0xother"})
+	al.getAgentLogs(rec, req)
+	r.Equal(http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,333 @@
+package runner
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed" // for the dashboard page
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/healthutils"
+	"github.com/forta-network/forta-node/store"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	dashboardStatsInterval = time.Second * 30
+	dashboardAlertsWindow  = time.Hour * 24
+)
+
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// DashboardSummary is what the dashboard page shows.
+type DashboardSummary struct {
+	Timestamp      string                `json:"timestamp"`
+	ChainID        int                   `json:"chainId"`
+	ChainLag       *int64                `json:"chainLag"`
+	Agents         DashboardAgents       `json:"agents"`
+	AgentReports   health.Reports        `json:"agentReports"`
+	FailingReports health.Reports        `json:"failingReports"`
+	Containers     []*DashboardContainer `json:"containers"`
+	Alerts         []*DashboardAlert     `json:"alerts"`
+}
+
+// DashboardAgents are the agent counts of the agent pools.
+type DashboardAgents struct {
+	Total   int64 `json:"total"`
+	Lagging int64 `json:"lagging"`
+	Failed  int64 `json:"failed"`
+}
+
+// DashboardContainer is the state and the resource usage of a node or an agent container.
+type DashboardContainer struct {
+	Name        string  `json:"name"`
+	State       string  `json:"state"`
+	CPUPercent  float64 `json:"cpuPercent"`
+	MemoryUsage int64   `json:"memoryUsage"`
+}
+
+// DashboardAlert is a stored alert.
+type DashboardAlert struct {
+	Timestamp string `json:"timestamp"`
+	Hash      string `json:"hash"`
+	AgentID   string `json:"agentId"`
+	AlertID   string `json:"alertId"`
+	Name      string `json:"name"`
+	Severity  string `json:"severity"`
+	ChainID   string `json:"chainId"`
+	TxHash    string `json:"txHash"`
+}
+
+// Dashboard serves the web UI of the node on the host. The recent alerts are read from the alert store
+// in the Forta dir, the agent health and the chain lag from the health reports of the containers and the
+// resource usage is collected from the container runtime in the background.
+type Dashboard struct {
+	ctx          context.Context
+	cfg          config.Config
+	server       *http.Server
+	dockerClient clients.DockerClient
+
+	checkHealth func() health.Reports
+	containers  []*DashboardContainer
+	mu          sync.RWMutex
+}
+
+// NewDashboard creates the dashboard which shows the state of the runner containers.
+func NewDashboard(ctx context.Context, cfg config.Config, runner *Runner) *Dashboard {
+	return &Dashboard{
+		ctx:          ctx,
+		cfg:          cfg,
+		dockerClient: runner.globalClient,
+		checkHealth:  runner.checkHealth,
+	}
+}
+
+// Start starts the service.
+func (d *Dashboard) Start() error {
+	if !d.cfg.Dashboard.Enable {
+		return nil
+	}
+	if len(d.cfg.Dashboard.Token) == 0 && !isLoopbackAddress(d.cfg.Dashboard.Address) {
+		return fmt.Errorf("dashboard token is required to serve on %s", d.cfg.Dashboard.Address)
+	}
+	go d.collectContainers()
+	d.server = &http.Server{
+		Addr:    d.cfg.Dashboard.Address,
+		Handler: d.router(),
+	}
+	utils.GoListenAndServe(d.server)
+	log.WithField("address", d.cfg.Dashboard.Address).Info("serving the dashboard")
+	return nil
+}
+
+func (d *Dashboard) router() http.Handler {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/", d.getPage).Methods(http.MethodGet)
+	router.Handle("/api/summary", d.withToken(http.HandlerFunc(d.getSummary))).Methods(http.MethodGet)
+	return router
+}
+
+// withToken accepts only the requests which have the token as the bearer token if there is a token.
+func (d *Dashboard) withToken(handler http.Handler) http.Handler {
+	expected := []byte(fmt.Sprintf("Bearer %s", d.cfg.Dashboard.Token))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(d.cfg.Dashboard.Token) > 0 &&
+			subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "invalid token"})
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackAddress tells if the address is reachable only from the host.
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (d *Dashboard) getPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(dashboardPage); err != nil {
+		log.WithError(err).Error("error writing the dashboard page")
+	}
+}
+
+func (d *Dashboard) getSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := d.summary()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		log.WithError(err).Error("error writing the dashboard summary")
+	}
+}
+
+func (d *Dashboard) summary() (*DashboardSummary, error) {
+	summary := &DashboardSummary{
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+		ChainID:        d.cfg.ChainID,
+		AgentReports:   health.Reports{},
+		FailingReports: health.Reports{},
+	}
+
+	reports := d.checkHealth()
+	for _, value := range healthutils.ReportValues(reports, "tx-stream", "chain.lag") {
+		if summary.ChainLag == nil || value > *summary.ChainLag {
+			lag := value
+			summary.ChainLag = &lag
+		}
+	}
+	for _, value := range healthutils.ReportValues(reports, "agent-pool", "agents.total") {
+		summary.Agents.Total += value
+	}
+	for _, value := range healthutils.ReportValues(reports, "agent-pool", "agents.lagging") {
+		summary.Agents.Lagging += value
+	}
+	for _, value := range healthutils.ReportValues(reports, "agent-pool", "agents.failed") {
+		summary.Agents.Failed += value
+	}
+	for _, report := range reports {
+		// the agent pool, the canaries and the other agent services
+		if strings.Contains(report.Name, ".service.agent") {
+			summary.AgentReports = append(summary.AgentReports, report)
+		}
+		switch report.Status {
+		case health.StatusDown, health.StatusFailing, health.StatusLagging:
+			summary.FailingReports = append(summary.FailingReports, report)
+		}
+	}
+
+	d.mu.RLock()
+	summary.Containers = d.containers
+	d.mu.RUnlock()
+	if summary.Containers == nil {
+		summary.Containers = []*DashboardContainer{}
+	}
+
+	alerts, err := d.recentAlerts()
+	if err != nil {
+		return nil, err
+	}
+	summary.Alerts = alerts
+	return summary, nil
+}
+
+// recentAlerts returns the latest stored alerts of the last day first. The files of the chains are read one
+// after the other so the alerts are trimmed by their time.
+func (d *Dashboard) recentAlerts() ([]*DashboardAlert, error) {
+	limit := d.cfg.Dashboard.AlertsLimit
+	var alerts []*DashboardAlert
+	latestFirst := func() {
+		sort.SliceStable(alerts, func(i, j int) bool {
+			return alerts[i].Timestamp > alerts[j].Timestamp
+		})
+		if len(alerts) > limit {
+			alerts = alerts[:limit]
+		}
+	}
+	now := time.Now()
+	err := store.ReadStoredAlerts(
+		path.Join(d.cfg.FortaDir, config.DefaultAlertsDirName), nil, now.Add(-dashboardAlertsWindow), now,
+		func(alert *protocol.SignedAlert) error {
+			alerts = append(alerts, toDashboardAlert(alert))
+			if len(alerts) >= limit*2 {
+				latestFirst()
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	latestFirst()
+	if alerts == nil {
+		alerts = []*DashboardAlert{}
+	}
+	return alerts, nil
+}
+
+func toDashboardAlert(alert *protocol.SignedAlert) *DashboardAlert {
+	tags := alert.GetAlert().GetTags()
+	return &DashboardAlert{
+		Timestamp: store.StoredAlertTime(alert).UTC().Format(time.RFC3339),
+		Hash:      store.StoredAlertHash(alert),
+		AgentID:   alert.GetAlert().GetAgent().GetId(),
+		AlertID:   alert.GetAlert().GetFinding().GetAlertId(),
+		Name:      alert.GetAlert().GetFinding().GetName(),
+		Severity:  alert.GetAlert().GetFinding().GetSeverity().String(),
+		ChainID:   tags["chainId"],
+		TxHash:    tags["txHash"],
+	}
+}
+
+// collectContainers collects the resource usage of the containers periodically since getting the stats of
+// a container takes a while.
+func (d *Dashboard) collectContainers() {
+	ticker := time.NewTicker(dashboardStatsInterval)
+	defer ticker.Stop()
+	for {
+		d.doCollectContainers()
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dashboard) doCollectContainers() {
+	containers, err := d.dockerClient.GetContainers(d.ctx)
+	if err != nil {
+		log.WithError(err).Warn("failed to get the containers for the dashboard")
+		return
+	}
+	dashboardContainers := make([]*DashboardContainer, 0, len(containers))
+	for _, container := range containers {
+		if len(container.Names) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(container.Names[0], "/")
+		if !strings.HasPrefix(name, config.ContainerNamePrefix+"-") {
+			continue
+		}
+		dc := &DashboardContainer{Name: name, State: container.State}
+		if container.State == "running" {
+			stats, err := d.dockerClient.GetContainerStats(d.ctx, container.ID)
+			if err != nil {
+				log.WithError(err).WithField("container", name).Debug("failed to get the container stats")
+			} else {
+				dc.CPUPercent = stats.CPUPercent
+				dc.MemoryUsage = stats.MemoryUsage
+			}
+		}
+		dashboardContainers = append(dashboardContainers, dc)
+	}
+	sort.Slice(dashboardContainers, func(i, j int) bool {
+		return dashboardContainers[i].Name < dashboardContainers[j].Name
+	})
+
+	d.mu.Lock()
+	d.containers = dashboardContainers
+	d.mu.Unlock()
+}
+
+// Stop stops the service.
+func (d *Dashboard) Stop() error {
+	if d.server != nil {
+		return d.server.Close()
+	}
+	return nil
+}
+
+// Name returns the name of the service.
+func (d *Dashboard) Name() string {
+	return "dashboard"
+}
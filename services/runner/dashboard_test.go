@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/clients"
+	mock_clients "github.com/forta-network/forta-node/clients/mocks"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func testDashboardAlert(id string, ts time.Time) *protocol.SignedAlert {
+	return &protocol.SignedAlert{
+		Alert: &protocol.Alert{
+			Id:        id,
+			Timestamp: ts.Format(time.RFC3339Nano),
+			Agent:     &protocol.AgentInfo{Id: "0xagent"},
+			Finding:   &protocol.Finding{AlertId: "ALERT-" + id, Severity: protocol.Finding_HIGH},
+			Tags:      map[string]string{"txHash": "0xtx"},
+		},
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	r := require.New(t)
+
+	cfg := config.Config{ChainID: 1, FortaDir: t.TempDir()}
+	cfg.Dashboard.AlertsLimit = 2
+	client := mock_clients.NewMockDockerClient(gomock.NewController(t))
+	d := &Dashboard{
+		ctx:          context.Background(),
+		cfg:          cfg,
+		dockerClient: client,
+		checkHealth: func() health.Reports {
+			return health.Reports{
+				{Name: "forta.container.forta-scanner", Status: health.StatusOK, Details: "running"},
+				{Name: "forta.container.forta-scanner.service.tx-stream.chain.lag", Status: health.StatusInfo, Details: "3"},
+				{Name: "forta.container.forta-scanner.service.agent-pool.agents.total", Status: health.StatusOK, Details: "5"},
+				{Name: "forta.container.forta-scanner.service.agent-pool.agents.failed", Status: health.StatusInfo, Details: "1"},
+				{Name: "forta.container.forta-scanner.service.agent-canaries.canary.0xagent", Status: health.StatusFailing},
+			}
+		},
+	}
+
+	// the latest alerts of all chains are shown
+	alertsDir := path.Join(cfg.FortaDir, config.DefaultAlertsDirName)
+	now := time.Now().UTC()
+	r.NoError(store.NewAlertFileStore(alertsDir, 1, 7).Put(testDashboardAlert("1", now.Add(-time.Minute*3))))
+	r.NoError(store.NewAlertFileStore(alertsDir, 1, 7).Put(testDashboardAlert("2", now.Add(-time.Minute))))
+	r.NoError(store.NewAlertFileStore(alertsDir, 137, 7).Put(testDashboardAlert("3", now.Add(-time.Minute*2))))
+
+	client.EXPECT().GetContainers(gomock.Any()).Return(clients.DockerContainerList{
+		{ID: "id1", Names: []string{"/forta-scanner"}, State: "running"},
+		{ID: "id2", Names: []string{"/forta-agent-0x1234"}, State: "exited"},
+		{ID: "id3", Names: []string{"/other"}, State: "running"},
+		{ID: "id4", State: "created"},
+	}, nil)
+	client.EXPECT().GetContainerStats(gomock.Any(), "id1").Return(&clients.ContainerStats{CPUPercent: 12.5, MemoryUsage: 1024}, nil)
+	d.doCollectContainers()
+
+	handler := d.router()
+	req := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	r.Equal(http.StatusOK, w.Code)
+
+	var summary DashboardSummary
+	r.NoError(json.NewDecoder(w.Body).Decode(&summary))
+	r.Equal(1, summary.ChainID)
+	r.Equal(int64(3), *summary.ChainLag)
+	r.Equal(DashboardAgents{Total: 5, Failed: 1}, summary.Agents)
+	r.Len(summary.AgentReports, 3)
+	r.Len(summary.FailingReports, 1)
+	r.Equal([]*DashboardContainer{
+		{Name: "forta-agent-0x1234", State: "exited"},
+		{Name: "forta-scanner", State: "running", CPUPercent: 12.5, MemoryUsage: 1024},
+	}, summary.Containers)
+	r.Len(summary.Alerts, 2)
+	r.Equal("ALERT-2", summary.Alerts[0].AlertID)
+	r.Equal("ALERT-3", summary.Alerts[1].AlertID)
+	r.Equal("0xtx", summary.Alerts[0].TxHash)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	r.Equal(http.StatusOK, w.Code)
+	r.Contains(w.Body.String(), "api/summary")
+}
+
+func TestDashboard_Token(t *testing.T) {
+	r := require.New(t)
+
+	cfg := config.Config{ChainID: 1, FortaDir: t.TempDir()}
+	cfg.Dashboard.AlertsLimit = 1
+	cfg.Dashboard.Token = "secret"
+	d := &Dashboard{
+		ctx: context.Background(),
+		cfg: cfg,
+		checkHealth: func() health.Reports {
+			return health.Reports{}
+		},
+	}
+	handler := d.router()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	r.Equal(http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	r.Equal(http.StatusOK, w.Code)
+
+	// the page loads the summary with the token
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	r.Equal(http.StatusOK, w.Code)
+}
+
+func TestDashboard_StartWithoutToken(t *testing.T) {
+	r := require.New(t)
+
+	cfg := config.Config{}
+	cfg.Dashboard.Enable = true
+	cfg.Dashboard.Address = "0.0.0.0:8101"
+	r.Error((&Dashboard{ctx: context.Background(), cfg: cfg}).Start())
+
+	r.True(isLoopbackAddress("127.0.0.1:8101"))
+	r.True(isLoopbackAddress("localhost:8101"))
+	r.True(isLoopbackAddress("[::1]:8101"))
+	r.False(isLoopbackAddress(":8101"))
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/clients/messaging"
+	log "github.com/sirupsen/logrus"
+)
+
+// DevAgents replaces the supervisor in the dev process. The process agents are started by the
+// process agents service, so the run and the stop actions only report them back to the agent pool.
+// The container agents are not run since there is no container runtime.
+type DevAgents struct {
+	ctx       context.Context
+	msgClient clients.MessageClient
+}
+
+// NewDevAgents creates a new dev agents service.
+func NewDevAgents(ctx context.Context, msgClient clients.MessageClient) *DevAgents {
+	return &DevAgents{
+		ctx:       ctx,
+		msgClient: msgClient,
+	}
+}
+
+func (da *DevAgents) handleAgentRun(payload messaging.AgentPayload) error {
+	var running messaging.AgentPayload
+	for _, agent := range payload {
+		if !agent.IsProcess() {
+			log.WithField("agent", agent.ID).Warn("skipping the container agent in the dev process - please add a command to run it")
+			continue
+		}
+		running = append(running, agent)
+	}
+	if len(running) > 0 {
+		da.msgClient.Publish(messaging.SubjectAgentsStatusRunning, running)
+	}
+	return nil
+}
+
+func (da *DevAgents) handleAgentStop(payload messaging.AgentPayload) error {
+	if len(payload) > 0 {
+		da.msgClient.Publish(messaging.SubjectAgentsStatusStopped, payload)
+	}
+	return nil
+}
+
+// Start starts the service.
+func (da *DevAgents) Start() error {
+	da.msgClient.Subscribe(messaging.SubjectAgentsActionRun, messaging.WithAck(messaging.AgentsHandler(da.handleAgentRun)))
+	da.msgClient.Subscribe(messaging.SubjectAgentsActionStop, messaging.WithAck(messaging.AgentsHandler(da.handleAgentStop)))
+	return nil
+}
+
+// Stop stops the service.
+func (da *DevAgents) Stop() error {
+	return nil
+}
+
+// Name returns the name of the service.
+func (da *DevAgents) Name() string {
+	return "dev-agents"
+}
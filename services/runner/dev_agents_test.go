@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/forta-network/forta-node/clients/messaging"
+	mock_clients "github.com/forta-network/forta-node/clients/mocks"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevAgents(t *testing.T) {
+	r := require.New(t)
+
+	msgClient := mock_clients.NewMockMessageClient(gomock.NewController(t))
+	da := NewDevAgents(context.Background(), msgClient)
+
+	processAgent := config.AgentConfig{ID: "process-agent", Command: []string{"npm", "start"}, Port: "50052"}
+	containerAgent := config.AgentConfig{ID: "container-agent", Image: "agent:latest"}
+
+	// only the process agents are reported as running
+	msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusRunning, messaging.AgentPayload{processAgent})
+	r.NoError(da.handleAgentRun(messaging.AgentPayload{processAgent, containerAgent}))
+
+	// nothing to report
+	r.NoError(da.handleAgentRun(messaging.AgentPayload{containerAgent}))
+
+	msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusStopped, messaging.AgentPayload{processAgent})
+	r.NoError(da.handleAgentStop(messaging.AgentPayload{processAgent}))
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/crash"
 )
 
 func (runner *Runner) checkHealth() (allReports health.Reports) {
@@ -65,5 +66,15 @@ func (runner *Runner) checkHealth() (allReports health.Reports) {
 			Details: "no source found",
 		})
 	}
+	allReports = append(allReports, runner.crashesReport())
 	return
 }
+
+// crashesReport reports the number of the crash reports which the node processes wrote.
+func (runner *Runner) crashesReport() *health.Report {
+	names, err := crash.ListReports(crash.Dir(runner.cfg.FortaDir))
+	if err != nil {
+		return &health.Report{Name: "forta.crashes.count", Status: health.StatusUnknown, Details: err.Error()}
+	}
+	return &health.Report{Name: "forta.crashes.count", Status: health.StatusInfo, Details: strconv.Itoa(len(names))}
+}
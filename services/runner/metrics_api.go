@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/healthutils"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// MetricsAPI serves the health reports of the scanner, supervisor and the other node containers
+// as Prometheus metrics.
+type MetricsAPI struct {
+	ctx    context.Context
+	cfg    config.Config
+	server *http.Server
+
+	checkHealth func() health.Reports
+}
+
+// NewMetricsAPI creates the metrics API which collects the reports of the runner containers.
+func NewMetricsAPI(ctx context.Context, cfg config.Config, runner *Runner) *MetricsAPI {
+	return &MetricsAPI{
+		ctx:         ctx,
+		cfg:         cfg,
+		checkHealth: runner.checkHealth,
+	}
+}
+
+// Start starts the service.
+func (api *MetricsAPI) Start() error {
+	if !api.cfg.Metrics.Enable {
+		return nil
+	}
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/metrics", api.getMetrics).Methods(http.MethodGet)
+	api.server = &http.Server{
+		Addr:    api.cfg.Metrics.Address,
+		Handler: router,
+	}
+	utils.GoListenAndServe(api.server)
+	log.WithField("address", api.cfg.Metrics.Address).Info("serving the metrics")
+	return nil
+}
+
+func (api *MetricsAPI) getMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := healthutils.WriteMetrics(w, api.checkHealth()); err != nil {
+		log.WithError(err).Error("error writing the metrics")
+	}
+}
+
+// Stop stops the service.
+func (api *MetricsAPI) Stop() error {
+	if api.server != nil {
+		return api.server.Close()
+	}
+	return nil
+}
+
+// Name returns the name of the service.
+func (api *MetricsAPI) Name() string {
+	return "metrics-api"
+}
@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/healthutils"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// NodeHealthResponse is the response of the node health endpoints.
+type NodeHealthResponse struct {
+	Status health.Status  `json:"status"`
+	Checks health.Reports `json:"checks"`
+}
+
+// NodeHealthAPI composes the health of the node containers and serves it at /health for the liveness
+// probes and at /ready for the readiness probes.
+type NodeHealthAPI struct {
+	ctx    context.Context
+	cfg    config.Config
+	server *http.Server
+
+	checkHealth func() health.Reports
+}
+
+// NewNodeHealthAPI creates the node health API which checks the reports of the runner containers.
+func NewNodeHealthAPI(ctx context.Context, cfg config.Config, runner *Runner) *NodeHealthAPI {
+	return &NodeHealthAPI{
+		ctx:         ctx,
+		cfg:         cfg,
+		checkHealth: runner.checkHealth,
+	}
+}
+
+// Start starts the service.
+func (api *NodeHealthAPI) Start() error {
+	if !api.cfg.NodeHealth.Enable {
+		return nil
+	}
+	api.server = &http.Server{
+		Addr:    api.cfg.NodeHealth.Address,
+		Handler: api.router(),
+	}
+	utils.GoListenAndServe(api.server)
+	log.WithField("address", api.cfg.NodeHealth.Address).Info("serving the node health")
+	return nil
+}
+
+func (api *NodeHealthAPI) router() http.Handler {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/health", api.getHealth).Methods(http.MethodGet)
+	router.HandleFunc("/ready", api.getReady).Methods(http.MethodGet)
+	return router
+}
+
+func (api *NodeHealthAPI) checks() health.Reports {
+	return healthutils.NodeChecks(api.checkHealth(), checkWritable(api.cfg.FortaDir), api.cfg.NodeHealth)
+}
+
+func (api *NodeHealthAPI) getHealth(w http.ResponseWriter, r *http.Request) {
+	checks := api.checks()
+	writeNodeHealth(w, checks, healthutils.IsAlive(checks))
+}
+
+func (api *NodeHealthAPI) getReady(w http.ResponseWriter, r *http.Request) {
+	checks := api.checks()
+	writeNodeHealth(w, checks, healthutils.IsReady(checks))
+}
+
+func writeNodeHealth(w http.ResponseWriter, checks health.Reports, ok bool) {
+	resp := &NodeHealthResponse{Status: health.StatusOK, Checks: checks}
+	code := http.StatusOK
+	if !ok {
+		resp.Status = health.StatusFailing
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.WithError(err).Error("error writing the node health")
+	}
+}
+
+// checkWritable checks if a file can be written to the dir.
+func checkWritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".health-")
+	if err != nil {
+		return fmt.Errorf("failed to create a file: %v", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Stop stops the service.
+func (api *NodeHealthAPI) Stop() error {
+	if api.server != nil {
+		return api.server.Close()
+	}
+	return nil
+}
+
+// Name returns the name of the service.
+func (api *NodeHealthAPI) Name() string {
+	return "node-health-api"
+}
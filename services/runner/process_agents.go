@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const processAgentsSyncInterval = time.Second * 10
+
+// ProcessAgents runs the agents which have a command in the dev agents file as processes on the
+// host, so that the developers can attach debuggers to them. The scanner dials them through the
+// host like the agent containers. A process which exits is started again at the next sync.
+type ProcessAgents struct {
+	ctx      context.Context
+	enabled  bool
+	filePath string
+
+	processes map[string]*agentProcess
+	mu        sync.Mutex
+}
+
+type agentProcess struct {
+	agent config.AgentConfig
+	cmd   *exec.Cmd
+	done  chan struct{}
+}
+
+// NewProcessAgents creates a new process agents service.
+func NewProcessAgents(ctx context.Context, cfg config.Config) *ProcessAgents {
+	return &ProcessAgents{
+		ctx:       ctx,
+		enabled:   config.DevAgentsEnabled(cfg),
+		filePath:  path.Join(cfg.FortaDir, config.DefaultDevAgentsFileName),
+		processes: make(map[string]*agentProcess),
+	}
+}
+
+// Start starts the service.
+func (pa *ProcessAgents) Start() error {
+	if !pa.enabled {
+		return nil
+	}
+	pa.sync()
+	go func() {
+		ticker := time.NewTicker(processAgentsSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pa.ctx.Done():
+				return
+			case <-ticker.C:
+				pa.sync()
+			}
+		}
+	}()
+	return nil
+}
+
+// sync starts the new and the exited process agents and stops the removed or changed ones.
+func (pa *ProcessAgents) sync() {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	agents, err := store.ReadDevAgents(pa.filePath)
+	if err != nil {
+		log.WithError(err).WithField("path", pa.filePath).Warn("failed to read the dev agents - skipping process agents sync")
+		return
+	}
+	latest := make(map[string]config.AgentConfig)
+	for _, agent := range agents {
+		if agent.IsProcess() {
+			latest[agent.ID] = *agent
+		}
+	}
+
+	for id, process := range pa.processes {
+		agent, ok := latest[id]
+		if ok && reflect.DeepEqual(agent, process.agent) && !process.exited() {
+			continue
+		}
+		process.stop()
+		delete(pa.processes, id)
+	}
+
+	for id, agent := range latest {
+		if _, ok := pa.processes[id]; ok {
+			continue
+		}
+		process, err := startAgentProcess(agent)
+		if err != nil {
+			log.WithError(err).WithField("agent", id).Error("failed to start the process agent")
+			continue
+		}
+		pa.processes[id] = process
+	}
+}
+
+func startAgentProcess(agent config.AgentConfig) (*agentProcess, error) {
+	logger := log.WithField("agent", agent.ID)
+	output := logger.WriterLevel(log.InfoLevel)
+
+	cmd := exec.Command(agent.Command[0], agent.Command[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", config.EnvAgentGrpcPort, agent.GrpcPort()))
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// run in a new process group so that the children of the command can be stopped as well
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		output.Close()
+		return nil, err
+	}
+	logger.WithField("pid", cmd.Process.Pid).Info("started the process agent")
+
+	process := &agentProcess{agent: agent, cmd: cmd, done: make(chan struct{})}
+	go func() {
+		err := cmd.Wait()
+		output.Close()
+		logger.WithError(err).Warn("process agent exited")
+		close(process.done)
+	}()
+	return process, nil
+}
+
+func (process *agentProcess) exited() bool {
+	select {
+	case <-process.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop interrupts the process group and kills it if it doesn't exit soon.
+func (process *agentProcess) stop() {
+	if process.exited() {
+		return
+	}
+	pgid := -process.cmd.Process.Pid
+	_ = syscall.Kill(pgid, syscall.SIGINT)
+	select {
+	case <-process.done:
+	case <-time.After(time.Second * 10):
+		_ = syscall.Kill(pgid, syscall.SIGKILL)
+		<-process.done
+	}
+}
+
+// Stop stops the service.
+func (pa *ProcessAgents) Stop() error {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	for id, process := range pa.processes {
+		process.stop()
+		delete(pa.processes, id)
+	}
+	return nil
+}
+
+// Name returns the name of the service.
+func (pa *ProcessAgents) Name() string {
+	return "process-agents"
+}
@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessAgents(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	outPath := path.Join(dir, "port")
+	pa := NewProcessAgents(context.Background(), config.Config{FortaDir: dir})
+	defer pa.Stop()
+
+	r.NoError(ioutil.WriteFile(path.Join(dir, config.DefaultDevAgentsFileName), []byte(`
+agents:
+  - id: container-agent
+    image: agent:latest
+  - id: process-agent
+    command: [sh, -c, "echo $AGENT_GRPC_PORT > `+outPath+`; sleep 30"]
+    port: "50052"
+`), 0644))
+	pa.sync()
+	r.Len(pa.processes, 1)
+	process := pa.processes["process-agent"]
+
+	r.Eventually(func() bool {
+		b, _ := ioutil.ReadFile(outPath)
+		return strings.TrimSpace(string(b)) == "50052"
+	}, time.Second*5, time.Millisecond*50)
+
+	// unchanged: keeps running
+	pa.sync()
+	r.Equal(process, pa.processes["process-agent"])
+
+	// removed: stopped
+	r.NoError(ioutil.WriteFile(path.Join(dir, config.DefaultDevAgentsFileName), []byte("agents: []\n"), 0644))
+	pa.sync()
+	r.Empty(pa.processes)
+	r.True(process.exited())
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const releaseHealthCheckInterval = time.Second * 10
+
+// waitUntilHealthy checks the health of the node until the end of the health check duration
+// and returns the result of the last check.
+func (runner *Runner) waitUntilHealthy() bool {
+	deadline := time.After(time.Duration(runner.cfg.AutoUpdate.HealthCheckMinutes) * time.Minute)
+	ticker := time.NewTicker(releaseHealthCheckInterval)
+	defer ticker.Stop()
+
+	var healthy bool
+	for {
+		select {
+		case <-runner.ctx.Done():
+			return true // shutting down - not the fault of the release
+		case <-deadline:
+			return healthy
+		case <-ticker.C:
+			healthy = isReleaseHealthy(runner.checkHealth())
+		}
+	}
+}
+
+// isReleaseHealthy checks if the service containers are running and none of the services is failing.
+func isReleaseHealthy(reports health.Reports) bool {
+	required := map[string]bool{
+		fmt.Sprintf("forta.container.%s", config.DockerSupervisorContainerName): false,
+		fmt.Sprintf("forta.container.%s", config.DockerScannerContainerName):    false,
+	}
+	for _, report := range reports {
+		isContainer := strings.Count(report.Name, ".") == 2
+		isSummary := strings.HasSuffix(report.Name, ".summary")
+		if !isContainer && !isSummary && report.Name != "docker" {
+			continue
+		}
+		if report.Status == health.StatusDown || report.Status == health.StatusFailing {
+			return false
+		}
+		if _, ok := required[report.Name]; ok {
+			required[report.Name] = true
+		}
+	}
+	for _, found := range required {
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// rollback goes back to the previous release and rejects the failed release.
+func (runner *Runner) rollback(previousRefs, failedRefs store.ImageRefs) {
+	logger := log.WithFields(log.Fields{
+		"supervisor":         failedRefs.Supervisor,
+		"previousSupervisor": previousRefs.Supervisor,
+	})
+	logger.Error("node is not healthy after the update - rolling back")
+
+	if err := runner.rejectRelease(failedRefs); err != nil {
+		logger.WithError(err).Warn("failed to persist the rejected release")
+	}
+	runner.updateContainers(previousRefs)
+}
+
+func (runner *Runner) rejectedReleaseFilePath() string {
+	return path.Join(runner.cfg.FortaDir, config.DefaultRejectedReleaseFile)
+}
+
+// rejectRelease persists the rejected release so that it is not used after a restart.
+func (runner *Runner) rejectRelease(refs store.ImageRefs) error {
+	return ioutil.WriteFile(runner.rejectedReleaseFilePath(), []byte(refs.Supervisor), 0644)
+}
+
+func (runner *Runner) isRejectedRelease(refs store.ImageRefs) bool {
+	b, err := ioutil.ReadFile(runner.rejectedReleaseFilePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithError(err).Warn("failed to read the rejected release")
+		}
+		return false
+	}
+	return strings.TrimSpace(string(b)) == refs.Supervisor
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/clients"
+	mock_clients "github.com/forta-network/forta-node/clients/mocks"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReleaseHealthy(t *testing.T) {
+	r := require.New(t)
+
+	reports := health.Reports{
+		{Name: "forta.container.forta-supervisor", Status: health.StatusOK},
+		{Name: "forta.container.forta-supervisor.summary", Status: health.StatusOK},
+		{Name: "forta.container.forta-scanner", Status: health.StatusOK},
+		{Name: "forta.container.forta-scanner.summary", Status: health.StatusOK},
+		{Name: "forta.container.forta-scanner.event.checked.time", Status: health.StatusFailing},
+	}
+	r.True(isReleaseHealthy(reports))
+
+	reports[3].Status = health.StatusFailing
+	r.False(isReleaseHealthy(reports))
+
+	// the scanner is required
+	r.False(isReleaseHealthy(reports[:2]))
+
+	r.False(isReleaseHealthy(health.Reports{{Name: "docker", Status: health.StatusDown}}))
+}
+
+func TestApplyRelease_RollbackOnFailedHealthCheck(t *testing.T) {
+	r := require.New(t)
+
+	client := mock_clients.NewMockDockerClient(gomock.NewController(t))
+	runner := &Runner{
+		ctx:                  context.Background(),
+		cfg:                  config.Config{Development: true, FortaDir: t.TempDir()},
+		dockerClient:         client,
+		globalClient:         client,
+		currentUpdaterImg:    "updater-1",
+		currentSupervisorImg: "supervisor-1",
+		supervisorContainer:  &clients.DockerContainer{ID: "supervisor-1-id"},
+	}
+	previousRefs := store.ImageRefs{Supervisor: "supervisor-1", Updater: "updater-1"}
+	failedRefs := store.ImageRefs{Supervisor: "supervisor-2", Updater: "updater-1"}
+
+	client.EXPECT().TerminateContainer(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	client.EXPECT().WaitContainerExit(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	client.EXPECT().Prune(gomock.Any()).Return(nil).Times(2)
+	client.EXPECT().WaitContainerPrune(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	client.EXPECT().WaitContainerStart(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	// the failed release is started first and then the previous release is restored
+	gomock.InOrder(
+		client.EXPECT().EnsureLocalImage(gomock.Any(), "supervisor", "supervisor-2").Return(nil),
+		client.EXPECT().StartContainer(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, cfg clients.DockerContainerConfig) (*clients.DockerContainer, error) {
+				r.Equal("supervisor-2", cfg.Image)
+				return &clients.DockerContainer{ID: "supervisor-2-id"}, nil
+			}),
+		client.EXPECT().EnsureLocalImage(gomock.Any(), "supervisor", "supervisor-1").Return(nil),
+		client.EXPECT().StartContainer(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, cfg clients.DockerContainerConfig) (*clients.DockerContainer, error) {
+				r.Equal("supervisor-1", cfg.Image)
+				return &clients.DockerContainer{ID: "supervisor-1-new-id"}, nil
+			}),
+	)
+
+	var checked bool
+	runner.applyRelease(failedRefs, func() bool {
+		checked = true
+		return false
+	})
+	r.True(checked)
+	r.Equal(previousRefs.Supervisor, runner.getCurrentRefs().Supervisor)
+	r.True(runner.isRejectedRelease(failedRefs))
+
+	// the rejected release is not applied again
+	runner.applyRelease(failedRefs, func() bool {
+		r.FailNow("the rejected release should not be checked")
+		return false
+	})
+	r.Equal(previousRefs.Supervisor, runner.getCurrentRefs().Supervisor)
+}
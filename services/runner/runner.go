@@ -3,6 +3,7 @@ package runner
 import (
 	"context"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/ethereum"
+	"github.com/forta-network/forta-core-go/release"
 	"github.com/forta-network/forta-core-go/utils"
 	"github.com/forta-network/forta-node/clients"
 	"github.com/forta-network/forta-node/config"
@@ -30,6 +32,7 @@ type Runner struct {
 	supervisorContainer  *clients.DockerContainer
 	currentUpdaterImg    string
 	currentSupervisorImg string
+	currentReleaseInfo   *release.ReleaseInfo
 	containerMu          sync.RWMutex // protects above refs and containers
 
 	healthClient health.HealthClient
@@ -170,6 +173,7 @@ func (runner *Runner) startEmbeddedSupervisor() {
 		logger.WithError(err).Panic("error replacing supervisor")
 	} else {
 		runner.currentSupervisorImg = builtInRefs.Supervisor
+		runner.currentReleaseInfo = builtInRefs.ReleaseInfo
 	}
 }
 
@@ -182,7 +186,35 @@ func (runner *Runner) keepContainersUpToDate() {
 	}()
 
 	for latestRefs := range runner.imgStore.Latest() {
-		runner.updateContainers(latestRefs)
+		runner.applyRelease(latestRefs, runner.waitUntilHealthy)
+	}
+}
+
+// applyRelease updates the containers to the release and rolls back if the node does not become healthy.
+func (runner *Runner) applyRelease(latestRefs store.ImageRefs, waitUntilHealthy func() bool) {
+	if runner.isRejectedRelease(latestRefs) {
+		log.WithField("supervisor", latestRefs.Supervisor).Warn("skipping the release which was rolled back")
+		return
+	}
+	previousRefs := runner.getCurrentRefs()
+	runner.updateContainers(latestRefs)
+	// no rollback for the first release and the updater-only releases
+	if len(previousRefs.Supervisor) == 0 || previousRefs.Supervisor == latestRefs.Supervisor {
+		return
+	}
+	if waitUntilHealthy() {
+		return
+	}
+	runner.rollback(previousRefs, latestRefs)
+}
+
+func (runner *Runner) getCurrentRefs() store.ImageRefs {
+	runner.containerMu.RLock()
+	defer runner.containerMu.RUnlock()
+	return store.ImageRefs{
+		Supervisor:  runner.currentSupervisorImg,
+		Updater:     runner.currentUpdaterImg,
+		ReleaseInfo: runner.currentReleaseInfo,
 	}
 }
 
@@ -213,6 +245,7 @@ func (runner *Runner) updateContainers(latestRefs store.ImageRefs) {
 			logger.WithError(err).Panic("error replacing supervisor")
 		} else {
 			runner.currentSupervisorImg = latestRefs.Supervisor
+			runner.currentReleaseInfo = latestRefs.ReleaseInfo
 		}
 	} else {
 		log.Debug("same image - not replacing supervisor")
@@ -265,14 +298,19 @@ func (runner *Runner) startUpdater(logger *log.Entry, latestRefs store.ImageRefs
 		return err
 	}
 
+	env := map[string]string{
+		config.EnvDevelopment: strconv.FormatBool(runner.cfg.Development),
+		config.EnvReleaseInfo: latestRefs.ReleaseInfo.String(),
+	}
+	// the containers read the same config
+	for k, v := range config.GetEnvOverrides(os.Environ()) {
+		env[k] = v
+	}
 	uc, err := runner.dockerClient.StartContainer(runner.ctx, clients.DockerContainerConfig{
 		Name:  config.DockerUpdaterContainerName,
 		Image: updaterRef,
 		Cmd:   []string{config.DefaultFortaNodeBinaryPath, "updater"},
-		Env: map[string]string{
-			config.EnvDevelopment: strconv.FormatBool(runner.cfg.Development),
-			config.EnvReleaseInfo: latestRefs.ReleaseInfo.String(),
-		},
+		Env:   env,
 		Volumes: map[string]string{
 			runner.cfg.FortaDir: config.DefaultContainerFortaDirPath,
 		},
@@ -303,20 +341,41 @@ func (runner *Runner) startSupervisor(logger *log.Entry, latestRefs store.ImageR
 	if err != nil {
 		return err
 	}
-	sc, err := runner.dockerClient.StartContainer(runner.ctx, clients.DockerContainerConfig{
-		Name:  config.DockerSupervisorContainerName,
-		Image: supervisorRef,
-		Cmd:   []string{config.DefaultFortaNodeBinaryPath, "supervisor"},
-		Env: map[string]string{
-			// supervisor needs to know and mount the forta dir on the host os
-			config.EnvHostFortaDir: runner.cfg.FortaDir,
-			config.EnvReleaseInfo:  latestRefs.ReleaseInfo.String(),
-		},
-		Volumes: map[string]string{
-			// give access to host docker
-			"/var/run/docker.sock": "/var/run/docker.sock",
-			runner.cfg.FortaDir:    config.DefaultContainerFortaDirPath,
-		},
+	runtimeSocket := runner.cfg.ContainerRuntime.HostSocketPath()
+	env := map[string]string{
+		// supervisor needs to know and mount the forta dir on the host os
+		config.EnvHostFortaDir:      runner.cfg.FortaDir,
+		config.EnvHostRuntimeSocket: runtimeSocket,
+		config.EnvReleaseInfo:       latestRefs.ReleaseInfo.String(),
+	}
+	for k, v := range runner.cfg.ReplayEnv() {
+		env[k] = v
+	}
+	for k, v := range config.GetEnvOverrides(os.Environ()) {
+		env[k] = v
+	}
+	// give access to the container runtime on the host
+	volumes := runner.cfg.ContainerRuntime.Volumes(runtimeSocket)
+	volumes[runner.cfg.FortaDir] = config.DefaultContainerFortaDirPath
+	// only the supervisor can read the CA which issues the agent certificates
+	if runner.cfg.Security.AgentMTLS && !runner.cfg.Kubernetes.Enable {
+		volumes[config.DockerAgentCAVolumeName] = config.DefaultContainerAgentCAPath
+	}
+	// the supervisor manages the agents on kubernetes with the kubectl of the host
+	if runner.cfg.Kubernetes.Enable {
+		volumes[config.KubectlHostPath] = config.KubectlHostPath
+	}
+	networkIDs, err := runner.createSupervisorNetworks()
+	if err != nil {
+		logger.WithError(err).Errorf("failed to create the supervisor networks")
+		return err
+	}
+	supervisorCfg := clients.DockerContainerConfig{
+		Name:    config.DockerSupervisorContainerName,
+		Image:   supervisorRef,
+		Cmd:     []string{config.DefaultFortaNodeBinaryPath, "supervisor"},
+		Env:     env,
+		Volumes: volumes,
 		Ports: map[string]string{
 			"": config.DefaultHealthPort, // random host port
 		},
@@ -326,7 +385,12 @@ func (runner *Runner) startSupervisor(logger *log.Entry, latestRefs store.ImageR
 		DialHost:    true,
 		MaxLogSize:  runner.cfg.Log.MaxLogSize,
 		MaxLogFiles: runner.cfg.Log.MaxLogFiles,
-	})
+	}
+	if len(networkIDs) > 0 {
+		supervisorCfg.NetworkID = networkIDs[0]
+		supervisorCfg.LinkNetworkIDs = networkIDs[1:]
+	}
+	sc, err := runner.dockerClient.StartContainer(runner.ctx, supervisorCfg)
 	if err != nil {
 		logger.WithError(err).Errorf("failed to start the supervisor")
 		return err
@@ -376,3 +440,23 @@ func (runner *Runner) doKeepContainersAlive() error {
 
 	return nil
 }
+
+// createSupervisorNetworks creates the node networks which the supervisor needs to join when it is
+// created. This is only needed with containerd since it cannot attach the running containers to networks.
+func (runner *Runner) createSupervisorNetworks() ([]string, error) {
+	if runner.cfg.ContainerRuntime.Runtime != config.ContainerRuntimeContainerd {
+		return nil, nil
+	}
+	nodeNetworkID, err := runner.globalClient.CreatePublicNetwork(runner.ctx, config.DockerNetworkName)
+	if err != nil {
+		return nil, err
+	}
+	if runner.cfg.ExposeNats {
+		return []string{nodeNetworkID}, nil
+	}
+	natsNetworkID, err := runner.globalClient.CreateInternalNetwork(runner.ctx, config.DockerNatsContainerName)
+	if err != nil {
+		return nil, err
+	}
+	return []string{nodeNetworkID, natsNetworkID}, nil
+}
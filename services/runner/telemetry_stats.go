@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/healthutils"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// TelemetryStats are the anonymized operational stats of the node. They do not include the scanner
+// address, the agent IDs or the details of the health reports.
+type TelemetryStats struct {
+	InstallationID string  `json:"installationId"`
+	Version        string  `json:"version"`
+	ChainID        int     `json:"chainId"`
+	Containers     int     `json:"containers"`
+	Agents         int64   `json:"agents"`
+	FailedAgents   int64   `json:"failedAgents"`
+	ChainLag       int64   `json:"chainLag"`
+	Reports        int     `json:"reports"`
+	FailingReports int     `json:"failingReports"`
+	ErrorRate      float64 `json:"errorRate"`
+	Timestamp      string  `json:"timestamp"`
+}
+
+// TelemetryStatsReporter reports the stats of the node periodically if the operator opted in.
+type TelemetryStatsReporter struct {
+	ctx    context.Context
+	cfg    config.Config
+	client *http.Client
+
+	checkHealth func() health.Reports
+}
+
+// NewTelemetryStatsReporter creates a new reporter which collects the stats from the runner containers.
+func NewTelemetryStatsReporter(ctx context.Context, cfg config.Config, runner *Runner) *TelemetryStatsReporter {
+	return &TelemetryStatsReporter{
+		ctx:         ctx,
+		cfg:         cfg,
+		client:      &http.Client{Timeout: time.Second * 30},
+		checkHealth: runner.checkHealth,
+	}
+}
+
+// Start starts the service.
+func (reporter *TelemetryStatsReporter) Start() error {
+	if !reporter.cfg.TelemetryConfig.Stats.Enable || reporter.cfg.PrivateModeConfig.Enable {
+		return nil
+	}
+	installationID, err := readInstallationID(reporter.cfg.FortaDir)
+	if err != nil {
+		return fmt.Errorf("failed to read the telemetry installation id: %v", err)
+	}
+	go reporter.reportStats(installationID)
+	log.WithField("url", reporter.cfg.TelemetryConfig.Stats.URL).Info("reporting the telemetry stats")
+	return nil
+}
+
+// readInstallationID reads the random ID which identifies the stats of this node and creates it if it
+// does not exist.
+func readInstallationID(fortaDir string) (string, error) {
+	filePath := path.Join(fortaDir, config.DefaultTelemetryIDFile)
+	b, err := ioutil.ReadFile(filePath)
+	if err == nil && len(strings.TrimSpace(string(b))) > 0 {
+		return strings.TrimSpace(string(b)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	installationID := uuid.Must(uuid.NewRandom()).String()
+	if err := ioutil.WriteFile(filePath, []byte(installationID), 0644); err != nil {
+		return "", err
+	}
+	return installationID, nil
+}
+
+func (reporter *TelemetryStatsReporter) reportStats(installationID string) {
+	ticker := time.NewTicker(time.Duration(reporter.cfg.TelemetryConfig.Stats.IntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-reporter.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		stats := collectTelemetryStats(installationID, reporter.cfg.ChainID, reporter.checkHealth())
+		if err := reporter.sendStats(stats); err != nil {
+			log.WithError(err).Warn("failed to send the telemetry stats")
+		}
+	}
+}
+
+func collectTelemetryStats(installationID string, chainID int, reports health.Reports) *TelemetryStats {
+	stats := &TelemetryStats{
+		InstallationID: installationID,
+		Version:        config.Version,
+		ChainID:        chainID,
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+	}
+	if len(stats.Version) == 0 {
+		stats.Version = "dev"
+	}
+	for _, report := range reports {
+		if report.Status == health.StatusInfo {
+			continue
+		}
+		container := strings.TrimPrefix(report.Name, "forta.container.")
+		if container != report.Name && !strings.Contains(container, ".") && report.Status == health.StatusOK {
+			stats.Containers++
+		}
+		stats.Reports++
+		if report.Status == health.StatusDown || report.Status == health.StatusFailing || report.Status == health.StatusLagging {
+			stats.FailingReports++
+		}
+	}
+	if stats.Reports > 0 {
+		stats.ErrorRate = float64(stats.FailingReports) / float64(stats.Reports)
+	}
+	for _, value := range healthutils.ReportValues(reports, "agent-pool", "agents.total") {
+		stats.Agents += value
+	}
+	for _, value := range healthutils.ReportValues(reports, "agent-pool", "agents.failed") {
+		stats.FailedAgents += value
+	}
+	for _, value := range healthutils.ReportValues(reports, "tx-stream", "chain.lag") {
+		if value > stats.ChainLag {
+			stats.ChainLag = value
+		}
+	}
+	return stats
+}
+
+func (reporter *TelemetryStatsReporter) sendStats(stats *TelemetryStats) error {
+	b, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(reporter.ctx, http.MethodPost, reporter.cfg.TelemetryConfig.Stats.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := reporter.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Stop stops the service.
+func (reporter *TelemetryStatsReporter) Stop() error {
+	return nil
+}
+
+// Name returns the name of the service.
+func (reporter *TelemetryStatsReporter) Name() string {
+	return "telemetry-stats"
+}
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadInstallationID(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	installationID, err := readInstallationID(dir)
+	r.NoError(err)
+	r.NotEmpty(installationID)
+
+	sameID, err := readInstallationID(dir)
+	r.NoError(err)
+	r.Equal(installationID, sameID)
+}
+
+func TestTelemetryStats(t *testing.T) {
+	r := require.New(t)
+
+	reports := health.Reports{
+		{Name: "forta.container.forta-scanner", Status: health.StatusOK, Details: "running"},
+		{Name: "forta.container.forta-supervisor", Status: health.StatusDown, Details: "exited"},
+		{Name: "forta.container.forta-scanner.service.tx-stream.chain.lag", Status: health.StatusInfo, Details: "3"},
+		{Name: "forta.container.forta-scanner.service.agent-pool.agents.total", Status: health.StatusOK, Details: "5"},
+		{Name: "forta.container.forta-scanner.service.agent-pool.agents.failed", Status: health.StatusInfo, Details: "1"},
+		{Name: "forta.container.forta-scanner.service.publisher.event.batch-publish.error", Status: health.StatusOK},
+	}
+
+	var received TelemetryStats
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.NoError(json.NewDecoder(req.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	cfg := config.Config{}
+	cfg.TelemetryConfig.Stats.URL = server.URL
+	reporter := &TelemetryStatsReporter{ctx: context.Background(), cfg: cfg, client: server.Client()}
+	r.NoError(reporter.sendStats(collectTelemetryStats("installation-id", 137, reports)))
+
+	r.Equal("installation-id", received.InstallationID)
+	r.Equal(137, received.ChainID)
+	r.Equal(1, received.Containers)
+	r.Equal(int64(5), received.Agents)
+	r.Equal(int64(1), received.FailedAgents)
+	r.Equal(int64(3), received.ChainLag)
+	r.Equal(4, received.Reports)
+	r.Equal(1, received.FailingReports)
+	r.Equal(0.25, received.ErrorRate)
+}
@@ -2,7 +2,11 @@ package agentpool
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,31 +21,72 @@ import (
 	"github.com/forta-network/forta-node/config"
 	"github.com/forta-network/forta-node/services/scanner"
 	"github.com/forta-network/forta-node/services/scanner/agentpool/poolagent"
+	"github.com/golang/protobuf/proto"
 	log "github.com/sirupsen/logrus"
 )
 
+// agentActionsBufferSize is how many acked agent actions can wait for the supervisor.
+const agentActionsBufferSize = 100
+
+// agentRestartTimeout is how long a restarted agent waits for its container to stop.
+const agentRestartTimeout = 2 * time.Minute
+
+// agentRestart is an agent which is run with the new config after its container stops.
+type agentRestart struct {
+	cfg    config.AgentConfig
+	cancel context.CancelFunc
+}
+
+// agentAction is an agent action which waits to be published.
+type agentAction struct {
+	subject string
+	agents  []config.AgentConfig
+}
+
+// agentClientTLSDir is where the supervisor copies the scanner client files to.
+const agentClientTLSDir = "/"
+
 // AgentPool maintains the pool of agents that the scanner should
 // interact with.
 type AgentPool struct {
-	ctx          context.Context
-	agents       []*poolagent.Agent
-	txResults    chan *scanner.TxResult
-	blockResults chan *scanner.BlockResult
-	msgClient    clients.MessageClient
-	dialer       func(config.AgentConfig) (clients.AgentClient, error)
-	mu           sync.RWMutex
+	ctx              context.Context
+	chainID          int64
+	agents           []*poolagent.Agent
+	txResults        chan *scanner.TxResult
+	pendingTxResults chan *scanner.TxResult
+	blockResults     chan *scanner.BlockResult
+	msgClient        clients.MessageClient
+	ackCfg           config.AckedPublishConfig
+	actions          chan agentAction
+	dialer           func(config.AgentConfig) (clients.AgentClient, error)
+	restarts         map[string]*agentRestart      // container name -> pending restart
+	failed           map[string]config.AgentConfig // container name -> failed config
+	mu               sync.RWMutex
 }
 
 // NewAgentPool creates a new agent pool.
-func NewAgentPool(ctx context.Context, cfg config.ScannerConfig, msgClient clients.MessageClient) *AgentPool {
+func NewAgentPool(ctx context.Context, cfg config.Config, msgClient clients.MessageClient) *AgentPool {
 	agentPool := &AgentPool{
-		ctx:          ctx,
-		txResults:    make(chan *scanner.TxResult),
-		blockResults: make(chan *scanner.BlockResult),
-		msgClient:    msgClient,
+		ctx:              ctx,
+		chainID:          int64(cfg.ChainID),
+		txResults:        make(chan *scanner.TxResult),
+		pendingTxResults: make(chan *scanner.TxResult),
+		blockResults:     make(chan *scanner.BlockResult),
+		msgClient:        msgClient,
+		ackCfg:           cfg.Messaging.Ack,
+		actions:          make(chan agentAction, agentActionsBufferSize),
+		restarts:         make(map[string]*agentRestart),
+		failed:           make(map[string]config.AgentConfig),
 		dialer: func(ac config.AgentConfig) (clients.AgentClient, error) {
 			client := agentgrpc.NewClient()
-			if err := client.Dial(ac); err != nil {
+			if config.UseAgentMTLS(cfg, ac) {
+				tlsConfig, err := agentTLSConfig(ac)
+				if err != nil {
+					return nil, err
+				}
+				client.WithTLS(tlsConfig)
+			}
+			if err := client.DialHost(ac, agentHost(cfg, ac)); err != nil {
 				return nil, err
 			}
 			return client, nil
@@ -50,9 +95,29 @@ func NewAgentPool(ctx context.Context, cfg config.ScannerConfig, msgClient clien
 
 	agentPool.registerMessageHandlers()
 	go agentPool.logAgentChanBuffersLoop()
+	go agentPool.publishActionsLoop()
 	return agentPool
 }
 
+// agentTLSConfig returns the client TLS config with the certificate which the supervisor issued to the
+// scanner and copied to the root of the container.
+func agentTLSConfig(ac config.AgentConfig) (*tls.Config, error) {
+	tlsConfig, err := agentgrpc.LoadClientTLSConfig(agentClientTLSDir, ac.ContainerName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the agent client certificate: %v", err)
+	}
+	return tlsConfig, nil
+}
+
+// agentHost returns the host which the agent is dialed at. The dev process reaches the process agents
+// on the same host.
+func agentHost(cfg config.Config, ac config.AgentConfig) string {
+	if cfg.DevProcess && ac.IsProcess() {
+		return "localhost"
+	}
+	return cfg.Kubernetes.AgentHost(ac)
+}
+
 // Health implements health.Reporter interface.
 func (ap *AgentPool) Health() health.Reports {
 	ap.mu.RLock()
@@ -80,6 +145,11 @@ func (ap *AgentPool) Health() health.Reports {
 			Status:  health.StatusInfo,
 			Details: strconv.Itoa(fullCount),
 		},
+		&health.Report{
+			Name:    "agents.failed",
+			Status:  health.StatusInfo,
+			Details: strconv.Itoa(len(ap.failed)),
+		},
 	}
 }
 
@@ -125,7 +195,9 @@ func (ap *AgentPool) SendEvaluateTxRequest(req *protocol.EvaluateTxRequest) {
 	}
 	var metricsList []*protocol.AgentMetric
 	for _, agent := range agents {
-		if !agent.IsReady() || !agent.ShouldProcessBlock(req.Event.Block.BlockNumber) {
+		// the agents which subscribe to logs receive them from the log requests
+		if !agent.IsReady() || agent.Config().SubscribesToLogs() || !ap.supportsChain(agent, req.Event.Network.GetChainId()) ||
+			!agent.ShouldProcessBlock(req.Event.Block.BlockNumber) {
 			continue
 		}
 		lg.WithFields(log.Fields{
@@ -158,11 +230,269 @@ func (ap *AgentPool) SendEvaluateTxRequest(req *protocol.EvaluateTxRequest) {
 	}).Debug("Finished SendEvaluateTxRequest")
 }
 
+// requestChainID returns the chain ID of a request. The requests without a chain ID are
+// from the main chain and the requests with an invalid chain ID are from no chain.
+func (ap *AgentPool) requestChainID(chainIDHex string) (int64, bool) {
+	if len(chainIDHex) == 0 {
+		return ap.chainID, true
+	}
+	chainID, err := hexutil.DecodeUint64(chainIDHex)
+	if err != nil {
+		return 0, false
+	}
+	return int64(chainID), true
+}
+
+// supportsChain tells if the agent should process a request from the chain. The agents which
+// don't declare any chains process only the requests from the main chain.
+func (ap *AgentPool) supportsChain(agent *poolagent.Agent, chainIDHex string) bool {
+	chainID, ok := ap.requestChainID(chainIDHex)
+	return ok && agent.Config().SupportsChain(chainID, ap.chainID)
+}
+
 // TxResults returns the receive-only tx results channel.
 func (ap *AgentPool) TxResults() <-chan *scanner.TxResult {
 	return ap.txResults
 }
 
+// SendEvaluateLogRequest sends the logs of a transaction to the agents which subscribe to logs.
+// Each agent receives only the logs which match its filters and the results are sent
+// to the tx results channel.
+func (ap *AgentPool) SendEvaluateLogRequest(req *protocol.EvaluateTxRequest) {
+	startTime := time.Now()
+	lg := log.WithFields(log.Fields{
+		"tx":        req.Event.Transaction.Hash,
+		"component": "pool",
+	})
+	lg.Debug("SendEvaluateLogRequest")
+
+	ap.mu.RLock()
+	agents := ap.agents
+	ap.mu.RUnlock()
+
+	var metricsList []*protocol.AgentMetric
+	for _, agent := range agents {
+		agentCfg := agent.Config()
+		if !agent.IsReady() || !agentCfg.SubscribesToLogs() || !ap.supportsChain(agent, req.Event.Network.GetChainId()) ||
+			!agent.ShouldProcessBlock(req.Event.Block.BlockNumber) {
+			continue
+		}
+
+		agentReq := filterLogRequest(agentCfg, req)
+		if agentReq == nil {
+			continue
+		}
+		encoded, err := agentgrpc.EncodeMessage(agentReq)
+		if err != nil {
+			lg.WithError(err).Error("failed to encode message")
+			continue
+		}
+
+		// unblock req send and discard agent if agent is closed
+		select {
+		case <-agent.Closed():
+			ap.discardAgent(agent)
+		case agent.TxRequestCh() <- &poolagent.TxRequest{
+			Original: agentReq,
+			Encoded:  encoded,
+		}:
+		default: // do not try to send if the buffer is full
+			lg.WithField("agent", agentCfg.ID).Debug("agent tx request buffer is full - skipping")
+			metricsList = append(metricsList, metrics.CreateAgentMetric(agentCfg.ID, metrics.MetricTxDrop, 1))
+		}
+	}
+	metrics.SendAgentMetrics(ap.msgClient, metricsList)
+
+	lg.WithFields(log.Fields{
+		"duration": time.Since(startTime),
+	}).Debug("Finished SendEvaluateLogRequest")
+}
+
+// filterLogRequest returns a copy of the request which contains only the logs that match the
+// agent filters. It returns nil if none of the logs match.
+func filterLogRequest(agentCfg config.AgentConfig, req *protocol.EvaluateTxRequest) *protocol.EvaluateTxRequest {
+	var logs []*protocol.TransactionEvent_Log
+	for _, l := range req.Event.Logs {
+		if agentCfg.MatchesLog(l.Address, l.Topics) {
+			logs = append(logs, l)
+		}
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+	if len(logs) == len(req.Event.Logs) {
+		return req
+	}
+	agentReq := proto.Clone(req).(*protocol.EvaluateTxRequest)
+	agentReq.Event.Logs = logs
+	if agentReq.Event.Receipt != nil {
+		agentReq.Event.Receipt.Logs = logs
+	}
+	return agentReq
+}
+
+// LogFilters returns the log filters of the agents which subscribe to the logs of the chain.
+func (ap *AgentPool) LogFilters(chainID int64) []config.LogFilter {
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+
+	var filters []config.LogFilter
+	for _, agent := range ap.agents {
+		if agent.Config().SupportsChain(chainID, ap.chainID) {
+			filters = append(filters, agent.Config().LogFilters...)
+		}
+	}
+	return filters
+}
+
+// SendEvaluateUserOpRequest sends the request of a user operation to the agents which subscribe to
+// user operations. The results are sent to the tx results channel.
+func (ap *AgentPool) SendEvaluateUserOpRequest(req *protocol.EvaluateTxRequest) {
+	startTime := time.Now()
+	lg := log.WithFields(log.Fields{
+		"tx":        req.Event.Transaction.Hash,
+		"component": "pool",
+	})
+	lg.Debug("SendEvaluateUserOpRequest")
+
+	ap.mu.RLock()
+	agents := ap.agents
+	ap.mu.RUnlock()
+
+	encoded, err := agentgrpc.EncodeMessage(req)
+	if err != nil {
+		lg.WithError(err).Error("failed to encode message")
+		return
+	}
+	var metricsList []*protocol.AgentMetric
+	for _, agent := range agents {
+		if !agent.IsReady() || !agent.Config().UserOperations || !ap.supportsChain(agent, req.Event.Network.GetChainId()) ||
+			!agent.ShouldProcessBlock(req.Event.Block.BlockNumber) {
+			continue
+		}
+
+		// unblock req send and discard agent if agent is closed
+		select {
+		case <-agent.Closed():
+			ap.discardAgent(agent)
+		case agent.TxRequestCh() <- &poolagent.TxRequest{
+			Original: req,
+			Encoded:  encoded,
+		}:
+		default: // do not try to send if the buffer is full
+			lg.WithField("agent", agent.Config().ID).Debug("agent tx request buffer is full - skipping")
+			metricsList = append(metricsList, metrics.CreateAgentMetric(agent.Config().ID, metrics.MetricTxDrop, 1))
+		}
+	}
+	metrics.SendAgentMetrics(ap.msgClient, metricsList)
+
+	lg.WithFields(log.Fields{
+		"duration": time.Since(startTime),
+	}).Debug("Finished SendEvaluateUserOpRequest")
+}
+
+// HasUserOpAgents tells if any of the agents subscribes to user operations.
+func (ap *AgentPool) HasUserOpAgents() bool {
+	ap.mu.RLock()
+	defer ap.mu.RUnlock()
+
+	for _, agent := range ap.agents {
+		if agent.Config().UserOperations {
+			return true
+		}
+	}
+	return false
+}
+
+// SendEvaluateCanaryRequest sends the canary tx request only to the agent. It tells if the agent was
+// ready to receive it.
+func (ap *AgentPool) SendEvaluateCanaryRequest(agentID string, req *protocol.EvaluateTxRequest) bool {
+	lg := log.WithFields(log.Fields{
+		"agent":     agentID,
+		"component": "pool",
+	})
+
+	ap.mu.RLock()
+	agents := ap.agents
+	ap.mu.RUnlock()
+
+	encoded, err := agentgrpc.EncodeMessage(req)
+	if err != nil {
+		lg.WithError(err).Error("failed to encode the canary request")
+		return false
+	}
+	for _, agent := range agents {
+		if agent.Config().ID != agentID || !agent.IsReady() {
+			continue
+		}
+		select {
+		case <-agent.Closed():
+			ap.discardAgent(agent)
+		case agent.TxRequestCh() <- &poolagent.TxRequest{
+			Original: req,
+			Encoded:  encoded,
+		}:
+			return true
+		default: // the canary is sent again later
+			lg.Debug("agent tx request buffer is full - skipping the canary")
+		}
+		return false
+	}
+	return false
+}
+
+// SendEvaluatePendingTxRequest sends the pending tx request to all of the active agents which
+// opted in to receive pending transactions and should be processing the latest block.
+func (ap *AgentPool) SendEvaluatePendingTxRequest(req *protocol.EvaluateTxRequest, latestBlock uint64) {
+	startTime := time.Now()
+	lg := log.WithFields(log.Fields{
+		"tx":        req.Event.Transaction.Hash,
+		"component": "pool",
+	})
+	lg.Debug("SendEvaluatePendingTxRequest")
+
+	ap.mu.RLock()
+	agents := ap.agents
+	ap.mu.RUnlock()
+
+	encoded, err := agentgrpc.EncodeMessage(req)
+	if err != nil {
+		lg.WithError(err).Error("failed to encode message")
+		return
+	}
+	latestBlockHex := hexutil.EncodeUint64(latestBlock)
+	var metricsList []*protocol.AgentMetric
+	for _, agent := range agents {
+		if !agent.IsReady() || !agent.Config().PendingTransactions || !ap.supportsChain(agent, req.Event.Network.GetChainId()) ||
+			!agent.ShouldProcessBlock(latestBlockHex) {
+			continue
+		}
+
+		// unblock req send and discard agent if agent is closed
+		select {
+		case <-agent.Closed():
+			ap.discardAgent(agent)
+		case agent.PendingTxRequestCh() <- &poolagent.TxRequest{
+			Original: req,
+			Encoded:  encoded,
+		}:
+		default: // do not try to send if the buffer is full
+			lg.WithField("agent", agent.Config().ID).Debug("agent pending tx request buffer is full - skipping")
+			metricsList = append(metricsList, metrics.CreateAgentMetric(agent.Config().ID, metrics.MetricPendingTxDrop, 1))
+		}
+	}
+	metrics.SendAgentMetrics(ap.msgClient, metricsList)
+
+	lg.WithFields(log.Fields{
+		"duration": time.Since(startTime),
+	}).Debug("Finished SendEvaluatePendingTxRequest")
+}
+
+// PendingTxResults returns the receive-only pending tx results channel.
+func (ap *AgentPool) PendingTxResults() <-chan *scanner.TxResult {
+	return ap.pendingTxResults
+}
+
 // SendEvaluateBlockRequest sends the request to all of the active agents which
 // should be processing the block.
 func (ap *AgentPool) SendEvaluateBlockRequest(req *protocol.EvaluateBlockRequest) {
@@ -184,7 +514,7 @@ func (ap *AgentPool) SendEvaluateBlockRequest(req *protocol.EvaluateBlockRequest
 
 	var metricsList []*protocol.AgentMetric
 	for _, agent := range agents {
-		if !agent.IsReady() || !agent.ShouldProcessBlock(req.Event.BlockNumber) {
+		if !agent.IsReady() || !ap.supportsChain(agent, req.Event.Network.GetChainId()) || !agent.ShouldProcessBlock(req.Event.BlockNumber) {
 			continue
 		}
 
@@ -212,7 +542,9 @@ func (ap *AgentPool) SendEvaluateBlockRequest(req *protocol.EvaluateBlockRequest
 	}
 
 	blockNumber, _ := hexutil.DecodeUint64(req.Event.BlockNumber)
+	chainID, _ := ap.requestChainID(req.Event.Network.GetChainId())
 	ap.msgClient.Publish(messaging.SubjectScannerBlock, &messaging.ScannerPayload{
+		ChainID:          uint64(chainID),
 		LatestBlockInput: blockNumber,
 	})
 
@@ -245,6 +577,16 @@ func (ap *AgentPool) BlockResults() <-chan *scanner.BlockResult {
 }
 
 func (ap *AgentPool) handleAgentVersionsUpdate(payload messaging.AgentPayload) error {
+	agentsToRun, agentsToStop := ap.updateAgentVersions(payload)
+	// the acked publishes wait for the supervisor so the pool should not be locked
+	ap.publishAction(messaging.SubjectAgentsActionRun, agentsToRun)
+	ap.publishAction(messaging.SubjectAgentsActionStop, agentsToStop)
+	return nil
+}
+
+// updateAgentVersions updates the pool with the latest agent versions and returns the agents which should
+// be run and stopped.
+func (ap *AgentPool) updateAgentVersions(payload messaging.AgentPayload) ([]config.AgentConfig, []config.AgentConfig) {
 	ap.mu.Lock()
 	defer ap.mu.Unlock()
 
@@ -258,12 +600,24 @@ func (ap *AgentPool) handleAgentVersionsUpdate(payload messaging.AgentPayload) e
 	// and send a "run" message.
 	var agentsToRun []config.AgentConfig
 	for _, agentCfg := range latestVersions {
+		// the agent runs with the latest config after the restart
+		if restart, ok := ap.restarts[agentCfg.ContainerName()]; ok {
+			restart.cfg = agentCfg
+			continue
+		}
+		// the failed agents are run again only with a new config
+		if failedCfg, ok := ap.failed[agentCfg.ContainerName()]; ok {
+			if !agentConfigChanged(failedCfg, agentCfg) {
+				continue
+			}
+			delete(ap.failed, agentCfg.ContainerName())
+		}
 		var found bool
 		for _, agent := range ap.agents {
 			found = found || (agent.Config().ContainerName() == agentCfg.ContainerName())
 		}
 		if !found {
-			newAgents = append(newAgents, poolagent.New(ap.ctx, agentCfg, ap.msgClient, ap.txResults, ap.blockResults))
+			newAgents = append(newAgents, ap.newAgent(agentCfg))
 			agentsToRun = append(agentsToRun, agentCfg)
 			log.WithField("agent", agentCfg.ID).Info("will trigger start")
 		}
@@ -281,23 +635,140 @@ func (ap *AgentPool) handleAgentVersionsUpdate(payload messaging.AgentPayload) e
 				break
 			}
 		}
-		if !found {
+		switch {
+		case !found && ap.isBeingReplaced(agent, latestVersions):
+			// keep processing with the previous version until the new version is attached
+			newAgents = append(newAgents, agent)
+		case !found:
 			agent.Close()
 			agentsToStop = append(agentsToStop, agent.Config())
 			log.WithField("agent", agent.Config().ID).WithField("image", agent.Config().Image).Info("will trigger stop")
-		} else {
+		case agentConfigChanged(agent.Config(), agentCfg):
+			// stop the container first and run it again with the new config after it stops
+			agent.Close()
+			agentsToStop = append(agentsToStop, agent.Config())
+			ap.scheduleRestart(agentCfg)
+			log.WithField("agent", agent.Config().ID).WithField("image", agentCfg.Image).Info("will trigger restart")
+		default:
 			newAgents = append(newAgents, agent)
 		}
 	}
 
+	// Forget the pending restarts of the agents which were removed.
+	for containerName, restart := range ap.restarts {
+		var found bool
+		for _, latestCfg := range latestVersions {
+			found = found || latestCfg.ContainerName() == containerName
+		}
+		if !found {
+			restart.cancel()
+			delete(ap.restarts, containerName)
+			log.WithField("agent", restart.cfg.ID).Info("cancelled restart")
+		}
+	}
+	for containerName := range ap.failed {
+		var found bool
+		for _, latestCfg := range latestVersions {
+			found = found || latestCfg.ContainerName() == containerName
+		}
+		if !found {
+			delete(ap.failed, containerName)
+		}
+	}
+
 	ap.agents = newAgents
-	if len(agentsToRun) > 0 {
-		ap.msgClient.Publish(messaging.SubjectAgentsActionRun, agentsToRun)
+	return agentsToRun, agentsToStop
+}
+
+// scheduleRestart runs the agent with the new config after its container stops. The agent is run
+// anyway if the stopped status does not arrive before the restart timeout.
+func (ap *AgentPool) scheduleRestart(agentCfg config.AgentConfig) {
+	ctx, cancel := context.WithTimeout(ap.ctx, agentRestartTimeout)
+	restart := &agentRestart{cfg: agentCfg, cancel: cancel}
+	ap.restarts[agentCfg.ContainerName()] = restart
+	go ap.waitRestart(ctx, agentCfg.ContainerName(), restart)
+}
+
+func (ap *AgentPool) waitRestart(ctx context.Context, containerName string, restart *agentRestart) {
+	<-ctx.Done()
+	if ctx.Err() != context.DeadlineExceeded {
+		return
 	}
-	if len(agentsToStop) > 0 {
-		ap.msgClient.Publish(messaging.SubjectAgentsActionStop, agentsToStop)
+	ap.publishAction(messaging.SubjectAgentsActionRun, ap.expireRestart(containerName, restart))
+}
+
+// expireRestart returns the config to run if the restart is still pending after the timeout.
+func (ap *AgentPool) expireRestart(containerName string, restart *agentRestart) []config.AgentConfig {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	if ap.restarts[containerName] != restart {
+		return nil
 	}
-	return nil
+	delete(ap.restarts, containerName)
+	ap.agents = append(ap.agents, ap.newAgent(restart.cfg))
+	log.WithField("agent", restart.cfg.ID).WithField("image", restart.cfg.Image).Warn("agent did not stop in time - will trigger start after restart")
+	return []config.AgentConfig{restart.cfg}
+}
+
+// publishAction publishes the agent action. The acked actions are published by the actions loop, so that
+// the pool acks the latest versions without waiting for the supervisor to handle the actions.
+func (ap *AgentPool) publishAction(subject string, agents []config.AgentConfig) {
+	if len(agents) == 0 {
+		return
+	}
+	if ap.ackCfg.Enable && ap.actions != nil {
+		ap.actions <- agentAction{subject: subject, agents: agents}
+		return
+	}
+	messaging.PublishCritical(ap.msgClient, ap.ackCfg, subject, agents) // plain publish
+}
+
+// publishActionsLoop publishes the acked actions in order. The supervisor acks each action after
+// handling it.
+func (ap *AgentPool) publishActionsLoop() {
+	for {
+		select {
+		case <-ap.ctx.Done():
+			return
+		case action := <-ap.actions:
+			if err := messaging.PublishCritical(ap.msgClient, ap.ackCfg, action.subject, action.agents); err != nil {
+				log.WithError(err).WithField("subject", action.subject).Warn("agent action was not acked")
+			}
+		}
+	}
+}
+
+// isBeingReplaced tells if the ready agent should keep running until the new version of it is
+// attached. The new versions of the registry agents run in new containers, so the previous version
+// can be stopped after the switch without a detection gap. The previous version keeps running
+// also if the new version fails.
+func (ap *AgentPool) isBeingReplaced(agent *poolagent.Agent, latestVersions messaging.AgentPayload) bool {
+	if !agent.IsReady() {
+		return false
+	}
+	for _, latestCfg := range latestVersions {
+		if strings.EqualFold(latestCfg.ID, agent.Config().ID) && latestCfg.ContainerName() != agent.Config().ContainerName() {
+			return true
+		}
+	}
+	return false
+}
+
+func (ap *AgentPool) newAgent(agentCfg config.AgentConfig) *poolagent.Agent {
+	return poolagent.New(ap.ctx, agentCfg, ap.msgClient, poolagent.Results{
+		TxResults:        ap.txResults,
+		PendingTxResults: ap.pendingTxResults,
+		BlockResults:     ap.blockResults,
+	})
+}
+
+// agentConfigChanged tells if the agent must be restarted to apply the latest config. The pools
+// only tell why the agent is run.
+func agentConfigChanged(current, latest config.AgentConfig) bool {
+	current.Pools = nil
+	latest.Pools = nil
+	return !reflect.DeepEqual(current, latest)
 }
 
 func (ap *AgentPool) handleStatusRunning(payload messaging.AgentPayload) error {
@@ -306,9 +777,14 @@ func (ap *AgentPool) handleStatusRunning(payload messaging.AgentPayload) error {
 	var agentsToStop []config.AgentConfig
 	var agentsReady []config.AgentConfig
 
+	ap.mu.RLock()
+	agents := ap.agents
+	ap.mu.RUnlock()
+
 	for _, agentCfg := range payload {
-		for _, agent := range ap.agents {
+		for _, agent := range agents {
 			if agent.Config().ContainerName() == agentCfg.ContainerName() {
+				// the agent is not ready before the connection is established
 				c, err := ap.dialer(agent.Config())
 				if err != nil {
 					log.WithField("agent", agent.Config().ID).WithError(err).Error("handleStatusRunning: error while dialing")
@@ -316,8 +792,7 @@ func (ap *AgentPool) handleStatusRunning(payload messaging.AgentPayload) error {
 					continue
 				}
 				agent.SetClient(c)
-				agent.SetReady()
-				agent.StartProcessing()
+				agentsToStop = append(agentsToStop, ap.attachAgent(agent)...)
 				log.WithField("agent", agent.Config().ID).WithField("image", agent.Config().Image).Info("attached")
 				agentsReady = append(agentsReady, agent.Config())
 			}
@@ -326,13 +801,42 @@ func (ap *AgentPool) handleStatusRunning(payload messaging.AgentPayload) error {
 	if len(agentsReady) > 0 {
 		ap.msgClient.Publish(messaging.SubjectAgentsStatusAttached, agentsReady)
 	}
-	if len(agentsToStop) > 0 {
-		ap.msgClient.Publish(messaging.SubjectAgentsActionStop, agentsToStop)
-	}
+	ap.publishAction(messaging.SubjectAgentsActionStop, agentsToStop)
 	return nil
 }
 
+// attachAgent marks the agent ready and detaches the previous versions of it at the same time.
+// It returns the configs of the previous versions which should be stopped.
+func (ap *AgentPool) attachAgent(attached *poolagent.Agent) (replaced []config.AgentConfig) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	var newAgents []*poolagent.Agent
+	for _, agent := range ap.agents {
+		if agent != attached && strings.EqualFold(agent.Config().ID, attached.Config().ID) &&
+			agent.Config().ContainerName() != attached.Config().ContainerName() {
+			agent.Close()
+			replaced = append(replaced, agent.Config())
+			log.WithField("agent", agent.Config().ID).WithField("image", agent.Config().Image).Info("replaced")
+			continue
+		}
+		newAgents = append(newAgents, agent)
+	}
+	ap.agents = newAgents
+
+	attached.SetReady()
+	attached.StartProcessing()
+	return
+}
+
 func (ap *AgentPool) handleStatusStopped(payload messaging.AgentPayload) error {
+	ap.publishAction(messaging.SubjectAgentsActionRun, ap.removeStoppedAgents(payload))
+	return nil
+}
+
+// removeStoppedAgents removes the stopped agents from the pool and returns the restarted agents which
+// should be run with the new config.
+func (ap *AgentPool) removeStoppedAgents(payload messaging.AgentPayload) []config.AgentConfig {
 	ap.mu.Lock()
 	defer ap.mu.Unlock()
 
@@ -353,12 +857,43 @@ func (ap *AgentPool) handleStatusStopped(payload messaging.AgentPayload) error {
 			newAgents = append(newAgents, agent)
 		}
 	}
+
+	// Run the restarted agents with the new config.
+	var agentsToRun []config.AgentConfig
+	for _, agentCfg := range payload {
+		restart, ok := ap.restarts[agentCfg.ContainerName()]
+		if !ok {
+			continue
+		}
+		restart.cancel()
+		delete(ap.restarts, agentCfg.ContainerName())
+		restartCfg := restart.cfg
+		newAgents = append(newAgents, ap.newAgent(restartCfg))
+		agentsToRun = append(agentsToRun, restartCfg)
+		log.WithField("agent", restartCfg.ID).WithField("image", restartCfg.Image).Info("will trigger start after restart")
+	}
 	ap.agents = newAgents
+	return agentsToRun
+}
+
+// handleStatusFailed remembers the failed agents so that they are not run again with the same
+// config. The supervisor stops them and publishes the stopped status as well.
+func (ap *AgentPool) handleStatusFailed(payload messaging.AgentFailedPayload) error {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	log.WithFields(log.Fields{
+		"agent":  payload.Agent.ID,
+		"image":  payload.Agent.Image,
+		"reason": payload.Reason,
+	}).Warn("agent failed")
+	ap.failed[payload.Agent.ContainerName()] = payload.Agent
 	return nil
 }
 
 func (ap *AgentPool) registerMessageHandlers() {
-	ap.msgClient.Subscribe(messaging.SubjectAgentsVersionsLatest, messaging.AgentsHandler(ap.handleAgentVersionsUpdate))
+	ap.msgClient.Subscribe(messaging.SubjectAgentsVersionsLatest, messaging.WithAck(messaging.Idempotent(messaging.AgentsHandler(ap.handleAgentVersionsUpdate))))
 	ap.msgClient.Subscribe(messaging.SubjectAgentsStatusRunning, messaging.AgentsHandler(ap.handleStatusRunning))
 	ap.msgClient.Subscribe(messaging.SubjectAgentsStatusStopped, messaging.AgentsHandler(ap.handleStatusStopped))
+	ap.msgClient.Subscribe(messaging.SubjectAgentsStatusFailed, messaging.AgentFailedHandler(ap.handleStatusFailed))
 }
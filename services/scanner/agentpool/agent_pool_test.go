@@ -3,6 +3,7 @@ package agentpool
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/forta-network/forta-core-go/protocol"
 	"github.com/forta-network/forta-node/clients"
@@ -47,10 +48,13 @@ func (s *Suite) SetupTest() {
 	s.msgClient = mock_clients.NewMockMessageClient(gomock.NewController(s.T()))
 	s.agentClient = mock_clients.NewMockAgentClient(gomock.NewController(s.T()))
 	s.ap = &AgentPool{
-		ctx:          context.Background(),
-		txResults:    make(chan *scanner.TxResult),
-		blockResults: make(chan *scanner.BlockResult),
-		msgClient:    s.msgClient,
+		ctx:              context.Background(),
+		txResults:        make(chan *scanner.TxResult),
+		pendingTxResults: make(chan *scanner.TxResult),
+		blockResults:     make(chan *scanner.BlockResult),
+		msgClient:        s.msgClient,
+		restarts:         make(map[string]*agentRestart),
+		failed:           make(map[string]config.AgentConfig),
 		dialer: func(agentCfg config.AgentConfig) (clients.AgentClient, error) {
 			return s.agentClient, nil
 		},
@@ -128,3 +132,331 @@ func (s *Suite) TestStartProcessStop() {
 	s.agentClient.EXPECT().Close()
 	s.r.NoError(s.ap.handleAgentVersionsUpdate(emptyPayload))
 }
+
+// TestRestartOnConfigChange tests that the agent container is restarted when its config changes.
+func (s *Suite) TestRestartOnConfigChange() {
+	agentConfig := config.AgentConfig{ID: testAgentID, Image: "agent:latest", IsLocal: true}
+	updatedConfig := agentConfig
+	updatedConfig.Image = "agent:dev"
+
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, messaging.AgentPayload{agentConfig})
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{agentConfig}))
+
+	// the pools don't cause a restart
+	inPool := agentConfig
+	inPool.Pools = []string{"pool"}
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{inPool}))
+	s.r.Len(s.ap.agents, 1)
+
+	// the container is stopped first
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionStop, messaging.AgentPayload{agentConfig})
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{updatedConfig}))
+	s.r.Empty(s.ap.agents)
+
+	// and runs with the new config after it stops
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, messaging.AgentPayload{updatedConfig})
+	s.r.NoError(s.ap.handleStatusStopped(messaging.AgentPayload{agentConfig}))
+	s.r.Len(s.ap.agents, 1)
+	s.r.Equal(updatedConfig, s.ap.agents[0].Config())
+	s.r.Empty(s.ap.restarts)
+}
+
+// TestRestartTimeout tests that the restarted agent is run with the new config if its container
+// does not stop in time.
+func (s *Suite) TestRestartTimeout() {
+	agentConfig := config.AgentConfig{ID: testAgentID, Image: "agent:latest", IsLocal: true}
+	updatedConfig := agentConfig
+	updatedConfig.Image = "agent:dev"
+
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, messaging.AgentPayload{agentConfig})
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{agentConfig}))
+
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionStop, messaging.AgentPayload{agentConfig})
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{updatedConfig}))
+	restart := s.ap.restarts[agentConfig.ContainerName()]
+	s.r.NotNil(restart)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, messaging.AgentPayload{updatedConfig})
+	s.ap.waitRestart(ctx, agentConfig.ContainerName(), restart)
+	s.r.Len(s.ap.agents, 1)
+	s.r.Equal(updatedConfig, s.ap.agents[0].Config())
+	s.r.Empty(s.ap.restarts)
+}
+
+// TestBlueGreenUpdate tests that the previous version of an agent keeps running until the new
+// version is attached.
+func (s *Suite) TestBlueGreenUpdate() {
+	agentConfig := config.AgentConfig{
+		ID:    testAgentID,
+		Image: "disco.forta.network/agent@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+	}
+	updatedConfig := agentConfig
+	updatedConfig.Image = "disco.forta.network/agent@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	failingConfig := agentConfig
+	failingConfig.Image = "disco.forta.network/agent@sha256:3333333333333333333333333333333333333333333333333333333333333333"
+
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, messaging.AgentPayload{agentConfig})
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{agentConfig}))
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusAttached, messaging.AgentPayload{agentConfig})
+	s.r.NoError(s.ap.handleStatusRunning(messaging.AgentPayload{agentConfig}))
+
+	// the new version is run next to the previous version
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, messaging.AgentPayload{updatedConfig})
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{updatedConfig}))
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{updatedConfig}))
+	s.r.Len(s.ap.agents, 2)
+	s.r.True(s.ap.agents[1].IsReady())
+
+	// and the previous version is stopped after the new version is attached
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusAttached, messaging.AgentPayload{updatedConfig})
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionStop, messaging.AgentPayload{agentConfig})
+	s.agentClient.EXPECT().Close()
+	s.r.NoError(s.ap.handleStatusRunning(messaging.AgentPayload{updatedConfig}))
+	s.r.Len(s.ap.agents, 1)
+	s.r.Equal(updatedConfig, s.ap.agents[0].Config())
+	s.r.True(s.ap.agents[0].IsReady())
+
+	// the previous version keeps running if the new version fails
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, messaging.AgentPayload{failingConfig})
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{failingConfig}))
+	s.r.NoError(s.ap.handleStatusFailed(messaging.AgentFailedPayload{Agent: failingConfig, Reason: "crash loop"}))
+	s.r.NoError(s.ap.handleStatusStopped(messaging.AgentPayload{failingConfig}))
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{failingConfig}))
+	s.r.Len(s.ap.agents, 1)
+	s.r.Equal(updatedConfig, s.ap.agents[0].Config())
+}
+
+// TestFailedAgent tests that the failed agents are run again only with a new config.
+func (s *Suite) TestFailedAgent() {
+	agentConfig := config.AgentConfig{ID: testAgentID, Image: "agent:latest", IsLocal: true}
+	updatedConfig := agentConfig
+	updatedConfig.Image = "agent:fixed"
+
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, messaging.AgentPayload{agentConfig})
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{agentConfig}))
+
+	// the supervisor publishes the failed and the stopped status
+	s.r.NoError(s.ap.handleStatusFailed(messaging.AgentFailedPayload{Agent: agentConfig, Reason: "crash loop"}))
+	s.r.NoError(s.ap.handleStatusStopped(messaging.AgentPayload{agentConfig}))
+	s.r.Empty(s.ap.agents)
+
+	// not run again with the same config
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{agentConfig}))
+	s.r.Empty(s.ap.agents)
+
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, messaging.AgentPayload{updatedConfig})
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(messaging.AgentPayload{updatedConfig}))
+	s.r.Len(s.ap.agents, 1)
+	s.r.Empty(s.ap.failed)
+}
+
+// TestSendEvaluatePendingTxRequest tests that the pending transactions are sent only to the agents
+// which opted in and should process the latest block.
+func (s *Suite) TestSendEvaluatePendingTxRequest() {
+	stopBlock := uint64(100)
+	agentPayload := messaging.AgentPayload{
+		{ID: "opted-in", PendingTransactions: true},
+		{ID: "not-opted-in"},
+		{ID: "stopped", PendingTransactions: true, StopBlock: &stopBlock},
+	}
+
+	// Given that the agents are running
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, gomock.Any())
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(agentPayload))
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusAttached, gomock.Any())
+	s.r.NoError(s.ap.handleStatusRunning(agentPayload))
+
+	// When a pending tx request is received after the stop block of one of the agents
+	// Then only the opted in agent within the block range should process it
+	txReq := &protocol.EvaluateTxRequest{
+		Event: &protocol.TransactionEvent{
+			Transaction: &protocol.TransactionEvent_EthTransaction{
+				Hash: "0x0",
+			},
+		},
+	}
+	s.agentClient.EXPECT().Invoke(
+		gomock.Any(), agentgrpc.MethodEvaluateTx,
+		gomock.AssignableToTypeOf(&grpc.PreparedMsg{}), gomock.AssignableToTypeOf(&protocol.EvaluateTxResponse{}),
+	).Return(nil).Times(1)
+	s.ap.SendEvaluatePendingTxRequest(txReq, stopBlock+1)
+
+	txResult := <-s.ap.PendingTxResults()
+	s.r.Equal("opted-in", txResult.AgentConfig.ID)
+	s.r.Equal(txReq, txResult.Request)
+}
+
+// TestSendEvaluateLogRequest tests that the agents which subscribe to logs receive only the matching logs
+// and do not receive the transactions.
+func (s *Suite) TestSendEvaluateLogRequest() {
+	agentPayload := messaging.AgentPayload{
+		{ID: "subscribed", LogFilters: []config.LogFilter{{Addresses: []string{"0x1"}}}},
+		{ID: "not-matching", LogFilters: []config.LogFilter{{Addresses: []string{"0x3"}}}},
+	}
+
+	// Given that the agents are running
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, gomock.Any())
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(agentPayload))
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusAttached, gomock.Any())
+	s.r.NoError(s.ap.handleStatusRunning(agentPayload))
+	s.r.Len(s.ap.LogFilters(s.ap.chainID), 2)
+	s.r.Len(s.ap.LogFilters(s.ap.chainID+1), 0)
+
+	// When a tx request is received
+	// Then the agents which subscribe to logs should not process it
+	s.ap.SendEvaluateTxRequest(&protocol.EvaluateTxRequest{
+		Event: &protocol.TransactionEvent{
+			Block:       &protocol.TransactionEvent_EthBlock{BlockNumber: "0x1"},
+			Transaction: &protocol.TransactionEvent_EthTransaction{Hash: "0x0"},
+		},
+	})
+
+	// When a log request is received
+	// Then only the agent with matching filters should process the matching logs
+	logReq := &protocol.EvaluateTxRequest{
+		Event: &protocol.TransactionEvent{
+			Block:       &protocol.TransactionEvent_EthBlock{BlockNumber: "0x1"},
+			Transaction: &protocol.TransactionEvent_EthTransaction{Hash: "0x0"},
+			Logs: []*protocol.TransactionEvent_Log{
+				{Address: "0x1", LogIndex: "0x0"},
+				{Address: "0x2", LogIndex: "0x1"},
+			},
+		},
+	}
+	s.agentClient.EXPECT().Invoke(
+		gomock.Any(), agentgrpc.MethodEvaluateTx,
+		gomock.AssignableToTypeOf(&grpc.PreparedMsg{}), gomock.AssignableToTypeOf(&protocol.EvaluateTxResponse{}),
+	).Return(nil).Times(1)
+	s.ap.SendEvaluateLogRequest(logReq)
+
+	txResult := <-s.ap.TxResults()
+	s.r.Equal("subscribed", txResult.AgentConfig.ID)
+	s.r.Len(txResult.Request.Event.Logs, 1)
+	s.r.Equal("0x1", txResult.Request.Event.Logs[0].Address)
+	s.r.Len(logReq.Event.Logs, 2)
+}
+
+// TestSendEvaluateUserOpRequest tests that the user operations are sent only to the agents which
+// subscribe to user operations.
+func (s *Suite) TestSendEvaluateUserOpRequest() {
+	agentPayload := messaging.AgentPayload{
+		{ID: "subscribed", UserOperations: true},
+		{ID: "not-subscribed"},
+	}
+
+	// Given that the agents are running
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, gomock.Any())
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(agentPayload))
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusAttached, gomock.Any())
+	s.r.NoError(s.ap.handleStatusRunning(agentPayload))
+	s.r.True(s.ap.HasUserOpAgents())
+
+	// When a user operation request is received
+	// Then only the subscribed agent should process it
+	userOpReq := &protocol.EvaluateTxRequest{
+		Event: &protocol.TransactionEvent{
+			Block:       &protocol.TransactionEvent_EthBlock{BlockNumber: "0x1"},
+			Transaction: &protocol.TransactionEvent_EthTransaction{Hash: "0x0"},
+		},
+	}
+	s.agentClient.EXPECT().Invoke(
+		gomock.Any(), agentgrpc.MethodEvaluateTx,
+		gomock.AssignableToTypeOf(&grpc.PreparedMsg{}), gomock.AssignableToTypeOf(&protocol.EvaluateTxResponse{}),
+	).Return(nil).Times(1)
+	s.ap.SendEvaluateUserOpRequest(userOpReq)
+
+	txResult := <-s.ap.TxResults()
+	s.r.Equal("subscribed", txResult.AgentConfig.ID)
+	s.r.Equal(userOpReq, txResult.Request)
+}
+
+// TestSendEvaluateTxRequest_Chains tests that the requests are sent only to the agents which
+// run on the chain of the request.
+func (s *Suite) TestSendEvaluateTxRequest_Chains() {
+	s.ap.chainID = 1
+	agentPayload := messaging.AgentPayload{
+		{ID: "main-chain"},
+		{ID: "other-chain", ChainIDs: []int64{137}},
+	}
+
+	// Given that the agents are running
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, gomock.Any())
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(agentPayload))
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusAttached, gomock.Any())
+	s.r.NoError(s.ap.handleStatusRunning(agentPayload))
+
+	// When a tx request is received from the other chain
+	// Then only the agent which declares the chain should process it
+	txReq := &protocol.EvaluateTxRequest{
+		Event: &protocol.TransactionEvent{
+			Network:     &protocol.TransactionEvent_Network{ChainId: "0x89"},
+			Block:       &protocol.TransactionEvent_EthBlock{BlockNumber: "0x1"},
+			Transaction: &protocol.TransactionEvent_EthTransaction{Hash: "0x0"},
+		},
+	}
+	s.agentClient.EXPECT().Invoke(
+		gomock.Any(), agentgrpc.MethodEvaluateTx,
+		gomock.AssignableToTypeOf(&grpc.PreparedMsg{}), gomock.AssignableToTypeOf(&protocol.EvaluateTxResponse{}),
+	).Return(nil).Times(1)
+	s.ap.SendEvaluateTxRequest(txReq)
+
+	txResult := <-s.ap.TxResults()
+	s.r.Equal("other-chain", txResult.AgentConfig.ID)
+
+	// When a tx request without a chain ID is received
+	// Then only the agent which does not declare any chains should process it
+	txReq.Event.Network = nil
+	s.agentClient.EXPECT().Invoke(
+		gomock.Any(), agentgrpc.MethodEvaluateTx,
+		gomock.AssignableToTypeOf(&grpc.PreparedMsg{}), gomock.AssignableToTypeOf(&protocol.EvaluateTxResponse{}),
+	).Return(nil).Times(1)
+	s.ap.SendEvaluateTxRequest(txReq)
+
+	txResult = <-s.ap.TxResults()
+	s.r.Equal("main-chain", txResult.AgentConfig.ID)
+
+	// When a tx request with an invalid chain ID is received
+	// Then no agents should process it
+	txReq.Event.Network = &protocol.TransactionEvent_Network{ChainId: "invalid"}
+	s.ap.SendEvaluateTxRequest(txReq)
+}
+
+// TestSendEvaluateCanaryRequest tests that the canary requests are sent only to the agent.
+func (s *Suite) TestSendEvaluateCanaryRequest() {
+	agentPayload := messaging.AgentPayload{
+		{ID: "canary-agent"},
+		{ID: "other-agent"},
+	}
+	canaryReq := &protocol.EvaluateTxRequest{
+		RequestId: scanner.CanaryRequestIDPrefix + testRequestID,
+		Event: &protocol.TransactionEvent{
+			Block:       &protocol.TransactionEvent_EthBlock{BlockNumber: "0x1"},
+			Transaction: &protocol.TransactionEvent_EthTransaction{Hash: "0x0"},
+		},
+	}
+
+	// Given that the agents are not ready
+	// Then the canary should not be sent
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsActionRun, gomock.Any())
+	s.r.NoError(s.ap.handleAgentVersionsUpdate(agentPayload))
+	s.r.False(s.ap.SendEvaluateCanaryRequest("canary-agent", canaryReq))
+
+	// Given that the agents are running
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusAttached, gomock.Any())
+	s.r.NoError(s.ap.handleStatusRunning(agentPayload))
+
+	// When a canary request is sent to an agent
+	// Then only that agent should process it
+	s.agentClient.EXPECT().Invoke(
+		gomock.Any(), agentgrpc.MethodEvaluateTx,
+		gomock.AssignableToTypeOf(&grpc.PreparedMsg{}), gomock.AssignableToTypeOf(&protocol.EvaluateTxResponse{}),
+	).Return(nil).Times(1)
+	s.r.True(s.ap.SendEvaluateCanaryRequest("canary-agent", canaryReq))
+	s.r.False(s.ap.SendEvaluateCanaryRequest("unknown-agent", canaryReq))
+
+	txResult := <-s.ap.TxResults()
+	s.r.Equal("canary-agent", txResult.AgentConfig.ID)
+	s.r.True(scanner.IsCanaryRequest(txResult.Request))
+}
@@ -2,6 +2,7 @@ package poolagent
 
 import (
 	"context"
+	"fmt"
 	"github.com/forta-network/forta-core-go/domain"
 	"sync"
 	"time"
@@ -9,12 +10,15 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/forta-network/forta-node/metrics"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/forta-network/forta-core-go/protocol"
 	"github.com/forta-network/forta-node/clients"
 	"github.com/forta-network/forta-node/clients/agentgrpc"
 	"github.com/forta-network/forta-node/clients/messaging"
 	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/crash"
 	"github.com/forta-network/forta-node/services/scanner"
 
 	log "github.com/sirupsen/logrus"
@@ -22,9 +26,11 @@ import (
 
 // Constants
 const (
-	DefaultBufferSize = 2000
-	AgentTimeout      = 30 * time.Second
-	MaxFindings       = 10
+	DefaultBufferSize   = 2000
+	PendingTxBufferSize = 500
+	AgentTimeout        = 30 * time.Second
+	GPUAgentTimeout     = 2 * time.Minute // the ML agents which run on GPUs need more time
+	MaxFindings         = 10
 )
 
 // Agent receives blocks and transactions, and produces results.
@@ -32,13 +38,16 @@ type Agent struct {
 	ctx    context.Context
 	config config.AgentConfig
 
-	txRequests    chan *TxRequest // never closed - deallocated when agent is discarded
-	txResults     chan<- *scanner.TxResult
-	blockRequests chan *BlockRequest // never closed - deallocated when agent is discarded
-	blockResults  chan<- *scanner.BlockResult
+	txRequests        chan *TxRequest // never closed - deallocated when agent is discarded
+	txResults         chan<- *scanner.TxResult
+	pendingTxRequests chan *TxRequest // never closed - deallocated when agent is discarded
+	pendingTxResults  chan<- *scanner.TxResult
+	blockRequests     chan *BlockRequest // never closed - deallocated when agent is discarded
+	blockResults      chan<- *scanner.BlockResult
 
 	errCounter *errorCounter
 	msgClient  clients.MessageClient
+	timeout    time.Duration
 
 	client    clients.AgentClient
 	ready     chan struct{}
@@ -59,19 +68,33 @@ type BlockRequest struct {
 	Encoded  *grpc.PreparedMsg
 }
 
+// Results contains the channels which the agent sends the results to.
+type Results struct {
+	TxResults        chan<- *scanner.TxResult
+	PendingTxResults chan<- *scanner.TxResult
+	BlockResults     chan<- *scanner.BlockResult
+}
+
 // New creates a new agent.
-func New(ctx context.Context, agentCfg config.AgentConfig, msgClient clients.MessageClient, txResults chan<- *scanner.TxResult, blockResults chan<- *scanner.BlockResult) *Agent {
+func New(ctx context.Context, agentCfg config.AgentConfig, msgClient clients.MessageClient, results Results) *Agent {
+	timeout := AgentTimeout
+	if agentCfg.UsesGPU() {
+		timeout = GPUAgentTimeout
+	}
 	return &Agent{
-		ctx:           ctx,
-		config:        agentCfg,
-		txRequests:    make(chan *TxRequest, DefaultBufferSize),
-		txResults:     txResults,
-		blockRequests: make(chan *BlockRequest, DefaultBufferSize),
-		blockResults:  blockResults,
-		errCounter:    NewErrorCounter(3, isCriticalErr),
-		msgClient:     msgClient,
-		ready:         make(chan struct{}),
-		closed:        make(chan struct{}),
+		ctx:               ctx,
+		config:            agentCfg,
+		txRequests:        make(chan *TxRequest, DefaultBufferSize),
+		txResults:         results.TxResults,
+		pendingTxRequests: make(chan *TxRequest, PendingTxBufferSize),
+		pendingTxResults:  results.PendingTxResults,
+		blockRequests:     make(chan *BlockRequest, DefaultBufferSize),
+		blockResults:      results.BlockResults,
+		errCounter:        NewErrorCounter(3, isCriticalErr),
+		msgClient:         msgClient,
+		timeout:           timeout,
+		ready:             make(chan struct{}),
+		closed:            make(chan struct{}),
 	}
 }
 
@@ -85,11 +108,12 @@ func isCriticalErr(err error) bool {
 // LogStatus logs the status of the agent.
 func (agent *Agent) LogStatus() {
 	log.WithFields(log.Fields{
-		"agent":       agent.config.ID,
-		"blockBuffer": len(agent.blockRequests),
-		"txBuffer":    len(agent.txRequests),
-		"ready":       agent.IsReady(),
-		"closed":      agent.IsClosed(),
+		"agent":           agent.config.ID,
+		"blockBuffer":     len(agent.blockRequests),
+		"txBuffer":        len(agent.txRequests),
+		"pendingTxBuffer": len(agent.pendingTxRequests),
+		"ready":           agent.IsReady(),
+		"closed":          agent.IsClosed(),
 	}).Debug("agent status")
 }
 
@@ -108,6 +132,11 @@ func (agent *Agent) TxRequestCh() chan<- *TxRequest {
 	return agent.txRequests
 }
 
+// PendingTxRequestCh returns the pending transaction request channel safely.
+func (agent *Agent) PendingTxRequestCh() chan<- *TxRequest {
+	return agent.pendingTxRequests
+}
+
 // BlockRequestCh returns the block request channel safely.
 func (agent *Agent) BlockRequestCh() chan<- *BlockRequest {
 	return agent.blockRequests
@@ -168,8 +197,14 @@ func (agent *Agent) SetClient(agentClient clients.AgentClient) {
 // StartProcessing launches the goroutines to concurrently process incoming requests
 // from request channels.
 func (agent *Agent) StartProcessing() {
-	go agent.processTransactions()
-	go agent.processBlocks()
+	crash.Go(fmt.Sprintf("agent-%s-txs", agent.config.ID), agent.processTransactions, agent.stop)
+	crash.Go(fmt.Sprintf("agent-%s-blocks", agent.config.ID), agent.processBlocks, agent.stop)
+}
+
+// stop closes the agent and requests stopping the agent container.
+func (agent *Agent) stop() {
+	agent.Close()
+	agent.msgClient.Publish(messaging.SubjectAgentsActionStop, messaging.AgentPayload{agent.config})
 }
 
 func (agent *Agent) processTransactions() {
@@ -178,12 +213,31 @@ func (agent *Agent) processTransactions() {
 		"component": "agent",
 		"evaluate":  "transaction",
 	})
-	for request := range agent.txRequests {
+	for {
+		// pending transactions are processed only if there are no mined transactions waiting
+		var (
+			request *TxRequest
+			results chan<- *scanner.TxResult
+		)
+		select {
+		case request = <-agent.txRequests:
+			results = agent.txResults
+		default:
+			select {
+			case request = <-agent.txRequests:
+				results = agent.txResults
+			case request = <-agent.pendingTxRequests:
+				results = agent.pendingTxResults
+			case <-agent.closed:
+				return
+			}
+		}
+
 		startTime := time.Now()
 		if agent.IsClosed() {
 			return
 		}
-		ctx, cancel := context.WithTimeout(agent.ctx, AgentTimeout)
+		ctx, cancel := context.WithTimeout(agent.ctx, agent.timeout)
 		lg.WithField("duration", time.Since(startTime)).Debugf("sending request")
 		resp := new(protocol.EvaluateTxResponse)
 
@@ -212,7 +266,7 @@ func (agent *Agent) processTransactions() {
 			ts.BotRequest = requestTime
 			ts.BotResponse = responseTime
 
-			agent.txResults <- &scanner.TxResult{
+			results <- &scanner.TxResult{
 				AgentConfig: agent.config,
 				Request:     request.Original,
 				Response:    resp,
@@ -222,10 +276,14 @@ func (agent *Agent) processTransactions() {
 			continue
 		}
 		lg.WithField("duration", time.Since(startTime)).WithError(err).Error("error invoking agent")
+		if status.Code(err) == codes.DeadlineExceeded {
+			metrics.SendAgentMetrics(agent.msgClient, []*protocol.AgentMetric{
+				metrics.CreateAgentMetric(agent.config.ID, metrics.MetricTxTimeout, 1),
+			})
+		}
 		if agent.errCounter.TooManyErrs(err) {
 			lg.WithField("duration", time.Since(startTime)).Error("too many errors - shutting down agent")
-			agent.Close()
-			agent.msgClient.Publish(messaging.SubjectAgentsActionStop, messaging.AgentPayload{agent.config})
+			agent.stop()
 			agent.msgClient.PublishProto(messaging.SubjectMetricAgent, &protocol.AgentMetricList{
 				Metrics: []*protocol.AgentMetric{{
 					AgentId:   agent.config.ID,
@@ -251,7 +309,7 @@ func (agent *Agent) processBlocks() {
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(agent.ctx, AgentTimeout)
+		ctx, cancel := context.WithTimeout(agent.ctx, agent.timeout)
 		lg.WithField("duration", time.Since(startTime)).Debugf("sending request")
 		resp := new(protocol.EvaluateBlockResponse)
 		requestTime := time.Now().UTC()
@@ -289,10 +347,14 @@ func (agent *Agent) processBlocks() {
 			continue
 		}
 		lg.WithField("duration", time.Since(startTime)).WithError(err).Error("error invoking agent")
+		if status.Code(err) == codes.DeadlineExceeded {
+			metrics.SendAgentMetrics(agent.msgClient, []*protocol.AgentMetric{
+				metrics.CreateAgentMetric(agent.config.ID, metrics.MetricBlockTimeout, 1),
+			})
+		}
 		if agent.errCounter.TooManyErrs(err) {
 			lg.WithField("duration", time.Since(startTime)).Error("too many errors - shutting down agent")
-			agent.Close()
-			agent.msgClient.Publish(messaging.SubjectAgentsActionStop, messaging.AgentPayload{agent.config})
+			agent.stop()
 			return
 		}
 	}
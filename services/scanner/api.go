@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/forta-network/forta-core-go/feeds"
 	"github.com/forta-network/forta-core-go/utils"
 	"github.com/goccy/go-json"
 
@@ -18,7 +17,7 @@ import (
 type API struct {
 	ctx     context.Context
 	started bool
-	feed    feeds.BlockFeed
+	feed    DataSource
 	server  *http.Server
 }
 
@@ -103,7 +102,7 @@ func (t *API) Name() string {
 	return "ScannerAPI"
 }
 
-func NewScannerAPI(ctx context.Context, feed feeds.BlockFeed) *API {
+func NewScannerAPI(ctx context.Context, feed DataSource) *API {
 	return &API{
 		ctx:  ctx,
 		feed: feed,
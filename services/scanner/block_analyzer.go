@@ -10,6 +10,7 @@ import (
 	"github.com/forta-network/forta-core-go/utils"
 	"github.com/forta-network/forta-node/clients/messaging"
 	"github.com/forta-network/forta-node/metrics"
+	"github.com/forta-network/forta-node/store"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/golang/protobuf/jsonpb"
@@ -69,11 +70,12 @@ func (t *BlockAnalyzerService) findingToAlert(result *BlockResult, ts time.Time,
 	if err != nil {
 		return nil, err
 	}
-	tags := map[string]string{
+	tags := store.AlertSchemaTags(map[string]string{
 		"agentImage": result.AgentConfig.Image,
 		"agentId":    result.AgentConfig.ID,
 		"chainId":    chainId.String(),
-	}
+	})
+	tags[store.AlertHashTag] = store.FindingHash(result.AgentConfig.ID, chainId.String(), result.Request.Event.BlockHash, "", f)
 
 	alertType := protocol.AlertType_PRIVATE
 	if !f.Private && !result.Response.Private {
@@ -0,0 +1,247 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/metrics"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// CanaryRequestIDPrefix is the prefix of the canary tx request IDs.
+const CanaryRequestIDPrefix = "canary-"
+
+// IsCanaryRequest tells if the request is a canary which should not be published.
+func IsCanaryRequest(req *protocol.EvaluateTxRequest) bool {
+	return strings.HasPrefix(req.RequestId, CanaryRequestIDPrefix)
+}
+
+// CanaryService injects the synthetic transactions into the agents periodically and checks that the
+// agents return the expected findings for them. The agents which miss the canaries too many times in a
+// row are reported as failing.
+type CanaryService struct {
+	ctx       context.Context
+	cfg       config.AgentCanariesConfig
+	pool      AgentPool
+	msgClient clients.MessageClient
+
+	canaries []*canary
+	pending  map[string]*canary // request ID -> canary
+	mu       sync.Mutex
+}
+
+type canary struct {
+	config   config.AgentCanary
+	request  *protocol.EvaluateTxRequest
+	misses   int
+	lastPass health.TimeTracker
+	lastErr  string
+}
+
+// NewCanaryService creates a new canary service. The fixtures are loaded from the Forta dir.
+func NewCanaryService(ctx context.Context, cfg config.Config, pool AgentPool, msgClient clients.MessageClient) (*CanaryService, error) {
+	cs := &CanaryService{
+		ctx:       ctx,
+		cfg:       cfg.AgentCanaries,
+		pool:      pool,
+		msgClient: msgClient,
+		pending:   make(map[string]*canary),
+	}
+	for i, canaryCfg := range cfg.AgentCanaries.Canaries {
+		fixturePath := canaryCfg.Fixture
+		if !path.IsAbs(fixturePath) {
+			fixturePath = path.Join(cfg.FortaDir, fixturePath)
+		}
+		req, err := loadCanaryFixture(fixturePath, cfg.ChainID)
+		if err != nil {
+			return nil, fmt.Errorf("canaries[%d]: %v", i, err)
+		}
+		cs.canaries = append(cs.canaries, &canary{config: canaryCfg, request: req})
+	}
+	return cs, nil
+}
+
+// loadCanaryFixture reads the tx request from the file. The request is for the chain if the fixture does
+// not have a chain ID.
+func loadCanaryFixture(filePath string, chainID int) (*protocol.EvaluateTxRequest, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the fixture: %v", err)
+	}
+	var req protocol.EvaluateTxRequest
+	if err := jsonpb.Unmarshal(bytes.NewReader(b), &req); err != nil {
+		return nil, fmt.Errorf("failed to parse the fixture: %v", err)
+	}
+	if req.Event == nil || req.Event.Transaction == nil || req.Event.Block == nil {
+		return nil, fmt.Errorf("the fixture needs an event with the transaction and the block")
+	}
+	if len(req.Event.Network.GetChainId()) == 0 {
+		req.Event.Network = &protocol.TransactionEvent_Network{ChainId: hexutil.EncodeUint64(uint64(chainID))}
+	}
+	return &req, nil
+}
+
+// Start starts the service.
+func (cs *CanaryService) Start() error {
+	go func() {
+		ticker := time.NewTicker(time.Duration(cs.cfg.IntervalMinutes) * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-cs.ctx.Done():
+				return
+			case <-ticker.C:
+				cs.sendCanaries()
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the service.
+func (cs *CanaryService) Stop() error {
+	return nil
+}
+
+// Name returns the name of the service.
+func (cs *CanaryService) Name() string {
+	return "agent-canaries"
+}
+
+// sendCanaries sends the canaries to the agents which are ready. A canary which does not get a result
+// before the timeout is a miss.
+func (cs *CanaryService) sendCanaries() {
+	timeout := time.Duration(cs.cfg.TimeoutSeconds) * time.Second
+	for _, c := range cs.canaries {
+		req := proto.Clone(c.request).(*protocol.EvaluateTxRequest)
+		req.RequestId = CanaryRequestIDPrefix + uuid.Must(uuid.NewUUID()).String()
+
+		cs.mu.Lock()
+		cs.pending[req.RequestId] = c
+		cs.mu.Unlock()
+		if !cs.pool.SendEvaluateCanaryRequest(c.config.AgentID, req) {
+			cs.mu.Lock()
+			delete(cs.pending, req.RequestId)
+			cs.mu.Unlock()
+			log.WithField("agent", c.config.AgentID).Debug("agent is not ready - skipping the canary")
+			continue
+		}
+
+		requestID := req.RequestId
+		time.AfterFunc(timeout, func() {
+			cs.mu.Lock()
+			defer cs.mu.Unlock()
+			if c, ok := cs.pending[requestID]; ok {
+				delete(cs.pending, requestID)
+				cs.missUnsafe(c, fmt.Sprintf("no result in %s", timeout))
+			}
+		})
+	}
+}
+
+// HandleResult checks the result if it is for a canary. It tells if the result was a canary so that its
+// findings are not published.
+func (cs *CanaryService) HandleResult(result *TxResult) bool {
+	if !IsCanaryRequest(result.Request) {
+		return false
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	c, ok := cs.pending[result.Request.RequestId]
+	if !ok {
+		return true // timed out already
+	}
+	delete(cs.pending, result.Request.RequestId)
+	if missing := missingAlertIDs(c.config.ExpectedAlertIDs, result.Response); len(missing) > 0 {
+		cs.missUnsafe(c, fmt.Sprintf("missing the findings: %s", strings.Join(missing, ", ")))
+		return true
+	}
+	if c.misses >= cs.cfg.MaxMisses {
+		log.WithField("agent", c.config.AgentID).Info("agent returned the expected canary findings again")
+	}
+	c.misses = 0
+	c.lastErr = ""
+	c.lastPass.Set()
+	return true
+}
+
+// missingAlertIDs returns the expected alert IDs which are not in the findings. If no alert IDs are
+// expected, at least one finding is.
+func missingAlertIDs(expected []string, resp *protocol.EvaluateTxResponse) []string {
+	if resp.Status == protocol.ResponseStatus_ERROR {
+		return []string{"error response"}
+	}
+	if len(expected) == 0 {
+		if len(resp.Findings) == 0 {
+			return []string{"any finding"}
+		}
+		return nil
+	}
+	found := make(map[string]bool)
+	for _, finding := range resp.Findings {
+		found[finding.AlertId] = true
+	}
+	var missing []string
+	for _, alertID := range expected {
+		if !found[alertID] {
+			missing = append(missing, alertID)
+		}
+	}
+	return missing
+}
+
+func (cs *CanaryService) missUnsafe(c *canary, reason string) {
+	c.misses++
+	c.lastErr = reason
+	logger := log.WithFields(log.Fields{
+		"agent":  c.config.AgentID,
+		"misses": c.misses,
+		"reason": reason,
+	})
+	if c.misses >= cs.cfg.MaxMisses {
+		logger.Error("agent stopped detecting the canary transaction")
+	} else {
+		logger.Warn("agent missed the canary transaction")
+	}
+	metrics.SendAgentMetrics(cs.msgClient, []*protocol.AgentMetric{
+		metrics.CreateAgentMetric(c.config.AgentID, metrics.MetricCanaryMiss, 1),
+	})
+}
+
+// Health implements the health.Reporter interface.
+func (cs *CanaryService) Health() health.Reports {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var reports health.Reports
+	for _, c := range cs.canaries {
+		name := fmt.Sprintf("canary.%s", c.config.AgentID)
+		if c.misses >= cs.cfg.MaxMisses {
+			reports = append(reports, &health.Report{
+				Name:    name,
+				Status:  health.StatusFailing,
+				Details: fmt.Sprintf("missed %d canaries in a row: %s", c.misses, c.lastErr),
+			})
+		} else {
+			reports = append(reports, &health.Report{Name: name, Status: health.StatusOK, Details: fmt.Sprintf("%d misses", c.misses)})
+		}
+		reports = append(reports, c.lastPass.GetReport(name+".pass.time"))
+	}
+	return reports
+}
@@ -0,0 +1,130 @@
+package scanner
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+const testCanaryFixture = `{
+  "event": {
+    "transaction": {"hash": "0xcafe", "from": "0x1", "to": "0x2"},
+    "block": {"blockNumber": "0x10", "blockHash": "0xbeef"}
+  }
+}`
+
+type canaryAgentPool struct {
+	AgentPool
+	ready    bool
+	requests chan *protocol.EvaluateTxRequest
+}
+
+func (pool *canaryAgentPool) SendEvaluateCanaryRequest(agentID string, req *protocol.EvaluateTxRequest) bool {
+	if !pool.ready {
+		return false
+	}
+	pool.requests <- req
+	return true
+}
+
+func canaryResult(req *protocol.EvaluateTxRequest, alertIDs ...string) *TxResult {
+	resp := &protocol.EvaluateTxResponse{Status: protocol.ResponseStatus_SUCCESS}
+	for _, alertID := range alertIDs {
+		resp.Findings = append(resp.Findings, &protocol.Finding{AlertId: alertID})
+	}
+	return &TxResult{Request: req, Response: resp}
+}
+
+func canaryHealth(cs *CanaryService) health.Status {
+	return cs.Health()[0].Status
+}
+
+func TestCanaryService(t *testing.T) {
+	r := require.New(t)
+
+	fortaDir := t.TempDir()
+	r.NoError(ioutil.WriteFile(path.Join(fortaDir, "canary.json"), []byte(testCanaryFixture), 0644))
+	cfg := config.Config{
+		ChainID:  137,
+		FortaDir: fortaDir,
+		AgentCanaries: config.AgentCanariesConfig{
+			Enable:         true,
+			TimeoutSeconds: 1,
+			MaxMisses:      2,
+			Canaries: []config.AgentCanary{
+				{AgentID: "0xagent", Fixture: "canary.json", ExpectedAlertIDs: []string{"CANARY-1"}},
+			},
+		},
+	}
+	bus := messaging.NewLocalBus()
+	missMetrics := make(chan *protocol.AgentMetric, 10)
+	bus.NewClient("test").Subscribe(messaging.SubjectMetricAgent, messaging.AgentMetricHandler(func(ms *protocol.AgentMetricList) error {
+		for _, m := range ms.Metrics {
+			if m.Name == metrics.MetricCanaryMiss {
+				missMetrics <- m
+			}
+		}
+		return nil
+	}))
+	pool := &canaryAgentPool{requests: make(chan *protocol.EvaluateTxRequest, 10)}
+	cs, err := NewCanaryService(context.Background(), cfg, pool, bus.NewClient("canaries"))
+	r.NoError(err)
+
+	// the agents which are not ready are skipped
+	cs.sendCanaries()
+	r.Len(pool.requests, 0)
+	r.Empty(cs.pending)
+
+	// the canary is sent with the chain ID of the node and its result is not published
+	pool.ready = true
+	cs.sendCanaries()
+	req := <-pool.requests
+	r.True(IsCanaryRequest(req))
+	r.Equal("0x89", req.Event.Network.ChainId)
+	r.True(cs.HandleResult(canaryResult(req, "CANARY-1", "OTHER")))
+	r.Equal(health.StatusOK, canaryHealth(cs))
+	r.False(cs.HandleResult(canaryResult(&protocol.EvaluateTxRequest{RequestId: "regular"})))
+
+	// the agent fails after missing the canaries too many times in a row
+	cs.sendCanaries()
+	r.True(cs.HandleResult(canaryResult(<-pool.requests, "OTHER")))
+	r.Equal(health.StatusOK, canaryHealth(cs))
+	cs.sendCanaries()
+	<-pool.requests
+	select {
+	case m := <-missMetrics:
+		r.Equal("0xagent", m.AgentId)
+	case <-time.After(time.Second * 3):
+		r.FailNow("no miss metric")
+	}
+	r.Eventually(func() bool {
+		return canaryHealth(cs) == health.StatusFailing
+	}, time.Second*3, time.Millisecond*100)
+	r.Contains(cs.Health()[0].Details, "no result in")
+
+	// the agent recovers with a single expected result
+	cs.sendCanaries()
+	r.True(cs.HandleResult(canaryResult(<-pool.requests, "CANARY-1")))
+	r.Equal(health.StatusOK, canaryHealth(cs))
+}
+
+func TestMissingAlertIDs(t *testing.T) {
+	r := require.New(t)
+
+	r.Empty(missingAlertIDs(nil, &protocol.EvaluateTxResponse{Findings: []*protocol.Finding{{AlertId: "A"}}}))
+	r.NotEmpty(missingAlertIDs(nil, &protocol.EvaluateTxResponse{}))
+	r.Equal([]string{"B"}, missingAlertIDs([]string{"A", "B"}, &protocol.EvaluateTxResponse{Findings: []*protocol.Finding{{AlertId: "A"}}}))
+	r.NotEmpty(missingAlertIDs(nil, &protocol.EvaluateTxResponse{
+		Status:   protocol.ResponseStatus_ERROR,
+		Findings: []*protocol.Finding{{AlertId: "A"}},
+	}))
+}
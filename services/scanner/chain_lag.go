@@ -0,0 +1,185 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/ethereum"
+	"github.com/forta-network/forta-node/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ChainLagTracker tracks how many blocks the last processed block is behind the chain head. When the lag
+// is over the configured max lag, it enters the catch-up mode and notifies the handlers. It leaves the
+// catch-up mode when the lag is under half of the max lag.
+type ChainLagTracker struct {
+	ctx    context.Context
+	cfg    config.CatchUpConfig
+	client ethereum.Client
+	offset int64
+
+	head       int64
+	processed  int64
+	catchingUp bool
+	handlers   []func(catchingUp bool)
+	mu         sync.RWMutex
+
+	lastCatchUp health.MessageTracker
+}
+
+// OnCatchUp adds a handler which is called when the catch-up mode starts or ends.
+func (lt *ChainLagTracker) OnCatchUp(handler func(catchingUp bool)) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.handlers = append(lt.handlers, handler)
+}
+
+// SetProcessed sets the last processed block.
+func (lt *ChainLagTracker) SetProcessed(blockNumber int64) {
+	lt.mu.Lock()
+	lt.processed = blockNumber
+	lt.mu.Unlock()
+	lt.update()
+}
+
+func (lt *ChainLagTracker) setHead(blockNumber int64) {
+	lt.mu.Lock()
+	lt.head = blockNumber
+	lt.mu.Unlock()
+	lt.update()
+}
+
+// Lag returns the number of blocks between the last processed block and the chain head. The blocks which
+// wait for the confirmations are not counted.
+func (lt *ChainLagTracker) Lag() int64 {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	return lt.lag()
+}
+
+func (lt *ChainLagTracker) lag() int64 {
+	if lt.head == 0 || lt.processed == 0 {
+		return 0
+	}
+	lag := lt.head - lt.offset - lt.processed
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// CatchingUp tells if the tracker is in the catch-up mode.
+func (lt *ChainLagTracker) CatchingUp() bool {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	return lt.catchingUp
+}
+
+func (lt *ChainLagTracker) update() {
+	if !lt.cfg.Enabled {
+		return
+	}
+	lt.mu.Lock()
+	lag := lt.lag()
+	catchingUp := lt.catchingUp
+	switch {
+	case !catchingUp && lag > lt.cfg.MaxLag:
+		catchingUp = true
+	case catchingUp && lag < lt.cfg.MaxLag/2:
+		catchingUp = false
+	}
+	if catchingUp == lt.catchingUp {
+		lt.mu.Unlock()
+		return
+	}
+	lt.catchingUp = catchingUp
+	handlers := lt.handlers
+	lt.mu.Unlock()
+
+	logger := log.WithField("lag", lag)
+	if catchingUp {
+		logger.Warn("scanner is behind the chain head - started catching up")
+		lt.lastCatchUp.Set(fmt.Sprintf("started catching up with lag %d", lag))
+	} else {
+		logger.Info("scanner reached the chain head - stopped catching up")
+		lt.lastCatchUp.Set(fmt.Sprintf("stopped catching up with lag %d", lag))
+	}
+	for _, handler := range handlers {
+		handler(catchingUp)
+	}
+}
+
+func (lt *ChainLagTracker) checkHead() {
+	blockNumber, err := lt.client.BlockNumber(lt.ctx)
+	if err != nil {
+		log.WithError(err).Warn("failed to get the chain head")
+		return
+	}
+	lt.setHead(blockNumber.Int64())
+}
+
+// Start starts tracking the chain head.
+func (lt *ChainLagTracker) Start() {
+	go func() {
+		lt.checkHead()
+		ticker := time.NewTicker(time.Duration(lt.cfg.CheckIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lt.ctx.Done():
+				return
+			case <-ticker.C:
+				lt.checkHead()
+			}
+		}
+	}()
+}
+
+// Health implements the health.Reporter interface.
+func (lt *ChainLagTracker) Health() health.Reports {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	return health.Reports{
+		&health.Report{Name: "chain.head", Status: health.StatusInfo, Details: strconv.FormatInt(lt.head, 10)},
+		&health.Report{Name: "chain.lag", Status: health.StatusInfo, Details: strconv.FormatInt(lt.lag(), 10)},
+		&health.Report{Name: "chain.catch-up", Status: health.StatusInfo, Details: strconv.FormatBool(lt.catchingUp)},
+		lt.lastCatchUp.GetReport("chain.catch-up.change"),
+	}
+}
+
+// NewChainLagTracker creates a new tracker which gets the chain head from the client. The offset is the
+// number of blocks which the block feed waits for before processing a block.
+func NewChainLagTracker(ctx context.Context, client ethereum.Client, offset int, cfg config.CatchUpConfig) *ChainLagTracker {
+	return &ChainLagTracker{
+		ctx:    ctx,
+		cfg:    cfg,
+		client: client,
+		offset: int64(offset),
+	}
+}
+
+// CatchUpTraceClient skips tracing the blocks while the scanner is catching up.
+type CatchUpTraceClient struct {
+	ethereum.Client
+	tracker *ChainLagTracker
+}
+
+// TraceBlock returns no traces while catching up.
+func (c *CatchUpTraceClient) TraceBlock(ctx context.Context, number *big.Int) ([]domain.Trace, error) {
+	if c.tracker.CatchingUp() {
+		return nil, nil
+	}
+	return c.Client.TraceBlock(ctx, number)
+}
+
+// NewCatchUpTraceClient creates a new trace client which skips the traces while catching up.
+func NewCatchUpTraceClient(traceClient ethereum.Client, tracker *ChainLagTracker) *CatchUpTraceClient {
+	return &CatchUpTraceClient{Client: traceClient, tracker: tracker}
+}
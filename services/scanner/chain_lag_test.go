@@ -0,0 +1,64 @@
+package scanner
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/ethereum"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTraceClient struct {
+	ethereum.Client
+}
+
+func (c *fakeTraceClient) TraceBlock(ctx context.Context, number *big.Int) ([]domain.Trace, error) {
+	return []domain.Trace{{}}, nil
+}
+
+func TestChainLagTracker(t *testing.T) {
+	r := require.New(t)
+
+	lt := NewChainLagTracker(context.Background(), nil, 2, config.CatchUpConfig{Enabled: true, MaxLag: 10})
+	var changes []bool
+	lt.OnCatchUp(func(catchingUp bool) {
+		changes = append(changes, catchingUp)
+	})
+	traceClient := NewCatchUpTraceClient(&fakeTraceClient{}, lt)
+
+	lt.setHead(100)
+	lt.SetProcessed(90)
+	r.Equal(int64(8), lt.Lag())
+	r.False(lt.CatchingUp())
+
+	lt.setHead(120)
+	r.Equal(int64(28), lt.Lag())
+	r.True(lt.CatchingUp())
+	traces, err := traceClient.TraceBlock(context.Background(), big.NewInt(91))
+	r.NoError(err)
+	r.Empty(traces)
+
+	// stays in the catch-up mode until the lag is under half of the max lag
+	lt.SetProcessed(110)
+	r.True(lt.CatchingUp())
+	lt.SetProcessed(114)
+	r.False(lt.CatchingUp())
+	traces, err = traceClient.TraceBlock(context.Background(), big.NewInt(115))
+	r.NoError(err)
+	r.Len(traces, 1)
+
+	r.Equal([]bool{true, false}, changes)
+}
+
+func TestChainLagTracker_Disabled(t *testing.T) {
+	r := require.New(t)
+
+	lt := NewChainLagTracker(context.Background(), nil, 0, config.CatchUpConfig{MaxLag: 10})
+	lt.setHead(100)
+	lt.SetProcessed(50)
+	r.Equal(int64(50), lt.Lag())
+	r.False(lt.CatchingUp())
+}
@@ -0,0 +1,206 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxCheckpointPendingBlocks is the number of blocks which the checkpoint waits for an incomplete block. The
+// transactions which the tx feed skips as duplicates are never handled, so their blocks are never completed.
+const maxCheckpointPendingBlocks = 100
+
+// maxCheckpointPendingAge is how long the checkpoint waits for the transactions of a block. The transactions
+// which are dropped before they are handled never complete their blocks.
+var maxCheckpointPendingAge = time.Minute * 10
+
+// BlockCheckpoint keeps the last block of which all transactions were handled, so that the scanning can
+// resume from that block after a restart.
+type BlockCheckpoint struct {
+	store store.StringStore
+
+	pending map[uint64]*pendingBlock
+	order   []uint64
+	last    uint64
+	saved   uint64
+	mu      sync.Mutex
+
+	lastSave health.TimeTracker
+	lastErr  health.ErrorTracker
+}
+
+type pendingBlock struct {
+	txs     int
+	started bool
+	updated time.Time
+}
+
+// Load reads the last processed block from the store.
+func (bc *BlockCheckpoint) Load() (uint64, bool) {
+	s, err := bc.store.Get()
+	if err != nil || len(s) == 0 {
+		return 0, false
+	}
+	blockNumber, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		log.WithError(err).Warn("invalid block checkpoint")
+		return 0, false
+	}
+	bc.mu.Lock()
+	bc.last = blockNumber
+	bc.saved = blockNumber
+	bc.mu.Unlock()
+	return blockNumber, true
+}
+
+// StartBlock returns the block which the block feed should start from to analyze the blocks after the checkpoint.
+// If the checkpoint is more than maxBackfill blocks behind the analyzed head, the feed starts maxBackfill blocks
+// behind. The offset is the number of blocks which the block feed waits for before analyzing a block.
+func StartBlock(checkpoint, head uint64, offset int, maxBackfill uint64) uint64 {
+	analyzedHead := head - uint64(offset)
+	if head < uint64(offset) {
+		analyzedHead = 0
+	}
+	start := checkpoint + 1
+	if analyzedHead > maxBackfill && start < analyzedHead-maxBackfill {
+		start = analyzedHead - maxBackfill
+	}
+	if start > analyzedHead {
+		start = analyzedHead
+	}
+	return start + uint64(offset)
+}
+
+func (bc *BlockCheckpoint) getPending(number uint64) *pendingBlock {
+	block, ok := bc.pending[number]
+	if !ok {
+		block = &pendingBlock{}
+		bc.pending[number] = block
+	}
+	block.updated = time.Now()
+	return block
+}
+
+// isPassed tells if the checkpoint has already moved past the block.
+func (bc *BlockCheckpoint) isPassed(number uint64) bool {
+	return bc.last > 0 && number <= bc.last
+}
+
+// BlockStarted adds a block which has the given number of transactions to handle. The blocks which were
+// already started or passed are ignored.
+func (bc *BlockCheckpoint) BlockStarted(number uint64, txCount int) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.isPassed(number) {
+		return
+	}
+	if block, ok := bc.pending[number]; ok && block.started {
+		log.WithField("block", number).Debug("block was already started - ignoring for the checkpoint")
+		return
+	}
+	// the transactions can be handled before the block handler is called
+	block := bc.getPending(number)
+	block.txs += txCount
+	block.started = true
+	bc.order = append(bc.order, number)
+	bc.advance()
+}
+
+// TxDone marks a transaction of the block as handled.
+func (bc *BlockCheckpoint) TxDone(number uint64) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if bc.isPassed(number) {
+		return
+	}
+	bc.getPending(number).txs--
+	bc.advance()
+}
+
+// advance moves the checkpoint to the last block which has no pending blocks before it. The blocks which
+// wait too long for their transactions are expired.
+func (bc *BlockCheckpoint) advance() {
+	for len(bc.order) > 0 {
+		number := bc.order[0]
+		block := bc.pending[number]
+		expired := len(bc.order) > maxCheckpointPendingBlocks || time.Since(block.updated) > maxCheckpointPendingAge
+		if block.txs > 0 && !expired {
+			break
+		}
+		if block.txs > 0 {
+			log.WithField("block", number).Warn("block has unhandled transactions - moving the checkpoint")
+		}
+		bc.last = number
+		bc.order = bc.order[1:]
+		delete(bc.pending, number)
+	}
+	// the transactions of the blocks which are never started
+	for number, block := range bc.pending {
+		if !block.started && (bc.isPassed(number) || time.Since(block.updated) > maxCheckpointPendingAge) {
+			delete(bc.pending, number)
+		}
+	}
+}
+
+// Last returns the last processed block.
+func (bc *BlockCheckpoint) Last() uint64 {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return bc.last
+}
+
+// Save writes the last processed block to the store if it has changed.
+func (bc *BlockCheckpoint) Save() error {
+	bc.mu.Lock()
+	last, saved := bc.last, bc.saved
+	bc.mu.Unlock()
+	if last == saved {
+		return nil
+	}
+	err := bc.store.Put(strconv.FormatUint(last, 10))
+	bc.lastErr.Set(err)
+	if err != nil {
+		return fmt.Errorf("failed to save the block checkpoint: %v", err)
+	}
+	bc.mu.Lock()
+	bc.saved = last
+	bc.mu.Unlock()
+	bc.lastSave.Set()
+	return nil
+}
+
+func (bc *BlockCheckpoint) saveLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := bc.Save(); err != nil {
+				log.WithError(err).Warn("failed to save the block checkpoint")
+			}
+		}
+	}
+}
+
+// Health implements the health.Reporter interface.
+func (bc *BlockCheckpoint) Health() health.Reports {
+	return health.Reports{
+		&health.Report{Name: "checkpoint.block", Status: health.StatusInfo, Details: strconv.FormatUint(bc.Last(), 10)},
+		bc.lastSave.GetReport("checkpoint.save.time"),
+		bc.lastErr.GetReport("checkpoint.save.error"),
+	}
+}
+
+// NewBlockCheckpoint creates a new checkpoint which is kept in the store.
+func NewBlockCheckpoint(store store.StringStore) *BlockCheckpoint {
+	return &BlockCheckpoint{store: store, pending: make(map[uint64]*pendingBlock)}
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"context"
+	"math/big"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockCheckpoint(t *testing.T) {
+	r := require.New(t)
+
+	checkpointStore := store.NewFileStringStore(path.Join(t.TempDir(), ".last-block-1"))
+	bc := NewBlockCheckpoint(checkpointStore)
+	_, ok := bc.Load()
+	r.False(ok)
+
+	bc.BlockStarted(10, 2)
+	bc.BlockStarted(11, 0)
+	r.Zero(bc.Last())
+
+	// a tx of the next block is handled before its block handler is called
+	bc.TxDone(12)
+	bc.TxDone(10)
+	bc.TxDone(10)
+	r.Equal(uint64(11), bc.Last())
+	bc.BlockStarted(12, 1)
+	r.Equal(uint64(12), bc.Last())
+
+	r.NoError(bc.Save())
+	loaded := NewBlockCheckpoint(checkpointStore)
+	last, ok := loaded.Load()
+	r.True(ok)
+	r.Equal(uint64(12), last)
+}
+
+func TestBlockCheckpoint_Incomplete(t *testing.T) {
+	r := require.New(t)
+
+	bc := NewBlockCheckpoint(store.NewFileStringStore(path.Join(t.TempDir(), ".last-block-1")))
+	bc.BlockStarted(1, 1)
+	for i := uint64(2); i <= maxCheckpointPendingBlocks; i++ {
+		bc.BlockStarted(i, 0)
+	}
+	r.Zero(bc.Last())
+
+	// the incomplete block is not waited for forever
+	bc.BlockStarted(maxCheckpointPendingBlocks+1, 0)
+	r.Equal(uint64(maxCheckpointPendingBlocks+1), bc.Last())
+}
+
+func TestBlockCheckpoint_DuplicateBlock(t *testing.T) {
+	r := require.New(t)
+
+	bc := NewBlockCheckpoint(store.NewFileStringStore(path.Join(t.TempDir(), ".last-block-1")))
+	bc.BlockStarted(10, 1)
+	bc.BlockStarted(10, 1)
+	bc.TxDone(10)
+	r.Equal(uint64(10), bc.Last())
+
+	// the blocks which the checkpoint passed do not move it back
+	r.NotPanics(func() {
+		bc.BlockStarted(10, 1)
+		bc.BlockStarted(9, 0)
+		bc.TxDone(10)
+	})
+	r.Equal(uint64(10), bc.Last())
+	r.Empty(bc.pending)
+	bc.BlockStarted(11, 0)
+	r.Equal(uint64(11), bc.Last())
+}
+
+func TestBlockCheckpoint_DroppedTx(t *testing.T) {
+	r := require.New(t)
+
+	maxAge := maxCheckpointPendingAge
+	maxCheckpointPendingAge = time.Millisecond * 10
+	defer func() {
+		maxCheckpointPendingAge = maxAge
+	}()
+
+	bc := NewBlockCheckpoint(store.NewFileStringStore(path.Join(t.TempDir(), ".last-block-1")))
+	bc.BlockStarted(1, 2)
+	bc.TxDone(1)
+	// a tx of a block which is never started
+	bc.TxDone(5)
+	bc.BlockStarted(2, 0)
+	r.Zero(bc.Last())
+
+	// the dropped tx is expired
+	time.Sleep(maxCheckpointPendingAge * 2)
+	bc.BlockStarted(3, 0)
+	r.Equal(uint64(3), bc.Last())
+	r.Empty(bc.pending)
+}
+
+type checkpointAgentPool struct {
+	AgentPool
+	checkpoint *BlockCheckpoint
+	lastOnSend uint64
+}
+
+func (pool *checkpointAgentPool) SendEvaluateTxRequest(req *protocol.EvaluateTxRequest) {
+	pool.lastOnSend = pool.checkpoint.Last()
+}
+
+func TestTxAnalyzer_Checkpoint(t *testing.T) {
+	r := require.New(t)
+
+	bc := NewBlockCheckpoint(store.NewFileStringStore(path.Join(t.TempDir(), ".last-block-1")))
+	pool := &checkpointAgentPool{checkpoint: bc}
+	analyzer, err := NewTxAnalyzerService(context.Background(), TxAnalyzerServiceConfig{
+		AgentPool:  pool,
+		Checkpoint: bc,
+	})
+	r.NoError(err)
+
+	bc.BlockStarted(10, 1)
+	analyzer.analyzeTx(&domain.TransactionEvent{
+		BlockEvt: &domain.BlockEvent{
+			ChainID: big.NewInt(1),
+			Block:   &domain.Block{Number: "0xa", Hash: "0xbeef", Timestamp: "0x1"},
+		},
+		Transaction: &domain.Transaction{Hash: "0xcafe", From: "0x1"},
+		Timestamps:  &domain.TrackingTimestamps{},
+	})
+	// the tx is done only after it is sent to the agents
+	r.Zero(pool.lastOnSend)
+	r.Equal(uint64(10), bc.Last())
+}
+
+func TestStartBlock(t *testing.T) {
+	r := require.New(t)
+
+	// resumes after the checkpoint
+	r.Equal(uint64(101), StartBlock(100, 110, 0, 1000))
+	// the feed waits for the offset
+	r.Equal(uint64(104), StartBlock(100, 110, 3, 1000))
+	// backfills max 5 blocks
+	r.Equal(uint64(105), StartBlock(50, 110, 0, 5))
+	// the checkpoint is ahead of the head
+	r.Equal(uint64(110), StartBlock(200, 110, 0, 5))
+}
@@ -0,0 +1,242 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/ethereum"
+	"github.com/forta-network/forta-core-go/feeds"
+	"github.com/forta-network/forta-core-go/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Data source types
+const (
+	DataSourceRPC  = "rpc"
+	DataSourceFile = "file"
+)
+
+// maxFileBlockSize is the max size of a block line in a data source file.
+const maxFileBlockSize = 64 * 1024 * 1024
+
+// DataSource provides the blocks which the scanner analyzes. The transaction, block and log streams are
+// fed from the block events, so a data source provides every block with its traces and logs.
+type DataSource interface {
+	feeds.BlockFeed
+}
+
+// NewRPCDataSource creates a data source which reads the blocks from the JSON-RPC providers.
+func NewRPCDataSource(ctx context.Context, client, traceClient ethereum.Client, cfg feeds.BlockFeedConfig) (DataSource, error) {
+	return feeds.NewBlockFeed(ctx, client, traceClient, cfg)
+}
+
+// FileBlock is a block in a data source file. Every line of the file is a block.
+type FileBlock struct {
+	Block  *domain.Block     `json:"block"`
+	Traces []domain.Trace    `json:"traces,omitempty"`
+	Logs   []domain.LogEntry `json:"logs,omitempty"`
+}
+
+// FileDataSource reads the blocks from a file, so that the agents can be run against recorded chain data
+// without a JSON-RPC provider.
+type FileDataSource struct {
+	ctx       context.Context
+	path      string
+	chainID   *big.Int
+	rateLimit *time.Ticker
+
+	start, end *big.Int
+	started    bool
+	handlers   []fileBlockHandler
+	mu         sync.RWMutex
+
+	lastBlock health.MessageTracker
+}
+
+type fileBlockHandler struct {
+	handler func(evt *domain.BlockEvent) error
+	errCh   chan<- error
+}
+
+// Start starts reading the blocks.
+func (fs *FileDataSource) Start() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.started {
+		return
+	}
+	fs.started = true
+	go fs.loop()
+}
+
+// StartRange starts reading the blocks in the range.
+func (fs *FileDataSource) StartRange(start int64, end int64, rate int64) {
+	fs.mu.Lock()
+	if rate > 0 {
+		fs.rateLimit = time.NewTicker(time.Duration(rate) * time.Millisecond)
+	}
+	fs.start = big.NewInt(start)
+	fs.end = big.NewInt(end)
+	fs.mu.Unlock()
+	fs.Start()
+}
+
+// IsStarted tells if the data source is started.
+func (fs *FileDataSource) IsStarted() bool {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.started
+}
+
+// Subscribe adds a handler for the block events.
+func (fs *FileDataSource) Subscribe(handler func(evt *domain.BlockEvent) error) <-chan error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	errCh := make(chan error, 1)
+	fs.handlers = append(fs.handlers, fileBlockHandler{handler: handler, errCh: errCh})
+	return errCh
+}
+
+func (fs *FileDataSource) loop() {
+	err := fs.forEachBlock()
+	if err == nil {
+		err = feeds.ErrEndBlockReached
+	}
+	if err != feeds.ErrEndBlockReached {
+		log.WithError(err).Warn("failed while reading blocks from file")
+	}
+	fs.mu.RLock()
+	handlers := fs.handlers
+	fs.mu.RUnlock()
+	for _, handler := range handlers {
+		handler.errCh <- err
+	}
+}
+
+func (fs *FileDataSource) forEachBlock() error {
+	f, err := os.Open(fs.path)
+	if err != nil {
+		return fmt.Errorf("failed to open the data source file: %v", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReaderSize(f, 64*1024)
+	for {
+		if fs.ctx.Err() != nil {
+			return fs.ctx.Err()
+		}
+		line, err := readLine(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		var fileBlock FileBlock
+		if err := json.Unmarshal(line, &fileBlock); err != nil {
+			return fmt.Errorf("invalid block in the data source file: %v", err)
+		}
+		if fileBlock.Block == nil {
+			continue
+		}
+		blockNum, err := utils.HexToBigInt(fileBlock.Block.Number)
+		if err != nil {
+			return fmt.Errorf("invalid block number in the data source file: %v", err)
+		}
+		fs.mu.RLock()
+		start, end, rateLimit := fs.start, fs.end, fs.rateLimit
+		fs.mu.RUnlock()
+		if start != nil && blockNum.Cmp(start) < 0 {
+			continue
+		}
+		if end != nil && blockNum.Cmp(end) > 0 {
+			return feeds.ErrEndBlockReached
+		}
+		if rateLimit != nil {
+			<-rateLimit.C
+		}
+		if err := fs.handleBlock(&fileBlock); err != nil {
+			return err
+		}
+		fs.lastBlock.Set(blockNum.String())
+	}
+}
+
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		part, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, part...)
+		if len(line) > maxFileBlockSize {
+			return nil, fmt.Errorf("block is larger than %d bytes", maxFileBlockSize)
+		}
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
+
+func (fs *FileDataSource) handleBlock(fileBlock *FileBlock) error {
+	evt := &domain.BlockEvent{
+		EventType: domain.EventTypeBlock,
+		Block:     fileBlock.Block,
+		ChainID:   fs.chainID,
+		Traces:    fileBlock.Traces,
+		Logs:      fileBlock.Logs,
+		Timestamps: &domain.TrackingTimestamps{
+			Feed: time.Now().UTC(),
+		},
+	}
+	if blockTs, err := fileBlock.Block.GetTimestamp(); err == nil {
+		evt.Timestamps.Block = *blockTs
+	}
+	fs.mu.RLock()
+	handlers := fs.handlers
+	fs.mu.RUnlock()
+	for _, handler := range handlers {
+		if err := handler.handler(evt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the data source. The name is the same with the RPC block feed so that
+// the health reports are the same.
+func (fs *FileDataSource) Name() string {
+	return "block-feed"
+}
+
+// Health implements the health.Reporter interface.
+func (fs *FileDataSource) Health() health.Reports {
+	return health.Reports{
+		fs.lastBlock.GetReport("last-block"),
+	}
+}
+
+// NewFileDataSource creates a new data source which reads the blocks from the file. The blocks in the file
+// should be in order.
+func NewFileDataSource(ctx context.Context, path string, chainID *big.Int, rateLimit *time.Ticker) *FileDataSource {
+	return &FileDataSource{
+		ctx:       ctx,
+		path:      path,
+		chainID:   chainID,
+		rateLimit: rateLimit,
+	}
+}
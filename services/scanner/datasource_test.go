@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/feeds"
+	"github.com/stretchr/testify/require"
+)
+
+func writeBlocksFile(t *testing.T, blockNums ...string) string {
+	var data []byte
+	for _, blockNum := range blockNums {
+		txHash := "0x" + blockNum[2:] + "aa"
+		b, err := json.Marshal(&FileBlock{
+			Block: &domain.Block{
+				Number:       blockNum,
+				Timestamp:    "0x5",
+				Transactions: []domain.Transaction{{Hash: txHash}},
+			},
+			Logs: []domain.LogEntry{{TransactionHash: &txHash}},
+		})
+		require.NoError(t, err)
+		data = append(data, b...)
+		data = append(data, '\n')
+	}
+	filePath := path.Join(t.TempDir(), "blocks.jsonl")
+	require.NoError(t, ioutil.WriteFile(filePath, data, 0644))
+	return filePath
+}
+
+func TestFileDataSource(t *testing.T) {
+	r := require.New(t)
+
+	ds := NewFileDataSource(context.Background(), writeBlocksFile(t, "0x1", "0x2", "0x3"), big.NewInt(1), nil)
+	var blocks []string
+	errCh := ds.Subscribe(func(evt *domain.BlockEvent) error {
+		r.Equal(int64(1), evt.ChainID.Int64())
+		r.Len(evt.Logs, 1)
+		blocks = append(blocks, evt.Block.Number)
+		return nil
+	})
+	ds.Start()
+	r.Equal(feeds.ErrEndBlockReached, <-errCh)
+	r.Equal([]string{"0x1", "0x2", "0x3"}, blocks)
+	r.Equal("3", ds.Health()[0].Details)
+}
+
+func TestFileDataSource_Range(t *testing.T) {
+	r := require.New(t)
+
+	ds := NewFileDataSource(context.Background(), writeBlocksFile(t, "0x1", "0x2", "0x3", "0x4"), big.NewInt(1), nil)
+	var blocks []string
+	errCh := ds.Subscribe(func(evt *domain.BlockEvent) error {
+		blocks = append(blocks, evt.Block.Number)
+		return nil
+	})
+	ds.StartRange(2, 3, 1)
+	r.Equal(feeds.ErrEndBlockReached, <-errCh)
+	r.Equal([]string{"0x2", "0x3"}, blocks)
+}
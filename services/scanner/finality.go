@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/ethereum"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type rpcCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// FinalizedClient makes the block feed process only the finalized blocks. The latest block number is
+// the number of the latest finalized block and the blocks after it are returned only after they are
+// finalized.
+type FinalizedClient struct {
+	ethereum.Client
+	rpcClient    rpcCaller
+	pollInterval time.Duration
+
+	finalized   *big.Int
+	finalizedMu sync.RWMutex
+}
+
+type finalizedHeader struct {
+	Number *hexutil.Big `json:"number"`
+}
+
+func (fc *FinalizedClient) getFinalized(ctx context.Context) (*big.Int, error) {
+	var header finalizedHeader
+	if err := fc.rpcClient.CallContext(ctx, &header, "eth_getBlockByNumber", "finalized", false); err != nil {
+		return nil, err
+	}
+	if header.Number == nil {
+		return nil, ethereum.ErrNotFound
+	}
+	finalized := header.Number.ToInt()
+	fc.finalizedMu.Lock()
+	fc.finalized = finalized
+	fc.finalizedMu.Unlock()
+	return finalized, nil
+}
+
+func (fc *FinalizedClient) isFinalized(number *big.Int) bool {
+	fc.finalizedMu.RLock()
+	defer fc.finalizedMu.RUnlock()
+	return fc.finalized != nil && number.Cmp(fc.finalized) <= 0
+}
+
+// waitForFinalized waits until the block with the given number is finalized.
+func (fc *FinalizedClient) waitForFinalized(ctx context.Context, number *big.Int) error {
+	for !fc.isFinalized(number) {
+		if _, err := fc.getFinalized(ctx); err != nil {
+			log.WithError(err).Warn("failed to get the finalized block")
+		}
+		if fc.isFinalized(number) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(fc.pollInterval):
+		}
+	}
+	return nil
+}
+
+// BlockNumber returns the number of the latest finalized block.
+func (fc *FinalizedClient) BlockNumber(ctx context.Context) (*big.Int, error) {
+	return fc.getFinalized(ctx)
+}
+
+// BlockByNumber returns the block after it is finalized. The latest finalized block is returned if
+// the number is nil.
+func (fc *FinalizedClient) BlockByNumber(ctx context.Context, number *big.Int) (*domain.Block, error) {
+	if number == nil {
+		finalized, err := fc.getFinalized(ctx)
+		if err != nil {
+			return nil, err
+		}
+		number = finalized
+	}
+	if err := fc.waitForFinalized(ctx, number); err != nil {
+		return nil, err
+	}
+	return fc.Client.BlockByNumber(ctx, number)
+}
+
+// NewFinalizedClient creates a new client which returns only the finalized blocks.
+func NewFinalizedClient(client ethereum.Client, rpcClient rpcCaller, pollInterval time.Duration) *FinalizedClient {
+	return &FinalizedClient{
+		Client:       client,
+		rpcClient:    rpcClient,
+		pollInterval: pollInterval,
+	}
+}
@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/ethereum"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFinalizedRPC struct {
+	mu        sync.Mutex
+	finalized int64
+}
+
+func (f *fakeFinalizedRPC) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result.(*finalizedHeader).Number = (*hexutil.Big)(big.NewInt(f.finalized))
+	f.finalized++
+	return nil
+}
+
+type fakeBlockClient struct {
+	ethereum.Client
+}
+
+func (c *fakeBlockClient) BlockByNumber(ctx context.Context, number *big.Int) (*domain.Block, error) {
+	return &domain.Block{Number: hexutil.EncodeBig(number)}, nil
+}
+
+func TestFinalizedClient(t *testing.T) {
+	r := require.New(t)
+
+	rpcClient := &fakeFinalizedRPC{finalized: 10}
+	fc := NewFinalizedClient(&fakeBlockClient{}, rpcClient, time.Millisecond)
+
+	blockNum, err := fc.BlockNumber(context.Background())
+	r.NoError(err)
+	r.Equal(int64(10), blockNum.Int64())
+
+	// waits until block 13 is finalized
+	block, err := fc.BlockByNumber(context.Background(), big.NewInt(13))
+	r.NoError(err)
+	r.Equal("0xd", block.Number)
+	r.True(fc.isFinalized(big.NewInt(13)))
+	r.False(fc.isFinalized(big.NewInt(14)))
+
+	// does not wait for the finalized blocks
+	block, err = fc.BlockByNumber(context.Background(), big.NewInt(12))
+	r.NoError(err)
+	r.Equal("0xc", block.Number)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = fc.BlockByNumber(ctx, big.NewInt(100))
+	r.Error(err)
+}
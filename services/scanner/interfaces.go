@@ -26,6 +26,13 @@ type BlockResult struct {
 // to and receive the results from.
 type AgentPool interface {
 	SendEvaluateTxRequest(req *protocol.EvaluateTxRequest)
+	SendEvaluateLogRequest(req *protocol.EvaluateTxRequest)
+	LogFilters(chainID int64) []config.LogFilter
+	SendEvaluateUserOpRequest(req *protocol.EvaluateTxRequest)
+	HasUserOpAgents() bool
+	SendEvaluatePendingTxRequest(req *protocol.EvaluateTxRequest, latestBlock uint64)
+	SendEvaluateCanaryRequest(agentID string, req *protocol.EvaluateTxRequest) bool
+	PendingTxResults() <-chan *TxResult
 	TxResults() <-chan *TxResult
 	SendEvaluateBlockRequest(req *protocol.EvaluateBlockRequest)
 	BlockResults() <-chan *BlockResult
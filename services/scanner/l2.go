@@ -0,0 +1,127 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// L2InfoFieldNumber is the field number of the L2 info in the transaction events. The field is not in the
+// protocol definitions yet, so it is sent as an extension field which the agents can read by adding the
+// L2Info message below to their definitions:
+//
+//	message L2Info {
+//	  string type = 1;
+//	  bool isSystemTx = 2;
+//	  string l1Fee = 3;
+//	  string l1GasUsed = 4;
+//	  string l1GasPrice = 5;
+//	  string l1FeeScalar = 6;
+//	  string gasUsedForL1 = 7;
+//	  string l1BlockNumber = 8;
+//	  string txType = 9;
+//	}
+//
+//	L2Info l2 = 1000; // in TransactionEvent
+const L2InfoFieldNumber = 1000
+
+// The senders and the types of the system transactions.
+const (
+	optimismSystemSender = "0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001"
+	optimismDepositType  = "0x7e"
+	arbitrumSystemSender = "0x00000000000000000000000000000000000a4b05"
+	arbitrumInternalType = "0x6a"
+)
+
+// L2Info is the L2 specific info of a transaction.
+type L2Info struct {
+	L2Type     string
+	IsSystemTx bool
+	L2Fields
+}
+
+// L2Enricher adds the L2 specific info of the transactions to the transaction events, so that the agents
+// can tell the system transactions apart and see the L1 costs.
+type L2Enricher struct {
+	l2Type   string
+	receipts *ReceiptFetcher
+}
+
+// GetInfo returns the L2 info of the transaction. The receipt fields are added only if the receipts are fetched.
+func (e *L2Enricher) GetInfo(ctx context.Context, evt *domain.TransactionEvent) *L2Info {
+	info := &L2Info{L2Type: e.l2Type}
+	if e.receipts != nil {
+		fields, err := e.receipts.GetL2Fields(ctx, evt.BlockEvt.Block, evt.Transaction.Hash)
+		if err != nil {
+			log.WithError(err).WithField("tx", evt.Transaction.Hash).Warn("failed to get the l2 fields")
+		} else {
+			info.L2Fields = *fields
+		}
+	}
+	info.IsSystemTx = isSystemTx(e.l2Type, evt.Transaction.From, info.L2Fields.Type)
+	return info
+}
+
+func isSystemTx(l2Type, from string, txType *string) bool {
+	from = strings.ToLower(from)
+	switch l2Type {
+	case config.L2Optimism:
+		return from == optimismSystemSender || (txType != nil && strings.ToLower(*txType) == optimismDepositType)
+	case config.L2Arbitrum:
+		return from == arbitrumSystemSender || (txType != nil && strings.ToLower(*txType) == arbitrumInternalType)
+	}
+	return false
+}
+
+// Enrich adds the L2 info of the transaction to the message.
+func (e *L2Enricher) Enrich(ctx context.Context, msg *protocol.TransactionEvent, evt *domain.TransactionEvent) {
+	setL2Info(msg, e.GetInfo(ctx, evt))
+}
+
+func appendStringField(b []byte, num protowire.Number, s *string) []byte {
+	if s == nil || len(*s) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, *s)
+}
+
+// setL2Info adds the L2 info to the message as an extension field.
+func setL2Info(msg *protocol.TransactionEvent, info *L2Info) {
+	var b []byte
+	b = appendStringField(b, 1, &info.L2Type)
+	if info.IsSystemTx {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	b = appendStringField(b, 3, info.L1Fee)
+	b = appendStringField(b, 4, info.L1GasUsed)
+	b = appendStringField(b, 5, info.L1GasPrice)
+	b = appendStringField(b, 6, info.L1FeeScalar)
+	b = appendStringField(b, 7, info.GasUsedForL1)
+	b = appendStringField(b, 8, info.L1BlockNumber)
+	b = appendStringField(b, 9, info.L2Fields.Type)
+
+	appendExtensionField(msg, L2InfoFieldNumber, b)
+}
+
+// appendExtensionField adds an encoded message to the transaction event as an unknown field so that it is
+// sent to the agents without changing the protocol definitions.
+func appendExtensionField(msg *protocol.TransactionEvent, num protowire.Number, b []byte) {
+	m := msg.ProtoReflect()
+	unknown := m.GetUnknown()
+	unknown = protowire.AppendTag(unknown, num, protowire.BytesType)
+	unknown = protowire.AppendBytes(unknown, b)
+	m.SetUnknown(unknown)
+}
+
+// NewL2Enricher creates a new enricher for the L2 type. The receipt fetcher is optional.
+func NewL2Enricher(l2Type string, receipts *ReceiptFetcher) *L2Enricher {
+	return &L2Enricher{l2Type: l2Type, receipts: receipts}
+}
@@ -0,0 +1,61 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestIsSystemTx(t *testing.T) {
+	r := require.New(t)
+
+	r.True(isSystemTx(config.L2Optimism, "0xDeaDDEaDDeAdDeAdDEAdDEaddeAddEAdDEAd0001", nil))
+	r.True(isSystemTx(config.L2Optimism, "0x1", strPtr("0x7E")))
+	r.False(isSystemTx(config.L2Optimism, "0x1", strPtr("0x2")))
+	r.True(isSystemTx(config.L2Arbitrum, arbitrumSystemSender, nil))
+	r.True(isSystemTx(config.L2Arbitrum, "0x1", strPtr("0x6a")))
+	r.False(isSystemTx(config.L2Arbitrum, "0x1", strPtr("0x7e")))
+	r.False(isSystemTx("", optimismSystemSender, nil))
+}
+
+func TestSetL2Info(t *testing.T) {
+	r := require.New(t)
+
+	msg := &protocol.TransactionEvent{}
+	setL2Info(msg, &L2Info{
+		L2Type:     config.L2Optimism,
+		IsSystemTx: true,
+		L2Fields:   L2Fields{L1Fee: strPtr("0x10")},
+	})
+
+	unknown := msg.ProtoReflect().GetUnknown()
+	num, typ, n := protowire.ConsumeTag(unknown)
+	r.Equal(protowire.Number(L2InfoFieldNumber), num)
+	r.Equal(protowire.BytesType, typ)
+	info, m := protowire.ConsumeBytes(unknown[n:])
+	r.Equal(len(unknown), n+m)
+
+	fields := make(map[protowire.Number][]byte)
+	for len(info) > 0 {
+		num, typ, n := protowire.ConsumeTag(info)
+		r.Greater(n, 0)
+		m := protowire.ConsumeFieldValue(num, typ, info[n:])
+		r.Greater(m, 0)
+		fields[num] = info[n : n+m]
+		info = info[n+m:]
+	}
+	r.Len(fields, 3)
+	typeName, _ := protowire.ConsumeString(fields[1])
+	r.Equal(config.L2Optimism, typeName)
+	isSystemTx, _ := protowire.ConsumeVarint(fields[2])
+	r.Equal(uint64(1), isSystemTx)
+	l1Fee, _ := protowire.ConsumeString(fields[3])
+	r.Equal("0x10", l1Fee)
+}
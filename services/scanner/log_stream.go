@@ -0,0 +1,276 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	fortaeth "github.com/forta-network/forta-core-go/ethereum"
+	"github.com/forta-network/forta-core-go/feeds"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const logStreamBlockBufferSize = 10
+
+// getLogsAttempts is how many times the logs of a block are requested before the block is skipped.
+const getLogsAttempts = 5
+
+// getLogsRetryInterval is the wait before the first retry and it doubles with each retry.
+var getLogsRetryInterval = time.Second
+
+// LogStreamService fetches the logs which match the filters declared by the agents and
+// delivers them per transaction to the agents which subscribe to logs, so that these agents
+// don't need to receive every transaction.
+type LogStreamService struct {
+	ctx     context.Context
+	cfg     LogStreamServiceConfig
+	blockCh chan *domain.BlockEvent
+
+	lastBlockActivity health.TimeTracker
+	lastLogActivity   health.TimeTracker
+	lastErr           health.ErrorTracker
+}
+
+// LogStreamServiceConfig configures the log stream of a chain. Only the filters of the agents which
+// run on the chain are requested.
+type LogStreamServiceConfig struct {
+	ChainID   int64
+	EthClient fortaeth.Client
+	BlockFeed DataSource
+	AgentPool AgentPool
+}
+
+func (l *LogStreamService) handleBlock(evt *domain.BlockEvent) error {
+	select {
+	case <-l.ctx.Done():
+		return l.ctx.Err()
+	case l.blockCh <- evt:
+	}
+	return nil
+}
+
+func (l *LogStreamService) processBlocks() {
+	for evt := range l.blockCh {
+		l.lastBlockActivity.Set()
+		filters := l.cfg.AgentPool.LogFilters(l.cfg.ChainID)
+		if len(filters) == 0 {
+			continue
+		}
+		logs, err := l.getLogsWithRetry(evt, filters)
+		l.lastErr.Set(err)
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{
+				"chainId": l.cfg.ChainID,
+				"block":   evt.Block.Number,
+			}).Error("failed to get logs for agents - skipping the block")
+			continue
+		}
+		for _, txEvt := range groupLogsByTx(evt, logs) {
+			msg, err := txEvt.ToMessage()
+			if err != nil {
+				log.WithError(err).Error("error converting log event to message (skipping)")
+				continue
+			}
+			requestId := uuid.Must(uuid.NewUUID())
+			l.cfg.AgentPool.SendEvaluateLogRequest(&protocol.EvaluateTxRequest{RequestId: requestId.String(), Event: msg})
+			l.lastLogActivity.Set()
+		}
+	}
+}
+
+// getLogsWithRetry retries the failed log requests of the block with an increasing interval.
+func (l *LogStreamService) getLogsWithRetry(evt *domain.BlockEvent, filters []config.LogFilter) ([]domain.LogEntry, error) {
+	interval := getLogsRetryInterval
+	for attempt := 1; ; attempt++ {
+		logs, err := l.getLogs(evt, filters)
+		if err == nil || attempt == getLogsAttempts {
+			return logs, err
+		}
+		log.WithError(err).WithFields(log.Fields{
+			"chainId": l.cfg.ChainID,
+			"block":   evt.Block.Number,
+			"attempt": attempt,
+		}).Warn("failed to get logs for agents - retrying")
+		select {
+		case <-l.ctx.Done():
+			return nil, l.ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+}
+
+// getLogs requests the logs of the block which match the filters. Without a client, the logs of the
+// block event are used.
+func (l *LogStreamService) getLogs(evt *domain.BlockEvent, filters []config.LogFilter) ([]domain.LogEntry, error) {
+	if l.cfg.EthClient == nil {
+		return evt.Logs, nil
+	}
+	blockNum, err := utils.HexToBigInt(evt.Block.Number)
+	if err != nil {
+		return nil, err
+	}
+	q := unionFilterQuery(filters)
+	q.FromBlock = blockNum
+	q.ToBlock = blockNum
+	logs, err := l.cfg.EthClient.GetLogs(l.ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return toLogEntries(logs)
+}
+
+// unionFilterQuery returns a query which selects the logs of all filters. Only the addresses and
+// the first topics are combined so that a single eth_getLogs call is enough. The logs are matched
+// with the filters of each agent before they are sent.
+func unionFilterQuery(filters []config.LogFilter) (q ethereum.FilterQuery) {
+	var (
+		addrs     []common.Address
+		topics    []common.Hash
+		anyAddr   bool
+		anyTopic  bool
+		seenAddr  = make(map[common.Address]bool)
+		seenTopic = make(map[common.Hash]bool)
+	)
+	for _, filter := range filters {
+		if len(filter.Addresses) == 0 {
+			anyAddr = true
+		}
+		for _, addrStr := range filter.Addresses {
+			addr := common.HexToAddress(addrStr)
+			if !seenAddr[addr] {
+				seenAddr[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+		if len(filter.Topics) == 0 || len(filter.Topics[0]) == 0 {
+			anyTopic = true
+		}
+		if len(filter.Topics) > 0 {
+			for _, topicStr := range filter.Topics[0] {
+				topic := common.HexToHash(topicStr)
+				if !seenTopic[topic] {
+					seenTopic[topic] = true
+					topics = append(topics, topic)
+				}
+			}
+		}
+	}
+	if !anyAddr {
+		q.Addresses = addrs
+	}
+	if !anyTopic {
+		q.Topics = [][]common.Hash{topics}
+	}
+	return
+}
+
+func toLogEntries(logs []types.Log) ([]domain.LogEntry, error) {
+	var logEntries []domain.LogEntry
+	b, err := json.Marshal(logs)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &logEntries); err != nil {
+		return nil, err
+	}
+	return logEntries, nil
+}
+
+// groupLogsByTx creates a transaction event for each transaction which emitted the logs. The events
+// contain only the logs and the fields which identify the transaction.
+func groupLogsByTx(blockEvt *domain.BlockEvent, logs []domain.LogEntry) []*domain.TransactionEvent {
+	var (
+		txEvts []*domain.TransactionEvent
+		byHash = make(map[string]*domain.TransactionEvent)
+	)
+	for _, logEntry := range logs {
+		if logEntry.TransactionHash == nil {
+			continue
+		}
+		txHash := strings.ToLower(*logEntry.TransactionHash)
+		txEvt, ok := byHash[txHash]
+		if !ok {
+			txEvt = &domain.TransactionEvent{
+				BlockEvt: &domain.BlockEvent{
+					EventType:  blockEvt.EventType,
+					Block:      blockEvt.Block,
+					ChainID:    blockEvt.ChainID,
+					Timestamps: blockEvt.Timestamps,
+				},
+				Transaction: findTxSummary(blockEvt.Block, txHash),
+				Timestamps: &domain.TrackingTimestamps{
+					Block: blockEvt.Timestamps.Block,
+					Feed:  time.Now().UTC(),
+				},
+			}
+			byHash[txHash] = txEvt
+			txEvts = append(txEvts, txEvt)
+		}
+		txEvt.BlockEvt.Logs = append(txEvt.BlockEvt.Logs, logEntry)
+	}
+	return txEvts
+}
+
+func findTxSummary(block *domain.Block, txHash string) *domain.Transaction {
+	for _, tx := range block.Transactions {
+		if strings.EqualFold(tx.Hash, txHash) {
+			return &domain.Transaction{
+				BlockHash: tx.BlockHash,
+				From:      tx.From,
+				Hash:      tx.Hash,
+				Nonce:     tx.Nonce,
+				To:        tx.To,
+			}
+		}
+	}
+	return &domain.Transaction{BlockHash: block.Hash, Hash: txHash}
+}
+
+func (l *LogStreamService) Start() error {
+	log.Infof("Starting %s", l.Name())
+	go l.processBlocks()
+	go func() {
+		if err := <-l.cfg.BlockFeed.Subscribe(l.handleBlock); err != nil && err != feeds.ErrEndBlockReached {
+			log.WithError(err).Error("log stream block subscription ended")
+		}
+	}()
+	return nil
+}
+
+func (l *LogStreamService) Stop() error {
+	log.Infof("Stopping %s", l.Name())
+	return nil
+}
+
+func (l *LogStreamService) Name() string {
+	return "log-stream"
+}
+
+// Health implements health.Reporter interface.
+func (l *LogStreamService) Health() health.Reports {
+	return health.Reports{
+		l.lastBlockActivity.GetReport("event.block.time"),
+		l.lastLogActivity.GetReport("event.log.time"),
+		l.lastErr.GetReport("event.log.error"),
+	}
+}
+
+func NewLogStreamService(ctx context.Context, cfg LogStreamServiceConfig) (*LogStreamService, error) {
+	return &LogStreamService{
+		ctx:     ctx,
+		cfg:     cfg,
+		blockCh: make(chan *domain.BlockEvent, logStreamBlockBufferSize),
+	}, nil
+}
@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/forta-network/forta-core-go/domain"
+	mock_ethereum "github.com/forta-network/forta-core-go/ethereum/mocks"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnionFilterQuery(t *testing.T) {
+	r := require.New(t)
+
+	q := unionFilterQuery([]config.LogFilter{
+		{Addresses: []string{"0x01"}, Topics: [][]string{{"0x0a"}}},
+		{Addresses: []string{"0x02", "0x01"}, Topics: [][]string{{"0x0b"}, {"0x0c"}}},
+	})
+	r.Equal([]common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}, q.Addresses)
+	r.Equal([][]common.Hash{{common.HexToHash("0x0a"), common.HexToHash("0x0b")}}, q.Topics)
+
+	// a filter without addresses or topics widens the query
+	q = unionFilterQuery([]config.LogFilter{
+		{Addresses: []string{"0x01"}, Topics: [][]string{{"0x0a"}}},
+		{Topics: [][]string{{}, {"0x0c"}}},
+	})
+	r.Nil(q.Addresses)
+	r.Nil(q.Topics)
+}
+
+func TestGroupLogsByTx(t *testing.T) {
+	r := require.New(t)
+
+	strPtr := func(s string) *string { return &s }
+	to := "0x0000000000000000000000000000000000000003"
+	blockEvt := &domain.BlockEvent{
+		Block: &domain.Block{
+			Hash:   "0xb1",
+			Number: "0x1",
+			Transactions: []domain.Transaction{
+				{Hash: "0xaa", From: "0x0000000000000000000000000000000000000002", To: &to, Input: strPtr("0x1234")},
+			},
+		},
+		Timestamps: &domain.TrackingTimestamps{},
+	}
+	txEvts := groupLogsByTx(blockEvt, []domain.LogEntry{
+		{TransactionHash: strPtr("0xaa"), LogIndex: strPtr("0x0")},
+		{TransactionHash: strPtr("0xbb"), LogIndex: strPtr("0x1")},
+		{TransactionHash: strPtr("0xaa"), LogIndex: strPtr("0x2")},
+	})
+
+	r.Len(txEvts, 2)
+	r.Equal("0xaa", txEvts[0].Transaction.Hash)
+	r.Equal(&to, txEvts[0].Transaction.To)
+	r.Nil(txEvts[0].Transaction.Input)
+	r.Len(txEvts[0].BlockEvt.Logs, 2)
+	r.Equal("0xbb", txEvts[1].Transaction.Hash)
+	r.Equal("0xb1", txEvts[1].Transaction.BlockHash)
+	r.Len(txEvts[1].BlockEvt.Logs, 1)
+}
+
+func TestLogStream_GetLogsWithRetry(t *testing.T) {
+	r := require.New(t)
+
+	retryInterval := getLogsRetryInterval
+	getLogsRetryInterval = time.Millisecond
+	defer func() { getLogsRetryInterval = retryInterval }()
+
+	ethClient := mock_ethereum.NewMockClient(gomock.NewController(t))
+	l, err := NewLogStreamService(context.Background(), LogStreamServiceConfig{ChainID: 1, EthClient: ethClient})
+	r.NoError(err)
+	evt := &domain.BlockEvent{Block: &domain.Block{Number: "0x1"}}
+	filters := []config.LogFilter{{Addresses: []string{"0x01"}}}
+
+	// the failed requests are retried
+	gomock.InOrder(
+		ethClient.EXPECT().GetLogs(gomock.Any(), gomock.Any()).Return(nil, errors.New("failed")).Times(2),
+		ethClient.EXPECT().GetLogs(gomock.Any(), gomock.Any()).Return([]types.Log{{TxHash: common.HexToHash("0xaa")}}, nil),
+	)
+	logs, err := l.getLogsWithRetry(evt, filters)
+	r.NoError(err)
+	r.Len(logs, 1)
+
+	// the block fails after the last attempt
+	ethClient.EXPECT().GetLogs(gomock.Any(), gomock.Any()).Return(nil, errors.New("failed")).Times(getLogsAttempts)
+	_, err = l.getLogsWithRetry(evt, filters)
+	r.Error(err)
+}
@@ -0,0 +1,475 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Mempool sources
+const (
+	MempoolSourceAuto         = "auto"
+	MempoolSourceSubscription = "subscription"
+	MempoolSourceFilter       = "filter"
+	MempoolSourceTxPool       = "txpool"
+)
+
+// Mempool RPC method names
+const (
+	methodBlockNumber                 = "eth_blockNumber"
+	methodGetTransactionByHash        = "eth_getTransactionByHash"
+	methodNewPendingTransactionFilter = "eth_newPendingTransactionFilter"
+	methodGetFilterChanges            = "eth_getFilterChanges"
+	methodTxPoolContent               = "txpool_content"
+
+	subscriptionPendingTxs = "newPendingTransactions"
+)
+
+const (
+	mempoolFetchWorkers   = 4
+	mempoolFetchBatchSize = 100
+	mempoolFetchAttempts  = 3
+	mempoolFetchWait      = 100 * time.Millisecond
+	mempoolRefetchDelay   = time.Second
+	mempoolHashQueueSize  = 10000
+	mempoolSeenCacheSize  = 100000
+	rpcErrMethodNotFound  = -32601
+)
+
+// PendingTx is a transaction which is not included in a block yet. The event of a pending
+// transaction has no block and no receipt.
+type PendingTx struct {
+	Event *protocol.TransactionEvent
+	// LatestBlock is the chain head at the time the transaction was seen.
+	LatestBlock uint64
+}
+
+// MempoolStreamService pulls pending transactions from the mempool of the node
+// and emits them to a channel.
+type MempoolStreamService struct {
+	cfg       MempoolStreamServiceConfig
+	ctx       context.Context
+	rpc       *rpc.Client
+	txOutput  chan *PendingTx
+	hashQueue chan *pendingHash
+	seen      utils.Cache
+
+	latestBlock uint64
+	recovered   bool
+
+	lastTxActivity health.TimeTracker
+	lastErr        health.ErrorTracker
+	lastFetchErr   health.ErrorTracker
+	currentSource  health.MessageTracker
+}
+
+type MempoolStreamServiceConfig struct {
+	ChainID          *big.Int
+	JsonRpc          config.JsonRpcConfig
+	Source           string
+	PollInterval     time.Duration
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+}
+
+type pendingHash struct {
+	Hash     common.Hash
+	Attempts int
+}
+
+// ReadOnlyTxStream returns the receive-only pending tx stream.
+func (m *MempoolStreamService) ReadOnlyTxStream() <-chan *PendingTx {
+	return m.txOutput
+}
+
+func (m *MempoolStreamService) sources() []string {
+	if m.cfg.Source == MempoolSourceAuto || m.cfg.Source == "" {
+		return []string{MempoolSourceSubscription, MempoolSourceFilter, MempoolSourceTxPool}
+	}
+	return []string{m.cfg.Source}
+}
+
+func (m *MempoolStreamService) Start() error {
+	log.Infof("Starting %s", m.Name())
+	for i := 0; i < mempoolFetchWorkers; i++ {
+		go m.fetchTxs()
+	}
+	go m.run()
+	return nil
+}
+
+// run streams from the selected source and falls back to the next source in the
+// list if the node does not support the current one.
+func (m *MempoolStreamService) run() {
+	sources := m.sources()
+	backoff := m.cfg.RetryInterval
+	var i int
+	for {
+		source := sources[i]
+		m.currentSource.Set(source)
+		m.recovered = false
+		err := m.stream(source)
+		if m.ctx.Err() != nil {
+			return
+		}
+		m.lastErr.Set(err)
+		if isUnsupportedErr(err) && i < len(sources)-1 {
+			log.WithError(err).WithField("source", source).Warn("mempool source is not supported - falling back to the next source")
+			i++
+			continue
+		}
+		if m.recovered {
+			backoff = m.cfg.RetryInterval
+		}
+		log.WithError(err).WithFields(log.Fields{
+			"source":  source,
+			"backoff": backoff,
+		}).Warn("mempool stream interrupted - retrying")
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > m.cfg.MaxRetryInterval {
+			backoff = m.cfg.MaxRetryInterval
+		}
+	}
+}
+
+func (m *MempoolStreamService) stream(source string) error {
+	switch source {
+	case MempoolSourceSubscription:
+		return m.subscribe()
+	case MempoolSourceFilter:
+		return m.pollFilter()
+	case MempoolSourceTxPool:
+		return m.pollTxPool()
+	default:
+		return fmt.Errorf("unknown mempool source: %s", source)
+	}
+}
+
+// markRecovered resets the error state after the source worked.
+func (m *MempoolStreamService) markRecovered() {
+	m.recovered = true
+	m.lastErr.Set(nil)
+}
+
+// subscribe listens to the pending tx hashes and queues them for fetching.
+func (m *MempoolStreamService) subscribe() error {
+	if err := m.updateLatestBlock(); err != nil {
+		return err
+	}
+	hashes := make(chan common.Hash, mempoolFetchBatchSize)
+	sub, err := m.rpc.EthSubscribe(m.ctx, hashes, subscriptionPendingTxs)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to pending txs: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return m.ctx.Err()
+		case err := <-sub.Err():
+			return fmt.Errorf("pending tx subscription failed: %w", err)
+		case <-ticker.C:
+			if err := m.updateLatestBlock(); err != nil {
+				return err
+			}
+			m.markRecovered()
+		case hash := <-hashes:
+			m.queueHash(&pendingHash{Hash: hash})
+		}
+	}
+}
+
+// pollFilter polls the pending tx hashes from a filter and queues them for fetching.
+func (m *MempoolStreamService) pollFilter() error {
+	var filterID string
+	if err := m.rpc.CallContext(m.ctx, &filterID, methodNewPendingTransactionFilter); err != nil {
+		return fmt.Errorf("failed to create pending tx filter: %w", err)
+	}
+
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.updateLatestBlock(); err != nil {
+			return err
+		}
+		var hashes []common.Hash
+		if err := m.rpc.CallContext(m.ctx, &hashes, methodGetFilterChanges, filterID); err != nil {
+			return fmt.Errorf("failed to get pending tx filter changes: %w", err)
+		}
+		for _, hash := range hashes {
+			m.queueHash(&pendingHash{Hash: hash})
+		}
+		m.markRecovered()
+
+		select {
+		case <-m.ctx.Done():
+			return m.ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollTxPool reads the pending transactions from the txpool API periodically. The whole pool
+// is read in every round, so only the difference from the previous round is emitted.
+func (m *MempoolStreamService) pollTxPool() error {
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+
+	known := make(map[string]bool)
+	for {
+		if err := m.updateLatestBlock(); err != nil {
+			return err
+		}
+		var content map[string]map[string]map[string]*domain.Transaction
+		if err := m.rpc.CallContext(m.ctx, &content, methodTxPoolContent); err != nil {
+			return fmt.Errorf("failed to get txpool content: %w", err)
+		}
+		current := make(map[string]bool)
+		for _, txsByNonce := range content["pending"] {
+			for _, tx := range txsByNonce {
+				if tx == nil {
+					continue
+				}
+				current[tx.Hash] = true
+				if !known[tx.Hash] {
+					m.emitTx(tx)
+				}
+			}
+		}
+		// forget the transactions which left the pool
+		known = current
+		m.markRecovered()
+
+		select {
+		case <-m.ctx.Done():
+			return m.ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *MempoolStreamService) queueHash(ph *pendingHash) {
+	if m.seen.Exists(ph.Hash.Hex()) {
+		return
+	}
+	select {
+	case <-m.ctx.Done():
+	case m.hashQueue <- ph:
+	}
+}
+
+// fetchTxs reads the queued hashes and fetches the transactions in batches.
+func (m *MempoolStreamService) fetchTxs() {
+	for {
+		var batch []*pendingHash
+		select {
+		case <-m.ctx.Done():
+			return
+		case ph := <-m.hashQueue:
+			batch = append(batch, ph)
+		}
+		timeout := time.After(mempoolFetchWait)
+	collect:
+		for len(batch) < mempoolFetchBatchSize {
+			select {
+			case <-m.ctx.Done():
+				return
+			case ph := <-m.hashQueue:
+				batch = append(batch, ph)
+			case <-timeout:
+				break collect
+			}
+		}
+		m.fetchBatch(batch)
+	}
+}
+
+func (m *MempoolStreamService) fetchBatch(batch []*pendingHash) {
+	txs := make([]*domain.Transaction, len(batch))
+	elems := make([]rpc.BatchElem, len(batch))
+	for i, ph := range batch {
+		elems[i] = rpc.BatchElem{
+			Method: methodGetTransactionByHash,
+			Args:   []interface{}{ph.Hash},
+			Result: &txs[i],
+		}
+	}
+	if err := m.rpc.BatchCallContext(m.ctx, elems); err != nil {
+		m.lastFetchErr.Set(err)
+		log.WithError(err).WithField("count", len(batch)).Warn("failed to fetch pending txs")
+		m.refetch(batch)
+		return
+	}
+
+	var failed []*pendingHash
+	var lastErr error
+	for i, elem := range elems {
+		tx := txs[i]
+		switch {
+		case elem.Error != nil:
+			lastErr = elem.Error
+			failed = append(failed, batch[i])
+		case tx == nil:
+			// not indexed by the node yet or already dropped from the mempool
+			failed = append(failed, batch[i])
+		case len(tx.BlockHash) > 0:
+			// already mined
+			m.seen.Add(tx.Hash)
+		default:
+			if !m.seen.ExistsAndAdd(tx.Hash) {
+				m.emitTx(tx)
+			}
+		}
+	}
+	m.lastFetchErr.Set(lastErr)
+	if lastErr != nil {
+		log.WithError(lastErr).Warn("failed to fetch some of the pending txs")
+	}
+	m.refetch(failed)
+}
+
+// refetch queues the hashes again after a delay, until they run out of attempts.
+func (m *MempoolStreamService) refetch(batch []*pendingHash) {
+	var retries []*pendingHash
+	for _, ph := range batch {
+		ph.Attempts++
+		if ph.Attempts < mempoolFetchAttempts {
+			retries = append(retries, ph)
+		}
+	}
+	if len(retries) == 0 {
+		return
+	}
+	time.AfterFunc(mempoolRefetchDelay, func() {
+		for _, ph := range retries {
+			m.queueHash(ph)
+		}
+	})
+}
+
+// updateLatestBlock keeps track of the chain head so the pending transactions
+// can refer to the block they are expected to be included after.
+func (m *MempoolStreamService) updateLatestBlock() error {
+	var blockNumber hexutil.Uint64
+	if err := m.rpc.CallContext(m.ctx, &blockNumber, methodBlockNumber); err != nil {
+		return fmt.Errorf("failed to get the latest block number: %w", err)
+	}
+	atomic.StoreUint64(&m.latestBlock, uint64(blockNumber))
+	return nil
+}
+
+func (m *MempoolStreamService) emitTx(tx *domain.Transaction) {
+	evt, err := toPendingTxEvent(m.cfg.ChainID, tx, time.Now().UTC())
+	if err != nil {
+		log.WithError(err).WithField("tx", tx.Hash).Error("error converting pending tx to message (skipping)")
+		return
+	}
+	select {
+	case <-m.ctx.Done():
+	case m.txOutput <- &PendingTx{Event: evt, LatestBlock: atomic.LoadUint64(&m.latestBlock)}:
+		m.lastTxActivity.Set()
+	}
+}
+
+// toPendingTxEvent converts the transaction to an event without a block and a receipt.
+// The feed timestamp is the time the transaction was seen.
+func toPendingTxEvent(chainID *big.Int, tx *domain.Transaction, seenAt time.Time) (*protocol.TransactionEvent, error) {
+	evt := &domain.TransactionEvent{
+		BlockEvt: &domain.BlockEvent{
+			EventType: domain.EventTypeBlock,
+			ChainID:   chainID,
+			Block:     &domain.Block{},
+		},
+		Transaction: tx,
+		Timestamps: &domain.TrackingTimestamps{
+			Feed: seenAt,
+		},
+	}
+	msg, err := evt.ToMessage()
+	if err != nil {
+		return nil, err
+	}
+	msg.Block = nil
+	msg.Receipt = nil
+	return msg, nil
+}
+
+func isUnsupportedErr(err error) bool {
+	if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == rpcErrMethodNotFound {
+		return true
+	}
+	errStr := strings.ToLower(err.Error())
+	return strings.Contains(errStr, "not supported") || strings.Contains(errStr, "does not exist") ||
+		strings.Contains(errStr, "not available")
+}
+
+func (m *MempoolStreamService) Stop() error {
+	log.Infof("Stopping %s", m.Name())
+	m.rpc.Close()
+	return nil
+}
+
+func (m *MempoolStreamService) Name() string {
+	return "mempool-stream"
+}
+
+// Health implements health.Reporter interface.
+func (m *MempoolStreamService) Health() health.Reports {
+	return health.Reports{
+		m.lastTxActivity.GetReport("event.transaction.time"),
+		m.lastErr.GetReport("event.stream.error"),
+		m.lastFetchErr.GetReport("event.fetch.error"),
+		m.currentSource.GetReport("source"),
+	}
+}
+
+func NewMempoolStreamService(ctx context.Context, cfg MempoolStreamServiceConfig) (*MempoolStreamService, error) {
+	rpcClient, err := rpc.DialContext(ctx, cfg.JsonRpc.Url)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range cfg.JsonRpc.Headers {
+		rpcClient.SetHeader(k, v)
+	}
+	return newMempoolStreamService(ctx, rpcClient, cfg), nil
+}
+
+func newMempoolStreamService(ctx context.Context, rpcClient *rpc.Client, cfg MempoolStreamServiceConfig) *MempoolStreamService {
+	return &MempoolStreamService{
+		cfg:       cfg,
+		ctx:       ctx,
+		rpc:       rpcClient,
+		txOutput:  make(chan *PendingTx),
+		hashQueue: make(chan *pendingHash, mempoolHashQueueSize),
+		seen:      utils.NewCache(mempoolSeenCacheSize),
+	}
+}
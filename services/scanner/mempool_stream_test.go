@@ -0,0 +1,169 @@
+package scanner
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testPendingTxHash = common.HexToHash("0x01")
+	testMinedTxHash   = common.HexToHash("0x02")
+	testMissingTxHash = common.HexToHash("0x03")
+	testNextTxHash    = common.HexToHash("0x04")
+	testTxTo          = "0x0000000000000000000000000000000000000001"
+)
+
+func testTx(hash common.Hash, blockHash string) *domain.Transaction {
+	return &domain.Transaction{
+		BlockHash: blockHash,
+		From:      "0x0000000000000000000000000000000000000002",
+		Hash:      hash.Hex(),
+		Nonce:     "0x1",
+		To:        &testTxTo,
+	}
+}
+
+type fakeEthAPI struct {
+	mu           sync.Mutex
+	filterRounds [][]common.Hash
+}
+
+func (api *fakeEthAPI) BlockNumber() hexutil.Uint64 {
+	return 100
+}
+
+func (api *fakeEthAPI) NewPendingTransactionFilter() string {
+	return "0x1"
+}
+
+func (api *fakeEthAPI) GetFilterChanges(id string) []common.Hash {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.filterRounds) == 0 {
+		return nil
+	}
+	hashes := api.filterRounds[0]
+	api.filterRounds = api.filterRounds[1:]
+	return hashes
+}
+
+func (api *fakeEthAPI) GetTransactionByHash(hash common.Hash) *domain.Transaction {
+	switch hash {
+	case testPendingTxHash:
+		return testTx(hash, "")
+	case testMinedTxHash:
+		return testTx(hash, "0x1234")
+	default:
+		return nil
+	}
+}
+
+type fakeTxPoolAPI struct {
+	mu     sync.Mutex
+	rounds [][]*domain.Transaction
+}
+
+func (api *fakeTxPoolAPI) Content() map[string]map[string]map[string]*domain.Transaction {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	var txs []*domain.Transaction
+	if len(api.rounds) > 0 {
+		txs = api.rounds[0]
+		api.rounds = api.rounds[1:]
+	}
+	pending := make(map[string]map[string]*domain.Transaction)
+	for _, tx := range txs {
+		pending[tx.Hash] = map[string]*domain.Transaction{"1": tx}
+	}
+	return map[string]map[string]map[string]*domain.Transaction{"pending": pending}
+}
+
+func startTestMempoolStream(t *testing.T, source string, apis ...rpc.API) *MempoolStreamService {
+	server := rpc.NewServer()
+	for _, api := range apis {
+		require.NoError(t, server.RegisterName(api.Namespace, api.Service))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stream := newMempoolStreamService(ctx, rpc.DialInProc(server), MempoolStreamServiceConfig{
+		ChainID:          big.NewInt(1),
+		Source:           source,
+		PollInterval:     10 * time.Millisecond,
+		RetryInterval:    10 * time.Millisecond,
+		MaxRetryInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, stream.Start())
+	return stream
+}
+
+func requireNextPendingTx(t *testing.T, stream *MempoolStreamService, hash common.Hash) {
+	select {
+	case tx := <-stream.ReadOnlyTxStream():
+		require.Equal(t, hash.Hex(), tx.Event.Transaction.Hash)
+		require.Nil(t, tx.Event.Block)
+		require.Nil(t, tx.Event.Receipt)
+		require.Equal(t, uint64(100), tx.LatestBlock)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for pending tx %s", hash.Hex())
+	}
+}
+
+func requireNoPendingTx(t *testing.T, stream *MempoolStreamService) {
+	select {
+	case tx := <-stream.ReadOnlyTxStream():
+		t.Fatalf("unexpected pending tx %s", tx.Event.Transaction.Hash)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestMempoolStream_Filter(t *testing.T) {
+	stream := startTestMempoolStream(t, MempoolSourceFilter, rpc.API{
+		Namespace: "eth",
+		Service: &fakeEthAPI{
+			filterRounds: [][]common.Hash{
+				{testPendingTxHash, testMinedTxHash, testMissingTxHash},
+				{testPendingTxHash},
+			},
+		},
+	})
+
+	requireNextPendingTx(t, stream, testPendingTxHash)
+	requireNoPendingTx(t, stream)
+}
+
+func TestMempoolStream_FallbackToTxPool(t *testing.T) {
+	txA := testTx(testPendingTxHash, "")
+	txB := testTx(testNextTxHash, "")
+	// no eth API for filters: auto source must fall back to the txpool
+	stream := startTestMempoolStream(t, MempoolSourceAuto,
+		rpc.API{
+			Namespace: "eth",
+			Service:   &fakeBlockNumberAPI{},
+		},
+		rpc.API{
+			Namespace: "txpool",
+			Service: &fakeTxPoolAPI{
+				rounds: [][]*domain.Transaction{{txA}, {txA, txB}, {txA, txB}},
+			},
+		},
+	)
+
+	requireNextPendingTx(t, stream, testPendingTxHash)
+	requireNextPendingTx(t, stream, testNextTxHash)
+	requireNoPendingTx(t, stream)
+}
+
+type fakeBlockNumberAPI struct{}
+
+func (api *fakeBlockNumberAPI) BlockNumber() hexutil.Uint64 {
+	return 100
+}
@@ -0,0 +1,174 @@
+package scanner
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/clients/webhook"
+	"github.com/forta-network/forta-core-go/clients/webhook/client/models"
+	"github.com/forta-network/forta-core-go/clients/webhook/client/operations"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/protocol/transform"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/metrics"
+	"github.com/forta-network/forta-node/store"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// PendingTxAnalyzerService sends pending transactions to the agents which opted in and
+// delivers the findings. The findings are kept apart from the alert batches, because
+// a pending transaction can be dropped or included in a block later.
+type PendingTxAnalyzerService struct {
+	ctx context.Context
+	cfg PendingTxAnalyzerServiceConfig
+
+	lastInputActivity  health.TimeTracker
+	lastOutputActivity health.TimeTracker
+	lastDeliveryErr    health.ErrorTracker
+}
+
+type PendingTxAnalyzerServiceConfig struct {
+	TxChannel     <-chan *PendingTx
+	AgentPool     AgentPool
+	MsgClient     clients.MessageClient
+	WebhookClient webhook.AlertWebhookClient
+}
+
+// WARNING, this must be deterministic (any maps must be converted to sorted lists)
+func (t *PendingTxAnalyzerService) calculateAlertID(result *TxResult, f *protocol.Finding) string {
+	addrs := utils.MapKeys(result.Request.Event.Addresses)
+	sort.Strings(addrs)
+	idStr := strings.Join([]string{
+		"pending",
+		result.Request.Event.Network.ChainId,
+		result.Request.Event.Transaction.Hash,
+		f.Name,
+		f.Description,
+		f.Protocol,
+		f.Type.String(),
+		f.AlertId,
+		f.Severity.String(),
+		result.AgentConfig.Image,
+		result.AgentConfig.ID,
+		strings.Join(addrs, ""),
+		strings.Join(f.Addresses, "")}, "")
+	return crypto.Keccak256Hash([]byte(idStr)).Hex()
+}
+
+func (t *PendingTxAnalyzerService) findingToAlert(result *TxResult, ts time.Time, f *protocol.Finding) (*protocol.Alert, error) {
+	chainID, err := utils.HexToBigInt(result.Request.Event.Network.ChainId)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.Alert{
+		Id:      t.calculateAlertID(result, f),
+		Finding: f,
+		Type:    protocol.AlertType_UNKNOWN_ALERT_TYPE,
+		Agent:   result.AgentConfig.ToAgentInfo(),
+		Tags: store.AlertSchemaTags(map[string]string{
+			"agentImage": result.AgentConfig.Image,
+			"agentId":    result.AgentConfig.ID,
+			"chainId":    chainID.String(),
+			"txHash":     result.Request.Event.Transaction.Hash,
+			"pending":    "true",
+		}),
+		Timestamp:  ts.Format(utils.AlertTimeFormat),
+		Timestamps: result.Timestamps.ToMessage(),
+	}, nil
+}
+
+func (t *PendingTxAnalyzerService) handleResult(result *TxResult) {
+	ts := time.Now().UTC()
+	chainID, _ := utils.HexToBigInt(result.Request.Event.Network.ChainId)
+
+	var alertList models.AlertList
+	for _, f := range result.Response.Findings {
+		alert, err := t.findingToAlert(result, ts, f)
+		if err != nil {
+			log.WithError(err).Error("failed to transform pending tx finding to alert")
+			continue
+		}
+		alertList.Alerts = append(alertList.Alerts, transform.ToWebhookAlert(alert, chainID.Uint64(), nil, result.Request.Event))
+	}
+	t.cfg.MsgClient.PublishProto(messaging.SubjectMetricAgent, &protocol.AgentMetricList{
+		Metrics: metrics.GetPendingTxMetrics(result.AgentConfig, result.Response),
+	})
+	if len(alertList.Alerts) == 0 {
+		return
+	}
+
+	if t.cfg.WebhookClient == nil {
+		for _, alert := range alertList.Alerts {
+			log.WithFields(log.Fields{
+				"agent":   result.AgentConfig.ID,
+				"tx":      alert.Source.TransactionHash,
+				"alertId": alert.AlertID,
+				"hash":    alert.Hash,
+			}).Info("pending tx alert")
+		}
+		return
+	}
+	_, err := t.cfg.WebhookClient.SendAlerts(&operations.SendAlertsParams{
+		Context:   t.ctx,
+		AlertList: &alertList,
+	})
+	t.lastDeliveryErr.Set(err)
+	if err != nil {
+		log.WithError(err).Error("failed to send pending tx alerts")
+	}
+}
+
+func (t *PendingTxAnalyzerService) Start() error {
+	log.Infof("Starting %s", t.Name())
+
+	go func() {
+		for result := range t.cfg.AgentPool.PendingTxResults() {
+			t.handleResult(result)
+			t.lastOutputActivity.Set()
+		}
+	}()
+
+	go func() {
+		for tx := range t.cfg.TxChannel {
+			requestId := uuid.Must(uuid.NewUUID())
+			request := &protocol.EvaluateTxRequest{RequestId: requestId.String(), Event: tx.Event}
+			t.cfg.AgentPool.SendEvaluatePendingTxRequest(request, tx.LatestBlock)
+			t.lastInputActivity.Set()
+		}
+	}()
+
+	return nil
+}
+
+func (t *PendingTxAnalyzerService) Stop() error {
+	log.Infof("Stopping %s", t.Name())
+	return nil
+}
+
+func (t *PendingTxAnalyzerService) Name() string {
+	return "pending-tx-analyzer"
+}
+
+// Health implements the health.Reporter interface.
+func (t *PendingTxAnalyzerService) Health() health.Reports {
+	return health.Reports{
+		t.lastInputActivity.GetReport("event.input.time"),
+		t.lastOutputActivity.GetReport("event.output.time"),
+		t.lastDeliveryErr.GetReport("event.delivery.error"),
+	}
+}
+
+func NewPendingTxAnalyzerService(ctx context.Context, cfg PendingTxAnalyzerServiceConfig) (*PendingTxAnalyzerService, error) {
+	return &PendingTxAnalyzerService{
+		cfg: cfg,
+		ctx: ctx,
+	}, nil
+}
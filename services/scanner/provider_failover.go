@@ -0,0 +1,464 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/ethereum"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/rpccache"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// errorRateWeight is the weight of the latest result in the error rate of a provider.
+const errorRateWeight = 0.2
+
+// ProviderFailover is a local JSON-RPC endpoint which forwards the requests to the first healthy
+// provider. The scanner clients connect to this endpoint, so that a flaky provider is swapped out
+// while the clients keep retrying their requests. The requests are limited by the upstream limiter
+// and the responses are cached if they are configured.
+type ProviderFailover struct {
+	ctx        context.Context
+	cfg        ProviderFailoverConfig
+	providers  []*rpcProvider
+	httpClient *http.Client
+	server     *http.Server
+	cacheSrv   *http.Server
+
+	active   int
+	activeMu sync.RWMutex // guards the providers too
+
+	lastCheck  health.TimeTracker
+	lastChange health.MessageTracker
+	lastErr    health.ErrorTracker
+}
+
+type ProviderFailoverConfig struct {
+	Name      string
+	ChainID   uint64
+	Providers []config.JsonRpcConfig
+	Failover  config.FailoverConfig
+	Limiter   *UpstreamLimiter
+	Cache     *rpccache.Cache
+	// ListenAddr is a random free local port by default.
+	ListenAddr string
+	// CacheAddr serves the cacheable methods to the json-rpc proxy if it is set.
+	CacheAddr string
+	MsgClient clients.MessageClient
+}
+
+type rpcProvider struct {
+	cfg  config.JsonRpcConfig
+	name string
+
+	mu          sync.RWMutex
+	blockNumber uint64
+	latency     time.Duration
+	errorRate   float64
+	lastErr     error
+}
+
+func (p *rpcProvider) recordResult(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var failed float64
+	if err != nil {
+		failed = 1
+	}
+	p.errorRate = p.errorRate*(1-errorRateWeight) + failed*errorRateWeight
+}
+
+func (p *rpcProvider) recordCheck(blockNumber uint64, latency time.Duration, err error) {
+	p.recordResult(err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastErr = err
+	p.latency = latency
+	if err == nil {
+		p.blockNumber = blockNumber
+	}
+}
+
+func (p *rpcProvider) isHealthy(cfg config.FailoverConfig, maxBlockNumber uint64) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastErr == nil &&
+		p.latency <= time.Duration(cfg.MaxLatencyMs)*time.Millisecond &&
+		p.errorRate <= cfg.MaxErrorRate &&
+		maxBlockNumber-p.blockNumber <= uint64(cfg.MaxHeadLag)
+}
+
+// providerName identifies the provider without exposing the API keys in the URL.
+func providerName(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		return rawurl
+	}
+	return u.Host
+}
+
+// URL returns the local endpoint which the clients should connect to.
+func (pf *ProviderFailover) URL() string {
+	return fmt.Sprintf("http://%s", pf.cfg.ListenAddr)
+}
+
+func (pf *ProviderFailover) activeProvider() int {
+	pf.activeMu.RLock()
+	defer pf.activeMu.RUnlock()
+	return pf.active
+}
+
+func (pf *ProviderFailover) getProviders() []*rpcProvider {
+	pf.activeMu.RLock()
+	defer pf.activeMu.RUnlock()
+	return pf.providers
+}
+
+// providerOrder returns the active provider first and then the others in the configured order.
+func (pf *ProviderFailover) providerOrder() []*rpcProvider {
+	pf.activeMu.RLock()
+	defer pf.activeMu.RUnlock()
+	active := pf.active
+	ordered := []*rpcProvider{pf.providers[active]}
+	for i, provider := range pf.providers {
+		if i != active {
+			ordered = append(ordered, provider)
+		}
+	}
+	return ordered
+}
+
+func (pf *ProviderFailover) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// the request is charged once even if it is retried with the other providers
+	if pf.cfg.Limiter != nil {
+		if err := pf.cfg.Limiter.Wait(req.Context(), rpccache.RequestMethods(body)); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	for _, provider := range pf.providerOrder() {
+		resp, err := pf.forward(req, provider, body)
+		provider.recordResult(err)
+		if err != nil {
+			log.WithError(err).WithField("provider", provider.name).Warn("json-rpc provider request failed")
+			continue
+		}
+		defer resp.Body.Close()
+		for h, v := range resp.Header {
+			w.Header()[h] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			log.WithError(err).Warn("failed to write json-rpc response")
+		}
+		return
+	}
+	http.Error(w, "all json-rpc providers failed", http.StatusBadGateway)
+}
+
+func (pf *ProviderFailover) forward(req *http.Request, provider *rpcProvider, body []byte) (*http.Response, error) {
+	fwdReq, err := http.NewRequestWithContext(req.Context(), req.Method, provider.cfg.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	fwdReq.Header = req.Header.Clone()
+	for h, v := range provider.cfg.Headers {
+		fwdReq.Header.Set(h, v)
+	}
+	resp, err := pf.httpClient.Do(fwdReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (pf *ProviderFailover) checkProviders() {
+	var wg sync.WaitGroup
+	for _, provider := range pf.getProviders() {
+		wg.Add(1)
+		go func(provider *rpcProvider) {
+			defer wg.Done()
+			pf.checkProvider(provider)
+		}(provider)
+	}
+	wg.Wait()
+	pf.lastCheck.Set()
+	pf.selectProvider()
+}
+
+func (pf *ProviderFailover) checkProvider(provider *rpcProvider) {
+	ctx, cancel := context.WithTimeout(pf.ctx, time.Duration(pf.cfg.Failover.MaxLatencyMs)*time.Millisecond)
+	defer cancel()
+
+	if pf.cfg.Limiter != nil {
+		if err := pf.cfg.Limiter.Wait(ctx, []string{"eth_blockNumber"}); err != nil {
+			return
+		}
+	}
+	start := time.Now()
+	blockNumber, err := getBlockNumber(ctx, provider.cfg)
+	provider.recordCheck(blockNumber, time.Since(start), err)
+	if err != nil {
+		log.WithError(err).WithField("provider", provider.name).Warn("json-rpc provider check failed")
+	}
+}
+
+func getBlockNumber(ctx context.Context, cfg config.JsonRpcConfig) (uint64, error) {
+	rpcClient, err := ethereum.NewRpcClient(cfg.Url)
+	if err != nil {
+		return 0, err
+	}
+	defer rpcClient.Close()
+	for h, v := range cfg.Headers {
+		rpcClient.SetHeader(h, v)
+	}
+	var blockNumber hexutil.Uint64
+	if err := rpcClient.CallContext(ctx, &blockNumber, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return uint64(blockNumber), nil
+}
+
+// selectProvider activates the first healthy provider. The active provider is kept if none of the
+// providers are healthy.
+func (pf *ProviderFailover) selectProvider() {
+	pf.activeMu.Lock()
+	var maxBlockNumber uint64
+	for _, provider := range pf.providers {
+		provider.mu.RLock()
+		if provider.lastErr == nil && provider.blockNumber > maxBlockNumber {
+			maxBlockNumber = provider.blockNumber
+		}
+		provider.mu.RUnlock()
+	}
+
+	selected := -1
+	for i, provider := range pf.providers {
+		if provider.isHealthy(pf.cfg.Failover, maxBlockNumber) {
+			selected = i
+			break
+		}
+	}
+	if selected < 0 {
+		pf.activeMu.Unlock()
+		pf.lastErr.Set(errors.New("no healthy json-rpc providers"))
+		return
+	}
+	pf.lastErr.Set(nil)
+
+	previous := pf.active
+	pf.active = selected
+	payload := messaging.ProviderPayload{
+		ChainID:  pf.cfg.ChainID,
+		Previous: pf.providers[previous].name,
+		Active:   pf.providers[selected].name,
+	}
+	pf.activeMu.Unlock()
+	if previous == selected {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"chainId":  payload.ChainID,
+		"previous": payload.Previous,
+		"active":   payload.Active,
+	}).Warn("switched json-rpc provider")
+	pf.lastChange.Set(fmt.Sprintf("switched from %s to %s", payload.Previous, payload.Active))
+	if pf.cfg.MsgClient != nil {
+		pf.cfg.MsgClient.Publish(messaging.SubjectScannerProvider, payload)
+	}
+}
+
+// serve binds the local endpoint and the cache endpoint and starts serving the requests.
+func (pf *ProviderFailover) serve() error {
+	listener, err := net.Listen("tcp", pf.cfg.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for json-rpc provider failover: %v", err)
+	}
+	var handler http.Handler = pf
+	if pf.cfg.Cache != nil {
+		handler = pf.cfg.Cache.Handler(pf)
+	}
+	pf.server = &http.Server{Handler: handler}
+	go func() {
+		if err := pf.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("json-rpc provider failover server failed")
+		}
+	}()
+	if pf.cfg.Cache != nil && len(pf.cfg.CacheAddr) > 0 {
+		cacheListener, err := net.Listen("tcp", pf.cfg.CacheAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for the json-rpc cache: %v", err)
+		}
+		pf.cacheSrv = &http.Server{Handler: pf.cfg.Cache.RestrictedHandler(pf)}
+		go func() {
+			if err := pf.cacheSrv.Serve(cacheListener); err != nil && err != http.ErrServerClosed {
+				log.WithError(err).Error("json-rpc cache server failed")
+			}
+		}()
+	}
+	return nil
+}
+
+func (pf *ProviderFailover) Start() error {
+	log.Infof("Starting %s", pf.Name())
+
+	if err := pf.serve(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(pf.cfg.Failover.CheckIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			// a single provider is used only for limiting the requests
+			if len(pf.getProviders()) > 1 {
+				pf.checkProviders()
+			}
+			select {
+			case <-pf.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// SetProviders replaces the providers without dropping the requests. The known providers keep their
+// check results and the active provider stays active if it is still configured.
+func (pf *ProviderFailover) SetProviders(cfgs []config.JsonRpcConfig) error {
+	if len(cfgs) == 0 {
+		return errors.New("no json-rpc providers")
+	}
+	pf.activeMu.Lock()
+	defer pf.activeMu.Unlock()
+
+	known := make(map[string]*rpcProvider)
+	for _, provider := range pf.providers {
+		known[provider.cfg.Url] = provider
+	}
+	activeUrl := pf.providers[pf.active].cfg.Url
+
+	var (
+		providers []*rpcProvider
+		active    int
+	)
+	for i, providerCfg := range cfgs {
+		provider, ok := known[providerCfg.Url]
+		if !ok || !reflect.DeepEqual(provider.cfg.Headers, providerCfg.Headers) {
+			provider = &rpcProvider{
+				cfg:  providerCfg,
+				name: providerName(providerCfg.Url),
+			}
+		}
+		if providerCfg.Url == activeUrl {
+			active = i
+		}
+		providers = append(providers, provider)
+	}
+	pf.providers = providers
+	pf.active = active
+	return nil
+}
+
+// SetUpstream changes the upstream limits if the requests are limited.
+func (pf *ProviderFailover) SetUpstream(cfg config.UpstreamConfig) {
+	if pf.cfg.Limiter == nil {
+		if cfg.Enabled() {
+			log.WithField("name", pf.Name()).Warn("upstream limits cannot be enabled without a restart")
+		}
+		return
+	}
+	pf.cfg.Limiter.SetConfig(cfg)
+}
+
+func (pf *ProviderFailover) Stop() error {
+	log.Infof("Stopping %s", pf.Name())
+	if pf.cacheSrv != nil {
+		pf.cacheSrv.Close()
+	}
+	if pf.server != nil {
+		return pf.server.Close()
+	}
+	return nil
+}
+
+func (pf *ProviderFailover) Name() string {
+	return pf.cfg.Name
+}
+
+// Health implements the health.Reporter interface.
+func (pf *ProviderFailover) Health() health.Reports {
+	reports := health.Reports{
+		pf.lastCheck.GetReport("check.time"),
+		pf.lastChange.GetReport("provider.change"),
+		pf.lastErr.GetReport("provider.error"),
+	}
+	if pf.cfg.Limiter != nil {
+		reports = append(reports, pf.cfg.Limiter.Health()...)
+	}
+	return reports
+}
+
+// NewProviderFailover creates the local endpoint for the providers. The first provider is active
+// until the first check.
+func NewProviderFailover(ctx context.Context, cfg ProviderFailoverConfig) (*ProviderFailover, error) {
+	if len(cfg.Providers) == 0 {
+		return nil, errors.New("no json-rpc providers")
+	}
+	// the clients are created with the url before the endpoint starts
+	if len(cfg.ListenAddr) == 0 {
+		addr, err := freeLocalAddr()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find a port for json-rpc provider failover: %v", err)
+		}
+		cfg.ListenAddr = addr
+	}
+	pf := &ProviderFailover{
+		ctx:        ctx,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+	for _, providerCfg := range cfg.Providers {
+		pf.providers = append(pf.providers, &rpcProvider{
+			cfg:  providerCfg,
+			name: providerName(providerCfg.Url),
+		})
+	}
+	return pf, nil
+}
+
+// freeLocalAddr returns a local address with a port which is free at the moment.
+func freeLocalAddr() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return listener.Addr().String(), nil
+}
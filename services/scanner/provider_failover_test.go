@@ -0,0 +1,165 @@
+package scanner
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/forta-network/forta-node/clients/messaging"
+	mock_clients "github.com/forta-network/forta-node/clients/mocks"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProviderAPI struct {
+	mu          sync.Mutex
+	blockNumber uint64
+}
+
+func (api *fakeProviderAPI) BlockNumber() hexutil.Uint64 {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return hexutil.Uint64(api.blockNumber)
+}
+
+func (api *fakeProviderAPI) setBlockNumber(blockNumber uint64) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.blockNumber = blockNumber
+}
+
+func startTestProvider(t *testing.T, api *fakeProviderAPI) *httptest.Server {
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", api))
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func testFailoverConfig() config.FailoverConfig {
+	return config.FailoverConfig{
+		CheckIntervalSeconds: 1,
+		MaxHeadLag:           5,
+		MaxErrorRate:         0.5,
+		MaxLatencyMs:         1000,
+	}
+}
+
+func requireBlockNumber(t *testing.T, url string, expected uint64) {
+	rpcClient, err := rpc.Dial(url)
+	require.NoError(t, err)
+	defer rpcClient.Close()
+	var blockNumber hexutil.Uint64
+	require.NoError(t, rpcClient.Call(&blockNumber, "eth_blockNumber"))
+	require.Equal(t, expected, uint64(blockNumber))
+}
+
+func TestProviderFailover_HeadLag(t *testing.T) {
+	r := require.New(t)
+	ctrl := gomock.NewController(t)
+	msgClient := mock_clients.NewMockMessageClient(ctrl)
+
+	primaryAPI := &fakeProviderAPI{blockNumber: 100}
+	fallbackAPI := &fakeProviderAPI{blockNumber: 100}
+	primary := startTestProvider(t, primaryAPI)
+	fallback := startTestProvider(t, fallbackAPI)
+
+	pf, err := NewProviderFailover(context.Background(), ProviderFailoverConfig{
+		Name:      "test-provider-failover",
+		ChainID:   1,
+		Providers: []config.JsonRpcConfig{{Url: primary.URL}, {Url: fallback.URL}},
+		Failover:  testFailoverConfig(),
+		MsgClient: msgClient,
+	})
+	r.NoError(err)
+	r.NoError(pf.serve())
+	defer pf.Stop()
+
+	pf.checkProviders()
+	r.Equal(0, pf.activeProvider())
+
+	// the primary provider falls behind: fail over
+	fallbackAPI.setBlockNumber(110)
+	msgClient.EXPECT().Publish(messaging.SubjectScannerProvider, messaging.ProviderPayload{
+		ChainID:  1,
+		Previous: providerName(primary.URL),
+		Active:   providerName(fallback.URL),
+	})
+	pf.checkProviders()
+	r.Equal(1, pf.activeProvider())
+	requireBlockNumber(t, pf.URL(), 110)
+
+	// the primary provider catches up: fail back
+	primaryAPI.setBlockNumber(110)
+	msgClient.EXPECT().Publish(messaging.SubjectScannerProvider, messaging.ProviderPayload{
+		ChainID:  1,
+		Previous: providerName(fallback.URL),
+		Active:   providerName(primary.URL),
+	})
+	pf.checkProviders()
+	r.Equal(0, pf.activeProvider())
+}
+
+func TestProviderFailover_ProviderDown(t *testing.T) {
+	r := require.New(t)
+
+	primary := startTestProvider(t, &fakeProviderAPI{blockNumber: 100})
+	fallback := startTestProvider(t, &fakeProviderAPI{blockNumber: 101})
+
+	limiter := NewUpstreamLimiter(config.UpstreamConfig{Burst: 10, DailyBudget: 100})
+	pf, err := NewProviderFailover(context.Background(), ProviderFailoverConfig{
+		Name:      "test-provider-failover",
+		Providers: []config.JsonRpcConfig{{Url: primary.URL}, {Url: fallback.URL}},
+		Failover:  testFailoverConfig(),
+		Limiter:   limiter,
+	})
+	r.NoError(err)
+	r.NoError(pf.serve())
+	defer pf.Stop()
+
+	requireBlockNumber(t, pf.URL(), 100)
+
+	// the requests are forwarded to the next provider before the next check
+	primary.Close()
+	requireBlockNumber(t, pf.URL(), 101)
+
+	// the retried request is charged once
+	r.Equal(map[string]int64{"eth_blockNumber": 2}, limiter.MethodCalls())
+
+	pf.checkProviders()
+	r.Equal(1, pf.activeProvider())
+}
+
+func TestProviderFailover_SetProviders(t *testing.T) {
+	r := require.New(t)
+
+	primary := startTestProvider(t, &fakeProviderAPI{blockNumber: 100})
+	fallback := startTestProvider(t, &fakeProviderAPI{blockNumber: 101})
+
+	pf, err := NewProviderFailover(context.Background(), ProviderFailoverConfig{
+		Name:      "test-provider-failover",
+		Providers: []config.JsonRpcConfig{{Url: primary.URL}},
+		Failover:  testFailoverConfig(),
+	})
+	r.NoError(err)
+	r.NoError(pf.serve())
+	defer pf.Stop()
+
+	requireBlockNumber(t, pf.URL(), 100)
+
+	// the active provider stays active after adding a provider
+	r.NoError(pf.SetProviders([]config.JsonRpcConfig{{Url: fallback.URL}, {Url: primary.URL}}))
+	r.Equal(1, pf.activeProvider())
+	requireBlockNumber(t, pf.URL(), 100)
+
+	// the requests go to the new provider after removing the active one
+	r.NoError(pf.SetProviders([]config.JsonRpcConfig{{Url: fallback.URL}}))
+	r.Equal(0, pf.activeProvider())
+	requireBlockNumber(t, pf.URL(), 101)
+
+	r.Error(pf.SetProviders(nil))
+}
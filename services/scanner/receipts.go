@@ -0,0 +1,336 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"golang.org/x/sync/errgroup"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// receiptBlockCacheSize is the number of blocks which the receipts are kept for. The transactions
+// of a block are processed concurrently, so only the last few blocks need to be kept.
+const receiptBlockCacheSize = 10
+
+// methodNotFoundCode is the JSON-RPC error code of the unsupported methods.
+const methodNotFoundCode = -32601
+
+type batchCaller interface {
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+type receiptCaller interface {
+	batchCaller
+	rpcCaller
+}
+
+// blockReceiptsMethod is a method which returns all receipts of a block in one call.
+type blockReceiptsMethod struct {
+	name string
+	args func(block *domain.Block) []interface{}
+}
+
+var blockReceiptsMethods = []blockReceiptsMethod{
+	{
+		name: "eth_getBlockReceipts",
+		args: func(block *domain.Block) []interface{} { return []interface{}{block.Hash} },
+	},
+	{
+		name: "parity_getBlockReceipts",
+		args: func(block *domain.Block) []interface{} { return []interface{}{block.Number} },
+	},
+}
+
+// ReceiptFetcher fetches the receipts of all transactions of a block when the receipt of a transaction
+// from that block is requested for the first time. The receipts are fetched in one call if the provider
+// supports one of the block receipts methods. Otherwise, they are fetched in JSON-RPC batches which
+// are requested concurrently by up to the configured number of workers.
+type ReceiptFetcher struct {
+	client    receiptCaller
+	batchSize int64
+	workers   int
+
+	// the block receipts methods which are not known to be unsupported
+	blockMethods   []blockReceiptsMethod
+	blockMethodsMu sync.Mutex
+
+	blocks     map[string]*blockReceipts
+	blockOrder []string
+	mu         sync.Mutex
+}
+
+// Receipt is a transaction receipt with the L2 fields.
+type Receipt struct {
+	domain.TransactionReceipt
+	L2Fields
+}
+
+// L2Fields are the receipt fields which the L2 chains add.
+type L2Fields struct {
+	Type *string `json:"type"`
+	// Optimism
+	L1Fee       *string `json:"l1Fee"`
+	L1GasUsed   *string `json:"l1GasUsed"`
+	L1GasPrice  *string `json:"l1GasPrice"`
+	L1FeeScalar *string `json:"l1FeeScalar"`
+	// Arbitrum
+	GasUsedForL1  *string `json:"gasUsedForL1"`
+	L1BlockNumber *string `json:"l1BlockNumber"`
+}
+
+type blockReceipts struct {
+	receipts map[string]*Receipt
+	mu       sync.Mutex
+}
+
+// GetReceipt returns the receipt of a transaction from the block.
+func (rf *ReceiptFetcher) GetReceipt(ctx context.Context, block *domain.Block, txHash string) (*domain.TransactionReceipt, error) {
+	receipt, err := rf.getReceipt(ctx, block, txHash)
+	if err != nil {
+		return nil, err
+	}
+	return &receipt.TransactionReceipt, nil
+}
+
+// GetL2Fields returns the L2 fields of the receipt of a transaction from the block.
+func (rf *ReceiptFetcher) GetL2Fields(ctx context.Context, block *domain.Block, txHash string) (*L2Fields, error) {
+	receipt, err := rf.getReceipt(ctx, block, txHash)
+	if err != nil {
+		return nil, err
+	}
+	return &receipt.L2Fields, nil
+}
+
+func (rf *ReceiptFetcher) getReceipt(ctx context.Context, block *domain.Block, txHash string) (*Receipt, error) {
+	receipts, err := rf.getReceipts(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	receipt, ok := receipts[strings.ToLower(txHash)]
+	if !ok {
+		return nil, fmt.Errorf("receipt not found for tx %s", txHash)
+	}
+	return receipt, nil
+}
+
+// Prefetch starts fetching the receipts of the block before its transactions are processed.
+func (rf *ReceiptFetcher) Prefetch(ctx context.Context, block *domain.Block) {
+	go func() {
+		if _, err := rf.getReceipts(ctx, block); err != nil {
+			log.WithError(err).WithField("block", block.Hash).Warn("failed to prefetch receipts")
+		}
+	}()
+}
+
+func (rf *ReceiptFetcher) getReceipts(ctx context.Context, block *domain.Block) (map[string]*Receipt, error) {
+	br := rf.getBlock(block.Hash)
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	if br.receipts == nil {
+		receipts, err := rf.fetchReceipts(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		br.receipts = receipts
+	}
+	return br.receipts, nil
+}
+
+func (rf *ReceiptFetcher) getBlock(blockHash string) *blockReceipts {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	br, ok := rf.blocks[blockHash]
+	if ok {
+		return br
+	}
+	br = &blockReceipts{}
+	rf.blocks[blockHash] = br
+	rf.blockOrder = append(rf.blockOrder, blockHash)
+	if len(rf.blockOrder) > receiptBlockCacheSize {
+		delete(rf.blocks, rf.blockOrder[0])
+		rf.blockOrder = rf.blockOrder[1:]
+	}
+	return br
+}
+
+// isMethodNotFound tells if the provider doesn't support the method. The other errors don't remove
+// the method, even if their messages look similar.
+func isMethodNotFound(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode
+}
+
+func (rf *ReceiptFetcher) getBlockMethods() []blockReceiptsMethod {
+	rf.blockMethodsMu.Lock()
+	defer rf.blockMethodsMu.Unlock()
+	return rf.blockMethods
+}
+
+func (rf *ReceiptFetcher) removeBlockMethod(name string) {
+	rf.blockMethodsMu.Lock()
+	defer rf.blockMethodsMu.Unlock()
+	var methods []blockReceiptsMethod
+	for _, method := range rf.blockMethods {
+		if method.name != name {
+			methods = append(methods, method)
+		}
+	}
+	rf.blockMethods = methods
+	log.WithField("method", name).Info("json-rpc provider does not support the block receipts method")
+}
+
+// fetchBlockReceipts fetches all receipts of the block in one call. It returns false if none of the
+// block receipts methods is supported or the receipts don't match the block.
+func (rf *ReceiptFetcher) fetchBlockReceipts(ctx context.Context, block *domain.Block) (map[string]*Receipt, bool) {
+	for _, method := range rf.getBlockMethods() {
+		var results []*Receipt
+		err := rf.client.CallContext(ctx, &results, method.name, method.args(block)...)
+		if err != nil && isMethodNotFound(err) {
+			rf.removeBlockMethod(method.name)
+			continue
+		}
+		if err != nil {
+			log.WithError(err).WithField("method", method.name).Warn("failed to get block receipts")
+			return nil, false
+		}
+		if len(results) != len(block.Transactions) {
+			log.WithFields(log.Fields{
+				"method":   method.name,
+				"block":    block.Hash,
+				"receipts": len(results),
+			}).Warn("block receipts do not match the transactions")
+			return nil, false
+		}
+		receipts := make(map[string]*Receipt)
+		for _, receipt := range results {
+			if receipt == nil || receipt.TransactionHash == nil {
+				continue
+			}
+			receipts[strings.ToLower(*receipt.TransactionHash)] = receipt
+		}
+		return receipts, true
+	}
+	return nil, false
+}
+
+func (rf *ReceiptFetcher) fetchReceipts(ctx context.Context, block *domain.Block) (map[string]*Receipt, error) {
+	if len(block.Transactions) == 0 {
+		return map[string]*Receipt{}, nil
+	}
+	if receipts, ok := rf.fetchBlockReceipts(ctx, block); ok {
+		return receipts, nil
+	}
+
+	// every batch writes the receipts to its own range
+	results := make([]*Receipt, len(block.Transactions))
+	workers := make(chan struct{}, rf.workers)
+	grp, ctx := errgroup.WithContext(ctx)
+	batchSize := int(atomic.LoadInt64(&rf.batchSize))
+	for start := 0; start < len(block.Transactions); start += batchSize {
+		end := start + batchSize
+		if end > len(block.Transactions) {
+			end = len(block.Transactions)
+		}
+		start := start
+		txs := block.Transactions[start:end]
+		workers <- struct{}{}
+		grp.Go(func() error {
+			defer func() { <-workers }()
+			return rf.fetchBatch(ctx, txs, results[start:end])
+		})
+	}
+	if err := grp.Wait(); err != nil {
+		return nil, err
+	}
+
+	receipts := make(map[string]*Receipt)
+	for i, receipt := range results {
+		if receipt == nil {
+			continue
+		}
+		receipts[strings.ToLower(block.Transactions[i].Hash)] = receipt
+	}
+	return receipts, nil
+}
+
+func (rf *ReceiptFetcher) fetchBatch(ctx context.Context, txs []domain.Transaction, results []*Receipt) error {
+	batch := make([]rpc.BatchElem, 0, len(txs))
+	for _, tx := range txs {
+		batch = append(batch, rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{tx.Hash},
+			Result: &Receipt{},
+		})
+	}
+	if err := rf.client.BatchCallContext(ctx, batch); err != nil {
+		return err
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return fmt.Errorf("failed to get receipt for tx %s: %v", txs[i].Hash, elem.Error)
+		}
+		receipt := elem.Result.(*Receipt)
+		if receipt.TransactionHash == nil {
+			continue
+		}
+		results[i] = receipt
+	}
+	return nil
+}
+
+// SetBatchSize changes the number of receipts which are requested in a batch.
+func (rf *ReceiptFetcher) SetBatchSize(batchSize int) {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	atomic.StoreInt64(&rf.batchSize, int64(batchSize))
+}
+
+// applyReceipt replaces the receipt fields which are derived from the transaction with the
+// ones from the actual receipt.
+func applyReceipt(msg *protocol.TransactionEvent, receipt *domain.TransactionReceipt) {
+	if msg.Receipt == nil || receipt == nil {
+		return
+	}
+	setIfNotNil := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setIfNotNil(&msg.Receipt.Status, receipt.Status)
+	setIfNotNil(&msg.Receipt.GasUsed, receipt.GasUsed)
+	setIfNotNil(&msg.Receipt.CumulativeGasUsed, receipt.CumulativeGasUsed)
+	setIfNotNil(&msg.Receipt.LogsBloom, receipt.LogsBloom)
+	if receipt.ContractAddress != nil {
+		msg.Receipt.ContractAddress = strings.ToLower(*receipt.ContractAddress)
+	}
+}
+
+// NewReceiptFetcher creates a new receipt fetcher.
+func NewReceiptFetcher(client *rpc.Client, batchSize, workers int) *ReceiptFetcher {
+	return newReceiptFetcher(client, batchSize, workers)
+}
+
+func newReceiptFetcher(client receiptCaller, batchSize, workers int) *ReceiptFetcher {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return &ReceiptFetcher{
+		client:       client,
+		batchSize:    int64(batchSize),
+		workers:      workers,
+		blockMethods: blockReceiptsMethods,
+		blocks:       make(map[string]*blockReceipts),
+	}
+}
@@ -0,0 +1,156 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReceiptAPI struct{}
+
+func (api *fakeReceiptAPI) GetTransactionReceipt(hash common.Hash) *domain.TransactionReceipt {
+	if hash == testMissingTxHash {
+		return nil
+	}
+	txHash := hash.Hex()
+	status := "0x0"
+	return &domain.TransactionReceipt{TransactionHash: &txHash, Status: &status}
+}
+
+type fakeBlockReceiptsAPI struct {
+	fakeReceiptAPI
+}
+
+func (api *fakeBlockReceiptsAPI) GetBlockReceipts(blockHash string) []*domain.TransactionReceipt {
+	return []*domain.TransactionReceipt{
+		api.GetTransactionReceipt(testPendingTxHash),
+		api.GetTransactionReceipt(testMinedTxHash),
+	}
+}
+
+type countingBatchCaller struct {
+	client *rpc.Client
+
+	mu      sync.Mutex
+	batches []int
+	calls   []string
+}
+
+func (c *countingBatchCaller) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	c.mu.Lock()
+	c.calls = append(c.calls, method)
+	c.mu.Unlock()
+	return c.client.CallContext(ctx, result, method, args...)
+}
+
+func (c *countingBatchCaller) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	c.mu.Lock()
+	c.batches = append(c.batches, len(b))
+	c.mu.Unlock()
+	return c.client.BatchCallContext(ctx, b)
+}
+
+func TestReceiptFetcher(t *testing.T) {
+	r := require.New(t)
+
+	server := rpc.NewServer()
+	r.NoError(server.RegisterName("eth", &fakeReceiptAPI{}))
+	caller := &countingBatchCaller{client: rpc.DialInProc(server)}
+	rf := newReceiptFetcher(caller, 2, 2)
+
+	block := &domain.Block{
+		Hash: "0xb1",
+		Transactions: []domain.Transaction{
+			{Hash: testPendingTxHash.Hex()},
+			{Hash: testMinedTxHash.Hex()},
+			{Hash: testMissingTxHash.Hex()},
+		},
+	}
+	for _, tx := range block.Transactions[:2] {
+		receipt, err := rf.GetReceipt(context.Background(), block, tx.Hash)
+		r.NoError(err)
+		r.Equal(tx.Hash, *receipt.TransactionHash)
+	}
+	_, err := rf.GetReceipt(context.Background(), block, testMissingTxHash.Hex())
+	r.Error(err)
+
+	// the receipts of the block are fetched once in concurrent batches of two
+	r.ElementsMatch([]int{2, 1}, caller.batches)
+
+	// the unsupported block receipts methods are not tried again
+	r.Equal([]string{"eth_getBlockReceipts", "parity_getBlockReceipts"}, caller.calls)
+	_, err = rf.GetReceipt(context.Background(), &domain.Block{
+		Hash:         "0xb2",
+		Transactions: []domain.Transaction{{Hash: testMinedTxHash.Hex()}},
+	}, testMinedTxHash.Hex())
+	r.NoError(err)
+	r.Len(caller.calls, 2)
+}
+
+func TestReceiptFetcher_BlockReceipts(t *testing.T) {
+	r := require.New(t)
+
+	server := rpc.NewServer()
+	r.NoError(server.RegisterName("eth", &fakeBlockReceiptsAPI{}))
+	caller := &countingBatchCaller{client: rpc.DialInProc(server)}
+	rf := newReceiptFetcher(caller, 1, 1)
+
+	block := &domain.Block{
+		Hash: "0xb1",
+		Transactions: []domain.Transaction{
+			{Hash: testPendingTxHash.Hex()},
+			{Hash: testMinedTxHash.Hex()},
+		},
+	}
+	for _, tx := range block.Transactions {
+		receipt, err := rf.GetReceipt(context.Background(), block, tx.Hash)
+		r.NoError(err)
+		r.Equal(tx.Hash, *receipt.TransactionHash)
+	}
+
+	// all receipts are fetched in one call
+	r.Equal([]string{"eth_getBlockReceipts"}, caller.calls)
+	r.Empty(caller.batches)
+}
+
+type testRPCError struct {
+	code int
+}
+
+func (e testRPCError) Error() string  { return "method not found" }
+func (e testRPCError) ErrorCode() int { return e.code }
+
+func TestIsMethodNotFound(t *testing.T) {
+	r := require.New(t)
+
+	r.True(isMethodNotFound(testRPCError{code: methodNotFoundCode}))
+	r.True(isMethodNotFound(fmt.Errorf("failed: %w", testRPCError{code: methodNotFoundCode})))
+	r.False(isMethodNotFound(testRPCError{code: -32000}))
+	r.False(isMethodNotFound(errors.New("block receipts are not available")))
+}
+
+func TestApplyReceipt(t *testing.T) {
+	r := require.New(t)
+
+	status := "0x0"
+	gasUsed := "0x5208"
+	contractAddr := "0xABCD"
+	msg := &protocol.TransactionEvent{Receipt: &protocol.TransactionEvent_EthReceipt{Status: "0x1", GasUsed: "0x10000"}}
+	applyReceipt(msg, &domain.TransactionReceipt{Status: &status, GasUsed: &gasUsed, ContractAddress: &contractAddr})
+	r.Equal("0x0", msg.Receipt.Status)
+	r.Equal("0x5208", msg.Receipt.GasUsed)
+	r.Equal("0xabcd", msg.Receipt.ContractAddress)
+
+	// without a receipt, the derived fields are kept
+	msg = &protocol.TransactionEvent{Receipt: &protocol.TransactionEvent_EthReceipt{Status: "0x1"}}
+	applyReceipt(msg, nil)
+	r.Equal("0x1", msg.Receipt.Status)
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-node/clients/messaging"
 	"github.com/forta-network/forta-node/metrics"
+	"github.com/forta-network/forta-node/store"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/forta-network/forta-core-go/domain"
 	"github.com/forta-network/forta-core-go/protocol"
@@ -34,6 +36,9 @@ type TxAnalyzerServiceConfig struct {
 	AlertSender clients.AlertSender
 	AgentPool   AgentPool
 	MsgClient   clients.MessageClient
+	L2Enricher  *L2Enricher
+	Canaries    *CanaryService
+	Checkpoint  *BlockCheckpoint
 }
 
 // WARNING, this must be deterministic (any maps must be converted to sorted lists)
@@ -72,11 +77,14 @@ func (t *TxAnalyzerService) findingToAlert(result *TxResult, ts time.Time, f *pr
 		return nil, err
 	}
 
-	tags := map[string]string{
+	tags := store.AlertSchemaTags(map[string]string{
 		"agentImage": result.AgentConfig.Image,
 		"agentId":    result.AgentConfig.ID,
 		"chainId":    chainId.String(),
-	}
+	})
+	tags[store.AlertHashTag] = store.FindingHash(
+		result.AgentConfig.ID, chainId.String(), result.Request.Event.Block.BlockHash, result.Request.Event.Transaction.Hash, f,
+	)
 
 	alertType := protocol.AlertType_PRIVATE
 	if !f.Private && !result.Response.Private {
@@ -102,6 +110,10 @@ func (t *TxAnalyzerService) Start() error {
 
 	go func() {
 		for result := range t.cfg.AgentPool.TxResults() {
+			// the canary findings are only checked
+			if t.cfg.Canaries != nil && t.cfg.Canaries.HandleResult(result) {
+				continue
+			}
 			ts := time.Now().UTC()
 
 			rt := &clients.AgentRoundTrip{
@@ -141,20 +153,7 @@ func (t *TxAnalyzerService) Start() error {
 	go func() {
 		// for each transaction
 		for tx := range t.cfg.TxChannel {
-			// convert to message
-			msg, err := tx.ToMessage()
-			if err != nil {
-				log.WithError(err).Error("error converting tx event to message (skipping)")
-				continue
-			}
-
-			// create a request
-			requestId := uuid.Must(uuid.NewUUID())
-			request := &protocol.EvaluateTxRequest{RequestId: requestId.String(), Event: msg}
-
-			// forward to the pool
-			t.cfg.AgentPool.SendEvaluateTxRequest(request)
-
+			t.analyzeTx(tx)
 			t.lastInputActivity.Set()
 		}
 	}()
@@ -162,6 +161,34 @@ func (t *TxAnalyzerService) Start() error {
 	return nil
 }
 
+// analyzeTx sends the transaction to the agents and marks it on the checkpoint after the agents got it.
+func (t *TxAnalyzerService) analyzeTx(tx *domain.TransactionEvent) {
+	if t.cfg.Checkpoint != nil {
+		defer func() {
+			if blockNum, err := hexutil.DecodeUint64(tx.BlockEvt.Block.Number); err == nil {
+				t.cfg.Checkpoint.TxDone(blockNum)
+			}
+		}()
+	}
+	// convert to message
+	msg, err := tx.ToMessage()
+	if err != nil {
+		log.WithError(err).Error("error converting tx event to message (skipping)")
+		return
+	}
+	applyReceipt(msg, tx.Receipt)
+	if t.cfg.L2Enricher != nil {
+		t.cfg.L2Enricher.Enrich(t.ctx, msg, tx)
+	}
+
+	// create a request
+	requestId := uuid.Must(uuid.NewUUID())
+	request := &protocol.EvaluateTxRequest{RequestId: requestId.String(), Event: msg}
+
+	// forward to the pool
+	t.cfg.AgentPool.SendEvaluateTxRequest(request)
+}
+
 func (t *TxAnalyzerService) Stop() error {
 	log.Infof("Stopping %s", t.Name())
 	return nil
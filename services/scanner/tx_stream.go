@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/domain"
 	"github.com/forta-network/forta-core-go/ethereum"
@@ -29,8 +30,14 @@ type TxStreamServiceConfig struct {
 	JsonRpcConfig       config.JsonRpcConfig
 	TraceJsonRpcConfig  config.JsonRpcConfig
 	SkipBlocksOlderThan *time.Duration
+	ReceiptFetcher      *ReceiptFetcher
+	LagTracker          *ChainLagTracker
+	Checkpoint          *BlockCheckpoint
 }
 
+// checkpointSaveInterval is how often the block checkpoint is written.
+const checkpointSaveInterval = 10 * time.Second
+
 func (t *TxStreamService) ReadOnlyBlockStream() <-chan *domain.BlockEvent {
 	return t.blockOutput
 }
@@ -39,13 +46,40 @@ func (t *TxStreamService) ReadOnlyTxStream() <-chan *domain.TransactionEvent {
 	return t.txOutput
 }
 
+// Checkpoint returns the block checkpoint which the handled transactions should be marked on.
+func (t *TxStreamService) Checkpoint() *BlockCheckpoint {
+	return t.cfg.Checkpoint
+}
+
+// ReceiptFetcher returns the receipt fetcher of the stream if the receipts are fetched.
+func (t *TxStreamService) ReceiptFetcher() *ReceiptFetcher {
+	return t.cfg.ReceiptFetcher
+}
+
 func (t *TxStreamService) handleBlock(evt *domain.BlockEvent) error {
+	if t.cfg.ReceiptFetcher != nil {
+		t.cfg.ReceiptFetcher.Prefetch(t.ctx, evt.Block)
+	}
+	blockNum, err := hexutil.DecodeUint64(evt.Block.Number)
+	if err == nil && t.cfg.LagTracker != nil {
+		t.cfg.LagTracker.SetProcessed(int64(blockNum))
+	}
+	if err == nil && t.cfg.Checkpoint != nil {
+		t.cfg.Checkpoint.BlockStarted(blockNum, len(evt.Block.Transactions))
+	}
 	t.blockOutput <- evt
 	t.lastBlockActivity.Set()
 	return nil
 }
 
 func (t *TxStreamService) handleTx(evt *domain.TransactionEvent) error {
+	if t.cfg.ReceiptFetcher != nil {
+		receipt, err := t.cfg.ReceiptFetcher.GetReceipt(t.ctx, evt.BlockEvt.Block, evt.Transaction.Hash)
+		if err != nil {
+			log.WithError(err).WithField("tx", evt.Transaction.Hash).Warn("failed to get receipt")
+		}
+		evt.Receipt = receipt
+	}
 	t.txOutput <- evt
 	t.lastTxActivity.Set()
 	return nil
@@ -53,6 +87,12 @@ func (t *TxStreamService) handleTx(evt *domain.TransactionEvent) error {
 
 func (t *TxStreamService) Start() error {
 	log.Infof("Starting %s", t.Name())
+	if t.cfg.LagTracker != nil {
+		t.cfg.LagTracker.Start()
+	}
+	if t.cfg.Checkpoint != nil {
+		go t.cfg.Checkpoint.saveLoop(t.ctx, checkpointSaveInterval)
+	}
 	go func() {
 		if err := t.txFeed.ForEachTransaction(t.handleBlock, t.handleTx); err != nil {
 			log.WithError(err).Panic("tx feed error")
@@ -63,6 +103,11 @@ func (t *TxStreamService) Start() error {
 
 func (t *TxStreamService) Stop() error {
 	log.Infof("Stopping %s", t.Name())
+	if t.cfg.Checkpoint != nil {
+		if err := t.cfg.Checkpoint.Save(); err != nil {
+			log.WithError(err).Warn("failed to save the block checkpoint")
+		}
+	}
 	if t.txOutput != nil {
 		close(t.txOutput)
 	}
@@ -78,13 +123,20 @@ func (t *TxStreamService) Name() string {
 
 // Health implements health.Reporter interface.
 func (t *TxStreamService) Health() health.Reports {
-	return health.Reports{
+	reports := health.Reports{
 		t.lastBlockActivity.GetReport("event.block.time"),
 		t.lastTxActivity.GetReport("event.transaction.time"),
 	}
+	if t.cfg.LagTracker != nil {
+		reports = append(reports, t.cfg.LagTracker.Health()...)
+	}
+	if t.cfg.Checkpoint != nil {
+		reports = append(reports, t.cfg.Checkpoint.Health()...)
+	}
+	return reports
 }
 
-func NewTxStreamService(ctx context.Context, ethClient ethereum.Client, blockFeed feeds.BlockFeed, cfg TxStreamServiceConfig) (*TxStreamService, error) {
+func NewTxStreamService(ctx context.Context, ethClient ethereum.Client, blockFeed DataSource, cfg TxStreamServiceConfig) (*TxStreamService, error) {
 	txOutput := make(chan *domain.TransactionEvent)
 	blockOutput := make(chan *domain.BlockEvent)
 
@@ -0,0 +1,167 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/config"
+	"golang.org/x/time/rate"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// UpstreamLimiter delays the requests to the JSON-RPC providers to stay under the configured rate and
+// daily budget, and counts the calls per method.
+type UpstreamLimiter struct {
+	cfg     config.UpstreamConfig
+	limiter *rate.Limiter
+	now     func() time.Time
+
+	day         time.Time
+	used        int64
+	methodCalls map[string]int64
+	mu          sync.Mutex
+
+	lastWait health.MessageTracker
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// reserve counts the calls and returns how long they should wait for the budget of the next day.
+func (ul *UpstreamLimiter) reserve(methods []string) time.Duration {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+
+	now := ul.now()
+	if day := startOfDay(now); day.After(ul.day) {
+		ul.day = day
+		ul.used = 0
+	}
+	for _, method := range methods {
+		ul.methodCalls[method]++
+	}
+
+	n := int64(len(methods))
+	if ul.cfg.DailyBudget == 0 {
+		return 0
+	}
+	nextDay := ul.day.Add(24 * time.Hour)
+	if ul.used+n > ul.cfg.DailyBudget {
+		// the calls are counted toward the next day
+		ul.day = nextDay
+		ul.used = n
+		return nextDay.Sub(now)
+	}
+	ul.used += n
+
+	// spread the remaining budget over the rest of the day
+	limit := rate.Limit(float64(ul.cfg.DailyBudget-ul.used) / nextDay.Sub(now).Seconds())
+	if ul.cfg.Rate > 0 && rate.Limit(ul.cfg.Rate) < limit {
+		limit = rate.Limit(ul.cfg.Rate)
+	}
+	ul.limiter.SetLimitAt(now, limit)
+	return 0
+}
+
+// Wait blocks until the calls to the methods are allowed.
+func (ul *UpstreamLimiter) Wait(ctx context.Context, methods []string) error {
+	if delay := ul.reserve(methods); delay > 0 {
+		log.WithField("delay", delay).Warn("daily json-rpc budget is used - waiting for the next day")
+		ul.lastWait.Set(fmt.Sprintf("daily budget is used, waiting for %s", delay))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	if ul.limiter.Limit() == rate.Inf {
+		return nil
+	}
+	// the burst is the max number of calls at once
+	n := len(methods)
+	if n > ul.limiter.Burst() {
+		n = ul.limiter.Burst()
+	}
+	return ul.limiter.WaitN(ctx, n)
+}
+
+// MethodCalls returns the number of calls per method.
+func (ul *UpstreamLimiter) MethodCalls() map[string]int64 {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+	calls := make(map[string]int64, len(ul.methodCalls))
+	for method, count := range ul.methodCalls {
+		calls[method] = count
+	}
+	return calls
+}
+
+// Health implements the health.Reporter interface.
+func (ul *UpstreamLimiter) Health() health.Reports {
+	calls := ul.MethodCalls()
+	methods := make([]string, 0, len(calls))
+	for method := range calls {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	reports := health.Reports{ul.lastWait.GetReport("upstream.budget.wait")}
+	ul.mu.Lock()
+	if ul.cfg.DailyBudget > 0 {
+		reports = append(reports, &health.Report{
+			Name:    "upstream.budget.used",
+			Status:  health.StatusInfo,
+			Details: fmt.Sprintf("%d/%d", ul.used, ul.cfg.DailyBudget),
+		})
+	}
+	ul.mu.Unlock()
+	for _, method := range methods {
+		reports = append(reports, &health.Report{
+			Name:    fmt.Sprintf("upstream.calls.%s", method),
+			Status:  health.StatusInfo,
+			Details: fmt.Sprintf("%d", calls[method]),
+		})
+	}
+	return reports
+}
+
+// SetConfig changes the limits. The calls which were counted today still use the daily budget.
+func (ul *UpstreamLimiter) SetConfig(cfg config.UpstreamConfig) {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+	ul.cfg = cfg
+	limit, burst := upstreamLimits(cfg)
+	now := ul.now()
+	ul.limiter.SetLimitAt(now, limit)
+	ul.limiter.SetBurstAt(now, burst)
+}
+
+func upstreamLimits(cfg config.UpstreamConfig) (rate.Limit, int) {
+	limit := rate.Inf
+	if cfg.Rate > 0 {
+		limit = rate.Limit(cfg.Rate)
+	}
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return limit, burst
+}
+
+// NewUpstreamLimiter creates a new limiter for the upstream requests.
+func NewUpstreamLimiter(cfg config.UpstreamConfig) *UpstreamLimiter {
+	limit, burst := upstreamLimits(cfg)
+	return &UpstreamLimiter{
+		cfg:         cfg,
+		limiter:     rate.NewLimiter(limit, burst),
+		now:         time.Now,
+		methodCalls: make(map[string]int64),
+	}
+}
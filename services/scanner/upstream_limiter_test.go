@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestUpstreamLimiter_Budget(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Date(2022, 5, 10, 12, 0, 0, 0, time.UTC)
+	ul := NewUpstreamLimiter(config.UpstreamConfig{Burst: 10, DailyBudget: 3})
+	ul.now = func() time.Time { return now }
+
+	r.Zero(ul.reserve([]string{"eth_blockNumber"}))
+	r.Zero(ul.reserve([]string{"eth_getLogs", "eth_getLogs"}))
+	r.Equal(map[string]int64{"eth_blockNumber": 1, "eth_getLogs": 2}, ul.MethodCalls())
+
+	// the budget is used: wait for the next day
+	r.Equal(12*time.Hour, ul.reserve([]string{"eth_blockNumber"}))
+
+	// the next day has a new budget
+	now = now.Add(12 * time.Hour)
+	r.Zero(ul.reserve([]string{"eth_blockNumber"}))
+	r.Equal(int64(2), ul.used)
+}
+
+func TestUpstreamLimiter_Rate(t *testing.T) {
+	r := require.New(t)
+
+	ul := NewUpstreamLimiter(config.UpstreamConfig{Rate: 1000, Burst: 1})
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		r.NoError(ul.Wait(context.Background(), []string{"eth_blockNumber"}))
+	}
+	r.GreaterOrEqual(time.Since(start), 3*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.Error(ul.Wait(ctx, []string{"eth_blockNumber"}))
+}
+
+func TestUpstreamLimiter_SetConfig(t *testing.T) {
+	r := require.New(t)
+
+	ul := NewUpstreamLimiter(config.UpstreamConfig{Burst: 10})
+	r.Equal(rate.Inf, ul.limiter.Limit())
+
+	ul.SetConfig(config.UpstreamConfig{Rate: 5, Burst: 2})
+	r.Equal(rate.Limit(5), ul.limiter.Limit())
+	r.Equal(2, ul.limiter.Burst())
+}
@@ -0,0 +1,318 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/feeds"
+	"github.com/forta-network/forta-core-go/protocol"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const userOpStreamBlockBufferSize = 10
+
+// UserOperationFieldNumber is the field number of the user operation in the transaction events. Like the
+// L2 info, the field is sent as an extension field which the agents can read by adding the UserOperation
+// message below to their definitions:
+//
+//	message UserOperation {
+//	  string sender = 1;
+//	  string nonce = 2;
+//	  string initCode = 3;
+//	  string callData = 4;
+//	  string callGasLimit = 5;
+//	  string verificationGasLimit = 6;
+//	  string preVerificationGas = 7;
+//	  string maxFeePerGas = 8;
+//	  string maxPriorityFeePerGas = 9;
+//	  string paymasterAndData = 10;
+//	  string signature = 11;
+//	  string entryPoint = 12;
+//	  string beneficiary = 13;
+//	  uint32 index = 14;
+//	  string aggregator = 15;
+//	}
+//
+//	UserOperation userOp = 1001; // in TransactionEvent
+const UserOperationFieldNumber = 1001
+
+const userOpComponents = `{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},` +
+	`{"name":"initCode","type":"bytes"},{"name":"callData","type":"bytes"},{"name":"callGasLimit","type":"uint256"},` +
+	`{"name":"verificationGasLimit","type":"uint256"},{"name":"preVerificationGas","type":"uint256"},` +
+	`{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},` +
+	`{"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}`
+
+const entryPointABI = `[{"type":"function","name":"handleOps","inputs":[` +
+	`{"name":"ops","type":"tuple[]","components":[` + userOpComponents + `]},` +
+	`{"name":"beneficiary","type":"address"}],"outputs":[]},` +
+	`{"type":"function","name":"handleAggregatedOps","inputs":[` +
+	`{"name":"opsPerAggregator","type":"tuple[]","components":[` +
+	`{"name":"userOps","type":"tuple[]","components":[` + userOpComponents + `]},` +
+	`{"name":"aggregator","type":"address"},{"name":"signature","type":"bytes"}]},` +
+	`{"name":"beneficiary","type":"address"}],"outputs":[]}]`
+
+var (
+	handleOpsMethod           = mustParseEntryPointMethod("handleOps")
+	handleAggregatedOpsMethod = mustParseEntryPointMethod("handleAggregatedOps")
+)
+
+func mustParseEntryPointMethod(name string) abi.Method {
+	parsed, err := abi.JSON(strings.NewReader(entryPointABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed.Methods[name]
+}
+
+// UserOperation is an ERC-4337 user operation.
+type UserOperation struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+// UserOpsPerAggregator is a group of the user operations in a handleAggregatedOps call.
+type UserOpsPerAggregator struct {
+	UserOps    []UserOperation
+	Aggregator common.Address
+	Signature  []byte
+}
+
+// HandleOpsCall is a decoded handleOps or handleAggregatedOps call. Aggregators has the aggregator of
+// each user operation if the call is a handleAggregatedOps call.
+type HandleOpsCall struct {
+	EntryPoint  string
+	Ops         []UserOperation
+	Aggregators []common.Address
+	Beneficiary common.Address
+}
+
+// DecodeHandleOps decodes the user operations of a handleOps or a handleAggregatedOps call. It returns
+// false if the input is not one of these calls.
+func DecodeHandleOps(input string) (*HandleOpsCall, bool, error) {
+	data, err := hexutil.Decode(input)
+	if err != nil || len(data) < 4 {
+		return nil, false, nil
+	}
+	switch {
+	case bytes.Equal(data[:4], handleOpsMethod.ID):
+		args, err := handleOpsMethod.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to decode handleOps: %v", err)
+		}
+		ops := *abi.ConvertType(args[0], new([]UserOperation)).(*[]UserOperation)
+		return &HandleOpsCall{Ops: ops, Beneficiary: args[1].(common.Address)}, true, nil
+
+	case bytes.Equal(data[:4], handleAggregatedOpsMethod.ID):
+		args, err := handleAggregatedOpsMethod.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to decode handleAggregatedOps: %v", err)
+		}
+		groups := *abi.ConvertType(args[0], new([]UserOpsPerAggregator)).(*[]UserOpsPerAggregator)
+		call := &HandleOpsCall{Beneficiary: args[1].(common.Address)}
+		for _, group := range groups {
+			for _, op := range group.UserOps {
+				call.Ops = append(call.Ops, op)
+				call.Aggregators = append(call.Aggregators, group.Aggregator)
+			}
+		}
+		return call, true, nil
+
+	default:
+		return nil, false, nil
+	}
+}
+
+// UserOpStreamService decodes the EntryPoint handleOps calls into user operations and delivers each
+// user operation to the agents which subscribe to user operations. The user operations are decoded from
+// the calls, so the operations of the reverted transactions are delivered too.
+type UserOpStreamService struct {
+	ctx         context.Context
+	cfg         UserOpStreamServiceConfig
+	entryPoints map[string]bool
+	blockCh     chan *domain.BlockEvent
+
+	lastBlockActivity  health.TimeTracker
+	lastUserOpActivity health.TimeTracker
+	lastErr            health.ErrorTracker
+}
+
+type UserOpStreamServiceConfig struct {
+	BlockFeed   DataSource
+	AgentPool   AgentPool
+	EntryPoints []string
+}
+
+// handleBlock queues the block without blocking the block feed. The block is skipped if the user
+// operations of the previous blocks are still being delivered.
+func (u *UserOpStreamService) handleBlock(evt *domain.BlockEvent) error {
+	select {
+	case <-u.ctx.Done():
+		return u.ctx.Err()
+	case u.blockCh <- evt:
+	default:
+		log.WithField("block", evt.Block.Number).Warn("user operation stream buffer is full - skipping block")
+		u.lastErr.Set(fmt.Errorf("skipped block %s: buffer is full", evt.Block.Number))
+	}
+	return nil
+}
+
+func (u *UserOpStreamService) processBlocks() {
+	for evt := range u.blockCh {
+		u.lastBlockActivity.Set()
+		if !u.cfg.AgentPool.HasUserOpAgents() {
+			continue
+		}
+		for i := range evt.Block.Transactions {
+			tx := &evt.Block.Transactions[i]
+			if tx.To == nil || !u.entryPoints[strings.ToLower(*tx.To)] || tx.Input == nil {
+				continue
+			}
+			call, ok, err := DecodeHandleOps(*tx.Input)
+			if !ok {
+				continue
+			}
+			u.lastErr.Set(err)
+			if err != nil {
+				log.WithError(err).WithField("tx", tx.Hash).Warn("failed to decode user operations")
+				continue
+			}
+			call.EntryPoint = *tx.To
+			u.sendUserOps(evt, tx, call)
+		}
+	}
+}
+
+func (u *UserOpStreamService) sendUserOps(blockEvt *domain.BlockEvent, tx *domain.Transaction, call *HandleOpsCall) {
+	txEvt := &domain.TransactionEvent{
+		BlockEvt: &domain.BlockEvent{
+			EventType:  blockEvt.EventType,
+			Block:      blockEvt.Block,
+			ChainID:    blockEvt.ChainID,
+			Timestamps: blockEvt.Timestamps,
+		},
+		Transaction: tx,
+		Timestamps: &domain.TrackingTimestamps{
+			Block: blockEvt.Timestamps.Block,
+			Feed:  time.Now().UTC(),
+		},
+	}
+	for i := range call.Ops {
+		msg, err := txEvt.ToMessage()
+		if err != nil {
+			log.WithError(err).Error("error converting user operation event to message (skipping)")
+			return
+		}
+		setUserOperation(msg, call, i)
+		requestId := uuid.Must(uuid.NewUUID())
+		u.cfg.AgentPool.SendEvaluateUserOpRequest(&protocol.EvaluateTxRequest{RequestId: requestId.String(), Event: msg})
+		u.lastUserOpActivity.Set()
+	}
+}
+
+func appendBigIntField(b []byte, num protowire.Number, n *big.Int) []byte {
+	if n == nil {
+		return b
+	}
+	s := hexutil.EncodeBig(n)
+	return appendStringField(b, num, &s)
+}
+
+func appendBytesField(b []byte, num protowire.Number, data []byte) []byte {
+	s := hexutil.Encode(data)
+	return appendStringField(b, num, &s)
+}
+
+// setUserOperation adds the user operation at the index of the call to the message as an extension field.
+func setUserOperation(msg *protocol.TransactionEvent, call *HandleOpsCall, index int) {
+	op := call.Ops[index]
+	sender := strings.ToLower(op.Sender.Hex())
+	entryPoint := strings.ToLower(call.EntryPoint)
+	beneficiary := strings.ToLower(call.Beneficiary.Hex())
+
+	var b []byte
+	b = appendStringField(b, 1, &sender)
+	b = appendBigIntField(b, 2, op.Nonce)
+	b = appendBytesField(b, 3, op.InitCode)
+	b = appendBytesField(b, 4, op.CallData)
+	b = appendBigIntField(b, 5, op.CallGasLimit)
+	b = appendBigIntField(b, 6, op.VerificationGasLimit)
+	b = appendBigIntField(b, 7, op.PreVerificationGas)
+	b = appendBigIntField(b, 8, op.MaxFeePerGas)
+	b = appendBigIntField(b, 9, op.MaxPriorityFeePerGas)
+	b = appendBytesField(b, 10, op.PaymasterAndData)
+	b = appendBytesField(b, 11, op.Signature)
+	b = appendStringField(b, 12, &entryPoint)
+	b = appendStringField(b, 13, &beneficiary)
+	if index > 0 {
+		b = protowire.AppendTag(b, 14, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(index))
+	}
+	if index < len(call.Aggregators) {
+		aggregator := strings.ToLower(call.Aggregators[index].Hex())
+		b = appendStringField(b, 15, &aggregator)
+	}
+	appendExtensionField(msg, UserOperationFieldNumber, b)
+}
+
+func (u *UserOpStreamService) Start() error {
+	log.Infof("Starting %s", u.Name())
+	go u.processBlocks()
+	go func() {
+		if err := <-u.cfg.BlockFeed.Subscribe(u.handleBlock); err != nil && err != feeds.ErrEndBlockReached {
+			log.WithError(err).Error("user operation stream block subscription ended")
+		}
+	}()
+	return nil
+}
+
+func (u *UserOpStreamService) Stop() error {
+	log.Infof("Stopping %s", u.Name())
+	return nil
+}
+
+func (u *UserOpStreamService) Name() string {
+	return "user-op-stream"
+}
+
+// Health implements health.Reporter interface.
+func (u *UserOpStreamService) Health() health.Reports {
+	return health.Reports{
+		u.lastBlockActivity.GetReport("event.block.time"),
+		u.lastUserOpActivity.GetReport("event.user-op.time"),
+		u.lastErr.GetReport("event.user-op.error"),
+	}
+}
+
+func NewUserOpStreamService(ctx context.Context, cfg UserOpStreamServiceConfig) (*UserOpStreamService, error) {
+	entryPoints := make(map[string]bool)
+	for _, entryPoint := range cfg.EntryPoints {
+		entryPoints[strings.ToLower(entryPoint)] = true
+	}
+	return &UserOpStreamService{
+		ctx:         ctx,
+		cfg:         cfg,
+		entryPoints: entryPoints,
+		blockCh:     make(chan *domain.BlockEvent, userOpStreamBlockBufferSize),
+	}, nil
+}
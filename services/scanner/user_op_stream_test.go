@@ -0,0 +1,139 @@
+package scanner
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func packHandleOps(t *testing.T, ops []UserOperation, beneficiary common.Address) string {
+	data, err := handleOpsMethod.Inputs.Pack(ops, beneficiary)
+	require.NoError(t, err)
+	return hexutil.Encode(append(handleOpsMethod.ID, data...))
+}
+
+func testUserOps() []UserOperation {
+	return []UserOperation{
+		{
+			Sender:               common.HexToAddress("0x1"),
+			Nonce:                big.NewInt(1),
+			CallData:             []byte{1, 2},
+			CallGasLimit:         big.NewInt(100),
+			VerificationGasLimit: big.NewInt(200),
+			PreVerificationGas:   big.NewInt(300),
+			MaxFeePerGas:         big.NewInt(10),
+			MaxPriorityFeePerGas: big.NewInt(1),
+			Signature:            []byte{3},
+		},
+		{
+			Sender:               common.HexToAddress("0x2"),
+			Nonce:                big.NewInt(2),
+			CallGasLimit:         big.NewInt(100),
+			VerificationGasLimit: big.NewInt(200),
+			PreVerificationGas:   big.NewInt(300),
+			MaxFeePerGas:         big.NewInt(10),
+			MaxPriorityFeePerGas: big.NewInt(1),
+		},
+	}
+}
+
+func TestDecodeHandleOps(t *testing.T) {
+	r := require.New(t)
+
+	beneficiary := common.HexToAddress("0x3")
+	call, ok, err := DecodeHandleOps(packHandleOps(t, testUserOps(), beneficiary))
+	r.NoError(err)
+	r.True(ok)
+	r.Len(call.Ops, 2)
+	r.Equal(common.HexToAddress("0x1"), call.Ops[0].Sender)
+	r.Equal([]byte{1, 2}, call.Ops[0].CallData)
+	r.Equal(int64(2), call.Ops[1].Nonce.Int64())
+	r.Equal(beneficiary, call.Beneficiary)
+
+	// not a handleOps call
+	_, ok, err = DecodeHandleOps("0x12345678")
+	r.NoError(err)
+	r.False(ok)
+
+	// invalid arguments
+	_, ok, err = DecodeHandleOps(hexutil.Encode(handleOpsMethod.ID) + "00")
+	r.Error(err)
+	r.True(ok)
+}
+
+func TestDecodeHandleOps_Aggregated(t *testing.T) {
+	r := require.New(t)
+
+	ops := testUserOps()
+	groups := []UserOpsPerAggregator{
+		{UserOps: ops[:1], Aggregator: common.HexToAddress("0xa1"), Signature: []byte{1}},
+		{UserOps: ops[1:], Aggregator: common.HexToAddress("0xa2"), Signature: []byte{2}},
+	}
+	beneficiary := common.HexToAddress("0x3")
+	data, err := handleAggregatedOpsMethod.Inputs.Pack(groups, beneficiary)
+	r.NoError(err)
+
+	call, ok, err := DecodeHandleOps(hexutil.Encode(append(handleAggregatedOpsMethod.ID, data...)))
+	r.NoError(err)
+	r.True(ok)
+	r.Len(call.Ops, 2)
+	r.Equal(common.HexToAddress("0x2"), call.Ops[1].Sender)
+	r.Equal([]common.Address{common.HexToAddress("0xa1"), common.HexToAddress("0xa2")}, call.Aggregators)
+	r.Equal(beneficiary, call.Beneficiary)
+}
+
+func TestUserOpStreamService_HandleBlock(t *testing.T) {
+	r := require.New(t)
+
+	u, err := NewUserOpStreamService(context.Background(), UserOpStreamServiceConfig{})
+	r.NoError(err)
+
+	// the block feed is not blocked when the buffer is full
+	for i := 0; i < userOpStreamBlockBufferSize+1; i++ {
+		r.NoError(u.handleBlock(&domain.BlockEvent{Block: &domain.Block{Number: hexutil.EncodeUint64(uint64(i))}}))
+	}
+	r.Len(u.blockCh, userOpStreamBlockBufferSize)
+	r.NotEmpty(u.lastErr.String())
+}
+
+func TestSetUserOperation(t *testing.T) {
+	r := require.New(t)
+
+	call := &HandleOpsCall{
+		EntryPoint:  "0xEntryPoint",
+		Ops:         testUserOps(),
+		Beneficiary: common.HexToAddress("0x3"),
+	}
+	msg := &protocol.TransactionEvent{}
+	setUserOperation(msg, call, 1)
+
+	unknown := msg.ProtoReflect().GetUnknown()
+	num, _, n := protowire.ConsumeTag(unknown)
+	r.Equal(protowire.Number(UserOperationFieldNumber), num)
+	op, _ := protowire.ConsumeBytes(unknown[n:])
+
+	fields := make(map[protowire.Number][]byte)
+	for len(op) > 0 {
+		num, typ, n := protowire.ConsumeTag(op)
+		r.Greater(n, 0)
+		m := protowire.ConsumeFieldValue(num, typ, op[n:])
+		r.Greater(m, 0)
+		fields[num] = op[n : n+m]
+		op = op[n+m:]
+	}
+	sender, _ := protowire.ConsumeString(fields[1])
+	r.Equal("0x0000000000000000000000000000000000000002", sender)
+	nonce, _ := protowire.ConsumeString(fields[2])
+	r.Equal("0x2", nonce)
+	entryPoint, _ := protowire.ConsumeString(fields[12])
+	r.Equal("0xentrypoint", entryPoint)
+	index, _ := protowire.ConsumeVarint(fields[14])
+	r.Equal(uint64(1), index)
+}
@@ -12,7 +12,10 @@ import (
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/forta-network/forta-node/audit"
 	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/crash"
+	"github.com/forta-network/forta-node/logutils"
 )
 
 const (
@@ -30,7 +33,7 @@ type Service interface {
 	Name() string
 }
 
-var sigc = make(chan os.Signal)
+var sigc = make(chan os.Signal, 1)
 
 var execIDKey = struct{}{}
 
@@ -83,17 +86,34 @@ func ContainerMain(name string, getServices func(ctx context.Context, cfg config
 		return
 	}
 
-	lvl, err := log.ParseLevel(cfg.Log.Level)
+	lvl, err := log.ParseLevel(config.LogLevel(cfg))
 	if err != nil {
 		logger.WithError(err).Error("could not initialize log level")
 		return
 	}
 	log.SetLevel(lvl)
 	log.SetFormatter(&log.JSONFormatter{})
+	crash.Init(cfg, name)
+	defer crash.Capture(name)
+	logFile, err := logutils.InitFileOutput(cfg, name)
+	if err != nil {
+		logger.WithError(err).Error("could not initialize the log file")
+		return
+	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
+	auditLog, err := audit.Init(cfg, name)
+	if err != nil {
+		logger.WithError(err).Error("could not initialize the audit log")
+		return
+	}
+	defer auditLog.Close()
 	logger.Info("starting")
 	defer logger.Info("exiting")
 
-	ctx, cancel := InitMainContext()
+	// the containers reload the config instead of exiting on the reload signal
+	ctx, cancel := initMainContext(syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	defer cancel()
 
 	serviceList, err := getServices(ctx, cfg)
@@ -101,6 +121,9 @@ func ContainerMain(name string, getServices func(ctx context.Context, cfg config
 		logger.WithError(err).Error("could not initialize services")
 		return
 	}
+	crash.Go("watch-config", func() { watchConfig(ctx, logger, cfg, serviceList) })
+	crash.Go("watch-debug-dumps", func() { watchDebugDumpRequests(ctx, logger, name, cfg) })
+	StartPprof(ctx, cfg)
 
 	if err := StartServices(ctx, cancel, logger, serviceList); err != nil {
 		logger.WithError(err).Error("failed to start services")
@@ -115,13 +138,17 @@ func IsGracefulShutdown() bool {
 }
 
 func InitMainContext() (context.Context, context.CancelFunc) {
-	execIDCtx := initExecID(context.Background())
-	ctx, cancel := context.WithCancel(execIDCtx)
-	signal.Notify(sigc,
+	return initMainContext(
 		syscall.SIGHUP,
 		syscall.SIGINT,
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
+}
+
+func initMainContext(signals ...os.Signal) (context.Context, context.CancelFunc) {
+	execIDCtx := initExecID(context.Background())
+	ctx, cancel := context.WithCancel(execIDCtx)
+	signal.Notify(sigc, signals...)
 	go func() {
 		sig := <-sigc
 		log.Infof("received signal: %s", sig.String())
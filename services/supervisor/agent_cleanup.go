@@ -0,0 +1,194 @@
+package supervisor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAgentCleanupInterval  = time.Hour
+	defaultAgentImageGracePeriod = time.Hour * 24
+)
+
+var agentContainerNamePrefix = fmt.Sprintf("%s-agent-", config.ContainerNamePrefix)
+
+func (sup *SupervisorService) cleanUpAgents() {
+	ticker := time.NewTicker(defaultAgentCleanupInterval)
+	for {
+		select {
+		case <-sup.ctx.Done():
+			ticker.Stop()
+			return
+
+		case <-ticker.C:
+			sup.doCleanUpAgents()
+		}
+	}
+}
+
+// doCleanUpAgents removes the containers and the networks of the agents which do not run anymore
+// and the agent images which were not used during the grace period, so that the long running
+// nodes do not accumulate the stale layers of the old agent versions.
+func (sup *SupervisorService) doCleanUpAgents() {
+	err := sup.removeStoppedAgentContainers()
+	if err == nil {
+		err = sup.removeUnusedAgentImages()
+	}
+	sup.lastAgentCleanup.Set()
+	sup.lastAgentCleanupError.Set(err)
+	if err != nil {
+		log.WithError(err).Warn("failed to clean up the agents")
+	}
+}
+
+// removeStoppedAgentContainers removes the agent containers which are not managed anymore, with
+// their anonymous volumes and their networks. The stopped and the failed agents leave such containers.
+func (sup *SupervisorService) removeStoppedAgentContainers() error {
+	sup.mu.RLock()
+	defer sup.mu.RUnlock()
+
+	containers, err := sup.agentClient.GetContainers(sup.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the containers: %v", err)
+	}
+	for _, container := range containers {
+		if len(container.Names) == 0 || container.State == "running" || container.State == "restarting" {
+			continue
+		}
+		name := container.Names[0][1:] // remove / in the beginning
+		if !strings.HasPrefix(name, agentContainerNamePrefix) {
+			continue
+		}
+		if _, ok := sup.getContainerUnsafe(name); ok {
+			continue
+		}
+
+		logger := log.WithFields(log.Fields{
+			"containerName": name,
+			"containerId":   container.ID,
+		})
+		if err := sup.agentClient.RemoveContainer(sup.ctx, container.ID); err != nil {
+			logger.WithError(err).Warn("failed to remove the stopped agent container")
+			continue
+		}
+		logger.Info("removed the stopped agent container")
+
+		// the agents share the node network if they do not have their own networks
+		if !sup.canAttachNetworks() {
+			continue
+		}
+		for _, nodeContainer := range []*clients.DockerContainer{sup.scannerContainer, sup.jsonRpcContainer} {
+			if nodeContainer == nil {
+				continue
+			}
+			if err := sup.client.DetachNetwork(sup.ctx, nodeContainer.ID, name); err != nil {
+				logger.WithError(err).Warn("failed to detach from the agent network")
+			}
+		}
+		if err := sup.client.RemoveNetworkByName(sup.ctx, name); err != nil {
+			logger.WithError(err).Warn("failed to remove the agent network")
+		}
+	}
+	return nil
+}
+
+// removeUnusedAgentImages removes the registry agent images which no agent containers used during
+// the grace period. The images which were already there when the supervisor started are given the
+// same grace period.
+func (sup *SupervisorService) removeUnusedAgentImages() error {
+	images, err := sup.agentClient.GetImages(sup.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the images: %v", err)
+	}
+
+	sup.mu.RLock()
+	inUse := make(map[string]bool)
+	for _, container := range sup.containers {
+		if container.IsAgent {
+			inUse[container.AgentConfig.Image] = true
+		}
+	}
+	sup.mu.RUnlock()
+
+	now := time.Now()
+	gracePeriod := sup.agentImageGracePeriod()
+	present := make(map[string]bool)
+	for _, image := range images {
+		if !sup.isRegistryAgentImage(image) {
+			continue
+		}
+		present[image.ID] = true
+		if isImageInUse(image, inUse) {
+			sup.agentImagesLastUsed[image.ID] = now
+			continue
+		}
+		lastUsed, ok := sup.agentImagesLastUsed[image.ID]
+		if !ok {
+			sup.agentImagesLastUsed[image.ID] = now
+			continue
+		}
+		if now.Sub(lastUsed) < gracePeriod {
+			continue
+		}
+		logger := log.WithFields(log.Fields{
+			"image":    image.ID,
+			"refs":     image.RepoDigests,
+			"lastUsed": lastUsed,
+		})
+		if err := sup.agentClient.RemoveImage(sup.ctx, image.ID); err != nil {
+			logger.WithError(err).Warn("failed to remove the unused agent image")
+			continue
+		}
+		logger.Info("removed the unused agent image")
+		delete(sup.agentImagesLastUsed, image.ID)
+	}
+
+	// forget the images which were removed by others
+	for imageID := range sup.agentImagesLastUsed {
+		if !present[imageID] {
+			delete(sup.agentImagesLastUsed, imageID)
+		}
+	}
+	return nil
+}
+
+// isRegistryAgentImage tells if the image was pulled from the agent container registry.
+func (sup *SupervisorService) isRegistryAgentImage(image types.ImageSummary) bool {
+	prefix := fmt.Sprintf("%s/", sup.config.Config.Registry.ContainerRegistry)
+	for _, repoDigest := range image.RepoDigests {
+		if strings.HasPrefix(repoDigest, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isImageInUse(image types.ImageSummary, inUse map[string]bool) bool {
+	if inUse[image.ID] {
+		return true
+	}
+	for _, repoDigest := range image.RepoDigests {
+		if inUse[repoDigest] {
+			return true
+		}
+	}
+	for _, repoTag := range image.RepoTags {
+		if inUse[repoTag] {
+			return true
+		}
+	}
+	return false
+}
+
+func (sup *SupervisorService) agentImageGracePeriod() time.Duration {
+	if minutes := sup.config.Config.AgentCleanup.ImageGracePeriodMinutes; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultAgentImageGracePeriod
+}
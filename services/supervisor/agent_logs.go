@@ -42,7 +42,7 @@ func (sup *SupervisorService) doSyncAgentLogs() error {
 		if !container.IsAgent {
 			continue
 		}
-		dockerContainer, err := sup.client.GetContainerByID(sup.ctx, container.ID)
+		dockerContainer, err := sup.agentClient.GetContainerByID(sup.ctx, container.ID)
 		if err != nil {
 			log.WithError(err).Warn("failed to get agent container")
 			continue
@@ -50,7 +50,7 @@ func (sup *SupervisorService) doSyncAgentLogs() error {
 		if dockerContainer.Labels[clients.DockerLabelFortaSettingsAgentLogsEnable] != "true" {
 			continue
 		}
-		logs, err := sup.client.GetContainerLogs(
+		logs, err := sup.agentClient.GetContainerLogs(
 			sup.ctx, container.ID,
 			strconv.Itoa(defaultAgentLogTailLines),
 			defaultAgentLogAvgMaxCharsPerLine*defaultAgentLogTailLines,
@@ -0,0 +1,88 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/forta-network/forta-node/clients/vault"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// agentSecretEnv reads the secrets of the agent and adds them to the agent env.
+func (sup *SupervisorService) agentSecretEnv(agent config.AgentConfig, env map[string]string) error {
+	secretsCfg := sup.config.Config.AgentSecrets
+	var vaultClient vault.Client
+	if len(secretsCfg.Vault.Address) > 0 {
+		token, err := ioutil.ReadFile(path.Join(sup.config.Config.FortaDir, secretsCfg.Vault.TokenFile))
+		if err != nil {
+			return fmt.Errorf("failed to read the vault token: %v", err)
+		}
+		vaultClient = vault.NewClient(secretsCfg.Vault.Address, secretsCfg.Vault.Mount, strings.TrimSpace(string(token)))
+	}
+	secrets, err := resolveAgentSecrets(sup.ctx, secretsCfg, vaultClient, agent.ID)
+	if err != nil {
+		return err
+	}
+	if err := addSecretEnv(env, secrets); err != nil {
+		return err
+	}
+	if len(secrets) > 0 {
+		names := make([]string, 0, len(secrets))
+		for name := range secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		log.WithFields(log.Fields{
+			"agent":   agent.ID,
+			"secrets": strings.Join(names, ","),
+		}).Info("injected the agent secrets")
+	}
+	return nil
+}
+
+// resolveAgentSecrets collects the secrets of the agent from the config and from Vault.
+func resolveAgentSecrets(
+	ctx context.Context, secretsCfg config.AgentSecretsConfig, vaultClient vault.Client, agentID string,
+) (map[string]string, error) {
+	secrets := make(map[string]string)
+	for _, agentSecrets := range secretsCfg.Agents {
+		if !strings.EqualFold(agentSecrets.AgentID, agentID) {
+			continue
+		}
+		for name, value := range agentSecrets.Env {
+			secrets[name] = value
+		}
+		if len(agentSecrets.VaultPath) == 0 {
+			continue
+		}
+		if vaultClient == nil {
+			return nil, fmt.Errorf("vault address is not configured for the secrets of agent %s", agentID)
+		}
+		vaultSecrets, err := vaultClient.ReadSecret(ctx, agentSecrets.VaultPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the secrets of agent %s from vault: %v", agentID, err)
+		}
+		for name, value := range vaultSecrets {
+			secrets[name] = value
+		}
+	}
+	return secrets, nil
+}
+
+// addSecretEnv adds the secrets to the env. The secrets cannot override the env vars which the node sets.
+func addSecretEnv(env map[string]string, secrets map[string]string) error {
+	for name := range secrets {
+		if _, ok := env[name]; ok {
+			return fmt.Errorf("agent secret cannot override the env var %s", name)
+		}
+	}
+	for name, value := range secrets {
+		env[name] = value
+	}
+	return nil
+}
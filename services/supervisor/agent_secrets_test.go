@@ -0,0 +1,77 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testVaultClient map[string]map[string]string
+
+func (c testVaultClient) ReadSecret(ctx context.Context, secretPath string) (map[string]string, error) {
+	secret, ok := c[secretPath]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return secret, nil
+}
+
+func TestResolveAgentSecrets(t *testing.T) {
+	r := require.New(t)
+
+	secretsCfg := config.AgentSecretsConfig{
+		Agents: []config.AgentSecretConfig{
+			{
+				AgentID: "0xAGENT1",
+				Env: map[string]string{
+					"API_KEY":  "from-config",
+					"API_USER": "user",
+				},
+				VaultPath: "agents/agent-1",
+			},
+			{
+				AgentID: "0xagent2",
+				Env: map[string]string{
+					"API_KEY": "agent-2",
+				},
+			},
+		},
+	}
+	vaultClient := testVaultClient{
+		"agents/agent-1": {"API_KEY": "from-vault"},
+	}
+
+	secrets, err := resolveAgentSecrets(context.Background(), secretsCfg, vaultClient, "0xagent1")
+	r.NoError(err)
+	r.Equal(map[string]string{"API_KEY": "from-vault", "API_USER": "user"}, secrets)
+
+	secrets, err = resolveAgentSecrets(context.Background(), secretsCfg, nil, "0xagent2")
+	r.NoError(err)
+	r.Equal(map[string]string{"API_KEY": "agent-2"}, secrets)
+
+	secrets, err = resolveAgentSecrets(context.Background(), secretsCfg, nil, "0xagent3")
+	r.NoError(err)
+	r.Empty(secrets)
+
+	_, err = resolveAgentSecrets(context.Background(), secretsCfg, nil, "0xagent1")
+	r.Error(err)
+
+	secretsCfg.Agents[0].VaultPath = "agents/unknown"
+	_, err = resolveAgentSecrets(context.Background(), secretsCfg, vaultClient, "0xagent1")
+	r.Error(err)
+}
+
+func TestAddSecretEnv(t *testing.T) {
+	r := require.New(t)
+
+	env := map[string]string{config.EnvJsonRpcHost: "proxy"}
+	r.NoError(addSecretEnv(env, map[string]string{"API_KEY": "abc"}))
+	r.Equal("abc", env["API_KEY"])
+
+	r.Error(addSecretEnv(env, map[string]string{config.EnvJsonRpcHost: "evil", "OTHER": "x"}))
+	r.Equal("proxy", env[config.EnvJsonRpcHost])
+	r.NotContains(env, "OTHER")
+}
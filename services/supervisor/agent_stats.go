@@ -0,0 +1,65 @@
+package supervisor
+
+import (
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultAgentStatsInterval = time.Minute
+
+func (sup *SupervisorService) collectAgentStats() {
+	ticker := time.NewTicker(defaultAgentStatsInterval)
+	for {
+		select {
+		case <-sup.ctx.Done():
+			ticker.Stop()
+			return
+
+		case <-ticker.C:
+			sup.doCollectAgentStats()
+		}
+	}
+}
+
+// doCollectAgentStats sends the resource usage of the agent containers as the agent metrics, together
+// with how many times the agents have exited within the restart period.
+func (sup *SupervisorService) doCollectAgentStats() {
+	type agentContainer struct {
+		id, name, agentID string
+		exits             int
+	}
+	sup.mu.RLock()
+	var agentContainers []agentContainer
+	for _, container := range sup.containers {
+		if container.IsAgent && !container.failed {
+			agentContainers = append(agentContainers, agentContainer{
+				id:      container.ID,
+				name:    container.Name,
+				agentID: container.AgentConfig.ID,
+				exits:   container.exits,
+			})
+		}
+	}
+	sup.mu.RUnlock()
+
+	var metricsList []*protocol.AgentMetric
+	for _, container := range agentContainers {
+		metricsList = append(metricsList, metrics.CreateAgentMetric(container.agentID, metrics.MetricRestarts, float64(container.exits)))
+
+		stats, err := sup.agentClient.GetContainerStats(sup.ctx, container.id)
+		if err != nil {
+			log.WithError(err).WithField("container", container.name).Warn("failed to get the agent container stats")
+			continue
+		}
+		metricsList = append(metricsList,
+			metrics.CreateAgentMetric(container.agentID, metrics.MetricCPUUsage, stats.CPUPercent),
+			metrics.CreateAgentMetric(container.agentID, metrics.MetricMemoryUsage, float64(stats.MemoryUsage)),
+			metrics.CreateAgentMetric(container.agentID, metrics.MetricNetworkRx, float64(stats.NetworkRx)),
+			metrics.CreateAgentMetric(container.agentID, metrics.MetricNetworkTx, float64(stats.NetworkTx)),
+		)
+	}
+	metrics.SendAgentMetrics(sup.msgClient, metricsList)
+}
@@ -0,0 +1,110 @@
+package supervisor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/forta-network/forta-node/clients/agentgrpc"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const imagePasswdFile = "/etc/passwd"
+
+// loadAgentCA loads the CA from the volume which only the supervisor mounts and returns the client files
+// which the scanner dials the agents with. The CA key of the older releases is removed from the Forta
+// dir since the scanner and the proxy mount it.
+func (sup *SupervisorService) loadAgentCA() (map[string][]byte, error) {
+	for _, fileName := range []string{agentgrpc.CAKeyFileName, agentgrpc.CACertFileName} {
+		legacyPath := path.Join(sup.config.Config.FortaDir, config.DefaultAgentTLSDirName, fileName)
+		if err := os.Remove(legacyPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove the old agent ca: %v", err)
+		}
+	}
+	ca, err := agentgrpc.LoadOrCreateCA(config.DefaultContainerAgentCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the agent ca: %v", err)
+	}
+	sup.agentCA = ca
+	return ca.ClientFiles(config.DockerScannerContainerName)
+}
+
+// agentTLSVolumes issues the agent certificates and returns the volume which mounts them to the agent
+// container. The env vars point the agent to the files.
+func (sup *SupervisorService) agentTLSVolumes(agent config.AgentConfig, image string, env map[string]string) (map[string]string, error) {
+	if !config.UseAgentMTLS(sup.config.Config, agent) {
+		return nil, nil
+	}
+	if sup.agentCA == nil {
+		return nil, fmt.Errorf("agent ca is not loaded")
+	}
+	uid, err := sup.agentImageUID(image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the agent image user: %v", err)
+	}
+	certDir := path.Join(config.DefaultAgentTLSDirName, agent.ContainerName())
+	if err := sup.agentCA.WriteAgentCerts(path.Join(sup.config.Config.FortaDir, certDir), agent.ContainerName(), uid); err != nil {
+		return nil, err
+	}
+	env[config.EnvAgentGrpcTLSCert] = path.Join(config.DefaultAgentTLSMountPath, agentgrpc.CertFileName)
+	env[config.EnvAgentGrpcTLSKey] = path.Join(config.DefaultAgentTLSMountPath, agentgrpc.KeyFileName)
+	env[config.EnvAgentGrpcTLSClientCA] = path.Join(config.DefaultAgentTLSMountPath, agentgrpc.CACertFileName)
+	return map[string]string{
+		path.Join(os.Getenv(config.EnvHostFortaDir), certDir): config.DefaultAgentTLSMountPath,
+	}, nil
+}
+
+// agentImageUID returns the user ID which the agent container runs as.
+func (sup *SupervisorService) agentImageUID(image string) (int, error) {
+	inspection, err := sup.agentImageClient.InspectImage(sup.ctx, image)
+	if err != nil {
+		return 0, err
+	}
+	var imageUser string
+	if inspection.Config != nil {
+		imageUser = inspection.Config.User
+	}
+	uid, err := resolveImageUID(imageUser, func() ([]byte, error) {
+		return sup.agentImageClient.ReadImageFile(sup.ctx, image, imagePasswdFile)
+	})
+	if err != nil {
+		return 0, err
+	}
+	log.WithFields(log.Fields{
+		"image": image,
+		"user":  imageUser,
+		"uid":   uid,
+	}).Debug("resolved the agent image user")
+	return uid, nil
+}
+
+// resolveImageUID resolves the "user[:group]" of an image to the user ID. The passwd file of the image
+// is read only if the user is a name.
+func resolveImageUID(imageUser string, readPasswd func() ([]byte, error)) (int, error) {
+	userName := strings.SplitN(imageUser, ":", 2)[0]
+	if len(userName) == 0 || userName == "root" {
+		return 0, nil
+	}
+	if uid, err := strconv.Atoi(userName); err == nil {
+		return uid, nil
+	}
+	passwd, err := readPasswd()
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(passwd))
+	for scanner.Scan() {
+		// name:password:uid:gid:comment:home:shell
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 || fields[0] != userName {
+			continue
+		}
+		return strconv.Atoi(fields[2])
+	}
+	return 0, fmt.Errorf("user %s is not in %s", userName, imagePasswdFile)
+}
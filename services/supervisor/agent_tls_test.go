@@ -0,0 +1,38 @@
+package supervisor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveImageUID(t *testing.T) {
+	r := require.New(t)
+
+	passwd := []byte("root:x:0:0:root:/root:/bin/sh\nnode:x:1000:1000::/home/node:/bin/sh\n")
+	readPasswd := func() ([]byte, error) {
+		return passwd, nil
+	}
+	noPasswd := func() ([]byte, error) {
+		return nil, errors.New("should not read the passwd file")
+	}
+
+	for imageUser, expectedUID := range map[string]int{
+		"":          0,
+		"root":      0,
+		"1001":      1001,
+		"1001:1001": 1001,
+	} {
+		uid, err := resolveImageUID(imageUser, noPasswd)
+		r.NoError(err)
+		r.Equal(expectedUID, uid, imageUser)
+	}
+
+	uid, err := resolveImageUID("node:node", readPasswd)
+	r.NoError(err)
+	r.Equal(1000, uid)
+
+	_, err = resolveImageUID("nobody", readPasswd)
+	r.Error(err)
+}
@@ -0,0 +1,23 @@
+package supervisor
+
+import (
+	"path"
+
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// handleDeadLetter keeps the messages which the node containers failed to handle in the Forta dir, so
+// that the admin API can serve them.
+func (sup *SupervisorService) handleDeadLetter(payload messaging.DeadLetterPayload) error {
+	sup.lastDeadLetter.Set()
+	log.WithFields(log.Fields{
+		"subject":  payload.Subject,
+		"client":   payload.Client,
+		"attempts": payload.Attempts,
+	}).Warnf("received dead letter: %s", payload.Error)
+	deadLetters := store.NewMessageDeadLetters(path.Join(sup.config.Config.FortaDir, config.DefaultMsgDeadLettersFile))
+	return deadLetters.Append(payload)
+}
@@ -0,0 +1,77 @@
+package supervisor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultDiskUsageCheckInterval = time.Minute
+
+func (sup *SupervisorService) checkAgentDiskUsage() {
+	ticker := time.NewTicker(defaultDiskUsageCheckInterval)
+	for {
+		select {
+		case <-sup.ctx.Done():
+			ticker.Stop()
+			return
+
+		case <-ticker.C:
+			sup.doCheckAgentDiskUsage()
+		}
+	}
+}
+
+// doCheckAgentDiskUsage sends the disk usage metrics of the agents and stops the agents which
+// exceed their limits, since most of the storage drivers cannot limit the writable layer.
+func (sup *SupervisorService) doCheckAgentDiskUsage() {
+	sup.mu.RLock()
+	var agentContainers []*Container
+	for _, container := range sup.containers {
+		if container.IsAgent && !container.failed {
+			agentContainers = append(agentContainers, container)
+		}
+	}
+	sup.mu.RUnlock()
+
+	var (
+		metricsList []*protocol.AgentMetric
+		exceeded    = make(map[*Container]string)
+	)
+	for _, container := range agentContainers {
+		usage, err := sup.agentClient.GetContainerDiskUsage(sup.ctx, container.ID)
+		if err != nil {
+			log.WithError(err).WithField("container", container.Name).Warn("failed to get the agent disk usage")
+			continue
+		}
+		metricsList = append(metricsList, metrics.CreateAgentMetric(container.AgentConfig.ID, metrics.MetricDiskUsage, float64(usage)))
+
+		limits := config.GetAgentResourceLimits(sup.config.Config.ResourcesConfig, *container.AgentConfig)
+		if limits.Disk > 0 && usage > limits.Disk {
+			log.WithFields(log.Fields{
+				"container": container.Name,
+				"usage":     usage,
+				"limit":     limits.Disk,
+			}).Error("agent container exceeded the disk limit - marking as failed")
+			exceeded[container] = fmt.Sprintf("disk limit exceeded: %d/%d bytes", usage, limits.Disk)
+		}
+	}
+	if len(metricsList) > 0 {
+		metrics.SendAgentMetrics(sup.msgClient, metricsList)
+	}
+	if len(exceeded) == 0 {
+		return
+	}
+
+	sup.mu.Lock()
+	for container, reason := range exceeded {
+		container.failed = true
+		container.failReason = reason
+	}
+	sup.mu.Unlock()
+	sup.handleFailedAgents()
+}
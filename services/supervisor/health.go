@@ -5,6 +5,7 @@ import (
 
 	"github.com/forta-network/forta-core-go/utils"
 	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/clients/messaging"
 
 	"fmt"
 	"time"
@@ -16,6 +17,16 @@ import (
 const defaultHealthCheckInterval = time.Second * 5
 const maxAttempts = 10
 
+// The exited agents are restarted after a backoff which doubles on every exit. The backoff is
+// reset if the agent does not exit again within the restart period. An agent which keeps exiting
+// is in a crash loop and is marked as failed after the max attempts.
+const (
+	minAgentRestartBackoff      = time.Second * 10
+	maxAgentRestartBackoff      = time.Minute * 10
+	defaultAgentRestartPeriod   = time.Minute * 30
+	defaultAgentRestartAttempts = 5
+)
+
 func (sup *SupervisorService) healthCheck() {
 	ticker := time.NewTicker(defaultHealthCheckInterval)
 	for {
@@ -33,6 +44,14 @@ func (sup *SupervisorService) healthCheck() {
 }
 
 func (sup *SupervisorService) doHealthCheck() error {
+	if err := sup.checkContainers(); err != nil {
+		return err
+	}
+	sup.handleFailedAgents()
+	return nil
+}
+
+func (sup *SupervisorService) checkContainers() error {
 	sup.mu.RLock()
 	defer sup.mu.RUnlock()
 	for _, knownContainer := range sup.containers {
@@ -41,7 +60,7 @@ func (sup *SupervisorService) doHealthCheck() error {
 		// this has a threshold so that the healthcheck doesn't fail while a container is starting
 		err := utils.TryTimes(func(attempt int) error {
 			var err error
-			foundContainer, err = sup.client.GetContainerByID(sup.ctx, knownContainer.ID)
+			foundContainer, err = sup.containerClient(knownContainer).GetContainerByID(sup.ctx, knownContainer.ID)
 			currAttempt := attempt + 1
 			if err != nil && errors.Is(err, clients.ErrContainerNotFound) {
 				log.Warnf("healthcheck: container '%s' with id '%s' was not found (attempt=%d/%d)", knownContainer.Name, knownContainer.ID, currAttempt, maxAttempts)
@@ -70,8 +89,17 @@ func (sup *SupervisorService) ensureUp(knownContainer *Container, foundContainer
 	case "created", "running", "restarting", "paused", "dead":
 		return nil
 	case "exited":
+		if knownContainer.IsAgent {
+			wait, err := sup.backOffExitedAgent(knownContainer)
+			if err != nil {
+				return err
+			}
+			if wait {
+				return nil
+			}
+		}
 		log.Warnf("starting exited container '%s'", knownContainer.Name)
-		_, err := sup.client.StartContainer(sup.ctx, knownContainer.Config)
+		_, err := sup.containerClient(knownContainer).StartContainer(sup.ctx, knownContainer.Config)
 		if err != nil {
 			return fmt.Errorf("failed to start container '%s': %v", knownContainer.Name, err)
 		}
@@ -81,3 +109,111 @@ func (sup *SupervisorService) ensureUp(knownContainer *Container, foundContainer
 	}
 	return nil
 }
+
+// backOffExitedAgent tells if the restart of the exited agent should wait. The agent is marked as
+// failed instead if it has exited too many times within the restart period.
+func (sup *SupervisorService) backOffExitedAgent(knownContainer *Container) (bool, error) {
+	if knownContainer.failed {
+		return true, nil
+	}
+	inspection, err := sup.agentClient.InspectContainer(sup.ctx, knownContainer.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect container '%s': %v", knownContainer.Name, err)
+	}
+
+	now := time.Now()
+	if knownContainer.restartAt.IsZero() {
+		if now.Sub(knownContainer.lastExit) > sup.agentRestartPeriod() {
+			knownContainer.exits = 0
+		}
+		knownContainer.exits++
+		knownContainer.lastExit = now
+		knownContainer.exitReason = exitReason(inspection.State)
+		if inspection.State != nil && inspection.State.OOMKilled {
+			sup.lastAgentOOMKill.Set()
+		}
+
+		logger := log.WithFields(log.Fields{
+			"container": knownContainer.Name,
+			"exits":     knownContainer.exits,
+			"reason":    knownContainer.exitReason,
+		})
+		if knownContainer.exits > sup.maxAgentRestartAttempts() {
+			logger.Error("agent container is in a crash loop - marking as failed")
+			knownContainer.failed = true
+			knownContainer.failReason = fmt.Sprintf("crash loop: %s", knownContainer.exitReason)
+			return true, nil
+		}
+
+		backoff := maxAgentRestartBackoff
+		if knownContainer.exits < 8 {
+			backoff = minAgentRestartBackoff << (knownContainer.exits - 1)
+		}
+		if backoff > maxAgentRestartBackoff {
+			backoff = maxAgentRestartBackoff
+		}
+		knownContainer.restartAt = now.Add(backoff)
+		logger.WithField("backoff", backoff).Warn("agent container exited - restarting after backoff")
+	}
+	if now.Before(knownContainer.restartAt) {
+		return true, nil
+	}
+	knownContainer.restartAt = time.Time{}
+	return false, nil
+}
+
+func exitReason(state *types.ContainerState) string {
+	switch {
+	case state == nil:
+		return "exited"
+	case state.OOMKilled:
+		return "OOM-killed"
+	default:
+		return fmt.Sprintf("exited with code %d", state.ExitCode)
+	}
+}
+
+func (sup *SupervisorService) agentRestartPeriod() time.Duration {
+	if minutes := sup.config.Config.AgentRestarts.PeriodMinutes; minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultAgentRestartPeriod
+}
+
+func (sup *SupervisorService) maxAgentRestartAttempts() int {
+	if attempts := sup.config.Config.AgentRestarts.MaxAttempts; attempts > 0 {
+		return attempts
+	}
+	return defaultAgentRestartAttempts
+}
+
+// handleFailedAgents stops the agents which are in a crash loop or exceed their limits and tells the other services that
+// they have failed, so that they are not run again until their config changes.
+func (sup *SupervisorService) handleFailedAgents() {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	var remainingContainers []*Container
+	for _, container := range sup.containers {
+		if !container.failed {
+			remainingContainers = append(remainingContainers, container)
+			continue
+		}
+		if err := sup.agentClient.StopContainer(sup.ctx, container.ID); err != nil {
+			log.WithError(err).WithField("container", container.Name).Warn("failed to stop the failed agent container")
+		}
+		sup.lastAgentFailure.Set()
+		sup.lastAgentFailureMsg.Set(fmt.Sprintf("%s: %s", container.AgentConfig.ID, container.failReason))
+		restarts := container.exits - 1
+		if restarts < 0 {
+			restarts = 0
+		}
+		sup.msgClient.Publish(messaging.SubjectAgentsStatusFailed, &messaging.AgentFailedPayload{
+			Agent:    *container.AgentConfig,
+			Restarts: restarts,
+			Reason:   container.failReason,
+		})
+		sup.msgClient.Publish(messaging.SubjectAgentsStatusStopped, messaging.AgentPayload{*container.AgentConfig})
+	}
+	sup.containers = remainingContainers
+}
@@ -0,0 +1,59 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	errImageNotPinned   = errors.New("agent image is not pinned by a digest")
+	errImageDigestDrift = errors.New("agent image does not match the pinned digest")
+)
+
+// pinAgentImage returns the image reference which the agent container should run. The registry agents
+// must reference their images by digest and the pulled image must have the same digest. The tags of
+// the local agents are resolved to the image IDs once and the agents are refused if the tags later
+// point to other images.
+func (sup *SupervisorService) pinAgentImage(agent config.AgentConfig) (string, error) {
+	inspection, err := sup.agentImageClient.InspectImage(sup.ctx, agent.Image)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect the agent image: %v", err)
+	}
+
+	_, digest := utils.SplitImageRef(agent.Image)
+	if len(digest) > 0 {
+		for _, repoDigest := range inspection.RepoDigests {
+			if _, found := utils.SplitImageRef(repoDigest); found == digest {
+				return agent.Image, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %s", errImageDigestDrift, agent.Image)
+	}
+
+	if !agent.IsLocal {
+		return "", fmt.Errorf("%w: %s", errImageNotPinned, agent.Image)
+	}
+
+	sup.pinnedImagesMu.Lock()
+	defer sup.pinnedImagesMu.Unlock()
+	key := fmt.Sprintf("%s|%s", strings.ToLower(agent.ID), agent.Image)
+	pinned, ok := sup.pinnedImages[key]
+	if !ok {
+		log.WithFields(log.Fields{
+			"agent": agent.ID,
+			"image": agent.Image,
+			"id":    inspection.ID,
+		}).Info("pinned the local agent image")
+		sup.pinnedImages[key] = inspection.ID
+		return inspection.ID, nil
+	}
+	if pinned != inspection.ID {
+		return "", fmt.Errorf("%w: %s moved from %s to %s", errImageDigestDrift, agent.Image, pinned, inspection.ID)
+	}
+	return pinned, nil
+}
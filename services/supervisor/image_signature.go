@@ -0,0 +1,57 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/forta-network/forta-node/audit"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Image signature policies
+const (
+	ImageSignaturePolicyEnforce = "enforce"
+	ImageSignaturePolicyWarn    = "warn"
+	ImageSignaturePolicyOff     = "off"
+)
+
+var errNoImageSigningKey = errors.New("agent manifest declares no image signing key")
+
+// verifyAgentImage verifies the cosign signature of the agent image with the key which the agent
+// declares in the manifest. The local agents are not verified.
+func (sup *SupervisorService) verifyAgentImage(agent config.AgentConfig, image string) error {
+	policy := sup.config.Config.Registry.ImageSignaturePolicy
+	if len(policy) == 0 || policy == ImageSignaturePolicyOff || agent.IsLocal {
+		return nil
+	}
+
+	err := errNoImageSigningKey
+	if len(agent.CosignPublicKey) > 0 {
+		err = sup.imageVerifier.Verify(sup.ctx, image, agent.CosignPublicKey)
+	}
+	details := map[string]string{
+		"agent":  agent.ID,
+		"image":  image,
+		"policy": policy,
+		"result": "verified",
+	}
+	if err != nil {
+		details["result"] = err.Error()
+	}
+	audit.Record(audit.EventImageVerified, details)
+	if err == nil {
+		return nil
+	}
+
+	logger := log.WithError(err).WithFields(log.Fields{
+		"agent": agent.ID,
+		"image": image,
+	})
+	if policy == ImageSignaturePolicyWarn {
+		logger.Warn("failed to verify the agent image signature - starting anyway")
+		return nil
+	}
+	logger.Error("failed to verify the agent image signature")
+	return fmt.Errorf("failed to verify the agent image signature: %w", err)
+}
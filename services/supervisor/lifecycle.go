@@ -0,0 +1,127 @@
+package supervisor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/forta-network/forta-node/audit"
+	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/clients/messaging"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// durable consumer names of the supervisor on the lifecycle stream
+const (
+	lifecycleRunConsumer      = "supervisor-agents-run"
+	lifecycleStopConsumer     = "supervisor-agents-stop"
+	lifecycleVersionsConsumer = "supervisor-agents-versions"
+)
+
+// lifecycleClient is the message client which can replay the lifecycle messages.
+type lifecycleClient interface {
+	EnsureLifecycleStream(maxAge time.Duration) error
+	SubscribeDurable(subject, durable string, handler interface{})
+}
+
+// registerLifecycleHandlers subscribes to the lifecycle messages with the durable consumers, so that the
+// supervisor replays the run and the stop actions which were published while it was down. Only the latest
+// agent list is used, so an older list which is redelivered after a newer one cannot stop the agents. The
+// agents of a replayed run action which are not in the latest list anymore are stopped with the next list.
+// It returns false if the lifecycle stream is not enabled or the message client cannot replay.
+func (sup *SupervisorService) registerLifecycleHandlers() (bool, error) {
+	lifecycleCfg := sup.config.Config.Messaging.Lifecycle
+	if !lifecycleCfg.Durable {
+		return false, nil
+	}
+	client, ok := sup.msgClient.(lifecycleClient)
+	if !ok {
+		log.Warn("message client cannot replay the lifecycle messages - using the plain subscriptions")
+		return false, nil
+	}
+	if err := client.EnsureLifecycleStream(time.Duration(lifecycleCfg.MaxAgeHours) * time.Hour); err != nil {
+		return false, err
+	}
+	client.SubscribeDurable(messaging.SubjectAgentsActionRun, lifecycleRunConsumer, messaging.WithAck(messaging.AgentsHandler(sup.handleAgentRun)))
+	client.SubscribeDurable(messaging.SubjectAgentsActionStop, lifecycleStopConsumer, messaging.WithAck(messaging.AgentsHandler(sup.handleAgentStop)))
+	client.SubscribeDurable(messaging.SubjectAgentsVersionsLatest, lifecycleVersionsConsumer, messaging.LatestOnly(messaging.AgentsHandler(sup.handleAgentVersions)))
+	return true, nil
+}
+
+// agentContainerIDUnsafe finds the container of the agent. The replayed stop actions can be about the
+// agent containers which were started before the supervisor restarted, so those are looked up on the
+// container runtime.
+func (sup *SupervisorService) agentContainerIDUnsafe(name string) (string, bool) {
+	if container, ok := sup.getContainerUnsafe(name); ok {
+		return container.ID, true
+	}
+	if !sup.config.Config.Messaging.Lifecycle.Durable {
+		return "", false
+	}
+	container, err := sup.agentClient.GetContainerByName(sup.ctx, name)
+	if err != nil || container.State != "running" {
+		return "", false
+	}
+	return container.ID, true
+}
+
+// handleAgentVersions stops the running agent containers which are not in the latest agent list. The
+// replayed latest versions fix the drift when the supervisor missed the stop actions.
+func (sup *SupervisorService) handleAgentVersions(payload messaging.AgentPayload) error {
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	latest := make(map[string]bool)
+	for _, agent := range payload {
+		latest[agent.ContainerName()] = true
+	}
+	containers, err := sup.agentClient.GetContainers(sup.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the containers: %v", err)
+	}
+
+	stopped := make(map[string]bool)
+	var stoppedAgents messaging.AgentPayload
+	for _, container := range containers {
+		if len(container.Names) == 0 || container.State != "running" {
+			continue
+		}
+		name := container.Names[0][1:] // remove / in the beginning
+		agentID, ok := container.Labels[clients.DockerLabelFortaAgentID]
+		if !ok || !strings.HasPrefix(name, agentContainerNamePrefix) || latest[name] {
+			continue
+		}
+		if err := sup.agentClient.StopContainer(sup.ctx, container.ID); err != nil {
+			return fmt.Errorf("failed to stop container '%s': %v", container.ID, err)
+		}
+		log.WithFields(log.Fields{
+			"agent":         agentID,
+			"containerName": name,
+		}).Info("stopped the agent which is not in the latest versions")
+		audit.Record(audit.EventAgentStopped, map[string]string{
+			"agent":     agentID,
+			"container": name,
+		})
+		stopped[container.ID] = true
+		agentCfg := config.AgentConfig{ID: agentID}
+		if tracked, ok := sup.getContainerUnsafe(name); ok && tracked.AgentConfig != nil {
+			agentCfg = *tracked.AgentConfig
+		}
+		stoppedAgents = append(stoppedAgents, agentCfg)
+	}
+	if len(stoppedAgents) == 0 {
+		return nil
+	}
+
+	var remainingContainers []*Container
+	for _, container := range sup.containers {
+		if !stopped[container.ID] {
+			remainingContainers = append(remainingContainers, container)
+		}
+	}
+	sup.containers = remainingContainers
+
+	sup.msgClient.Publish(messaging.SubjectAgentsStatusStopped, stoppedAgents)
+	return nil
+}
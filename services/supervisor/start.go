@@ -2,8 +2,12 @@ package supervisor
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,6 +24,8 @@ import (
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/security"
 	"github.com/forta-network/forta-node/clients"
+	"github.com/forta-network/forta-node/clients/agentgrpc"
+	"github.com/forta-network/forta-node/clients/cosign"
 	"github.com/forta-network/forta-node/clients/messaging"
 	"github.com/forta-network/forta-node/config"
 	"github.com/forta-network/forta-node/services"
@@ -37,27 +43,45 @@ type SupervisorService struct {
 
 	client           clients.DockerClient
 	globalClient     clients.DockerClient
+	agentClient      clients.DockerClient // same with the client unless the agents run on kubernetes
 	agentImageClient clients.DockerClient
 
 	manifestClient manifest.Client
 	releaseClient  release.Client
+	imageVerifier  cosign.Verifier
 
 	msgClient   clients.MessageClient
 	config      SupervisorServiceConfig
 	maxLogSize  string
 	maxLogFiles int
 
+	agentCA          *agentgrpc.CA
+	agentTokenSecret string // for the agent pods to identify themselves to the proxy
+
 	scannerContainer *clients.DockerContainer
 	jsonRpcContainer *clients.DockerContainer
 	containers       []*Container
 	mu               sync.RWMutex
 
+	pinnedImages   map[string]string
+	pinnedImagesMu sync.Mutex
+
+	agentImagesLastUsed map[string]time.Time // updated only by the cleanup
+
 	lastRun                   health.TimeTracker
 	lastStop                  health.TimeTracker
 	lastTelemetryRequest      health.TimeTracker
 	lastTelemetryRequestError health.ErrorTracker
 	lastAgentLogsRequest      health.TimeTracker
 	lastAgentLogsRequestError health.ErrorTracker
+	lastAgentOOMKill          health.TimeTracker
+	lastAgentFailure          health.TimeTracker
+	lastAgentFailureMsg       health.MessageTracker
+	lastAgentRejection        health.TimeTracker
+	lastAgentRejectionMsg     health.MessageTracker
+	lastAgentCleanup          health.TimeTracker
+	lastAgentCleanupError     health.ErrorTracker
+	lastDeadLetter            health.TimeTracker
 
 	healthClient health.HealthClient
 
@@ -76,6 +100,14 @@ type Container struct {
 	clients.DockerContainer
 	IsAgent     bool
 	AgentConfig *config.AgentConfig
+
+	// updated only by the health check
+	exits      int
+	lastExit   time.Time
+	exitReason string
+	restartAt  time.Time
+	failed     bool
+	failReason string
 }
 
 func (sup *SupervisorService) Start() error {
@@ -84,6 +116,11 @@ func (sup *SupervisorService) Start() error {
 	}
 
 	go sup.healthCheck()
+	go sup.checkAgentDiskUsage()
+	go sup.collectAgentStats()
+	if !sup.config.Config.AgentCleanup.Disable {
+		go sup.cleanUpAgents()
+	}
 
 	return nil
 }
@@ -115,6 +152,11 @@ func (sup *SupervisorService) start() error {
 	if len(hostFortaDir) == 0 {
 		return fmt.Errorf("supervisor needs to know $%s to mount to the other containers it runs", config.EnvHostFortaDir)
 	}
+	// older runners do not set this and only support docker
+	hostRuntimeSocket := os.Getenv(config.EnvHostRuntimeSocket)
+	if len(hostRuntimeSocket) == 0 {
+		hostRuntimeSocket = config.DefaultContainerRuntimeSocket
+	}
 	releaseInfo := release.ReleaseInfoFromString(os.Getenv(config.EnvReleaseInfo))
 	releaseInfo, err = sup.getFullReleaseInfo(releaseInfo)
 	if err != nil {
@@ -178,7 +220,7 @@ func (sup *SupervisorService) start() error {
 	sup.addContainerUnsafe(ipfsContainer)
 
 	// start nats, wait for it and connect from the supervisor
-	natsContainer, err := sup.client.StartContainer(sup.ctx, clients.DockerContainerConfig{
+	natsConfig := clients.DockerContainerConfig{
 		Name:  config.DockerNatsContainerName,
 		Image: "nats:2.3.2",
 		Ports: map[string]string{
@@ -189,7 +231,15 @@ func (sup *SupervisorService) start() error {
 		NetworkID:   internalNetworkID,
 		MaxLogFiles: sup.maxLogFiles,
 		MaxLogSize:  sup.maxLogSize,
-	})
+	}
+	// persist the alert and the lifecycle streams in the forta dir
+	if sup.config.Config.Publish.JetStream.Enable || sup.config.Config.Messaging.Lifecycle.Durable {
+		natsConfig.Cmd = []string{"--config", "nats-server.conf", "--jetstream", "--store_dir", "/data/jetstream"}
+		natsConfig.Volumes = map[string]string{
+			path.Join(hostFortaDir, config.DefaultJetStreamDirName): "/data/jetstream",
+		}
+	}
+	natsContainer, err := sup.client.StartContainer(sup.ctx, natsConfig)
 	if err != nil {
 		return err
 	}
@@ -202,50 +252,101 @@ func (sup *SupervisorService) start() error {
 	if sup.msgClient == nil {
 		sup.msgClient = messaging.NewClient("supervisor", fmt.Sprintf("%s:%s", config.DockerNatsContainerName, config.DefaultNatsPort))
 	}
-	sup.registerMessageHandlers()
+	if err := sup.registerMessageHandlers(); err != nil {
+		return err
+	}
+
+	// containerd cannot attach the running containers to the nats network later
+	var natsLinkNetworkIDs []string
+	if !sup.canAttachNetworks() && !sup.config.Config.ExposeNats {
+		natsLinkNetworkIDs = []string{internalNetworkID}
+	}
+
+	// the node containers read the same config
+	envOverrides := config.GetEnvOverrides(os.Environ())
 
+	// give access to inspect the agent containers on the host
+	jsonRpcVolumes := sup.config.Config.ContainerRuntime.InspectVolumes(hostRuntimeSocket)
+	jsonRpcVolumes[hostFortaDir] = config.DefaultContainerFortaDirPath
+	jsonRpcEnv := make(map[string]string)
+	for k, v := range envOverrides {
+		jsonRpcEnv[k] = v
+	}
+	jsonRpcPorts := map[string]string{
+		"": config.DefaultHealthPort, // random host port
+	}
+	// the agent pods reach the proxy through the node host
+	if sup.config.Config.Kubernetes.Enable {
+		jsonRpcEnv[config.EnvAgentTokenSecret] = sup.agentTokenSecret
+		jsonRpcPorts[sup.config.Config.Kubernetes.ProxyHostPort] = config.DefaultJSONRPCProxyPort
+	}
 	sup.jsonRpcContainer, err = sup.client.StartContainer(sup.ctx, clients.DockerContainerConfig{
-		Name:  config.DockerJSONRPCProxyContainerName,
-		Image: commonNodeImage,
-		Cmd:   []string{config.DefaultFortaNodeBinaryPath, "json-rpc"},
-		Volumes: map[string]string{
-			// give access to host docker
-			"/var/run/docker.sock": "/var/run/docker.sock",
-			hostFortaDir:           config.DefaultContainerFortaDirPath,
-		},
-		Ports: map[string]string{
-			"": config.DefaultHealthPort, // random host port
-		},
-		DialHost:    true,
-		NetworkID:   nodeNetworkID,
-		MaxLogFiles: sup.maxLogFiles,
-		MaxLogSize:  sup.maxLogSize,
+		Name:           config.DockerJSONRPCProxyContainerName,
+		Image:          commonNodeImage,
+		Cmd:            []string{config.DefaultFortaNodeBinaryPath, "json-rpc"},
+		Env:            jsonRpcEnv,
+		Volumes:        jsonRpcVolumes,
+		Ports:          jsonRpcPorts,
+		DialHost:       true,
+		NetworkID:      nodeNetworkID,
+		LinkNetworkIDs: natsLinkNetworkIDs,
+		MaxLogFiles:    sup.maxLogFiles,
+		MaxLogSize:     sup.maxLogSize,
 	})
 	if err != nil {
 		return err
 	}
 	sup.addContainerUnsafe(sup.jsonRpcContainer)
 
+	scannerEnv := map[string]string{
+		config.EnvReleaseInfo: releaseInfo.String(),
+	}
+	for k, v := range sup.config.Config.ReplayEnv() {
+		scannerEnv[k] = v
+	}
+	for k, v := range envOverrides {
+		scannerEnv[k] = v
+	}
+	scannerPorts := map[string]string{
+		"": config.DefaultHealthPort, // random host port
+	}
+	if sup.config.Config.Publish.Incidents.Enable {
+		scannerPorts[sup.config.Config.Publish.Incidents.HostPort] = config.DefaultIncidentsPort
+	}
+	if sup.config.Config.Publish.AgentSLA.Enable {
+		scannerPorts[sup.config.Config.Publish.AgentSLA.HostPort] = config.DefaultAgentSLAPort
+	}
+	if sup.config.Config.Publish.Attestation.Enable {
+		scannerPorts[sup.config.Config.Publish.Attestation.HostPort] = config.DefaultAttestationPort
+	}
+	scannerFiles := map[string][]byte{
+		"passphrase": []byte(sup.config.Passphrase),
+	}
+	// the scanner gets only its client certificate and the agent CA key stays in the supervisor
+	if sup.config.Config.Security.AgentMTLS && !sup.config.Config.Kubernetes.Enable {
+		clientFiles, err := sup.loadAgentCA()
+		if err != nil {
+			return err
+		}
+		for fileName, b := range clientFiles {
+			scannerFiles[fileName] = b
+		}
+	}
 	sup.scannerContainer, err = sup.client.StartContainer(sup.ctx, clients.DockerContainerConfig{
 		Name:  config.DockerScannerContainerName,
 		Image: commonNodeImage,
 		Cmd:   []string{config.DefaultFortaNodeBinaryPath, "scanner"},
-		Env: map[string]string{
-			config.EnvReleaseInfo: releaseInfo.String(),
-		},
+		Env:   scannerEnv,
 		Volumes: map[string]string{
 			hostFortaDir: config.DefaultContainerFortaDirPath,
 		},
-		Ports: map[string]string{
-			"": config.DefaultHealthPort, // random host port
-		},
-		Files: map[string][]byte{
-			"passphrase": []byte(sup.config.Passphrase),
-		},
-		DialHost:    true,
-		NetworkID:   nodeNetworkID,
-		MaxLogFiles: sup.maxLogFiles,
-		MaxLogSize:  sup.maxLogSize,
+		Ports:          scannerPorts,
+		Files:          scannerFiles,
+		DialHost:       true,
+		NetworkID:      nodeNetworkID,
+		LinkNetworkIDs: natsLinkNetworkIDs,
+		MaxLogFiles:    sup.maxLogFiles,
+		MaxLogSize:     sup.maxLogSize,
 	})
 	if err != nil {
 		return err
@@ -264,6 +365,20 @@ func (sup *SupervisorService) start() error {
 	return nil
 }
 
+// canAttachNetworks tells if the running containers can join new networks.
+func (sup *SupervisorService) canAttachNetworks() bool {
+	return sup.config.Config.ContainerRuntime.Runtime != config.ContainerRuntimeContainerd &&
+		!sup.config.Config.Kubernetes.Enable
+}
+
+// containerClient returns the client which manages the container.
+func (sup *SupervisorService) containerClient(container *Container) clients.DockerClient {
+	if container.IsAgent {
+		return sup.agentClient
+	}
+	return sup.client
+}
+
 func (sup *SupervisorService) attachToNetwork(containerName, nodeNetworkID string) error {
 	container, err := sup.client.GetContainerByName(sup.ctx, containerName)
 	if err != nil {
@@ -439,7 +554,7 @@ func (sup *SupervisorService) Stop() error {
 		}
 		var err error
 		if cnt.IsAgent {
-			err = sup.client.StopContainer(ctx, cnt.DockerContainer.ID)
+			err = sup.agentClient.StopContainer(ctx, cnt.DockerContainer.ID)
 		} else {
 			err = sup.client.InterruptContainer(ctx, cnt.DockerContainer.ID)
 		}
@@ -490,15 +605,28 @@ func (sup *SupervisorService) Health() health.Reports {
 		sup.lastTelemetryRequestError.GetReport("event.telemetry-sync.error"),
 		sup.lastAgentLogsRequest.GetReport("event.agent-logs-sync.time"),
 		sup.lastAgentLogsRequestError.GetReport("event.agent-logs-sync.error"),
+		&health.Report{
+			Name:    "event.agent-oom-kill.time",
+			Status:  health.StatusInfo,
+			Details: sup.lastAgentOOMKill.String(),
+		},
+		sup.lastAgentFailure.GetReport("event.agent-failed.time"),
+		sup.lastAgentFailureMsg.GetReport("event.agent-failed.details"),
+		sup.lastAgentRejection.GetReport("event.agent-rejected.time"),
+		sup.lastAgentRejectionMsg.GetReport("event.agent-rejected.details"),
+		sup.lastAgentCleanup.GetReport("event.agent-cleanup.time"),
+		sup.lastAgentCleanupError.GetReport("event.agent-cleanup.error"),
+		sup.lastDeadLetter.GetReport("event.dead-letter.time"),
 	}
 }
 
 func NewSupervisorService(ctx context.Context, cfg SupervisorServiceConfig) (*SupervisorService, error) {
-	dockerClient, err := clients.NewDockerClient("supervisor")
+	runtime := cfg.Config.ContainerRuntime
+	dockerClient, err := clients.NewRuntimeClient("supervisor", runtime, runtime.ContainerSocketPath())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the docker client: %v", err)
 	}
-	globalClient, err := clients.NewDockerClient("")
+	globalClient, err := clients.NewRuntimeClient("", runtime, runtime.ContainerSocketPath())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the global docker client: %v", err)
 	}
@@ -510,27 +638,56 @@ func NewSupervisorService(ctx context.Context, cfg SupervisorServiceConfig) (*Su
 
 	// agent image client is helpful for loading private mode agents from a restricted container registry
 	var agentImageClient clients.DockerClient
-	if cfg.Config.PrivateModeConfig.Enable && cfg.Config.PrivateModeConfig.ContainerRegistry != nil {
+	switch {
+	case cfg.Config.PrivateModeConfig.Enable && cfg.Config.PrivateModeConfig.ContainerRegistry != nil &&
+		runtime.Runtime == config.ContainerRuntimeContainerd:
+		err = errors.New("private container registry is not supported with containerd")
+	case cfg.Config.PrivateModeConfig.Enable && cfg.Config.PrivateModeConfig.ContainerRegistry != nil:
 		agentImageClient, err = clients.NewAuthDockerClient(
 			"",
 			cfg.Config.PrivateModeConfig.ContainerRegistry.Username,
 			cfg.Config.PrivateModeConfig.ContainerRegistry.Password,
 		)
-	} else {
-		agentImageClient, err = clients.NewDockerClient("")
+	default:
+		agentImageClient, err = clients.NewRuntimeClient("", runtime, runtime.ContainerSocketPath())
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the private docker client: %v", err)
 	}
 
+	// the cluster pulls the agent images when the agents run on kubernetes
+	agentClient := clients.DockerClient(dockerClient)
+	var agentTokenSecret string
+	if kubernetesCfg := cfg.Config.Kubernetes; kubernetesCfg.Enable {
+		if len(kubernetesCfg.NodeHost) == 0 {
+			return nil, errors.New("node host must be set when the agents run on kubernetes")
+		}
+		if _, err := kubernetesCfg.ScannerIPBlock(); err != nil {
+			return nil, err
+		}
+		agentClient = clients.NewKubernetesClient("supervisor", kubernetesCfg)
+		agentImageClient = agentClient
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to create the agent token secret: %v", err)
+		}
+		agentTokenSecret = hex.EncodeToString(secret)
+	}
+
 	return &SupervisorService{
 		ctx:              ctx,
 		client:           dockerClient,
 		globalClient:     globalClient,
+		agentClient:      agentClient,
 		agentImageClient: agentImageClient,
+		agentTokenSecret: agentTokenSecret,
 		releaseClient:    releaseClient,
 		config:           cfg,
 		healthClient:     health.NewClient(),
 		agentLogsClient:  agentlogs.NewClient(cfg.Config.AgentLogsConfig.URL),
+		pinnedImages:     make(map[string]string),
+		imageVerifier:    cosign.NewVerifier(),
+
+		agentImagesLastUsed: make(map[string]time.Time),
 	}, nil
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/forta-network/forta-node/audit"
 	"github.com/forta-network/forta-node/clients"
 	"github.com/forta-network/forta-node/clients/messaging"
 	"github.com/forta-network/forta-node/config"
@@ -13,12 +14,32 @@ import (
 
 var (
 	errAgentAlreadyRunning = errors.New("agent already running")
+	errAgentNotIsolated    = errors.New("agents cannot get their own networks with containerd - set containerRuntime.sharedAgentNetwork to run them on the node network")
 )
 
 func (sup *SupervisorService) startAgent(agent config.AgentConfig) error {
+	// the runner starts the process agents on the host
+	if agent.IsProcess() {
+		log.WithField("agent", agent.ID).Info("skipped starting container for process agent")
+		return nil
+	}
+
+	// the agents would reach the node containers and the other agents on the node network
+	runtimeCfg := sup.config.Config.ContainerRuntime
+	if !sup.config.Config.Kubernetes.Enable && !runtimeCfg.CanIsolateAgents() && !runtimeCfg.SharedAgentNetwork {
+		return errAgentNotIsolated
+	}
+
 	if err := sup.agentImageClient.EnsureLocalImage(sup.ctx, fmt.Sprintf("agent %s", agent.ID), agent.Image); err != nil {
 		return err
 	}
+	image, err := sup.pinAgentImage(agent)
+	if err != nil {
+		return err
+	}
+	if err := sup.verifyAgentImage(agent, image); err != nil {
+		return err
+	}
 
 	sup.mu.Lock()
 	defer sup.mu.Unlock()
@@ -28,47 +49,124 @@ func (sup *SupervisorService) startAgent(agent config.AgentConfig) error {
 		return errAgentAlreadyRunning
 	}
 
-	nwID, err := sup.client.CreatePublicNetwork(sup.ctx, agent.ContainerName())
+	// the agents share the node network if the scanner and the proxy cannot join their networks
+	networkName := agent.ContainerName()
+	if !sup.canAttachNetworks() {
+		networkName = config.DockerNetworkName
+	}
+	egress := sup.config.Config.Egress.Enable
+	var nwID string
+	if egress && sup.canAttachNetworks() {
+		// the egress proxy is the only way out of the agent network
+		nwID, err = sup.agentClient.CreateInternalNetwork(sup.ctx, networkName)
+	} else {
+		if egress {
+			log.WithField("agent", agent.ID).Warn("cannot isolate the agent network - egress policy is not enforced")
+		}
+		nwID, err = sup.agentClient.CreatePublicNetwork(sup.ctx, networkName)
+	}
 	if err != nil {
 		return err
 	}
 
-	limits := config.GetAgentResourceLimits(sup.config.Config.ResourcesConfig)
+	limits := config.GetAgentResourceLimits(sup.config.Config.ResourcesConfig, agent)
+	security := config.GetAgentSecurityOptions(sup.config.Config.Security, agent)
+
+	env := map[string]string{
+		config.EnvJsonRpcHost:   config.DockerJSONRPCProxyContainerName,
+		config.EnvJsonRpcPort:   config.DefaultJSONRPCProxyPort,
+		config.EnvAgentGrpcPort: agent.GrpcPort(),
+	}
+	// the agent pods reach the proxy through the node host and the egress proxy is not reachable
+	kubernetes := sup.config.Config.Kubernetes.Enable
+	if kubernetes {
+		env[config.EnvJsonRpcHost] = sup.config.Config.Kubernetes.NodeHost
+		env[config.EnvJsonRpcPort] = sup.config.Config.Kubernetes.ProxyHostPort
+		env[config.EnvJsonRpcToken] = config.AgentJsonRpcToken(sup.agentTokenSecret, agent)
+	}
+	if egress && !kubernetes {
+		for k, v := range config.EgressProxyEnv() {
+			env[k] = v
+		}
+	}
+	volumes, err := sup.agentTLSVolumes(agent, image, env)
+	if err != nil {
+		return err
+	}
+	if err := sup.agentSecretEnv(agent, env); err != nil {
+		return err
+	}
 
-	agentContainer, err := sup.client.StartContainer(sup.ctx, clients.DockerContainerConfig{
+	agentContainer, err := sup.agentClient.StartContainer(sup.ctx, agentSecurityConfig(clients.DockerContainerConfig{
 		Name:           agent.ContainerName(),
-		Image:          agent.Image,
+		Image:          image,
 		NetworkID:      nwID,
 		LinkNetworkIDs: []string{},
-		Env: map[string]string{
-			config.EnvJsonRpcHost:   config.DockerJSONRPCProxyContainerName,
-			config.EnvJsonRpcPort:   "8545",
-			config.EnvAgentGrpcPort: agent.GrpcPort(),
-		},
-		MaxLogFiles: sup.maxLogFiles,
-		MaxLogSize:  sup.maxLogSize,
-		CPUQuota:    limits.CPUQuota,
-		Memory:      limits.Memory,
+		Env:            env,
+		Volumes:        volumes,
+		MaxLogFiles:    sup.maxLogFiles,
+		MaxLogSize:     sup.maxLogSize,
+		CPUQuota:       limits.CPUQuota,
+		CPUShares:      limits.CPUShares,
+		Memory:         limits.Memory,
+		DiskSize:       agentDiskSize(sup.config.Config.ResourcesConfig, limits),
+		GPUs:           limits.GPUs,
+		GPURuntime:     sup.config.Config.ResourcesConfig.AgentGPURuntime,
 		Labels: map[string]string{
 			clients.DockerLabelFortaSupervisorStrategyVersion: SupervisorStrategyVersion,
+			clients.DockerLabelFortaAgentID:                   agent.ID,
 		},
-	})
+	}, security))
 	if err != nil {
 		return err
 	}
 	// Attach the scanner and the JSON-RPC proxy to the agent's network.
 	for _, containerID := range []string{sup.scannerContainer.ID, sup.jsonRpcContainer.ID} {
-		err := sup.client.AttachNetwork(sup.ctx, containerID, nwID)
+		err := sup.agentClient.AttachNetwork(sup.ctx, containerID, nwID)
 		if err != nil {
 			return err
 		}
 	}
 
 	sup.addContainerUnsafe(agentContainer, &agent)
+	audit.Record(audit.EventAgentStarted, map[string]string{
+		"agent":     agent.ID,
+		"image":     image,
+		"container": agent.ContainerName(),
+	})
 
 	return nil
 }
 
+// agentDiskSize returns the writable layer limit if the storage driver should enforce it.
+func agentDiskSize(resourcesCfg config.ResourcesConfig, limits *config.AgentResourceLimits) int64 {
+	if !resourcesCfg.AgentDiskStorageQuota {
+		return 0
+	}
+	return limits.Disk
+}
+
+// agentSecurityConfig applies the hardening options to the agent container config.
+func agentSecurityConfig(containerCfg clients.DockerContainerConfig, opts *config.AgentSecurityOptions) clients.DockerContainerConfig {
+	if len(opts.SeccompProfile) > 0 {
+		containerCfg.SecurityOpts = append(containerCfg.SecurityOpts, fmt.Sprintf("seccomp=%s", opts.SeccompProfile))
+	}
+	if opts.NoNewPrivileges {
+		containerCfg.SecurityOpts = append(containerCfg.SecurityOpts, "no-new-privileges")
+	}
+	if opts.DropCapabilities {
+		containerCfg.CapDrop = []string{"ALL"}
+		containerCfg.CapAdd = opts.Capabilities
+	}
+	containerCfg.ReadOnlyRootfs = opts.ReadOnlyRootfs
+	if opts.TmpfsSizeMiB > 0 {
+		containerCfg.Tmpfs = map[string]string{
+			config.AgentScratchDir: fmt.Sprintf("rw,noexec,nosuid,size=%dm", opts.TmpfsSizeMiB),
+		}
+	}
+	return containerCfg
+}
+
 func (sup *SupervisorService) getContainerUnsafe(name string) (*Container, bool) {
 	for _, container := range sup.containers {
 		if container.Name == name {
@@ -123,16 +221,20 @@ func (sup *SupervisorService) handleAgentStop(payload messaging.AgentPayload) er
 
 	stopped := make(map[string]bool)
 	for _, agentCfg := range payload {
-		container, ok := sup.getContainerUnsafe(agentCfg.ContainerName())
+		containerID, ok := sup.agentContainerIDUnsafe(agentCfg.ContainerName())
 		if !ok {
 			log.Warnf("container for agent '%s' was not found - skipping stop action", agentCfg.ContainerName())
 			continue
 		}
-		if err := sup.client.StopContainer(sup.ctx, container.ID); err != nil {
-			return fmt.Errorf("failed to stop container '%s': %v", container.ID, err)
+		if err := sup.agentClient.StopContainer(sup.ctx, containerID); err != nil {
+			return fmt.Errorf("failed to stop container '%s': %v", containerID, err)
 		}
 		log.Infof("successfully stopped the container: %v", agentCfg.ContainerName())
-		stopped[container.ID] = true
+		audit.Record(audit.EventAgentStopped, map[string]string{
+			"agent":     agentCfg.ID,
+			"container": agentCfg.ContainerName(),
+		})
+		stopped[containerID] = true
 	}
 
 	// Remove the stopped agents from the list.
@@ -151,7 +253,28 @@ func (sup *SupervisorService) handleAgentStop(payload messaging.AgentPayload) er
 	return nil
 }
 
-func (sup *SupervisorService) registerMessageHandlers() {
-	sup.msgClient.Subscribe(messaging.SubjectAgentsActionRun, messaging.AgentsHandler(sup.handleAgentRun))
-	sup.msgClient.Subscribe(messaging.SubjectAgentsActionStop, messaging.AgentsHandler(sup.handleAgentStop))
+// handleAgentRejected reports the last agent which was not run because its manifest is not valid.
+func (sup *SupervisorService) handleAgentRejected(payload messaging.AgentRejectedPayload) error {
+	log.WithFields(log.Fields{
+		"agent":    payload.AgentID,
+		"manifest": payload.Manifest,
+		"field":    payload.Field,
+	}).Warnf("agent was rejected: %s", payload.Reason)
+	sup.lastAgentRejection.Set()
+	sup.lastAgentRejectionMsg.Set(fmt.Sprintf("%s: %s: %s", payload.AgentID, payload.Field, payload.Reason))
+	return nil
+}
+
+func (sup *SupervisorService) registerMessageHandlers() error {
+	sup.msgClient.Subscribe(messaging.SubjectAgentsRejected, messaging.AgentRejectedHandler(sup.handleAgentRejected))
+	sup.msgClient.Subscribe(messaging.SubjectMessagingDeadLetter, messaging.DeadLetterHandler(sup.handleDeadLetter))
+	durable, err := sup.registerLifecycleHandlers()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to the lifecycle stream: %v", err)
+	}
+	if !durable {
+		sup.msgClient.Subscribe(messaging.SubjectAgentsActionRun, messaging.WithAck(messaging.Idempotent(messaging.AgentsHandler(sup.handleAgentRun))))
+		sup.msgClient.Subscribe(messaging.SubjectAgentsActionStop, messaging.WithAck(messaging.Idempotent(messaging.AgentsHandler(sup.handleAgentStop))))
+	}
+	return nil
 }
@@ -2,10 +2,13 @@ package supervisor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/forta-network/forta-core-go/protocol"
 	"github.com/forta-network/forta-core-go/release"
 
 	"github.com/docker/docker/api/types"
@@ -16,7 +19,9 @@ import (
 	"github.com/forta-network/forta-node/clients/messaging"
 	mock_clients "github.com/forta-network/forta-node/clients/mocks"
 	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/metrics"
 	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -89,9 +94,13 @@ func (s *Suite) SetupTest() {
 		ctx:              context.Background(),
 		client:           s.dockerClient,
 		globalClient:     s.globalClient,
+		agentClient:      s.dockerClient,
 		msgClient:        s.msgClient,
 		releaseClient:    s.releaseClient,
 		agentImageClient: s.agentImageClient,
+		pinnedImages:     make(map[string]string),
+
+		agentImagesLastUsed: make(map[string]time.Time),
 	}
 	service.config.Config.TelemetryConfig.Disable = true
 	service.config.Config.Log.Level = "debug"
@@ -128,6 +137,8 @@ func (s *Suite) SetupTest() {
 	s.dockerClient.EXPECT().WaitContainerStart(service.ctx, gomock.Any()).Return(nil).AnyTimes()
 	s.msgClient.EXPECT().Subscribe(messaging.SubjectAgentsActionRun, gomock.Any())
 	s.msgClient.EXPECT().Subscribe(messaging.SubjectAgentsActionStop, gomock.Any())
+	s.msgClient.EXPECT().Subscribe(messaging.SubjectAgentsRejected, gomock.Any())
+	s.msgClient.EXPECT().Subscribe(messaging.SubjectMessagingDeadLetter, gomock.Any())
 
 	s.r.NoError(service.start())
 }
@@ -185,6 +196,9 @@ func (s *Suite) TestAgentRun() {
 	// Creates the agent network, starts the agent container, attaches the scanner and the proxy to the
 	// agent network, publishes a "running" message.
 	s.agentImageClient.EXPECT().EnsureLocalImage(s.service.ctx, "agent test-agent", agentConfig.Image).Return(nil)
+	s.agentImageClient.EXPECT().InspectImage(s.service.ctx, agentConfig.Image).Return(&types.ImageInspect{
+		RepoDigests: []string{testImageRef},
+	}, nil)
 	s.dockerClient.EXPECT().CreatePublicNetwork(s.service.ctx, testAgentContainerName).Return(testAgentNetworkID, nil)
 	s.dockerClient.EXPECT().StartContainer(s.service.ctx, (configMatcher)(clients.DockerContainerConfig{
 		Name: agentConfig.ContainerName(),
@@ -206,6 +220,9 @@ func (s *Suite) TestAgentRunAgain() {
 	// Expect it to only publish a message again to ensure the subscribers that
 	// the agent is running.
 	s.agentImageClient.EXPECT().EnsureLocalImage(s.service.ctx, "agent test-agent", agentConfig.Image).Return(nil)
+	s.agentImageClient.EXPECT().InspectImage(s.service.ctx, agentConfig.Image).Return(&types.ImageInspect{
+		RepoDigests: []string{testImageRef},
+	}, nil)
 	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusRunning, agentPayload)
 
 	s.r.NoError(s.service.handleAgentRun(agentPayload))
@@ -231,3 +248,266 @@ func (s *Suite) TestAgentStopNone() {
 
 	s.r.NoError(s.service.handleAgentStop(agentPayload))
 }
+
+// TestAgentStopReplayed tests stopping an agent container which was started before the supervisor restarted.
+func (s *Suite) TestAgentStopReplayed() {
+	s.service.config.Config.Messaging.Lifecycle.Durable = true
+
+	_, agentPayload := testAgentData()
+	s.dockerClient.EXPECT().GetContainerByName(s.service.ctx, testAgentContainerName).Return(&types.Container{
+		ID: testAgentContainerID, State: "running",
+	}, nil)
+	s.dockerClient.EXPECT().StopContainer(s.service.ctx, testAgentContainerID)
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusStopped, agentPayload)
+
+	s.r.NoError(s.service.handleAgentStop(agentPayload))
+}
+
+// TestAgentVersions tests stopping the agents which are not in the latest versions.
+func (s *Suite) TestAgentRejected() {
+	s.r.NoError(s.service.handleAgentRejected(messaging.AgentRejectedPayload{
+		AgentID: testAgentID,
+		Field:   "image",
+		Reason:  "invalid reference",
+	}))
+
+	report, ok := s.service.Health().GetByName("event.agent-rejected.details")
+	s.r.True(ok)
+	s.r.Equal(fmt.Sprintf("%s: image: invalid reference", testAgentID), report.Details)
+}
+
+func (s *Suite) TestAgentVersions() {
+	s.TestAgentRun()
+
+	agentConfig, agentPayload := testAgentData()
+	s.dockerClient.EXPECT().GetContainers(s.service.ctx).Return(clients.DockerContainerList{
+		{
+			ID:     testAgentContainerID,
+			Names:  []string{"/" + testAgentContainerName},
+			State:  "running",
+			Labels: map[string]string{clients.DockerLabelFortaAgentID: testAgentID},
+		},
+		{
+			ID:    testScannerContainerID,
+			Names: []string{"/" + config.DockerScannerContainerName},
+			State: "running",
+		},
+	}, nil).Times(2)
+
+	// the agent is in the latest versions
+	s.r.NoError(s.service.handleAgentVersions(agentPayload))
+
+	// the agent was removed
+	s.dockerClient.EXPECT().StopContainer(s.service.ctx, testAgentContainerID)
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusStopped, messaging.AgentPayload{agentConfig})
+	s.r.NoError(s.service.handleAgentVersions(messaging.AgentPayload{}))
+	_, ok := s.service.getContainerUnsafe(testAgentContainerName)
+	s.r.False(ok)
+}
+
+// TestAgentOOMKilled tests restarting an OOM-killed agent after a backoff.
+func (s *Suite) TestAgentOOMKilled() {
+	s.TestAgentRun()
+
+	agentContainer, ok := s.service.getContainerUnsafe(testAgentContainerName)
+	s.r.True(ok)
+	agentContainer.Config.Name = testAgentContainerName
+	exited := &types.Container{ID: testAgentContainerID, State: "exited"}
+	oomKilled := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{OOMKilled: true}},
+	}
+
+	// waits on the first check
+	s.dockerClient.EXPECT().InspectContainer(s.service.ctx, testAgentContainerID).Return(oomKilled, nil)
+	s.r.NoError(s.service.ensureUp(agentContainer, exited))
+	s.r.Equal(1, agentContainer.exits)
+	s.r.False(agentContainer.restartAt.IsZero())
+
+	// restarts after the backoff
+	agentContainer.restartAt = time.Now().Add(-time.Second)
+	s.dockerClient.EXPECT().InspectContainer(s.service.ctx, testAgentContainerID).Return(oomKilled, nil)
+	s.dockerClient.EXPECT().StartContainer(s.service.ctx, (configMatcher)(clients.DockerContainerConfig{
+		Name: testAgentContainerName,
+	})).Return(&clients.DockerContainer{}, nil)
+	s.r.NoError(s.service.ensureUp(agentContainer, exited))
+	s.r.True(agentContainer.restartAt.IsZero())
+
+	// the backoff doubles on the next kill
+	s.dockerClient.EXPECT().InspectContainer(s.service.ctx, testAgentContainerID).Return(oomKilled, nil)
+	s.r.NoError(s.service.ensureUp(agentContainer, exited))
+	s.r.Equal(2, agentContainer.exits)
+	s.r.True(agentContainer.restartAt.After(time.Now().Add(minAgentRestartBackoff)))
+}
+
+// TestAgentCrashLoop tests marking an agent which keeps exiting as failed.
+func (s *Suite) TestAgentCrashLoop() {
+	s.TestAgentRun()
+
+	agentConfig, agentPayload := testAgentData()
+	agentContainer, ok := s.service.getContainerUnsafe(testAgentContainerName)
+	s.r.True(ok)
+	exited := &types.Container{ID: testAgentContainerID, State: "exited"}
+	crashed := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{ExitCode: 1}},
+	}
+
+	// the last exit was the last attempt
+	agentContainer.exits = defaultAgentRestartAttempts
+	agentContainer.lastExit = time.Now()
+	s.dockerClient.EXPECT().InspectContainer(s.service.ctx, testAgentContainerID).Return(crashed, nil)
+	s.r.NoError(s.service.ensureUp(agentContainer, exited))
+	s.r.True(agentContainer.failed)
+
+	// stops the failed agent and publishes the status
+	s.dockerClient.EXPECT().StopContainer(s.service.ctx, testAgentContainerID)
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusFailed, &messaging.AgentFailedPayload{
+		Agent:    agentConfig,
+		Restarts: defaultAgentRestartAttempts,
+		Reason:   "crash loop: exited with code 1",
+	})
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusStopped, agentPayload)
+	s.service.handleFailedAgents()
+	_, ok = s.service.getContainerUnsafe(testAgentContainerName)
+	s.r.False(ok)
+}
+
+// TestAgentDiskLimitExceeded tests stopping an agent which exceeds the disk limit.
+func (s *Suite) TestAgentDiskLimitExceeded() {
+	s.TestAgentRun()
+
+	agentConfig, agentPayload := testAgentData()
+
+	s.dockerClient.EXPECT().GetContainerDiskUsage(s.service.ctx, testAgentContainerID).Return(int64(2048*1048576), nil)
+	s.msgClient.EXPECT().PublishProto(messaging.SubjectMetricAgent, gomock.Any())
+	s.dockerClient.EXPECT().StopContainer(s.service.ctx, testAgentContainerID)
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusFailed, &messaging.AgentFailedPayload{
+		Agent:  agentConfig,
+		Reason: "disk limit exceeded: 2147483648/1073741824 bytes",
+	})
+	s.msgClient.EXPECT().Publish(messaging.SubjectAgentsStatusStopped, agentPayload)
+	s.service.doCheckAgentDiskUsage()
+
+	_, ok := s.service.getContainerUnsafe(testAgentContainerName)
+	s.r.False(ok)
+}
+
+// TestAgentStats tests sending the resource usage of the agents as metrics.
+func (s *Suite) TestAgentStats() {
+	s.TestAgentRun()
+
+	s.dockerClient.EXPECT().GetContainerStats(s.service.ctx, testAgentContainerID).Return(&clients.ContainerStats{
+		CPUPercent:  12.5,
+		MemoryUsage: 1024,
+		NetworkRx:   10,
+		NetworkTx:   20,
+	}, nil)
+	s.msgClient.EXPECT().PublishProto(messaging.SubjectMetricAgent, gomock.Any()).Do(func(subject string, payload proto.Message) {
+		values := make(map[string]float64)
+		for _, metric := range payload.(*protocol.AgentMetricList).Metrics {
+			s.r.Equal(testAgentID, metric.AgentId)
+			values[metric.Name] = metric.Value
+		}
+		s.r.Equal(map[string]float64{
+			metrics.MetricRestarts:    0,
+			metrics.MetricCPUUsage:    12.5,
+			metrics.MetricMemoryUsage: 1024,
+			metrics.MetricNetworkRx:   10,
+			metrics.MetricNetworkTx:   20,
+		}, values)
+	})
+	s.service.doCollectAgentStats()
+}
+
+// TestCleanUpAgents tests removing the stopped agent containers and the unused agent images.
+func (s *Suite) TestCleanUpAgents() {
+	s.TestAgentStopOne()
+	s.service.config.Config.Registry.ContainerRegistry = "some.docker.registry.io"
+
+	// the stopped agent container is removed with its network
+	s.dockerClient.EXPECT().GetContainers(s.service.ctx).Return(clients.DockerContainerList{
+		{ID: testAgentContainerID, Names: []string{"/" + testAgentContainerName}, State: "exited"},
+		{ID: testScannerContainerID, Names: []string{"/" + config.DockerScannerContainerName}, State: "exited"},
+		{ID: "other-agent-container-id", Names: []string{"/forta-agent-other"}, State: "running"},
+	}, nil)
+	s.dockerClient.EXPECT().RemoveContainer(s.service.ctx, testAgentContainerID)
+	s.dockerClient.EXPECT().DetachNetwork(s.service.ctx, testScannerContainerID, testAgentContainerName)
+	s.dockerClient.EXPECT().DetachNetwork(s.service.ctx, testProxyContainerID, testAgentContainerName)
+	s.dockerClient.EXPECT().RemoveNetworkByName(s.service.ctx, testAgentContainerName)
+
+	// the agent image is kept during the grace period
+	images := []types.ImageSummary{
+		{ID: "sha256:1", RepoDigests: []string{testImageRef}},
+		{ID: "sha256:2", RepoTags: []string{"some.docker.registry.io/foobar:latest"}},
+	}
+	s.dockerClient.EXPECT().GetImages(s.service.ctx).Return(images, nil)
+	s.service.doCleanUpAgents()
+	s.r.Contains(s.service.agentImagesLastUsed, "sha256:1")
+	s.r.NotContains(s.service.agentImagesLastUsed, "sha256:2")
+
+	// and removed after the grace period
+	s.service.agentImagesLastUsed["sha256:1"] = time.Now().Add(-defaultAgentImageGracePeriod)
+	s.dockerClient.EXPECT().GetImages(s.service.ctx).Return(images, nil)
+	s.dockerClient.EXPECT().RemoveImage(s.service.ctx, "sha256:1")
+	s.r.NoError(s.service.removeUnusedAgentImages())
+	s.r.NotContains(s.service.agentImagesLastUsed, "sha256:1")
+}
+
+// TestPinAgentImage tests pinning the agent images.
+func (s *Suite) TestPinAgentImage() {
+	agentConfig, _ := testAgentData()
+
+	// the pulled image has another digest
+	s.agentImageClient.EXPECT().InspectImage(s.service.ctx, agentConfig.Image).Return(&types.ImageInspect{
+		RepoDigests: []string{"some.docker.registry.io/foobar@sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+	}, nil)
+	_, err := s.service.pinAgentImage(agentConfig)
+	s.r.ErrorIs(err, errImageDigestDrift)
+
+	// the registry agents can't use tags
+	agentConfig.Image = "foobar:latest"
+	s.agentImageClient.EXPECT().InspectImage(s.service.ctx, agentConfig.Image).Return(&types.ImageInspect{ID: "sha256:1"}, nil)
+	_, err = s.service.pinAgentImage(agentConfig)
+	s.r.ErrorIs(err, errImageNotPinned)
+
+	// the local agent tags are pinned to the image IDs
+	agentConfig.IsLocal = true
+	s.agentImageClient.EXPECT().InspectImage(s.service.ctx, agentConfig.Image).Return(&types.ImageInspect{ID: "sha256:1"}, nil).Times(2)
+	image, err := s.service.pinAgentImage(agentConfig)
+	s.r.NoError(err)
+	s.r.Equal("sha256:1", image)
+	image, err = s.service.pinAgentImage(agentConfig)
+	s.r.NoError(err)
+	s.r.Equal("sha256:1", image)
+
+	s.agentImageClient.EXPECT().InspectImage(s.service.ctx, agentConfig.Image).Return(&types.ImageInspect{ID: "sha256:2"}, nil)
+	_, err = s.service.pinAgentImage(agentConfig)
+	s.r.ErrorIs(err, errImageDigestDrift)
+}
+
+type testImageVerifier struct {
+	err error
+}
+
+func (v *testImageVerifier) Verify(ctx context.Context, imageRef, publicKeyPEM string) error {
+	return v.err
+}
+
+// TestVerifyAgentImage tests the image signature policies.
+func (s *Suite) TestVerifyAgentImage() {
+	agentConfig, _ := testAgentData()
+	verifier := &testImageVerifier{err: errors.New("invalid signature")}
+	s.service.imageVerifier = verifier
+
+	s.service.config.Config.Registry.ImageSignaturePolicy = ImageSignaturePolicyOff
+	s.r.NoError(s.service.verifyAgentImage(agentConfig, agentConfig.Image))
+
+	s.service.config.Config.Registry.ImageSignaturePolicy = ImageSignaturePolicyWarn
+	s.r.NoError(s.service.verifyAgentImage(agentConfig, agentConfig.Image))
+
+	s.service.config.Config.Registry.ImageSignaturePolicy = ImageSignaturePolicyEnforce
+	s.r.ErrorIs(s.service.verifyAgentImage(agentConfig, agentConfig.Image), errNoImageSigningKey)
+	agentConfig.CosignPublicKey = "key"
+	s.r.Error(s.service.verifyAgentImage(agentConfig, agentConfig.Image))
+	verifier.err = nil
+	s.r.NoError(s.service.verifyAgentImage(agentConfig, agentConfig.Image))
+}
@@ -0,0 +1,120 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/forta-network/forta-node/config"
+)
+
+const releaseIndexTimeout = time.Second * 30
+
+// ReleaseIndex is served at the release URL and points to the latest release of each channel.
+type ReleaseIndex struct {
+	Channels map[string]ChannelRelease `json:"channels"`
+}
+
+// ChannelRelease is the latest release of a channel. The signature is the Ethereum signed message
+// signature of the signed release payload.
+type ChannelRelease struct {
+	Version   string `json:"version"`
+	Reference string `json:"reference"`
+	Signature string `json:"signature"`
+}
+
+// signedRelease is the payload which the release signers sign. The channel and the version are
+// signed together with the reference so that a signed release cannot be replayed in another
+// channel or under another version.
+type signedRelease struct {
+	Channel   string `json:"channel"`
+	Version   string `json:"version"`
+	Reference string `json:"reference"`
+}
+
+// SignedReleasePayload returns the message which is signed for the release of the channel.
+func SignedReleasePayload(channel string, channelRelease ChannelRelease) []byte {
+	b, _ := json.Marshal(&signedRelease{
+		Channel:   channel,
+		Version:   channelRelease.Version,
+		Reference: channelRelease.Reference,
+	})
+	return b
+}
+
+// releaseIndexSource reads the release reference of the channel from the release index.
+type releaseIndexSource struct {
+	cfg    config.AutoUpdateConfig
+	client *http.Client
+}
+
+func newReleaseIndexSource(cfg config.AutoUpdateConfig) (*releaseIndexSource, error) {
+	if len(cfg.ReleaseSigners) == 0 {
+		return nil, errors.New("release signers are required for the release url")
+	}
+	return &releaseIndexSource{
+		cfg:    cfg,
+		client: &http.Client{Timeout: releaseIndexTimeout},
+	}, nil
+}
+
+// GetReference returns the verified release reference and version of the channel.
+func (ris *releaseIndexSource) GetReference(ctx context.Context) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ris.cfg.ReleaseURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := ris.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get the release index: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return "", "", fmt.Errorf("unexpected release index response with code %d: %s", resp.StatusCode, string(b))
+	}
+	var index ReleaseIndex
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return "", "", fmt.Errorf("failed to decode the release index: %v", err)
+	}
+	channelRelease, ok := index.Channels[ris.cfg.Channel]
+	if !ok || len(channelRelease.Reference) == 0 || len(channelRelease.Version) == 0 {
+		return "", "", fmt.Errorf("no release found for channel %s", ris.cfg.Channel)
+	}
+	if err := VerifyReleaseSignature(ris.cfg.Channel, channelRelease, ris.cfg.ReleaseSigners); err != nil {
+		return "", "", err
+	}
+	return channelRelease.Reference, channelRelease.Version, nil
+}
+
+// VerifyReleaseSignature checks if the release of the channel is signed by one of the signers.
+func VerifyReleaseSignature(channel string, channelRelease ChannelRelease, signers []string) error {
+	sig, err := hexutil.Decode(channelRelease.Signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return fmt.Errorf("invalid release signature: %s", channelRelease.Signature)
+	}
+	// the wallets use 27 and 28 as the recovery ID
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubKey, err := crypto.SigToPub(accounts.TextHash(SignedReleasePayload(channel, channelRelease)), sig)
+	if err != nil {
+		return fmt.Errorf("failed to recover the release signer: %v", err)
+	}
+	signer := crypto.PubkeyToAddress(*pubKey)
+	for _, trusted := range signers {
+		if common.HexToAddress(trusted) == signer {
+			return nil
+		}
+	}
+	return fmt.Errorf("release %s is signed by an untrusted signer %s", channelRelease.Reference, strings.ToLower(signer.Hex()))
+}
@@ -0,0 +1,92 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func signTestRelease(t *testing.T, channel, version, reference string) (ChannelRelease, string) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	channelRelease := ChannelRelease{Version: version, Reference: reference}
+	sig, err := crypto.Sign(accounts.TextHash(SignedReleasePayload(channel, channelRelease)), key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+	channelRelease.Signature = hexutil.Encode(sig)
+	return channelRelease, crypto.PubkeyToAddress(key.PublicKey).Hex()
+}
+
+func TestVerifyReleaseSignature(t *testing.T) {
+	r := require.New(t)
+
+	channelRelease, signer := signTestRelease(t, "stable", "v1.0.0", "reference")
+	r.NoError(VerifyReleaseSignature("stable", channelRelease, []string{signer}))
+
+	_, otherSigner := signTestRelease(t, "stable", "v1.0.0", "reference")
+	r.Error(VerifyReleaseSignature("stable", channelRelease, []string{otherSigner}))
+
+	// the signed release cannot be used in another channel
+	r.Error(VerifyReleaseSignature("testing", channelRelease, []string{signer}))
+
+	otherVersion := channelRelease
+	otherVersion.Version = "v0.9.0"
+	r.Error(VerifyReleaseSignature("stable", otherVersion, []string{signer}))
+
+	otherReference := channelRelease
+	otherReference.Reference = "other-reference"
+	r.Error(VerifyReleaseSignature("stable", otherReference, []string{signer}))
+
+	r.Error(VerifyReleaseSignature("stable", ChannelRelease{Version: "v1.0.0", Reference: "reference", Signature: "0x1234"}, []string{signer}))
+}
+
+func TestReleaseIndexSource(t *testing.T) {
+	r := require.New(t)
+
+	stable, signer := signTestRelease(t, "stable", "v1.0.0", "stable-reference")
+	testingRelease, _ := signTestRelease(t, "testing", "v1.1.0", "testing-reference")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(&ReleaseIndex{
+			Channels: map[string]ChannelRelease{
+				"stable":  stable,
+				"testing": testingRelease,
+			},
+		})
+	}))
+	defer server.Close()
+
+	_, err := NewReleaseSource(config.AutoUpdateConfig{Channel: "stable", ReleaseURL: server.URL}, nil)
+	r.Error(err, "signers are required")
+
+	source, err := NewReleaseSource(config.AutoUpdateConfig{
+		Channel:        "stable",
+		ReleaseURL:     server.URL,
+		ReleaseSigners: []string{signer},
+	}, nil)
+	r.NoError(err)
+	ref, version, err := source.GetReference(context.Background())
+	r.NoError(err)
+	r.Equal("stable-reference", ref)
+	r.Equal("v1.0.0", version)
+
+	// the testing release is signed by another key
+	source, err = NewReleaseSource(config.AutoUpdateConfig{
+		Channel:        "testing",
+		ReleaseURL:     server.URL,
+		ReleaseSigners: []string{signer},
+	}, nil)
+	r.NoError(err)
+	_, _, err = source.GetReference(context.Background())
+	r.Error(err)
+
+	_, err = NewReleaseSource(config.AutoUpdateConfig{Channel: "testing"}, nil)
+	r.Error(err, "release url is required")
+}
@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,6 +21,35 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// ReleaseSource provides the reference of the latest release manifest. The version is empty if the
+// source does not provide a signed version.
+type ReleaseSource interface {
+	GetReference(ctx context.Context) (reference string, version string, err error)
+}
+
+// registrySource reads the latest release from the scanner version contract.
+type registrySource struct {
+	rg registry.Client
+}
+
+// NewReleaseSource creates the release source of the config. The releases are read from the scanner
+// version contract if the release URL is not set.
+func NewReleaseSource(cfg config.AutoUpdateConfig, rg registry.Client) (ReleaseSource, error) {
+	if len(cfg.ReleaseURL) > 0 {
+		return newReleaseIndexSource(cfg)
+	}
+	if cfg.Channel != "stable" {
+		return nil, fmt.Errorf("release url is required for channel %s", cfg.Channel)
+	}
+	return &registrySource{rg: rg}, nil
+}
+
+// GetReference implements the ReleaseSource interface.
+func (rs *registrySource) GetReference(ctx context.Context) (string, string, error) {
+	ref, err := rs.rg.GetScannerNodeVersion()
+	return ref, "", err
+}
+
 // UpdaterService receives the release updates.
 type UpdaterService struct {
 	ctx  context.Context
@@ -26,10 +57,11 @@ type UpdaterService struct {
 
 	mu     sync.RWMutex
 	rl     release.Client
-	rg     registry.Client
+	source ReleaseSource
 	server *http.Server
 
 	developmentMode bool
+	runningVersion  string
 
 	latestReference string
 	latestRelease   *release.ReleaseManifest
@@ -41,15 +73,16 @@ type UpdaterService struct {
 }
 
 // NewUpdaterService creates a new updater service.
-func NewUpdaterService(ctx context.Context, rg registry.Client, rc release.Client,
+func NewUpdaterService(ctx context.Context, source ReleaseSource, rc release.Client,
 	port string, developmentMode bool, delaySeconds int,
 ) *UpdaterService {
 	return &UpdaterService{
 		ctx:             ctx,
 		port:            port,
-		rg:              rg,
+		source:          source,
 		rl:              rc,
 		developmentMode: developmentMode,
+		runningVersion:  config.Version,
 		delaySeconds:    delaySeconds,
 	}
 }
@@ -122,7 +155,7 @@ func (updater *UpdaterService) updateLatestReleaseWithDelay(delay time.Duration)
 
 	log.Info("updating latest release")
 
-	ref, err := updater.rg.GetScannerNodeVersion()
+	ref, version, err := updater.source.GetReference(updater.ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get the latest release manifest ref: %v", err)
 	}
@@ -132,6 +165,9 @@ func (updater *UpdaterService) updateLatestReleaseWithDelay(delay time.Duration)
 			log.WithError(err).Error("error getting release manifest")
 			return fmt.Errorf("failed while downloading the release manifest: %v", err)
 		}
+		if err := updater.checkReleaseVersion(rm, version); err != nil {
+			return err
+		}
 
 		// so that all scanners don't update simultaneously, this waits a period of time
 		if delay > 0 {
@@ -156,6 +192,57 @@ func (updater *UpdaterService) updateLatestReleaseWithDelay(delay time.Duration)
 	return nil
 }
 
+// checkReleaseVersion ensures that the manifest has the signed version and that the release is not
+// older than the running version.
+func (updater *UpdaterService) checkReleaseVersion(rm *release.ReleaseManifest, signedVersion string) error {
+	releaseVersion := rm.Release.Version
+	if len(signedVersion) > 0 && releaseVersion != signedVersion {
+		return fmt.Errorf("release manifest version %s does not match the signed version %s", releaseVersion, signedVersion)
+	}
+	if isOlderVersion(releaseVersion, updater.runningVersion) {
+		return fmt.Errorf("release version %s is older than the running version %s", releaseVersion, updater.runningVersion)
+	}
+	return nil
+}
+
+// isOlderVersion checks if the version is lower than the other version. The versions which are not
+// in the major.minor.patch format cannot be compared and are not considered older.
+func isOlderVersion(version, other string) bool {
+	v, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	o, ok := parseVersion(other)
+	if !ok {
+		return false
+	}
+	for i := range v {
+		if v[i] != o[i] {
+			return v[i] < o[i]
+		}
+	}
+	return false
+}
+
+func parseVersion(version string) (parsed [3]int, ok bool) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) != len(parsed) {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
 func (updater *UpdaterService) readLocalReleaseManifest() error {
 	b, err := ioutil.ReadFile(path.Join(config.DefaultContainerFortaDirPath, "test-release.json"))
 	if err != nil {
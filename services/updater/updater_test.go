@@ -21,7 +21,7 @@ func TestUpdaterService_UpdateLatestRelease(t *testing.T) {
 
 	rg := rm.NewMockClient(c)
 	is := im.NewMockClient(c)
-	updater := NewUpdaterService(context.Background(), rg, is, "8080", false, testDefaultCheckIntervalSeconds)
+	updater := NewUpdaterService(context.Background(), &registrySource{rg: rg}, is, "8080", false, testDefaultCheckIntervalSeconds)
 
 	rg.EXPECT().GetScannerNodeVersion().Return("reference", nil).Times(1)
 	is.EXPECT().GetReleaseManifest(gomock.Any(), "reference").Return(&release.ReleaseManifest{}, nil).Times(1)
@@ -33,7 +33,7 @@ func TestUpdaterService_UpdateLatestReleaseCached(t *testing.T) {
 	c := gomock.NewController(t)
 	rg := rm.NewMockClient(c)
 	is := im.NewMockClient(c)
-	updater := NewUpdaterService(context.Background(), rg, is, "8080", false, testDefaultCheckIntervalSeconds)
+	updater := NewUpdaterService(context.Background(), &registrySource{rg: rg}, is, "8080", false, testDefaultCheckIntervalSeconds)
 
 	// update twice
 	rg.EXPECT().GetScannerNodeVersion().Return("reference", nil).Times(2)
@@ -48,7 +48,7 @@ func TestUpdaterService_UpdateLatestReleaseNotCached(t *testing.T) {
 	c := gomock.NewController(t)
 	rg := rm.NewMockClient(c)
 	is := im.NewMockClient(c)
-	updater := NewUpdaterService(context.Background(), rg, is, "8080", false, testDefaultCheckIntervalSeconds)
+	updater := NewUpdaterService(context.Background(), &registrySource{rg: rg}, is, "8080", false, testDefaultCheckIntervalSeconds)
 
 	// update twice
 	rg.EXPECT().GetScannerNodeVersion().Return("reference1", nil).Times(1)
@@ -61,3 +61,56 @@ func TestUpdaterService_UpdateLatestReleaseNotCached(t *testing.T) {
 	assert.NoError(t, updater.updateLatestRelease())
 	assert.NoError(t, updater.updateLatestRelease())
 }
+
+func TestUpdaterService_RejectOlderRelease(t *testing.T) {
+	c := gomock.NewController(t)
+	rg := rm.NewMockClient(c)
+	is := im.NewMockClient(c)
+	updater := NewUpdaterService(context.Background(), &registrySource{rg: rg}, is, "8080", false, testDefaultCheckIntervalSeconds)
+	updater.runningVersion = "v1.2.0"
+
+	rg.EXPECT().GetScannerNodeVersion().Return("reference1", nil).Times(1)
+	is.EXPECT().GetReleaseManifest(gomock.Any(), "reference1").Return(&release.ReleaseManifest{
+		Release: release.Release{Version: "v1.1.9"},
+	}, nil).Times(1)
+	assert.Error(t, updater.updateLatestRelease())
+	assert.Nil(t, updater.latestRelease)
+
+	rg.EXPECT().GetScannerNodeVersion().Return("reference2", nil).Times(1)
+	is.EXPECT().GetReleaseManifest(gomock.Any(), "reference2").Return(&release.ReleaseManifest{
+		Release: release.Release{Version: "v1.2.1"},
+	}, nil).Times(1)
+	assert.NoError(t, updater.updateLatestRelease())
+	assert.Equal(t, "reference2", updater.latestReference)
+}
+
+func TestUpdaterService_SignedVersionMismatch(t *testing.T) {
+	c := gomock.NewController(t)
+	is := im.NewMockClient(c)
+	updater := NewUpdaterService(context.Background(), &testReleaseSource{ref: "reference", version: "v1.2.0"}, is, "8080", false, testDefaultCheckIntervalSeconds)
+
+	is.EXPECT().GetReleaseManifest(gomock.Any(), "reference").Return(&release.ReleaseManifest{
+		Release: release.Release{Version: "v1.3.0"},
+	}, nil).Times(1)
+	assert.Error(t, updater.updateLatestRelease())
+}
+
+type testReleaseSource struct {
+	ref     string
+	version string
+}
+
+func (trs *testReleaseSource) GetReference(ctx context.Context) (string, string, error) {
+	return trs.ref, trs.version, nil
+}
+
+func TestIsOlderVersion(t *testing.T) {
+	assert.True(t, isOlderVersion("v1.2.3", "v1.2.4"))
+	assert.True(t, isOlderVersion("v0.9.10", "1.0.0"))
+	assert.False(t, isOlderVersion("v1.2.3", "v1.2.3"))
+	assert.False(t, isOlderVersion("v1.10.0", "v1.9.0"))
+	assert.False(t, isOlderVersion("v1.2.3-rc1", "v1.2.3"))
+	// unknown versions are not compared
+	assert.False(t, isOlderVersion("v1.0.0", ""))
+	assert.False(t, isOlderVersion("latest", "v1.0.0"))
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/forta-network/forta-core-go/protocol"
+)
+
+// AlertHashTag is the alert tag which contains the canonical hash of the finding.
+const AlertHashTag = "alertHash"
+
+// canonicalFinding is the normalized content of a finding. The fields are encoded in a fixed order so
+// that the same finding always has the same hash.
+type canonicalFinding struct {
+	AgentID     string     `json:"agentId"`
+	ChainID     string     `json:"chainId"`
+	BlockHash   string     `json:"blockHash"`
+	TxHash      string     `json:"txHash"`
+	AlertID     string     `json:"alertId"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Protocol    string     `json:"protocol"`
+	Severity    string     `json:"severity"`
+	Type        string     `json:"type"`
+	Private     bool       `json:"private"`
+	Addresses   []string   `json:"addresses"`
+	Metadata    [][]string `json:"metadata"`
+}
+
+// FindingHash returns the canonical hash of the finding of an agent for a block or a transaction. The
+// hash does not change with the order of the addresses and the metadata, the letter case of the hex
+// values and the surrounding whitespace, so that the same finding can be detected after the restarts
+// and the replays.
+func FindingHash(agentID, chainID, blockHash, txHash string, finding *protocol.Finding) string {
+	cf := canonicalFinding{
+		AgentID:     normalizeHex(agentID),
+		ChainID:     normalizeHex(chainID),
+		BlockHash:   normalizeHex(blockHash),
+		TxHash:      normalizeHex(txHash),
+		AlertID:     strings.TrimSpace(finding.AlertId),
+		Name:        strings.TrimSpace(finding.Name),
+		Description: strings.TrimSpace(finding.Description),
+		Protocol:    strings.TrimSpace(finding.Protocol),
+		Severity:    finding.Severity.String(),
+		Type:        finding.Type.String(),
+		Private:     finding.Private,
+		Addresses:   make([]string, 0, len(finding.Addresses)),
+		Metadata:    make([][]string, 0, len(finding.Metadata)),
+	}
+	seen := make(map[string]bool)
+	for _, address := range finding.Addresses {
+		address = normalizeHex(address)
+		if len(address) == 0 || seen[address] {
+			continue
+		}
+		seen[address] = true
+		cf.Addresses = append(cf.Addresses, address)
+	}
+	sort.Strings(cf.Addresses)
+	for key, value := range finding.Metadata {
+		cf.Metadata = append(cf.Metadata, []string{strings.TrimSpace(key), strings.TrimSpace(value)})
+	}
+	sort.Slice(cf.Metadata, func(i, j int) bool {
+		return cf.Metadata[i][0] < cf.Metadata[j][0]
+	})
+
+	b, _ := json.Marshal(cf) // can't fail with strings
+	return crypto.Keccak256Hash(b).Hex()
+}
+
+func normalizeHex(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// StoredAlertHash returns the hash which identifies the alert in the stores. The alerts from before the
+// hash tag are identified with the alert ID.
+func StoredAlertHash(alert *protocol.SignedAlert) string {
+	if hash, ok := alert.GetAlert().GetTags()[AlertHashTag]; ok && len(hash) > 0 {
+		return hash
+	}
+	return alert.GetAlert().GetId()
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindingHash(t *testing.T) {
+	r := require.New(t)
+
+	finding := &protocol.Finding{
+		AlertId:     "ALERT-1",
+		Name:        "name",
+		Description: "description",
+		Severity:    protocol.Finding_HIGH,
+		Type:        protocol.Finding_EXPLOIT,
+		Addresses:   []string{"0xAA", "0xbb"},
+		Metadata:    map[string]string{"a": "1", "b": "2"},
+	}
+	hash := FindingHash("0xAgent", "1", "0xBlock", "0xTx", finding)
+
+	// the order, the letter case of the hex values and the whitespace do not change the hash
+	normalized := &protocol.Finding{
+		AlertId:     "ALERT-1",
+		Name:        " name",
+		Description: "description\n",
+		Severity:    protocol.Finding_HIGH,
+		Type:        protocol.Finding_EXPLOIT,
+		Addresses:   []string{"0xbb", "0xaa", "0xAA"},
+		Metadata:    map[string]string{"b": "2", "a": "1"},
+	}
+	r.Equal(hash, FindingHash("0xagent", "1", "0xblock", "0xtx", normalized))
+
+	// the content, the agent and the tx change the hash
+	r.NotEqual(hash, FindingHash("0xother", "1", "0xblock", "0xtx", finding))
+	r.NotEqual(hash, FindingHash("0xagent", "1", "0xblock", "0xothertx", finding))
+	r.NotEqual(hash, FindingHash("0xagent", "137", "0xblock", "0xtx", finding))
+	normalized.Metadata["b"] = "3"
+	r.NotEqual(hash, FindingHash("0xagent", "1", "0xblock", "0xtx", normalized))
+}
+
+func TestStoredAlertHash(t *testing.T) {
+	r := require.New(t)
+
+	alert := &protocol.SignedAlert{Alert: &protocol.Alert{Id: "0xid"}}
+	r.Equal("0xid", StoredAlertHash(alert))
+	alert.Alert.Tags = map[string]string{AlertHashTag: "0xhash"}
+	r.Equal("0xhash", StoredAlertHash(alert))
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/forta-network/forta-core-go/protocol"
+)
+
+// LatestAlertSchemaVersion is the schema version of the alerts which this node creates. The alerts
+// without the schema version tag are from the first version.
+const LatestAlertSchemaVersion = 2
+
+// AlertSchemaVersionTag is the alert tag which contains the schema version.
+const AlertSchemaVersionTag = "schemaVersion"
+
+// alertMigration upgrades an alert from a schema version to the next version. The migrations change
+// only the tags since the rest of the alert is signed.
+type alertMigration func(alert *protocol.Alert, chainID uint64)
+
+var alertMigrations = map[int]alertMigration{
+	1: migrateAlertV1,
+}
+
+// AlertSchemaTags returns the tags which every new alert should have.
+func AlertSchemaTags(tags map[string]string) map[string]string {
+	tags[AlertSchemaVersionTag] = strconv.Itoa(LatestAlertSchemaVersion)
+	return tags
+}
+
+// AlertSchemaVersion returns the schema version of the alert.
+func AlertSchemaVersion(alert *protocol.Alert) (int, error) {
+	versionStr, ok := alert.Tags[AlertSchemaVersionTag]
+	if !ok {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(versionStr)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid alert schema version '%s'", versionStr)
+	}
+	return version, nil
+}
+
+// MigrateAlert upgrades the alert to the latest schema version. The chain ID is used for the alerts which
+// don't contain the chain ID.
+func MigrateAlert(alert *protocol.Alert, chainID uint64) error {
+	version, err := AlertSchemaVersion(alert)
+	if err != nil {
+		return err
+	}
+	if version > LatestAlertSchemaVersion {
+		return fmt.Errorf("unsupported alert schema version %d, the latest supported version is %d", version, LatestAlertSchemaVersion)
+	}
+	for ; version < LatestAlertSchemaVersion; version++ {
+		alertMigrations[version](alert, chainID)
+	}
+	alert.Tags[AlertSchemaVersionTag] = strconv.Itoa(LatestAlertSchemaVersion)
+	return nil
+}
+
+// MigrateAlertBatch upgrades all alerts in the batch to the latest schema version.
+func MigrateAlertBatch(batch *protocol.AlertBatch) error {
+	var agentAlerts []*protocol.AgentAlerts
+	for _, blockRes := range batch.Results {
+		agentAlerts = append(agentAlerts, blockRes.Results...)
+		for _, txRes := range blockRes.Transactions {
+			agentAlerts = append(agentAlerts, txRes.Results...)
+		}
+	}
+	agentAlerts = append(agentAlerts, batch.PrivateAlerts...)
+
+	for _, agentAlert := range agentAlerts {
+		for _, signedAlert := range agentAlert.Alerts {
+			if signedAlert.Alert == nil {
+				continue
+			}
+			if err := MigrateAlert(signedAlert.Alert, batch.ChainId); err != nil {
+				return fmt.Errorf("alert %s: %v", signedAlert.Alert.Id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateAlertV1 adds the agent and the chain tags which were missing in some of the first version alerts.
+func migrateAlertV1(alert *protocol.Alert, chainID uint64) {
+	if alert.Tags == nil {
+		alert.Tags = make(map[string]string)
+	}
+	if _, ok := alert.Tags["agentId"]; !ok && alert.Agent != nil {
+		alert.Tags["agentId"] = alert.Agent.Id
+	}
+	if _, ok := alert.Tags["chainId"]; !ok && chainID > 0 {
+		alert.Tags["chainId"] = strconv.FormatUint(chainID, 10)
+	}
+}
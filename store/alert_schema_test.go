@@ -0,0 +1,70 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertSchemaTags(t *testing.T) {
+	r := require.New(t)
+
+	tags := AlertSchemaTags(map[string]string{"agentId": "0x1"})
+	r.Equal("2", tags[AlertSchemaVersionTag])
+
+	version, err := AlertSchemaVersion(&protocol.Alert{Tags: tags})
+	r.NoError(err)
+	r.Equal(LatestAlertSchemaVersion, version)
+}
+
+func TestMigrateAlert(t *testing.T) {
+	r := require.New(t)
+
+	alert := &protocol.Alert{Agent: &protocol.AgentInfo{Id: "0x1"}}
+	version, err := AlertSchemaVersion(alert)
+	r.NoError(err)
+	r.Equal(1, version)
+
+	r.NoError(MigrateAlert(alert, 137))
+	r.Equal(map[string]string{
+		"agentId":             "0x1",
+		"chainId":             "137",
+		AlertSchemaVersionTag: "2",
+	}, alert.Tags)
+
+	// the existing tags are kept
+	alert = &protocol.Alert{Agent: &protocol.AgentInfo{Id: "0x1"}, Tags: map[string]string{"chainId": "1"}}
+	r.NoError(MigrateAlert(alert, 137))
+	r.Equal("1", alert.Tags["chainId"])
+}
+
+func TestMigrateAlert_Unsupported(t *testing.T) {
+	r := require.New(t)
+
+	r.Error(MigrateAlert(&protocol.Alert{Tags: map[string]string{AlertSchemaVersionTag: "3"}}, 1))
+	r.Error(MigrateAlert(&protocol.Alert{Tags: map[string]string{AlertSchemaVersionTag: "bad"}}, 1))
+}
+
+func TestMigrateAlertBatch(t *testing.T) {
+	r := require.New(t)
+
+	txAlert := &protocol.SignedAlert{Alert: &protocol.Alert{Id: "tx", Agent: &protocol.AgentInfo{Id: "0x1"}}}
+	privateAlert := &protocol.SignedAlert{Alert: &protocol.Alert{Id: "private", Agent: &protocol.AgentInfo{Id: "0x2"}}}
+	batch := &protocol.AlertBatch{
+		ChainId: 1,
+		Results: []*protocol.BlockResults{
+			{
+				Transactions: []*protocol.TransactionResults{
+					{Results: []*protocol.AgentAlerts{{Alerts: []*protocol.SignedAlert{txAlert}}}},
+				},
+			},
+		},
+		PrivateAlerts: []*protocol.AgentAlerts{{Alerts: []*protocol.SignedAlert{privateAlert}}},
+	}
+
+	r.NoError(MigrateAlertBatch(batch))
+	r.Equal("2", txAlert.Alert.Tags[AlertSchemaVersionTag])
+	r.Equal("1", txAlert.Alert.Tags["chainId"])
+	r.Equal("0x2", privateAlert.Alert.Tags["agentId"])
+}
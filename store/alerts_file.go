@@ -0,0 +1,270 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	alertFileDateFormat = "2006-01-02"
+	alertFileExt        = ".ndjson"
+	alertHashesFileExt  = ".hashes"
+)
+
+// ErrDuplicateAlert is returned when the alert is stored already.
+var ErrDuplicateAlert = errors.New("alert is stored already")
+
+// AlertFileStore appends the alerts of a chain to a file per day as JSON lines, so that the alerts can
+// be read without the node APIs. The files which are older than the retention are removed.
+//
+//	<dir>/<chain ID>/<date>.ndjson
+//	<dir>/<chain ID>/<date>.hashes
+//
+// The writes are idempotent: the hashes of the stored alerts are appended to the index file of the day
+// and an alert with a stored hash is not written again. Only the index files of the retention are
+// loaded at the first write.
+type AlertFileStore struct {
+	dir       string
+	chainID   uint64
+	retention time.Duration
+
+	lastCleanup string
+	hashes      map[string]string // alert hash -> day
+	mu          sync.Mutex
+}
+
+// NewAlertFileStore creates a new alert file store for the chain.
+func NewAlertFileStore(dir string, chainID uint64, retentionDays int) *AlertFileStore {
+	return &AlertFileStore{
+		dir:       path.Join(dir, strconv.FormatUint(chainID, 10)),
+		chainID:   chainID,
+		retention: time.Duration(retentionDays) * time.Hour * 24,
+	}
+}
+
+// Put appends the alert to the file of its day. It returns ErrDuplicateAlert if an alert with the same
+// hash is stored already.
+func (afs *AlertFileStore) Put(alert *protocol.SignedAlert) error {
+	b, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	day := StoredAlertTime(alert).UTC().Format(alertFileDateFormat)
+	hash := StoredAlertHash(alert)
+
+	afs.mu.Lock()
+	defer afs.mu.Unlock()
+
+	if err := os.MkdirAll(afs.dir, 0755); err != nil {
+		return err
+	}
+	if today := time.Now().UTC().Format(alertFileDateFormat); afs.lastCleanup != today {
+		afs.cleanup()
+		afs.lastCleanup = today
+	}
+	if afs.hashes == nil {
+		if err := afs.loadHashes(); err != nil {
+			return err
+		}
+	}
+	if _, ok := afs.hashes[hash]; ok {
+		return ErrDuplicateAlert
+	}
+	if err := appendLine(path.Join(afs.dir, day+alertFileExt), b); err != nil {
+		return err
+	}
+	afs.hashes[hash] = day
+	// the alert is stored again after a restart if this fails
+	if err := appendLine(path.Join(afs.dir, day+alertHashesFileExt), []byte(hash)); err != nil {
+		log.WithError(err).WithField("day", day).Warn("failed to index the alert hash")
+	}
+	return nil
+}
+
+func appendLine(filePath string, b []byte) error {
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// loadHashes reads the hashes of the alerts which were stored before the restart from the index files.
+// The index of an older alert file is created from the alerts in it.
+func (afs *AlertFileStore) loadHashes() error {
+	hashes := make(map[string]string)
+	for _, day := range alertFileDays(afs.dir) {
+		dayHashes, err := afs.readDayHashes(day)
+		if err != nil {
+			return err
+		}
+		for _, hash := range dayHashes {
+			hashes[hash] = day
+		}
+	}
+	afs.hashes = hashes
+	return nil
+}
+
+func (afs *AlertFileStore) readDayHashes(day string) ([]string, error) {
+	indexPath := path.Join(afs.dir, day+alertHashesFileExt)
+	b, err := ioutil.ReadFile(indexPath)
+	if err == nil {
+		return strings.Fields(string(b)), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	var dayHashes []string
+	err = readAlertFile(path.Join(afs.dir, day+alertFileExt), afs.chainID, time.Time{}, maxAlertTime, func(alert *protocol.SignedAlert) error {
+		dayHashes = append(dayHashes, StoredAlertHash(alert))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	index := strings.Join(dayHashes, "\n")
+	if len(index) > 0 {
+		index += "\n"
+	}
+	if err := ioutil.WriteFile(indexPath, []byte(index), 0644); err != nil {
+		log.WithError(err).WithField("day", day).Warn("failed to index the alert hashes")
+	}
+	return dayHashes, nil
+}
+
+// cleanup removes the files of the days before the retention.
+func (afs *AlertFileStore) cleanup() {
+	oldest := time.Now().UTC().Add(-afs.retention).Format(alertFileDateFormat)
+	for _, day := range alertFileDays(afs.dir) {
+		if day >= oldest {
+			continue
+		}
+		if err := os.Remove(path.Join(afs.dir, day+alertFileExt)); err != nil {
+			log.WithError(err).WithField("day", day).Warn("failed to remove the old alert file")
+		}
+		if err := os.Remove(path.Join(afs.dir, day+alertHashesFileExt)); err != nil && !os.IsNotExist(err) {
+			log.WithError(err).WithField("day", day).Warn("failed to remove the old alert hashes file")
+		}
+	}
+	for hash, day := range afs.hashes {
+		if day < oldest {
+			delete(afs.hashes, hash)
+		}
+	}
+}
+
+// maxAlertTime is after the time of all alerts.
+var maxAlertTime = time.Unix(1<<62, 0)
+
+// alertFileDays returns the days of the alert files in the dir in order.
+func alertFileDays(dir string) []string {
+	files, _ := ioutil.ReadDir(dir)
+	var days []string
+	for _, file := range files {
+		day := strings.TrimSuffix(file.Name(), alertFileExt)
+		if _, err := time.Parse(alertFileDateFormat, day); err != nil || day == file.Name() {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days
+}
+
+// StoredAlertTime returns the time of the alert or the current time if it doesn't have a valid timestamp.
+func StoredAlertTime(alert *protocol.SignedAlert) time.Time {
+	if ts, err := time.Parse(time.RFC3339Nano, alert.GetAlert().GetTimestamp()); err == nil {
+		return ts
+	}
+	return time.Now()
+}
+
+// ReadStoredAlerts reads the stored alerts of the chains in the time range [from, to) in order of the days
+// and calls the handler for each alert. The alerts of all chains are read if no chain ID is given.
+func ReadStoredAlerts(dir string, chainIDs []uint64, from, to time.Time, handler func(*protocol.SignedAlert) error) error {
+	var chainDirs []string
+	if len(chainIDs) > 0 {
+		for _, chainID := range chainIDs {
+			chainDirs = append(chainDirs, path.Join(dir, strconv.FormatUint(chainID, 10)))
+		}
+	} else {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				chainDirs = append(chainDirs, path.Join(dir, file.Name()))
+			}
+		}
+	}
+
+	firstDay := from.UTC().Format(alertFileDateFormat)
+	lastDay := to.UTC().Format(alertFileDateFormat)
+	for _, chainDir := range chainDirs {
+		for _, day := range alertFileDays(chainDir) {
+			if day < firstDay || day > lastDay {
+				continue
+			}
+			if err := readAlertFile(path.Join(chainDir, day+alertFileExt), chainDirID(chainDir), from, to, handler); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// chainDirID returns the chain ID of the alerts dir of a chain.
+func chainDirID(chainDir string) uint64 {
+	chainID, _ := strconv.ParseUint(path.Base(chainDir), 10, 64)
+	return chainID
+}
+
+// readAlertFile reads the alerts of the chain from the file and upgrades them to the latest schema version.
+func readAlertFile(filePath string, chainID uint64, from, to time.Time, handler func(*protocol.SignedAlert) error) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		var alert protocol.SignedAlert
+		// a line can be incomplete if the node stopped while writing it
+		if err := json.Unmarshal(scanner.Bytes(), &alert); err != nil {
+			log.WithError(err).Warnf("skipping the invalid alert at %s:%d", filePath, lineNum)
+			continue
+		}
+		if alert.Alert != nil {
+			if err := MigrateAlert(alert.Alert, chainID); err != nil {
+				log.WithError(err).Warnf("skipping the alert at %s:%d", filePath, lineNum)
+				continue
+			}
+		}
+		ts := StoredAlertTime(&alert)
+		if ts.Before(from) || !ts.Before(to) {
+			continue
+		}
+		if err := handler(&alert); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func testStoredAlert(id string, ts time.Time) *protocol.SignedAlert {
+	return &protocol.SignedAlert{
+		Alert: &protocol.Alert{
+			Id:        id,
+			Timestamp: ts.Format(time.RFC3339Nano),
+		},
+	}
+}
+
+func TestAlertFileStore(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	afs1 := NewAlertFileStore(dir, 1, 7)
+	afs137 := NewAlertFileStore(dir, 137, 7)
+
+	// an expired file is removed at the first write
+	r.NoError(os.MkdirAll(path.Join(dir, "1"), 0755))
+	expiredFile := path.Join(dir, "1", now.Add(-time.Hour*24*8).Format(alertFileDateFormat)+".ndjson")
+	r.NoError(ioutil.WriteFile(expiredFile, []byte("{}\n"), 0644))
+
+	r.NoError(afs1.Put(testStoredAlert("1", now.Add(-time.Hour*48))))
+	r.NoError(afs1.Put(testStoredAlert("2", now.Add(-time.Hour))))
+	r.NoError(afs137.Put(testStoredAlert("3", now.Add(-time.Minute))))
+	r.NoFileExists(expiredFile)
+
+	// an incomplete line is skipped
+	f, err := os.OpenFile(path.Join(dir, "1", now.Format(alertFileDateFormat)+".ndjson"), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	r.NoError(err)
+	_, err = f.WriteString(`{"alert":{"id":`)
+	r.NoError(err)
+	r.NoError(f.Close())
+
+	read := func(chainIDs []uint64, from, to time.Time) (ids []string) {
+		r.NoError(ReadStoredAlerts(dir, chainIDs, from, to, func(alert *protocol.SignedAlert) error {
+			ids = append(ids, alert.Alert.Id)
+			return nil
+		}))
+		return
+	}
+	r.ElementsMatch([]string{"2", "3"}, read(nil, now.Add(-time.Hour*2), now))
+	r.Equal([]string{"1", "2"}, read([]uint64{1}, now.Add(-time.Hour*72), now))
+	r.Equal([]string{"3"}, read([]uint64{137}, now.Add(-time.Hour*72), now))
+	r.Empty(read(nil, now.Add(-time.Hour*100), now.Add(-time.Hour*72)))
+
+	// the stored alerts are not written again after a restart
+	afs1 = NewAlertFileStore(dir, 1, 7)
+	r.ErrorIs(afs1.Put(testStoredAlert("1", now.Add(-time.Hour*48))), ErrDuplicateAlert)
+	hashed := testStoredAlert("4", now.Add(-time.Hour*24))
+	hashed.Alert.Tags = map[string]string{AlertHashTag: "0xhash"}
+	r.NoError(afs1.Put(hashed))
+	hashed = testStoredAlert("5", now.Add(-time.Hour*24))
+	hashed.Alert.Tags = map[string]string{AlertHashTag: "0xhash"}
+	r.ErrorIs(afs1.Put(hashed), ErrDuplicateAlert)
+	r.ElementsMatch([]string{"1", "2", "4"}, read([]uint64{1}, now.Add(-time.Hour*72), now))
+
+	// the hashes are loaded from the index files and the missing index of a day is created from the alerts
+	hashesFile := path.Join(dir, "1", now.Add(-time.Hour*48).Format(alertFileDateFormat)+alertHashesFileExt)
+	b, err := ioutil.ReadFile(hashesFile)
+	r.NoError(err)
+	r.Equal("1\n", string(b))
+	r.NoError(os.Remove(hashesFile))
+	afs1 = NewAlertFileStore(dir, 1, 7)
+	r.ErrorIs(afs1.Put(testStoredAlert("1", now.Add(-time.Hour*48))), ErrDuplicateAlert)
+	r.FileExists(hashesFile)
+}
+
+func TestReadStoredAlerts_Migrate(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	r.NoError(os.MkdirAll(path.Join(dir, "137"), 0755))
+	alertFile := path.Join(dir, "137", now.Format(alertFileDateFormat)+alertFileExt)
+	ts := now.Add(-time.Minute).Format(time.RFC3339Nano)
+	r.NoError(ioutil.WriteFile(alertFile, []byte(
+		`{"alert":{"id":"1","timestamp":"`+ts+`","agent":{"id":"0xagent"}}}`+"\n"+
+			`{"alert":{"id":"2","timestamp":"`+ts+`","tags":{"schemaVersion":"100"}}}`+"\n",
+	), 0644))
+
+	// the alerts of the older schema versions are upgraded and the unsupported versions are skipped
+	var alerts []*protocol.Alert
+	r.NoError(ReadStoredAlerts(dir, nil, now.Add(-time.Hour), now, func(alert *protocol.SignedAlert) error {
+		alerts = append(alerts, alert.Alert)
+		return nil
+	}))
+	r.Len(alerts, 1)
+	r.Equal("0xagent", alerts[0].Tags["agentId"])
+	r.Equal("137", alerts[0].Tags["chainId"])
+	r.Equal(strconv.Itoa(LatestAlertSchemaVersion), alerts[0].Tags[AlertSchemaVersionTag])
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/forta-network/forta-core-go/protocol"
+)
+
+// DefaultMemoryAlertsSize is the number of the alerts which the memory alert store keeps by default.
+const DefaultMemoryAlertsSize = 10000
+
+// MemoryAlertStore keeps the latest alerts in memory instead of publishing them. It is the publish
+// client of the dev process. The alerts with the same hash as a kept alert are ignored.
+type MemoryAlertStore struct {
+	size   int
+	alerts []*protocol.SignedAlert
+	hashes map[string]bool
+	mu     sync.RWMutex
+}
+
+// NewMemoryAlertStore creates a new memory alert store which keeps the latest alerts up to the size.
+func NewMemoryAlertStore(size int) *MemoryAlertStore {
+	return &MemoryAlertStore{size: size, hashes: make(map[string]bool)}
+}
+
+// Notify implements the clients.PublishClient interface. The notifications without an alert are ignored.
+func (mas *MemoryAlertStore) Notify(ctx context.Context, req *protocol.NotifyRequest) (*protocol.NotifyResponse, error) {
+	if req.SignedAlert == nil {
+		return &protocol.NotifyResponse{}, nil
+	}
+	mas.mu.Lock()
+	defer mas.mu.Unlock()
+
+	hash := StoredAlertHash(req.SignedAlert)
+	if mas.hashes[hash] {
+		return &protocol.NotifyResponse{}, nil
+	}
+	mas.hashes[hash] = true
+	mas.alerts = append(mas.alerts, req.SignedAlert)
+	if len(mas.alerts) > mas.size {
+		for _, dropped := range mas.alerts[:len(mas.alerts)-mas.size] {
+			delete(mas.hashes, StoredAlertHash(dropped))
+		}
+		mas.alerts = mas.alerts[len(mas.alerts)-mas.size:]
+	}
+	return &protocol.NotifyResponse{}, nil
+}
+
+// Alerts returns the latest alerts first. The alerts are filtered by the agent ID if it is not empty.
+// All matching alerts are returned if the limit is negative.
+func (mas *MemoryAlertStore) Alerts(agentID string, limit int) []*protocol.SignedAlert {
+	mas.mu.RLock()
+	defer mas.mu.RUnlock()
+
+	alerts := make([]*protocol.SignedAlert, 0)
+	for i := len(mas.alerts) - 1; i >= 0; i-- {
+		if limit >= 0 && len(alerts) == limit {
+			break
+		}
+		alert := mas.alerts[i]
+		if len(agentID) > 0 && (alert.Alert.GetAgent() == nil || !strings.EqualFold(alert.Alert.GetAgent().Id, agentID)) {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
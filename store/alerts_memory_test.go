@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func testSignedAlert(id, agentID string) *protocol.SignedAlert {
+	return &protocol.SignedAlert{
+		Alert: &protocol.Alert{
+			Id:    id,
+			Agent: &protocol.AgentInfo{Id: agentID},
+		},
+	}
+}
+
+func TestMemoryAlertStore(t *testing.T) {
+	r := require.New(t)
+
+	mas := NewMemoryAlertStore(3)
+	for _, alert := range []*protocol.SignedAlert{
+		testSignedAlert("1", "0xaa"),
+		testSignedAlert("2", "0xbb"),
+		testSignedAlert("3", "0xaa"),
+		testSignedAlert("4", "0xaa"),
+		testSignedAlert("4", "0xaa"), // duplicate
+	} {
+		_, err := mas.Notify(context.Background(), &protocol.NotifyRequest{SignedAlert: alert})
+		r.NoError(err)
+	}
+	// the notifications without an alert are not kept
+	_, err := mas.Notify(context.Background(), &protocol.NotifyRequest{})
+	r.NoError(err)
+
+	alertIDs := func(alerts []*protocol.SignedAlert) (ids []string) {
+		for _, alert := range alerts {
+			ids = append(ids, alert.Alert.Id)
+		}
+		return
+	}
+	r.Equal([]string{"4", "3", "2"}, alertIDs(mas.Alerts("", -1)))
+	r.Equal([]string{"4", "3"}, alertIDs(mas.Alerts("0xAA", -1)))
+	r.Equal([]string{"4"}, alertIDs(mas.Alerts("", 1)))
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/config"
+	"gopkg.in/yaml.v3"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DevAgentsFile is the file which developers use for injecting agents into the running agent set
+// without a registry transaction. The image can be a local image or a pre-pulled image reference.
+// An agent with a command runs as a process on the host instead, so that a debugger can be attached
+// to it, and it serves the gRPC API on the port.
+//
+//	agents:
+//	  - id: my-agent
+//	    image: my-agent:latest
+//	    chainIds: [1]
+//	  - id: my-debugged-agent
+//	    command: [npm, start]
+//	    port: "50052"
+type DevAgentsFile struct {
+	Agents []*config.AgentConfig `yaml:"agents"`
+}
+
+// ReadDevAgents reads the agents from the file. It returns no agents if the file doesn't exist.
+func ReadDevAgents(filePath string) ([]*config.AgentConfig, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseDevAgents(b)
+}
+
+func parseDevAgents(b []byte) ([]*config.AgentConfig, error) {
+	var file DevAgentsFile
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse: %v", err)
+	}
+	for i, agent := range file.Agents {
+		if agent == nil || len(agent.ID) == 0 {
+			return nil, fmt.Errorf("agents[%d]: id is required", i)
+		}
+		if agent.IsProcess() {
+			if _, err := strconv.ParseUint(agent.Port, 10, 16); err != nil {
+				return nil, fmt.Errorf("agents[%d]: port is required for the command", i)
+			}
+		} else if len(agent.Image) == 0 {
+			return nil, fmt.Errorf("agents[%d]: image is required", i)
+		}
+		agent.IsLocal = true
+	}
+	return file.Agents, nil
+}
+
+// mergeDevAgents adds the dev agents to the registry agents. A dev agent replaces the registry agent
+// which has the same ID.
+func mergeDevAgents(agents, devAgents []*config.AgentConfig) []*config.AgentConfig {
+	merged := make([]*config.AgentConfig, 0, len(agents)+len(devAgents))
+	replaced := make(map[string]bool)
+	for _, devAgent := range devAgents {
+		replaced[devAgent.ID] = true
+	}
+	for _, agent := range agents {
+		if !replaced[agent.ID] {
+			merged = append(merged, agent)
+		}
+	}
+	return append(merged, devAgents...)
+}
+
+// devAgentsStore merges the agents from the dev agents file with the agents of the registry store.
+type devAgentsStore struct {
+	RegistryStore
+	filePath string
+
+	registryAgents []*config.AgentConfig
+	lastFile       []byte
+	mu             sync.Mutex
+}
+
+// NewDevAgentsStore wraps the registry store and merges the agents from the dev agents file.
+func NewDevAgentsStore(registryStore RegistryStore, filePath string) *devAgentsStore {
+	return &devAgentsStore{
+		RegistryStore: registryStore,
+		filePath:      filePath,
+	}
+}
+
+// readIfChanged reads the dev agents file and tells if it has changed since the last read.
+func (ds *devAgentsStore) readIfChanged() bool {
+	b, err := ioutil.ReadFile(ds.filePath)
+	if err != nil && !os.IsNotExist(err) {
+		log.WithError(err).WithField("path", ds.filePath).Warn("failed to read the dev agents file")
+		return false
+	}
+	if bytes.Equal(b, ds.lastFile) {
+		return false
+	}
+	ds.lastFile = b
+	if _, err := parseDevAgents(b); err != nil {
+		log.WithError(err).WithField("path", ds.filePath).Warn("invalid dev agents file - ignoring")
+	}
+	return true
+}
+
+// devAgents returns the agents from the last read of the file.
+func (ds *devAgentsStore) devAgents() []*config.AgentConfig {
+	agents, err := parseDevAgents(ds.lastFile)
+	if err != nil {
+		return nil
+	}
+	return agents
+}
+
+func (ds *devAgentsStore) GetAgentsIfChanged(scanner string) ([]*config.AgentConfig, bool, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	agents, changed, err := ds.RegistryStore.GetAgentsIfChanged(scanner)
+	if err != nil {
+		return nil, false, err
+	}
+	if changed {
+		ds.registryAgents = agents
+	}
+	devChanged := ds.readIfChanged()
+	if !changed && !devChanged {
+		return nil, false, nil
+	}
+	return mergeDevAgents(ds.registryAgents, ds.devAgents()), true, nil
+}
+
+func (ds *devAgentsStore) GetAgents(scanner string) ([]*config.AgentConfig, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	agents, err := ds.RegistryStore.GetAgents(scanner)
+	if err != nil {
+		return nil, err
+	}
+	ds.registryAgents = agents
+	ds.readIfChanged()
+	return mergeDevAgents(agents, ds.devAgents()), nil
+}
+
+// Health implements the health.Reporter interface.
+func (ds *devAgentsStore) Health() health.Reports {
+	if reporter, ok := ds.RegistryStore.(interface{ Health() health.Reports }); ok {
+		return reporter.Health()
+	}
+	return nil
+}
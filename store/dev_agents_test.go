@@ -0,0 +1,76 @@
+package store
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	mock_store "github.com/forta-network/forta-node/store/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevAgentsStore(t *testing.T) {
+	r := require.New(t)
+
+	registryStore := mock_store.NewMockRegistryStore(gomock.NewController(t))
+	filePath := path.Join(t.TempDir(), config.DefaultDevAgentsFileName)
+	ds := NewDevAgentsStore(registryStore, filePath)
+
+	registryAgents := []*config.AgentConfig{{ID: "agent-1"}, {ID: "agent-2"}}
+
+	// no file: only the registry agents
+	registryStore.EXPECT().GetAgentsIfChanged(testScanner).Return(registryAgents, true, nil)
+	agents, changed, err := ds.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.True(changed)
+	r.Equal(registryAgents, agents)
+
+	// the dev agents are added and replace the registry agents with the same ID
+	r.NoError(ioutil.WriteFile(filePath, []byte(`
+agents:
+  - id: agent-2
+    image: agent-2:dev
+  - id: agent-3
+    image: agent-3:latest
+    chainIds: [1]
+`), 0644))
+	registryStore.EXPECT().GetAgentsIfChanged(testScanner).Return(nil, false, nil)
+	agents, changed, err = ds.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.True(changed)
+	r.Len(agents, 3)
+	r.Equal("agent-1", agents[0].ID)
+	r.Equal(config.AgentConfig{ID: "agent-2", Image: "agent-2:dev", IsLocal: true}, *agents[1])
+	r.Equal([]int64{1}, agents[2].ChainIDs)
+
+	// nothing changed
+	registryStore.EXPECT().GetAgentsIfChanged(testScanner).Return(nil, false, nil)
+	_, changed, err = ds.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.False(changed)
+}
+
+func TestParseDevAgents(t *testing.T) {
+	r := require.New(t)
+
+	_, err := parseDevAgents([]byte("agents:\n  - image: agent:latest\n"))
+	r.Error(err)
+	_, err = parseDevAgents([]byte("agents:\n  - id: agent-1\n"))
+	r.Error(err)
+
+	_, err = parseDevAgents([]byte("agents:\n  - id: agent-1\n    command: [npm, start]\n"))
+	r.Error(err)
+
+	agents, err := parseDevAgents(nil)
+	r.NoError(err)
+	r.Empty(agents)
+
+	agents, err = parseDevAgents([]byte("agents:\n  - id: agent-1\n    command: [npm, start]\n    port: \"50052\"\n"))
+	r.NoError(err)
+	r.Len(agents, 1)
+	r.True(agents[0].IsProcess())
+	r.Equal("50052", agents[0].GrpcPort())
+	r.Equal("host.docker.internal", agents[0].GrpcHost())
+}
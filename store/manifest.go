@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/ipfs"
+	"github.com/forta-network/forta-core-go/manifest"
+	"github.com/forta-network/forta-node/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AgentManifest contains the signed agent manifest and the optional declarations
+// which are not part of the core manifest type.
+type AgentManifest struct {
+	*manifest.SignedAgentManifest
+	Declarations AgentDeclarations
+}
+
+// AgentDeclarations are the optional manifest fields that let an agent opt in to
+// the features of the node.
+type AgentDeclarations struct {
+	SchemaVersion       int                    `json:"schemaVersion"`
+	PendingTransactions bool                   `json:"pendingTransactions"`
+	LogFilters          []config.LogFilter     `json:"logFilters"`
+	UserOperations      bool                   `json:"userOperations"`
+	Resources           *config.AgentResources `json:"resources"`
+	CosignPublicKey     string                 `json:"cosignPublicKey"`
+	EgressHosts         []string               `json:"egressHosts"`
+}
+
+// ManifestClient gets the agent manifests.
+type ManifestClient interface {
+	GetAgentManifest(ctx context.Context, reference string) (*AgentManifest, error)
+}
+
+// The backoff of a failing gateway doubles on every failure up to the max.
+const (
+	minGatewayBackoff = 5 * time.Second
+	maxGatewayBackoff = 5 * time.Minute
+)
+
+type ipfsGateway struct {
+	url      string
+	ic       ipfs.Client
+	failures int
+	retryAt  time.Time
+}
+
+func (gw *ipfsGateway) failed() {
+	backoff := minGatewayBackoff << gw.failures
+	if backoff > maxGatewayBackoff || backoff <= 0 {
+		backoff = maxGatewayBackoff
+	}
+	gw.failures++
+	gw.retryAt = time.Now().Add(backoff)
+}
+
+func (gw *ipfsGateway) succeeded() {
+	gw.failures = 0
+	gw.retryAt = time.Time{}
+}
+
+type manifestClient struct {
+	gateways []*ipfsGateway
+	timeout  time.Duration
+	cache    *ManifestCache
+	pinner   *ManifestPinner
+	mu       sync.Mutex
+
+	fetchLatency *latencyHistogram
+}
+
+// NewManifestClient creates a new manifest client which reads from the local IPFS node and the gateways.
+// The cache is optional.
+func NewManifestClient(cfg config.IPFSConfig, cache *ManifestCache) (*manifestClient, error) {
+	var urls []string
+	for _, url := range append([]string{cfg.LocalNodeURL, cfg.GatewayURL}, cfg.FallbackGatewayURLs...) {
+		if len(url) > 0 && !containsFold(urls, url) {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("no ipfs gateways")
+	}
+	mc := &manifestClient{
+		timeout:      time.Duration(cfg.GatewayTimeoutSeconds) * time.Second,
+		cache:        cache,
+		fetchLatency: newLatencyHistogram(),
+	}
+	if mc.timeout <= 0 {
+		mc.timeout = 10 * time.Second
+	}
+	for _, url := range urls {
+		ic, err := ipfs.NewClient(url)
+		if err != nil {
+			return nil, err
+		}
+		mc.gateways = append(mc.gateways, &ipfsGateway{url: url, ic: ic})
+	}
+	return mc, nil
+}
+
+// availableGateways returns the gateways which are not backing off in order. If all gateways
+// are backing off, all of them are tried so that the manifests are not blocked.
+func (mc *manifestClient) availableGateways() []*ipfsGateway {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	var available []*ipfsGateway
+	now := time.Now()
+	for _, gw := range mc.gateways {
+		if now.After(gw.retryAt) {
+			available = append(available, gw)
+		}
+	}
+	if len(available) == 0 {
+		return mc.gateways
+	}
+	return available
+}
+
+// GetAgentManifest gets the agent manifest from IPFS and parses the declarations from
+// the same document.
+func (mc *manifestClient) GetAgentManifest(ctx context.Context, reference string) (*AgentManifest, error) {
+	if mc.cache != nil {
+		if b, ok := mc.cache.Get(reference); ok {
+			mc.pin(reference)
+			return parseAgentManifest(b)
+		}
+	}
+	var lastErr error
+	for _, gw := range mc.availableGateways() {
+		start := time.Now()
+		b, err := mc.getBytes(ctx, gw, reference)
+		if err == nil {
+			mc.fetchLatency.Observe(time.Since(start))
+		}
+		mc.mu.Lock()
+		if err != nil {
+			gw.failed()
+		} else {
+			gw.succeeded()
+		}
+		mc.mu.Unlock()
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{
+				"gateway":   gw.url,
+				"reference": reference,
+			}).Warn("failed to get the agent manifest - trying the next gateway")
+			lastErr = err
+			continue
+		}
+		m, err := parseAgentManifest(b)
+		if err != nil {
+			return nil, err
+		}
+		if mc.cache != nil {
+			mc.cache.Put(reference, b)
+		}
+		mc.pin(reference)
+		return m, nil
+	}
+	return nil, lastErr
+}
+
+func (mc *manifestClient) pin(reference string) {
+	if mc.pinner != nil {
+		mc.pinner.Pin(reference)
+	}
+}
+
+func (mc *manifestClient) getBytes(ctx context.Context, gw *ipfsGateway, reference string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, mc.timeout)
+	defer cancel()
+	return gw.ic.GetBytes(ctx, reference)
+}
+
+func parseAgentManifest(b []byte) (*AgentManifest, error) {
+	var signedManifest manifest.SignedAgentManifest
+	if err := json.Unmarshal(b, &signedManifest); err != nil {
+		return nil, err
+	}
+	var declarationsDoc struct {
+		Manifest AgentDeclarations `json:"manifest"`
+	}
+	if err := json.Unmarshal(b, &declarationsDoc); err != nil {
+		return nil, err
+	}
+	return &AgentManifest{
+		SignedAgentManifest: &signedManifest,
+		Declarations:        declarationsDoc.Manifest,
+	}, nil
+}
@@ -0,0 +1,212 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/ipfs/go-cid"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultManifestCacheMaxSize is the max total size of the cached documents in bytes.
+const DefaultManifestCacheMaxSize = 64 * 1024 * 1024
+
+// unixfsChunkSize is the default chunk size of the IPFS files. The larger files are split to many blocks
+// so their content cannot be verified against the CID without the blocks.
+const unixfsChunkSize = 256 * 1024
+
+// ManifestCache keeps the manifest documents on disk by their IPFS content IDs, so that the manifests are
+// not downloaded again after a restart. The content of a CID doesn't change, so the documents don't expire
+// but the least recently used documents are removed when the cache is larger than the max size.
+type ManifestCache struct {
+	dir     string
+	maxSize int64
+	hits    uint64
+	misses  uint64
+	mu      sync.Mutex // protects the eviction
+}
+
+// filePath returns the cache file of the reference. Only the valid CIDs are cached.
+func (mc *ManifestCache) filePath(reference string) (string, bool) {
+	c, err := cid.Decode(reference)
+	if err != nil {
+		return "", false
+	}
+	return path.Join(mc.dir, c.String()+".json"), true
+}
+
+// Get returns the cached document of the reference.
+func (mc *ManifestCache) Get(reference string) ([]byte, bool) {
+	filePath, ok := mc.filePath(reference)
+	if !ok {
+		return nil, false
+	}
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		atomic.AddUint64(&mc.misses, 1)
+		return nil, false
+	}
+	// the modification time is the last use time for the eviction
+	now := time.Now()
+	os.Chtimes(filePath, now, now)
+	atomic.AddUint64(&mc.hits, 1)
+	return b, true
+}
+
+// Put writes the document of the reference to the cache if the document matches the reference.
+func (mc *ManifestCache) Put(reference string, b []byte) {
+	filePath, ok := mc.filePath(reference)
+	if !ok {
+		return
+	}
+	if err := verifyContentID(reference, b); err != nil {
+		log.WithError(err).WithField("reference", reference).Warn("not caching the manifest")
+		return
+	}
+	if err := os.MkdirAll(mc.dir, 0755); err != nil {
+		log.WithError(err).Warn("failed to create the manifest cache dir")
+		return
+	}
+	// write to a temp file first so that a partial document is never read
+	tmpFile, err := ioutil.TempFile(mc.dir, "manifest-*.tmp")
+	if err != nil {
+		log.WithError(err).Warn("failed to write to the manifest cache")
+		return
+	}
+	_, err = tmpFile.Write(b)
+	tmpFile.Close()
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), filePath)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		log.WithError(err).Warn("failed to write to the manifest cache")
+		return
+	}
+	mc.evict()
+}
+
+// evict removes the least recently used documents until the cache is not larger than the max size.
+func (mc *ManifestCache) evict() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	files, err := ioutil.ReadDir(mc.dir)
+	if err != nil {
+		return
+	}
+	var total int64
+	var cached []os.FileInfo
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".json") {
+			total += file.Size()
+			cached = append(cached, file)
+		}
+	}
+	sort.Slice(cached, func(i, j int) bool {
+		return cached[i].ModTime().Before(cached[j].ModTime())
+	})
+	for _, file := range cached {
+		if total <= mc.maxSize {
+			return
+		}
+		if err := os.Remove(path.Join(mc.dir, file.Name())); err != nil {
+			log.WithError(err).Warn("failed to remove from the manifest cache")
+			continue
+		}
+		total -= file.Size()
+	}
+}
+
+// verifyContentID checks if the content is the content of the CID. The content must be a raw block or a
+// single block UnixFS file, which is how IPFS adds the small files.
+func verifyContentID(reference string, content []byte) error {
+	c, err := cid.Decode(reference)
+	if err != nil {
+		return err
+	}
+	prefix := c.Prefix()
+	var block []byte
+	switch prefix.Codec {
+	case cid.Raw:
+		block = content
+	case cid.DagProtobuf:
+		if len(content) > unixfsChunkSize {
+			return fmt.Errorf("cannot verify the content larger than %d bytes", unixfsChunkSize)
+		}
+		block = unixfsFileBlock(content)
+	default:
+		return fmt.Errorf("unsupported codec %d", prefix.Codec)
+	}
+	sum, err := prefix.Sum(block)
+	if err != nil {
+		return err
+	}
+	if !sum.Equals(c) {
+		return fmt.Errorf("content does not match the cid: %s", sum)
+	}
+	return nil
+}
+
+// unixfsFileBlock encodes the content as the dag-pb node of a single block UnixFS file.
+func unixfsFileBlock(content []byte) []byte {
+	// UnixFS Data: Type = File, Data = content, filesize = len(content)
+	unixfsData := []byte{0x08, 0x02, 0x12}
+	unixfsData = appendUvarint(unixfsData, uint64(len(content)))
+	unixfsData = append(unixfsData, content...)
+	unixfsData = append(unixfsData, 0x18)
+	unixfsData = appendUvarint(unixfsData, uint64(len(content)))
+	// PBNode: Data = UnixFS Data
+	node := []byte{0x0a}
+	node = appendUvarint(node, uint64(len(unixfsData)))
+	return append(node, unixfsData...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+// Name returns the name of the cache.
+func (mc *ManifestCache) Name() string {
+	return "manifest-cache"
+}
+
+// Health implements the health.Reporter interface.
+func (mc *ManifestCache) Health() health.Reports {
+	return health.Reports{
+		&health.Report{Name: "manifest-cache.hits", Status: health.StatusInfo, Details: strconv.FormatUint(atomic.LoadUint64(&mc.hits), 10)},
+		&health.Report{Name: "manifest-cache.misses", Status: health.StatusInfo, Details: strconv.FormatUint(atomic.LoadUint64(&mc.misses), 10)},
+		&health.Report{Name: "manifest-cache.size", Status: health.StatusInfo, Details: strconv.FormatInt(mc.size(), 10)},
+	}
+}
+
+// size returns the total size of the cached documents in bytes.
+func (mc *ManifestCache) size() int64 {
+	files, err := ioutil.ReadDir(mc.dir)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, file := range files {
+		total += file.Size()
+	}
+	return total
+}
+
+// NewManifestCache creates a new manifest cache in the dir.
+func NewManifestCache(dir string) *ManifestCache {
+	return &ManifestCache{dir: dir, maxSize: DefaultManifestCacheMaxSize}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+)
+
+// fetchLatencyBuckets are the upper bounds of the manifest fetch latency histogram buckets.
+var fetchLatencyBuckets = []time.Duration{
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// latencyHistogram counts the durations in the buckets. The last count is for the durations which
+// exceed all buckets.
+type latencyHistogram struct {
+	counts []uint64
+	total  time.Duration
+	n      uint64
+	mu     sync.Mutex
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(fetchLatencyBuckets)+1)}
+}
+
+func (h *latencyHistogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i := 0
+	for i < len(fetchLatencyBuckets) && d > fetchLatencyBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.total += d
+	h.n++
+}
+
+// Reports returns the histogram as numeric reports which are exported as metrics: the cumulative count of
+// each bucket like "<name>.bucket.le-100ms", the total count and the sum of the durations in seconds.
+func (h *latencyHistogram) Reports(name string) health.Reports {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var (
+		reports    health.Reports
+		cumulative uint64
+	)
+	for i, count := range h.counts {
+		cumulative += count
+		bound := "inf"
+		if i < len(fetchLatencyBuckets) {
+			bound = fetchLatencyBuckets[i].String()
+		}
+		reports = append(reports, &health.Report{
+			Name:    fmt.Sprintf("%s.bucket.le-%s", name, bound),
+			Status:  health.StatusInfo,
+			Details: strconv.FormatUint(cumulative, 10),
+		})
+	}
+	return append(reports,
+		&health.Report{Name: name + ".count", Status: health.StatusInfo, Details: strconv.FormatUint(h.n, 10)},
+		&health.Report{Name: name + ".sum.seconds", Status: health.StatusInfo, Details: strconv.FormatFloat(h.total.Seconds(), 'f', -1, 64)},
+	)
+}
+
+// Health implements the health.Reporter interface.
+func (mc *manifestClient) Health() health.Reports {
+	return mc.fetchLatency.Reports("manifest-fetch.latency")
+}
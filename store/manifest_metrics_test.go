@@ -0,0 +1,37 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	r := require.New(t)
+
+	h := newLatencyHistogram()
+	h.Observe(50 * time.Millisecond)
+	h.Observe(100 * time.Millisecond)
+	h.Observe(time.Second)
+	h.Observe(time.Minute)
+
+	r.Equal([]uint64{2, 0, 0, 1, 0, 0, 0, 1}, h.counts)
+
+	details := make(map[string]string)
+	for _, report := range h.Reports("latency") {
+		details[report.Name] = report.Details
+	}
+	r.Equal(map[string]string{
+		"latency.bucket.le-100ms": "2",
+		"latency.bucket.le-250ms": "2",
+		"latency.bucket.le-500ms": "2",
+		"latency.bucket.le-1s":    "3",
+		"latency.bucket.le-2.5s":  "3",
+		"latency.bucket.le-5s":    "3",
+		"latency.bucket.le-10s":   "3",
+		"latency.bucket.le-inf":   "4",
+		"latency.count":           "4",
+		"latency.sum.seconds":     "61.15",
+	}, details)
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	ipfsapi "github.com/ipfs/go-ipfs-api"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const pinTimeout = time.Minute
+
+// ManifestPinner pins the agent manifests on the local IPFS node, so that the node keeps a copy of
+// the manifests and serves them to the others. The pinned manifests are listed in a file.
+type ManifestPinner struct {
+	sh       *ipfsapi.Shell
+	listPath string
+
+	pinned  map[string]bool
+	lastErr health.ErrorTracker
+	mu      sync.Mutex
+}
+
+// NewManifestPinner creates a new manifest pinner which uses the API of the local IPFS node.
+func NewManifestPinner(apiURL, listPath string) *ManifestPinner {
+	sh := ipfsapi.NewShell(apiURL)
+	sh.SetTimeout(pinTimeout)
+	return &ManifestPinner{
+		sh:       sh,
+		listPath: listPath,
+		pinned:   make(map[string]bool),
+	}
+}
+
+// Pin pins the manifest in the background if it is not pinned yet.
+func (mp *ManifestPinner) Pin(reference string) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if mp.pinned[reference] {
+		return
+	}
+	mp.pinned[reference] = true
+	go mp.pin(reference)
+}
+
+func (mp *ManifestPinner) pin(reference string) {
+	err := mp.sh.Pin(reference)
+	mp.lastErr.Set(err)
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if err != nil {
+		log.WithError(err).WithField("reference", reference).Warn("failed to pin the agent manifest")
+		delete(mp.pinned, reference) // retry next time
+		return
+	}
+	mp.writeListUnsafe()
+}
+
+func (mp *ManifestPinner) writeListUnsafe() {
+	if len(mp.listPath) == 0 {
+		return
+	}
+	list := make([]string, 0, len(mp.pinned))
+	for reference := range mp.pinned {
+		list = append(list, reference)
+	}
+	sort.Strings(list)
+	b, _ := json.MarshalIndent(list, "", "  ")
+	if err := ioutil.WriteFile(mp.listPath, b, 0644); err != nil {
+		log.WithError(err).Warn("failed to write the pinned manifests list")
+	}
+}
+
+// Name returns the name of the pinner.
+func (mp *ManifestPinner) Name() string {
+	return "manifest-pinner"
+}
+
+// Health implements the health.Reporter interface.
+func (mp *ManifestPinner) Health() health.Reports {
+	mp.mu.Lock()
+	count := len(mp.pinned)
+	mp.mu.Unlock()
+	return health.Reports{
+		&health.Report{Name: "manifest-pins.count", Status: health.StatusInfo, Details: strconv.Itoa(count)},
+		mp.lastErr.GetReport("manifest-pins.error"),
+	}
+}
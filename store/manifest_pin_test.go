@@ -0,0 +1,41 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestPinner(t *testing.T) {
+	r := require.New(t)
+
+	pinRequests := make(chan string, 2)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		pinRequests <- req.URL.Path + "?" + req.URL.Query().Get("arg")
+		w.Write([]byte(`{"Pins":["` + testAgentRef + `"]}`))
+	}))
+	defer api.Close()
+
+	listPath := path.Join(t.TempDir(), pinnedManifestsFileName)
+	mp := NewManifestPinner(api.URL, listPath)
+	mp.Pin(testAgentRef)
+	mp.Pin(testAgentRef) // pinned once
+
+	r.Equal("/api/v0/pin/add?"+testAgentRef, <-pinRequests)
+	r.Eventually(func() bool {
+		b, err := ioutil.ReadFile(listPath)
+		if err != nil {
+			return false
+		}
+		var list []string
+		return json.Unmarshal(b, &list) == nil && len(list) == 1 && list[0] == testAgentRef
+	}, time.Second, 10*time.Millisecond)
+	r.Empty(pinRequests)
+	r.Equal("1", mp.Health()[0].Details)
+}
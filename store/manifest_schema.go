@@ -0,0 +1,129 @@
+package store
+
+import (
+	"encoding/pem"
+	"fmt"
+	"regexp"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+)
+
+// LatestManifestSchemaVersion is the latest version of the agent manifest schema. The manifests
+// which don't declare a schema version are validated with the first version.
+const LatestManifestSchemaVersion = 1
+
+var topicRegexp = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
+// ManifestValidationError tells why an agent manifest was rejected.
+type ManifestValidationError struct {
+	SchemaVersion int
+	Field         string
+	Reason        string
+}
+
+func (e *ManifestValidationError) Error() string {
+	return fmt.Sprintf("invalid manifest (schema v%d): %s: %s", e.SchemaVersion, e.Field, e.Reason)
+}
+
+type manifestSchema func(m *AgentManifest, containerRegistry string) *ManifestValidationError
+
+var manifestSchemas = map[int]manifestSchema{
+	1: validateManifestV1,
+}
+
+// ValidateAgentManifest validates the agent manifest with the schema version it declares.
+func ValidateAgentManifest(m *AgentManifest, containerRegistry string) error {
+	version := m.Declarations.SchemaVersion
+	if version == 0 {
+		version = 1
+	}
+	schema, ok := manifestSchemas[version]
+	if !ok {
+		return &ManifestValidationError{
+			SchemaVersion: version,
+			Field:         "manifest.schemaVersion",
+			Reason:        fmt.Sprintf("unsupported version, the latest supported version is %d", LatestManifestSchemaVersion),
+		}
+	}
+	if err := schema(m, containerRegistry); err != nil {
+		err.SchemaVersion = version
+		return err
+	}
+	return nil
+}
+
+func validateManifestV1(m *AgentManifest, containerRegistry string) *ManifestValidationError {
+	if m.Manifest == nil {
+		return &ManifestValidationError{Field: "manifest", Reason: "required"}
+	}
+	if m.Manifest.ImageReference == nil || len(*m.Manifest.ImageReference) == 0 {
+		return &ManifestValidationError{Field: "manifest.imageReference", Reason: "required"}
+	}
+	if _, err := utils.ValidateDiscoImageRef(containerRegistry, *m.Manifest.ImageReference); err != nil {
+		return &ManifestValidationError{
+			Field:  "manifest.imageReference",
+			Reason: fmt.Sprintf("must be an image reference with a digest (<cid>@sha256:<digest>): %v", err),
+		}
+	}
+	seen := make(map[int64]bool)
+	for i, chainID := range m.Manifest.ChainIDs {
+		field := fmt.Sprintf("manifest.chainIds[%d]", i)
+		if chainID <= 0 {
+			return &ManifestValidationError{Field: field, Reason: "must be a positive chain ID"}
+		}
+		if seen[chainID] {
+			return &ManifestValidationError{Field: field, Reason: fmt.Sprintf("duplicate chain ID %d", chainID)}
+		}
+		seen[chainID] = true
+	}
+	if res := m.Declarations.Resources; res != nil {
+		if res.CPUShares < 0 || (res.CPUShares > 0 && res.CPUShares < 2) {
+			return &ManifestValidationError{Field: "manifest.resources.cpuShares", Reason: "must be at least 2"}
+		}
+		if res.MaxCPUs < 0 {
+			return &ManifestValidationError{Field: "manifest.resources.maxCpus", Reason: "must be positive"}
+		}
+		if res.MaxMemoryMiB < 0 || (res.MaxMemoryMiB > 0 && res.MaxMemoryMiB < 100) {
+			return &ManifestValidationError{Field: "manifest.resources.maxMemoryMib", Reason: "must be at least 100"}
+		}
+		if res.GPUs < 0 {
+			return &ManifestValidationError{Field: "manifest.resources.gpus", Reason: "must be positive"}
+		}
+	}
+	if key := m.Declarations.CosignPublicKey; len(key) > 0 {
+		if block, _ := pem.Decode([]byte(key)); block == nil || block.Type != "PUBLIC KEY" {
+			return &ManifestValidationError{Field: "manifest.cosignPublicKey", Reason: "must be a PEM encoded public key"}
+		}
+	}
+	for i, host := range m.Declarations.EgressHosts {
+		if !config.ValidateEgressHost(host) {
+			return &ManifestValidationError{
+				Field:  fmt.Sprintf("manifest.egressHosts[%d]", i),
+				Reason: "must be a host name with an optional port and wildcard prefix",
+			}
+		}
+	}
+	for i, logFilter := range m.Declarations.LogFilters {
+		for j, address := range logFilter.Addresses {
+			if !common.IsHexAddress(address) {
+				return &ManifestValidationError{
+					Field:  fmt.Sprintf("manifest.logFilters[%d].addresses[%d]", i, j),
+					Reason: "must be a hex address",
+				}
+			}
+		}
+		for j, position := range logFilter.Topics {
+			for k, topic := range position {
+				if !topicRegexp.MatchString(topic) {
+					return &ManifestValidationError{
+						Field:  fmt.Sprintf("manifest.logFilters[%d].topics[%d][%d]", i, j, k),
+						Reason: "must be a 32-byte hex topic",
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
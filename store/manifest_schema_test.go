@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAgentManifest(t *testing.T) {
+	topic := "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	address := "0x1000000000000000000000000000000000000000"
+
+	for _, testCase := range []struct {
+		name          string
+		manifest      string
+		field         string
+		schemaVersion int
+	}{
+		{
+			name:     "valid",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","chainIds":[1,137],"logFilters":[{"addresses":["` + address + `"],"topics":[["` + topic + `"]]}]}}`,
+		},
+		{
+			name:     "missing manifest",
+			manifest: `{}`,
+			field:    "manifest",
+		},
+		{
+			name:     "missing image",
+			manifest: `{"manifest":{}}`,
+			field:    "manifest.imageReference",
+		},
+		{
+			name:     "image without digest",
+			manifest: `{"manifest":{"imageReference":"bafybeide7cspdmxqjcpa3qvrayvfpiix2it4v6mjejjc22q72zbq7rm4re"}}`,
+			field:    "manifest.imageReference",
+		},
+		{
+			name:     "bad chain ID",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","chainIds":[1,0]}}`,
+			field:    "manifest.chainIds[1]",
+		},
+		{
+			name:     "duplicate chain ID",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","chainIds":[1,1]}}`,
+			field:    "manifest.chainIds[1]",
+		},
+		{
+			name:     "bad log filter address",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","logFilters":[{"addresses":["0x1"]}]}}`,
+			field:    "manifest.logFilters[0].addresses[0]",
+		},
+		{
+			name:     "bad log filter topic",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","logFilters":[{"topics":[[],["0x2"]]}]}}`,
+			field:    "manifest.logFilters[0].topics[1][0]",
+		},
+		{
+			name:     "valid resources",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","resources":{"cpuShares":512,"maxCpus":0.5,"maxMemoryMib":200,"gpus":1}}}`,
+		},
+		{
+			name:     "bad memory limit",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","resources":{"maxMemoryMib":10}}}`,
+			field:    "manifest.resources.maxMemoryMib",
+		},
+		{
+			name:     "bad gpu count",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","resources":{"gpus":-1}}}`,
+			field:    "manifest.resources.gpus",
+		},
+		{
+			name:     "bad cosign key",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","cosignPublicKey":"0x1"}}`,
+			field:    "manifest.cosignPublicKey",
+		},
+		{
+			name:     "valid egress hosts",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","egressHosts":["api.example.com","*.example.org:443"]}}`,
+		},
+		{
+			name:     "bad egress host",
+			manifest: `{"manifest":{"imageReference":"` + testImageRef + `","egressHosts":["api.example.com","https://example.org"]}}`,
+			field:    "manifest.egressHosts[1]",
+		},
+		{
+			name:          "unsupported schema version",
+			manifest:      `{"manifest":{"imageReference":"` + testImageRef + `","schemaVersion":99}}`,
+			field:         "manifest.schemaVersion",
+			schemaVersion: 99,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := require.New(t)
+
+			m, err := parseAgentManifest([]byte(testCase.manifest))
+			r.NoError(err)
+			err = ValidateAgentManifest(m, testContainerRegistry)
+			if len(testCase.field) == 0 {
+				r.NoError(err)
+				return
+			}
+			r.Error(err)
+			validationErr, ok := err.(*ManifestValidationError)
+			r.True(ok)
+			r.Equal(testCase.field, validationErr.Field)
+			if testCase.schemaVersion > 0 {
+				r.Equal(testCase.schemaVersion, validationErr.SchemaVersion)
+			} else {
+				r.Equal(1, validationErr.SchemaVersion)
+			}
+		})
+	}
+}
+
+func TestMakeAgentConfig_Rejected(t *testing.T) {
+	r := require.New(t)
+
+	var rejected []string
+	rs := &registryStore{
+		ctx: context.Background(),
+		mc: testManifestClient{
+			testAgentRef: []byte(`{"manifest":{"imageReference":"` + testImageRef + `","chainIds":[0]}}`),
+		},
+		cfg: config.Config{Registry: config.RegistryConfig{ContainerRegistry: testContainerRegistry}},
+		onRejected: func(agentID, ref string, err *ManifestValidationError) {
+			rejected = append(rejected, agentID, ref, err.Field)
+		},
+	}
+	_, err := rs.makeAgentConfig(testAgentID, testAgentRef)
+	r.Error(err)
+	r.Equal([]string{testAgentID, testAgentRef, "manifest.chainIds[0]"}, rejected)
+}
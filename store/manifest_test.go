@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAgentID           = "0x2000000000000000000000000000000000000000000000000000000000000000"
+	testAgentRef          = "QmWacxPov5FVCyvnpXroDJ76urakzN4ckpFhhRzpsAkRek"
+	testImageRef          = "bafybeide7cspdmxqjcpa3qvrayvfpiix2it4v6mjejjc22q72zbq7rm4re@sha256:cdd4ddccf5e9c740eb4144bcc68e3ea3a056789ec7453e94a6416dcfc80937a4"
+	testContainerRegistry = "some.reg.io"
+)
+
+type testManifestClient map[string][]byte
+
+func (mc testManifestClient) GetAgentManifest(ctx context.Context, reference string) (*AgentManifest, error) {
+	return parseAgentManifest(mc[reference])
+}
+
+func TestParseAgentManifest(t *testing.T) {
+	r := require.New(t)
+
+	m, err := parseAgentManifest([]byte(`{"manifest":{"imageReference":"` + testImageRef + `","pendingTransactions":true},"signature":"0x1"}`))
+	r.NoError(err)
+	r.Equal(testImageRef, *m.Manifest.ImageReference)
+	r.Equal("0x1", m.Signature)
+	r.True(m.Declarations.PendingTransactions)
+
+	m, err = parseAgentManifest([]byte(`{"manifest":{"imageReference":"` + testImageRef + `"}}`))
+	r.NoError(err)
+	r.False(m.Declarations.PendingTransactions)
+	r.Empty(m.Declarations.LogFilters)
+	r.False(m.Declarations.UserOperations)
+
+	m, err = parseAgentManifest([]byte(`{"manifest":{"imageReference":"` + testImageRef + `","logFilters":[{"addresses":["0x1"],"topics":[["0x2"]]}]}}`))
+	r.NoError(err)
+	r.Equal([]config.LogFilter{{Addresses: []string{"0x1"}, Topics: [][]string{{"0x2"}}}}, m.Declarations.LogFilters)
+
+	m, err = parseAgentManifest([]byte(`{"manifest":{"imageReference":"` + testImageRef + `","userOperations":true}}`))
+	r.NoError(err)
+	r.True(m.Declarations.UserOperations)
+}
+
+func TestMakeAgentConfig_PendingTransactions(t *testing.T) {
+	r := require.New(t)
+
+	rs := &registryStore{
+		ctx: context.Background(),
+		mc: testManifestClient{
+			testAgentRef: []byte(`{"manifest":{"imageReference":"` + testImageRef + `","pendingTransactions":true}}`),
+		},
+		cfg: config.Config{Registry: config.RegistryConfig{ContainerRegistry: testContainerRegistry}},
+	}
+
+	agentCfg, err := rs.makeAgentConfig(testAgentID, testAgentRef)
+	r.NoError(err)
+	r.Equal(testAgentID, agentCfg.ID)
+	r.Equal(testContainerRegistry+"/"+testImageRef, agentCfg.Image)
+	r.True(agentCfg.PendingTransactions)
+}
+
+func TestManifestClient_Fallback(t *testing.T) {
+	r := require.New(t)
+
+	var failingRequests int
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		failingRequests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"manifest":{"imageReference":"` + testImageRef + `"}}`))
+	}))
+	defer working.Close()
+
+	mc, err := NewManifestClient(config.IPFSConfig{
+		LocalNodeURL:          failing.URL,
+		GatewayURL:            working.URL,
+		GatewayTimeoutSeconds: 1,
+	}, nil)
+	r.NoError(err)
+
+	m, err := mc.GetAgentManifest(context.Background(), testAgentRef)
+	r.NoError(err)
+	r.Equal(testImageRef, *m.Manifest.ImageReference)
+	r.Equal(1, failingRequests)
+
+	// the failing gateway is skipped while backing off
+	_, err = mc.GetAgentManifest(context.Background(), testAgentRef)
+	r.NoError(err)
+	r.Equal(1, failingRequests)
+}
+
+func TestManifestClient_Cache(t *testing.T) {
+	r := require.New(t)
+
+	content := []byte(`{"manifest":{"imageReference":"` + testImageRef + `"}}`)
+	ref := testContentID(t, content)
+	var requests int
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write(content)
+	}))
+	defer gateway.Close()
+
+	cfg := config.IPFSConfig{GatewayURL: gateway.URL, GatewayTimeoutSeconds: 1}
+	cacheDir := t.TempDir()
+
+	cache := NewManifestCache(cacheDir)
+	mc, err := NewManifestClient(cfg, cache)
+	r.NoError(err)
+	_, err = mc.GetAgentManifest(context.Background(), ref)
+	r.NoError(err)
+	r.Equal(1, requests)
+
+	// a new client reads from the disk
+	cache = NewManifestCache(cacheDir)
+	mc, err = NewManifestClient(cfg, cache)
+	r.NoError(err)
+	m, err := mc.GetAgentManifest(context.Background(), ref)
+	r.NoError(err)
+	r.Equal(testImageRef, *m.Manifest.ImageReference)
+	r.Equal(1, requests)
+
+	// the content which does not match the reference is not cached
+	_, err = mc.GetAgentManifest(context.Background(), testAgentRef)
+	r.NoError(err)
+	_, err = mc.GetAgentManifest(context.Background(), testAgentRef)
+	r.NoError(err)
+	r.Equal(3, requests)
+
+	reports := cache.Health()
+	r.Equal("1", reports[0].Details)
+	r.Equal("2", reports[1].Details)
+	r.NotEqual("0", reports[2].Details)
+}
+
+// testContentID returns the CID which IPFS gives to the small file.
+func testContentID(t *testing.T, content []byte) string {
+	c, err := cid.Decode(testAgentRef)
+	require.NoError(t, err)
+	sum, err := c.Prefix().Sum(unixfsFileBlock(content))
+	require.NoError(t, err)
+	return sum.String()
+}
+
+func TestVerifyContentID(t *testing.T) {
+	r := require.New(t)
+
+	// the CIDs of the files which were added to IPFS
+	r.NoError(verifyContentID("QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", []byte("hello world\n")))
+	r.NoError(verifyContentID("bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e", []byte("hello world")))
+
+	r.Error(verifyContentID("QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", []byte("hello world")))
+	r.Error(verifyContentID("invalid", []byte("hello world")))
+}
+
+func TestManifestCache_Evict(t *testing.T) {
+	r := require.New(t)
+
+	cache := NewManifestCache(t.TempDir())
+	first := []byte(`{"manifest":{"name":"first"}}`)
+	second := []byte(`{"manifest":{"name":"second"}}`)
+	third := []byte(`{"manifest":{"name":"third"}}`)
+	cache.maxSize = int64(len(first) + len(second) + 1)
+
+	firstRef, secondRef, thirdRef := testContentID(t, first), testContentID(t, second), testContentID(t, third)
+	cache.Put(firstRef, first)
+	cache.Put(secondRef, second)
+	// the first document is used recently
+	past := time.Now().Add(-time.Hour)
+	secondPath, _ := cache.filePath(secondRef)
+	r.NoError(os.Chtimes(secondPath, past, past))
+	_, ok := cache.Get(firstRef)
+	r.True(ok)
+
+	cache.Put(thirdRef, third)
+	_, ok = cache.Get(secondRef)
+	r.False(ok)
+	_, ok = cache.Get(firstRef)
+	r.True(ok)
+	_, ok = cache.Get(thirdRef)
+	r.True(ok)
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/forta-network/forta-node/clients/messaging"
+)
+
+// maxMessageDeadLettersSize is the size after which the dead letters file is rotated, so that the
+// subscribers which keep failing cannot fill the disk.
+const maxMessageDeadLettersSize = 10 * 1024 * 1024
+
+// MessageDeadLetters keeps the bus messages which the subscribers failed to handle as JSON lines.
+type MessageDeadLetters struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewMessageDeadLetters creates a new dead letters file store.
+func NewMessageDeadLetters(filePath string) *MessageDeadLetters {
+	return &MessageDeadLetters{filePath: filePath}
+}
+
+// Append appends the dead letter. The file is moved to the .old file when it gets too large.
+func (dl *MessageDeadLetters) Append(deadLetter messaging.DeadLetterPayload) error {
+	b, err := json.Marshal(&deadLetter)
+	if err != nil {
+		return err
+	}
+
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if info, err := os.Stat(dl.filePath); err == nil && info.Size()+int64(len(b)) > maxMessageDeadLettersSize {
+		if err := os.Rename(dl.filePath, dl.filePath+".old"); err != nil {
+			return fmt.Errorf("failed to rotate the dead letters: %v", err)
+		}
+	}
+	f, err := os.OpenFile(dl.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// ReadMessageDeadLetters reads the last dead letters from the file. It returns no dead letters if the
+// file doesn't exist.
+func ReadMessageDeadLetters(filePath string, limit int) ([]*messaging.DeadLetterPayload, error) {
+	f, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var deadLetters []*messaging.DeadLetterPayload
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageDeadLettersSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		var deadLetter messaging.DeadLetterPayload
+		if err := json.Unmarshal([]byte(line), &deadLetter); err != nil {
+			return nil, fmt.Errorf("invalid dead letter: %v", err)
+		}
+		deadLetters = append(deadLetters, &deadLetter)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(deadLetters) > limit {
+		deadLetters = deadLetters[len(deadLetters)-limit:]
+	}
+	return deadLetters, nil
+}
+
+// ClearMessageDeadLetters removes the dead letters.
+func ClearMessageDeadLetters(filePath string) error {
+	for _, p := range []string{filePath, filePath + ".old"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -49,6 +49,21 @@ func (mr *MockRegistryStoreMockRecorder) FindAgentGlobally(agentID interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAgentGlobally", reflect.TypeOf((*MockRegistryStore)(nil).FindAgentGlobally), agentID)
 }
 
+// GetAgents mocks base method.
+func (m *MockRegistryStore) GetAgents(scanner string) ([]*config.AgentConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAgents", scanner)
+	ret0, _ := ret[0].([]*config.AgentConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAgents indicates an expected call of GetAgents.
+func (mr *MockRegistryStoreMockRecorder) GetAgents(scanner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAgents", reflect.TypeOf((*MockRegistryStore)(nil).GetAgents), scanner)
+}
+
 // GetAgentsIfChanged mocks base method.
 func (m *MockRegistryStore) GetAgentsIfChanged(scanner string) ([]*config.AgentConfig, bool, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ReadPausedAgents reads the IDs of the agents which were paused with the admin API. It returns
+// no agents if the file doesn't exist.
+func ReadPausedAgents(filePath string) ([]string, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parsePausedAgents(b)
+}
+
+func parsePausedAgents(b []byte) ([]string, error) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, nil
+	}
+	var agentIDs []string
+	if err := json.Unmarshal(b, &agentIDs); err != nil {
+		return nil, fmt.Errorf("failed to parse: %v", err)
+	}
+	return agentIDs, nil
+}
+
+// SetAgentPaused adds the agent to the paused agents or removes it and returns the paused agents.
+func SetAgentPaused(filePath, agentID string, paused bool) ([]string, error) {
+	agentIDs, err := ReadPausedAgents(filePath)
+	if err != nil {
+		return nil, err
+	}
+	agentID = strings.ToLower(agentID)
+	updated := make([]string, 0, len(agentIDs)+1)
+	for _, pausedID := range agentIDs {
+		if pausedID != agentID {
+			updated = append(updated, pausedID)
+		}
+	}
+	if paused {
+		updated = append(updated, agentID)
+	}
+	sort.Strings(updated)
+	b, err := json.Marshal(updated)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filePath, b, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write the paused agents: %v", err)
+	}
+	return updated, nil
+}
+
+// removePausedAgents removes the paused agents from the agents.
+func removePausedAgents(agents []*config.AgentConfig, pausedIDs []string) []*config.AgentConfig {
+	if len(pausedIDs) == 0 {
+		return agents
+	}
+	paused := make(map[string]bool)
+	for _, agentID := range pausedIDs {
+		paused[strings.ToLower(agentID)] = true
+	}
+	running := make([]*config.AgentConfig, 0, len(agents))
+	for _, agent := range agents {
+		if !paused[strings.ToLower(agent.ID)] {
+			running = append(running, agent)
+		}
+	}
+	return running
+}
+
+// pausedAgentsStore removes the agents in the paused agents file from the agents of the registry store,
+// so that the paused agents are stopped until they are resumed.
+type pausedAgentsStore struct {
+	RegistryStore
+	filePath string
+
+	agents   []*config.AgentConfig
+	loaded   bool
+	lastFile []byte
+	mu       sync.Mutex
+}
+
+// NewPausedAgentsStore wraps the registry store and removes the paused agents.
+func NewPausedAgentsStore(registryStore RegistryStore, filePath string) *pausedAgentsStore {
+	return &pausedAgentsStore{
+		RegistryStore: registryStore,
+		filePath:      filePath,
+	}
+}
+
+// readIfChanged reads the paused agents file and tells if it has changed since the last read.
+func (ps *pausedAgentsStore) readIfChanged() bool {
+	b, err := ioutil.ReadFile(ps.filePath)
+	if err != nil && !os.IsNotExist(err) {
+		log.WithError(err).WithField("path", ps.filePath).Warn("failed to read the paused agents file")
+		return false
+	}
+	if bytes.Equal(b, ps.lastFile) {
+		return false
+	}
+	ps.lastFile = b
+	if _, err := parsePausedAgents(b); err != nil {
+		log.WithError(err).WithField("path", ps.filePath).Warn("invalid paused agents file - ignoring")
+	}
+	return true
+}
+
+// pausedAgents returns the paused agents from the last read of the file.
+func (ps *pausedAgentsStore) pausedAgents() []string {
+	agentIDs, err := parsePausedAgents(ps.lastFile)
+	if err != nil {
+		return nil
+	}
+	return agentIDs
+}
+
+func (ps *pausedAgentsStore) GetAgentsIfChanged(scanner string) ([]*config.AgentConfig, bool, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	agents, changed, err := ps.RegistryStore.GetAgentsIfChanged(scanner)
+	if err != nil {
+		return nil, false, err
+	}
+	if changed {
+		ps.agents = agents
+		ps.loaded = true
+	}
+	pausedChanged := ps.readIfChanged()
+	// the paused agents cannot change the agents before the registry store has loaded them
+	if !ps.loaded || (!changed && !pausedChanged) {
+		return nil, false, nil
+	}
+	return removePausedAgents(ps.agents, ps.pausedAgents()), true, nil
+}
+
+func (ps *pausedAgentsStore) GetAgents(scanner string) ([]*config.AgentConfig, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	agents, err := ps.RegistryStore.GetAgents(scanner)
+	if err != nil {
+		return nil, err
+	}
+	ps.agents = agents
+	ps.loaded = true
+	ps.readIfChanged()
+	return removePausedAgents(agents, ps.pausedAgents()), nil
+}
+
+// Health implements the health.Reporter interface.
+func (ps *pausedAgentsStore) Health() health.Reports {
+	if reporter, ok := ps.RegistryStore.(interface{ Health() health.Reports }); ok {
+		return reporter.Health()
+	}
+	return nil
+}
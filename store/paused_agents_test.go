@@ -0,0 +1,78 @@
+package store
+
+import (
+	"path"
+	"testing"
+
+	"github.com/forta-network/forta-node/config"
+	mock_store "github.com/forta-network/forta-node/store/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPausedAgentsStore(t *testing.T) {
+	r := require.New(t)
+
+	registryStore := mock_store.NewMockRegistryStore(gomock.NewController(t))
+	filePath := path.Join(t.TempDir(), config.DefaultPausedAgentsFile)
+	ps := NewPausedAgentsStore(registryStore, filePath)
+
+	registryAgents := []*config.AgentConfig{{ID: "agent-1"}, {ID: "agent-2"}}
+
+	// no file: all registry agents
+	registryStore.EXPECT().GetAgentsIfChanged(testScanner).Return(registryAgents, true, nil)
+	agents, changed, err := ps.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.True(changed)
+	r.Equal(registryAgents, agents)
+
+	// the paused agent is removed
+	paused, err := SetAgentPaused(filePath, "AGENT-2", true)
+	r.NoError(err)
+	r.Equal([]string{"agent-2"}, paused)
+	registryStore.EXPECT().GetAgentsIfChanged(testScanner).Return(nil, false, nil)
+	agents, changed, err = ps.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.True(changed)
+	r.Len(agents, 1)
+	r.Equal("agent-1", agents[0].ID)
+
+	// nothing changed
+	registryStore.EXPECT().GetAgentsIfChanged(testScanner).Return(nil, false, nil)
+	_, changed, err = ps.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.False(changed)
+
+	// the resumed agent is added back
+	paused, err = SetAgentPaused(filePath, "agent-2", false)
+	r.NoError(err)
+	r.Empty(paused)
+	registryStore.EXPECT().GetAgents(testScanner).Return(registryAgents, nil)
+	agents, err = ps.GetAgents(testScanner)
+	r.NoError(err)
+	r.Equal(registryAgents, agents)
+}
+
+func TestPausedAgentsStore_NotLoaded(t *testing.T) {
+	r := require.New(t)
+
+	registryStore := mock_store.NewMockRegistryStore(gomock.NewController(t))
+	filePath := path.Join(t.TempDir(), config.DefaultPausedAgentsFile)
+	ps := NewPausedAgentsStore(registryStore, filePath)
+	_, err := SetAgentPaused(filePath, "agent-2", true)
+	r.NoError(err)
+
+	// the paused agents do not replace the agents with an empty list before the registry store loads
+	registryStore.EXPECT().GetAgentsIfChanged(testScanner).Return(nil, false, nil)
+	agents, changed, err := ps.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.False(changed)
+	r.Nil(agents)
+
+	registryStore.EXPECT().GetAgentsIfChanged(testScanner).Return([]*config.AgentConfig{{ID: "agent-1"}, {ID: "agent-2"}}, true, nil)
+	agents, changed, err = ps.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.True(changed)
+	r.Len(agents, 1)
+	r.Equal("agent-1", agents[0].ID)
+}
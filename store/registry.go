@@ -4,14 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/big"
+	"path"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/ethereum"
-	"github.com/forta-network/forta-core-go/manifest"
 	"github.com/forta-network/forta-core-go/registry"
 	"github.com/forta-network/forta-core-go/utils"
 	"github.com/forta-network/forta-node/config"
@@ -20,24 +23,149 @@ import (
 type RegistryStore interface {
 	FindAgentGlobally(agentID string) (*config.AgentConfig, error)
 	GetAgentsIfChanged(scanner string) ([]*config.AgentConfig, bool, error)
+	GetAgents(scanner string) ([]*config.AgentConfig, error)
 }
 
+// AgentRejectionHandler handles the agents of which the manifests are not valid.
+type AgentRejectionHandler func(agentID, ref string, err *ManifestValidationError)
+
+// manifestCacheDirName is the dir in the Forta dir which keeps the agent manifests.
+const manifestCacheDirName = "manifests"
+
+// pinnedManifestsFileName is the file in the Forta dir which lists the manifests pinned on the local IPFS node.
+const pinnedManifestsFileName = "pinned-manifests.json"
+
+// AllPools is the pool ID which selects all agents of the chain.
+const AllPools = "all"
+
+// manifestWorkers is how many agent manifests are resolved at the same time.
+const manifestWorkers = 10
+
+// stakeRefreshInterval is how often the agents are reloaded to check the stakes again if there is a min stake.
+const stakeRefreshInterval = 10 * time.Minute
+
+// allPoolsRefreshInterval is how often the agents of all pools are reloaded. The chain agents don't
+// have an assignment hash which tells that they have changed.
+const allPoolsRefreshInterval = 10 * time.Minute
+
 type registryStore struct {
-	ctx context.Context
-	mc  manifest.Client
-	rc  registry.Client
-	cfg config.Config
+	ctx    context.Context
+	mc     ManifestClient
+	rc     registry.Client
+	cfg    config.Config
+	cache  *ManifestCache
+	pinner *ManifestPinner
+
+	onRejected AgentRejectionHandler
+
+	stakes     AgentStakeGetter
+	minStake   *big.Int
+	lastStakes map[string]*big.Int
 
 	lastUpdate time.Time
-	version    string
+	versions   map[string]string
 	mu         sync.Mutex
 }
 
+// poolIDs returns the pools of which the agents are run. The first pool is the scanner itself.
+func (rs *registryStore) poolIDs(scanner string) []string {
+	pools := []string{scanner}
+	for _, poolID := range rs.cfg.Registry.PoolIDs {
+		if !containsFold(pools, poolID) {
+			pools = append(pools, poolID)
+		}
+	}
+	return pools
+}
+
+// poolVersions returns the assignment hash of each pool.
+func (rs *registryStore) poolVersions(pools []string) (map[string]string, error) {
+	versions := make(map[string]string)
+	for _, poolID := range pools {
+		if strings.EqualFold(poolID, AllPools) {
+			continue
+		}
+		hash, err := rs.rc.GetAssignmentHash(poolID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the assignment hash of pool %s: %v", poolID, err)
+		}
+		versions[poolID] = hash.Hash
+	}
+	return versions, nil
+}
+
+func (rs *registryStore) shouldUpdate(pools []string, versions map[string]string) bool {
+	if len(versions) != len(rs.versions) {
+		return true
+	}
+	for poolID, version := range versions {
+		if rs.versions[poolID] != version {
+			return true
+		}
+	}
+	if rs.minStake != nil && time.Since(rs.lastUpdate) > stakeRefreshInterval {
+		return true
+	}
+	return containsFold(pools, AllPools) && time.Since(rs.lastUpdate) > allPoolsRefreshInterval
+}
+
+// hasMinStake tells if the agent has enough stake to run.
+func (rs *registryStore) hasMinStake(agentID string) (bool, error) {
+	if rs.minStake == nil {
+		return true, nil
+	}
+	stake, err := rs.stakes.GetActiveStake(agentID)
+	if err != nil {
+		// keep using the last known stake so that an RPC error does not stop the agent
+		lastStake, ok := rs.lastStakes[agentID]
+		if !ok {
+			return false, fmt.Errorf("failed to get the stake of agent %s: %v", agentID, err)
+		}
+		log.WithField("agentId", agentID).WithError(err).Warn("failed to get the stake - using the last known stake")
+		stake = lastStake
+	}
+	if rs.lastStakes == nil {
+		rs.lastStakes = make(map[string]*big.Int)
+	}
+	rs.lastStakes[agentID] = stake
+	return stake.Cmp(rs.minStake) >= 0, nil
+}
+
+func (rs *registryStore) forEachPoolAgent(poolID string, handler func(a *registry.Agent) error) error {
+	if strings.EqualFold(poolID, AllPools) {
+		return rs.rc.ForEachChainAgent(int64(rs.cfg.ChainID), handler)
+	}
+	return rs.rc.ForEachAssignedAgent(poolID, handler)
+}
+
 func (rs *registryStore) GetAgentsIfChanged(scanner string) ([]*config.AgentConfig, bool, error) {
 	// because we peg the latest block, it can be problematic if this is called concurrently
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
-	hash, err := rs.rc.GetAssignmentHash(scanner)
+	return rs.getAgents(scanner, false)
+}
+
+// GetAgents loads all agents of the pools from the current registry state, even if the
+// assignments have not changed.
+func (rs *registryStore) GetAgents(scanner string) ([]*config.AgentConfig, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	agts, _, err := rs.getAgents(scanner, true)
+	return agts, err
+}
+
+// IsRegisteredScanner tells if the scanner is registered on the scanner registry.
+func (rs *registryStore) IsRegisteredScanner(scanner string) (bool, error) {
+	scn, err := rs.rc.GetScanner(scanner)
+	if err != nil {
+		return false, err
+	}
+	return scn != nil, nil
+}
+
+func (rs *registryStore) getAgents(scanner string, force bool) ([]*config.AgentConfig, bool, error) {
+	pools := rs.poolIDs(scanner)
+	versions, err := rs.poolVersions(pools)
 	if err != nil {
 		return nil, false, err
 	}
@@ -51,40 +179,160 @@ func (rs *registryStore) GetAgentsIfChanged(scanner string) ([]*config.AgentConf
 		return []*config.AgentConfig{}, true, nil
 	}
 
-	if rs.version != hash.Hash || time.Since(rs.lastUpdate) > 1*time.Hour {
-		if err := rs.rc.PegLatestBlock(); err != nil {
-			return nil, false, err
+	if !force && !rs.shouldUpdate(pools, versions) {
+		return nil, false, nil
+	}
+	agts, err := rs.loadAgents(pools)
+	if err != nil {
+		return nil, false, err
+	}
+	rs.versions = versions
+	rs.lastUpdate = time.Now()
+	return agts, true, nil
+}
+
+// registryAgent is an agent from the pools of which the manifest is not resolved yet.
+type registryAgent struct {
+	agentID  string
+	manifest string
+	pools    []string
+}
+
+// loadAgents enumerates the agents of the pools at the latest block.
+func (rs *registryStore) loadAgents(pools []string) ([]*config.AgentConfig, error) {
+	regAgents, err := rs.getPoolAgents(pools)
+	if err != nil {
+		return nil, err
+	}
+	agtCfgs, errs := rs.resolveAgents(regAgents)
+
+	var (
+		agts   []*config.AgentConfig
+		failed int
+	)
+	for i, agtCfg := range agtCfgs {
+		if errs[i] != nil {
+			failed++
+			log.WithField("agentId", regAgents[i].agentID).WithError(errs[i]).Warn("could not parse config for agent")
+			// ignore agent and move on
+			continue
+		}
+		if agtCfg == nil {
+			continue
+		}
+		// the agents of the other chains are not run on this node
+		if !agtCfg.SupportsAnyChain(rs.cfg.ScannedChainIDs(), int64(rs.cfg.ChainID)) {
+			log.WithFields(log.Fields{
+				"agentId":  agtCfg.ID,
+				"chainIds": agtCfg.ChainIDs,
+			}).Info("agent does not scan the chains of this node - skipping")
+			continue
 		}
-		defer rs.rc.ResetOpts()
-		var agts []*config.AgentConfig
+		agtCfg.Pools = regAgents[i].pools
+		agts = append(agts, agtCfg)
+	}
+	if failed > 0 {
+		log.WithFields(log.Fields{
+			"failed": failed,
+			"total":  len(regAgents),
+		}).Warn("failed to load some of the agents")
+	}
+
+	// failed to load all: not doing this can cause getting stuck with the latest hash and zero agents
+	if len(agts) == 0 && failed > 0 {
+		return nil, errors.New("loaded zero agents")
+	}
+	return agts, nil
+}
 
-		var failedLoadingAny bool
-		err := rs.rc.ForEachAssignedAgent(scanner, func(a *registry.Agent) error {
-			agtCfg, err := rs.makeAgentConfig(a.AgentID, a.Manifest)
+// getPoolAgents gets the agents of the pools from the registry.
+func (rs *registryStore) getPoolAgents(pools []string) ([]*registryAgent, error) {
+	if err := rs.rc.PegLatestBlock(); err != nil {
+		return nil, err
+	}
+	defer rs.rc.ResetOpts()
+	var (
+		regAgents []*registryAgent
+		byID      = make(map[string]*registryAgent)
+	)
+	for _, poolID := range pools {
+		err := rs.forEachPoolAgent(poolID, func(a *registry.Agent) error {
+			// the agents in multiple pools are run once
+			if regAgent, ok := byID[a.AgentID]; ok {
+				regAgent.pools = append(regAgent.pools, poolID)
+				return nil
+			}
+			hasMinStake, err := rs.hasMinStake(a.AgentID)
 			if err != nil {
-				failedLoadingAny = true
-				log.WithField("agentId", a.AgentID).WithError(err).Warn("could not parse config for agent")
-				// ignore agent and move on by not returning the error
+				return err
+			}
+			if !hasMinStake {
+				log.WithField("agentId", a.AgentID).Info("agent has less than the min stake - skipping")
 				return nil
 			}
-			agts = append(agts, agtCfg)
+			regAgent := &registryAgent{agentID: a.AgentID, manifest: a.Manifest, pools: []string{poolID}}
+			byID[a.AgentID] = regAgent
+			regAgents = append(regAgents, regAgent)
 			return nil
 		})
-
 		if err != nil {
-			return nil, false, err
+			return nil, fmt.Errorf("failed to get the agents of pool %s: %v", poolID, err)
 		}
+	}
+	return regAgents, nil
+}
 
-		// failed to load all: not doing this can cause getting stuck with the latest hash and zero agents
-		if len(agts) == 0 && failedLoadingAny {
-			return nil, false, errors.New("loaded zero agents")
+// resolveAgents resolves the manifests of the agents concurrently. The results are in the order
+// of the agents.
+func (rs *registryStore) resolveAgents(regAgents []*registryAgent) ([]*config.AgentConfig, []error) {
+	var (
+		agtCfgs = make([]*config.AgentConfig, len(regAgents))
+		errs    = make([]error, len(regAgents))
+		sem     = make(chan struct{}, manifestWorkers)
+		wg      sync.WaitGroup
+	)
+	for i, regAgent := range regAgents {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, regAgent *registryAgent) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			agtCfgs[i], errs[i] = rs.makeAgentConfig(regAgent.agentID, regAgent.manifest)
+		}(i, regAgent)
+	}
+	wg.Wait()
+	return agtCfgs, errs
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
 		}
+	}
+	return false
+}
 
-		rs.version = hash.Hash
-		rs.lastUpdate = time.Now()
-		return agts, true, nil
+// OnAgentRejected sets the handler of the rejected agents.
+func (rs *registryStore) OnAgentRejected(handler AgentRejectionHandler) {
+	rs.onRejected = handler
+}
+
+// Health implements the health.Reporter interface.
+func (rs *registryStore) Health() health.Reports {
+	var reports health.Reports
+	if rs.cache != nil {
+		reports = append(reports, rs.cache.Health()...)
+	}
+	if rs.pinner != nil {
+		reports = append(reports, rs.pinner.Health()...)
+	}
+	if reporter, ok := rs.mc.(interface{ Health() health.Reports }); ok {
+		reports = append(reports, reporter.Health()...)
 	}
-	return nil, false, nil
+	return reports
 }
 
 func (rs *registryStore) FindAgentGlobally(agentID string) (*config.AgentConfig, error) {
@@ -99,41 +347,56 @@ func (rs *registryStore) makeAgentConfig(agentID string, ref string) (*config.Ag
 	if len(ref) == 0 {
 		return nil, nil
 	}
-	var agentData *manifest.SignedAgentManifest
-
-	var err error
-	for i := 0; i < 10; i++ {
-		agentData, err = rs.mc.GetAgentManifest(rs.ctx, ref)
-		if err == nil {
-			break
-		}
-	}
+	// the manifest client tries all gateways
+	agentData, err := rs.mc.GetAgentManifest(rs.ctx, ref)
 	if err != nil {
 		err = fmt.Errorf("failed to load the agent file using ipfs ref: %v", err)
 		return nil, err
 	}
 
-	if agentData.Manifest.ImageReference == nil {
-		return nil, fmt.Errorf("invalid agent image reference, it is nil")
-	}
-
-	image, err := utils.ValidateDiscoImageRef(rs.cfg.Registry.ContainerRegistry, *agentData.Manifest.ImageReference)
-	if err != nil {
-		return nil, fmt.Errorf("invalid agent image reference '%s': %v", *agentData.Manifest.ImageReference, err)
+	if err := ValidateAgentManifest(agentData, rs.cfg.Registry.ContainerRegistry); err != nil {
+		if validationErr, ok := err.(*ManifestValidationError); ok && rs.onRejected != nil {
+			rs.onRejected(agentID, ref, validationErr)
+		}
+		return nil, err
 	}
+	image, _ := utils.ValidateDiscoImageRef(rs.cfg.Registry.ContainerRegistry, *agentData.Manifest.ImageReference)
 
 	return &config.AgentConfig{
-		ID:       agentID,
-		Image:    image,
-		Manifest: ref,
+		ID:                  agentID,
+		Image:               image,
+		Manifest:            ref,
+		PendingTransactions: agentData.Declarations.PendingTransactions,
+		LogFilters:          agentData.Declarations.LogFilters,
+		ChainIDs:            agentData.Manifest.ChainIDs,
+		UserOperations:      agentData.Declarations.UserOperations,
+		Resources:           agentData.Declarations.Resources,
+		CosignPublicKey:     agentData.Declarations.CosignPublicKey,
+		EgressHosts:         agentData.Declarations.EgressHosts,
 	}, nil
 }
 
 func NewRegistryStore(ctx context.Context, cfg config.Config, ethClient ethereum.Client) (*registryStore, error) {
-	mc, err := manifest.NewClient(cfg.Registry.IPFS.GatewayURL)
+	var cache *ManifestCache
+	if len(cfg.FortaDir) > 0 {
+		cache = NewManifestCache(path.Join(cfg.FortaDir, manifestCacheDirName))
+	}
+	mc, err := NewManifestClient(cfg.Registry.IPFS, cache)
 	if err != nil {
 		return nil, err
 	}
+	var pinner *ManifestPinner
+	if cfg.Registry.IPFS.PinManifests {
+		if len(cfg.Registry.IPFS.LocalNodeAPIURL) == 0 {
+			return nil, errors.New("the local ipfs node api url is required for pinning the manifests")
+		}
+		var listPath string
+		if len(cfg.FortaDir) > 0 {
+			listPath = path.Join(cfg.FortaDir, pinnedManifestsFileName)
+		}
+		pinner = NewManifestPinner(cfg.Registry.IPFS.LocalNodeAPIURL, listPath)
+		mc.pinner = pinner
+	}
 
 	rc, err := GetRegistryClient(ctx, cfg, registry.ClientConfig{
 		JsonRpcUrl: cfg.Registry.JsonRpc.Url,
@@ -144,11 +407,27 @@ func NewRegistryStore(ctx context.Context, cfg config.Config, ethClient ethereum
 		return nil, err
 	}
 
+	minStake, err := parseMinStake(cfg.Registry.MinAgentStakeWei)
+	if err != nil {
+		return nil, err
+	}
+	var stakes AgentStakeGetter
+	if minStake != nil {
+		stakes, err = NewStakingClient(ctx, cfg.Registry.JsonRpc, rc.RegistryContracts().FortaStaking)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the staking client: %v", err)
+		}
+	}
+
 	return &registryStore{
-		ctx: ctx,
-		cfg: cfg,
-		mc:  mc,
-		rc:  rc,
+		ctx:      ctx,
+		cfg:      cfg,
+		mc:       mc,
+		rc:       rc,
+		cache:    cache,
+		pinner:   pinner,
+		stakes:   stakes,
+		minStake: minStake,
 	}, nil
 }
 
@@ -174,6 +453,11 @@ func (rs *privateRegistryStore) GetAgentsIfChanged(scanner string) ([]*config.Ag
 	return agentConfigs, true, nil
 }
 
+func (rs *privateRegistryStore) GetAgents(scanner string) ([]*config.AgentConfig, error) {
+	agentConfigs, _, err := rs.GetAgentsIfChanged(scanner)
+	return agentConfigs, err
+}
+
 func (rs *privateRegistryStore) FindAgentGlobally(agentID string) (*config.AgentConfig, error) {
 	return nil, errors.New("feature not available (private/local registry)")
 }
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/registry"
+	mock_registry "github.com/forta-network/forta-core-go/registry/mocks"
+	"github.com/forta-network/forta-node/config"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testScanner      = "0x1000000000000000000000000000000000000001"
+	testPoolScanner  = "0x1000000000000000000000000000000000000002"
+	testOtherAgentID = "0x3000000000000000000000000000000000000000000000000000000000000000"
+)
+
+func TestGetAgentsIfChanged_Pools(t *testing.T) {
+	r := require.New(t)
+
+	rc := mock_registry.NewMockClient(gomock.NewController(t))
+	cfg := config.Config{ChainID: 1}
+	cfg.Registry.ContainerRegistry = testContainerRegistry
+	cfg.Registry.PoolIDs = []string{testPoolScanner, AllPools}
+	rs := &registryStore{
+		ctx: context.Background(),
+		mc: testManifestClient{
+			testAgentRef: []byte(`{"manifest":{"imageReference":"` + testImageRef + `"}}`),
+		},
+		rc:  rc,
+		cfg: cfg,
+	}
+
+	forEachAgent := func(agentIDs ...string) func(string, func(a *registry.Agent) error) error {
+		return func(_ string, handler func(a *registry.Agent) error) error {
+			for _, agentID := range agentIDs {
+				if err := handler(&registry.Agent{AgentID: agentID, Manifest: testAgentRef}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	rc.EXPECT().GetAssignmentHash(testScanner).Return(&registry.AssignmentHash{Hash: "1"}, nil).Times(3)
+	rc.EXPECT().GetAssignmentHash(testPoolScanner).Return(&registry.AssignmentHash{Hash: "2"}, nil).Times(3)
+	rc.EXPECT().IsEnabledScanner(testScanner).Return(true, nil).Times(3)
+	rc.EXPECT().PegLatestBlock().Return(nil).Times(2)
+	rc.EXPECT().ResetOpts().Times(2)
+	rc.EXPECT().ForEachAssignedAgent(testScanner, gomock.Any()).DoAndReturn(forEachAgent(testAgentID)).Times(2)
+	rc.EXPECT().ForEachAssignedAgent(testPoolScanner, gomock.Any()).DoAndReturn(forEachAgent(testAgentID, testOtherAgentID)).Times(2)
+	rc.EXPECT().ForEachChainAgent(int64(1), gomock.Any()).Return(nil).Times(2)
+
+	agts, changed, err := rs.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.True(changed)
+	r.Len(agts, 2)
+	r.Equal(testAgentID, agts[0].ID)
+	r.Equal([]string{testScanner, testPoolScanner}, agts[0].Pools)
+	r.Equal(testOtherAgentID, agts[1].ID)
+	r.Equal([]string{testPoolScanner}, agts[1].Pools)
+
+	// the pools have not changed
+	_, changed, err = rs.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.False(changed)
+
+	// the agents are reloaded even if the pools have not changed
+	agts, err = rs.GetAgents(testScanner)
+	r.NoError(err)
+	r.Len(agts, 2)
+}
+
+type testStakes map[string]int64
+
+func (ts testStakes) GetActiveStake(agentID string) (*big.Int, error) {
+	return big.NewInt(ts[agentID]), nil
+}
+
+func TestGetAgentsIfChanged_MinStake(t *testing.T) {
+	r := require.New(t)
+
+	rc := mock_registry.NewMockClient(gomock.NewController(t))
+	cfg := config.Config{ChainID: 1}
+	cfg.Registry.ContainerRegistry = testContainerRegistry
+	rs := &registryStore{
+		ctx: context.Background(),
+		mc: testManifestClient{
+			testAgentRef: []byte(`{"manifest":{"imageReference":"` + testImageRef + `"}}`),
+		},
+		rc:       rc,
+		cfg:      cfg,
+		stakes:   testStakes{testAgentID: 100, testOtherAgentID: 99},
+		minStake: big.NewInt(100),
+	}
+
+	rc.EXPECT().GetAssignmentHash(testScanner).Return(&registry.AssignmentHash{Hash: "1"}, nil).Times(2)
+	rc.EXPECT().IsEnabledScanner(testScanner).Return(true, nil).Times(2)
+	rc.EXPECT().PegLatestBlock().Return(nil).Times(2)
+	rc.EXPECT().ResetOpts().Times(2)
+	rc.EXPECT().ForEachAssignedAgent(testScanner, gomock.Any()).DoAndReturn(
+		func(_ string, handler func(a *registry.Agent) error) error {
+			for _, agentID := range []string{testAgentID, testOtherAgentID} {
+				if err := handler(&registry.Agent{AgentID: agentID, Manifest: testAgentRef}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Times(2)
+
+	agts, changed, err := rs.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.True(changed)
+	r.Len(agts, 1)
+	r.Equal(testAgentID, agts[0].ID)
+
+	// the stakes are checked again after the refresh interval
+	rs.stakes = testStakes{testAgentID: 100, testOtherAgentID: 100}
+	rs.lastUpdate = time.Now().Add(-stakeRefreshInterval * 2)
+	agts, changed, err = rs.GetAgentsIfChanged(testScanner)
+	r.NoError(err)
+	r.True(changed)
+	r.Len(agts, 2)
+}
+
+type failingStakes struct{}
+
+func (failingStakes) GetActiveStake(agentID string) (*big.Int, error) {
+	return nil, errors.New("rpc error")
+}
+
+func TestHasMinStake_LastKnownStake(t *testing.T) {
+	r := require.New(t)
+
+	rs := &registryStore{
+		stakes:   testStakes{testAgentID: 100},
+		minStake: big.NewInt(100),
+	}
+	hasMinStake, err := rs.hasMinStake(testAgentID)
+	r.NoError(err)
+	r.True(hasMinStake)
+
+	// the last known stake is used on an RPC error
+	rs.stakes = failingStakes{}
+	hasMinStake, err = rs.hasMinStake(testAgentID)
+	r.NoError(err)
+	r.True(hasMinStake)
+
+	// there is no known stake for the other agent
+	_, err = rs.hasMinStake(testOtherAgentID)
+	r.Error(err)
+}
+
+func TestParseMinStake(t *testing.T) {
+	r := require.New(t)
+
+	minStake, err := parseMinStake("")
+	r.NoError(err)
+	r.Nil(minStake)
+
+	minStake, err = parseMinStake("1000000000000000000")
+	r.NoError(err)
+	r.Equal("1000000000000000000", minStake.String())
+
+	_, err = parseMinStake("-1")
+	r.Error(err)
+}
+
+func TestLoadAgents_Chains(t *testing.T) {
+	r := require.New(t)
+
+	rc := mock_registry.NewMockClient(gomock.NewController(t))
+	cfg := config.Config{ChainID: 1, Chains: []config.ChainConfig{{ChainID: 137}}}
+	cfg.Registry.ContainerRegistry = testContainerRegistry
+	rs := &registryStore{
+		ctx: context.Background(),
+		mc: testManifestClient{
+			"ethereum": []byte(`{"manifest":{"imageReference":"` + testImageRef + `","chainIds":[1]}}`),
+			"polygon":  []byte(`{"manifest":{"imageReference":"` + testImageRef + `","chainIds":[137]}}`),
+			"bsc":      []byte(`{"manifest":{"imageReference":"` + testImageRef + `","chainIds":[56]}}`),
+		},
+		rc:  rc,
+		cfg: cfg,
+	}
+
+	rc.EXPECT().PegLatestBlock().Return(nil)
+	rc.EXPECT().ResetOpts()
+	rc.EXPECT().ForEachAssignedAgent(testScanner, gomock.Any()).DoAndReturn(
+		func(_ string, handler func(a *registry.Agent) error) error {
+			for _, manifest := range []string{"ethereum", "polygon", "bsc"} {
+				if err := handler(&registry.Agent{AgentID: manifest, Manifest: manifest}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	agts, err := rs.loadAgents([]string{testScanner})
+	r.NoError(err)
+	r.Len(agts, 2)
+	r.Equal("ethereum", agts[0].ID)
+	r.Equal("polygon", agts[1].ID)
+}
+
+type concurrentManifestClient struct {
+	running    int32
+	maxRunning int32
+}
+
+func (mc *concurrentManifestClient) GetAgentManifest(ctx context.Context, reference string) (*AgentManifest, error) {
+	running := atomic.AddInt32(&mc.running, 1)
+	defer atomic.AddInt32(&mc.running, -1)
+	for {
+		maxRunning := atomic.LoadInt32(&mc.maxRunning)
+		if running <= maxRunning || atomic.CompareAndSwapInt32(&mc.maxRunning, maxRunning, running) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if reference == "bad" {
+		return nil, errors.New("failed")
+	}
+	return parseAgentManifest([]byte(`{"manifest":{"imageReference":"` + testImageRef + `"}}`))
+}
+
+func TestResolveAgents(t *testing.T) {
+	r := require.New(t)
+
+	mc := &concurrentManifestClient{}
+	rs := &registryStore{
+		ctx: context.Background(),
+		mc:  mc,
+		cfg: config.Config{Registry: config.RegistryConfig{ContainerRegistry: testContainerRegistry}},
+	}
+	var regAgents []*registryAgent
+	for i := 0; i < manifestWorkers*2; i++ {
+		regAgents = append(regAgents, &registryAgent{agentID: strconv.Itoa(i), manifest: testAgentRef})
+	}
+	regAgents[3].manifest = "bad"
+
+	agtCfgs, errs := rs.resolveAgents(regAgents)
+	r.Len(agtCfgs, len(regAgents))
+	for i, agtCfg := range agtCfgs {
+		if i == 3 {
+			r.Error(errs[i])
+			continue
+		}
+		r.NoError(errs[i])
+		r.Equal(strconv.Itoa(i), agtCfg.ID)
+	}
+	r.Greater(mc.maxRunning, int32(1))
+	r.LessOrEqual(mc.maxRunning, int32(manifestWorkers))
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/forta-network/forta-core-go/contracts/contract_forta_staking"
+	"github.com/forta-network/forta-core-go/utils"
+	"github.com/forta-network/forta-node/config"
+)
+
+// agentSubjectType is the subject type of the agents in the staking contract.
+const agentSubjectType = 1
+
+// AgentStakeGetter gets the active stakes of the agents.
+type AgentStakeGetter interface {
+	GetActiveStake(agentID string) (*big.Int, error)
+}
+
+type stakingClient struct {
+	ctx context.Context
+	fs  *contract_forta_staking.FortaStakingCaller
+}
+
+// NewStakingClient creates a new client which reads the agent stakes from the staking contract.
+func NewStakingClient(ctx context.Context, jsonRpc config.JsonRpcConfig, stakingAddress common.Address) (*stakingClient, error) {
+	rpcClient, err := rpc.DialContext(ctx, jsonRpc.Url)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range jsonRpc.Headers {
+		rpcClient.SetHeader(k, v)
+	}
+	fs, err := contract_forta_staking.NewFortaStakingCaller(stakingAddress, ethclient.NewClient(rpcClient))
+	if err != nil {
+		return nil, err
+	}
+	return &stakingClient{ctx: ctx, fs: fs}, nil
+}
+
+// GetActiveStake returns the active stake of the agent.
+func (sc *stakingClient) GetActiveStake(agentID string) (*big.Int, error) {
+	return sc.fs.ActiveStakeFor(&bind.CallOpts{Context: sc.ctx}, agentSubjectType, utils.AgentHexToBigInt(agentID))
+}
+
+// parseMinStake parses the min stake config. An empty value disables the stake check.
+func parseMinStake(s string) (*big.Int, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	minStake, ok := new(big.Int).SetString(s, 10)
+	if !ok || minStake.Sign() < 0 {
+		return nil, fmt.Errorf("invalid min agent stake: %s", s)
+	}
+	return minStake, nil
+}
@@ -0,0 +1,236 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-node/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultExportInterval = time.Second * 5
+	defaultExportBatch    = 512
+	defaultSpanBuffer     = 10000
+	defaultExportTimeout  = time.Second * 10
+
+	spanKindInternal = 1
+)
+
+// Exporter sends the spans to the OpenTelemetry collector with OTLP over HTTP in batches. The spans
+// are dropped instead of slowing down the node if the collector can't keep up.
+type Exporter struct {
+	ctx       context.Context
+	cfg       config.TracingConfig
+	client    *http.Client
+	spans     chan *Span
+	threshold uint64
+
+	dropped    uint64
+	lastExport health.TimeTracker
+	lastErr    health.ErrorTracker
+}
+
+// NewExporter creates a new exporter which exports until the context is done.
+func NewExporter(ctx context.Context, cfg config.TracingConfig) *Exporter {
+	exporter := &Exporter{
+		ctx:       ctx,
+		cfg:       cfg,
+		client:    &http.Client{Timeout: defaultExportTimeout},
+		spans:     make(chan *Span, defaultSpanBuffer),
+		threshold: sampleThreshold(cfg.SampleRatio),
+	}
+	go exporter.exportLoop()
+	return exporter
+}
+
+func sampleThreshold(ratio float64) uint64 {
+	if ratio >= 1 {
+		return math.MaxUint64
+	}
+	return uint64(ratio * math.MaxUint64)
+}
+
+// Sampled tells if the spans of the trace should be exported. The decision is made from the trace
+// ID so that a trace is exported as a whole.
+func (exporter *Exporter) Sampled(traceID TraceID) bool {
+	return binary.BigEndian.Uint64(traceID[:8]) <= exporter.threshold
+}
+
+// Export queues the spans of the sampled traces.
+func (exporter *Exporter) Export(spans ...*Span) {
+	for _, span := range spans {
+		if !exporter.Sampled(span.TraceID) {
+			continue
+		}
+		select {
+		case exporter.spans <- span:
+		default:
+			atomic.AddUint64(&exporter.dropped, 1)
+		}
+	}
+}
+
+func (exporter *Exporter) exportLoop() {
+	ticker := time.NewTicker(defaultExportInterval)
+	defer ticker.Stop()
+	var batch []*Span
+	for {
+		select {
+		case <-exporter.ctx.Done():
+			return
+		case span := <-exporter.spans:
+			batch = append(batch, span)
+			if len(batch) < defaultExportBatch {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		err := exporter.send(batch)
+		exporter.lastErr.Set(err)
+		if err != nil {
+			log.WithError(err).WithField("spans", len(batch)).Warn("failed to export the spans")
+		} else {
+			exporter.lastExport.Set()
+		}
+		batch = nil
+	}
+}
+
+func (exporter *Exporter) send(spans []*Span) error {
+	b, err := json.Marshal(exporter.toRequest(spans))
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/v1/traces", strings.TrimSuffix(exporter.cfg.Endpoint, "/"))
+	req, err := http.NewRequestWithContext(exporter.ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := exporter.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// the OTLP JSON encoding of the traces
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func toAttributes(attributes map[string]string) []otlpAttribute {
+	var otlpAttributes []otlpAttribute
+	for key, value := range attributes {
+		otlpAttributes = append(otlpAttributes, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+	}
+	sort.Slice(otlpAttributes, func(i, j int) bool {
+		return otlpAttributes[i].Key < otlpAttributes[j].Key
+	})
+	return otlpAttributes
+}
+
+func (exporter *Exporter) toRequest(spans []*Span) *otlpRequest {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		otlpSpans = append(otlpSpans, otlpSpan{
+			TraceID:           span.TraceID.String(),
+			SpanID:            span.SpanID.String(),
+			ParentSpanID:      span.ParentSpanID.String(),
+			Name:              span.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        toAttributes(span.Attributes),
+		})
+	}
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: toAttributes(map[string]string{"service.name": exporter.cfg.ServiceName}),
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: "forta-node"},
+						Spans: otlpSpans,
+					},
+				},
+			},
+		},
+	}
+}
+
+// Health implements the health.Reporter interface.
+func (exporter *Exporter) Health() health.Reports {
+	return health.Reports{
+		exporter.lastErr.GetReport("event.trace-export.error"),
+		&health.Report{
+			Name:    "event.trace-export.time",
+			Status:  health.StatusInfo,
+			Details: exporter.lastExport.String(),
+		},
+		&health.Report{
+			Name:    "trace-spans.dropped.count",
+			Status:  health.StatusInfo,
+			Details: strconv.FormatUint(atomic.LoadUint64(&exporter.dropped), 10),
+		},
+	}
+}
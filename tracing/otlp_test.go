@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_Send(t *testing.T) {
+	r := require.New(t)
+
+	var received otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Equal("/v1/traces", req.URL.Path)
+		r.Equal("application/json", req.Header.Get("Content-Type"))
+		r.NoError(json.NewDecoder(req.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	exporter := &Exporter{
+		ctx:    context.Background(),
+		cfg:    config.TracingConfig{Endpoint: server.URL + "/", ServiceName: "forta-node"},
+		client: server.Client(),
+	}
+	traceID, spanID := BlockIDs("1", "0xblockhash")
+	start := time.Unix(1652212800, 0)
+	root := &Span{TraceID: traceID, SpanID: spanID, Name: "block.ingest", Start: start, End: start.Add(time.Second)}
+	child := root.Child("agent.result", start.Add(time.Second), start.Add(time.Second*2))
+	child.Attributes["agent.id"] = "0xagent"
+	r.NoError(exporter.send([]*Span{root, child}))
+
+	r.Len(received.ResourceSpans, 1)
+	r.Equal("service.name", received.ResourceSpans[0].Resource.Attributes[0].Key)
+	r.Equal("forta-node", received.ResourceSpans[0].Resource.Attributes[0].Value.StringValue)
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	r.Len(spans, 2)
+	r.Equal(traceID.String(), spans[0].TraceID)
+	r.Equal(spanID.String(), spans[0].SpanID)
+	r.Empty(spans[0].ParentSpanID)
+	r.Equal("1652212800000000000", spans[0].StartTimeUnixNano)
+	r.Equal(traceID.String(), spans[1].TraceID)
+	r.Equal(spanID.String(), spans[1].ParentSpanID)
+	r.Equal([]otlpAttribute{{Key: "agent.id", Value: otlpValue{StringValue: "0xagent"}}}, spans[1].Attributes)
+}
+
+func TestExporter_Sampled(t *testing.T) {
+	r := require.New(t)
+
+	traceID, _ := BlockIDs("1", "0xblockhash")
+	r.True((&Exporter{threshold: sampleThreshold(1)}).Sampled(traceID))
+	r.False((&Exporter{threshold: sampleThreshold(0)}).Sampled(traceID))
+
+	// the same block is always in the same trace
+	otherTraceID, _ := BlockIDs("1", "0xblockhash")
+	r.Equal(traceID, otherTraceID)
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TraceID identifies the spans of a trace.
+type TraceID [16]byte
+
+// String returns the hex encoding which OTLP uses.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span in a trace.
+type SpanID [8]byte
+
+// String returns the hex encoding which OTLP uses. It is empty for the zero span ID.
+func (id SpanID) String() string {
+	if id == (SpanID{}) {
+		return ""
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// NewSpanID creates a random span ID.
+func NewSpanID() (id SpanID) {
+	rand.Read(id[:])
+	return
+}
+
+// BlockIDs derives the trace ID and the root span ID of a block, so that the spans of all agents
+// which evaluated the block are in the same trace.
+func BlockIDs(chainID, blockHash string) (traceID TraceID, spanID SpanID) {
+	hash := crypto.Keccak256([]byte(chainID + blockHash))
+	copy(traceID[:], hash[:16])
+	copy(spanID[:], hash[16:24])
+	return
+}
+
+// Span is a timed step of a trace.
+type Span struct {
+	TraceID      TraceID
+	SpanID       SpanID
+	ParentSpanID SpanID
+	Name         string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]string
+}
+
+// Child creates a child span with a random span ID.
+func (span *Span) Child(name string, start, end time.Time) *Span {
+	return &Span{
+		TraceID:      span.TraceID,
+		SpanID:       NewSpanID(),
+		ParentSpanID: span.SpanID,
+		Name:         name,
+		Start:        start,
+		End:          end,
+		Attributes:   make(map[string]string),
+	}
+}